@@ -26,6 +26,10 @@ import (
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/hooks"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/version"
 	log "github.com/sirupsen/logrus"
+
+	// Registers the device types arrays can be registered with
+	_ "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flasharray"
+	_ "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flashblade"
 )
 
 const (
@@ -58,6 +62,27 @@ func main() {
 		return
 	}
 
+	err = databaseService.CreateAlertRulesTemplate(context.Background())
+	if err != nil {
+		log.WithError(err).Fatal("Error initializing alert rules template")
+		os.Exit(1)
+		return
+	}
+
+	err = databaseService.CreateSNMPDestinationsTemplate(context.Background())
+	if err != nil {
+		log.WithError(err).Fatal("Error initializing SNMP destinations template")
+		os.Exit(1)
+		return
+	}
+
+	err = databaseService.CreateMaintenanceWindowsTemplate(context.Background())
+	if err != nil {
+		log.WithError(err).Fatal("Error initializing maintenance windows template")
+		os.Exit(1)
+		return
+	}
+
 	errorHook, err := hooks.NewErrorLogHook(sourceName, []log.Level{log.WarnLevel, log.ErrorLevel, log.FatalLevel}, databaseService)
 	if err != nil {
 		log.WithError(err).Fatal("Error creating ErrorLogHook, exiting...")
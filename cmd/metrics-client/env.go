@@ -15,6 +15,9 @@
 package main
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/caarlos0/env"
 	log "github.com/sirupsen/logrus"
 )
@@ -33,17 +36,75 @@ type EnvMetricsClientConfig struct {
 	VolumeMetricsTypeName          string `env:"ELASTIC_VOLUME_METRICS_TYPE_NAME" envDefault:"metrics"`
 	MetricsRetentionCheckPeriod    int    `env:"ELASTIC_METRICS_RETENTION_CHECK_PERIOD" envDefault:"24"`
 	MetricsRetentionPeriod         int    `env:"ELASTIC_METRICS_RETENTION_PERIOD" envDefault:"31"`
+	HourlyRollupRetentionPeriod    int    `env:"ELASTIC_HOURLY_ROLLUP_RETENTION_PERIOD" envDefault:"90"`
+	DailyRollupRetentionPeriod     int    `env:"ELASTIC_DAILY_ROLLUP_RETENTION_PERIOD" envDefault:"730"`
+	RollupEnabled                  bool   `env:"ELASTIC_METRICS_ROLLUP_ENABLED" envDefault:"true"` // If disabled, raw metrics are simply deleted at the end of their retention period
 	AlertsRetentionPeriod          int    `env:"ELASTIC_ALERTS_RETENTION_PERIOD" envDefault:"365"`
+	AuditRetentionPeriod           int    `env:"ELASTIC_AUDIT_RETENTION_PERIOD" envDefault:"365"`
+	CapacityForecastPeriod         int    `env:"CAPACITY_FORECAST_PERIOD" envDefault:"24"`        // Hours between forecasts
+	CapacityForecastHistoryDays    int    `env:"CAPACITY_FORECAST_HISTORY_DAYS" envDefault:"365"` // Days of history each forecast is fitted to
+	BackfillEnabled                bool   `env:"METRICS_BACKFILL_ENABLED" envDefault:"true"`
+	BackfillCheckPeriod            int    `env:"METRICS_BACKFILL_CHECK_PERIOD" envDefault:"600"` // Seconds between checks for gaps in the collected metrics
+	BackfillMaxAge                 int    `env:"METRICS_BACKFILL_MAX_AGE" envDefault:"24"`       // Hours back to look for gaps (arrays keep about a day of history)
 	ErrorLogRetentionPeriod        int    `env:"ELASTIC_ERROR_LOG_RETENTION_PERIOD" envDefault:"1"`
+	AlertRulesEnabled              bool   `env:"ALERT_RULES_ENABLED" envDefault:"true"`
+	AlertRulesRefreshPeriod        int    `env:"ALERT_RULES_REFRESH_PERIOD" envDefault:"60"` // Seconds between fetches of the alert rules
+	ReplicationLagAlertsEnabled    bool   `env:"REPLICATION_LAG_ALERTS_ENABLED" envDefault:"true"`
+	ReplicationLagGracePeriod      int    `env:"REPLICATION_LAG_GRACE_PERIOD" envDefault:"300"` // Seconds a replication can run past its frequency before alerting
+	MaintenanceWindowsEnabled      bool   `env:"MAINTENANCE_WINDOWS_ENABLED" envDefault:"true"`
+	MaintenanceRefreshPeriod       int    `env:"MAINTENANCE_WINDOWS_REFRESH_PERIOD" envDefault:"60"` // Seconds between fetches of the maintenance windows
 	StageTimerRetentionPeriod      int    `env:"ELASTIC_STAGE_TIMER_RETENTION_PERIOD" envDefault:"1"`
+	NotifyMinSeverity              string `env:"NOTIFY_MIN_SEVERITY" envDefault:"warning"` // info, warning or critical
+	NotifyStates                   string `env:"NOTIFY_STATES" envDefault:""`              // Comma-separated alert states, empty for all
+	NotifyArrayTags                string `env:"NOTIFY_ARRAY_TAGS" envDefault:""`          // Comma-separated key=value tags the array must have
+	NotifyMaxAttempts              int    `env:"NOTIFY_MAX_ATTEMPTS" envDefault:"5"`
+	NotifyRetryInitialDelay        int    `env:"NOTIFY_RETRY_INITIAL_DELAY" envDefault:"5"` // Seconds, doubled after every failed attempt
+	NotifyRetryMaxDelay            int    `env:"NOTIFY_RETRY_MAX_DELAY" envDefault:"300"`
+	NotifyQueueLength              int    `env:"NOTIFY_QUEUE_LENGTH" envDefault:"100"` // Pending notifications per channel before dropping
+	NotifySMTPAddress              string `env:"NOTIFY_SMTP_ADDRESS" envDefault:""`    // host:port, empty to disable
+	NotifySMTPUsername             string `env:"NOTIFY_SMTP_USERNAME" envDefault:""`
+	NotifySMTPPasswordFile         string `env:"NOTIFY_SMTP_PASSWORD_FILE" envDefault:""` // Read from a file so it isn't logged with the config
+	NotifySMTPFrom                 string `env:"NOTIFY_SMTP_FROM" envDefault:""`
+	NotifySMTPTo                   string `env:"NOTIFY_SMTP_TO" envDefault:""` // Comma-separated
+	NotifySMTPSubjectTemplate      string `env:"NOTIFY_SMTP_SUBJECT_TEMPLATE" envDefault:""`
+	NotifySMTPBodyTemplate         string `env:"NOTIFY_SMTP_BODY_TEMPLATE" envDefault:""`
+	NotifyWebhookURL               string `env:"NOTIFY_WEBHOOK_URL" envDefault:""` // Empty to disable
+	NotifyWebhookTemplate          string `env:"NOTIFY_WEBHOOK_TEMPLATE" envDefault:""`
+	NotifySyslogAddress            string `env:"NOTIFY_SYSLOG_ADDRESS" envDefault:""` // host:port, empty to disable
+	NotifySyslogNetwork            string `env:"NOTIFY_SYSLOG_NETWORK" envDefault:"udp"`
+	NotifySyslogFacility           int    `env:"NOTIFY_SYSLOG_FACILITY" envDefault:"16"` // local0
+	NotifySyslogTemplate           string `env:"NOTIFY_SYSLOG_TEMPLATE" envDefault:""`
+	SNMPTrapsEnabled               bool   `env:"SNMP_TRAPS_ENABLED" envDefault:"true"`             // Destinations are configured through the API server
+	SNMPDestinationsRefreshPeriod  int    `env:"SNMP_DESTINATIONS_REFRESH_PERIOD" envDefault:"60"` // Seconds between fetches of the SNMP destinations
 	AlertsIndexName                string `env:"ELASTIC_ALERT_INDEX_NAME" envDefault:"pure1-unplugged-alerts"`
 	AlertsTypeName                 string `env:"ELASTIC_ALERT_TYPE_NAME" envDefault:"alerts"`
 	Host                           string `env:"ELASTIC_HOST" envDefault:"localhost:9200"`
 	ArrayMetricCollectionPeriod    int    `env:"ELASTIC_ARRAY_METRIC_COLLECTION_PERIOD" envDefault:"30"`
 	FAVolumeMetricCollectionPeriod int    `env:"ELASTIC_FA_VOLUME_METRIC_COLLECTION_PERIOD" envDefault:"30"`
 	FBVolumeMetricCollectionPeriod int    `env:"ELASTIC_FB_VOLUME_METRIC_COLLECTION_PERIOD" envDefault:"300"` // Cannot collect as frequently as FA
-	WorkerPoolThreads              int    `env:"WORKER_THREADS" envDefault:"50"`                              // Reasonable defaults for most workloads
+	FAHostMetricCollectionPeriod   int    `env:"ELASTIC_FA_HOST_METRIC_COLLECTION_PERIOD" envDefault:"60"`
+	FAPGroupCollectionPeriod       int    `env:"ELASTIC_FA_PROTECTION_GROUP_COLLECTION_PERIOD" envDefault:"60"`
+	FAPodCollectionPeriod          int    `env:"ELASTIC_FA_POD_COLLECTION_PERIOD" envDefault:"60"`
+	HardwareCollectionPeriod       int    `env:"ELASTIC_HARDWARE_COLLECTION_PERIOD" envDefault:"60"`
+	AuditCollectionPeriod          int    `env:"ELASTIC_AUDIT_COLLECTION_PERIOD" envDefault:"300"`
+	SnapshotCollectionPeriod       int    `env:"ELASTIC_SNAPSHOT_COLLECTION_PERIOD" envDefault:"3600"`
+	FBPerformancePageSize          int    `env:"FB_PERFORMANCE_PAGE_SIZE" envDefault:"5"`
+	WorkerPoolThreads              int    `env:"WORKER_THREADS" envDefault:"50"` // Reasonable defaults for most workloads
 	WorkerPoolBufferLength         int    `env:"WORKER_BUFFER_LENGTH" envDefault:"200"`
+	PrometheusExporterEnabled      bool   `env:"PROMETHEUS_EXPORTER_ENABLED" envDefault:"false"`
+	PrometheusExporterPort         int    `env:"PROMETHEUS_EXPORTER_PORT" envDefault:"9491"`
+	PrometheusStalePeriod          int    `env:"PROMETHEUS_STALE_PERIOD" envDefault:"900"` // Seconds before a metric that isn't refreshed is dropped
+	FileSinkDirectory              string `env:"FILE_SINK_DIRECTORY" envDefault:""`        // Empty to disable
+	InfluxDBURL                    string `env:"INFLUXDB_URL" envDefault:""`               // Empty to disable
+	InfluxDBDatabase               string `env:"INFLUXDB_DATABASE" envDefault:"pure1_unplugged"`
+	SinkQueueLength                int    `env:"METRICS_SINK_QUEUE_LENGTH" envDefault:"100"` // Pending writes per secondary sink before dropping
+	SinkMaxAttempts                int    `env:"METRICS_SINK_MAX_ATTEMPTS" envDefault:"3"`
+	SinkRetryTime                  int    `env:"METRICS_SINK_RETRY_TIME" envDefault:"5"` // Seconds between attempts
+	SinkStatusLogPeriod            int    `env:"METRICS_SINK_STATUS_LOG_PERIOD" envDefault:"300"`
+	SpoolDirectory                 string `env:"SPOOL_DIRECTORY" envDefault:""` // Empty to disable
+	SpoolMaxSizeMB                 int    `env:"SPOOL_MAX_SIZE_MB" envDefault:"512"`
+	SpoolReplayPeriod              int    `env:"SPOOL_REPLAY_PERIOD" envDefault:"30"`       // Seconds between checks for Elastic being healthy again
+	ElasticWriteMaxAttempts        uint   `env:"ELASTIC_WRITE_MAX_ATTEMPTS" envDefault:"3"` // Only used with the spool enabled, otherwise writes retry forever
 }
 
 func parseMetricsEnvironmentVariables() error {
@@ -56,3 +117,28 @@ func parseMetricsEnvironmentVariables() error {
 	log.WithField("config", metricsClientEnvConf).Debug("Done initializing metrics client environment variables")
 	return nil
 }
+
+// splitList splits a comma-separated environment variable, dropping empty entries
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseTags parses a comma-separated list of key=value tags
+func parseTags(value string) (map[string]string, error) {
+	tags := map[string]string{}
+	for _, item := range splitList(value) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 {
+			return nil, fmt.Errorf("Invalid tag %s, expected key=value", item)
+		}
+		tags[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return tags, nil
+}
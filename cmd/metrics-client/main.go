@@ -16,12 +16,26 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/alertrules"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/apiserver"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/registry"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/elastic"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/fanout"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/file"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/influxdb"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/maintenance"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/notifier"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/prometheus"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/replication"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/spool"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/hooks"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/jobs"
@@ -55,7 +69,8 @@ func main() {
 	}
 
 	discoveryService := apiserver.NewConnection("http://pure1-unplugged-api-server")
-	collectorFactory := array.NewRESTFactory(discoveryService)
+	// Collectors (and their array sessions) are reused by every job until the array's registration changes
+	collectorFactory := array.NewCachingFactory(array.NewRESTFactory(discoveryService, metricsClientEnvConf.FBPerformancePageSize))
 	databaseService, err := elastic.InitializeClient(metricsClientEnvConf.Host, 0, time.Second*5)
 	if err != nil {
 		log.WithError(err).Fatal("Error initializing elastic connection, exiting...")
@@ -79,6 +94,48 @@ func main() {
 		return
 	}
 
+	err = databaseService.CreateHostMetricsTemplate(ctx)
+	if err != nil {
+		log.WithError(err).Fatal("Error initializing host metrics template")
+		os.Exit(1)
+		return
+	}
+
+	err = databaseService.CreateProtectionGroupMetricsTemplate(ctx)
+	if err != nil {
+		log.WithError(err).Fatal("Error initializing protection group metrics template")
+		os.Exit(1)
+		return
+	}
+
+	err = databaseService.CreatePodMetricsTemplate(ctx)
+	if err != nil {
+		log.WithError(err).Fatal("Error initializing pod metrics template")
+		os.Exit(1)
+		return
+	}
+
+	err = databaseService.CreateHardwareMetricsTemplate(ctx)
+	if err != nil {
+		log.WithError(err).Fatal("Error initializing hardware metrics template")
+		os.Exit(1)
+		return
+	}
+
+	err = databaseService.CreateAuditEventsTemplate(ctx)
+	if err != nil {
+		log.WithError(err).Fatal("Error initializing audit events template")
+		os.Exit(1)
+		return
+	}
+
+	err = databaseService.CreateSnapshotsTemplate(ctx)
+	if err != nil {
+		log.WithError(err).Fatal("Error initializing snapshots template")
+		os.Exit(1)
+		return
+	}
+
 	err = databaseService.CreateAlertsTemplate(ctx)
 	if err != nil {
 		log.WithError(err).Fatal("Error initializing alerts template")
@@ -86,6 +143,23 @@ func main() {
 		return
 	}
 
+	err = databaseService.CreateCapacityForecastsTemplate(ctx)
+	if err != nil {
+		log.WithError(err).Fatal("Error initializing capacity forecasts template")
+		os.Exit(1)
+		return
+	}
+
+	if metricsClientEnvConf.RollupEnabled {
+		err = databaseService.CreateMetricRollupsTemplate(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("Error initializing metric rollups template")
+			os.Exit(1)
+			return
+		}
+		databaseService.RequireRollupsBeforeCleaning(true)
+	}
+
 	timerHook, err := hooks.NewStageTimerHook(sourceName, databaseService)
 	if err != nil {
 		log.WithError(err).Fatal("Error creating StageTimerHook, exiting...")
@@ -101,30 +175,113 @@ func main() {
 	log.AddHook(timerHook)
 	log.AddHook(errorHook)
 
+	spoolDatabase, err := createSpoolDatabase(databaseService)
+	if err != nil {
+		log.WithError(err).Fatal("Error creating metrics spool, exiting...")
+		os.Exit(1)
+		return
+	}
+
+	alertNotifier, err := createNotifier(discoveryService, databaseService)
+	if err != nil {
+		log.WithError(err).Fatal("Error creating alert notifier, exiting...")
+		os.Exit(1)
+		return
+	}
+
+	metricsDatabase, err := createMetricsDatabase(databaseService, spoolDatabase, alertNotifier)
+	if err != nil {
+		log.WithError(err).Fatal("Error creating metrics sinks, exiting...")
+		os.Exit(1)
+		return
+	}
+
+	// Collected metrics are marked if they're in a maintenance window, then go through the alert rules
+	// (if enabled) on their way to the sinks
+	collectedMetricsDatabase := createMaintenanceMarker(createAlertRuleEvaluator(metricsDatabase, databaseService, discoveryService), discoveryService)
+
+	// Protection group metrics are checked for replication lag on their way to elastic, with any lag alerts
+	// going through the same path as the other collected alerts
+	protectionGroupDatabase := createReplicationLagMonitor(databaseService, collectedMetricsDatabase)
+
 	arrayMetricsCollectionFrequency := time.Duration(metricsClientEnvConf.ArrayMetricCollectionPeriod) * time.Second
 	faVolumeMetricsCollectionFrequency := time.Duration(metricsClientEnvConf.FAVolumeMetricCollectionPeriod) * time.Second
 	fbVolumeMetricsCollectionFrequency := time.Duration(metricsClientEnvConf.FBVolumeMetricCollectionPeriod) * time.Second
+	faHostMetricsCollectionFrequency := time.Duration(metricsClientEnvConf.FAHostMetricCollectionPeriod) * time.Second
+	faProtectionGroupCollectionFrequency := time.Duration(metricsClientEnvConf.FAPGroupCollectionPeriod) * time.Second
+	faPodCollectionFrequency := time.Duration(metricsClientEnvConf.FAPodCollectionPeriod) * time.Second
+	hardwareCollectionFrequency := time.Duration(metricsClientEnvConf.HardwareCollectionPeriod) * time.Second
+	auditCollectionFrequency := time.Duration(metricsClientEnvConf.AuditCollectionPeriod) * time.Second
+	snapshotCollectionFrequency := time.Duration(metricsClientEnvConf.SnapshotCollectionPeriod) * time.Second
 
 	arrayMetricsCollectionTicker := time.NewTicker(arrayMetricsCollectionFrequency)
 	faVolumeMetricsCollectionTicker := time.NewTicker(faVolumeMetricsCollectionFrequency)
 	fbVolumeMetricsCollectionTicker := time.NewTicker(fbVolumeMetricsCollectionFrequency)
+	faHostMetricsCollectionTicker := time.NewTicker(faHostMetricsCollectionFrequency)
+	faProtectionGroupCollectionTicker := time.NewTicker(faProtectionGroupCollectionFrequency)
+	faPodCollectionTicker := time.NewTicker(faPodCollectionFrequency)
+	hardwareCollectionTicker := time.NewTicker(hardwareCollectionFrequency)
+	auditCollectionTicker := time.NewTicker(auditCollectionFrequency)
+	snapshotCollectionTicker := time.NewTicker(snapshotCollectionFrequency)
 	dataRetentionTicker := time.NewTicker(time.Duration(metricsClientEnvConf.MetricsRetentionCheckPeriod) * time.Hour)
+	capacityForecastTicker := time.NewTicker(time.Duration(metricsClientEnvConf.CapacityForecastPeriod) * time.Hour)
+	sinkStatusTicker := time.NewTicker(time.Duration(metricsClientEnvConf.SinkStatusLogPeriod) * time.Second)
+	backfillTicker := time.NewTicker(time.Duration(metricsClientEnvConf.BackfillCheckPeriod) * time.Second)
 
 	workerPool := workerpool.CreateThreadPool(metricsClientEnvConf.WorkerPoolThreads, metricsClientEnvConf.WorkerPoolBufferLength)
 
+	// Forecast right away rather than waiting a whole period after every restart
+	createCapacityForecastJob(&workerPool, databaseService)
+	// Likewise fill in whatever was missed while this was down
+	createBackfillJobs(&workerPool, discoveryService, databaseService, collectorFactory, spoolDatabase, arrayMetricsCollectionFrequency)
+
 	for {
 		select {
 		case <-arrayMetricsCollectionTicker.C:
-			createArrayMetricsJobs(&workerPool, discoveryService, databaseService, collectorFactory, arrayMetricsCollectionFrequency)
+			createArrayMetricsJobs(&workerPool, discoveryService, collectedMetricsDatabase, collectorFactory, arrayMetricsCollectionFrequency)
 			break
 		case <-faVolumeMetricsCollectionTicker.C:
-			createVolumeMetricsJobs(&workerPool, discoveryService, databaseService, collectorFactory, faVolumeMetricsCollectionFrequency, common.FlashArray)
+			createVolumeMetricsJobs(&workerPool, discoveryService, collectedMetricsDatabase, collectorFactory, faVolumeMetricsCollectionFrequency, common.FlashArray)
 			break
 		case <-fbVolumeMetricsCollectionTicker.C:
-			createVolumeMetricsJobs(&workerPool, discoveryService, databaseService, collectorFactory, fbVolumeMetricsCollectionFrequency, common.FlashBlade)
+			createVolumeMetricsJobs(&workerPool, discoveryService, collectedMetricsDatabase, collectorFactory, fbVolumeMetricsCollectionFrequency, common.FlashBlade)
+			break
+		case <-faHostMetricsCollectionTicker.C:
+			createHostMetricsJobs(&workerPool, discoveryService, databaseService, collectorFactory, faHostMetricsCollectionFrequency)
+			break
+		case <-faProtectionGroupCollectionTicker.C:
+			createProtectionGroupJobs(&workerPool, discoveryService, protectionGroupDatabase, collectorFactory, faProtectionGroupCollectionFrequency)
+			break
+		case <-faPodCollectionTicker.C:
+			createPodJobs(&workerPool, discoveryService, databaseService, collectorFactory, faPodCollectionFrequency)
+			break
+		case <-hardwareCollectionTicker.C:
+			createHardwareJobs(&workerPool, discoveryService, databaseService, collectorFactory, hardwareCollectionFrequency)
+			break
+		case <-auditCollectionTicker.C:
+			createAuditJobs(&workerPool, discoveryService, databaseService, collectorFactory, auditCollectionFrequency)
+			break
+		case <-snapshotCollectionTicker.C:
+			createSnapshotJobs(&workerPool, discoveryService, databaseService, collectorFactory, snapshotCollectionFrequency)
 			break
 		case <-dataRetentionTicker.C:
-			createDataRetentionJobs(&workerPool, databaseService)
+			createDataRetentionJobs(&workerPool, metricsDatabase, databaseService, databaseService, databaseService, databaseService, databaseService, databaseService)
+			break
+		case <-capacityForecastTicker.C:
+			createCapacityForecastJob(&workerPool, databaseService)
+			break
+		case <-backfillTicker.C:
+			createBackfillJobs(&workerPool, discoveryService, databaseService, collectorFactory, spoolDatabase, arrayMetricsCollectionFrequency)
+			break
+		case <-sinkStatusTicker.C:
+			logSinkStatuses(metricsDatabase)
+			logCollectorCacheStatus(collectorFactory)
+			if spoolDatabase != nil {
+				logSpoolStatus(spoolDatabase)
+			}
+			if alertNotifier != nil {
+				logNotifierStatuses(alertNotifier)
+			}
 			break
 		}
 	}
@@ -149,6 +306,11 @@ func createArrayMetricsJobs(workerPool *workerpool.Pool, discoveryService resour
 	}
 	log.WithField("arrays", arrays).Trace("Fetched array list")
 
+	// Drop the cached collectors of arrays that were deleted
+	if collectorCache, ok := collectorFactory.(resources.CollectorCache); ok {
+		collectorCache.PruneCollectors(arrays)
+	}
+
 	for _, arrayStruct := range arrays {
 		log.WithField("array", arrayStruct).Trace("Enqueueing array metrics collect job for array")
 		workerPool.Enqueue(&jobs.ArrayMetricCollectJob{TargetArray: arrayStruct, CollectorFactory: collectorFactory, TargetDatabase: databaseService, TargetPool: workerPool}, collectionPeriod)
@@ -186,10 +348,206 @@ func createVolumeMetricsJobs(workerPool *workerpool.Pool, discoveryService resou
 	log.Trace("Array loop completed")
 }
 
-func createDataRetentionJobs(workerPool *workerpool.Pool, databaseService metrics.Database) {
+func createHostMetricsJobs(workerPool *workerpool.Pool, discoveryService resources.ArrayDiscovery, databaseService metrics.HostDatabase, collectorFactory resources.CollectorFactory, collectionPeriod time.Duration) {
+	if discoveryService == nil {
+		log.Error("Discovery service is nil, stopping")
+		return
+	}
+	if databaseService == nil {
+		log.Error("Database service is nil, stopping")
+		return
+	}
+
+	log.Trace("Starting to fetch arrays from discovery service")
+	arrays, err := discoveryService.GetArrays()
+
+	if err != nil {
+		log.WithError(err).Error("Error fetching array list, skipping this iteration")
+		return
+	}
+	log.WithField("arrays", arrays).Trace("Fetched array list")
+
+	for _, arrayStruct := range arrays {
+		// Only device types with hosts
+		if registry.HasCapability(arrayStruct.DeviceType, registry.CapabilityHosts) {
+			log.WithField("array", arrayStruct).Trace("Enqueueing host metrics collect job for array")
+			workerPool.Enqueue(&jobs.ArrayHostMetricCollectJob{TargetArray: arrayStruct, CollectorFactory: collectorFactory, TargetDatabase: databaseService, TargetPool: workerPool}, collectionPeriod)
+			log.WithField("array", arrayStruct).Trace("Finished enqueueing host metrics collect job for array")
+		}
+	}
+	log.Trace("Array loop completed")
+}
+
+func createProtectionGroupJobs(workerPool *workerpool.Pool, discoveryService resources.ArrayDiscovery, databaseService metrics.ProtectionGroupDatabase, collectorFactory resources.CollectorFactory, collectionPeriod time.Duration) {
+	if discoveryService == nil {
+		log.Error("Discovery service is nil, stopping")
+		return
+	}
+	if databaseService == nil {
+		log.Error("Database service is nil, stopping")
+		return
+	}
+
+	log.Trace("Starting to fetch arrays from discovery service")
+	arrays, err := discoveryService.GetArrays()
+
+	if err != nil {
+		log.WithError(err).Error("Error fetching array list, skipping this iteration")
+		return
+	}
+	log.WithField("arrays", arrays).Trace("Fetched array list")
+
+	for _, arrayStruct := range arrays {
+		// Only device types with protection groups
+		if registry.HasCapability(arrayStruct.DeviceType, registry.CapabilityProtectionGroups) {
+			log.WithField("array", arrayStruct).Trace("Enqueueing protection group collect job for array")
+			workerPool.Enqueue(&jobs.ArrayProtectionGroupCollectJob{TargetArray: arrayStruct, CollectorFactory: collectorFactory, TargetDatabase: databaseService, TargetPool: workerPool}, collectionPeriod)
+			log.WithField("array", arrayStruct).Trace("Finished enqueueing protection group collect job for array")
+		}
+	}
+	log.Trace("Array loop completed")
+}
+
+func createPodJobs(workerPool *workerpool.Pool, discoveryService resources.ArrayDiscovery, databaseService metrics.PodDatabase, collectorFactory resources.CollectorFactory, collectionPeriod time.Duration) {
+	if discoveryService == nil {
+		log.Error("Discovery service is nil, stopping")
+		return
+	}
+	if databaseService == nil {
+		log.Error("Database service is nil, stopping")
+		return
+	}
+
+	log.Trace("Starting to fetch arrays from discovery service")
+	arrays, err := discoveryService.GetArrays()
+
+	if err != nil {
+		log.WithError(err).Error("Error fetching array list, skipping this iteration")
+		return
+	}
+	log.WithField("arrays", arrays).Trace("Fetched array list")
+
+	for _, arrayStruct := range arrays {
+		// Only device types with pods
+		if registry.HasCapability(arrayStruct.DeviceType, registry.CapabilityPods) {
+			log.WithField("array", arrayStruct).Trace("Enqueueing pod collect job for array")
+			workerPool.Enqueue(&jobs.ArrayPodCollectJob{TargetArray: arrayStruct, CollectorFactory: collectorFactory, TargetDatabase: databaseService, TargetPool: workerPool}, collectionPeriod)
+			log.WithField("array", arrayStruct).Trace("Finished enqueueing pod collect job for array")
+		}
+	}
+	log.Trace("Array loop completed")
+}
+
+func createHardwareJobs(workerPool *workerpool.Pool, discoveryService resources.ArrayDiscovery, databaseService metrics.HardwareDatabase, collectorFactory resources.CollectorFactory, collectionPeriod time.Duration) {
+	if discoveryService == nil {
+		log.Error("Discovery service is nil, stopping")
+		return
+	}
+	if databaseService == nil {
+		log.Error("Database service is nil, stopping")
+		return
+	}
+
+	log.Trace("Starting to fetch arrays from discovery service")
+	arrays, err := discoveryService.GetArrays()
+
+	if err != nil {
+		log.WithError(err).Error("Error fetching array list, skipping this iteration")
+		return
+	}
+	log.WithField("arrays", arrays).Trace("Fetched array list")
+
+	for _, arrayStruct := range arrays {
+		// Only device types with hardware status
+		if registry.HasCapability(arrayStruct.DeviceType, registry.CapabilityHardware) {
+			log.WithField("array", arrayStruct).Trace("Enqueueing hardware collect job for array")
+			workerPool.Enqueue(&jobs.ArrayHardwareCollectJob{TargetArray: arrayStruct, CollectorFactory: collectorFactory, TargetDatabase: databaseService, TargetPool: workerPool}, collectionPeriod)
+			log.WithField("array", arrayStruct).Trace("Finished enqueueing hardware collect job for array")
+		}
+	}
+	log.Trace("Array loop completed")
+}
+
+func createAuditJobs(workerPool *workerpool.Pool, discoveryService resources.ArrayDiscovery, databaseService metrics.AuditDatabase, collectorFactory resources.CollectorFactory, collectionPeriod time.Duration) {
+	if discoveryService == nil {
+		log.Error("Discovery service is nil, stopping")
+		return
+	}
+	if databaseService == nil {
+		log.Error("Database service is nil, stopping")
+		return
+	}
+
+	log.Trace("Starting to fetch arrays from discovery service")
+	arrays, err := discoveryService.GetArrays()
+
+	if err != nil {
+		log.WithError(err).Error("Error fetching array list, skipping this iteration")
+		return
+	}
+	log.WithField("arrays", arrays).Trace("Fetched array list")
+
+	for _, arrayStruct := range arrays {
+		// Only device types with audit logs
+		if registry.HasCapability(arrayStruct.DeviceType, registry.CapabilityAudit) {
+			log.WithField("array", arrayStruct).Trace("Enqueueing audit collect job for array")
+			workerPool.Enqueue(&jobs.ArrayAuditCollectJob{TargetArray: arrayStruct, CollectorFactory: collectorFactory, TargetDatabase: databaseService, TargetPool: workerPool}, collectionPeriod)
+			log.WithField("array", arrayStruct).Trace("Finished enqueueing audit collect job for array")
+		}
+	}
+	log.Trace("Array loop completed")
+}
+
+func createSnapshotJobs(workerPool *workerpool.Pool, discoveryService resources.ArrayDiscovery, databaseService metrics.SnapshotDatabase, collectorFactory resources.CollectorFactory, collectionPeriod time.Duration) {
+	if discoveryService == nil {
+		log.Error("Discovery service is nil, stopping")
+		return
+	}
+	if databaseService == nil {
+		log.Error("Database service is nil, stopping")
+		return
+	}
+
+	log.Trace("Starting to fetch arrays from discovery service")
+	arrays, err := discoveryService.GetArrays()
+
+	if err != nil {
+		log.WithError(err).Error("Error fetching array list, skipping this iteration")
+		return
+	}
+	log.WithField("arrays", arrays).Trace("Fetched array list")
+
+	for _, arrayStruct := range arrays {
+		// Only device types with snapshot inventories
+		if registry.HasCapability(arrayStruct.DeviceType, registry.CapabilitySnapshots) {
+			log.WithField("array", arrayStruct).Trace("Enqueueing snapshot collect job for array")
+			workerPool.Enqueue(&jobs.ArraySnapshotCollectJob{TargetArray: arrayStruct, CollectorFactory: collectorFactory, TargetDatabase: databaseService, TargetPool: workerPool}, collectionPeriod)
+			log.WithField("array", arrayStruct).Trace("Finished enqueueing snapshot collect job for array")
+		}
+	}
+	log.Trace("Array loop completed")
+}
+
+func createDataRetentionJobs(workerPool *workerpool.Pool, databaseService metrics.Database, rollupDatabase metrics.RollupDatabase, hostDatabase metrics.HostDatabase, protectionGroupDatabase metrics.ProtectionGroupDatabase,
+	podDatabase metrics.PodDatabase, hardwareDatabase metrics.HardwareDatabase, auditDatabase metrics.AuditDatabase) {
 	log.Info("Beginning data retention enforcement")
+	if metricsClientEnvConf.RollupEnabled {
+		// Raw metrics that haven't been rolled up by the time the cleanup job gets to them are kept until the next run
+		workerPool.Enqueue(&jobs.MetricRollupJob{TargetDatabase: rollupDatabase, HourlyMaxAgeInDays: metricsClientEnvConf.HourlyRollupRetentionPeriod, DailyMaxAgeInDays: metricsClientEnvConf.DailyRollupRetentionPeriod}, time.Hour)
+		log.Trace("Metrics rollup job enqueued")
+	}
 	workerPool.Enqueue(&jobs.MetricCleanupJob{TargetDatabase: databaseService, MaxAgeInDays: metricsClientEnvConf.MetricsRetentionPeriod}, time.Hour) // Give it an hour to run, so it almost certainly will
-	log.Trace("Metrics cleanup job enqueued, enqueueing alerts cleanup job")
+	log.Trace("Metrics cleanup job enqueued, enqueueing host metrics cleanup job")
+	workerPool.Enqueue(&jobs.HostMetricCleanupJob{TargetDatabase: hostDatabase, MaxAgeInDays: metricsClientEnvConf.MetricsRetentionPeriod}, time.Hour)
+	log.Trace("Host metrics cleanup job enqueued, enqueueing protection group metrics cleanup job")
+	workerPool.Enqueue(&jobs.ProtectionGroupMetricCleanupJob{TargetDatabase: protectionGroupDatabase, MaxAgeInDays: metricsClientEnvConf.MetricsRetentionPeriod}, time.Hour)
+	log.Trace("Protection group metrics cleanup job enqueued, enqueueing pod metrics cleanup job")
+	workerPool.Enqueue(&jobs.PodMetricCleanupJob{TargetDatabase: podDatabase, MaxAgeInDays: metricsClientEnvConf.MetricsRetentionPeriod}, time.Hour)
+	log.Trace("Pod metrics cleanup job enqueued, enqueueing hardware metrics cleanup job")
+	workerPool.Enqueue(&jobs.HardwareMetricCleanupJob{TargetDatabase: hardwareDatabase, MaxAgeInDays: metricsClientEnvConf.MetricsRetentionPeriod}, time.Hour)
+	log.Trace("Hardware metrics cleanup job enqueued, enqueueing audit events cleanup job")
+	workerPool.Enqueue(&jobs.AuditEventCleanupJob{TargetDatabase: auditDatabase, MaxAgeInDays: metricsClientEnvConf.AuditRetentionPeriod}, time.Hour)
+	log.Trace("Audit events cleanup job enqueued, enqueueing alerts cleanup job")
 	workerPool.Enqueue(&jobs.AlertCleanupJob{TargetDatabase: databaseService, MaxAgeInDays: metricsClientEnvConf.AlertsRetentionPeriod}, time.Hour) // Give it an hour to run, so it almost certainly will
 	log.Trace("Alerts cleanup job enqueued")
 	workerPool.Enqueue(&jobs.ErrorLogCleanupJob{TargetDatabase: databaseService, MaxAgeInDays: metricsClientEnvConf.ErrorLogRetentionPeriod}, time.Hour)
@@ -197,3 +555,297 @@ func createDataRetentionJobs(workerPool *workerpool.Pool, databaseService metric
 	workerPool.Enqueue(&jobs.TimerLogCleanupJob{TargetDatabase: databaseService, MaxAgeInDays: metricsClientEnvConf.StageTimerRetentionPeriod}, time.Hour)
 	log.Trace("Stage timer log cleanup job enqueued")
 }
+
+// createSpoolDatabase puts an on-disk spool in front of Elastic if one is configured, so writes made while
+// Elastic is down are replayed later instead of being lost. Returns nil if the spool is disabled.
+func createSpoolDatabase(databaseService *elastic.Client) (*spool.Database, error) {
+	if len(metricsClientEnvConf.SpoolDirectory) == 0 {
+		return nil, nil
+	}
+
+	spoolDatabase, err := spool.NewDatabase(databaseService, databaseService.Connected, metricsClientEnvConf.SpoolDirectory, int64(metricsClientEnvConf.SpoolMaxSizeMB)*1024*1024)
+	if err != nil {
+		return nil, err
+	}
+	// Writes have to fail eventually for them to be spooled, rather than retrying until they go stale
+	databaseService.SetMaxAttempts(metricsClientEnvConf.ElasticWriteMaxAttempts)
+	go spoolDatabase.Run(time.Duration(metricsClientEnvConf.SpoolReplayPeriod) * time.Second)
+
+	log.WithFields(log.Fields{
+		"directory":   metricsClientEnvConf.SpoolDirectory,
+		"max_size_mb": metricsClientEnvConf.SpoolMaxSizeMB,
+	}).Info("Spooling failed Elastic writes to disk")
+	return spoolDatabase, nil
+}
+
+func createCapacityForecastJob(workerPool *workerpool.Pool, forecastDatabase metrics.ForecastDatabase) {
+	workerPool.Enqueue(&jobs.CapacityForecastJob{TargetDatabase: forecastDatabase, HistoryDays: metricsClientEnvConf.CapacityForecastHistoryDays}, time.Hour)
+	log.Trace("Capacity forecast job enqueued")
+}
+
+// createBackfillJobs enqueues a job per array to fill in gaps in its collected metrics from the array's own
+// performance history. Gaps are any time between collections longer than twice the collection period.
+func createBackfillJobs(workerPool *workerpool.Pool, discoveryService resources.ArrayDiscovery, backfillDatabase metrics.BackfillDatabase, collectorFactory resources.CollectorFactory,
+	spoolDatabase *spool.Database, collectionPeriod time.Duration) {
+	if !metricsClientEnvConf.BackfillEnabled {
+		return
+	}
+	if discoveryService == nil {
+		log.Error("Discovery service is nil, stopping")
+		return
+	}
+	// Spooled metrics fill in their own gaps once replayed, so wait for them rather than backfilling the same times
+	if spoolDatabase != nil && spoolDatabase.Status().Batches > 0 {
+		log.Debug("Spool not yet replayed, skipping metrics backfill this iteration")
+		return
+	}
+
+	log.Trace("Starting to fetch arrays from discovery service")
+	arrays, err := discoveryService.GetArrays()
+
+	if err != nil {
+		log.WithError(err).Error("Error fetching array list, skipping this iteration")
+		return
+	}
+	log.WithField("arrays", arrays).Trace("Fetched array list")
+
+	checkPeriod := time.Duration(metricsClientEnvConf.BackfillCheckPeriod) * time.Second
+	for _, arrayStruct := range arrays {
+		// Only device types with a performance history to backfill from
+		if registry.HasCapability(arrayStruct.DeviceType, registry.CapabilityPerformanceHistory) {
+			log.WithField("array", arrayStruct).Trace("Enqueueing backfill job for array")
+			workerPool.Enqueue(&jobs.ArrayBackfillJob{TargetArray: arrayStruct, CollectorFactory: collectorFactory, TargetDatabase: backfillDatabase,
+				MaxAgeInHours: metricsClientEnvConf.BackfillMaxAge, MaxInterval: int64(2 * collectionPeriod.Seconds())}, checkPeriod)
+			log.WithField("array", arrayStruct).Trace("Finished enqueueing backfill job for array")
+		}
+	}
+	log.Trace("Array loop completed")
+}
+
+// createAlertRuleEvaluator puts the alert rule evaluator in front of the given database if alert rules are
+// enabled, so the alerts raised by rules are written along with the metrics. Returns the given database otherwise.
+func createAlertRuleEvaluator(metricsDatabase metrics.Database, databaseService *elastic.Client, discoveryService *apiserver.APIServer) metrics.Database {
+	if !metricsClientEnvConf.AlertRulesEnabled {
+		return metricsDatabase
+	}
+
+	evaluator := alertrules.NewEvaluator(metricsDatabase, databaseService, discoveryService)
+	go evaluator.Run(time.Duration(metricsClientEnvConf.AlertRulesRefreshPeriod) * time.Second)
+	log.Info("Evaluating alert rules against collected metrics")
+	return evaluator
+}
+
+// createReplicationLagMonitor puts the replication lag monitor in front of elastic if replication lag alerts are
+// enabled, so lagging protection groups raise alerts through the given alert database. Returns elastic otherwise.
+func createReplicationLagMonitor(databaseService *elastic.Client, alertDatabase metrics.Database) metrics.ProtectionGroupDatabase {
+	if !metricsClientEnvConf.ReplicationLagAlertsEnabled {
+		return databaseService
+	}
+
+	log.Info("Alerting on replication lag of collected protection groups")
+	return replication.NewLagMonitor(databaseService, alertDatabase, databaseService, time.Duration(metricsClientEnvConf.ReplicationLagGracePeriod)*time.Second)
+}
+
+// createMaintenanceMarker puts the maintenance marker in front of the given database if maintenance windows are
+// enabled, so everything behind it knows which metrics and alerts were collected during maintenance. Returns the
+// given database otherwise.
+func createMaintenanceMarker(metricsDatabase metrics.Database, discoveryService *apiserver.APIServer) metrics.Database {
+	if !metricsClientEnvConf.MaintenanceWindowsEnabled {
+		return metricsDatabase
+	}
+
+	marker := maintenance.NewMarker(metricsDatabase, discoveryService, discoveryService)
+	go marker.Run(time.Duration(metricsClientEnvConf.MaintenanceRefreshPeriod) * time.Second)
+	log.Info("Marking metrics and alerts collected during maintenance windows")
+	return marker
+}
+
+// createNotifier creates the notifier that delivers alerts over every notification channel that's configured,
+// including SNMP traps to the destinations from the API server if they're enabled. Returns nil if no channels are configured.
+func createNotifier(discoveryService *apiserver.APIServer, alertDatabase resources.AlertDatabase) (*notifier.Notifier, error) {
+	channels := []notifier.Channel{}
+	timeout := 30 * time.Second
+
+	if len(metricsClientEnvConf.NotifySMTPAddress) > 0 {
+		subject, err := notifier.ParseTemplate("subject", metricsClientEnvConf.NotifySMTPSubjectTemplate, notifier.DefaultSubjectTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid email subject template: %v", err)
+		}
+		body, err := notifier.ParseTemplate("body", metricsClientEnvConf.NotifySMTPBodyTemplate, notifier.DefaultBodyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid email body template: %v", err)
+		}
+		password := ""
+		if len(metricsClientEnvConf.NotifySMTPPasswordFile) > 0 {
+			contents, err := ioutil.ReadFile(metricsClientEnvConf.NotifySMTPPasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("Error reading SMTP password: %v", err)
+			}
+			password = strings.TrimSpace(string(contents))
+		}
+		channel, err := notifier.NewSMTPChannel(metricsClientEnvConf.NotifySMTPAddress, metricsClientEnvConf.NotifySMTPUsername, password,
+			metricsClientEnvConf.NotifySMTPFrom, splitList(metricsClientEnvConf.NotifySMTPTo), subject, body)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	if len(metricsClientEnvConf.NotifyWebhookURL) > 0 {
+		message, err := notifier.ParseTemplate("message", metricsClientEnvConf.NotifyWebhookTemplate, notifier.DefaultMessageTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid webhook message template: %v", err)
+		}
+		channels = append(channels, notifier.NewWebhookChannel(metricsClientEnvConf.NotifyWebhookURL, message, timeout))
+	}
+	if len(metricsClientEnvConf.NotifySyslogAddress) > 0 {
+		message, err := notifier.ParseTemplate("message", metricsClientEnvConf.NotifySyslogTemplate, notifier.DefaultMessageTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid syslog message template: %v", err)
+		}
+		channel, err := notifier.NewSyslogChannel(metricsClientEnvConf.NotifySyslogNetwork, metricsClientEnvConf.NotifySyslogAddress,
+			metricsClientEnvConf.NotifySyslogFacility, sourceName, message, timeout)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	if metricsClientEnvConf.SNMPTrapsEnabled {
+		channel := notifier.NewSNMPChannel(discoveryService, timeout)
+		go channel.Run(time.Duration(metricsClientEnvConf.SNMPDestinationsRefreshPeriod) * time.Second)
+		channels = append(channels, channel)
+	}
+	if len(channels) == 0 {
+		return nil, nil
+	}
+
+	severity := &metrics.Alert{Severity: metricsClientEnvConf.NotifyMinSeverity}
+	severity.PopulateSeverityIndex()
+	if severity.SeverityIndex == 0 {
+		return nil, fmt.Errorf("Invalid minimum notification severity %s, must be info, warning or critical", metricsClientEnvConf.NotifyMinSeverity)
+	}
+	arrayTags, err := parseTags(metricsClientEnvConf.NotifyArrayTags)
+	if err != nil {
+		return nil, err
+	}
+	filter := notifier.Filter{
+		MinSeverityIndex: severity.SeverityIndex,
+		States:           splitList(metricsClientEnvConf.NotifyStates),
+		ArrayTags:        arrayTags,
+	}
+	backoff := notifier.Backoff{
+		MaxAttempts:  metricsClientEnvConf.NotifyMaxAttempts,
+		InitialDelay: time.Duration(metricsClientEnvConf.NotifyRetryInitialDelay) * time.Second,
+		MaxDelay:     time.Duration(metricsClientEnvConf.NotifyRetryMaxDelay) * time.Second,
+	}
+
+	for _, channel := range channels {
+		log.WithField("channel", channel.Name()).Info("Delivering alert notifications")
+	}
+	return notifier.NewNotifier(filter, alertDatabase, discoveryService, channels, backoff, metricsClientEnvConf.NotifyQueueLength), nil
+}
+
+// createMetricsDatabase fans metrics out to Elastic (the primary store, through the spool if there is one)
+// and any secondary sinks that are configured, including the alert notifier if there is one
+func createMetricsDatabase(databaseService *elastic.Client, spoolDatabase *spool.Database, alertNotifier *notifier.Notifier) (*fanout.Database, error) {
+	// The Elastic client already retries internally, so only try each write once at this level
+	primary := fanout.Sink{Name: "elastic", Database: databaseService, MaxAttempts: 1}
+	if spoolDatabase != nil {
+		primary.Database = spoolDatabase
+	}
+
+	secondaries := []fanout.Sink{}
+	retryTime := time.Duration(metricsClientEnvConf.SinkRetryTime) * time.Second
+	if metricsClientEnvConf.PrometheusExporterEnabled {
+		exporter := prometheus.NewExporter(time.Duration(metricsClientEnvConf.PrometheusStalePeriod) * time.Second)
+		secondaries = append(secondaries, fanout.Sink{Name: "prometheus", Database: exporter, MaxAttempts: 1})
+		go servePrometheusExporter(exporter, metricsClientEnvConf.PrometheusExporterPort)
+	}
+	if len(metricsClientEnvConf.FileSinkDirectory) > 0 {
+		writer, err := file.NewWriter(metricsClientEnvConf.FileSinkDirectory)
+		if err != nil {
+			return nil, err
+		}
+		secondaries = append(secondaries, fanout.Sink{Name: "file", Database: writer, MaxAttempts: metricsClientEnvConf.SinkMaxAttempts, RetryTime: retryTime})
+	}
+	if len(metricsClientEnvConf.InfluxDBURL) > 0 {
+		client := influxdb.NewClient(metricsClientEnvConf.InfluxDBURL, metricsClientEnvConf.InfluxDBDatabase, 30*time.Second)
+		secondaries = append(secondaries, fanout.Sink{Name: "influxdb", Database: client, MaxAttempts: metricsClientEnvConf.SinkMaxAttempts, RetryTime: retryTime})
+	}
+	if alertNotifier != nil {
+		// The notifier queues and retries deliveries itself, so writes to it never fail
+		secondaries = append(secondaries, fanout.Sink{Name: "notifier", Database: alertNotifier, MaxAttempts: 1})
+	}
+
+	for _, secondary := range secondaries {
+		log.WithField("sink", secondary.Name).Info("Writing metrics to secondary sink")
+	}
+	return fanout.NewDatabase(primary, secondaries, metricsClientEnvConf.SinkQueueLength), nil
+}
+
+func logSinkStatuses(metricsDatabase *fanout.Database) {
+	for _, status := range metricsDatabase.Statuses() {
+		log.WithFields(log.Fields{
+			"sink":         status.Name,
+			"primary":      status.Primary,
+			"succeeded":    status.Succeeded,
+			"failed":       status.Failed,
+			"dropped":      status.Dropped,
+			"queue_length": status.QueueLength,
+			"last_error":   status.LastError,
+			"last_success": status.LastSuccess,
+			"last_failure": status.LastFailure,
+		}).Info("Metrics sink status")
+	}
+}
+
+func logNotifierStatuses(alertNotifier *notifier.Notifier) {
+	for _, status := range alertNotifier.Statuses() {
+		log.WithFields(log.Fields{
+			"channel":      status.Name,
+			"sent":         status.Sent,
+			"failed":       status.Failed,
+			"dropped":      status.Dropped,
+			"queue_length": status.QueueLength,
+			"last_error":   status.LastError,
+			"last_success": status.LastSuccess,
+			"last_failure": status.LastFailure,
+		}).Info("Alert notification channel status")
+	}
+}
+
+func logCollectorCacheStatus(collectorFactory *array.CachingFactory) {
+	status := collectorFactory.Status()
+	log.WithFields(log.Fields{
+		"collectors": status.Collectors,
+		"hits":       status.Hits,
+		"misses":     status.Misses,
+		"evicted":    status.Evicted,
+		"hit_rate":   status.HitRate(),
+	}).Info("Collector cache status")
+}
+
+func logSpoolStatus(spoolDatabase *spool.Database) {
+	status := spoolDatabase.Status()
+	log.WithFields(log.Fields{
+		"batches":  status.Batches,
+		"bytes":    status.Bytes,
+		"spooled":  status.Spooled,
+		"replayed": status.Replayed,
+		"evicted":  status.Evicted,
+		"dropped":  status.Dropped,
+	}).Info("Metrics spool status")
+}
+
+func servePrometheusExporter(exporter *prometheus.Exporter, port int) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+
+	log.WithField("port", port).Info("Starting Prometheus exporter")
+	err := http.ListenAndServe(fmt.Sprintf(":%d", port), mux)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"port":  port,
+		}).Error("Prometheus exporter failed to listen and serve")
+	}
+}
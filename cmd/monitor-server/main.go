@@ -20,6 +20,7 @@ import (
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/apiserver"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flashblade"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/elastic"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/hooks"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/jobs"
@@ -54,7 +55,8 @@ func main() {
 	apiServerConn := apiserver.NewConnection("http://pure1-unplugged-api-server")
 	discoveryService := apiServerConn
 	metadataConn := apiServerConn
-	deviceFactory := array.NewRESTFactory(apiServerConn)
+	// Collectors (and their array sessions) are reused across monitor checks until the array's registration changes
+	deviceFactory := array.NewCachingFactory(array.NewRESTFactory(apiServerConn, flashblade.DefaultPerformancePageSize))
 
 	databaseService, err := elastic.InitializeClient(monitorServerEnv.ElasticHost, 0, time.Second*5)
 	if err != nil {
@@ -87,6 +89,7 @@ func main() {
 		select {
 		case <-metricsCollectionTicker.C:
 			createMonitorJobs(discoveryService, deviceFactory, metadataConn, workerPool)
+			logCollectorCacheStatus(deviceFactory)
 			break
 		}
 	}
@@ -98,6 +101,10 @@ func createMonitorJobs(discoveryService resources.ArrayDiscovery, deviceFactory
 		log.WithError(err).Error("Error getting devices from discovery service, skipping this iteration")
 		return
 	}
+	// Drop the cached collectors of devices that were deleted
+	if collectorCache, ok := deviceFactory.(resources.CollectorCache); ok {
+		collectorCache.PruneCollectors(devices)
+	}
 	for _, device := range devices {
 		log.WithFields(device.GetLogFields(true)).Trace("Enqueueing monitor check job for device")
 		pool.Enqueue(&jobs.MonitorCheckJob{
@@ -107,3 +114,14 @@ func createMonitorJobs(discoveryService resources.ArrayDiscovery, deviceFactory
 		}, time.Duration(monitorServerEnv.MonitorPeriod)*time.Second)
 	}
 }
+
+func logCollectorCacheStatus(deviceFactory *array.CachingFactory) {
+	status := deviceFactory.Status()
+	log.WithFields(log.Fields{
+		"collectors": status.Collectors,
+		"hits":       status.Hits,
+		"misses":     status.Misses,
+		"evicted":    status.Evicted,
+		"hit_rate":   status.HitRate(),
+	}).Info("Collector cache status")
+}
@@ -51,11 +51,20 @@ func postArray(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = purehttp.EnsureKeysAreFilled(mapped, "api_token", "device_type", "mgmt_endpoint", "name")
+	// Either an API token or API client credentials are needed, which PostArray checks
+	err = purehttp.EnsureKeysAreFilled(mapped, "device_type", "name")
 	if err != nil {
 		respondWithErrorCode(w, err, http.StatusBadRequest)
 		return
 	}
+	// Likewise either a single management endpoint or a list of them (checked when parsed)
+	if _, ok := mapped["mgmt_endpoints"]; !ok {
+		err = purehttp.EnsureKeysAreFilled(mapped, "mgmt_endpoint")
+		if err != nil {
+			respondWithErrorCode(w, err, http.StatusBadRequest)
+			return
+		}
+	}
 
 	result, err := connection.PostArray(mapped)
 	if err != nil {
@@ -126,6 +135,38 @@ func getArrayStatus(w http.ResponseWriter, r *http.Request) {
 	respondWithSuccess(w, results)
 }
 
+func getArrayForecasts(w http.ResponseWriter, r *http.Request) {
+	query, err := parseRequestQueryParams(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	results, err := connection.GetCapacityForecasts(query)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, results)
+}
+
+func getArrayHardware(w http.ResponseWriter, r *http.Request) {
+	query, err := parseRequestQueryParams(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	results, err := connection.GetArrayHardware(query)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, results)
+}
+
 func getArrayTags(w http.ResponseWriter, r *http.Request) {
 	query, err := parseRequestQueryParams(r)
 	if err != nil {
@@ -217,3 +258,373 @@ func deleteArrayTags(w http.ResponseWriter, r *http.Request) {
 
 	respondWithSuccess(w, res)
 }
+
+func getPods(w http.ResponseWriter, r *http.Request) {
+	query, err := parseRequestQueryParams(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	results, err := connection.GetPods(query)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, results)
+}
+
+func getAlerts(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAlertQueryParams(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	results, err := connection.GetAlerts(query)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, results)
+}
+
+func patchAlerts(w http.ResponseWriter, r *http.Request) {
+	// Changes are recorded against whoever made them, so they have to come through the ingress
+	user := strings.TrimSpace(r.Header.Get(purehttp.AuthenticatedUserHeader))
+	if len(user) == 0 {
+		respondWithErrorCode(w, fmt.Errorf("Request has no authenticated user"), http.StatusUnauthorized)
+		return
+	}
+
+	ids := splitQueryParam(r, "ids")
+	if len(ids) == 0 {
+		respondWithErrorCode(w, fmt.Errorf("Query parameter ids must be specified"), http.StatusBadRequest)
+		return
+	}
+
+	mapped, err := purehttp.ParseBodyToMap(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	res, err := connection.PatchAlerts(ids, mapped, user)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondWithSuccess(w, res)
+}
+
+func getAudit(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditQueryParams(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	results, err := connection.GetAuditEvents(query)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, results)
+}
+
+func getSnapshots(w http.ResponseWriter, r *http.Request) {
+	query, err := parseSnapshotQueryParams(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	results, err := connection.GetSnapshots(query)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, results)
+}
+
+func getStaleSnapshots(w http.ResponseWriter, r *http.Request) {
+	query, err := parseStaleSnapshotQueryParams(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	results, err := connection.GetSnapshots(query)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, results)
+}
+
+func getAlertRules(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDsQueryParam(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	results, err := connection.GetAlertRules(ids)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, results)
+}
+
+func postAlertRule(w http.ResponseWriter, r *http.Request) {
+	mapped, err := purehttp.ParseBodyToMap(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	// The other required keys are checked when the rule is validated, but a missing threshold would just be zero
+	if _, ok := mapped["threshold"]; !ok {
+		respondWithErrorCode(w, fmt.Errorf("Key threshold is not present"), http.StatusBadRequest)
+		return
+	}
+
+	result, err := connection.PostAlertRule(mapped)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondWithSuccess(w, result)
+}
+
+func patchAlertRules(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDsQueryParam(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	if len(ids) == 0 {
+		respondWithErrorCode(w, fmt.Errorf("Query parameter ids must be specified"), http.StatusBadRequest)
+		return
+	}
+
+	mapped, err := purehttp.ParseBodyToMap(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	res, err := connection.PatchAlertRules(ids, mapped)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondWithSuccess(w, res)
+}
+
+func deleteAlertRules(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDsQueryParam(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	if len(ids) == 0 {
+		respondWithErrorCode(w, fmt.Errorf("Query parameter ids must be specified"), http.StatusBadRequest)
+		return
+	}
+
+	count, err := connection.DeleteAlertRules(ids)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"deletedCount": count,
+	}
+	respondWithSuccess(w, response)
+}
+
+func getMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDsQueryParam(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	results, err := connection.GetMaintenanceWindows(ids)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, results)
+}
+
+func postMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	mapped, err := purehttp.ParseBodyToMap(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	result, err := connection.PostMaintenanceWindow(mapped)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondWithSuccess(w, result)
+}
+
+func patchMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDsQueryParam(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	if len(ids) == 0 {
+		respondWithErrorCode(w, fmt.Errorf("Query parameter ids must be specified"), http.StatusBadRequest)
+		return
+	}
+
+	mapped, err := purehttp.ParseBodyToMap(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	res, err := connection.PatchMaintenanceWindows(ids, mapped)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondWithSuccess(w, res)
+}
+
+func deleteMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDsQueryParam(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	if len(ids) == 0 {
+		respondWithErrorCode(w, fmt.Errorf("Query parameter ids must be specified"), http.StatusBadRequest)
+		return
+	}
+
+	count, err := connection.DeleteMaintenanceWindows(ids)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"deletedCount": count,
+	}
+	respondWithSuccess(w, response)
+}
+
+func getSNMPDestinations(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDsQueryParam(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	results, err := connection.GetSNMPDestinations(ids)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, results)
+}
+
+func postSNMPDestination(w http.ResponseWriter, r *http.Request) {
+	mapped, err := purehttp.ParseBodyToMap(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	result, err := connection.PostSNMPDestination(mapped)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondWithSuccess(w, result)
+}
+
+func patchSNMPDestinations(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDsQueryParam(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	if len(ids) == 0 {
+		respondWithErrorCode(w, fmt.Errorf("Query parameter ids must be specified"), http.StatusBadRequest)
+		return
+	}
+
+	mapped, err := purehttp.ParseBodyToMap(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	res, err := connection.PatchSNMPDestinations(ids, mapped)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondWithSuccess(w, res)
+}
+
+func deleteSNMPDestinations(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDsQueryParam(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	if len(ids) == 0 {
+		respondWithErrorCode(w, fmt.Errorf("Query parameter ids must be specified"), http.StatusBadRequest)
+		return
+	}
+
+	count, err := connection.DeleteSNMPDestinations(ids)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"deletedCount": count,
+	}
+	respondWithSuccess(w, response)
+}
+
+func testSNMPDestinations(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDsQueryParam(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	if len(ids) == 0 {
+		respondWithErrorCode(w, fmt.Errorf("Query parameter ids must be specified"), http.StatusBadRequest)
+		return
+	}
+
+	res, err := connection.TestSNMPDestinations(ids)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondWithSuccess(w, res)
+}
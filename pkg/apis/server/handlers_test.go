@@ -22,14 +22,22 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/memory"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	purehttp "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/http"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/http/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	clientmock "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
+
+	// Registers the device types arrays can be registered with
+	_ "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flasharray"
+	_ "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flashblade"
 )
 
 const (
@@ -66,12 +74,14 @@ func assertError(t *testing.T, response httptest.ResponseRecorder, code int) {
 // 2. All keys are strings (including the two date/times, those are parsed at a higher level)
 // 3. ID is not empty
 func assertMapContainsArrayKeys(t *testing.T, body map[string]interface{}) {
-	keys := []string{"id", "name", "status", "mgmt_endpoint", "device_type", "api_token", "model", "version", "_as_of", "_last_updated"}
-	assert.Equal(t, len(keys), len(body))
+	keys := []string{"id", "name", "status", "mgmt_endpoint", "active_endpoint", "device_type", "api_token", "model", "version", "_as_of", "_last_updated", "ca_certificate", "certificate_fingerprint"}
+	assert.Equal(t, len(keys)+1, len(body))
 	for _, key := range keys {
 		assert.Contains(t, body, key)
 		assert.IsType(t, "", body[key])
 	}
+	assert.Contains(t, body, "mgmt_endpoints")
+	assert.IsType(t, []interface{}{}, body["mgmt_endpoints"])
 
 	assert.NotEmpty(t, strings.TrimSpace(body["id"].(string)))
 }
@@ -81,7 +91,7 @@ func assertMapContainsArrayKeys(t *testing.T, body map[string]interface{}) {
 // 2. All keys are strings
 // 3. ID is not empty
 func assertMapContainsStatusKeys(t *testing.T, body map[string]interface{}) {
-	keys := []string{"id", "status", "_as_of"}
+	keys := []string{"id", "status", "active_endpoint", "_as_of"}
 	assert.Equal(t, len(keys), len(body))
 	for _, key := range keys {
 		assert.Contains(t, body, key)
@@ -177,6 +187,7 @@ func TestGetArrayQuery(t *testing.T) {
 	tokenStorage := clientmock.APITokenStorageImpl{}
 	connection.DAO = &mockDAO
 	connection.Tokens = &tokenStorage
+	connection.APIClients = memory.NewInMemoryTokenStorage()
 
 	query := resources.GenerateEmptyQuery()
 	query.Ids = []string{"000000000000000000000000"}
@@ -232,6 +243,7 @@ func TestPostArrayValidFlashArray(t *testing.T) {
 	tokenStorage := clientmock.APITokenStorageImpl{}
 	connection.DAO = &mockDAO
 	connection.Tokens = &tokenStorage
+	connection.APIClients = memory.NewInMemoryTokenStorage()
 
 	name := "test_array1"
 	mgmtEndpoint := "192.168.99.100"
@@ -258,6 +270,46 @@ func TestPostArrayValidFlashArray(t *testing.T) {
 	assert.Equal(t, apiToken, body["api_token"])
 }
 
+func TestPostArrayMgmtEndpoints(t *testing.T) {
+	mockDAO := clientmock.ArrayDatabaseImpl{}
+	tokenStorage := clientmock.APITokenStorageImpl{}
+	connection.DAO = &mockDAO
+	connection.Tokens = &tokenStorage
+	connection.APIClients = memory.NewInMemoryTokenStorage()
+
+	mockDAO.On("InsertArray", mock.Anything).Return(nil)
+	tokenStorage.On("SaveToken", mock.AnythingOfType("string"), "asdf").Return(nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/arrays", strings.NewReader(`{
+	"name": "test_array1",
+	"mgmt_endpoints": ["array01.example.com", "2001:db8::10"],
+	"device_type": "FlashArray",
+	"api_token": "asdf"
+}`))
+
+	postArray(&recorder, req)
+	body := parseBody(t, recorder)
+	assertMapContainsArrayKeys(t, body)
+	assert.Equal(t, "array01.example.com", body["mgmt_endpoint"])
+	assert.Equal(t, []interface{}{"array01.example.com", "2001:db8::10"}, body["mgmt_endpoints"])
+}
+
+func TestPostArrayMissingMgmtEndpoint(t *testing.T) {
+	mockDAO := clientmock.ArrayDatabaseImpl{}
+	connection.DAO = &mockDAO
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/arrays", strings.NewReader(`{
+	"name": "test_array1",
+	"device_type": "FlashArray",
+	"api_token": "asdf"
+}`))
+
+	postArray(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
 func TestPostArrayInvalidJSON(t *testing.T) {
 	mockDAO := clientmock.ArrayDatabaseImpl{}
 	connection.DAO = &mockDAO
@@ -295,6 +347,7 @@ func TestPostArrayInternalError(t *testing.T) {
 	tokenStorage := clientmock.APITokenStorageImpl{}
 	connection.DAO = &mockDAO
 	connection.Tokens = &tokenStorage
+	connection.APIClients = memory.NewInMemoryTokenStorage()
 
 	name := "test_array1"
 	mgmtEndpoint := "192.168.99.100"
@@ -321,6 +374,7 @@ func TestPatchArrayValidFlashArray(t *testing.T) {
 	tokenStorage := clientmock.APITokenStorageImpl{}
 	connection.DAO = &mockDAO
 	connection.Tokens = &tokenStorage
+	connection.APIClients = memory.NewInMemoryTokenStorage()
 
 	id := "000000000000000000000000"
 	originalName := "test_array1"
@@ -413,6 +467,7 @@ func TestPatchArrayInternalPatchError(t *testing.T) {
 	tokenStorage := clientmock.APITokenStorageImpl{}
 	connection.DAO = &mockDAO
 	connection.Tokens = &tokenStorage
+	connection.APIClients = memory.NewInMemoryTokenStorage()
 
 	id := "000000000000000000000000"
 	originalName := "test_array1"
@@ -447,6 +502,7 @@ func TestDeleteArray(t *testing.T) {
 	tokenStorage := clientmock.APITokenStorageImpl{}
 	connection.DAO = &mockDAO
 	connection.Tokens = &tokenStorage
+	connection.APIClients = memory.NewInMemoryTokenStorage()
 
 	query := resources.GenerateEmptyQuery()
 	query.Ids = []string{"000000000000000000000000"}
@@ -560,6 +616,78 @@ func TestGetArrayStatusInternalError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
 }
 
+func TestGetPods(t *testing.T) {
+	mockDAO := clientmock.ArrayDatabaseImpl{}
+	mockPods := clientmock.PodStatusDatabaseImpl{}
+	connection.DAO = &mockDAO
+	connection.Pods = &mockPods
+
+	query := resources.GenerateEmptyQuery()
+	query.Ids = []string{"000000000000000000000000"}
+	mockDAO.On("FindArrays", &query).Return([]*resources.Array{
+		&resources.Array{InternalID: "000000000000000000000000"},
+	}, nil)
+	mockPods.On("FindLatestPodMetrics", []string{"000000000000000000000000"}).Return([]*metrics.PodMetric{
+		{ArrayID: "000000000000000000000000", PurityID: "purity-1", PodName: "pod1", Status: metrics.PodStatusOnline},
+	}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/pods?ids=000000000000000000000000", nil)
+
+	getPods(&recorder, req)
+	body := parseBody(t, recorder)
+	response := body["response"].([]interface{})
+	assert.Len(t, response, 1)
+	pod := response[0].(map[string]interface{})
+	assert.Equal(t, "pod1", pod["name"])
+	assert.Equal(t, false, pod["stretched"])
+}
+
+func TestGetPodsBadQuery(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/pods?ids=a", nil)
+
+	getPods(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestGetArrayHardware(t *testing.T) {
+	mockDAO := clientmock.ArrayDatabaseImpl{}
+	mockHardware := clientmock.HardwareStatusDatabaseImpl{}
+	connection.DAO = &mockDAO
+	connection.Hardware = &mockHardware
+
+	query := resources.GenerateEmptyQuery()
+	query.Ids = []string{"000000000000000000000000"}
+	mockDAO.On("FindArrays", &query).Return([]*resources.Array{
+		&resources.Array{InternalID: "000000000000000000000000"},
+	}, nil)
+	mockHardware.On("FindLatestHardwareMetrics", []string{"000000000000000000000000"}).Return([]*metrics.HardwareMetric{
+		{ArrayID: "000000000000000000000000", ComponentName: "CT0", ComponentType: metrics.HardwareComponentController, Status: "not ready"},
+	}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/arrays/hardware?ids=000000000000000000000000", nil)
+
+	getArrayHardware(&recorder, req)
+	body := parseBody(t, recorder)
+	response := body["response"].([]interface{})
+	assert.Len(t, response, 1)
+	array := response[0].(map[string]interface{})
+	assert.Equal(t, false, array["healthy"])
+	components := array["components"].([]interface{})
+	assert.Len(t, components, 1)
+	assert.Equal(t, "CT0", components[0].(map[string]interface{})["name"])
+}
+
+func TestGetArrayHardwareBadQuery(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/arrays/hardware?ids=a", nil)
+
+	getArrayHardware(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
 func TestGetArrayTags(t *testing.T) {
 	mockDAO := clientmock.ArrayDatabaseImpl{}
 	connection.DAO = &mockDAO
@@ -981,3 +1109,440 @@ func TestDeleteArrayTagsPatchError(t *testing.T) {
 	deleteArrayTags(&recorder, req)
 	assertError(t, recorder, http.StatusInternalServerError)
 }
+
+func TestGetAlertRules(t *testing.T) {
+	mockRules := clientmock.AlertRuleDatabaseImpl{}
+	connection.AlertRules = &mockRules
+
+	mockRules.On("FindAlertRules", []string{}).Return([]*resources.AlertRule{}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/alert-rules", nil)
+
+	getAlertRules(&recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, emptyBulkResponse, recorder.Body.String())
+}
+
+func TestGetAlertRulesBadQuery(t *testing.T) {
+	mockRules := clientmock.AlertRuleDatabaseImpl{}
+	connection.AlertRules = &mockRules
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/alert-rules?ids=a", nil)
+
+	getAlertRules(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestPostAlertRule(t *testing.T) {
+	mockRules := clientmock.AlertRuleDatabaseImpl{}
+	connection.AlertRules = &mockRules
+
+	mockRules.On("InsertAlertRule", mock.Anything).Return(nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/alert-rules", strings.NewReader(`{
+	"name": "Slow writes",
+	"resource": "volume",
+	"metric": "WriteLatency",
+	"operator": ">",
+	"threshold": 2000,
+	"volume_names": ["vol1"]
+}`))
+
+	postAlertRule(&recorder, req)
+	body := parseBody(t, recorder)
+	assert.Equal(t, "Slow writes", body["name"])
+	assert.Equal(t, "WriteLatency", body["metric"])
+	assert.Equal(t, float64(2000), body["threshold"])
+	assert.Equal(t, "0s", body["duration"])
+	assert.NotEmpty(t, body["id"])
+}
+
+func TestPostAlertRuleMissingThreshold(t *testing.T) {
+	mockRules := clientmock.AlertRuleDatabaseImpl{}
+	connection.AlertRules = &mockRules
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/alert-rules", strings.NewReader(`{
+	"name": "Slow writes",
+	"resource": "volume",
+	"metric": "WriteLatency",
+	"operator": ">"
+}`))
+
+	postAlertRule(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestPostAlertRuleInvalid(t *testing.T) {
+	mockRules := clientmock.AlertRuleDatabaseImpl{}
+	connection.AlertRules = &mockRules
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/alert-rules", strings.NewReader(`{
+	"name": "Slow writes",
+	"resource": "volume",
+	"metric": "PercentFull",
+	"operator": ">",
+	"threshold": 0.85
+}`))
+
+	postAlertRule(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestPatchAlertRulesMissingIDs(t *testing.T) {
+	mockRules := clientmock.AlertRuleDatabaseImpl{}
+	connection.AlertRules = &mockRules
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("PATCH", "/api-server/alert-rules", strings.NewReader(`{"enabled": false}`))
+
+	patchAlertRules(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestDeleteAlertRules(t *testing.T) {
+	mockRules := clientmock.AlertRuleDatabaseImpl{}
+	connection.AlertRules = &mockRules
+
+	mockRules.On("DeleteAlertRules", []string{"000000000000000000000000"}).Return([]string{"000000000000000000000000"}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("DELETE", "/api-server/alert-rules?ids=000000000000000000000000", nil)
+
+	deleteAlertRules(&recorder, req)
+	body := parseBody(t, recorder)
+	assert.Equal(t, float64(1), body["deletedCount"])
+}
+
+func TestDeleteAlertRulesMissingIDs(t *testing.T) {
+	mockRules := clientmock.AlertRuleDatabaseImpl{}
+	connection.AlertRules = &mockRules
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("DELETE", "/api-server/alert-rules", nil)
+
+	deleteAlertRules(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestGetSNMPDestinations(t *testing.T) {
+	mockDestinations := clientmock.SNMPDestinationDatabaseImpl{}
+	connection.SNMPDestinations = &mockDestinations
+
+	mockDestinations.On("FindSNMPDestinations", []string{}).Return([]*resources.SNMPDestination{}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/snmp-destinations", nil)
+
+	getSNMPDestinations(&recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, emptyBulkResponse, recorder.Body.String())
+}
+
+func TestPostSNMPDestination(t *testing.T) {
+	mockDestinations := clientmock.SNMPDestinationDatabaseImpl{}
+	connection.SNMPDestinations = &mockDestinations
+	mockCredentials := clientmock.APITokenStorageImpl{}
+	connection.SNMPCredentials = &mockCredentials
+
+	mockDestinations.On("InsertSNMPDestination", mock.Anything).Return(nil)
+	mockCredentials.On("SaveToken", mock.Anything, `{"community":"public"}`).Return(nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/snmp-destinations", strings.NewReader(`{
+	"name": "NOC",
+	"address": "nms.example.com",
+	"community": "public"
+}`))
+
+	postSNMPDestination(&recorder, req)
+	body := parseBody(t, recorder)
+	assert.Equal(t, "NOC", body["name"])
+	assert.Equal(t, "nms.example.com:162", body["address"])
+	assert.Equal(t, "v2c", body["version"])
+	assert.NotEmpty(t, body["id"])
+}
+
+func TestPostSNMPDestinationInvalid(t *testing.T) {
+	mockDestinations := clientmock.SNMPDestinationDatabaseImpl{}
+	connection.SNMPDestinations = &mockDestinations
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/snmp-destinations", strings.NewReader(`{
+	"name": "NOC",
+	"address": "nms.example.com",
+	"version": "v1",
+	"community": "public"
+}`))
+
+	postSNMPDestination(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestPatchSNMPDestinationsMissingIDs(t *testing.T) {
+	mockDestinations := clientmock.SNMPDestinationDatabaseImpl{}
+	connection.SNMPDestinations = &mockDestinations
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("PATCH", "/api-server/snmp-destinations", strings.NewReader(`{"enabled": false}`))
+
+	patchSNMPDestinations(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestDeleteSNMPDestinations(t *testing.T) {
+	mockDestinations := clientmock.SNMPDestinationDatabaseImpl{}
+	connection.SNMPDestinations = &mockDestinations
+	mockCredentials := clientmock.APITokenStorageImpl{}
+	connection.SNMPCredentials = &mockCredentials
+
+	mockDestinations.On("DeleteSNMPDestinations", []string{"000000000000000000000000"}).Return([]string{"000000000000000000000000"}, nil)
+	mockCredentials.On("DeleteToken", "000000000000000000000000").Return(nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("DELETE", "/api-server/snmp-destinations?ids=000000000000000000000000", nil)
+
+	deleteSNMPDestinations(&recorder, req)
+	body := parseBody(t, recorder)
+	assert.Equal(t, float64(1), body["deletedCount"])
+}
+
+func TestTestSNMPDestinations(t *testing.T) {
+	mockDestinations := clientmock.SNMPDestinationDatabaseImpl{}
+	connection.SNMPDestinations = &mockDestinations
+	mockCredentials := clientmock.APITokenStorageImpl{}
+	connection.SNMPCredentials = &mockCredentials
+	mockTraps := clientmock.SNMPTestTrapSenderImpl{}
+	connection.SNMPTestTraps = &mockTraps
+
+	destination := &resources.SNMPDestination{InternalID: "000000000000000000000000", Name: "NOC", Address: "10.0.0.1:162", Version: "v2c"}
+	mockDestinations.On("FindSNMPDestinations", []string{"000000000000000000000000"}).Return([]*resources.SNMPDestination{destination}, nil)
+	mockCredentials.On("HasToken", "000000000000000000000000").Return(true, nil)
+	mockCredentials.On("GetToken", "000000000000000000000000").Return(`{"community":"public"}`, nil)
+	mockTraps.On("SendTestTrap", destination).Return(nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/snmp-destinations/test?ids=000000000000000000000000", nil)
+
+	testSNMPDestinations(&recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"sent":true`)
+}
+
+func TestTestSNMPDestinationsMissingIDs(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/snmp-destinations/test", nil)
+
+	testSNMPDestinations(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestGetAlerts(t *testing.T) {
+	mockAlerts := clientmock.AlertDatabaseImpl{}
+	connection.Alerts = &mockAlerts
+
+	matchesQuery := mock.MatchedBy(func(query *resources.AlertQuery) bool {
+		return len(query.IDs) == 0 && query.ArrayIDs[0] == "array-1" && query.States[0] == "open" &&
+			query.Acknowledged != nil && !*query.Acknowledged && query.Snoozed == nil && query.Limit == 20
+	})
+	mockAlerts.On("FindAlerts", matchesQuery).Return([]*metrics.Alert{&metrics.Alert{ArrayID: "array-1", AlertID: 1, State: "open"}}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/alerts?array_ids=array-1&states=open&acknowledged=false&limit=20", nil)
+
+	getAlerts(&recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"id":"array-1-alert-1"`)
+}
+
+func TestGetAlertsBadQuery(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/alerts?acknowledged=maybe", nil)
+
+	getAlerts(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestGetAudit(t *testing.T) {
+	mockAudit := clientmock.AuditEventDatabaseImpl{}
+	connection.Audit = &mockAudit
+
+	matchesQuery := mock.MatchedBy(func(query *resources.AuditQuery) bool {
+		return query.ArrayIDs[0] == "array-1" && query.Users[0] == "pureuser" && len(query.Commands) == 0 &&
+			query.StartTime == 1546300800 && query.EndTime == 0 && query.Limit == 50
+	})
+	mockAudit.On("FindAuditEvents", matchesQuery).Return([]*metrics.AuditEvent{&metrics.AuditEvent{ArrayID: "array-1", Kind: metrics.AuditEventCommand, EventID: "12", User: "pureuser"}}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/audit?array_ids=array-1&users=pureuser&start_time=2019-01-01T00:00:00Z&limit=50", nil)
+
+	getAudit(&recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"id":"array-1-command-12"`)
+}
+
+func TestGetAuditBadQuery(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/audit?start_time=yesterday", nil)
+
+	getAudit(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestGetSnapshots(t *testing.T) {
+	mockSnapshots := clientmock.SnapshotInventoryDatabaseImpl{}
+	connection.Snapshots = &mockSnapshots
+
+	matchesQuery := mock.MatchedBy(func(query *resources.SnapshotQuery) bool {
+		return query.ArrayIDs[0] == "array-1" && query.Sources[0] == "vol1" && query.StaleBefore == 0 && query.Limit == 10
+	})
+	mockSnapshots.On("FindSnapshots", matchesQuery).Return([]*metrics.Snapshot{&metrics.Snapshot{ArrayID: "array-1", Name: "vol1.manual", Source: "vol1"}}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/snapshots?array_ids=array-1&sources=vol1&limit=10", nil)
+
+	getSnapshots(&recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"id":"array-1-vol1.manual"`)
+}
+
+func TestGetStaleSnapshots(t *testing.T) {
+	mockSnapshots := clientmock.SnapshotInventoryDatabaseImpl{}
+	connection.Snapshots = &mockSnapshots
+
+	expectedCutoff := time.Now().Add(-7 * 24 * time.Hour).Unix()
+	matchesQuery := mock.MatchedBy(func(query *resources.SnapshotQuery) bool {
+		return query.StaleBefore >= expectedCutoff && query.StaleBefore <= expectedCutoff+5
+	})
+	mockSnapshots.On("FindSnapshots", matchesQuery).Return([]*metrics.Snapshot{&metrics.Snapshot{ArrayID: "array-1", Name: "vol1.manual", Source: "vol1"}}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/snapshots/stale?max_age_days=7", nil)
+
+	getStaleSnapshots(&recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"id":"array-1-vol1.manual"`)
+}
+
+func TestGetStaleSnapshotsBadQuery(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/snapshots/stale?max_age_days=0", nil)
+
+	getStaleSnapshots(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestPatchAlerts(t *testing.T) {
+	mockAlerts := clientmock.AlertDatabaseImpl{}
+	connection.Alerts = &mockAlerts
+
+	alerts := []*metrics.Alert{&metrics.Alert{ArrayID: "array-1", AlertID: 1, State: "open"}}
+	mockAlerts.On("PatchAlertLifecycles", []string{"array-1-alert-1"}).Return(alerts, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("PATCH", "/api-server/alerts?ids=array-1-alert-1", strings.NewReader(`{"assigned_to": "someone@example.com"}`))
+	req.Header.Set(purehttp.AuthenticatedUserHeader, "admin@example.com")
+
+	patchAlerts(&recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"assigned_to":"someone@example.com"`)
+	assert.Contains(t, recorder.Body.String(), `"lifecycle_updated_by":"admin@example.com"`)
+}
+
+func TestPatchAlertsNoUser(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("PATCH", "/api-server/alerts?ids=array-1-alert-1", strings.NewReader(`{"acknowledged": true}`))
+
+	patchAlerts(&recorder, req)
+	assertError(t, recorder, http.StatusUnauthorized)
+}
+
+func TestPatchAlertsMissingIDs(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("PATCH", "/api-server/alerts", strings.NewReader(`{"acknowledged": true}`))
+	req.Header.Set(purehttp.AuthenticatedUserHeader, "admin@example.com")
+
+	patchAlerts(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestGetMaintenanceWindows(t *testing.T) {
+	mockWindows := clientmock.MaintenanceWindowDatabaseImpl{}
+	connection.MaintenanceWindows = &mockWindows
+
+	mockWindows.On("FindMaintenanceWindows", []string{}).Return([]*resources.MaintenanceWindow{}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("GET", "/api-server/maintenance-windows", nil)
+
+	getMaintenanceWindows(&recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, emptyBulkResponse, recorder.Body.String())
+}
+
+func TestPostMaintenanceWindow(t *testing.T) {
+	mockWindows := clientmock.MaintenanceWindowDatabaseImpl{}
+	connection.MaintenanceWindows = &mockWindows
+
+	mockWindows.On("InsertMaintenanceWindow", mock.Anything).Return(nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/maintenance-windows", strings.NewReader(`{
+	"array_ids": ["000000000000000000000000"],
+	"start": "2019-01-02T15:00:00Z",
+	"end": "2019-01-02T17:00:00Z",
+	"reason": "Purity upgrade"
+}`))
+
+	postMaintenanceWindow(&recorder, req)
+	body := parseBody(t, recorder)
+	assert.Equal(t, "Purity upgrade", body["reason"])
+	assert.Equal(t, "2019-01-02T15:00:00Z", body["start"])
+	assert.Equal(t, "2019-01-02T17:00:00Z", body["end"])
+}
+
+func TestPostMaintenanceWindowMissingReason(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("POST", "/api-server/maintenance-windows", strings.NewReader(`{
+	"array_ids": ["000000000000000000000000"],
+	"start": "2019-01-02T15:00:00Z",
+	"end": "2019-01-02T17:00:00Z"
+}`))
+
+	postMaintenanceWindow(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestPatchMaintenanceWindowsMissingIDs(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("PATCH", "/api-server/maintenance-windows", strings.NewReader(`{"reason": "Host migration"}`))
+
+	patchMaintenanceWindows(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
+
+func TestDeleteMaintenanceWindows(t *testing.T) {
+	mockWindows := clientmock.MaintenanceWindowDatabaseImpl{}
+	connection.MaintenanceWindows = &mockWindows
+
+	mockWindows.On("DeleteMaintenanceWindows", []string{"000000000000000000000000"}).Return([]string{"000000000000000000000000"}, nil)
+
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("DELETE", "/api-server/maintenance-windows?ids=000000000000000000000000", nil)
+
+	deleteMaintenanceWindows(&recorder, req)
+	body := parseBody(t, recorder)
+	assert.Equal(t, float64(1), body["deletedCount"])
+}
+
+func TestDeleteMaintenanceWindowsMissingIDs(t *testing.T) {
+	recorder := httptest.ResponseRecorder{Body: &bytes.Buffer{}}
+	req := httptest.NewRequest("DELETE", "/api-server/maintenance-windows", nil)
+
+	deleteMaintenanceWindows(&recorder, req)
+	assertError(t, recorder, http.StatusBadRequest)
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/elastic"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/kube"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/notifier"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/db"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/logger"
 	"github.com/gorilla/mux"
@@ -28,6 +29,7 @@ import (
 
 const (
 	elasticRetryTime = 5 * time.Second
+	snmpTestTimeout  = 10 * time.Second
 )
 
 // NewRouter will create a router configured with all methods, names, paths, queries, and handlers which are defined in routes.go
@@ -38,13 +40,30 @@ func NewRouter() *mux.Router {
 		return nil
 	}
 	tokenStore := kube.NewKubeSecretAPITokenStore(secretAccess)
+	snmpCredentialStore := kube.NewKubeSecretSNMPCredentialStore(secretAccess)
+	apiClientCredentialStore := kube.NewKubeSecretAPIClientCredentialStore(secretAccess)
 
 	elasticMeta, err := elastic.InitializeClient(APIServerEnv.ElasticHost, 0, elasticRetryTime)
 	if err != nil {
 		log.WithError(err).Fatal("Error getting Elastic connection")
 		return nil
 	}
-	connection = db.MetadataConnection{DAO: elasticMeta, Tokens: tokenStore}
+	connection = db.MetadataConnection{
+		DAO:                elasticMeta,
+		Tokens:             tokenStore,
+		APIClients:         apiClientCredentialStore,
+		Forecasts:          elasticMeta,
+		Pods:               elasticMeta,
+		Hardware:           elasticMeta,
+		AlertRules:         elasticMeta,
+		Alerts:             elasticMeta,
+		Audit:              elasticMeta,
+		Snapshots:          elasticMeta,
+		MaintenanceWindows: elasticMeta,
+		SNMPDestinations:   elasticMeta,
+		SNMPCredentials:    snmpCredentialStore,
+		SNMPTestTraps:      notifier.NewSNMPChannel(nil, snmpTestTimeout),
+	}
 
 	// Essentially means that "/path" redirects to "/path/"
 	// "your application will always see the path as specified in the route"
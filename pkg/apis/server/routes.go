@@ -75,6 +75,36 @@ var routes = []Route{
 		getArrayStatus,
 	},
 	// no body
+	Route{ // Returns capacity forecasts of registered storage arrays, and of the arrays sharing each tag
+		"ArrayForecastGet",
+		"GET",
+		"/arrays/forecast",
+		[]string{
+			"filter", "{filter}",
+			"ids", "{ids}",
+			"names", "{names}",
+			"limit", "{limit}",
+			"offset", "{offset}",
+			"sort", "{sort}",
+		},
+		getArrayForecasts,
+	},
+	// no body
+	Route{ // Returns the latest health of the hardware components of registered storage arrays
+		"ArrayHardwareGet",
+		"GET",
+		"/arrays/hardware",
+		[]string{
+			"filter", "{filter}",
+			"ids", "{ids}",
+			"names", "{names}",
+			"limit", "{limit}",
+			"offset", "{offset}",
+			"sort", "{sort}",
+		},
+		getArrayHardware,
+	},
+	// no body
 	Route{ // Returns a map of tags of registered storage arrays
 		"ArrayTagsGet",
 		"GET",
@@ -112,4 +142,214 @@ var routes = []Route{
 		},
 		deleteArrayTags,
 	},
+	// no body
+	Route{ // Returns the ActiveCluster topology of the pods on registered storage arrays
+		"PodGet",
+		"GET",
+		"/pods",
+		[]string{
+			"filter", "{filter}",
+			"ids", "{ids}",
+			"names", "{names}",
+			"limit", "{limit}",
+			"offset", "{offset}",
+			"sort", "{sort}",
+		},
+		getPods,
+	},
+	// no body
+	Route{ // Returns a list of alerts, along with how they're being handled
+		"AlertGet",
+		"GET",
+		"/alerts",
+		[]string{
+			"ids", "{ids}",
+			"array_ids", "{array_ids}",
+			"states", "{states}",
+			"assigned_to", "{assigned_to}",
+			"acknowledged", "{acknowledged}",
+			"snoozed", "{snoozed}",
+			"limit", "{limit}",
+			"offset", "{offset}",
+		},
+		getAlerts,
+	},
+	// with body
+	Route{ // Acknowledges, assigns, annotates or snoozes alerts
+		"AlertPatch",
+		"PATCH",
+		"/alerts",
+		[]string{
+			"ids", "{ids}",
+		},
+		patchAlerts,
+	},
+	// no body
+	Route{ // Returns the audit trail and login sessions collected from registered storage arrays, most recent first
+		"AuditGet",
+		"GET",
+		"/audit",
+		[]string{
+			"array_ids", "{array_ids}",
+			"kinds", "{kinds}",
+			"users", "{users}",
+			"commands", "{commands}",
+			"start_time", "{start_time}",
+			"end_time", "{end_time}",
+			"limit", "{limit}",
+			"offset", "{offset}",
+		},
+		getAudit,
+	},
+	// no body
+	Route{ // Returns the snapshot inventory of registered storage arrays, oldest first
+		"SnapshotGet",
+		"GET",
+		"/snapshots",
+		[]string{
+			"array_ids", "{array_ids}",
+			"sources", "{sources}",
+			"limit", "{limit}",
+			"offset", "{offset}",
+		},
+		getSnapshots,
+	},
+	// no body
+	Route{ // Returns the snapshots that are older than the given age, or weren't taken by any protection group or policy
+		"SnapshotStaleGet",
+		"GET",
+		"/snapshots/stale",
+		[]string{
+			"array_ids", "{array_ids}",
+			"sources", "{sources}",
+			"max_age_days", "{max_age_days}",
+			"limit", "{limit}",
+			"offset", "{offset}",
+		},
+		getStaleSnapshots,
+	},
+	// no body
+	Route{ // Returns a list of alert rules
+		"AlertRuleGet",
+		"GET",
+		"/alert-rules",
+		[]string{
+			"ids", "{ids}",
+		},
+		getAlertRules,
+	},
+	// with body
+	Route{ // Creates a new alert rule
+		"AlertRulePost",
+		"POST",
+		"/alert-rules",
+		[]string{},
+		postAlertRule,
+	},
+	// with body
+	Route{ // Updates alert rules
+		"AlertRulePatch",
+		"PATCH",
+		"/alert-rules",
+		[]string{
+			"ids", "{ids}",
+		},
+		patchAlertRules,
+	},
+	// no body
+	Route{ // Deletes alert rules
+		"AlertRuleDelete",
+		"DELETE",
+		"/alert-rules",
+		[]string{
+			"ids", "{ids}",
+		},
+		deleteAlertRules,
+	},
+	// no body
+	Route{ // Returns a list of maintenance windows
+		"MaintenanceWindowGet",
+		"GET",
+		"/maintenance-windows",
+		[]string{
+			"ids", "{ids}",
+		},
+		getMaintenanceWindows,
+	},
+	// with body
+	Route{ // Creates a new maintenance window
+		"MaintenanceWindowPost",
+		"POST",
+		"/maintenance-windows",
+		[]string{},
+		postMaintenanceWindow,
+	},
+	// with body
+	Route{ // Updates maintenance windows
+		"MaintenanceWindowPatch",
+		"PATCH",
+		"/maintenance-windows",
+		[]string{
+			"ids", "{ids}",
+		},
+		patchMaintenanceWindows,
+	},
+	// no body
+	Route{ // Deletes maintenance windows
+		"MaintenanceWindowDelete",
+		"DELETE",
+		"/maintenance-windows",
+		[]string{
+			"ids", "{ids}",
+		},
+		deleteMaintenanceWindows,
+	},
+	// no body
+	Route{ // Returns a list of SNMP trap destinations
+		"SNMPDestinationGet",
+		"GET",
+		"/snmp-destinations",
+		[]string{
+			"ids", "{ids}",
+		},
+		getSNMPDestinations,
+	},
+	// with body
+	Route{ // Creates a new SNMP trap destination
+		"SNMPDestinationPost",
+		"POST",
+		"/snmp-destinations",
+		[]string{},
+		postSNMPDestination,
+	},
+	// with body
+	Route{ // Updates SNMP trap destinations
+		"SNMPDestinationPatch",
+		"PATCH",
+		"/snmp-destinations",
+		[]string{
+			"ids", "{ids}",
+		},
+		patchSNMPDestinations,
+	},
+	// no body
+	Route{ // Deletes SNMP trap destinations
+		"SNMPDestinationDelete",
+		"DELETE",
+		"/snmp-destinations",
+		[]string{
+			"ids", "{ids}",
+		},
+		deleteSNMPDestinations,
+	},
+	// no body
+	Route{ // Sends a test trap to SNMP trap destinations
+		"SNMPDestinationTest",
+		"POST",
+		"/snmp-destinations/test",
+		[]string{
+			"ids", "{ids}",
+		},
+		testSNMPDestinations,
+	},
 }
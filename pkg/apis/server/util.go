@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/http/errors"
@@ -100,6 +101,15 @@ func parseRequestQueryParams(r *http.Request) (resources.ArrayQuery, error) {
 		}
 	}
 
+	var filter resources.FilterExpression
+	if len(strings.TrimSpace(r.FormValue("filter"))) > 0 {
+		parsedFilter, err := resources.ParseFilter(r.FormValue("filter"))
+		if err != nil {
+			return resources.ArrayQuery{}, errors.MakeBadRequestHTTPErr(err)
+		}
+		filter = parsedFilter
+	}
+
 	return resources.ArrayQuery{
 		Ids:            ids,
 		Names:          names,
@@ -109,9 +119,198 @@ func parseRequestQueryParams(r *http.Request) (resources.ArrayQuery, error) {
 		Offset:         offset,
 		Sort:           sortField,
 		SortDescending: sortDesc,
+		Filter:         filter,
 	}, nil
 }
 
+// parseIDsQueryParam parses the comma separated IDs of the "ids" query parameter, making sure they're all valid IDs
+func parseIDsQueryParam(r *http.Request) ([]string, error) {
+	if len(r.FormValue("ids")) == 0 {
+		return []string{}, nil
+	}
+
+	ids := strings.Split(r.FormValue("ids"), ",")
+	for _, id := range ids {
+		err := resources.ValidateHexObjectID(id)
+		if err != nil {
+			return nil, errors.MakeBadRequestHTTPErr(err)
+		}
+	}
+	return ids, nil
+}
+
+// parseAlertQueryParams parses the query parameters of an alert request
+func parseAlertQueryParams(r *http.Request) (*resources.AlertQuery, error) {
+	query := &resources.AlertQuery{
+		IDs:        splitQueryParam(r, "ids"), // Alert IDs aren't object IDs, so there's nothing to validate
+		ArrayIDs:   splitQueryParam(r, "array_ids"),
+		States:     splitQueryParam(r, "states"),
+		AssignedTo: strings.TrimSpace(r.FormValue("assigned_to")),
+	}
+
+	for _, param := range []struct {
+		name   string
+		target **bool
+	}{
+		{"acknowledged", &query.Acknowledged},
+		{"snoozed", &query.Snoozed},
+	} {
+		if len(r.FormValue(param.name)) == 0 {
+			continue
+		}
+		parsed, err := strconv.ParseBool(r.FormValue(param.name))
+		if err != nil {
+			return nil, errors.MakeBadRequestHTTPErr(fmt.Errorf("Query parameter %s must be true or false", param.name))
+		}
+		*param.target = &parsed
+	}
+
+	if len(r.FormValue("limit")) > 0 {
+		parsedLimit, err := strconv.ParseInt(r.FormValue("limit"), 10, 64)
+		if err != nil {
+			return nil, errors.MakeBadRequestHTTPErr(err)
+		}
+		if parsedLimit < 1 {
+			return nil, errors.MakeBadRequestHTTPErr(fmt.Errorf("Limit must be >= 1"))
+		}
+		query.Limit = int(parsedLimit)
+	}
+
+	if len(r.FormValue("offset")) > 0 {
+		parsedOffset, err := strconv.ParseInt(r.FormValue("offset"), 10, 64)
+		if err != nil {
+			return nil, errors.MakeBadRequestHTTPErr(err)
+		}
+		if parsedOffset < 0 {
+			return nil, errors.MakeBadRequestHTTPErr(fmt.Errorf("Offset must be >= 0"))
+		}
+		query.Offset = int(parsedOffset)
+	}
+
+	return query, nil
+}
+
+// parseAuditQueryParams parses the filters for audit events; start_time and end_time are RFC3339 timestamps
+func parseAuditQueryParams(r *http.Request) (*resources.AuditQuery, error) {
+	query := &resources.AuditQuery{
+		ArrayIDs: splitQueryParam(r, "array_ids"),
+		Kinds:    splitQueryParam(r, "kinds"),
+		Users:    splitQueryParam(r, "users"),
+		Commands: splitQueryParam(r, "commands"),
+	}
+
+	for _, param := range []struct {
+		name   string
+		target *int64
+	}{
+		{"start_time", &query.StartTime},
+		{"end_time", &query.EndTime},
+	} {
+		if len(r.FormValue(param.name)) == 0 {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(r.FormValue(param.name)))
+		if err != nil {
+			return nil, errors.MakeBadRequestHTTPErr(fmt.Errorf("Query parameter %s must be an RFC3339 timestamp", param.name))
+		}
+		*param.target = parsed.Unix()
+	}
+	if query.StartTime > 0 && query.EndTime > 0 && query.StartTime > query.EndTime {
+		return nil, errors.MakeBadRequestHTTPErr(fmt.Errorf("Start time must not be after end time"))
+	}
+
+	if len(r.FormValue("limit")) > 0 {
+		parsedLimit, err := strconv.ParseInt(r.FormValue("limit"), 10, 64)
+		if err != nil {
+			return nil, errors.MakeBadRequestHTTPErr(err)
+		}
+		if parsedLimit < 1 {
+			return nil, errors.MakeBadRequestHTTPErr(fmt.Errorf("Limit must be >= 1"))
+		}
+		query.Limit = int(parsedLimit)
+	}
+
+	if len(r.FormValue("offset")) > 0 {
+		parsedOffset, err := strconv.ParseInt(r.FormValue("offset"), 10, 64)
+		if err != nil {
+			return nil, errors.MakeBadRequestHTTPErr(err)
+		}
+		if parsedOffset < 0 {
+			return nil, errors.MakeBadRequestHTTPErr(fmt.Errorf("Offset must be >= 0"))
+		}
+		query.Offset = int(parsedOffset)
+	}
+
+	return query, nil
+}
+
+// defaultStaleSnapshotAge is how many days old a snapshot has to be to be reported as stale, if the request
+// doesn't say
+const defaultStaleSnapshotAge = 30
+
+// parseSnapshotQueryParams parses the filters for the snapshot inventory
+func parseSnapshotQueryParams(r *http.Request) (*resources.SnapshotQuery, error) {
+	query := &resources.SnapshotQuery{
+		ArrayIDs: splitQueryParam(r, "array_ids"),
+		Sources:  splitQueryParam(r, "sources"),
+	}
+
+	if len(r.FormValue("limit")) > 0 {
+		parsedLimit, err := strconv.ParseInt(r.FormValue("limit"), 10, 64)
+		if err != nil {
+			return nil, errors.MakeBadRequestHTTPErr(err)
+		}
+		if parsedLimit < 1 {
+			return nil, errors.MakeBadRequestHTTPErr(fmt.Errorf("Limit must be >= 1"))
+		}
+		query.Limit = int(parsedLimit)
+	}
+
+	if len(r.FormValue("offset")) > 0 {
+		parsedOffset, err := strconv.ParseInt(r.FormValue("offset"), 10, 64)
+		if err != nil {
+			return nil, errors.MakeBadRequestHTTPErr(err)
+		}
+		if parsedOffset < 0 {
+			return nil, errors.MakeBadRequestHTTPErr(fmt.Errorf("Offset must be >= 0"))
+		}
+		query.Offset = int(parsedOffset)
+	}
+
+	return query, nil
+}
+
+// parseStaleSnapshotQueryParams parses the filters for the stale snapshot report, which lists the snapshots
+// older than max_age_days or not taken by any protection group or policy
+func parseStaleSnapshotQueryParams(r *http.Request) (*resources.SnapshotQuery, error) {
+	query, err := parseSnapshotQueryParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAge := int64(defaultStaleSnapshotAge)
+	if len(r.FormValue("max_age_days")) > 0 {
+		maxAge, err = strconv.ParseInt(r.FormValue("max_age_days"), 10, 64)
+		if err != nil {
+			return nil, errors.MakeBadRequestHTTPErr(err)
+		}
+		if maxAge < 1 {
+			return nil, errors.MakeBadRequestHTTPErr(fmt.Errorf("Max age must be >= 1"))
+		}
+	}
+	query.StaleBefore = time.Now().Add(-time.Duration(maxAge) * 24 * time.Hour).Unix()
+
+	return query, nil
+}
+
+// splitQueryParam splits the comma separated values of the given query parameter
+func splitQueryParam(r *http.Request, name string) []string {
+	if len(r.FormValue(name)) == 0 {
+		return []string{}
+	}
+	return strings.Split(r.FormValue(name), ",")
+}
+
 func respond(w http.ResponseWriter, code int, body interface{}) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(code)
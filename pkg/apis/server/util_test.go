@@ -182,3 +182,19 @@ func TestParseRequestQuerySortNegative(t *testing.T) {
 	assert.Equal(t, "asdf", query.Sort)
 	assert.True(t, query.SortDescending)
 }
+
+func TestParseRequestQueryFilter(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api-server?filter=model%3D%27FA-X70%27%20and%20tags.site%3D%27dc1%27", nil)
+
+	query, err := parseRequestQueryParams(req)
+	assert.NoError(t, err)
+	assert.NotNil(t, query.Filter)
+	assert.False(t, query.IsEmpty())
+}
+
+func TestParseRequestQueryInvalidFilter(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api-server?filter=model%3D", nil)
+
+	_, err := parseRequestQueryParams(req)
+	errors.AssertIsHTTPErrOfCode(t, err, http.StatusBadRequest)
+}
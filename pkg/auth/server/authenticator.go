@@ -49,40 +49,49 @@ func ParseJWT(token string, hmacSecret string) (*jwt.Token, error) {
 // Authorized tests an http request for the Authorization: Bearer [token] header
 // and returns whether or not it's allowed to execute that request
 func Authorized(a *dexApp, req *http.Request) (bool, error) {
+	_, err := AuthorizedUser(a, req)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AuthorizedUser is like Authorized, but returns who the request is from if it's allowed: the email
+// the API token was generated for, or the user ID if the token has no email
+func AuthorizedUser(a *dexApp, req *http.Request) (string, error) {
 	ctx := oidc.ClientContext(req.Context(), a.client)
 
 	apiToken, err := purehttp.GetRequestAuthorizationToken(req)
 	if err != nil {
 		log.Debug("Couldn't find API token from request")
-		return false, err
+		return "", err
 	}
 
-	// Don't need to do anything with the token, just need to make sure it's a valid token and hasn't expired
-	_, err = ParseJWT(apiToken, tokenstore.HmacSecret)
+	parsedToken, err := ParseJWT(apiToken, tokenstore.HmacSecret)
 	if err != nil {
 		log.WithError(err).Debug("Couldn't parse JWT")
-		return false, err
+		return "", err
 	}
 
 	userID, err := a.apiTokenStore.GetUserForToken(apiToken)
 	if err != nil {
 		log.Debug("API token not found")
-		return false, err
+		return "", err
 	}
 
 	if !a.apiTokenStore.HasUserCredentials(userID) {
 		log.WithField("user", userID).Debug("API token valid and mapped to user, but user isn't associated with a valid token")
-		return false, fmt.Errorf("API token valid and mapped to user, but user isn't associated with a valid token")
+		return "", fmt.Errorf("API token valid and mapped to user, but user isn't associated with a valid token")
 	}
 
 	userToken, err := a.apiTokenStore.GetTokenForUser(userID)
 	if err != nil {
 		log.WithError(err).Debug("Failed to get OAuth token for user")
-		return false, err
+		return "", err
 	}
 	if userToken == nil {
 		log.Debug("User token fetched, but was nil")
-		return false, fmt.Errorf("User token fetched, but was nil")
+		return "", fmt.Errorf("User token fetched, but was nil")
 	}
 
 	if time.Now().After(userToken.Expiry) {
@@ -93,20 +102,25 @@ func Authorized(a *dexApp, req *http.Request) (bool, error) {
 				"err":  err,
 				"user": userID,
 			}).Debug("Failed to refresh token for user")
-			return false, err
+			return "", err
 		}
 		// Update the stored token
 		err = a.apiTokenStore.StoreUser(userID, newToken)
 		if err != nil {
 			log.WithError(err).Debug("Failed to store new token for user")
-			return false, err
+			return "", err
 		}
 		log.WithField("user", userID).Debug("Refreshed token for user successfully")
 	}
 
 	// If we got this far, we know the token is valid, since the tokens are only generated
 	// internally and expiry is set by Dex and never changed by us.
-	return true, nil
+	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok {
+		if email, ok := claims["email"].(string); ok && len(email) > 0 {
+			return email, nil
+		}
+	}
+	return userID, nil
 }
 
 func getScopes(a *dexApp, r *http.Request) []string {
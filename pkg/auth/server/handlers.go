@@ -28,9 +28,11 @@ import (
 )
 
 func (a *dexApp) handleVerify(w http.ResponseWriter, r *http.Request) {
-	authorized, _ := Authorized(a, r)
+	user, err := AuthorizedUser(a, r)
 
-	if authorized {
+	if err == nil {
+		// Lets the services behind the ingress know who the request is from
+		w.Header().Set(purehttp.AuthenticatedUserHeader, user)
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Authorized"))
 	} else {
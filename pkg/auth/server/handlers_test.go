@@ -67,6 +67,39 @@ func TestHandleVerifySuccess(t *testing.T) {
 	a.handleVerify(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "userid", w.Header().Get("X-Pure1-Unplugged-User"))
+}
+
+func TestHandleVerifySuccessWithEmail(t *testing.T) {
+	tokenstore.HmacSecret = "This is totally secret"
+
+	tokenStore := &tokenstoremock.TokenStore{}
+
+	authToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenstore.APITokenClaims{
+		Email: "someone@example.com",
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	}).SignedString([]byte(tokenstore.HmacSecret))
+	assert.NoError(t, err)
+
+	tokenStore.On("GetUserForToken", authToken).Return("userid", nil)
+	tokenStore.On("HasUserCredentials", "userid").Return(true)
+	tokenStore.On("GetTokenForUser", "userid").Return(&oauth2.Token{Expiry: time.Now().Add(time.Hour)}, nil)
+
+	a := dexApp{
+		apiTokenStore: tokenStore,
+	}
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", authToken))
+
+	a.handleVerify(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "someone@example.com", w.Header().Get("X-Pure1-Unplugged-User"))
 }
 
 func TestHandleLogin(t *testing.T) {
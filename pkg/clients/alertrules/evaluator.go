@@ -0,0 +1,373 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertrules
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.Database = (*Evaluator)(nil)
+
+const (
+	openState   = "open"
+	closedState = "closed"
+
+	// Breaches that haven't been evaluated for this long are closed, since the array or volume is gone
+	// (or no longer matches the rule after it was edited)
+	staleBreachPeriod = time.Hour
+)
+
+// NewEvaluator creates an evaluator in front of the given database. Rules are fetched from the given
+// discovery service, and any rule alerts left open by a previous run are looked up in the given alert database.
+func NewEvaluator(database metrics.Database, alerts metrics.RuleAlertDatabase, discovery resources.AlertRuleDiscovery) *Evaluator {
+	return &Evaluator{
+		database:  database,
+		alerts:    alerts,
+		discovery: discovery,
+		rules:     []*resources.AlertRule{},
+		breaches:  map[string]*breach{},
+	}
+}
+
+// AddArrayMetrics writes the given metrics to the wrapped database, then evaluates the array rules against them
+func (e *Evaluator) AddArrayMetrics(arrayMetrics []*metrics.ArrayMetric) error {
+	err := e.database.AddArrayMetrics(arrayMetrics)
+
+	samples := []*sample{}
+	for _, metric := range arrayMetrics {
+		if metric == nil {
+			continue
+		}
+		samples = append(samples, &sample{
+			resource:         resources.AlertRuleArrayResource,
+			arrayID:          metric.ArrayID,
+			arrayName:        metric.ArrayName,
+			arrayDisplayName: metric.DisplayName,
+			arrayTags:        metric.Tags,
+			createdAt:        metric.CreatedAt,
+			inMaintenance:    metric.InMaintenance,
+			getValue:         metric.GetFieldValue,
+		})
+	}
+	e.evaluate(samples)
+
+	return err
+}
+
+// AddVolumeMetrics writes the given metrics to the wrapped database, then evaluates the volume rules against them
+func (e *Evaluator) AddVolumeMetrics(volumeMetrics []*metrics.VolumeMetric) error {
+	err := e.database.AddVolumeMetrics(volumeMetrics)
+
+	samples := []*sample{}
+	for _, metric := range volumeMetrics {
+		if metric == nil {
+			continue
+		}
+		samples = append(samples, &sample{
+			resource:         resources.AlertRuleVolumeResource,
+			arrayID:          metric.ArrayID,
+			arrayName:        metric.ArrayName,
+			arrayDisplayName: metric.ArrayDisplayName,
+			arrayTags:        metric.ArrayTags,
+			volumeType:       metric.Type,
+			volumeName:       metric.VolumeName,
+			createdAt:        metric.CreatedAt,
+			inMaintenance:    metric.InMaintenance,
+			getValue:         metric.GetFieldValue,
+		})
+	}
+	e.evaluate(samples)
+
+	return err
+}
+
+// UpdateAlerts passes through to the wrapped database
+func (e *Evaluator) UpdateAlerts(alerts []*metrics.Alert) error {
+	return e.database.UpdateAlerts(alerts)
+}
+
+// CleanArrayMetrics passes through to the wrapped database
+func (e *Evaluator) CleanArrayMetrics(maxAgeInDays int) error {
+	return e.database.CleanArrayMetrics(maxAgeInDays)
+}
+
+// CleanVolumeMetrics passes through to the wrapped database
+func (e *Evaluator) CleanVolumeMetrics(maxAgeInDays int) error {
+	return e.database.CleanVolumeMetrics(maxAgeInDays)
+}
+
+// CleanAlerts passes through to the wrapped database
+func (e *Evaluator) CleanAlerts(maxAgeInDays int) error {
+	return e.database.CleanAlerts(maxAgeInDays)
+}
+
+// CleanErrorLogs passes through to the wrapped database
+func (e *Evaluator) CleanErrorLogs(maxAgeInDays int) error {
+	return e.database.CleanErrorLogs(maxAgeInDays)
+}
+
+// CleanTimerLogs passes through to the wrapped database
+func (e *Evaluator) CleanTimerLogs(maxAgeInDays int) error {
+	return e.database.CleanTimerLogs(maxAgeInDays)
+}
+
+// Run refreshes the rules right away, then every period for as long as the process runs
+func (e *Evaluator) Run(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		err := e.RefreshRules()
+		if err != nil {
+			log.WithError(err).Warn("Failed to refresh alert rules, will try again later")
+		}
+		<-ticker.C
+	}
+}
+
+// RefreshRules fetches the current rules. The alerts of rules that have been deleted or disabled since the
+// last refresh are closed, as are the alerts of arrays and volumes that haven't been seen in a while.
+func (e *Evaluator) RefreshRules() error {
+	rules, err := e.discovery.GetAlertRules()
+	if err != nil {
+		return err
+	}
+
+	e.lock.Lock()
+	loaded := e.loaded
+	e.lock.Unlock()
+
+	var openAlerts []*metrics.Alert
+	if !loaded {
+		openAlerts, err = e.alerts.FindOpenRuleAlerts()
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	e.lock.Lock()
+	if !e.loaded {
+		for _, alert := range openAlerts {
+			ruleID, _ := alert.Variables["rule_id"].(string)
+			volumeType, _ := alert.Variables["volume_type"].(string)
+			volumeName, _ := alert.Variables["volume_name"].(string)
+			e.breaches[getBreachKey(ruleID, alert.ArrayID, volumeType, volumeName)] = &breach{
+				ruleID:   ruleID,
+				since:    alert.Created,
+				lastSeen: now,
+				alert:    alert,
+			}
+		}
+		e.loaded = true
+		log.WithField("open_alerts", len(openAlerts)).Info("Loaded open alert rule alerts")
+	}
+
+	enabledRules := []*resources.AlertRule{}
+	enabledRuleIDs := map[string]bool{}
+	for _, rule := range rules {
+		if rule.Enabled {
+			enabledRules = append(enabledRules, rule)
+			enabledRuleIDs[rule.InternalID] = true
+		}
+	}
+	e.rules = enabledRules
+
+	closedAlerts := []*metrics.Alert{}
+	for key, existing := range e.breaches {
+		if enabledRuleIDs[existing.ruleID] && now.Sub(existing.lastSeen) < staleBreachPeriod {
+			continue
+		}
+		delete(e.breaches, key)
+		if existing.alert != nil {
+			closedAlerts = append(closedAlerts, closeAlert(existing.alert, now.Unix()))
+		}
+	}
+	e.lock.Unlock()
+
+	log.WithField("rules", len(enabledRules)).Trace("Refreshed alert rules")
+	e.writeAlerts(closedAlerts)
+	return nil
+}
+
+// evaluate is a helper function that evaluates every rule against the given samples (in order), and writes
+// the alerts that were opened, updated or closed
+func (e *Evaluator) evaluate(samples []*sample) {
+	if len(samples) == 0 {
+		return
+	}
+
+	e.lock.Lock()
+	if !e.loaded {
+		e.lock.Unlock()
+		return
+	}
+	now := time.Now()
+	alerts := []*metrics.Alert{}
+	for _, s := range samples {
+		alerts = append(alerts, e.evaluateSample(s, now)...)
+	}
+	e.lock.Unlock()
+
+	e.writeAlerts(dedupeAlerts(alerts))
+}
+
+// evaluateSample is a helper function that evaluates every rule against the given sample, returning the alerts
+// that changed. Must be called with the lock held.
+func (e *Evaluator) evaluateSample(s *sample, now time.Time) []*metrics.Alert {
+	alerts := []*metrics.Alert{}
+	for _, rule := range e.rules {
+		if rule.Resource != s.resource || !rule.Matches(s.arrayID, s.arrayTags, s.volumeName) {
+			continue
+		}
+		value, ok := s.getValue(rule.Metric)
+		if !ok {
+			// Not collected this time around
+			continue
+		}
+
+		key := getBreachKey(rule.InternalID, s.arrayID, s.volumeType, s.volumeName)
+		existing, breached := e.breaches[key]
+		if s.inMaintenance {
+			// Leave alerts that were already open as they are, but don't count time in maintenance towards
+			// the duration of a breach that hasn't raised an alert yet
+			if breached && existing.alert != nil {
+				existing.lastSeen = now
+			} else if breached {
+				delete(e.breaches, key)
+			}
+			continue
+		}
+		if !rule.IsBreachedBy(value) {
+			if breached {
+				delete(e.breaches, key)
+				if existing.alert != nil {
+					alerts = append(alerts, closeAlert(existing.alert, s.createdAt))
+				}
+			}
+			continue
+		}
+
+		if !breached {
+			existing = &breach{ruleID: rule.InternalID, since: s.createdAt}
+			e.breaches[key] = existing
+		}
+		existing.lastSeen = now
+		if existing.alert == nil && s.createdAt-existing.since < rule.DurationSeconds {
+			// Not breached for long enough yet
+			continue
+		}
+		existing.alert = newRuleAlert(rule, s, existing.since, value)
+		alerts = append(alerts, existing.alert)
+	}
+	return alerts
+}
+
+// writeAlerts is a helper function to write the given alerts to the wrapped database
+func (e *Evaluator) writeAlerts(alerts []*metrics.Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	err := e.database.UpdateAlerts(alerts)
+	if err != nil {
+		log.WithError(err).WithField("alerts", len(alerts)).Error("Error writing alert rule alerts")
+	}
+}
+
+// newRuleAlert is a helper function to create the (open) alert for a rule breached by the given sample
+func newRuleAlert(rule *resources.AlertRule, s *sample, since int64, value float64) *metrics.Alert {
+	subject := fmt.Sprintf("array %s", s.arrayDisplayName)
+	if len(s.volumeName) > 0 {
+		subject = fmt.Sprintf("volume %s on array %s", s.volumeName, s.arrayDisplayName)
+	}
+
+	alert := &metrics.Alert{
+		AlertID:          getRuleAlertID(rule.InternalID, s.arrayID, s.volumeType, s.volumeName, since),
+		ArrayDisplayName: s.arrayDisplayName,
+		ArrayID:          s.arrayID,
+		ArrayName:        s.arrayName,
+		Component:        s.volumeName,
+		Created:          since,
+		Description:      fmt.Sprintf("%s of %s is %v, which has been %s %v since %s", rule.Metric, subject, value, rule.Operator, rule.Threshold, time.Unix(since, 0).UTC().Format(time.RFC3339)),
+		Flagged:          true,
+		Severity:         rule.Severity,
+		Source:           metrics.AlertSourceRule,
+		State:            openState,
+		Summary:          fmt.Sprintf("%s: %s %s %v", rule.Name, rule.Metric, rule.Operator, rule.Threshold),
+		Updated:          s.createdAt,
+		Variables: map[string]interface{}{
+			"rule_id":     rule.InternalID,
+			"rule_name":   rule.Name,
+			"metric":      rule.Metric,
+			"operator":    rule.Operator,
+			"threshold":   rule.Threshold,
+			"value":       value,
+			"volume_type": s.volumeType,
+			"volume_name": s.volumeName,
+		},
+	}
+	alert.PopulateSeverityIndex()
+	return alert
+}
+
+// closeAlert is a helper function to create a closed copy of the given alert
+func closeAlert(alert *metrics.Alert, closedAt int64) *metrics.Alert {
+	closed := *alert
+	closed.State = closedState
+	closed.Flagged = false
+	closed.Updated = closedAt
+	return &closed
+}
+
+// getRuleAlertID is a helper function to derive the ID of the alert for a single breach, so the same breach
+// always maps to the same alert (even across restarts). Alert IDs are mapped as integers in Elastic, so this
+// has to fit in 31 bits.
+func getRuleAlertID(ruleID string, arrayID string, volumeType string, volumeName string, since int64) uint64 {
+	hash := fnv.New32a()
+	hash.Write([]byte(fmt.Sprintf("%s/%s/%s/%s/%d", ruleID, arrayID, volumeType, volumeName, since)))
+	id := uint64(hash.Sum32() & 0x7fffffff)
+	if id == 0 {
+		// Zero is treated as a missing ID
+		id = 1
+	}
+	return id
+}
+
+func getBreachKey(ruleID string, arrayID string, volumeType string, volumeName string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", ruleID, arrayID, volumeType, volumeName)
+}
+
+// dedupeAlerts is a helper function to keep only the last version of each alert (FlashBlade volume metrics
+// come in several samples at once), keeping the order the alerts were first seen in
+func dedupeAlerts(alerts []*metrics.Alert) []*metrics.Alert {
+	indices := map[string]int{}
+	deduped := []*metrics.Alert{}
+	for _, alert := range alerts {
+		key := fmt.Sprintf("%s/%d", alert.ArrayID, alert.AlertID)
+		if index, ok := indices[key]; ok {
+			deduped[index] = alert
+			continue
+		}
+		indices[key] = len(deduped)
+		deduped = append(deduped, alert)
+	}
+	return deduped
+}
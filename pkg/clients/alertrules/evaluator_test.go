@@ -0,0 +1,307 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertrules
+
+import (
+	"testing"
+	"time"
+
+	clientmock "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	percentFullRule = &resources.AlertRule{
+		InternalID:      "rule1",
+		Name:            "Nearly full",
+		Enabled:         true,
+		Resource:        resources.AlertRuleArrayResource,
+		Metric:          "PercentFull",
+		Operator:        ">",
+		Threshold:       0.85,
+		DurationSeconds: 900,
+		Severity:        "critical",
+		ArrayTags:       map[string]string{"env": "prod"},
+	}
+	writeLatencyRule = &resources.AlertRule{
+		InternalID: "rule2",
+		Name:       "Slow writes",
+		Enabled:    true,
+		Resource:   resources.AlertRuleVolumeResource,
+		Metric:     "WriteLatency",
+		Operator:   ">",
+		Threshold:  2000,
+		Severity:   "warning",
+	}
+)
+
+// createEvaluator creates an evaluator with the given rules (and no alerts left open by a previous run) in front of
+// a database that records the alerts written to it
+func createEvaluator(t *testing.T, rules []*resources.AlertRule, openAlerts []*metrics.Alert) (*Evaluator, *[]*metrics.Alert) {
+	written := []*metrics.Alert{}
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("AddArrayMetrics", mock.Anything).Return(nil)
+	database.On("AddVolumeMetrics", mock.Anything).Return(nil)
+	database.On("UpdateAlerts", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		written = append(written, args.Get(0).([]*metrics.Alert)...)
+	})
+
+	discovery := &clientmock.AlertRuleDiscoveryImpl{}
+	discovery.On("GetAlertRules").Return(rules, nil)
+	alerts := &clientmock.RuleAlertDatabaseImpl{}
+	alerts.On("FindOpenRuleAlerts").Return(openAlerts, nil)
+
+	evaluator := NewEvaluator(database, alerts, discovery)
+	assert.NoError(t, evaluator.RefreshRules())
+	return evaluator, &written
+}
+
+func arrayMetric(percentFull float64, createdAt int64, tags map[string]string) *metrics.ArrayMetric {
+	return &metrics.ArrayMetric{
+		ArrayCapacityMetric: &metrics.ArrayCapacityMetric{PercentFull: percentFull},
+		ArrayID:             "array1",
+		ArrayName:           "array-1",
+		DisplayName:         "Array 1",
+		CreatedAt:           createdAt,
+		Tags:                tags,
+	}
+}
+
+func TestArrayRuleOpensAfterDurationAndCloses(t *testing.T) {
+	evaluator, written := createEvaluator(t, []*resources.AlertRule{percentFullRule}, []*metrics.Alert{})
+	prod := map[string]string{"env": "prod"}
+
+	// Breached, but not for long enough yet
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 1000, prod)}))
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 1600, prod)}))
+	assert.Empty(t, *written)
+
+	// Breached for the full 15 minutes
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.91, 1900, prod)}))
+	assert.Len(t, *written, 1)
+	opened := (*written)[0]
+	assert.Equal(t, metrics.AlertSourceRule, opened.Source)
+	assert.Equal(t, "open", opened.State)
+	assert.Equal(t, int64(1000), opened.Created)
+	assert.Equal(t, int64(1900), opened.Updated)
+	assert.Equal(t, "array1", opened.ArrayID)
+	assert.Equal(t, "critical", opened.Severity)
+	assert.Equal(t, byte(3), opened.SeverityIndex)
+	assert.Equal(t, 0.91, opened.Variables["value"])
+	assert.NotZero(t, opened.AlertID)
+
+	// Still breached: the same alert is updated
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.92, 1930, prod)}))
+	assert.Len(t, *written, 2)
+	assert.Equal(t, opened.AlertID, (*written)[1].AlertID)
+	assert.Equal(t, "open", (*written)[1].State)
+	assert.Equal(t, 0.92, (*written)[1].Variables["value"])
+
+	// No longer breached: closed
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.8, 1960, prod)}))
+	assert.Len(t, *written, 3)
+	assert.Equal(t, opened.AlertID, (*written)[2].AlertID)
+	assert.Equal(t, "closed", (*written)[2].State)
+	assert.False(t, (*written)[2].Flagged)
+	assert.Equal(t, int64(1960), (*written)[2].Updated)
+
+	// Breached again: a new alert
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 2000, prod)}))
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 2900, prod)}))
+	assert.Len(t, *written, 4)
+	assert.NotEqual(t, opened.AlertID, (*written)[3].AlertID)
+	assert.Equal(t, int64(2000), (*written)[3].Created)
+}
+
+func TestArrayRuleBreachResetsBeforeDuration(t *testing.T) {
+	evaluator, written := createEvaluator(t, []*resources.AlertRule{percentFullRule}, []*metrics.Alert{})
+	prod := map[string]string{"env": "prod"}
+
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 1000, prod)}))
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.8, 1500, prod)}))
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 1900, prod)}))
+	assert.Empty(t, *written)
+}
+
+func TestArrayRuleSuppressedInMaintenance(t *testing.T) {
+	evaluator, written := createEvaluator(t, []*resources.AlertRule{percentFullRule}, []*metrics.Alert{})
+	prod := map[string]string{"env": "prod"}
+
+	// Breaches during maintenance don't count towards the duration, and cancel one that was pending
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 1000, prod)}))
+	inMaintenance := arrayMetric(0.95, 1900, prod)
+	inMaintenance.InMaintenance = true
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{inMaintenance}))
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 2000, prod)}))
+	assert.Empty(t, *written)
+
+	// Alerts that are already open are left alone until maintenance is over
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 2900, prod)}))
+	assert.Len(t, *written, 1)
+	inMaintenance = arrayMetric(0.5, 3000, prod)
+	inMaintenance.InMaintenance = true
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{inMaintenance}))
+	assert.Len(t, *written, 1)
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.5, 3100, prod)}))
+	assert.Len(t, *written, 2)
+	assert.Equal(t, "closed", (*written)[1].State)
+}
+
+func TestArrayRuleOnlyMatchesTaggedArrays(t *testing.T) {
+	evaluator, written := createEvaluator(t, []*resources.AlertRule{percentFullRule}, []*metrics.Alert{})
+	dev := map[string]string{"env": "dev"}
+
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 1000, dev)}))
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 2000, dev)}))
+	assert.Empty(t, *written)
+}
+
+func TestVolumeRuleOpensImmediately(t *testing.T) {
+	evaluator, written := createEvaluator(t, []*resources.AlertRule{percentFullRule, writeLatencyRule}, []*metrics.Alert{})
+
+	err := evaluator.AddVolumeMetrics([]*metrics.VolumeMetric{
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 2500}, ArrayID: "array1", VolumeName: "vol1", CreatedAt: 1000},
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 3000}, ArrayID: "array1", VolumeName: "vol1", CreatedAt: 1030},
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 100}, ArrayID: "array1", VolumeName: "vol2", CreatedAt: 1000},
+		// Performance wasn't collected for this one
+		{VolumeCapacityMetric: &metrics.VolumeCapacityMetric{UsedSpace: 100}, ArrayID: "array1", VolumeName: "vol3", CreatedAt: 1000},
+	})
+	assert.NoError(t, err)
+
+	// Both vol1 samples belong to the same alert, so only the latest version is written
+	assert.Len(t, *written, 1)
+	assert.Equal(t, "vol1", (*written)[0].Component)
+	assert.Equal(t, "vol1", (*written)[0].Variables["volume_name"])
+	assert.Equal(t, float64(3000), (*written)[0].Variables["value"])
+	assert.Equal(t, int64(1000), (*written)[0].Created)
+}
+
+func TestVolumeRuleSeparatesVolumeTypes(t *testing.T) {
+	evaluator, written := createEvaluator(t, []*resources.AlertRule{writeLatencyRule}, []*metrics.Alert{})
+
+	// A file system and a bucket with the same name on the same FlashBlade are different breaches
+	err := evaluator.AddVolumeMetrics([]*metrics.VolumeMetric{
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 2500}, ArrayID: "array1", Type: metrics.VolumeMetricTypeFileSystem, VolumeName: "data", CreatedAt: 1000},
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 3000}, ArrayID: "array1", Type: metrics.VolumeMetricTypeBucket, VolumeName: "data", CreatedAt: 1000},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, *written, 2) {
+		assert.NotEqual(t, (*written)[0].AlertID, (*written)[1].AlertID)
+		assert.Equal(t, metrics.VolumeMetricTypeBucket, (*written)[1].Variables["volume_type"])
+	}
+
+	// The bucket recovering only closes its own alert
+	*written = []*metrics.Alert{}
+	err = evaluator.AddVolumeMetrics([]*metrics.VolumeMetric{
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 2500}, ArrayID: "array1", Type: metrics.VolumeMetricTypeFileSystem, VolumeName: "data", CreatedAt: 1030},
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 100}, ArrayID: "array1", Type: metrics.VolumeMetricTypeBucket, VolumeName: "data", CreatedAt: 1030},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, *written, 2) {
+		assert.Equal(t, "open", (*written)[0].State)
+		assert.Equal(t, "closed", (*written)[1].State)
+	}
+}
+
+func TestOpenAlertsFromPreviousRunAreClosed(t *testing.T) {
+	previous := newRuleAlert(writeLatencyRule, &sample{arrayID: "array1", volumeName: "vol1"}, 500, 2500)
+	evaluator, written := createEvaluator(t, []*resources.AlertRule{writeLatencyRule}, []*metrics.Alert{previous})
+
+	err := evaluator.AddVolumeMetrics([]*metrics.VolumeMetric{
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 100}, ArrayID: "array1", VolumeName: "vol1", CreatedAt: 1000},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, *written, 1)
+	assert.Equal(t, previous.AlertID, (*written)[0].AlertID)
+	assert.Equal(t, "closed", (*written)[0].State)
+}
+
+func TestDisabledRuleAlertsAreClosed(t *testing.T) {
+	written := []*metrics.Alert{}
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("AddVolumeMetrics", mock.Anything).Return(nil)
+	database.On("UpdateAlerts", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		written = append(written, args.Get(0).([]*metrics.Alert)...)
+	})
+	alerts := &clientmock.RuleAlertDatabaseImpl{}
+	alerts.On("FindOpenRuleAlerts").Return([]*metrics.Alert{}, nil)
+
+	disabled := *writeLatencyRule
+	disabled.Enabled = false
+
+	discovery := &clientmock.AlertRuleDiscoveryImpl{}
+	discovery.On("GetAlertRules").Return([]*resources.AlertRule{writeLatencyRule}, nil).Once()
+	discovery.On("GetAlertRules").Return([]*resources.AlertRule{&disabled}, nil)
+
+	evaluator := NewEvaluator(database, alerts, discovery)
+	assert.NoError(t, evaluator.RefreshRules())
+
+	err := evaluator.AddVolumeMetrics([]*metrics.VolumeMetric{
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 2500}, ArrayID: "array1", VolumeName: "vol1", CreatedAt: 1000},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, written, 1)
+
+	assert.NoError(t, evaluator.RefreshRules())
+	assert.Len(t, written, 2)
+	assert.Equal(t, written[0].AlertID, written[1].AlertID)
+	assert.Equal(t, "closed", written[1].State)
+
+	// Disabled rules aren't evaluated
+	err = evaluator.AddVolumeMetrics([]*metrics.VolumeMetric{
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 2500}, ArrayID: "array1", VolumeName: "vol1", CreatedAt: 1030},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, written, 2)
+}
+
+func TestStaleBreachesAreClosed(t *testing.T) {
+	evaluator, written := createEvaluator(t, []*resources.AlertRule{writeLatencyRule}, []*metrics.Alert{})
+
+	err := evaluator.AddVolumeMetrics([]*metrics.VolumeMetric{
+		{VolumePerformanceMetric: &metrics.VolumePerformanceMetric{WriteLatency: 2500}, ArrayID: "array1", VolumeName: "vol1", CreatedAt: 1000},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, *written, 1)
+
+	// The volume hasn't been seen in a while (it was deleted)
+	for _, existing := range evaluator.breaches {
+		existing.lastSeen = time.Now().Add(-2 * staleBreachPeriod)
+	}
+	assert.NoError(t, evaluator.RefreshRules())
+	assert.Len(t, *written, 2)
+	assert.Equal(t, "closed", (*written)[1].State)
+	assert.Empty(t, evaluator.breaches)
+}
+
+func TestNothingEvaluatedBeforeRulesAreLoaded(t *testing.T) {
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("AddArrayMetrics", mock.Anything).Return(nil)
+
+	evaluator := NewEvaluator(database, &clientmock.RuleAlertDatabaseImpl{}, &clientmock.AlertRuleDiscoveryImpl{})
+	assert.NoError(t, evaluator.AddArrayMetrics([]*metrics.ArrayMetric{arrayMetric(0.9, 1000, nil)}))
+	database.AssertNotCalled(t, "UpdateAlerts", mock.Anything)
+}
+
+func TestGetRuleAlertIDFitsInInteger(t *testing.T) {
+	for since := int64(0); since < 1000; since++ {
+		id := getRuleAlertID("rule1", "array1", "Volume", "vol1", since)
+		assert.True(t, id > 0 && id <= 0x7fffffff)
+	}
+	assert.Equal(t, getRuleAlertID("rule1", "array1", "", "", 1000), getRuleAlertID("rule1", "array1", "", "", 1000))
+}
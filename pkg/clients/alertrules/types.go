@@ -0,0 +1,63 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertrules
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+// Evaluator is a metrics.Database that wraps another one, passing every write through to it and evaluating
+// the user-defined alert rules against the array and volume metrics written. Breached rules are written to
+// the wrapped database as alerts with the rule source, which are opened once the threshold has been breached
+// for the rule's duration, updated while it stays breached, and closed once it no longer is. Metrics that are
+// marked as in maintenance don't open, update or close any alerts.
+type Evaluator struct {
+	database  metrics.Database
+	alerts    metrics.RuleAlertDatabase
+	discovery resources.AlertRuleDiscovery
+
+	lock     sync.Mutex
+	rules    []*resources.AlertRule // Enabled rules only
+	breaches map[string]*breach     // Keyed by rule, array, volume type and volume (see getBreachKey)
+	// Nothing is evaluated until the alerts left open by a previous run have been loaded, so they
+	// aren't opened a second time
+	loaded bool
+}
+
+// breach tracks a rule that is currently breached by an array or volume
+type breach struct {
+	ruleID   string
+	since    int64          // When the threshold was first breached (Unix seconds)
+	lastSeen time.Time      // When the rule was last evaluated against this array or volume
+	alert    *metrics.Alert // The last version of the alert written, nil until breached for the rule's duration
+}
+
+// sample is a single array or volume metric that rules are evaluated against
+type sample struct {
+	resource         string
+	arrayID          string
+	arrayName        string
+	arrayDisplayName string
+	arrayTags        map[string]string
+	volumeType       string // Volumes of different types (like file systems and buckets) can share names
+	volumeName       string
+	createdAt        int64
+	inMaintenance    bool
+	getValue         func(field string) (float64, bool)
+}
@@ -27,6 +27,10 @@ import (
 // Type guards: ensure this implements the interfaces
 var _ resources.ArrayDiscovery = (*APIServer)(nil)
 var _ resources.ArrayMetadata = (*APIServer)(nil)
+var _ resources.ArrayTagDiscovery = (*APIServer)(nil)
+var _ resources.AlertRuleDiscovery = (*APIServer)(nil)
+var _ resources.MaintenanceWindowDiscovery = (*APIServer)(nil)
+var _ resources.SNMPDestinationDiscovery = (*APIServer)(nil)
 
 // NewConnection establishes a connection with the API server
 // at the given backend URL. Note that this returns a pointer to an API
@@ -78,19 +82,102 @@ func (a *APIServer) GetTags(arrayID string) (map[string]string, error) {
 			if array.ID != arrayID {
 				continue
 			}
+			return parseTags(array.Tags), nil
+		}
+
+		return nil, fmt.Errorf("Response did not contain an array with the matching ID")
+	}
+	return nil, fmt.Errorf("Error casting response to bulkTagsResponse")
+}
+
+// GetAllTags fetches the tags for every device from the API server, keyed by device ID
+func (a *APIServer) GetAllTags() (map[string]map[string]string, error) {
+	log.WithField("endpoint", a.serverEndpoint).Trace("Starting API server all device tags GET")
+	uncastResponse, err := http.RestyGet(bulkTagsResponse{}, resty.R(), fmt.Sprintf("%s/arrays/tags", a.serverEndpoint))
+	if err != nil {
+		return nil, err
+	}
+	if response, ok := uncastResponse.(*bulkTagsResponse); ok {
+		allTags := map[string]map[string]string{}
+		for _, array := range response.Items {
+			allTags[array.ID] = parseTags(array.Tags)
+		}
+		return allTags, nil
+	}
+	return nil, fmt.Errorf("Error casting response to bulkTagsResponse")
+}
 
-			parsedTags := map[string]string{}
-			for _, tag := range array.Tags {
-				if tag.Key == "" {
-					continue
-				}
-				parsedTags[tag.Key] = tag.Value
+// GetAlertRules is an implementation of the AlertRuleDiscovery interface
+func (a *APIServer) GetAlertRules() ([]*resources.AlertRule, error) {
+	log.WithField("endpoint", a.serverEndpoint).Trace("Starting API server alert rule list GET")
+	uncastResponse, err := http.RestyGet(bulkAlertRuleResponse{}, resty.R(), fmt.Sprintf("%s/alert-rules", a.serverEndpoint))
+	if err != nil {
+		return nil, err
+	}
+	if response, ok := uncastResponse.(*bulkAlertRuleResponse); ok {
+		rules := []*resources.AlertRule{}
+		for _, item := range response.Items {
+			rule, err := resources.NewAlertRuleFromREST(item)
+			if err != nil {
+				return nil, err
 			}
+			rules = append(rules, rule)
+		}
+		return rules, nil
+	}
+	return nil, fmt.Errorf("Error casting response to bulkAlertRuleResponse")
+}
 
-			return parsedTags, nil
+// GetMaintenanceWindows is an implementation of the MaintenanceWindowDiscovery interface
+func (a *APIServer) GetMaintenanceWindows() ([]*resources.MaintenanceWindow, error) {
+	log.WithField("endpoint", a.serverEndpoint).Trace("Starting API server maintenance window list GET")
+	uncastResponse, err := http.RestyGet(bulkMaintenanceWindowResponse{}, resty.R(), fmt.Sprintf("%s/maintenance-windows", a.serverEndpoint))
+	if err != nil {
+		return nil, err
+	}
+	if response, ok := uncastResponse.(*bulkMaintenanceWindowResponse); ok {
+		windows := []*resources.MaintenanceWindow{}
+		for _, item := range response.Items {
+			window, err := resources.NewMaintenanceWindowFromREST(item)
+			if err != nil {
+				return nil, err
+			}
+			windows = append(windows, window)
 		}
+		return windows, nil
+	}
+	return nil, fmt.Errorf("Error casting response to bulkMaintenanceWindowResponse")
+}
 
-		return nil, fmt.Errorf("Response did not contain an array with the matching ID")
+// GetSNMPDestinations is an implementation of the SNMPDestinationDiscovery interface
+func (a *APIServer) GetSNMPDestinations() ([]*resources.SNMPDestination, error) {
+	log.WithField("endpoint", a.serverEndpoint).Trace("Starting API server SNMP destination list GET")
+	uncastResponse, err := http.RestyGet(bulkSNMPDestinationResponse{}, resty.R(), fmt.Sprintf("%s/snmp-destinations", a.serverEndpoint))
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("Error casting response to bulkTagsResponse")
+	if response, ok := uncastResponse.(*bulkSNMPDestinationResponse); ok {
+		destinations := []*resources.SNMPDestination{}
+		for _, item := range response.Items {
+			destination, err := resources.NewSNMPDestinationFromREST(item)
+			if err != nil {
+				return nil, err
+			}
+			destinations = append(destinations, destination)
+		}
+		return destinations, nil
+	}
+	return nil, fmt.Errorf("Error casting response to bulkSNMPDestinationResponse")
+}
+
+// parseTags is a helper function to convert the tags of a device from the API server into a map, skipping any without a key
+func parseTags(tags []*tag) map[string]string {
+	parsedTags := map[string]string{}
+	for _, tag := range tags {
+		if tag.Key == "" {
+			continue
+		}
+		parsedTags[tag.Key] = tag.Value
+	}
+	return parsedTags
 }
@@ -26,6 +26,22 @@ type bulkDeviceResponse struct {
 	Items []*resources.ArrayRegistrationInfo `json:"response"`
 }
 
+// Alert rules are parsed the same way as in requests to the API server, since they're
+// stored in a different format than they're served in
+type bulkAlertRuleResponse struct {
+	Items []map[string]interface{} `json:"response"`
+}
+
+// Maintenance windows are parsed the same way as alert rules
+type bulkMaintenanceWindowResponse struct {
+	Items []map[string]interface{} `json:"response"`
+}
+
+// SNMP destinations are parsed the same way, since their credentials are stored separately
+type bulkSNMPDestinationResponse struct {
+	Items []map[string]interface{} `json:"response"`
+}
+
 type bulkTagsResponse struct {
 	Items []*tagsResponse `json:"response"`
 }
@@ -0,0 +1,142 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package array
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: check that this struct implements the interfaces
+var _ resources.CollectorFactory = (*CachingFactory)(nil)
+var _ resources.CollectorCache = (*CachingFactory)(nil)
+
+// NewCachingFactory creates a CachingFactory around the given factory
+func NewCachingFactory(factory resources.CollectorFactory) *CachingFactory {
+	return &CachingFactory{
+		factory:    factory,
+		collectors: map[string]*cachedCollector{},
+	}
+}
+
+// InitializeCollector returns the cached collector for the array, creating it with the wrapped factory if
+// there is none yet or the array's registration info (such as its API token) changed since it was created.
+// Failures aren't cached, so the next call tries again.
+func (f *CachingFactory) InitializeCollector(arrayInfo *resources.ArrayRegistrationInfo) (resources.ArrayCollector, error) {
+	hash := registrationHash(arrayInfo)
+
+	f.lock.Lock()
+	cached, ok := f.collectors[arrayInfo.ID]
+	if !ok || cached.hash != hash {
+		if ok {
+			f.evicted++
+			log.WithFields(arrayInfo.GetLogFields(false)).Info("Array registration changed, replacing cached collector")
+		}
+		cached = &cachedCollector{hash: hash}
+		f.collectors[arrayInfo.ID] = cached
+	}
+	f.lock.Unlock()
+
+	cached.lock.Lock()
+	defer cached.lock.Unlock()
+	if cached.collector != nil {
+		f.countRequest(true)
+		return cached.collector, nil
+	}
+	f.countRequest(false)
+
+	collector, err := f.factory.InitializeCollector(arrayInfo)
+	if err != nil {
+		return nil, err
+	}
+	cached.collector = collector
+	return collector, nil
+}
+
+// EvictCollector drops the cached collector of the given array (if any), so the next job creates a new one
+func (f *CachingFactory) EvictCollector(arrayID string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.evict(arrayID)
+}
+
+// PruneCollectors drops the cached collectors of every array that isn't in the given list (arrays that were deleted)
+func (f *CachingFactory) PruneCollectors(arrays []*resources.ArrayRegistrationInfo) {
+	registered := map[string]bool{}
+	for _, arrayInfo := range arrays {
+		registered[arrayInfo.ID] = true
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for arrayID := range f.collectors {
+		if !registered[arrayID] {
+			f.evict(arrayID)
+		}
+	}
+}
+
+// Status returns the number of cached collectors and the cache counters
+func (f *CachingFactory) Status() CacheStatus {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return CacheStatus{
+		Collectors: len(f.collectors),
+		Hits:       f.hits,
+		Misses:     f.misses,
+		Evicted:    f.evicted,
+	}
+}
+
+// HitRate returns the fraction of requests served by a cached collector (0 if there were none)
+func (s CacheStatus) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// countRequest is a helper function that counts a cache hit or miss
+func (f *CachingFactory) countRequest(hit bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if hit {
+		f.hits++
+	} else {
+		f.misses++
+	}
+}
+
+// evict is a helper function that drops a cached collector, expecting the lock to be held
+func (f *CachingFactory) evict(arrayID string) {
+	if _, ok := f.collectors[arrayID]; !ok {
+		return
+	}
+	delete(f.collectors, arrayID)
+	f.evicted++
+	log.WithField("array_id", arrayID).Trace("Evicted cached collector")
+}
+
+// registrationHash is a helper function that hashes everything a collector is created from, so a new API token,
+// new credentials or new endpoints lead to a new collector (the token itself is never kept in the cache)
+func registrationHash(arrayInfo *resources.ArrayRegistrationInfo) string {
+	encoded, _ := json.Marshal(arrayInfo)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,159 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package array
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingCollector is a stub collector that only knows which array it was created for
+type countingCollector struct {
+	resources.ArrayCollector
+	arrayInfo resources.ArrayRegistrationInfo
+}
+
+// countingFactory is a stub factory that counts how many collectors it created
+type countingFactory struct {
+	lock    sync.Mutex
+	created int
+	err     error
+}
+
+func (f *countingFactory) InitializeCollector(arrayInfo *resources.ArrayRegistrationInfo) (resources.ArrayCollector, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.created++
+	return &countingCollector{arrayInfo: *arrayInfo}, nil
+}
+
+func TestCachingFactoryReusesCollector(t *testing.T) {
+	inner := &countingFactory{}
+	factory := NewCachingFactory(inner)
+	arrayInfo := &resources.ArrayRegistrationInfo{ID: "array1", APIToken: "token1"}
+
+	first, err := factory.InitializeCollector(arrayInfo)
+	assert.NoError(t, err)
+	second, err := factory.InitializeCollector(arrayInfo)
+	assert.NoError(t, err)
+
+	assert.True(t, first == second)
+	assert.Equal(t, 1, inner.created)
+	assert.Equal(t, CacheStatus{Collectors: 1, Hits: 1, Misses: 1}, factory.Status())
+	assert.Equal(t, 0.5, factory.Status().HitRate())
+}
+
+func TestCachingFactoryTokenChange(t *testing.T) {
+	inner := &countingFactory{}
+	factory := NewCachingFactory(inner)
+
+	first, err := factory.InitializeCollector(&resources.ArrayRegistrationInfo{ID: "array1", APIToken: "token1"})
+	assert.NoError(t, err)
+	second, err := factory.InitializeCollector(&resources.ArrayRegistrationInfo{ID: "array1", APIToken: "token2"})
+	assert.NoError(t, err)
+
+	assert.False(t, first == second)
+	assert.Equal(t, "token2", second.(*countingCollector).arrayInfo.APIToken)
+	assert.Equal(t, 2, inner.created)
+	assert.Equal(t, CacheStatus{Collectors: 1, Misses: 2, Evicted: 1}, factory.Status())
+}
+
+func TestCachingFactoryEndpointChange(t *testing.T) {
+	inner := &countingFactory{}
+	factory := NewCachingFactory(inner)
+
+	_, err := factory.InitializeCollector(&resources.ArrayRegistrationInfo{ID: "array1", MgmtEndpoint: "10.0.0.1"})
+	assert.NoError(t, err)
+	_, err = factory.InitializeCollector(&resources.ArrayRegistrationInfo{ID: "array1", MgmtEndpoint: "10.0.0.1", MgmtEndpoints: []string{"10.0.0.1", "10.0.0.2"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, inner.created)
+}
+
+func TestCachingFactoryEvictCollector(t *testing.T) {
+	inner := &countingFactory{}
+	factory := NewCachingFactory(inner)
+	arrayInfo := &resources.ArrayRegistrationInfo{ID: "array1", APIToken: "token1"}
+
+	_, err := factory.InitializeCollector(arrayInfo)
+	assert.NoError(t, err)
+	factory.EvictCollector("array1")
+	factory.EvictCollector("array2") // Not cached: nothing to evict
+	_, err = factory.InitializeCollector(arrayInfo)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, inner.created)
+	assert.Equal(t, CacheStatus{Collectors: 1, Misses: 2, Evicted: 1}, factory.Status())
+}
+
+func TestCachingFactoryPruneCollectors(t *testing.T) {
+	inner := &countingFactory{}
+	factory := NewCachingFactory(inner)
+	array1 := &resources.ArrayRegistrationInfo{ID: "array1"}
+	array2 := &resources.ArrayRegistrationInfo{ID: "array2"}
+
+	_, err := factory.InitializeCollector(array1)
+	assert.NoError(t, err)
+	_, err = factory.InitializeCollector(array2)
+	assert.NoError(t, err)
+
+	factory.PruneCollectors([]*resources.ArrayRegistrationInfo{array2})
+	assert.Equal(t, CacheStatus{Collectors: 1, Misses: 2, Evicted: 1}, factory.Status())
+
+	_, err = factory.InitializeCollector(array2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, inner.created)
+}
+
+func TestCachingFactoryDoesNotCacheErrors(t *testing.T) {
+	inner := &countingFactory{err: errors.New("unreachable")}
+	factory := NewCachingFactory(inner)
+	arrayInfo := &resources.ArrayRegistrationInfo{ID: "array1"}
+
+	_, err := factory.InitializeCollector(arrayInfo)
+	assert.Error(t, err)
+
+	inner.err = nil
+	collector, err := factory.InitializeCollector(arrayInfo)
+	assert.NoError(t, err)
+	assert.NotNil(t, collector)
+	assert.Equal(t, 1, inner.created)
+}
+
+func TestCachingFactoryConcurrentJobs(t *testing.T) {
+	inner := &countingFactory{}
+	factory := NewCachingFactory(inner)
+	arrayInfo := &resources.ArrayRegistrationInfo{ID: "array1", APIToken: "token1"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := factory.InitializeCollector(arrayInfo)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, inner.created)
+	assert.Equal(t, CacheStatus{Collectors: 1, Hits: 19, Misses: 1}, factory.Status())
+}
@@ -16,28 +16,50 @@ package array
 
 import (
 	"fmt"
+	"strings"
 
-	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flasharray"
-	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flashblade"
-	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/registry"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/util"
+
+	// The built-in device types register themselves with the registry
+	_ "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flasharray"
+	_ "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flashblade"
 )
 
 // Type guard: check that this struct implements the interface
 var _ resources.CollectorFactory = (*restFactory)(nil)
 
-// NewRESTFactory produces a RESTFactory, which produces REST client Collectors
-func NewRESTFactory(metaConnection resources.ArrayMetadata) resources.CollectorFactory {
-	return &restFactory{metaConnection: metaConnection}
+// NewRESTFactory produces a RESTFactory, which produces REST client Collectors for every device type in the
+// registry. FlashBlade Collectors request flashBladePageSize file system performance metrics per page.
+func NewRESTFactory(metaConnection resources.ArrayMetadata, flashBladePageSize int) resources.CollectorFactory {
+	return &restFactory{metaConnection: metaConnection, flashBladePageSize: flashBladePageSize}
 }
 
 func (r *restFactory) InitializeCollector(arrayInfo *resources.ArrayRegistrationInfo) (resources.ArrayCollector, error) {
-	switch arrayInfo.DeviceType {
-	case common.FlashArray:
-		return flasharray.NewCollector(arrayInfo.ID, arrayInfo.Name, arrayInfo.MgmtEndpoint, arrayInfo.APIToken, r.metaConnection)
-	case common.FlashBlade:
-		return flashblade.NewCollector(arrayInfo.ID, arrayInfo.Name, arrayInfo.MgmtEndpoint, arrayInfo.APIToken, r.metaConnection)
-	default:
-		return nil, fmt.Errorf("Unknown DeviceType")
+	device, ok := registry.Lookup(arrayInfo.DeviceType)
+	if !ok {
+		return nil, fmt.Errorf("Unknown DeviceType %s, supported device types are: %s", arrayInfo.DeviceType, strings.Join(registry.DeviceTypes(), ", "))
+	}
+
+	tlsConfig, err := util.NewArrayTLSConfig(endpointHostnames(arrayInfo.GetMgmtEndpoints()), arrayInfo.CACertificate, arrayInfo.CertificateFingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	return device.NewCollector(arrayInfo, registry.CollectorOptions{
+		MetaConnection: r.metaConnection,
+		PageSize:       r.flashBladePageSize,
+		TLSConfig:      tlsConfig,
+	})
+}
+
+// endpointHostnames is a helper function that returns the host names (or IP addresses) of the given
+// endpoints, which the array certificate can be verified for
+func endpointHostnames(endpoints []string) []string {
+	hostnames := []string{}
+	for _, endpoint := range endpoints {
+		hostnames = append(hostnames, util.EndpointHostname(endpoint))
 	}
+	return hostnames
 }
@@ -20,8 +20,10 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/util"
 	"github.com/go-resty/resty"
 	log "github.com/sirupsen/logrus"
@@ -33,13 +35,30 @@ const (
 	APIVersionEndpoint                    = "/api/api_version"
 	ArrayEndpoint                         = "/array"
 	ArrayCapacityMetricsEndpoint          = "/array?space=true"
+	ArrayConnectionsEndpoint              = "/array/connection"
 	ArrayControllersEndpoint              = "/array?controllers=true"
 	ArrayPerformanceMetricsEndpoint       = "/array?action=monitor&size=true"
+	ArrayPerformanceHistoryEndpoint       = "/array?action=monitor&size=true&historical="
+	DriveEndpoint                         = "/drive"
+	HardwareEndpoint                      = "/hardware"
+	HostEndpoint                          = "/host"
 	HostCountEndpoint                     = "/host?start=0&limit=1"
+	HostGroupEndpoint                     = "/hgroup"
+	HostGroupPerformanceMetricsEndpoint   = "/hgroup?action=monitor"
+	HostPerformanceMetricsEndpoint        = "/host?action=monitor"
+	HostPersonalityEndpoint               = "/host?personality=true"
+	MessageAuditEndpoint                  = "/message?audit=true"
 	MessageFlaggedEndpoint                = "/message?flagged=true"
+	MessageLoginEndpoint                  = "/message?login=true"
 	MessageTimelineEndpoint               = "/message?timeline=true"
+	PodEndpoint                           = "/pod"
+	PodMediatorEndpoint                   = "/pod?mediator=true"
+	ProtectionGroupEndpoint               = "/pgroup"
+	ProtectionGroupScheduleEndpoint       = "/pgroup?schedule=true"
+	ProtectionGroupSnapshotsEndpoint      = "/pgroup?snap=true&transfer=true"
 	SessionEndpoint                       = "/auth/session"
 	VolumeCapacityMetricsEndpoint         = "/volume?space=true"
+	VolumeConnectionsEndpoint             = "/volume?connect=true"
 	VolumeCountEndpoint                   = "/volume?start=0&limit=1"
 	VolumePerformanceMetricsEndpoint      = "/volume?action=monitor"
 	VolumePendingEradicationCountEndpoint = "/volume?pending_only=true&start=0&limit=1"
@@ -49,6 +68,7 @@ const (
 
 // These are other constants
 const (
+	PodAPIVersion        = "1.13" // Pods aren't available in the preferred API version
 	PreferredAPIVersion  = "1.7"
 	RequestAttemptCount  = 3
 	TotalItemCountHeader = "x-total-item-count"
@@ -57,34 +77,61 @@ const (
 )
 
 // NewClient creates a new FlashArray client and initializes it by getting the API version,
-// refreshing a new session, and getting the array metadata
-func NewClient(displayName string, managementEndpoint string, apiToken string) (ArrayClient, error) {
-	// Ignore the verification for using HTTPS
-	resty.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+// refreshing a new session, and getting the array metadata. The management endpoints are tried in order
+// until one answers. If the array offers REST 2.x, or API client credentials are given, a REST 2.x client
+// is returned instead (a nil tlsConfig skips certificate verification)
+func NewClient(displayName string, managementEndpoints []string, apiToken string, apiClient *resources.APIClientCredentials, tlsConfig *tls.Config) (ArrayClient, error) {
+	if len(managementEndpoints) == 0 {
+		return nil, fmt.Errorf("No management endpoint given for %s", displayName)
+	}
 
-	// Convert the endpoint to an IP
-	ip, err := util.ParseEndpoint(displayName, managementEndpoint)
-	if err != nil {
-		return nil, err
+	// Without a registered CA bundle or fingerprint, ignore the verification for using HTTPS
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
 	}
 
 	client := Client{
-		APIToken:     apiToken,
-		DisplayName:  displayName,
-		ManagementIP: ip,
+		APIToken:    apiToken,
+		DisplayName: displayName,
+		// Each client gets its own REST client so TLS settings (and sessions) don't leak between arrays
+		restClient: resty.New().SetTLSClientConfig(tlsConfig),
 	}
 
-	// Get the API versions and verify the preferred one is supported
-	apiVersion, err := client.getAPIVersion()
-	if err != nil {
+	// Get the API versions and verify the preferred one is supported, failing over between the endpoints.
+	// Host names are resolved again on every connection, so DNS changes are picked up.
+	var apiVersion string
+	var firstErr error
+	for _, endpoint := range managementEndpoints {
+		client.ManagementEndpoint = endpoint
+		version, err := client.getAPIVersion(apiClient != nil)
+		if err == nil {
+			apiVersion = version
+			firstErr = nil
+			break
+		}
+		log.WithFields(log.Fields{
+			"display_name": displayName,
+			"endpoint":     endpoint,
+			"error":        err,
+		}).Warn("Could not reach FlashArray management endpoint, trying the next one")
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	// Report the primary endpoint's error if none of them answered, since that's the one expected to work
+	if firstErr != nil {
 		client.logCreationError()
-		return nil, err
+		return nil, firstErr
+	}
+	if isAPIVersion2(apiVersion) {
+		return newClientV2(&client, apiVersion, apiClient)
 	}
 	client.APIVersion = apiVersion
 
 	log.WithFields(log.Fields{
 		"api_version":  client.APIVersion,
 		"display_name": client.DisplayName,
+		"endpoint":     client.ManagementEndpoint,
 	}).Info("Successfully created FlashArray Client")
 	return &client, nil
 }
@@ -111,6 +158,18 @@ func (client *Client) GetArrayCapacityMetrics() (*ArrayCapacityMetricsResponse,
 	return &(*result)[0], nil
 }
 
+// GetArrayConnections returns the arrays this array is connected to (for replication)
+func (client *Client) GetArrayConnections() ([]*ArrayConnectionResponse, error) {
+	url := client.createFullURL(ArrayConnectionsEndpoint)
+	response, _, err := client.performGet(url, []*ArrayConnectionResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*ArrayConnectionResponse)
+	return *result, nil
+}
+
 // GetArrayInfo returns the basic array metadata
 func (client *Client) GetArrayInfo() (*ArrayInfoResponse, error) {
 	url := client.createFullURL(ArrayEndpoint)
@@ -135,34 +194,232 @@ func (client *Client) GetArrayPerformanceMetrics() (*ArrayPerformanceMetricsResp
 	return &(*result)[0], nil
 }
 
+// GetArrayPerformanceHistory returns the performance metrics the array kept between the given times (Unix seconds),
+// oldest first, at whatever resolution the array keeps them at for that long
+func (client *Client) GetArrayPerformanceHistory(start int64, end int64) ([]*ArrayPerformanceMetricsResponse, error) {
+	url := client.createFullURL(ArrayPerformanceHistoryEndpoint + getPerformanceHistoryWindow(start, time.Now()))
+	response, _, err := client.performGet(url, []*ArrayPerformanceMetricsResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*ArrayPerformanceMetricsResponse
+	for _, metric := range *response.(*[]*ArrayPerformanceMetricsResponse) {
+		createdAt := parseFlashArrayTime(metric.Time)
+		if createdAt >= start && createdAt <= end {
+			result = append(result, metric)
+		}
+	}
+	return result, nil
+}
+
+// GetAuditEvents returns the audit trail of the commands run on the array at or after the given time (Unix seconds)
+func (client *Client) GetAuditEvents(since int64) ([]*AuditResponse, error) {
+	url := client.createFullURL(MessageAuditEndpoint)
+	response, _, err := client.performGet(url, []*AuditResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*AuditResponse
+	for _, audit := range *response.(*[]*AuditResponse) {
+		if parseFlashArrayTime(audit.Opened) >= since {
+			result = append(result, audit)
+		}
+	}
+	return result, nil
+}
+
+// GetControllers returns the mode, model, status and version of every controller
+func (client *Client) GetControllers() ([]*ArrayControllersResponse, error) {
+	url := client.createFullURL(ArrayControllersEndpoint)
+	response, _, err := client.performGet(url, []*ArrayControllersResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*ArrayControllersResponse)
+	return *result, nil
+}
+
+// GetDrives returns the status of every drive bay and NVRAM module
+func (client *Client) GetDrives() ([]*DriveResponse, error) {
+	url := client.createFullURL(DriveEndpoint)
+	response, _, err := client.performGet(url, []*DriveResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*DriveResponse)
+	return *result, nil
+}
+
+// GetHardware returns the status of every hardware component (chassis, controllers, fans, power supplies, sensors, ...)
+func (client *Client) GetHardware() ([]*HardwareResponse, error) {
+	url := client.createFullURL(HardwareEndpoint)
+	response, _, err := client.performGet(url, []*HardwareResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*HardwareResponse)
+	return *result, nil
+}
+
 // GetHostCount returns the count of hosts on the array
 func (client *Client) GetHostCount() (uint32, error) {
 	return client.getResourceCount(HostCountEndpoint)
 }
 
+// GetHostGroupPerformanceMetrics returns the performance metrics for all host groups
+func (client *Client) GetHostGroupPerformanceMetrics() ([]*HostPerformanceMetricsResponse, error) {
+	return client.getHostPerformanceMetrics(HostGroupPerformanceMetricsEndpoint)
+}
+
+// GetHostGroups returns all host groups along with their member hosts
+func (client *Client) GetHostGroups() ([]*HostGroupResponse, error) {
+	url := client.createFullURL(HostGroupEndpoint)
+	response, _, err := client.performGet(url, []*HostGroupResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*HostGroupResponse)
+	return *result, nil
+}
+
+// GetHostPerformanceMetrics returns the performance metrics for all hosts
+func (client *Client) GetHostPerformanceMetrics() ([]*HostPerformanceMetricsResponse, error) {
+	return client.getHostPerformanceMetrics(HostPerformanceMetricsEndpoint)
+}
+
+// GetHostPersonalities returns the personality of all hosts
+func (client *Client) GetHostPersonalities() ([]*HostPersonalityResponse, error) {
+	url := client.createFullURL(HostPersonalityEndpoint)
+	response, _, err := client.performGet(url, []*HostPersonalityResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*HostPersonalityResponse)
+	return *result, nil
+}
+
+// GetHosts returns all hosts along with their WWNs and IQNs
+func (client *Client) GetHosts() ([]*HostResponse, error) {
+	url := client.createFullURL(HostEndpoint)
+	response, _, err := client.performGet(url, []*HostResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*HostResponse)
+	return *result, nil
+}
+
+// GetManagementEndpoint returns the management endpoint the client connected to
+func (client *Client) GetManagementEndpoint() string {
+	return client.ManagementEndpoint
+}
+
 // GetModel returns the model of the primary controller (usually CT0)
 func (client *Client) GetModel() (string, error) {
-	url := client.createFullURL(ArrayControllersEndpoint)
-	response, _, err := client.performGet(url, []ArrayControllersResponse{})
+	controllers, err := client.GetControllers()
 	if err != nil {
 		return "", err
 	}
+	return getPrimaryControllerModel(client.DisplayName, controllers)
+}
 
-	result := response.(*[]ArrayControllersResponse)
+// GetPodMediators returns the mediator of every pod. Returns no pods if the array doesn't support them.
+func (client *Client) GetPodMediators() ([]*PodMediatorResponse, error) {
+	if !client.supportsAPIVersion(PodAPIVersion) {
+		return []*PodMediatorResponse{}, nil
+	}
 
-	// Return the model of the primary
-	for _, controller := range *result {
-		if controller.Mode == "primary" {
-			return controller.Model, nil
-		}
+	url := client.createVersionedURL(PodAPIVersion, PodMediatorEndpoint)
+	response, _, err := client.performGet(url, []*PodMediatorResponse{})
+	if err != nil {
+		return nil, err
 	}
 
-	// If no primary is found, return the model of the first controller
-	log.WithFields(log.Fields{
-		"display_name": client.DisplayName,
-		"url":          url,
-	}).Warn("No primary controller found")
-	return (*result)[0].Model, nil
+	result := response.(*[]*PodMediatorResponse)
+	return *result, nil
+}
+
+// GetPods returns all pods along with the state of each of their member arrays. Returns no pods if
+// the array doesn't support them.
+func (client *Client) GetPods() ([]*PodResponse, error) {
+	if !client.supportsAPIVersion(PodAPIVersion) {
+		log.WithFields(log.Fields{
+			"display_name":    client.DisplayName,
+			"pod_api_version": PodAPIVersion,
+		}).Trace("Array doesn't support pods, skipping")
+		return []*PodResponse{}, nil
+	}
+
+	url := client.createVersionedURL(PodAPIVersion, PodEndpoint)
+	response, _, err := client.performGet(url, []*PodResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*PodResponse)
+	return *result, nil
+}
+
+// GetProtectionGroups returns all protection groups along with their members and targets
+func (client *Client) GetProtectionGroups() ([]*ProtectionGroupResponse, error) {
+	url := client.createFullURL(ProtectionGroupEndpoint)
+	response, _, err := client.performGet(url, []*ProtectionGroupResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*ProtectionGroupResponse)
+	return *result, nil
+}
+
+// GetProtectionGroupSchedules returns the snapshot and replication schedules of all protection groups
+func (client *Client) GetProtectionGroupSchedules() ([]*ProtectionGroupScheduleResponse, error) {
+	url := client.createFullURL(ProtectionGroupScheduleEndpoint)
+	response, _, err := client.performGet(url, []*ProtectionGroupScheduleResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*ProtectionGroupScheduleResponse)
+	return *result, nil
+}
+
+// GetProtectionGroupSnapshots returns all protection group snapshots along with their replication transfer statistics
+func (client *Client) GetProtectionGroupSnapshots() ([]*ProtectionGroupSnapshotResponse, error) {
+	url := client.createFullURL(ProtectionGroupSnapshotsEndpoint)
+	response, _, err := client.performGet(url, []*ProtectionGroupSnapshotResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*ProtectionGroupSnapshotResponse)
+	return *result, nil
+}
+
+// GetSessions returns the logins, logouts and failed authentications on the array at or after the given time
+// (Unix seconds)
+func (client *Client) GetSessions(since int64) ([]*SessionResponse, error) {
+	url := client.createFullURL(MessageLoginEndpoint)
+	response, _, err := client.performGet(url, []*SessionResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*SessionResponse
+	for _, session := range *response.(*[]*SessionResponse) {
+		if parseFlashArrayTime(session.Opened) >= since {
+			result = append(result, session)
+		}
+	}
+	return result, nil
 }
 
 // GetVolumeCapacityMetrics returns the capacity metrics for all volumes
@@ -177,6 +434,19 @@ func (client *Client) GetVolumeCapacityMetrics() ([]*VolumeCapacityMetricsRespon
 	return *result, nil
 }
 
+// GetVolumeConnections returns every connection between a volume and a host, either private
+// or shared through a host group
+func (client *Client) GetVolumeConnections() ([]*VolumeConnectionResponse, error) {
+	url := client.createFullURL(VolumeConnectionsEndpoint)
+	response, _, err := client.performGet(url, []*VolumeConnectionResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*VolumeConnectionResponse)
+	return *result, nil
+}
+
 // GetVolumeCount returns the count of volumes on the array (without getting all of the volumes)
 func (client *Client) GetVolumeCount() (uint32, error) {
 	return client.getResourceCount(VolumeCountEndpoint)
@@ -219,7 +489,26 @@ func (client *Client) GetVolumeSnapshots() ([]*VolumeSnapshotResponse, error) {
 // createFullURL is a helper function that returns a URL for the specified endpoint/params with the
 // management endpoint and API version
 func (client *Client) createFullURL(endpoint string) string {
-	return fmt.Sprintf("https://%s%s/%s%s", client.ManagementIP.String(), APIPrefix, client.APIVersion, endpoint)
+	return client.createVersionedURL(client.APIVersion, endpoint)
+}
+
+// createVersionedURL is a helper function that returns a URL for the specified endpoint/params with the
+// management endpoint and the given API version, for endpoints that need a different version
+func (client *Client) createVersionedURL(apiVersion string, endpoint string) string {
+	return fmt.Sprintf("https://%s%s/%s%s", util.EndpointURLHost(client.ManagementEndpoint), APIPrefix, apiVersion, endpoint)
+}
+
+// getHostPerformanceMetrics is a helper function that returns performance metrics for the specified
+// host or host group monitor endpoint
+func (client *Client) getHostPerformanceMetrics(monitorEndpoint string) ([]*HostPerformanceMetricsResponse, error) {
+	url := client.createFullURL(monitorEndpoint)
+	response, _, err := client.performGet(url, []*HostPerformanceMetricsResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*[]*HostPerformanceMetricsResponse)
+	return *result, nil
 }
 
 // getAlerts is a helper function that returns alerts for the specified messages endpoint
@@ -235,9 +524,9 @@ func (client *Client) getAlerts(alertEndpoint string) ([]*AlertResponse, error)
 }
 
 // getAPIVersion is a helper function that checks the available API versions and that the desired
-// version is available; it warns if it is not
-func (client *Client) getAPIVersion() (string, error) {
-	url := fmt.Sprintf("https://%s%s", client.ManagementIP.String(), APIVersionEndpoint)
+// version is available; it warns if it is not. REST 2.x is required to authenticate with an API client.
+func (client *Client) getAPIVersion(requireV2 bool) (string, error) {
+	url := fmt.Sprintf("https://%s%s", util.EndpointURLHost(client.ManagementEndpoint), APIVersionEndpoint)
 	response, _, err := client.performGet(url, APIVersionResponse{})
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -248,21 +537,31 @@ func (client *Client) getAPIVersion() (string, error) {
 	}
 
 	result := response.(*APIVersionResponse)
+	client.apiVersions = result.Version
 
-	// If the preferred API version exists, we'll use that
-	for _, ver := range result.Version {
-		if ver == PreferredAPIVersion {
-			return PreferredAPIVersion, nil
-		}
+	apiVersion, preferred, err := chooseAPIVersion(result.Version, requireV2)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"api_versions": result.Version,
+			"display_name": client.DisplayName,
+			"error":        err,
+		}).Error("Could not find a usable API version")
+		return "", err
 	}
+	if !preferred {
+		log.WithFields(log.Fields{
+			"api_version":              apiVersion,
+			"display_name":             client.DisplayName,
+			"preferred_api_version":    PreferredAPIVersion,
+			"preferred_api_version_v2": PreferredAPIVersionV2,
+		}).Warn("Could not use preferred API version; defaulting to latest")
+	}
+	return apiVersion, nil
+}
 
-	// Otherwise, default to latest API version
-	log.WithFields(log.Fields{
-		"display_name":          client.DisplayName,
-		"latest_api_version":    result.Version[len(result.Version)-1],
-		"preferred_api_version": PreferredAPIVersion,
-	}).Warn("Could not use preferred API version; defaulting to latest")
-	return result.Version[len(result.Version)-1], nil
+// supportsAPIVersion is a helper function that checks whether the array listed the given API version as available
+func (client *Client) supportsAPIVersion(apiVersion string) bool {
+	return containsAPIVersion(client.apiVersions, apiVersion)
 }
 
 // getResourceCount is a helper function that returns the number of resources where we don't need
@@ -304,7 +603,8 @@ func (client *Client) performGet(url string, result interface{}) (interface{}, h
 			"display_name": client.DisplayName,
 			"url":          url,
 		}).Trace("Making GET request")
-		response, err := resty.R().SetHeader(UserAgentHeader, UserAgent).SetResult(result).Get(url)
+		session := client.currentSession()
+		response, err := client.restClient.R().SetHeader(UserAgentHeader, UserAgent).SetResult(result).Get(url)
 
 		// If there was a client error we quit
 		if err != nil {
@@ -323,7 +623,7 @@ func (client *Client) performGet(url string, result interface{}) (interface{}, h
 				"status_code":  response.StatusCode(),
 				"url":          url,
 			}).Trace("Session expired; refreshing session and retrying")
-			client.refreshSession()
+			client.refreshExpiredSession(session)
 			continue
 		}
 		if response.StatusCode() == 500 {
@@ -349,6 +649,26 @@ func (client *Client) performGet(url string, result interface{}) (interface{}, h
 	return nil, nil, errors.New("No successful GET request")
 }
 
+// currentSession is a helper function that returns the number of the session requests are currently made with
+func (client *Client) currentSession() int {
+	client.sessionLock.Lock()
+	defer client.sessionLock.Unlock()
+	return client.session
+}
+
+// refreshExpiredSession is a helper function that refreshes the session a request was rejected with, unless
+// another request (sharing this client) already refreshed it
+func (client *Client) refreshExpiredSession(expired int) {
+	client.sessionLock.Lock()
+	defer client.sessionLock.Unlock()
+	if client.session != expired {
+		return
+	}
+	if client.refreshSession() == nil {
+		client.session++
+	}
+}
+
 // refreshSession is a helper function that makes a POST request to refresh the client session
 func (client *Client) refreshSession() error {
 	url := client.createFullURL(SessionEndpoint)
@@ -356,7 +676,7 @@ func (client *Client) refreshSession() error {
 		"display_name": client.DisplayName,
 		"url":          url,
 	}).Trace("Making POST request to refresh session")
-	response, err := resty.R().SetHeader(UserAgentHeader, UserAgent).SetBody(map[string]interface{}{"api_token": client.APIToken}).Post(url)
+	response, err := client.restClient.R().SetHeader(UserAgentHeader, UserAgent).SetBody(map[string]interface{}{"api_token": client.APIToken}).Post(url)
 
 	// Verify the request was successful
 	if err != nil {
@@ -377,3 +697,95 @@ func (client *Client) refreshSession() error {
 	}
 	return nil
 }
+
+// chooseAPIVersion is a helper function that picks which of the given API versions (oldest first) to use,
+// and whether it's a preferred one. REST 2.x is used whenever the preferred 2.x version is available or
+// required; otherwise REST 1.x is preferred over a 2.x version we haven't tested against.
+func chooseAPIVersion(apiVersions []string, requireV2 bool) (string, bool, error) {
+	if containsAPIVersion(apiVersions, PreferredAPIVersionV2) {
+		return PreferredAPIVersionV2, true, nil
+	}
+
+	var latestV1, latestV2 string
+	for _, ver := range apiVersions {
+		if isAPIVersion2(ver) {
+			latestV2 = ver
+		} else {
+			latestV1 = ver
+		}
+	}
+
+	if requireV2 {
+		if latestV2 == "" {
+			return "", false, errors.New("Array does not support REST 2.x, which is needed for API client credentials")
+		}
+		return latestV2, false, nil
+	}
+	if containsAPIVersion(apiVersions, PreferredAPIVersion) {
+		return PreferredAPIVersion, true, nil
+	}
+	if latestV1 != "" {
+		return latestV1, false, nil
+	}
+	if latestV2 != "" {
+		return latestV2, false, nil
+	}
+	return "", false, errors.New("Array returned no API versions")
+}
+
+// containsAPIVersion is a helper function that checks whether the given API version is in the list
+func containsAPIVersion(apiVersions []string, apiVersion string) bool {
+	for _, ver := range apiVersions {
+		if ver == apiVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// isAPIVersion2 is a helper function that checks whether the given API version is a REST 2.x version
+func isAPIVersion2(apiVersion string) bool {
+	return strings.HasPrefix(apiVersion, "2.")
+}
+
+// getPrimaryControllerModel is a helper function that returns the model of the primary controller,
+// or of the first controller if there is no primary
+func getPrimaryControllerModel(displayName string, controllers []*ArrayControllersResponse) (string, error) {
+	// Return the model of the primary
+	for _, controller := range controllers {
+		if controller.Mode == "primary" {
+			return controller.Model, nil
+		}
+	}
+
+	// If no primary is found, return the model of the first controller
+	log.WithFields(log.Fields{
+		"display_name": displayName,
+	}).Warn("No primary controller found")
+	if len(controllers) == 0 {
+		return "", errors.New("No controllers returned")
+	}
+	return controllers[0].Model, nil
+}
+
+// performanceHistoryWindows are the windows the array keeps performance history for (the longer the window,
+// the lower the resolution), shortest first
+var performanceHistoryWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"3h", 3 * time.Hour},
+	{"24h", 24 * time.Hour},
+}
+
+// getPerformanceHistoryWindow is a helper function that returns the shortest performance history window reaching
+// back to the given time (Unix seconds), or the longest window if none does
+func getPerformanceHistoryWindow(start int64, now time.Time) string {
+	for _, window := range performanceHistoryWindows {
+		if !time.Unix(start, 0).Before(now.Add(-window.duration)) {
+			return window.name
+		}
+	}
+	return performanceHistoryWindows[len(performanceHistoryWindows)-1].name
+}
@@ -15,7 +15,10 @@
 package flasharray
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -27,19 +30,37 @@ const (
 )
 
 func TestFlashArrayClientWrongEndpoint(t *testing.T) {
-	_, err := NewClient("test-client", "10.14.75.103", testArrayToken)
+	_, err := NewClient("test-client", []string{"10.14.75.103"}, testArrayToken, nil, nil)
 	assert.Error(t, err)
 }
 
 func TestFlashArrayClientInvalidEndpoint(t *testing.T) {
-	_, err := NewClient("test-client", "https://aaaaaa.com", testArrayToken)
+	_, err := NewClient("test-client", []string{"https://aaaaaa.com"}, testArrayToken, nil, nil)
 	assert.Error(t, err)
 }
 
+func TestFlashArrayClientNoEndpoints(t *testing.T) {
+	_, err := NewClient("test-client", []string{}, testArrayToken, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestFlashArrayClientEndpointFailover(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version": ["1.6", "1.7"]}`))
+	}))
+	defer server.Close()
+
+	// Nothing listens on the first endpoint, so the client should fail over to the second
+	client, err := NewClient("test-client", []string{"127.0.0.1:1", server.URL}, testArrayToken, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL, client.GetManagementEndpoint())
+}
+
 func TestFlashArrayClientInvalidToken(t *testing.T) {
 	t.Skip("Waiting for mock interceptor")
 
-	client, err := NewClient("test-client", testArrayEndpoint, "nope")
+	client, err := NewClient("test-client", []string{testArrayEndpoint}, "nope", nil, nil)
 	assert.NoError(t, err)
 
 	_, err = client.GetArrayInfo()
@@ -51,7 +72,7 @@ func TestFlashArrayClient(t *testing.T) {
 
 	logrus.SetLevel(logrus.TraceLevel)
 
-	client, err := NewClient("test-client", testArrayEndpoint, testArrayToken)
+	client, err := NewClient("test-client", []string{testArrayEndpoint}, testArrayToken, nil, nil)
 	assert.NoError(t, err)
 
 	var response interface{}
@@ -108,3 +129,12 @@ func TestFlashArrayClient(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, response)
 }
+
+func TestGetPerformanceHistoryWindow(t *testing.T) {
+	now := time.Unix(1560000000, 0)
+	assert.Equal(t, "1h", getPerformanceHistoryWindow(now.Add(-30*time.Minute).Unix(), now))
+	assert.Equal(t, "1h", getPerformanceHistoryWindow(now.Add(-time.Hour).Unix(), now))
+	assert.Equal(t, "3h", getPerformanceHistoryWindow(now.Add(-2*time.Hour).Unix(), now))
+	assert.Equal(t, "24h", getPerformanceHistoryWindow(now.Add(-12*time.Hour).Unix(), now))
+	assert.Equal(t, "24h", getPerformanceHistoryWindow(now.Add(-48*time.Hour).Unix(), now)) // Longest kept
+}
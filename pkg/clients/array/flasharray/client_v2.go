@@ -0,0 +1,1016 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flasharray
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/util"
+	jwt "github.com/dgrijalva/jwt-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: check that this struct implements the interface
+var _ ArrayClient = (*ClientV2)(nil)
+
+// These are REST 2.x endpoint constants
+const (
+	AlertsEndpointV2                         = "/alerts"
+	ArrayConnectionsEndpointV2               = "/array-connections"
+	ArraysEndpointV2                         = "/arrays"
+	ArraysPerformanceEndpointV2              = "/arrays/performance"
+	ArraysSpaceEndpointV2                    = "/arrays/space"
+	AuditsEndpointV2                         = "/audits"
+	ConnectionsEndpointV2                    = "/connections"
+	ControllersEndpointV2                    = "/controllers"
+	DrivesEndpointV2                         = "/drives"
+	HardwareEndpointV2                       = "/hardware"
+	HostGroupsEndpointV2                     = "/host-groups"
+	HostGroupsHostsEndpointV2                = "/host-groups/hosts"
+	HostGroupsPerformanceEndpointV2          = "/host-groups/performance"
+	HostsEndpointV2                          = "/hosts"
+	HostsPerformanceEndpointV2               = "/hosts/performance"
+	LoginEndpointV2                          = "/login"
+	OAuth2TokenEndpoint                      = "/oauth2/1.0/token"
+	PodsEndpointV2                           = "/pods"
+	ProtectionGroupsEndpointV2               = "/protection-groups"
+	ProtectionGroupsHostGroupsEndpointV2     = "/protection-groups/host-groups"
+	ProtectionGroupsHostsEndpointV2          = "/protection-groups/hosts"
+	ProtectionGroupsTargetsEndpointV2        = "/protection-groups/targets"
+	ProtectionGroupsVolumesEndpointV2        = "/protection-groups/volumes"
+	ProtectionGroupSnapshotsEndpointV2       = "/protection-group-snapshots"
+	ProtectionGroupSnapshotsTransferEndpoint = "/protection-group-snapshots/transfer"
+	SessionsEndpointV2                       = "/sessions"
+	VolumeSnapshotsEndpointV2                = "/volume-snapshots"
+	VolumesEndpointV2                        = "/volumes"
+	VolumesPerformanceEndpointV2             = "/volumes/performance"
+)
+
+// These are other REST 2.x constants
+const (
+	APIClientJWTLifetime         = 5 * time.Minute // Only needs to last until it's exchanged
+	APITokenHeader               = "api-token"
+	ArrayControllerType          = "array_controller"
+	AuthorizationHeader          = "Authorization"
+	AuthTokenHeader              = "x-auth-token"
+	OAuth2JWTTokenType           = "urn:ietf:params:oauth:token-type:jwt"
+	OAuth2TokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	PerformanceResolutionV2      = 30000 // ms
+	PreferredAPIVersionV2        = "2.4"
+)
+
+// newClientV2 is a helper function that creates a REST 2.x client from the REST 1.x client used to negotiate the API
+// version, with the API client credentials (if given) to authenticate with
+func newClientV2(client *Client, apiVersion string, apiClient *resources.APIClientCredentials) (ArrayClient, error) {
+	clientV2 := ClientV2{
+		APIClient:          apiClient,
+		APIToken:           client.APIToken,
+		APIVersion:         apiVersion,
+		DisplayName:        client.DisplayName,
+		ManagementEndpoint: client.ManagementEndpoint,
+		authHeaders:        map[string]string{},
+		restClient:         client.restClient,
+	}
+
+	// Parse the key up front so bad credentials fail now rather than on every request
+	if apiClient != nil {
+		privateKey, err := util.ParseRSAPrivateKey(apiClient.PrivateKey)
+		if err != nil {
+			client.logCreationError()
+			return nil, err
+		}
+		clientV2.privateKey = privateKey
+	}
+
+	log.WithFields(log.Fields{
+		"api_client":   apiClient != nil,
+		"api_version":  clientV2.APIVersion,
+		"display_name": clientV2.DisplayName,
+		"endpoint":     clientV2.ManagementEndpoint,
+	}).Info("Successfully created FlashArray REST 2.x Client")
+	return &clientV2, nil
+}
+
+// GetAlertsFlagged returns only open, flagged alerts from the array
+func (client *ClientV2) GetAlertsFlagged() ([]*AlertResponse, error) {
+	var alerts []*AlertResponseV2
+	err := client.getItems(AlertsEndpointV2, nil, &alerts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*AlertResponse
+	for _, alert := range alerts {
+		if alert.Flagged && alert.State != "closed" {
+			result = append(result, convertAlertResponseV2(alert))
+		}
+	}
+	return result, nil
+}
+
+// GetAlertsTimeline returns all alerts from the array, with Closed set for the closed ones
+func (client *ClientV2) GetAlertsTimeline() ([]*AlertResponse, error) {
+	var alerts []*AlertResponseV2
+	err := client.getItems(AlertsEndpointV2, nil, &alerts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*AlertResponse
+	for _, alert := range alerts {
+		result = append(result, convertAlertResponseV2(alert))
+	}
+	return result, nil
+}
+
+// GetArrayCapacityMetrics returns all capacity metrics for the array
+func (client *ClientV2) GetArrayCapacityMetrics() (*ArrayCapacityMetricsResponse, error) {
+	var spaces []*ArraySpaceResponseV2
+	err := client.getItems(ArraysSpaceEndpointV2, nil, &spaces)
+	if err != nil {
+		return nil, err
+	}
+	if len(spaces) == 0 {
+		return nil, errors.New("No array space returned")
+	}
+
+	space := spaces[0]
+	return &ArrayCapacityMetricsResponse{
+		Capacity:       space.Capacity,
+		DataReduction:  space.Space.DataReduction,
+		SharedSpace:    space.Space.Shared,
+		Snapshots:      space.Space.Snapshots,
+		SystemSpace:    space.Space.System,
+		TotalReduction: space.Space.TotalReduction,
+		TotalSpace:     space.Space.TotalPhysical,
+		VolumeSpace:    space.Space.Unique,
+	}, nil
+}
+
+// GetArrayConnections returns the arrays this array is connected to (for replication)
+func (client *ClientV2) GetArrayConnections() ([]*ArrayConnectionResponse, error) {
+	var connections []*ArrayConnectionResponseV2
+	err := client.getItems(ArrayConnectionsEndpointV2, nil, &connections)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*ArrayConnectionResponse
+	for _, connection := range connections {
+		result = append(result, &ArrayConnectionResponse{
+			ArrayName: connection.Remote.Name,
+			Connected: connection.Status == "connected",
+			ID:        connection.Remote.ID,
+			Type:      []string{connection.Type},
+		})
+	}
+	return result, nil
+}
+
+// GetArrayInfo returns the basic array metadata
+func (client *ClientV2) GetArrayInfo() (*ArrayInfoResponse, error) {
+	var arrays []*ArrayResponseV2
+	err := client.getItems(ArraysEndpointV2, nil, &arrays)
+	if err != nil {
+		return nil, err
+	}
+	if len(arrays) == 0 {
+		return nil, errors.New("No array info returned")
+	}
+
+	return &ArrayInfoResponse{
+		ArrayName: arrays[0].Name,
+		ID:        arrays[0].ID,
+		Version:   arrays[0].Version,
+	}, nil
+}
+
+// GetArrayPerformanceMetrics returns all performance metrics for the array
+func (client *ClientV2) GetArrayPerformanceMetrics() (*ArrayPerformanceMetricsResponse, error) {
+	var performance []*PerformanceResponseV2
+	err := client.getItems(ArraysPerformanceEndpointV2, nil, &performance)
+	if err != nil {
+		return nil, err
+	}
+	if len(performance) == 0 {
+		return nil, errors.New("No array performance returned")
+	}
+
+	return convertArrayPerformanceResponseV2(performance[0]), nil
+}
+
+// GetArrayPerformanceHistory returns the performance metrics the array kept between the given times (Unix seconds),
+// oldest first
+func (client *ClientV2) GetArrayPerformanceHistory(start int64, end int64) ([]*ArrayPerformanceMetricsResponse, error) {
+	params := url.Values{
+		"end_time":   {strconv.FormatInt(end*1000, 10)},
+		"resolution": {strconv.Itoa(PerformanceResolutionV2)},
+		"start_time": {strconv.FormatInt(start*1000, 10)},
+	}
+	var performance []*PerformanceResponseV2
+	err := client.getItems(ArraysPerformanceEndpointV2, params, &performance)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*ArrayPerformanceMetricsResponse
+	for _, metric := range performance {
+		result = append(result, convertArrayPerformanceResponseV2(metric))
+	}
+	return result, nil
+}
+
+// GetAuditEvents returns the audit trail of the commands run on the array at or after the given time (Unix seconds)
+func (client *ClientV2) GetAuditEvents(since int64) ([]*AuditResponse, error) {
+	params := url.Values{"filter": {fmt.Sprintf("time>=%d", since*1000)}}
+	var audits []*AuditResponseV2
+	err := client.getItems(AuditsEndpointV2, params, &audits)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*AuditResponse
+	for _, audit := range audits {
+		result = append(result, &AuditResponse{
+			Arguments:     audit.Arguments,
+			Command:       audit.Command,
+			ID:            parseRecordNumberV2(audit.Name),
+			Opened:        formatFlashArrayTime(audit.Time),
+			Subcommand:    audit.Subcommand,
+			User:          audit.User,
+			UserInterface: audit.UserInterface,
+		})
+	}
+	return result, nil
+}
+
+// GetControllers returns the mode, model, status and version of every array controller (shelf controllers are
+// left out, like they are in REST 1.x)
+func (client *ClientV2) GetControllers() ([]*ArrayControllersResponse, error) {
+	var controllers []*ControllerResponseV2
+	err := client.getItems(ControllersEndpointV2, nil, &controllers)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*ArrayControllersResponse
+	for _, controller := range controllers {
+		if controller.Type != "" && controller.Type != ArrayControllerType {
+			continue
+		}
+		result = append(result, &ArrayControllersResponse{
+			Mode:    controller.Mode,
+			Model:   controller.Model,
+			Name:    controller.Name,
+			Status:  controller.Status,
+			Version: controller.Version,
+		})
+	}
+	return result, nil
+}
+
+// GetDrives returns the status of every drive bay and NVRAM module
+func (client *ClientV2) GetDrives() ([]*DriveResponse, error) {
+	var drives []*DriveResponseV2
+	err := client.getItems(DrivesEndpointV2, nil, &drives)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*DriveResponse
+	for _, drive := range drives {
+		result = append(result, &DriveResponse{
+			Capacity:          drive.Capacity,
+			Details:           drive.Details,
+			LastEvacCompleted: formatFlashArrayTime(drive.LastEvacCompleted),
+			LastFailure:       formatFlashArrayTime(drive.LastFailure),
+			Name:              drive.Name,
+			Protocol:          drive.Protocol,
+			Status:            drive.Status,
+			Type:              drive.Type,
+		})
+	}
+	return result, nil
+}
+
+// GetHardware returns the status of every hardware component (chassis, controllers, fans, power supplies, sensors, ...)
+func (client *ClientV2) GetHardware() ([]*HardwareResponse, error) {
+	// The components are the same as in REST 1.x
+	var result []*HardwareResponse
+	err := client.getItems(HardwareEndpointV2, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetHostCount returns the count of hosts on the array
+func (client *ClientV2) GetHostCount() (uint32, error) {
+	return client.getResourceCount(HostsEndpointV2, nil)
+}
+
+// GetHostGroupPerformanceMetrics returns the performance metrics for all host groups
+func (client *ClientV2) GetHostGroupPerformanceMetrics() ([]*HostPerformanceMetricsResponse, error) {
+	return client.getHostPerformanceMetrics(HostGroupsPerformanceEndpointV2)
+}
+
+// GetHostGroups returns all host groups along with their member hosts
+func (client *ClientV2) GetHostGroups() ([]*HostGroupResponse, error) {
+	var hostGroups []*ReferenceV2
+	err := client.getItems(HostGroupsEndpointV2, nil, &hostGroups)
+	if err != nil {
+		return nil, err
+	}
+	var members []*MemberResponseV2
+	err = client.getItems(HostGroupsHostsEndpointV2, nil, &members)
+	if err != nil {
+		return nil, err
+	}
+
+	hostsMap := groupMembers(members)
+	var result []*HostGroupResponse
+	for _, hostGroup := range hostGroups {
+		hosts := hostsMap[hostGroup.Name]
+		if hosts == nil {
+			hosts = []string{}
+		}
+		result = append(result, &HostGroupResponse{Hosts: hosts, Name: hostGroup.Name})
+	}
+	return result, nil
+}
+
+// GetHostPerformanceMetrics returns the performance metrics for all hosts
+func (client *ClientV2) GetHostPerformanceMetrics() ([]*HostPerformanceMetricsResponse, error) {
+	return client.getHostPerformanceMetrics(HostsPerformanceEndpointV2)
+}
+
+// GetHostPersonalities returns the personality of all hosts
+func (client *ClientV2) GetHostPersonalities() ([]*HostPersonalityResponse, error) {
+	var hosts []*HostResponseV2
+	err := client.getItems(HostsEndpointV2, nil, &hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*HostPersonalityResponse
+	for _, host := range hosts {
+		result = append(result, &HostPersonalityResponse{Name: host.Name, Personality: host.Personality})
+	}
+	return result, nil
+}
+
+// GetHosts returns all hosts along with their WWNs and IQNs
+func (client *ClientV2) GetHosts() ([]*HostResponse, error) {
+	var hosts []*HostResponseV2
+	err := client.getItems(HostsEndpointV2, nil, &hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*HostResponse
+	for _, host := range hosts {
+		result = append(result, &HostResponse{
+			HostGroup: host.HostGroup.Name,
+			IQN:       host.IQNs,
+			Name:      host.Name,
+			WWN:       host.WWNs,
+		})
+	}
+	return result, nil
+}
+
+// GetManagementEndpoint returns the management endpoint the client connected to
+func (client *ClientV2) GetManagementEndpoint() string {
+	return client.ManagementEndpoint
+}
+
+// GetModel returns the model of the primary controller (usually CT0)
+func (client *ClientV2) GetModel() (string, error) {
+	controllers, err := client.GetControllers()
+	if err != nil {
+		return "", err
+	}
+	return getPrimaryControllerModel(client.DisplayName, controllers)
+}
+
+// GetPodMediators returns the mediator of every pod
+func (client *ClientV2) GetPodMediators() ([]*PodMediatorResponse, error) {
+	var pods []*PodResponseV2
+	err := client.getItems(PodsEndpointV2, nil, &pods)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*PodMediatorResponse
+	for _, pod := range pods {
+		result = append(result, &PodMediatorResponse{
+			Mediator:        pod.Mediator,
+			MediatorVersion: pod.MediatorVersion,
+			Name:            pod.Name,
+		})
+	}
+	return result, nil
+}
+
+// GetPods returns all pods along with the state of each of their member arrays
+func (client *ClientV2) GetPods() ([]*PodResponse, error) {
+	var pods []*PodResponseV2
+	err := client.getItems(PodsEndpointV2, nil, &pods)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*PodResponse
+	for _, pod := range pods {
+		podResponse := &PodResponse{Arrays: []*PodArrayResponse{}, Name: pod.Name, Source: pod.Source.Name}
+		for _, member := range pod.Arrays {
+			podResponse.Arrays = append(podResponse.Arrays, &PodArrayResponse{
+				ArrayID:        member.ID,
+				MediatorStatus: member.MediatorStatus,
+				Name:           member.Name,
+				Progress:       member.Progress,
+				Status:         member.Status,
+			})
+		}
+		result = append(result, podResponse)
+	}
+	return result, nil
+}
+
+// GetProtectionGroups returns all protection groups along with their members and targets
+func (client *ClientV2) GetProtectionGroups() ([]*ProtectionGroupResponse, error) {
+	var protectionGroups []*ProtectionGroupResponseV2
+	err := client.getItems(ProtectionGroupsEndpointV2, url.Values{"destroyed": {"false"}}, &protectionGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each kind of member has its own endpoint
+	membersMaps := make(map[string]map[string][]string)
+	for _, endpoint := range []string{ProtectionGroupsHostGroupsEndpointV2, ProtectionGroupsHostsEndpointV2, ProtectionGroupsVolumesEndpointV2} {
+		var members []*MemberResponseV2
+		err = client.getItems(endpoint, nil, &members)
+		if err != nil {
+			return nil, err
+		}
+		membersMaps[endpoint] = groupMembers(members)
+	}
+	var targets []*MemberResponseV2
+	err = client.getItems(ProtectionGroupsTargetsEndpointV2, nil, &targets)
+	if err != nil {
+		return nil, err
+	}
+	targetsMap := make(map[string][]*ProtectionGroupTargetResponse)
+	for _, target := range targets {
+		targetsMap[target.Group.Name] = append(targetsMap[target.Group.Name], &ProtectionGroupTargetResponse{
+			Allowed: target.Allowed,
+			Name:    target.Member.Name,
+		})
+	}
+
+	var result []*ProtectionGroupResponse
+	for _, protectionGroup := range protectionGroups {
+		result = append(result, &ProtectionGroupResponse{
+			HostGroups: membersMaps[ProtectionGroupsHostGroupsEndpointV2][protectionGroup.Name],
+			Hosts:      membersMaps[ProtectionGroupsHostsEndpointV2][protectionGroup.Name],
+			Name:       protectionGroup.Name,
+			Source:     protectionGroup.Source.Name,
+			Targets:    targetsMap[protectionGroup.Name],
+			Volumes:    membersMaps[ProtectionGroupsVolumesEndpointV2][protectionGroup.Name],
+		})
+	}
+	return result, nil
+}
+
+// GetProtectionGroupSchedules returns the snapshot and replication schedules of all protection groups
+func (client *ClientV2) GetProtectionGroupSchedules() ([]*ProtectionGroupScheduleResponse, error) {
+	var protectionGroups []*ProtectionGroupResponseV2
+	err := client.getItems(ProtectionGroupsEndpointV2, url.Values{"destroyed": {"false"}}, &protectionGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*ProtectionGroupScheduleResponse
+	for _, protectionGroup := range protectionGroups {
+		result = append(result, &ProtectionGroupScheduleResponse{
+			Name:               protectionGroup.Name,
+			ReplicateEnabled:   protectionGroup.ReplicationSchedule.Enabled,
+			ReplicateFrequency: protectionGroup.ReplicationSchedule.Frequency / 1000,
+			SnapshotEnabled:    protectionGroup.SnapshotSchedule.Enabled,
+			SnapshotFrequency:  protectionGroup.SnapshotSchedule.Frequency / 1000,
+		})
+	}
+	return result, nil
+}
+
+// GetProtectionGroupSnapshots returns all protection group snapshots along with their replication transfer statistics
+func (client *ClientV2) GetProtectionGroupSnapshots() ([]*ProtectionGroupSnapshotResponse, error) {
+	var snapshots []*ProtectionGroupSnapshotResponseV2
+	err := client.getItems(ProtectionGroupSnapshotsEndpointV2, url.Values{"destroyed": {"false"}}, &snapshots)
+	if err != nil {
+		return nil, err
+	}
+	var transfers []*ProtectionGroupSnapshotTransferResponseV2
+	err = client.getItems(ProtectionGroupSnapshotsTransferEndpoint, url.Values{"destroyed": {"false"}}, &transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	transfersMap := make(map[string]*ProtectionGroupSnapshotTransferResponseV2)
+	for _, transfer := range transfers {
+		transfersMap[transfer.Name] = transfer
+	}
+	var result []*ProtectionGroupSnapshotResponse
+	for _, snapshot := range snapshots {
+		snapshotResponse := &ProtectionGroupSnapshotResponse{
+			Created: formatFlashArrayTime(snapshot.Created),
+			Name:    snapshot.Name,
+			Source:  snapshot.Source.Name,
+		}
+		if transfer, ok := transfersMap[snapshot.Name]; ok {
+			snapshotResponse.Completed = formatFlashArrayTime(transfer.Completed)
+			snapshotResponse.DataTransferred = transfer.DataTransferred
+			snapshotResponse.PhysicalBytesWritten = transfer.PhysicalBytesWritten
+			snapshotResponse.Progress = transfer.Progress
+			snapshotResponse.Started = formatFlashArrayTime(transfer.Started)
+		}
+		result = append(result, snapshotResponse)
+	}
+	return result, nil
+}
+
+// GetSessions returns the logins, logouts and failed authentications on the array at or after the given time
+// (Unix seconds)
+func (client *ClientV2) GetSessions(since int64) ([]*SessionResponse, error) {
+	params := url.Values{"filter": {fmt.Sprintf("start_time>=%d", since*1000)}}
+	var sessions []*SessionResponseV2
+	err := client.getItems(SessionsEndpointV2, params, &sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*SessionResponse
+	for _, session := range sessions {
+		result = append(result, &SessionResponse{
+			Event:         session.Event,
+			ID:            parseRecordNumberV2(session.Name),
+			Location:      session.Location,
+			Method:        session.Method,
+			Opened:        formatFlashArrayTime(session.StartTime),
+			User:          session.User,
+			UserInterface: session.UserInterface,
+		})
+	}
+	return result, nil
+}
+
+// GetVolumeCapacityMetrics returns the capacity metrics for all volumes
+func (client *ClientV2) GetVolumeCapacityMetrics() ([]*VolumeCapacityMetricsResponse, error) {
+	var volumes []*VolumeResponseV2
+	err := client.getItems(VolumesEndpointV2, url.Values{"destroyed": {"false"}}, &volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*VolumeCapacityMetricsResponse
+	for _, volume := range volumes {
+		result = append(result, &VolumeCapacityMetricsResponse{
+			DataReduction:  volume.Space.DataReduction,
+			Name:           volume.Name,
+			Size:           volume.Provisioned,
+			TotalReduction: volume.Space.TotalReduction,
+		})
+	}
+	return result, nil
+}
+
+// GetVolumeConnections returns every connection between a volume and a host, either private
+// or shared through a host group
+func (client *ClientV2) GetVolumeConnections() ([]*VolumeConnectionResponse, error) {
+	var connections []*ConnectionResponseV2
+	err := client.getItems(ConnectionsEndpointV2, nil, &connections)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*VolumeConnectionResponse
+	for _, connection := range connections {
+		result = append(result, &VolumeConnectionResponse{
+			Host:      connection.Host.Name,
+			HostGroup: connection.HostGroup.Name,
+			LUN:       connection.LUN,
+			Name:      connection.Volume.Name,
+		})
+	}
+	return result, nil
+}
+
+// GetVolumeCount returns the count of volumes on the array (without getting all of the volumes)
+func (client *ClientV2) GetVolumeCount() (uint32, error) {
+	return client.getResourceCount(VolumesEndpointV2, url.Values{"destroyed": {"false"}})
+}
+
+// GetVolumePerformanceMetrics returns the performance metrics for all volumes
+func (client *ClientV2) GetVolumePerformanceMetrics() ([]*VolumePerformanceMetricsResponse, error) {
+	var performance []*PerformanceResponseV2
+	err := client.getItems(VolumesPerformanceEndpointV2, url.Values{"destroyed": {"false"}}, &performance)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*VolumePerformanceMetricsResponse
+	for _, metric := range performance {
+		result = append(result, &VolumePerformanceMetricsResponse{
+			InputPerSec:  uint64(metric.WriteBytesPerSec),
+			OutputPerSec: uint64(metric.ReadBytesPerSec),
+			Name:         metric.Name,
+			ReadLatency:  uint64(metric.UsecPerReadOp),
+			ReadsPerSec:  uint64(metric.ReadsPerSec),
+			WriteLatency: uint64(metric.UsecPerWriteOp),
+			WritesPerSec: uint64(metric.WritesPerSec),
+		})
+	}
+	return result, nil
+}
+
+// GetVolumePendingEradicationCount returns the count of volumes that are pending eradication
+func (client *ClientV2) GetVolumePendingEradicationCount() (uint32, error) {
+	return client.getResourceCount(VolumesEndpointV2, url.Values{"destroyed": {"true"}})
+}
+
+// GetVolumeSnapshotCount returns the count of volume snapshots on the array (without getting all of the snapshots)
+func (client *ClientV2) GetVolumeSnapshotCount() (uint32, error) {
+	return client.getResourceCount(VolumeSnapshotsEndpointV2, url.Values{"destroyed": {"false"}})
+}
+
+// GetVolumeSnapshots returns volume snapshots
+func (client *ClientV2) GetVolumeSnapshots() ([]*VolumeSnapshotResponse, error) {
+	var snapshots []*VolumeSnapshotResponseV2
+	err := client.getItems(VolumeSnapshotsEndpointV2, url.Values{"destroyed": {"false"}}, &snapshots)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*VolumeSnapshotResponse
+	for _, snapshot := range snapshots {
+		result = append(result, &VolumeSnapshotResponse{
+			Created: formatFlashArrayTime(snapshot.Created),
+			Name:    snapshot.Name,
+			Size:    snapshot.Provisioned,
+			Source:  snapshot.Source.Name,
+		})
+	}
+	return result, nil
+}
+
+// createFullURL is a helper function that returns a URL for the specified endpoint and query parameters with the
+// management endpoint and API version
+func (client *ClientV2) createFullURL(endpoint string, params url.Values) string {
+	fullURL := fmt.Sprintf("https://%s%s/%s%s", util.EndpointURLHost(client.ManagementEndpoint), APIPrefix, client.APIVersion, endpoint)
+	if len(params) > 0 {
+		fullURL = fmt.Sprintf("%s?%s", fullURL, params.Encode())
+	}
+	return fullURL
+}
+
+// createAPIClientJWT is a helper function that creates the signed JWT identifying the API client (and the user it acts
+// as), which the array exchanges for an access token
+func (client *ClientV2) createAPIClientJWT(now time.Time) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
+		Audience:  client.APIClient.ClientID,
+		ExpiresAt: now.Add(APIClientJWTLifetime).Unix(),
+		IssuedAt:  now.Unix(),
+		Issuer:    client.APIClient.Issuer,
+		Subject:   client.APIClient.Username,
+	})
+	token.Header["kid"] = client.APIClient.KeyID
+	return token.SignedString(client.privateKey)
+}
+
+// getHostPerformanceMetrics is a helper function that returns performance metrics for the specified
+// host or host group performance endpoint
+func (client *ClientV2) getHostPerformanceMetrics(performanceEndpoint string) ([]*HostPerformanceMetricsResponse, error) {
+	var performance []*PerformanceResponseV2
+	err := client.getItems(performanceEndpoint, nil, &performance)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*HostPerformanceMetricsResponse
+	for _, metric := range performance {
+		result = append(result, &HostPerformanceMetricsResponse{
+			InputPerSec:  uint64(metric.WriteBytesPerSec),
+			OutputPerSec: uint64(metric.ReadBytesPerSec),
+			Name:         metric.Name,
+			ReadLatency:  uint64(metric.UsecPerReadOp),
+			ReadsPerSec:  uint64(metric.ReadsPerSec),
+			WriteLatency: uint64(metric.UsecPerWriteOp),
+			WritesPerSec: uint64(metric.WritesPerSec),
+		})
+	}
+	return result, nil
+}
+
+// getItems is a helper function that gets every item from the specified list endpoint, following continuation tokens,
+// and unmarshals them into result (a pointer to a slice)
+func (client *ClientV2) getItems(endpoint string, params url.Values, result interface{}) error {
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+
+	var items []json.RawMessage
+	for {
+		response, _, err := client.performGet(client.createFullURL(endpoint, query), ItemsResponseV2{})
+		if err != nil {
+			return err
+		}
+		page := response.(*ItemsResponseV2)
+		items = append(items, page.Items...)
+
+		// An array handing back the same token again would otherwise keep this going forever
+		if page.ContinuationToken == "" || page.ContinuationToken == query.Get("continuation_token") {
+			break
+		}
+		query.Set("continuation_token", page.ContinuationToken)
+	}
+
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, result)
+}
+
+// getResourceCount is a helper function that returns the number of resources where we don't need
+// the actual items by only asking for the total item count
+func (client *ClientV2) getResourceCount(endpoint string, params url.Values) (uint32, error) {
+	query := url.Values{"limit": {"1"}, "total_item_count": {"true"}}
+	for key, values := range params {
+		query[key] = values
+	}
+
+	response, _, err := client.performGet(client.createFullURL(endpoint, query), ItemsResponseV2{})
+	if err != nil {
+		return 0, err
+	}
+	return response.(*ItemsResponseV2).TotalItemCount, nil
+}
+
+// performGet is a helper function that encapsulates exit and retry cases for GET requests
+// Returns the unmarshalled response data, response headers, and error
+func (client *ClientV2) performGet(url string, result interface{}) (interface{}, http.Header, error) {
+	// Each request can be retried multiple times
+	for i := 0; i < RequestAttemptCount; i++ {
+		log.WithFields(log.Fields{
+			"display_name": client.DisplayName,
+			"url":          url,
+		}).Trace("Making GET request")
+		authHeaders, session := client.currentSession()
+		response, err := client.restClient.R().SetHeader(UserAgentHeader, UserAgent).SetHeaders(authHeaders).SetResult(result).Get(url)
+
+		// If there was a client error we quit
+		if err != nil {
+			log.WithFields(log.Fields{
+				"display_name": client.DisplayName,
+				"error":        err,
+				"url":          url,
+			}).Error("Client error with GET request")
+			return nil, nil, err
+		}
+
+		// Cases where we try again
+		if response.StatusCode() == 401 {
+			log.WithFields(log.Fields{
+				"display_name": client.DisplayName,
+				"status_code":  response.StatusCode(),
+				"url":          url,
+			}).Trace("Session expired; refreshing session and retrying")
+			client.refreshExpiredSession(session)
+			continue
+		}
+		if response.StatusCode() == 500 {
+			log.WithFields(log.Fields{
+				"display_name": client.DisplayName,
+				"status_code":  response.StatusCode(),
+				"url":          url,
+			}).Warn("Array internal server error; waiting 500ms and retrying")
+			time.Sleep(500 * time.Millisecond)
+			continue
+		}
+
+		// Cases where we return
+		if response.StatusCode() == 200 {
+			return response.Result(), response.Header(), nil
+		}
+	}
+	// Log we failed
+	log.WithFields(log.Fields{
+		"display_name": client.DisplayName,
+		"url":          url,
+	}).Error("No successful GET request")
+	return nil, nil, errors.New("No successful GET request")
+}
+
+// currentSession is a helper function that returns the headers to authenticate requests with, along with the
+// number of the session they belong to
+func (client *ClientV2) currentSession() (map[string]string, int) {
+	client.sessionLock.RLock()
+	defer client.sessionLock.RUnlock()
+	return client.authHeaders, client.session
+}
+
+// refreshExpiredSession is a helper function that authenticates again if a request was rejected with the current
+// session, unless another request (sharing this client) already did
+func (client *ClientV2) refreshExpiredSession(expired int) {
+	client.sessionLock.Lock()
+	defer client.sessionLock.Unlock()
+	if client.session != expired {
+		return
+	}
+	if client.refreshSession() == nil {
+		client.session++
+	}
+}
+
+// refreshSession is a helper function that authenticates again: through an OAuth2 token exchange if the client
+// has an API client, otherwise by logging in with the API token
+func (client *ClientV2) refreshSession() error {
+	if client.APIClient != nil {
+		return client.exchangeAPIClientToken()
+	}
+	return client.login()
+}
+
+// exchangeAPIClientToken is a helper function that makes a POST request to exchange a freshly signed API client JWT
+// for an access token
+func (client *ClientV2) exchangeAPIClientToken() error {
+	assertion, err := client.createAPIClientJWT(time.Now())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"display_name": client.DisplayName,
+			"error":        err,
+		}).Error("Error signing API client JWT")
+		return err
+	}
+
+	url := fmt.Sprintf("https://%s%s", util.EndpointURLHost(client.ManagementEndpoint), OAuth2TokenEndpoint)
+	log.WithFields(log.Fields{
+		"display_name": client.DisplayName,
+		"url":          url,
+	}).Trace("Making POST request to exchange API client token")
+	response, err := client.restClient.R().SetHeader(UserAgentHeader, UserAgent).SetFormData(map[string]string{
+		"grant_type":         OAuth2TokenExchangeGrantType,
+		"subject_token":      assertion,
+		"subject_token_type": OAuth2JWTTokenType,
+	}).SetResult(OAuth2TokenResponse{}).Post(url)
+
+	// Verify the request was successful
+	if err != nil {
+		log.WithFields(log.Fields{
+			"display_name": client.DisplayName,
+			"error":        err,
+			"url":          url,
+		}).Error("Error making token exchange request")
+		return err
+	}
+	if response.StatusCode() != 200 {
+		log.WithFields(log.Fields{
+			"display_name": client.DisplayName,
+			"status_code":  response.StatusCode(),
+			"url":          url,
+		}).Error("Could not exchange API client token")
+		return errors.New("Could not exchange API client token")
+	}
+
+	result := response.Result().(*OAuth2TokenResponse)
+	client.authHeaders = map[string]string{AuthorizationHeader: fmt.Sprintf("Bearer %s", result.AccessToken)}
+	return nil
+}
+
+// login is a helper function that makes a POST request to start a new session with the API token
+func (client *ClientV2) login() error {
+	url := client.createFullURL(LoginEndpointV2, nil)
+	log.WithFields(log.Fields{
+		"display_name": client.DisplayName,
+		"url":          url,
+	}).Trace("Making POST request to start session")
+	response, err := client.restClient.R().SetHeader(UserAgentHeader, UserAgent).SetHeader(APITokenHeader, client.APIToken).Post(url)
+
+	// Verify the request was successful
+	if err != nil {
+		log.WithFields(log.Fields{
+			"display_name": client.DisplayName,
+			"error":        err,
+			"url":          url,
+		}).Error("Error making login request")
+		return err
+	}
+	if response.StatusCode() != 200 || response.Header().Get(AuthTokenHeader) == "" {
+		log.WithFields(log.Fields{
+			"display_name": client.DisplayName,
+			"status_code":  response.StatusCode(),
+			"url":          url,
+		}).Error("Could not start new session")
+		return errors.New("Could not start new session")
+	}
+
+	client.authHeaders = map[string]string{AuthTokenHeader: response.Header().Get(AuthTokenHeader)}
+	return nil
+}
+
+// convertAlertResponseV2 is a helper function that converts a REST 2.x alert into its REST 1.x form
+func convertAlertResponseV2(alert *AlertResponseV2) *AlertResponse {
+	response := &AlertResponse{
+		Actual:          alert.Actual,
+		Category:        alert.Category,
+		Code:            alert.Code,
+		ComponentName:   alert.ComponentName,
+		ComponentType:   alert.ComponentType,
+		CurrentSeverity: alert.Severity,
+		Details:         alert.Description,
+		Event:           alert.Summary,
+		Expected:        alert.Expected,
+		ID:              parseRecordNumberV2(alert.Name), // Alerts are numbered, but the number is only given as the name
+		Opened:          formatFlashArrayTime(alert.Created),
+	}
+	if alert.State == "closed" {
+		// Closed alerts need a closed time to be recognized as closed
+		response.Closed = formatFlashArrayTime(alert.Closed)
+		if response.Closed == "" {
+			response.Closed = response.Opened
+		}
+	}
+	return response
+}
+
+// convertArrayPerformanceResponseV2 is a helper function that converts a REST 2.x array performance metric into its
+// REST 1.x form
+func convertArrayPerformanceResponseV2(metric *PerformanceResponseV2) *ArrayPerformanceMetricsResponse {
+	return &ArrayPerformanceMetricsResponse{
+		BytesPerRead:  uint64(metric.BytesPerRead),
+		BytesPerWrite: uint64(metric.BytesPerWrite),
+		BytesPerOp:    uint64(metric.BytesPerOp),
+		InputPerSec:   uint64(metric.WriteBytesPerSec),
+		OutputPerSec:  uint64(metric.ReadBytesPerSec),
+		QueueDepth:    uint16(metric.QueueDepth),
+		ReadLatency:   uint64(metric.UsecPerReadOp),
+		ReadsPerSec:   uint64(metric.ReadsPerSec),
+		Time:          formatFlashArrayTime(metric.Time),
+		WriteLatency:  uint64(metric.UsecPerWriteOp),
+		WritesPerSec:  uint64(metric.WritesPerSec),
+	}
+}
+
+// parseRecordNumberV2 is a helper function that returns the number of a REST 2.x record (such as an alert or an
+// audit record) from its name, or a hash of the name if it isn't a number
+func parseRecordNumberV2(name string) uint64 {
+	number, err := strconv.ParseUint(name, 10, 64)
+	if err != nil {
+		hash := fnv.New64a()
+		hash.Write([]byte(name))
+		number = hash.Sum64()
+	}
+	return number
+}
+
+// formatFlashArrayTime is a helper function that formats a REST 2.x time (milliseconds since epoch) the way REST 1.x
+// does ("2006-01-02T15:04:05Z"), or returns an empty string if it isn't set
+func formatFlashArrayTime(milliseconds int64) string {
+	if milliseconds <= 0 {
+		return ""
+	}
+	return time.Unix(0, milliseconds*int64(time.Millisecond)).UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// groupMembers is a helper function that maps the name of each group to the names of its members
+func groupMembers(members []*MemberResponseV2) map[string][]string {
+	membersMap := make(map[string][]string)
+	for _, member := range members {
+		membersMap[member.Group.Name] = append(membersMap[member.Group.Name], member.Member.Name)
+	}
+	return membersMap
+}
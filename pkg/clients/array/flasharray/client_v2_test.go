@@ -0,0 +1,144 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flasharray
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChooseAPIVersionPreferredV2(t *testing.T) {
+	version, preferred, err := chooseAPIVersion([]string{"1.6", "1.7", "1.19", "2.0", "2.4", "2.5"}, false)
+	assert.NoError(t, err)
+	assert.True(t, preferred)
+	assert.Equal(t, PreferredAPIVersionV2, version)
+}
+
+func TestChooseAPIVersionPreferredV1(t *testing.T) {
+	version, preferred, err := chooseAPIVersion([]string{"1.6", "1.7", "1.19", "2.0", "2.1"}, false)
+	assert.NoError(t, err)
+	assert.True(t, preferred)
+	assert.Equal(t, PreferredAPIVersion, version)
+}
+
+func TestChooseAPIVersionRequireV2(t *testing.T) {
+	version, preferred, err := chooseAPIVersion([]string{"1.6", "1.7", "1.19", "2.0", "2.1"}, true)
+	assert.NoError(t, err)
+	assert.False(t, preferred)
+	assert.Equal(t, "2.1", version)
+}
+
+func TestChooseAPIVersionRequireV2Unsupported(t *testing.T) {
+	_, _, err := chooseAPIVersion([]string{"1.6", "1.7", "1.19"}, true)
+	assert.Error(t, err)
+}
+
+func TestChooseAPIVersionLatestV1(t *testing.T) {
+	version, preferred, err := chooseAPIVersion([]string{"1.8", "1.19", "2.1"}, false)
+	assert.NoError(t, err)
+	assert.False(t, preferred)
+	assert.Equal(t, "1.19", version)
+}
+
+func TestChooseAPIVersionNone(t *testing.T) {
+	_, _, err := chooseAPIVersion([]string{}, false)
+	assert.Error(t, err)
+}
+
+func TestClientV2CreateAPIClientJWT(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	client := &ClientV2{
+		APIClient: &resources.APIClientCredentials{
+			ClientID: "3f1a2b7c-client",
+			KeyID:    "9d8e7f6a-key",
+			Issuer:   "pure1-unplugged",
+			Username: "pureuser",
+		},
+		privateKey: privateKey,
+	}
+
+	now := time.Now()
+	signed, err := client.createAPIClientJWT(now)
+	assert.NoError(t, err)
+
+	claims := jwt.StandardClaims{}
+	token, err := jwt.ParseWithClaims(signed, &claims, func(token *jwt.Token) (interface{}, error) {
+		return &privateKey.PublicKey, nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, token.Valid)
+	assert.Equal(t, jwt.SigningMethodRS256.Alg(), token.Header["alg"])
+	assert.Equal(t, "9d8e7f6a-key", token.Header["kid"])
+	assert.Equal(t, "3f1a2b7c-client", claims.Audience)
+	assert.Equal(t, "pure1-unplugged", claims.Issuer)
+	assert.Equal(t, "pureuser", claims.Subject)
+	assert.Equal(t, now.Add(APIClientJWTLifetime).Unix(), claims.ExpiresAt)
+}
+
+func TestFormatFlashArrayTime(t *testing.T) {
+	assert.Equal(t, "2019-03-14T21:30:12Z", formatFlashArrayTime(1552599012345))
+	assert.Equal(t, "", formatFlashArrayTime(0))
+}
+
+func TestConvertAlertResponseV2(t *testing.T) {
+	alert := convertAlertResponseV2(&AlertResponseV2{
+		Code:          42,
+		ComponentName: "ct0.eth0",
+		Created:       1552599012000,
+		Description:   "Interface ct0.eth0 is down",
+		Flagged:       true,
+		Name:          "12345",
+		Severity:      "critical",
+		State:         "open",
+		Summary:       "Interface down",
+	})
+	assert.Equal(t, uint64(12345), alert.ID)
+	assert.Equal(t, "critical", alert.CurrentSeverity)
+	assert.Equal(t, "Interface down", alert.Event)
+	assert.Equal(t, "Interface ct0.eth0 is down", alert.Details)
+	assert.Equal(t, "2019-03-14T21:30:12Z", alert.Opened)
+	assert.Equal(t, "", alert.Closed)
+}
+
+func TestConvertAlertResponseV2Closed(t *testing.T) {
+	alert := convertAlertResponseV2(&AlertResponseV2{
+		Created: 1552599012000,
+		Name:    "12346",
+		State:   "closed",
+	})
+	assert.Equal(t, "2019-03-14T21:30:12Z", alert.Closed)
+
+	alert = convertAlertResponseV2(&AlertResponseV2{
+		Closed:  1552602612000,
+		Created: 1552599012000,
+		Name:    "12347",
+		State:   "closed",
+	})
+	assert.Equal(t, "2019-03-14T22:30:12Z", alert.Closed)
+}
+
+func TestConvertAlertResponseV2NonNumericName(t *testing.T) {
+	first := convertAlertResponseV2(&AlertResponseV2{Name: "alert-a"})
+	second := convertAlertResponseV2(&AlertResponseV2{Name: "alert-b"})
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, first.ID, convertAlertResponseV2(&AlertResponseV2{Name: "alert-a"}).ID)
+}
@@ -15,7 +15,11 @@
 package flasharray
 
 import (
+	"crypto/tls"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common"
@@ -26,11 +30,11 @@ import (
 )
 
 // NewCollector creates both a new array collector and its underlying array client
-func NewCollector(arrayID string, displayName string, managementEndpoint string, apiToken string, metaConnection resources.ArrayMetadata) (resources.ArrayCollector, error) {
+func NewCollector(arrayID string, displayName string, managementEndpoints []string, apiToken string, apiClient *resources.APIClientCredentials, tlsConfig *tls.Config, metaConnection resources.ArrayMetadata) (resources.ArrayCollector, error) {
 	timer := timing.NewStageTimer("flasharray.NewCollector", log.Fields{"display_name": displayName})
 	defer timer.Finish()
 
-	arrayClient, err := NewClient(displayName, managementEndpoint, apiToken)
+	arrayClient, err := NewClient(displayName, managementEndpoints, apiToken, apiClient, tlsConfig)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"display_name": displayName,
@@ -43,13 +47,14 @@ func NewCollector(arrayID string, displayName string, managementEndpoint string,
 		ArrayType:      common.FlashArray,
 		Client:         arrayClient,
 		DisplayName:    displayName,
-		MgmtEndpoint:   managementEndpoint,
+		MgmtEndpoint:   arrayClient.GetManagementEndpoint(),
 		metaConnection: metaConnection,
 	}
 
 	log.WithFields(log.Fields{
 		"array_type":   collector.ArrayType,
 		"display_name": collector.DisplayName,
+		"endpoint":     collector.MgmtEndpoint,
 	}).Info("Successfully created FlashArray Collector")
 	return &collector, nil
 }
@@ -113,11 +118,11 @@ func (collector *Collector) GetAllArrayData() (*metrics.AllArrayData, error) {
 	// Add the timeline alerts to the map
 	for _, response := range alertResponseBundle.TimelineResponse {
 		alert := convertAlertsResponse(response, collector.ArrayID, arrayInfo.ArrayName, collector.DisplayName, collector.MgmtEndpoint, false)
-		alertsMap[string(alert.AlertID)] = alert
+		alertsMap[strconv.FormatUint(alert.AlertID, 10)] = alert
 	}
 	// Mark the flagged alerts as flagged and leave the rest unflagged
 	for _, response := range alertResponseBundle.FlaggedResponse {
-		alertsMap[string(response.ID)].Flagged = true
+		alertsMap[strconv.FormatUint(response.ID, 10)].Flagged = true
 	}
 	// Convert the map to a list of values
 	var combinedAlerts []*metrics.Alert
@@ -238,7 +243,7 @@ func (collector *Collector) GetAllVolumeData(timeWindow int64) (*metrics.AllVolu
 			ArrayName:               arrayInfo.ArrayName,
 			ArrayTags:               arrayTags,
 			CreatedAt:               creationTime,
-			Type:                    "Volume",
+			Type:                    metrics.VolumeMetricTypeVolume,
 			VolumeName:              response.Name,
 		}
 		combinedVolumeMetrics = append(combinedVolumeMetrics, volumeMetric)
@@ -249,6 +254,495 @@ func (collector *Collector) GetAllVolumeData(timeWindow int64) (*metrics.AllVolu
 	}, nil
 }
 
+// Type guard: ensure this implements the interface
+var _ resources.HostCollector = (*Collector)(nil)
+
+// GetAllHostData makes multiple underlying requests to get inventory and performance data for hosts and host groups
+func (collector *Collector) GetAllHostData() (*metrics.AllHostData, error) {
+	log.WithFields(log.Fields{
+		"display_name": collector.DisplayName,
+	}).Trace("Getting all host data")
+	timer := timing.NewStageTimer("flasharray.Collector.GetAllHostData", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	// Get the basic array info
+	arrayInfo, err := collector.Client.GetArrayInfo()
+	if err != nil {
+		return nil, err // Can't mark the data without the array name
+	}
+
+	responseBundle := collector.fetchHosts()
+
+	// Fetch the array tags
+	arrayTags, err := collector.GetArrayTags()
+	if err != nil {
+		arrayTags = map[string]string{}
+	}
+
+	timer.Stage("parse_responses")
+
+	// Record the current time for the metrics
+	creationTime := time.Now().Unix()
+
+	// Map the personalities and performance metrics to their hosts and host groups
+	personalityMap := make(map[string]string)
+	for _, response := range responseBundle.PersonalitiesResponse {
+		personalityMap[response.Name] = response.Personality
+	}
+	hostPerformanceMap := make(map[string]*metrics.HostPerformanceMetric)
+	for _, response := range responseBundle.HostPerformanceMetricsResponse {
+		hostPerformanceMap[response.Name] = convertHostPerformanceMetricsResponse(response)
+	}
+	hostGroupPerformanceMap := make(map[string]*metrics.HostPerformanceMetric)
+	for _, response := range responseBundle.HostGroupPerformanceMetricsResponse {
+		hostGroupPerformanceMap[response.Name] = convertHostPerformanceMetricsResponse(response)
+	}
+
+	// Map the connected volumes to their hosts and host groups (a volume shared through a host group
+	// is listed once for every host in the group)
+	hostVolumesMap := make(map[string]map[string]struct{})
+	hostGroupVolumesMap := make(map[string]map[string]struct{})
+	for _, connection := range responseBundle.ConnectionsResponse {
+		if len(connection.Host) > 0 {
+			addConnectedVolume(hostVolumesMap, connection.Host, connection.Name)
+		}
+		if len(connection.HostGroup) > 0 {
+			addConnectedVolume(hostGroupVolumesMap, connection.HostGroup, connection.Name)
+		}
+	}
+
+	var hostMetrics []*metrics.HostMetric
+	for _, response := range responseBundle.HostsResponse {
+		volumes := getConnectedVolumes(hostVolumesMap, response.Name)
+		hostMetrics = append(hostMetrics, &metrics.HostMetric{
+			HostPerformanceMetric: hostPerformanceMap[response.Name],
+			ArrayDisplayName:      collector.DisplayName,
+			ArrayID:               collector.ArrayID,
+			ArrayName:             arrayInfo.ArrayName,
+			ArrayTags:             arrayTags,
+			CreatedAt:             creationTime,
+			Type:                  metrics.HostMetricTypeHost,
+			HostName:              response.Name,
+			HostGroup:             response.HostGroup,
+			Personality:           personalityMap[response.Name],
+			WWNs:                  response.WWN,
+			IQNs:                  response.IQN,
+			Volumes:               volumes,
+			VolumeCount:           uint32(len(volumes)),
+		})
+	}
+	for _, response := range responseBundle.HostGroupsResponse {
+		volumes := getConnectedVolumes(hostGroupVolumesMap, response.Name)
+		hostMetrics = append(hostMetrics, &metrics.HostMetric{
+			HostPerformanceMetric: hostGroupPerformanceMap[response.Name],
+			ArrayDisplayName:      collector.DisplayName,
+			ArrayID:               collector.ArrayID,
+			ArrayName:             arrayInfo.ArrayName,
+			ArrayTags:             arrayTags,
+			CreatedAt:             creationTime,
+			Type:                  metrics.HostMetricTypeHostGroup,
+			HostName:              response.Name,
+			Hosts:                 response.Hosts,
+			Volumes:               volumes,
+			VolumeCount:           uint32(len(volumes)),
+		})
+	}
+
+	return &metrics.AllHostData{
+		HostMetrics: hostMetrics,
+	}, nil
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.ProtectionGroupCollector = (*Collector)(nil)
+
+// GetAllProtectionGroupData makes multiple underlying requests to get the schedules, snapshots and replication
+// state of all protection groups
+func (collector *Collector) GetAllProtectionGroupData() (*metrics.AllProtectionGroupData, error) {
+	log.WithFields(log.Fields{
+		"display_name": collector.DisplayName,
+	}).Trace("Getting all protection group data")
+	timer := timing.NewStageTimer("flasharray.Collector.GetAllProtectionGroupData", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	// Get the basic array info
+	arrayInfo, err := collector.Client.GetArrayInfo()
+	if err != nil {
+		return nil, err // Can't mark the data without the array name
+	}
+
+	responseBundle := collector.fetchProtectionGroups()
+
+	// Fetch the array tags
+	arrayTags, err := collector.GetArrayTags()
+	if err != nil {
+		arrayTags = map[string]string{}
+	}
+
+	timer.Stage("parse_responses")
+
+	// Record the current time for the metrics
+	creationTime := time.Now().Unix()
+
+	scheduleMap := make(map[string]*ProtectionGroupScheduleResponse)
+	for _, response := range responseBundle.SchedulesResponse {
+		scheduleMap[response.Name] = response
+	}
+	connectedMap := make(map[string]bool)
+	for _, response := range responseBundle.ConnectionsResponse {
+		connectedMap[response.ArrayName] = response.Connected
+	}
+	snapshotsMap := make(map[string][]*ProtectionGroupSnapshotResponse)
+	for _, response := range responseBundle.SnapshotsResponse {
+		snapshotsMap[response.Source] = append(snapshotsMap[response.Source], response)
+	}
+
+	var protectionGroupMetrics []*metrics.ProtectionGroupMetric
+	for _, response := range responseBundle.ProtectionGroupsResponse {
+		targets := []*metrics.ProtectionGroupTarget{}
+		for _, target := range response.Targets {
+			targets = append(targets, &metrics.ProtectionGroupTarget{
+				Name:      target.Name,
+				Allowed:   target.Allowed,
+				Connected: connectedMap[target.Name],
+			})
+		}
+		metric := &metrics.ProtectionGroupMetric{
+			ArrayDisplayName:    collector.DisplayName,
+			ArrayID:             collector.ArrayID,
+			ArrayName:           arrayInfo.ArrayName,
+			ArrayTags:           arrayTags,
+			CreatedAt:           creationTime,
+			ProtectionGroupName: response.Name,
+			Source:              response.Source,
+			Hosts:               response.Hosts,
+			HostGroups:          response.HostGroups,
+			Volumes:             response.Volumes,
+			Targets:             targets,
+		}
+		if schedule, ok := scheduleMap[response.Name]; ok {
+			metric.SnapshotEnabled = schedule.SnapshotEnabled
+			metric.SnapshotFrequency = schedule.SnapshotFrequency
+			metric.ReplicationEnabled = schedule.ReplicateEnabled
+			metric.ReplicationFrequency = schedule.ReplicateFrequency
+		}
+		populateProtectionGroupSnapshots(metric, snapshotsMap[response.Name])
+		protectionGroupMetrics = append(protectionGroupMetrics, metric)
+	}
+
+	return &metrics.AllProtectionGroupData{
+		ProtectionGroupMetrics: protectionGroupMetrics,
+	}, nil
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.PodCollector = (*Collector)(nil)
+
+// GetAllPodData makes multiple underlying requests to get the ActiveCluster pods of the array, with the
+// state of each pod and its mediator on every member array
+func (collector *Collector) GetAllPodData() (*metrics.AllPodData, error) {
+	log.WithFields(log.Fields{
+		"display_name": collector.DisplayName,
+	}).Trace("Getting all pod data")
+	timer := timing.NewStageTimer("flasharray.Collector.GetAllPodData", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	// Get the basic array info
+	arrayInfo, err := collector.Client.GetArrayInfo()
+	if err != nil {
+		return nil, err // Can't mark the data without the array name
+	}
+
+	responseBundle := collector.fetchPods()
+
+	// Fetch the array tags
+	arrayTags, err := collector.GetArrayTags()
+	if err != nil {
+		arrayTags = map[string]string{}
+	}
+
+	timer.Stage("parse_responses")
+
+	// Record the current time for the metrics
+	creationTime := time.Now().Unix()
+
+	mediatorMap := make(map[string]*PodMediatorResponse)
+	for _, response := range responseBundle.MediatorsResponse {
+		mediatorMap[response.Name] = response
+	}
+
+	var podMetrics []*metrics.PodMetric
+	for _, response := range responseBundle.PodsResponse {
+		metric := &metrics.PodMetric{
+			ArrayDisplayName: collector.DisplayName,
+			ArrayID:          collector.ArrayID,
+			ArrayName:        arrayInfo.ArrayName,
+			ArrayTags:        arrayTags,
+			CreatedAt:        creationTime,
+			PurityID:         arrayInfo.ID,
+			PodName:          response.Name,
+			Source:           response.Source,
+			Members:          []*metrics.PodMember{},
+			MemberCount:      uint32(len(response.Arrays)),
+			Stretched:        len(response.Arrays) > 1,
+		}
+		if mediator, ok := mediatorMap[response.Name]; ok {
+			metric.Mediator = mediator.Mediator
+			metric.MediatorVersion = mediator.MediatorVersion
+		}
+		for _, member := range response.Arrays {
+			podMember := &metrics.PodMember{
+				ArrayID:        member.ArrayID,
+				ArrayName:      member.Name,
+				Status:         member.Status,
+				MediatorStatus: member.MediatorStatus,
+			}
+			if member.Status == metrics.PodStatusResyncing {
+				podMember.ResyncProgress = member.Progress
+			}
+			if member.Status == metrics.PodStatusOnline {
+				metric.OnlineMemberCount++
+			}
+			// This array is in the member list too, which is where its own view of the pod comes from
+			if member.ArrayID == arrayInfo.ID {
+				metric.Status = podMember.Status
+				metric.MediatorStatus = podMember.MediatorStatus
+				metric.ResyncProgress = podMember.ResyncProgress
+			}
+			metric.Members = append(metric.Members, podMember)
+		}
+		podMetrics = append(podMetrics, metric)
+	}
+
+	return &metrics.AllPodData{
+		PodMetrics: podMetrics,
+	}, nil
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.HardwareCollector = (*Collector)(nil)
+
+// GetAllHardwareData makes multiple underlying requests to get the status of every controller, drive, power supply,
+// fan and temperature sensor of the array
+func (collector *Collector) GetAllHardwareData() (*metrics.AllHardwareData, error) {
+	log.WithFields(log.Fields{
+		"display_name": collector.DisplayName,
+	}).Trace("Getting all hardware data")
+	timer := timing.NewStageTimer("flasharray.Collector.GetAllHardwareData", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	// Get the basic array info
+	arrayInfo, err := collector.Client.GetArrayInfo()
+	if err != nil {
+		return nil, err // Can't mark the data without the array name
+	}
+
+	responseBundle := collector.fetchHardware()
+
+	// Fetch the array tags
+	arrayTags, err := collector.GetArrayTags()
+	if err != nil {
+		arrayTags = map[string]string{}
+	}
+
+	timer.Stage("parse_responses")
+
+	// Record the current time for the metrics
+	creationTime := time.Now().Unix()
+
+	newMetric := func(name string, componentType string, status string, details string) *metrics.HardwareMetric {
+		return &metrics.HardwareMetric{
+			ArrayDisplayName: collector.DisplayName,
+			ArrayID:          collector.ArrayID,
+			ArrayName:        arrayInfo.ArrayName,
+			ArrayTags:        arrayTags,
+			CreatedAt:        creationTime,
+			ComponentName:    name,
+			ComponentType:    componentType,
+			Status:           status,
+			Healthy:          metrics.IsHealthyHardwareStatus(status),
+			Details:          details,
+		}
+	}
+
+	var hardwareMetrics []*metrics.HardwareMetric
+	for _, response := range responseBundle.ControllersResponse {
+		metric := newMetric(response.Name, metrics.HardwareComponentController, response.Status, "")
+		metric.Mode = response.Mode
+		metric.Model = response.Model
+		metric.Version = response.Version
+		hardwareMetrics = append(hardwareMetrics, metric)
+	}
+
+	for _, response := range responseBundle.DrivesResponse {
+		metric := newMetric(response.Name, metrics.HardwareComponentDrive, response.Status, response.Details)
+		metric.Capacity = response.Capacity
+		metric.DriveType = response.Type
+		metric.Protocol = response.Protocol
+		metric.LastFailure = parseFlashArrayTime(response.LastFailure)
+		metric.LastEvacuation = parseFlashArrayTime(response.LastEvacCompleted)
+		hardwareMetrics = append(hardwareMetrics, metric)
+	}
+
+	for _, response := range responseBundle.HardwareResponse {
+		componentType, ok := classifyHardwareComponent(response.Name)
+		if !ok {
+			continue
+		}
+		metric := newMetric(response.Name, componentType, response.Status, response.Details)
+		metric.Slot = response.Slot
+		metric.Speed = response.Speed
+		metric.Temperature = response.Temperature
+		hardwareMetrics = append(hardwareMetrics, metric)
+	}
+
+	return &metrics.AllHardwareData{
+		HardwareMetrics: hardwareMetrics,
+	}, nil
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.HistoryCollector = (*Collector)(nil)
+
+// GetArrayPerformanceHistory gets the performance metrics the array kept between the given times (Unix seconds),
+// oldest first, to fill in metrics that weren't collected. Only the performance part of the metrics is set.
+func (collector *Collector) GetArrayPerformanceHistory(start int64, end int64) ([]*metrics.ArrayMetric, error) {
+	log.WithFields(log.Fields{
+		"display_name": collector.DisplayName,
+		"end":          end,
+		"start":        start,
+	}).Trace("Getting array performance history")
+	timer := timing.NewStageTimer("flasharray.Collector.GetArrayPerformanceHistory", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	history, err := collector.Client.GetArrayPerformanceHistory(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Not fatal, just like for the collected metrics
+	arrayTags, err := collector.GetArrayTags()
+	if err != nil {
+		arrayTags = map[string]string{}
+	}
+
+	timer.Stage("parse_responses")
+
+	var arrayMetrics []*metrics.ArrayMetric
+	for _, response := range history {
+		arrayMetrics = append(arrayMetrics, &metrics.ArrayMetric{
+			ArrayPerformanceMetric: convertArrayPerformanceMetricsResponse(response),
+			ArrayID:                collector.ArrayID,
+			ArrayName:              collector.DisplayName,
+			ArrayType:              collector.ArrayType,
+			Backfilled:             true,
+			CreatedAt:              parseFlashArrayTime(response.Time),
+			DisplayName:            collector.DisplayName,
+			Tags:                   arrayTags,
+		})
+	}
+	return arrayMetrics, nil
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.AuditCollector = (*Collector)(nil)
+
+// GetAuditEvents gets the commands run on the array and the sessions opened on it at or after the given time
+// (Unix seconds). Both have to be fetched for either to be returned, since the latest event returned is where
+// the next collection picks up from.
+func (collector *Collector) GetAuditEvents(since int64) ([]*metrics.AuditEvent, error) {
+	log.WithFields(log.Fields{
+		"display_name": collector.DisplayName,
+		"since":        since,
+	}).Trace("Getting audit events")
+	timer := timing.NewStageTimer("flasharray.Collector.GetAuditEvents", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	// Get the basic array info
+	arrayInfo, err := collector.Client.GetArrayInfo()
+	if err != nil {
+		return nil, err // Can't mark the data without the array name
+	}
+
+	timer.Stage("GetAuditEvents")
+	auditResponses, err := collector.Client.GetAuditEvents(since)
+	if err != nil {
+		return nil, err
+	}
+
+	timer.Stage("GetSessions")
+	sessionResponses, err := collector.Client.GetSessions(since)
+	if err != nil {
+		return nil, err
+	}
+
+	timer.Stage("parse_responses")
+
+	// Record the current time for the events
+	creationTime := time.Now().Unix()
+
+	auditEvents := make([]*metrics.AuditEvent, 0, len(auditResponses)+len(sessionResponses))
+	for _, response := range auditResponses {
+		event := convertAuditResponse(response)
+		collector.populateAuditEvent(event, arrayInfo.ArrayName, creationTime)
+		auditEvents = append(auditEvents, event)
+	}
+	for _, response := range sessionResponses {
+		event := convertSessionResponse(response)
+		collector.populateAuditEvent(event, arrayInfo.ArrayName, creationTime)
+		auditEvents = append(auditEvents, event)
+	}
+	return auditEvents, nil
+}
+
+// populateAuditEvent is a helper function that marks the given audit event with the array it came from
+func (collector *Collector) populateAuditEvent(event *metrics.AuditEvent, arrayName string, creationTime int64) {
+	event.ArrayDisplayName = collector.DisplayName
+	event.ArrayID = collector.ArrayID
+	event.ArrayName = arrayName
+	event.ArrayType = collector.ArrayType
+	event.CollectedAt = creationTime
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.SnapshotCollector = (*Collector)(nil)
+
+// GetSnapshots gets every volume snapshot on the array, along with the protection group that took it (if any)
+func (collector *Collector) GetSnapshots() ([]*metrics.Snapshot, error) {
+	log.WithField("display_name", collector.DisplayName).Trace("Getting snapshot inventory")
+	timer := timing.NewStageTimer("flasharray.Collector.GetSnapshots", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	// Get the basic array info
+	arrayInfo, err := collector.Client.GetArrayInfo()
+	if err != nil {
+		return nil, err // Can't mark the data without the array name
+	}
+
+	timer.Stage("GetVolumeSnapshots")
+	snapshotResponses, err := collector.Client.GetVolumeSnapshots()
+	if err != nil {
+		return nil, err // A partial inventory would look like the missing snapshots were deleted
+	}
+
+	timer.Stage("parse_responses")
+
+	// Record the current time for the inventory
+	creationTime := time.Now().Unix()
+
+	snapshots := make([]*metrics.Snapshot, 0, len(snapshotResponses))
+	for _, response := range snapshotResponses {
+		snapshot := convertVolumeSnapshotResponse(response)
+		snapshot.ArrayDisplayName = collector.DisplayName
+		snapshot.ArrayID = collector.ArrayID
+		snapshot.ArrayName = arrayInfo.ArrayName
+		snapshot.ArrayType = collector.ArrayType
+		snapshot.CollectedAt = creationTime
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
 // GetArrayID returns the ID of the array
 func (collector *Collector) GetArrayID() string {
 	return collector.ArrayID
@@ -306,6 +800,11 @@ func (collector *Collector) GetDisplayName() string {
 	return collector.DisplayName
 }
 
+// GetManagementEndpoint returns the management endpoint the collector is connected through
+func (collector *Collector) GetManagementEndpoint() string {
+	return collector.MgmtEndpoint
+}
+
 // fetchAllAlerts is a helper function that makes requests for the various alert types and adds
 // a bundled response to the channel
 func (collector *Collector) fetchAllAlerts(alertsChan chan AlertResponseBundle) {
@@ -388,6 +887,165 @@ func (collector *Collector) fetchObjectCounts(itemCountChan chan ObjectCountResp
 	itemCountChan <- responseBundle
 }
 
+// fetchHosts is a helper function that makes requests for the host and host group inventory and performance
+// metrics and returns them bundled together
+func (collector *Collector) fetchHosts() HostResponseBundle {
+	timer := timing.NewStageTimer("flasharray.Collector.fetchHosts", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	timer.Stage("GetHosts")
+	hostsResponse, err := collector.Client.GetHosts()
+	if err != nil {
+		collector.logIncompleteData(err, "GetHosts")
+		hostsResponse = []*HostResponse{}
+	}
+
+	timer.Stage("GetHostPersonalities")
+	personalitiesResponse, err := collector.Client.GetHostPersonalities()
+	if err != nil {
+		collector.logIncompleteData(err, "GetHostPersonalities")
+		personalitiesResponse = []*HostPersonalityResponse{}
+	}
+
+	timer.Stage("GetHostGroups")
+	hostGroupsResponse, err := collector.Client.GetHostGroups()
+	if err != nil {
+		collector.logIncompleteData(err, "GetHostGroups")
+		hostGroupsResponse = []*HostGroupResponse{}
+	}
+
+	timer.Stage("GetVolumeConnections")
+	connectionsResponse, err := collector.Client.GetVolumeConnections()
+	if err != nil {
+		collector.logIncompleteData(err, "GetVolumeConnections")
+		connectionsResponse = []*VolumeConnectionResponse{}
+	}
+
+	timer.Stage("GetHostPerformanceMetrics")
+	hostPerformanceResponse, err := collector.Client.GetHostPerformanceMetrics()
+	if err != nil {
+		collector.logIncompleteData(err, "GetHostPerformanceMetrics")
+		hostPerformanceResponse = []*HostPerformanceMetricsResponse{}
+	}
+
+	timer.Stage("GetHostGroupPerformanceMetrics")
+	hostGroupPerformanceResponse, err := collector.Client.GetHostGroupPerformanceMetrics()
+	if err != nil {
+		collector.logIncompleteData(err, "GetHostGroupPerformanceMetrics")
+		hostGroupPerformanceResponse = []*HostPerformanceMetricsResponse{}
+	}
+
+	return HostResponseBundle{
+		ConnectionsResponse:                 connectionsResponse,
+		HostGroupPerformanceMetricsResponse: hostGroupPerformanceResponse,
+		HostGroupsResponse:                  hostGroupsResponse,
+		HostPerformanceMetricsResponse:      hostPerformanceResponse,
+		HostsResponse:                       hostsResponse,
+		PersonalitiesResponse:               personalitiesResponse,
+	}
+}
+
+// fetchProtectionGroups is a helper function that makes requests for the protection groups, their schedules and
+// snapshots, and the connected arrays, and returns them bundled together
+func (collector *Collector) fetchProtectionGroups() ProtectionGroupResponseBundle {
+	timer := timing.NewStageTimer("flasharray.Collector.fetchProtectionGroups", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	timer.Stage("GetProtectionGroups")
+	protectionGroupsResponse, err := collector.Client.GetProtectionGroups()
+	if err != nil {
+		collector.logIncompleteData(err, "GetProtectionGroups")
+		protectionGroupsResponse = []*ProtectionGroupResponse{}
+	}
+
+	timer.Stage("GetProtectionGroupSchedules")
+	schedulesResponse, err := collector.Client.GetProtectionGroupSchedules()
+	if err != nil {
+		collector.logIncompleteData(err, "GetProtectionGroupSchedules")
+		schedulesResponse = []*ProtectionGroupScheduleResponse{}
+	}
+
+	timer.Stage("GetProtectionGroupSnapshots")
+	snapshotsResponse, err := collector.Client.GetProtectionGroupSnapshots()
+	if err != nil {
+		collector.logIncompleteData(err, "GetProtectionGroupSnapshots")
+		snapshotsResponse = []*ProtectionGroupSnapshotResponse{}
+	}
+
+	timer.Stage("GetArrayConnections")
+	connectionsResponse, err := collector.Client.GetArrayConnections()
+	if err != nil {
+		collector.logIncompleteData(err, "GetArrayConnections")
+		connectionsResponse = []*ArrayConnectionResponse{}
+	}
+
+	return ProtectionGroupResponseBundle{
+		ConnectionsResponse:      connectionsResponse,
+		ProtectionGroupsResponse: protectionGroupsResponse,
+		SchedulesResponse:        schedulesResponse,
+		SnapshotsResponse:        snapshotsResponse,
+	}
+}
+
+// fetchPods is a helper function that makes requests for the pods and their mediators, and returns them bundled together
+func (collector *Collector) fetchPods() PodResponseBundle {
+	timer := timing.NewStageTimer("flasharray.Collector.fetchPods", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	timer.Stage("GetPods")
+	podsResponse, err := collector.Client.GetPods()
+	if err != nil {
+		collector.logIncompleteData(err, "GetPods")
+		podsResponse = []*PodResponse{}
+	}
+
+	timer.Stage("GetPodMediators")
+	mediatorsResponse, err := collector.Client.GetPodMediators()
+	if err != nil {
+		collector.logIncompleteData(err, "GetPodMediators")
+		mediatorsResponse = []*PodMediatorResponse{}
+	}
+
+	return PodResponseBundle{
+		MediatorsResponse: mediatorsResponse,
+		PodsResponse:      podsResponse,
+	}
+}
+
+// fetchHardware is a helper function that makes requests for the controllers, drives and other hardware components,
+// and returns them bundled together
+func (collector *Collector) fetchHardware() HardwareResponseBundle {
+	timer := timing.NewStageTimer("flasharray.Collector.fetchHardware", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	timer.Stage("GetControllers")
+	controllersResponse, err := collector.Client.GetControllers()
+	if err != nil {
+		collector.logIncompleteData(err, "GetControllers")
+		controllersResponse = []*ArrayControllersResponse{}
+	}
+
+	timer.Stage("GetDrives")
+	drivesResponse, err := collector.Client.GetDrives()
+	if err != nil {
+		collector.logIncompleteData(err, "GetDrives")
+		drivesResponse = []*DriveResponse{}
+	}
+
+	timer.Stage("GetHardware")
+	hardwareResponse, err := collector.Client.GetHardware()
+	if err != nil {
+		collector.logIncompleteData(err, "GetHardware")
+		hardwareResponse = []*HardwareResponse{}
+	}
+
+	return HardwareResponseBundle{
+		ControllersResponse: controllersResponse,
+		DrivesResponse:      drivesResponse,
+		HardwareResponse:    hardwareResponse,
+	}
+}
+
 // logIncompleteData is a helper function to log errors when data gathering failed at some stage
 func (collector *Collector) logIncompleteData(err error, subject string) {
 	log.WithFields(log.Fields{
@@ -424,6 +1082,7 @@ func convertAlertsResponse(response *AlertResponse, arrayID string, arrayName st
 		Severity:         response.CurrentSeverity,
 		State:            state,
 		Summary:          response.Event,
+		Source:           metrics.AlertSourceArray,
 	}
 	alert.PopulateSeverityIndex()
 	return alert
@@ -483,3 +1142,162 @@ func convertVolumePerformanceMetricsResponse(response *VolumePerformanceMetricsR
 		WriteLatency:   response.WriteLatency,
 	}
 }
+
+// ConvertHostPerformanceMetricsResponse converts a host or host group performance metric response into the desired resource
+func convertHostPerformanceMetricsResponse(response *HostPerformanceMetricsResponse) *metrics.HostPerformanceMetric {
+	return &metrics.HostPerformanceMetric{
+		ReadBandwidth:  response.OutputPerSec,
+		ReadIOPS:       response.ReadsPerSec,
+		ReadLatency:    response.ReadLatency,
+		WriteBandwidth: response.InputPerSec,
+		WriteIOPS:      response.WritesPerSec,
+		WriteLatency:   response.WriteLatency,
+	}
+}
+
+// addConnectedVolume is a helper function that records a volume as connected to the given host or host group
+func addConnectedVolume(volumesMap map[string]map[string]struct{}, name string, volume string) {
+	if _, ok := volumesMap[name]; !ok {
+		volumesMap[name] = make(map[string]struct{})
+	}
+	volumesMap[name][volume] = struct{}{}
+}
+
+// getConnectedVolumes is a helper function that returns the sorted volumes connected to the given host or host group
+func getConnectedVolumes(volumesMap map[string]map[string]struct{}, name string) []string {
+	volumes := []string{}
+	for volume := range volumesMap[name] {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+	return volumes
+}
+
+// populateProtectionGroupSnapshots is a helper function that fills in the snapshot and replication state of a protection
+// group metric from the snapshots of the group
+func populateProtectionGroupSnapshots(metric *metrics.ProtectionGroupMetric, snapshots []*ProtectionGroupSnapshotResponse) {
+	var lastReplicated *ProtectionGroupSnapshotResponse
+	var lastReplicatedCreated int64
+	var lastInProgressCreated int64
+	for _, snapshot := range snapshots {
+		metric.SnapshotCount++
+		created := parseFlashArrayTime(snapshot.Created)
+		if created > metric.LastSnapshot {
+			metric.LastSnapshot = created
+		}
+		if len(snapshot.Completed) > 0 {
+			if lastReplicated == nil || created > lastReplicatedCreated {
+				lastReplicated = snapshot
+				lastReplicatedCreated = created
+			}
+		} else if len(snapshot.Started) > 0 && created > lastInProgressCreated {
+			// Still being replicated
+			lastInProgressCreated = created
+			metric.TransferProgress = snapshot.Progress
+		}
+	}
+
+	if lastReplicated != nil {
+		metric.LastReplication = parseFlashArrayTime(lastReplicated.Completed)
+		metric.ReplicationLag = metric.CreatedAt - lastReplicatedCreated
+		if started := parseFlashArrayTime(lastReplicated.Started); started > 0 {
+			metric.LastTransferDuration = metric.LastReplication - started
+		}
+		metric.LastTransferDataBytes = lastReplicated.DataTransferred
+		metric.LastTransferPhysicalBytes = lastReplicated.PhysicalBytesWritten
+	}
+}
+
+// convertAuditResponse is a helper function that converts an audit record into an audit event, without the
+// array it came from
+func convertAuditResponse(response *AuditResponse) *metrics.AuditEvent {
+	return &metrics.AuditEvent{
+		Arguments:  response.Arguments,
+		Command:    response.Command,
+		EventID:    strconv.FormatUint(response.ID, 10),
+		Interface:  response.UserInterface,
+		Kind:       metrics.AuditEventCommand,
+		Subcommand: response.Subcommand,
+		Time:       parseFlashArrayTime(response.Opened),
+		User:       response.User,
+	}
+}
+
+// convertSessionResponse is a helper function that converts a session record into an audit event, without the
+// array it came from
+func convertSessionResponse(response *SessionResponse) *metrics.AuditEvent {
+	return &metrics.AuditEvent{
+		Event:     response.Event,
+		EventID:   strconv.FormatUint(response.ID, 10),
+		Interface: response.UserInterface,
+		Kind:      metrics.AuditEventSession,
+		Location:  response.Location,
+		Method:    response.Method,
+		Time:      parseFlashArrayTime(response.Opened),
+		User:      response.User,
+	}
+}
+
+// convertVolumeSnapshotResponse is a helper function that converts a volume snapshot into a snapshot, without the
+// array it came from. The protection group and suffix only come in the snapshot name, which is "volume.suffix"
+// for snapshots of the volume alone and "pgroup.suffix.volume" for protection group snapshots (either can start
+// with a pod name and "::").
+func convertVolumeSnapshotResponse(response *VolumeSnapshotResponse) *metrics.Snapshot {
+	snapshot := &metrics.Snapshot{
+		Created: parseFlashArrayTime(response.Created),
+		Name:    response.Name,
+		Size:    response.Size,
+		Source:  response.Source,
+	}
+
+	name := response.Name
+	pod := ""
+	if index := strings.Index(name, "::"); index >= 0 {
+		pod = name[:index+2]
+		name = name[index+2:]
+	}
+	parts := strings.SplitN(name, ".", 3)
+	switch len(parts) {
+	case 2:
+		snapshot.Suffix = parts[1]
+	case 3:
+		snapshot.ProtectionGroup = pod + parts[0]
+		snapshot.Suffix = parts[1]
+		snapshot.Managed = true
+	}
+	return snapshot
+}
+
+// parseFlashArrayTime is a helper function that parses a FlashArray time (formatted in "2006-01-02T15:04:05Z") into
+// Unix seconds, or returns 0 if it isn't set or can't be parsed
+func parseFlashArrayTime(value string) int64 {
+	parsed, err := time.Parse("2006-01-02T15:04:05Z", value)
+	if err != nil {
+		return 0
+	}
+	return parsed.UTC().Unix()
+}
+
+// classifyHardwareComponent is a helper function that works out the type of a /hardware component from its name
+// (such as "CH0.PWR1" or "CT0.FAN2"). Returns false for controllers and drive bays, which are reported with more
+// detail by their own endpoints.
+func classifyHardwareComponent(name string) (string, bool) {
+	parts := strings.Split(name, ".")
+	if len(parts) == 1 && strings.HasPrefix(name, "CT") {
+		return "", false
+	}
+
+	component := strings.TrimRight(parts[len(parts)-1], "0123456789")
+	switch component {
+	case "BAY", "NVB":
+		return "", false
+	case "FAN":
+		return metrics.HardwareComponentFan, true
+	case "PWR":
+		return metrics.HardwareComponentPowerSupply, true
+	case "TMP":
+		return metrics.HardwareComponentTemperatureSensor, true
+	default:
+		return metrics.HardwareComponentOther, true
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -31,14 +32,14 @@ const (
 )
 
 func TestFlashArrayCollectorInvalidEndpoint(t *testing.T) {
-	_, err := NewCollector("000000000000000000000000", "test-array", "101.241.128.13", testArrayToken2, nil)
+	_, err := NewCollector("000000000000000000000000", "test-array", []string{"101.241.128.13"}, testArrayToken2, nil, nil, nil)
 	assert.Error(t, err)
 }
 
 func TestFlashArrayCollectorInvalidToken(t *testing.T) {
 	t.Skip("Waiting for mock interceptor")
 
-	client, err := NewCollector("000000000000000000000000", "test-array", testArrayEndpoint2, "nah", nil)
+	client, err := NewCollector("000000000000000000000000", "test-array", []string{testArrayEndpoint2}, "nah", nil, nil, nil)
 	assert.NoError(t, err)
 
 	_, err = client.GetArrayName()
@@ -55,7 +56,7 @@ func TestFlashArrayCollector(t *testing.T) {
 		"tag1": "value1",
 	}, nil)
 
-	collector, err := NewCollector("000000000000000000000000", "test-array", testArrayEndpoint2, testArrayToken2, metaInterface)
+	collector, err := NewCollector("000000000000000000000000", "test-array", []string{testArrayEndpoint2}, testArrayToken2, nil, nil, metaInterface)
 	assert.NoError(t, err)
 
 	var response interface{}
@@ -112,7 +113,7 @@ func TestFlashArrayCollectorTagFetchError(t *testing.T) {
 	metaInterface := &mock.ArrayMetadataImpl{}
 	metaInterface.On("GetTags", "000000000000000000000000").Return(map[string]string{}, fmt.Errorf("Some error"))
 
-	collector, err := NewCollector("000000000000000000000000", "test-array", testArrayEndpoint2, testArrayToken2, metaInterface)
+	collector, err := NewCollector("000000000000000000000000", "test-array", []string{testArrayEndpoint2}, testArrayToken2, nil, nil, metaInterface)
 	assert.NoError(t, err)
 
 	var response interface{}
@@ -155,3 +156,422 @@ func TestFlashArrayCollectorTagFetchError(t *testing.T) {
 	response = collector.GetDisplayName()
 	assert.NotNil(t, "cinder-fa1", response)
 }
+
+// hostTestClient stubs out the client requests made when collecting host data
+type hostTestClient struct {
+	ArrayClient
+}
+
+func (c *hostTestClient) GetArrayInfo() (*ArrayInfoResponse, error) {
+	return &ArrayInfoResponse{ArrayName: "array-1", ID: "000000000000000000000000", Version: "5.1.0"}, nil
+}
+
+func (c *hostTestClient) GetHosts() ([]*HostResponse, error) {
+	return []*HostResponse{
+		{Name: "esx-1", HostGroup: "cluster", WWN: []string{"21000024FF2A6B1C"}},
+		{Name: "linux-1", IQN: []string{"iqn.1994-05.com.redhat:linux-1"}},
+	}, nil
+}
+
+func (c *hostTestClient) GetHostPersonalities() ([]*HostPersonalityResponse, error) {
+	return []*HostPersonalityResponse{{Name: "esx-1", Personality: "esxi"}, {Name: "linux-1"}}, nil
+}
+
+func (c *hostTestClient) GetHostGroups() ([]*HostGroupResponse, error) {
+	return []*HostGroupResponse{{Name: "cluster", Hosts: []string{"esx-1"}}}, nil
+}
+
+func (c *hostTestClient) GetVolumeConnections() ([]*VolumeConnectionResponse, error) {
+	return []*VolumeConnectionResponse{
+		{Name: "datastore-2", Host: "esx-1", HostGroup: "cluster", LUN: 2},
+		{Name: "datastore-1", Host: "esx-1", HostGroup: "cluster", LUN: 1},
+		{Name: "boot", Host: "esx-1", LUN: 3},
+		{Name: "data", Host: "linux-1", LUN: 1},
+	}, nil
+}
+
+func (c *hostTestClient) GetHostPerformanceMetrics() ([]*HostPerformanceMetricsResponse, error) {
+	return []*HostPerformanceMetricsResponse{{Name: "esx-1", ReadsPerSec: 100, OutputPerSec: 4096, WritesPerSec: 50, ReadLatency: 250}}, nil
+}
+
+func (c *hostTestClient) GetHostGroupPerformanceMetrics() ([]*HostPerformanceMetricsResponse, error) {
+	return nil, fmt.Errorf("Some error")
+}
+
+func TestFlashArrayCollectorHostData(t *testing.T) {
+	metaInterface := &mock.ArrayMetadataImpl{}
+	metaInterface.On("GetTags", "000000000000000000000000").Return(map[string]string{
+		"tag1": "value1",
+	}, nil)
+
+	collector := &Collector{
+		ArrayID:        "000000000000000000000000",
+		ArrayType:      common.FlashArray,
+		Client:         &hostTestClient{},
+		DisplayName:    "test-array",
+		metaConnection: metaInterface,
+	}
+
+	hostData, err := collector.GetAllHostData()
+	assert.NoError(t, err)
+	assert.Len(t, hostData.HostMetrics, 3)
+
+	esx := hostData.HostMetrics[0]
+	assert.Equal(t, metrics.HostMetricTypeHost, esx.Type)
+	assert.Equal(t, "esx-1", esx.HostName)
+	assert.Equal(t, "cluster", esx.HostGroup)
+	assert.Equal(t, "esxi", esx.Personality)
+	assert.Equal(t, "array-1", esx.ArrayName)
+	assert.Equal(t, "value1", esx.ArrayTags["tag1"])
+	assert.Equal(t, []string{"21000024FF2A6B1C"}, esx.WWNs)
+	assert.Equal(t, []string{"boot", "datastore-1", "datastore-2"}, esx.Volumes)
+	assert.Equal(t, uint32(3), esx.VolumeCount)
+	assert.Equal(t, uint64(100), esx.ReadIOPS)
+	assert.Equal(t, uint64(4096), esx.ReadBandwidth)
+	assert.Equal(t, uint64(250), esx.ReadLatency)
+
+	linux := hostData.HostMetrics[1]
+	assert.Equal(t, "linux-1", linux.HostName)
+	assert.Empty(t, linux.HostGroup)
+	assert.Equal(t, []string{"iqn.1994-05.com.redhat:linux-1"}, linux.IQNs)
+	assert.Equal(t, []string{"data"}, linux.Volumes)
+	assert.Nil(t, linux.HostPerformanceMetric) // No performance data was returned for this host
+
+	cluster := hostData.HostMetrics[2]
+	assert.Equal(t, metrics.HostMetricTypeHostGroup, cluster.Type)
+	assert.Equal(t, "cluster", cluster.HostName)
+	assert.Equal(t, []string{"esx-1"}, cluster.Hosts)
+	assert.Equal(t, []string{"datastore-1", "datastore-2"}, cluster.Volumes)
+	assert.Nil(t, cluster.HostPerformanceMetric) // Host group performance failed, but the inventory is still collected
+}
+
+// protectionGroupTestClient stubs out the client requests made when collecting protection group data
+type protectionGroupTestClient struct {
+	ArrayClient
+}
+
+func (c *protectionGroupTestClient) GetArrayInfo() (*ArrayInfoResponse, error) {
+	return &ArrayInfoResponse{ArrayName: "array-1", ID: "000000000000000000000000", Version: "5.1.0"}, nil
+}
+
+func (c *protectionGroupTestClient) GetProtectionGroups() ([]*ProtectionGroupResponse, error) {
+	return []*ProtectionGroupResponse{
+		{
+			Name:    "pg1",
+			Source:  "array-1",
+			Volumes: []string{"vol1", "vol2"},
+			Targets: []*ProtectionGroupTargetResponse{{Name: "array-2", Allowed: true}, {Name: "array-3", Allowed: false}},
+		},
+		{Name: "array-4:pg2", Source: "array-4", Hosts: []string{"host1"}},
+	}, nil
+}
+
+func (c *protectionGroupTestClient) GetProtectionGroupSchedules() ([]*ProtectionGroupScheduleResponse, error) {
+	return []*ProtectionGroupScheduleResponse{
+		{Name: "pg1", SnapshotEnabled: true, SnapshotFrequency: 900, ReplicateEnabled: true, ReplicateFrequency: 3600},
+	}, nil
+}
+
+func (c *protectionGroupTestClient) GetProtectionGroupSnapshots() ([]*ProtectionGroupSnapshotResponse, error) {
+	return []*ProtectionGroupSnapshotResponse{
+		{Name: "pg1.1", Source: "pg1", Created: "2019-06-01T10:00:00Z", Started: "2019-06-01T10:00:00Z", Completed: "2019-06-01T10:05:00Z", DataTransferred: 100, PhysicalBytesWritten: 50, Progress: 1},
+		{Name: "pg1.2", Source: "pg1", Created: "2019-06-01T11:00:00Z", Started: "2019-06-01T11:00:00Z", Completed: "2019-06-01T11:02:00Z", DataTransferred: 200, PhysicalBytesWritten: 80, Progress: 1},
+		{Name: "pg1.3", Source: "pg1", Created: "2019-06-01T12:00:00Z", Started: "2019-06-01T12:00:00Z", Progress: 0.25},
+	}, nil
+}
+
+func (c *protectionGroupTestClient) GetArrayConnections() ([]*ArrayConnectionResponse, error) {
+	return []*ArrayConnectionResponse{{ArrayName: "array-2", Connected: true}, {ArrayName: "array-3", Connected: false}}, nil
+}
+
+func TestFlashArrayCollectorProtectionGroupData(t *testing.T) {
+	metaInterface := &mock.ArrayMetadataImpl{}
+	metaInterface.On("GetTags", "000000000000000000000000").Return(map[string]string{}, nil)
+
+	collector := &Collector{
+		ArrayID:        "000000000000000000000000",
+		ArrayType:      common.FlashArray,
+		Client:         &protectionGroupTestClient{},
+		DisplayName:    "test-array",
+		metaConnection: metaInterface,
+	}
+
+	protectionGroupData, err := collector.GetAllProtectionGroupData()
+	assert.NoError(t, err)
+	assert.Len(t, protectionGroupData.ProtectionGroupMetrics, 2)
+
+	pg1 := protectionGroupData.ProtectionGroupMetrics[0]
+	assert.Equal(t, "pg1", pg1.ProtectionGroupName)
+	assert.Equal(t, "array-1", pg1.Source)
+	assert.Equal(t, []string{"vol1", "vol2"}, pg1.Volumes)
+	assert.Len(t, pg1.Targets, 2)
+	assert.Equal(t, metrics.ProtectionGroupTarget{Name: "array-2", Allowed: true, Connected: true}, *pg1.Targets[0])
+	assert.Equal(t, metrics.ProtectionGroupTarget{Name: "array-3", Allowed: false, Connected: false}, *pg1.Targets[1])
+	assert.True(t, pg1.SnapshotEnabled)
+	assert.Equal(t, int64(900), pg1.SnapshotFrequency)
+	assert.True(t, pg1.ReplicationEnabled)
+	assert.Equal(t, int64(3600), pg1.ReplicationFrequency)
+	assert.Equal(t, uint32(3), pg1.SnapshotCount)
+	assert.Equal(t, parseFlashArrayTime("2019-06-01T12:00:00Z"), pg1.LastSnapshot)
+	// The newest replicated snapshot is the second one, since the third is still being transferred
+	assert.Equal(t, parseFlashArrayTime("2019-06-01T11:02:00Z"), pg1.LastReplication)
+	assert.Equal(t, pg1.CreatedAt-parseFlashArrayTime("2019-06-01T11:00:00Z"), pg1.ReplicationLag)
+	assert.Equal(t, int64(120), pg1.LastTransferDuration)
+	assert.Equal(t, uint64(200), pg1.LastTransferDataBytes)
+	assert.Equal(t, uint64(80), pg1.LastTransferPhysicalBytes)
+	assert.Equal(t, 0.25, pg1.TransferProgress)
+
+	pg2 := protectionGroupData.ProtectionGroupMetrics[1]
+	assert.Equal(t, "array-4:pg2", pg2.ProtectionGroupName)
+	assert.Equal(t, "array-4", pg2.Source)
+	assert.False(t, pg2.ReplicationEnabled)
+	assert.Equal(t, uint32(0), pg2.SnapshotCount)
+	assert.Equal(t, int64(0), pg2.LastReplication)
+	assert.Equal(t, int64(0), pg2.ReplicationLag)
+}
+
+// podTestClient stubs out the client requests made when collecting pod data
+type podTestClient struct {
+	ArrayClient
+}
+
+func (c *podTestClient) GetArrayInfo() (*ArrayInfoResponse, error) {
+	return &ArrayInfoResponse{ArrayName: "array-1", ID: "purity-id-1", Version: "5.1.0"}, nil
+}
+
+func (c *podTestClient) GetPods() ([]*PodResponse, error) {
+	return []*PodResponse{
+		{
+			Name: "pod1",
+			Arrays: []*PodArrayResponse{
+				{ArrayID: "purity-id-1", Name: "array-1", Status: "online", MediatorStatus: "online"},
+				{ArrayID: "purity-id-2", Name: "array-2", Status: "resyncing", MediatorStatus: "unreachable", Progress: 0.4},
+			},
+		},
+		{
+			Name:   "pod2",
+			Source: "pod1",
+			Arrays: []*PodArrayResponse{
+				{ArrayID: "purity-id-1", Name: "array-1", Status: "online", MediatorStatus: "online", Progress: 1},
+			},
+		},
+	}, nil
+}
+
+func (c *podTestClient) GetPodMediators() ([]*PodMediatorResponse, error) {
+	return []*PodMediatorResponse{{Name: "pod1", Mediator: "purestorage", MediatorVersion: "1.0"}}, nil
+}
+
+func TestFlashArrayCollectorPodData(t *testing.T) {
+	metaInterface := &mock.ArrayMetadataImpl{}
+	metaInterface.On("GetTags", "000000000000000000000000").Return(map[string]string{}, nil)
+
+	collector := &Collector{
+		ArrayID:        "000000000000000000000000",
+		ArrayType:      common.FlashArray,
+		Client:         &podTestClient{},
+		DisplayName:    "test-array",
+		metaConnection: metaInterface,
+	}
+
+	podData, err := collector.GetAllPodData()
+	assert.NoError(t, err)
+	assert.Len(t, podData.PodMetrics, 2)
+
+	pod1 := podData.PodMetrics[0]
+	assert.Equal(t, "000000000000000000000000", pod1.ArrayID)
+	assert.Equal(t, "purity-id-1", pod1.PurityID)
+	assert.Equal(t, "pod1", pod1.PodName)
+	assert.Equal(t, "purestorage", pod1.Mediator)
+	assert.Equal(t, "1.0", pod1.MediatorVersion)
+	assert.Equal(t, metrics.PodStatusOnline, pod1.Status)
+	assert.Equal(t, metrics.MediatorStatusOnline, pod1.MediatorStatus)
+	assert.True(t, pod1.Stretched)
+	assert.Equal(t, uint32(2), pod1.MemberCount)
+	assert.Equal(t, uint32(1), pod1.OnlineMemberCount)
+	assert.Len(t, pod1.Members, 2)
+	assert.Equal(t, metrics.PodMember{ArrayID: "purity-id-2", ArrayName: "array-2", Status: "resyncing", MediatorStatus: "unreachable", ResyncProgress: 0.4}, *pod1.Members[1])
+
+	pod2 := podData.PodMetrics[1]
+	assert.Equal(t, "pod1", pod2.Source)
+	assert.Equal(t, "", pod2.Mediator)
+	assert.False(t, pod2.Stretched)
+	// Progress only counts while resyncing
+	assert.Equal(t, float64(0), pod2.ResyncProgress)
+}
+
+// hardwareTestClient stubs out the client requests made when collecting hardware data
+type hardwareTestClient struct {
+	ArrayClient
+}
+
+func (c *hardwareTestClient) GetArrayInfo() (*ArrayInfoResponse, error) {
+	return &ArrayInfoResponse{ArrayName: "array-1", ID: "purity-id-1", Version: "5.1.0"}, nil
+}
+
+func (c *hardwareTestClient) GetControllers() ([]*ArrayControllersResponse, error) {
+	return []*ArrayControllersResponse{
+		{Name: "CT0", Mode: "primary", Model: "FA-m20r2", Status: "ready", Version: "5.1.0"},
+		{Name: "CT1", Mode: "secondary", Model: "FA-m20r2", Status: "not ready", Version: "5.1.0"},
+	}, nil
+}
+
+func (c *hardwareTestClient) GetDrives() ([]*DriveResponse, error) {
+	return []*DriveResponse{
+		{Name: "CH0.BAY0", Status: "healthy", Capacity: 1024, Type: "SSD", Protocol: "SAS", LastEvacCompleted: "1970-01-01T00:00:00Z", LastFailure: "2019-03-01T12:00:00Z"},
+	}, nil
+}
+
+func (c *hardwareTestClient) GetHardware() ([]*HardwareResponse, error) {
+	return []*HardwareResponse{
+		{Name: "CH0", Status: "ok"},
+		{Name: "CH0.BAY0", Status: "ok"},
+		{Name: "CH0.PWR1", Status: "critical", Details: "Power supply failed"},
+		{Name: "CT0", Status: "ok"},
+		{Name: "CT0.FAN0", Status: "ok", Speed: 5000},
+		{Name: "CT0.TMP1", Status: "ok", Temperature: 35},
+	}, nil
+}
+
+func TestFlashArrayCollectorHardwareData(t *testing.T) {
+	metaInterface := &mock.ArrayMetadataImpl{}
+	metaInterface.On("GetTags", "000000000000000000000000").Return(map[string]string{}, nil)
+
+	collector := &Collector{
+		ArrayID:        "000000000000000000000000",
+		ArrayType:      common.FlashArray,
+		Client:         &hardwareTestClient{},
+		DisplayName:    "test-array",
+		metaConnection: metaInterface,
+	}
+
+	hardwareData, err := collector.GetAllHardwareData()
+	assert.NoError(t, err)
+	// Both controllers, one drive, and the chassis, power supply, fan and sensor (the bay and controller are skipped)
+	assert.Len(t, hardwareData.HardwareMetrics, 7)
+
+	byName := map[string]*metrics.HardwareMetric{}
+	for _, metric := range hardwareData.HardwareMetrics {
+		assert.Equal(t, "000000000000000000000000", metric.ArrayID)
+		assert.Equal(t, "array-1", metric.ArrayName)
+		byName[metric.ComponentType+"/"+metric.ComponentName] = metric
+	}
+
+	ct0 := byName["controller/CT0"]
+	assert.True(t, ct0.Healthy)
+	assert.Equal(t, "primary", ct0.Mode)
+	assert.Equal(t, "FA-m20r2", ct0.Model)
+	assert.False(t, byName["controller/CT1"].Healthy)
+
+	drive := byName["drive/CH0.BAY0"]
+	assert.True(t, drive.Healthy)
+	assert.Equal(t, uint64(1024), drive.Capacity)
+	assert.Equal(t, int64(1551441600), drive.LastFailure)
+	assert.Equal(t, int64(0), drive.LastEvacuation)
+
+	power := byName["power_supply/CH0.PWR1"]
+	assert.False(t, power.Healthy)
+	assert.Equal(t, "Power supply failed", power.Details)
+	assert.Equal(t, uint64(5000), byName["fan/CT0.FAN0"].Speed)
+	assert.Equal(t, 35, byName["temperature_sensor/CT0.TMP1"].Temperature)
+	assert.NotNil(t, byName["other/CH0"])
+}
+
+// auditTestClient stubs out the client requests made when collecting audit events
+type auditTestClient struct {
+	ArrayClient
+	auditErr error
+}
+
+func (c *auditTestClient) GetArrayInfo() (*ArrayInfoResponse, error) {
+	return &ArrayInfoResponse{ArrayName: "array-1", ID: "purity-id-1", Version: "5.1.0"}, nil
+}
+
+func (c *auditTestClient) GetAuditEvents(since int64) ([]*AuditResponse, error) {
+	if c.auditErr != nil {
+		return nil, c.auditErr
+	}
+	return []*AuditResponse{
+		{ID: 1201, Opened: "2019-03-01T12:00:00Z", User: "pureuser", Command: "purevol", Subcommand: "create", Arguments: "--size 1G vol1"},
+	}, nil
+}
+
+func (c *auditTestClient) GetSessions(since int64) ([]*SessionResponse, error) {
+	return []*SessionResponse{
+		{ID: 1202, Opened: "2019-03-01T12:05:00Z", User: "pureuser", Event: "failed authentication", Location: "10.0.0.5", Method: "password"},
+	}, nil
+}
+
+func TestFlashArrayCollectorAuditEvents(t *testing.T) {
+	collector := &Collector{
+		ArrayID:     "000000000000000000000000",
+		ArrayType:   common.FlashArray,
+		Client:      &auditTestClient{},
+		DisplayName: "test-array",
+	}
+
+	events, err := collector.GetAuditEvents(0)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	command := events[0]
+	assert.Equal(t, "000000000000000000000000", command.ArrayID)
+	assert.Equal(t, "array-1", command.ArrayName)
+	assert.Equal(t, "test-array", command.ArrayDisplayName)
+	assert.Equal(t, common.FlashArray, command.ArrayType)
+	assert.Equal(t, metrics.AuditEventCommand, command.Kind)
+	assert.Equal(t, "1201", command.EventID)
+	assert.Equal(t, int64(1551441600), command.Time)
+	assert.Equal(t, "purevol", command.Command)
+	assert.Equal(t, "create", command.Subcommand)
+	assert.Equal(t, "--size 1G vol1", command.Arguments)
+
+	session := events[1]
+	assert.Equal(t, metrics.AuditEventSession, session.Kind)
+	assert.Equal(t, "1202", session.EventID)
+	assert.Equal(t, "failed authentication", session.Event)
+	assert.Equal(t, "10.0.0.5", session.Location)
+	assert.Equal(t, "password", session.Method)
+	assert.Equal(t, "", session.Command)
+}
+
+func TestFlashArrayCollectorAuditEventsError(t *testing.T) {
+	collector := &Collector{
+		ArrayID:     "000000000000000000000000",
+		ArrayType:   common.FlashArray,
+		Client:      &auditTestClient{auditErr: fmt.Errorf("Some error")},
+		DisplayName: "test-array",
+	}
+
+	// The sessions alone would move the next collection past the audit records that were missed
+	_, err := collector.GetAuditEvents(0)
+	assert.Error(t, err)
+}
+
+func TestConvertVolumeSnapshotResponse(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		protectionGroup string
+		suffix          string
+	}{
+		{"vol1.manual", "", "manual"},
+		{"pg1.42.vol1", "pg1", "42"},
+		{"pod1::vol1.3", "", "3"},
+		{"pod1::pg1.7.pod1::vol1", "pod1::pg1", "7"},
+		{"remote-array:pg1.9.vol1", "remote-array:pg1", "9"},
+	} {
+		snapshot := convertVolumeSnapshotResponse(&VolumeSnapshotResponse{
+			Created: "2019-03-01T12:00:00Z",
+			Name:    test.name,
+			Size:    1073741824,
+			Source:  "vol1",
+		})
+		assert.Equal(t, test.name, snapshot.Name, test.name)
+		assert.Equal(t, "vol1", snapshot.Source, test.name)
+		assert.Equal(t, int64(1551441600), snapshot.Created, test.name)
+		assert.Equal(t, uint64(1073741824), snapshot.Size, test.name)
+		assert.Equal(t, test.protectionGroup, snapshot.ProtectionGroup, test.name)
+		assert.Equal(t, test.suffix, snapshot.Suffix, test.name)
+		assert.Equal(t, test.protectionGroup != "", snapshot.Managed, test.name)
+	}
+}
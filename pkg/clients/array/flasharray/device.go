@@ -0,0 +1,49 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flasharray
+
+import (
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/registry"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+)
+
+func init() {
+	registry.Register(registry.Device{
+		Type: common.FlashArray,
+		Capabilities: []string{
+			registry.CapabilityAPIClient,
+			registry.CapabilityAudit,
+			registry.CapabilityHardware,
+			registry.CapabilityHosts,
+			registry.CapabilityPerformanceHistory,
+			registry.CapabilityPods,
+			registry.CapabilityProtectionGroups,
+			registry.CapabilitySnapshots,
+		},
+		NewCollector:         newRegisteredCollector,
+		ValidateRegistration: validateRegistration,
+	})
+}
+
+// validateRegistration checks the REST 2.x API client credentials (if given) are complete and usable
+func validateRegistration(array *resources.Array) error {
+	return array.ValidateAPIClientFields()
+}
+
+// newRegisteredCollector is a helper function that creates a collector for the device registry
+func newRegisteredCollector(arrayInfo *resources.ArrayRegistrationInfo, options registry.CollectorOptions) (resources.ArrayCollector, error) {
+	return NewCollector(arrayInfo.ID, arrayInfo.Name, arrayInfo.GetMgmtEndpoints(), arrayInfo.APIToken, arrayInfo.GetAPIClient(), options.TLSConfig, options.MetaConnection)
+}
@@ -15,9 +15,12 @@
 package flasharray
 
 import (
-	"net"
+	"crypto/rsa"
+	"encoding/json"
+	"sync"
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/go-resty/resty"
 )
 
 // Client and collector
@@ -27,11 +30,30 @@ type ArrayClient interface {
 	GetAlertsFlagged() ([]*AlertResponse, error)
 	GetAlertsTimeline() ([]*AlertResponse, error)
 	GetArrayCapacityMetrics() (*ArrayCapacityMetricsResponse, error)
+	GetArrayConnections() ([]*ArrayConnectionResponse, error)
 	GetArrayInfo() (*ArrayInfoResponse, error)
+	GetArrayPerformanceHistory(start int64, end int64) ([]*ArrayPerformanceMetricsResponse, error)
 	GetArrayPerformanceMetrics() (*ArrayPerformanceMetricsResponse, error)
+	GetAuditEvents(since int64) ([]*AuditResponse, error)
+	GetControllers() ([]*ArrayControllersResponse, error)
+	GetDrives() ([]*DriveResponse, error)
+	GetHardware() ([]*HardwareResponse, error)
 	GetHostCount() (uint32, error)
+	GetHostGroupPerformanceMetrics() ([]*HostPerformanceMetricsResponse, error)
+	GetHostGroups() ([]*HostGroupResponse, error)
+	GetHostPerformanceMetrics() ([]*HostPerformanceMetricsResponse, error)
+	GetHostPersonalities() ([]*HostPersonalityResponse, error)
+	GetHosts() ([]*HostResponse, error)
+	GetManagementEndpoint() string
 	GetModel() (string, error)
+	GetPodMediators() ([]*PodMediatorResponse, error)
+	GetPods() ([]*PodResponse, error)
+	GetProtectionGroups() ([]*ProtectionGroupResponse, error)
+	GetProtectionGroupSchedules() ([]*ProtectionGroupScheduleResponse, error)
+	GetProtectionGroupSnapshots() ([]*ProtectionGroupSnapshotResponse, error)
+	GetSessions(since int64) ([]*SessionResponse, error)
 	GetVolumeCapacityMetrics() ([]*VolumeCapacityMetricsResponse, error)
+	GetVolumeConnections() ([]*VolumeConnectionResponse, error)
 	GetVolumeCount() (uint32, error)
 	GetVolumePerformanceMetrics() ([]*VolumePerformanceMetricsResponse, error)
 	GetVolumePendingEradicationCount() (uint32, error)
@@ -41,10 +63,31 @@ type ArrayClient interface {
 
 // Client is a FlashArray client that handles specific REST API requests
 type Client struct {
-	APIToken     string
-	APIVersion   string
-	DisplayName  string
-	ManagementIP net.IP
+	APIToken           string
+	APIVersion         string
+	DisplayName        string
+	ManagementEndpoint string // The management endpoint (FQDN, IPv4 or IPv6) that answered
+
+	apiVersions []string // Every API version the array supports
+	restClient  *resty.Client
+	session     int        // Counts session refreshes, so concurrent requests rejected with the same session refresh it once
+	sessionLock sync.Mutex // Guards session (the session itself is a cookie of the REST client)
+}
+
+// ClientV2 is a FlashArray client that handles specific REST 2.x API requests, authenticating with an
+// API client if it has one and with the API token otherwise
+type ClientV2 struct {
+	APIClient          *resources.APIClientCredentials
+	APIToken           string
+	APIVersion         string
+	DisplayName        string
+	ManagementEndpoint string // The management endpoint (FQDN, IPv4 or IPv6) that answered
+
+	authHeaders map[string]string // Sent with every request, set once authenticated
+	privateKey  *rsa.PrivateKey   // Signs the API client JWTs
+	restClient  *resty.Client
+	session     int          // Counts session refreshes, so concurrent requests rejected with the same session refresh it once
+	sessionLock sync.RWMutex // Guards authHeaders and session
 }
 
 // Collector is a FlashArray collector that uses the client to make requests
@@ -71,6 +114,37 @@ type ArrayMetricsResponseBundle struct {
 	PerformanceMetricsResponse *ArrayPerformanceMetricsResponse
 }
 
+// HardwareResponseBundle is used to return all hardware component responses together
+type HardwareResponseBundle struct {
+	ControllersResponse []*ArrayControllersResponse
+	DrivesResponse      []*DriveResponse
+	HardwareResponse    []*HardwareResponse
+}
+
+// HostResponseBundle is used to return all host and host group responses together
+type HostResponseBundle struct {
+	ConnectionsResponse                 []*VolumeConnectionResponse
+	HostGroupPerformanceMetricsResponse []*HostPerformanceMetricsResponse
+	HostGroupsResponse                  []*HostGroupResponse
+	HostPerformanceMetricsResponse      []*HostPerformanceMetricsResponse
+	HostsResponse                       []*HostResponse
+	PersonalitiesResponse               []*HostPersonalityResponse
+}
+
+// PodResponseBundle is used to return all pod responses together
+type PodResponseBundle struct {
+	MediatorsResponse []*PodMediatorResponse
+	PodsResponse      []*PodResponse
+}
+
+// ProtectionGroupResponseBundle is used to return all protection group responses together
+type ProtectionGroupResponseBundle struct {
+	ConnectionsResponse      []*ArrayConnectionResponse
+	ProtectionGroupsResponse []*ProtectionGroupResponse
+	SchedulesResponse        []*ProtectionGroupScheduleResponse
+	SnapshotsResponse        []*ProtectionGroupSnapshotResponse
+}
+
 // ObjectCountResponseBundle is used to return all object count responses together
 type ObjectCountResponseBundle struct {
 	HostCount                     uint32
@@ -114,10 +188,21 @@ type ArrayCapacityMetricsResponse struct {
 	VolumeSpace    uint64  `json:"volumes"`
 }
 
+// ArrayConnectionResponse is from /array/connection
+type ArrayConnectionResponse struct {
+	ArrayName string   `json:"array_name"`
+	Connected bool     `json:"connected"`
+	ID        string   `json:"id"`
+	Type      []string `json:"type"`
+}
+
 // ArrayControllersResponse is from /array with parameters controllers=true
 type ArrayControllersResponse struct {
-	Mode  string `json:"mode"`
-	Model string `json:"model"`
+	Mode    string `json:"mode"`
+	Model   string `json:"model"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Version string `json:"version"`
 }
 
 // ArrayInfoResponse is from /array with no parameters
@@ -137,13 +222,150 @@ type ArrayPerformanceMetricsResponse struct {
 	QueueDepth    uint16 `json:"queue_depth"`
 	ReadLatency   uint64 `json:"usec_per_read_op"`
 	ReadsPerSec   uint64 `json:"reads_per_sec"`
+	Time          string `json:"time"` // Only set for history (historical=...)
 	WriteLatency  uint64 `json:"usec_per_write_op"`
 	WritesPerSec  uint64 `json:"writes_per_sec"`
 }
 
+// AuditResponse is from /message with parameters audit=true
+type AuditResponse struct {
+	Arguments     string `json:"arguments"`
+	Command       string `json:"command"`
+	ID            uint64 `json:"id"`
+	Opened        string `json:"opened"`
+	Subcommand    string `json:"subcommand"`
+	User          string `json:"user"`
+	UserInterface string `json:"user_interface"` // Only set through REST 2.x
+}
+
+// DriveResponse is from /drive with no parameters
+type DriveResponse struct {
+	Capacity          uint64 `json:"capacity"`
+	Details           string `json:"details"`
+	LastEvacCompleted string `json:"last_evac_completed"`
+	LastFailure       string `json:"last_failure"`
+	Name              string `json:"name"`
+	Protocol          string `json:"protocol"`
+	Status            string `json:"status"`
+	Type              string `json:"type"`
+}
+
 // EmptyResponse is from any endpoint where we only read the headers
 type EmptyResponse struct{}
 
+// HardwareResponse is from /hardware with no parameters
+type HardwareResponse struct {
+	Details     string `json:"details"`
+	Index       int    `json:"index"`
+	Name        string `json:"name"`
+	Slot        int    `json:"slot"`
+	Speed       uint64 `json:"speed"`
+	Status      string `json:"status"`
+	Temperature int    `json:"temperature"`
+}
+
+// HostGroupResponse is from /hgroup with no parameters
+type HostGroupResponse struct {
+	Hosts []string `json:"hosts"`
+	Name  string   `json:"name"`
+}
+
+// HostPerformanceMetricsResponse is from /host or /hgroup with parameters action=monitor
+type HostPerformanceMetricsResponse struct {
+	InputPerSec  uint64 `json:"input_per_sec"`
+	OutputPerSec uint64 `json:"output_per_sec"`
+	Name         string `json:"name"`
+	ReadLatency  uint64 `json:"usec_per_read_op"`
+	ReadsPerSec  uint64 `json:"reads_per_sec"`
+	WriteLatency uint64 `json:"usec_per_write_op"`
+	WritesPerSec uint64 `json:"writes_per_sec"`
+}
+
+// HostPersonalityResponse is from /host with parameters personality=true
+type HostPersonalityResponse struct {
+	Name        string `json:"name"`
+	Personality string `json:"personality"`
+}
+
+// HostResponse is from /host with no parameters
+type HostResponse struct {
+	HostGroup string   `json:"hgroup"`
+	IQN       []string `json:"iqn"`
+	Name      string   `json:"name"`
+	WWN       []string `json:"wwn"`
+}
+
+// PodResponse is from /pod with no parameters
+type PodResponse struct {
+	Arrays []*PodArrayResponse `json:"arrays"`
+	Name   string              `json:"name"`
+	Source string              `json:"source"`
+}
+
+// PodArrayResponse is a single member array of a pod
+type PodArrayResponse struct {
+	ArrayID        string  `json:"array_id"`
+	MediatorStatus string  `json:"mediator_status"`
+	Name           string  `json:"name"`
+	Progress       float64 `json:"progress"` // Only set while resyncing
+	Status         string  `json:"status"`
+}
+
+// PodMediatorResponse is from /pod with parameters mediator=true
+type PodMediatorResponse struct {
+	Mediator        string `json:"mediator"`
+	MediatorVersion string `json:"mediator_version"`
+	Name            string `json:"name"`
+}
+
+// ProtectionGroupResponse is from /pgroup with no parameters
+type ProtectionGroupResponse struct {
+	HostGroups []string                         `json:"hgroups"`
+	Hosts      []string                         `json:"hosts"`
+	Name       string                           `json:"name"`
+	Source     string                           `json:"source"`
+	Targets    []*ProtectionGroupTargetResponse `json:"targets"`
+	Volumes    []string                         `json:"volumes"`
+}
+
+// ProtectionGroupTargetResponse is a single replication target of a protection group
+type ProtectionGroupTargetResponse struct {
+	Allowed bool   `json:"allowed"`
+	Name    string `json:"name"`
+}
+
+// ProtectionGroupScheduleResponse is from /pgroup with parameters schedule=true
+type ProtectionGroupScheduleResponse struct {
+	Name               string `json:"name"`
+	ReplicateEnabled   bool   `json:"replicate_enabled"`
+	ReplicateFrequency int64  `json:"replicate_frequency"` // Seconds
+	SnapshotEnabled    bool   `json:"snap_enabled"`
+	SnapshotFrequency  int64  `json:"snap_frequency"` // Seconds
+}
+
+// ProtectionGroupSnapshotResponse is from /pgroup with parameters snap=true, transfer=true
+type ProtectionGroupSnapshotResponse struct {
+	Completed            string  `json:"completed"`
+	Created              string  `json:"created"`
+	DataTransferred      uint64  `json:"data_transferred"`
+	Name                 string  `json:"name"`
+	PhysicalBytesWritten uint64  `json:"physical_bytes_written"`
+	Progress             float64 `json:"progress"`
+	Source               string  `json:"source"`
+	Started              string  `json:"started"`
+}
+
+// SessionResponse is from /message with parameters login=true
+type SessionResponse struct {
+	Event         string `json:"event"`
+	ID            uint64 `json:"id"`
+	Location      string `json:"location"`
+	Method        string `json:"method"`
+	Opened        string `json:"opened"`
+	User          string `json:"user"`
+	UserInterface string `json:"user_interface"` // Only set through REST 2.x
+}
+
 // VolumeCapacityMetricsResponse is from /volume with parameters space=true
 type VolumeCapacityMetricsResponse struct {
 	DataReduction  float64 `json:"data_reduction"`
@@ -152,6 +374,14 @@ type VolumeCapacityMetricsResponse struct {
 	TotalReduction float64 `json:"total_reduction"`
 }
 
+// VolumeConnectionResponse is from /volume with parameters connect=true
+type VolumeConnectionResponse struct {
+	Host      string `json:"host"`
+	HostGroup string `json:"hgroup"`
+	LUN       uint32 `json:"lun"`
+	Name      string `json:"name"`
+}
+
 // VolumePerformanceMetricsResponse is from /volume with parameters action=monitor
 type VolumePerformanceMetricsResponse struct {
 	InputPerSec  uint64 `json:"input_per_sec"`
@@ -165,5 +395,228 @@ type VolumePerformanceMetricsResponse struct {
 
 // VolumeSnapshotResponse is from /volume with parameters snap=true
 type VolumeSnapshotResponse struct {
-	Source string `json:"source"`
+	Created string `json:"created"`
+	Name    string `json:"name"`
+	Size    uint64 `json:"size"`
+	Source  string `json:"source"`
+}
+
+// Responses returned by the REST 2.x client
+
+// ItemsResponseV2 is the envelope of every REST 2.x list response
+type ItemsResponseV2 struct {
+	ContinuationToken string            `json:"continuation_token"`
+	Items             []json.RawMessage `json:"items"`
+	TotalItemCount    uint32            `json:"total_item_count"` // Only set if requested
+}
+
+// OAuth2TokenResponse is from /oauth2/1.0/token
+type OAuth2TokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	ExpiresIn       int64  `json:"expires_in"` // Seconds
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+}
+
+// ReferenceV2 is a reference to another resource in REST 2.x responses
+type ReferenceV2 struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// AlertResponseV2 is from /alerts
+type AlertResponseV2 struct {
+	Actual        string `json:"actual"`
+	Category      string `json:"category"`
+	Closed        int64  `json:"closed"` // Milliseconds since epoch
+	Code          uint16 `json:"code"`
+	ComponentName string `json:"component_name"`
+	ComponentType string `json:"component_type"`
+	Created       int64  `json:"created"` // Milliseconds since epoch
+	Description   string `json:"description"`
+	Expected      string `json:"expected"`
+	Flagged       bool   `json:"flagged"`
+	Name          string `json:"name"` // The alert number
+	Severity      string `json:"severity"`
+	State         string `json:"state"`
+	Summary       string `json:"summary"`
+}
+
+// AuditResponseV2 is from /audits
+type AuditResponseV2 struct {
+	Arguments     string `json:"arguments"`
+	Command       string `json:"command"`
+	Name          string `json:"name"` // The audit record number
+	Subcommand    string `json:"subcommand"`
+	Time          int64  `json:"time"` // Milliseconds since epoch
+	User          string `json:"user"`
+	UserInterface string `json:"user_interface"`
+}
+
+// ArrayConnectionResponseV2 is from /array-connections
+type ArrayConnectionResponseV2 struct {
+	Remote ReferenceV2 `json:"remote"`
+	Status string      `json:"status"`
+	Type   string      `json:"type"`
+}
+
+// ArrayResponseV2 is from /arrays
+type ArrayResponseV2 struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ArraySpaceResponseV2 is from /arrays/space
+type ArraySpaceResponseV2 struct {
+	Capacity uint64          `json:"capacity"`
+	Space    SpaceResponseV2 `json:"space"`
+}
+
+// ConnectionResponseV2 is from /connections
+type ConnectionResponseV2 struct {
+	Host      ReferenceV2 `json:"host"`
+	HostGroup ReferenceV2 `json:"host_group"`
+	LUN       uint32      `json:"lun"`
+	Volume    ReferenceV2 `json:"volume"`
+}
+
+// ControllerResponseV2 is from /controllers
+type ControllerResponseV2 struct {
+	Mode    string `json:"mode"`
+	Model   string `json:"model"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Type    string `json:"type"`
+	Version string `json:"version"`
+}
+
+// DriveResponseV2 is from /drives
+type DriveResponseV2 struct {
+	Capacity          uint64 `json:"capacity"`
+	Details           string `json:"details"`
+	LastEvacCompleted int64  `json:"last_evac_completed"` // Milliseconds since epoch
+	LastFailure       int64  `json:"last_failure"`        // Milliseconds since epoch
+	Name              string `json:"name"`
+	Protocol          string `json:"protocol"`
+	Status            string `json:"status"`
+	Type              string `json:"type"`
+}
+
+// HostResponseV2 is from /hosts
+type HostResponseV2 struct {
+	HostGroup   ReferenceV2 `json:"host_group"`
+	IQNs        []string    `json:"iqns"`
+	Name        string      `json:"name"`
+	Personality string      `json:"personality"`
+	WWNs        []string    `json:"wwns"`
+}
+
+// MemberResponseV2 is from the member endpoints of groups, such as /host-groups/hosts or /protection-groups/targets
+type MemberResponseV2 struct {
+	Allowed bool        `json:"allowed"` // Protection group targets only
+	Group   ReferenceV2 `json:"group"`
+	Member  ReferenceV2 `json:"member"`
+}
+
+// PerformanceResponseV2 is from /arrays/performance, /hosts/performance, /host-groups/performance or /volumes/performance
+type PerformanceResponseV2 struct {
+	BytesPerOp       float64 `json:"bytes_per_op"`
+	BytesPerRead     float64 `json:"bytes_per_read"`
+	BytesPerWrite    float64 `json:"bytes_per_write"`
+	Name             string  `json:"name"`
+	QueueDepth       float64 `json:"queue_depth"`
+	ReadBytesPerSec  float64 `json:"read_bytes_per_sec"`
+	ReadsPerSec      float64 `json:"reads_per_sec"`
+	Time             int64   `json:"time"` // Milliseconds since epoch
+	UsecPerReadOp    float64 `json:"usec_per_read_op"`
+	UsecPerWriteOp   float64 `json:"usec_per_write_op"`
+	WriteBytesPerSec float64 `json:"write_bytes_per_sec"`
+	WritesPerSec     float64 `json:"writes_per_sec"`
+}
+
+// PodResponseV2 is from /pods
+type PodResponseV2 struct {
+	Arrays          []*PodArrayResponseV2 `json:"arrays"`
+	Mediator        string                `json:"mediator"`
+	MediatorVersion string                `json:"mediator_version"`
+	Name            string                `json:"name"`
+	Source          ReferenceV2           `json:"source"`
+}
+
+// PodArrayResponseV2 is a single member array of a pod
+type PodArrayResponseV2 struct {
+	ID             string  `json:"id"`
+	MediatorStatus string  `json:"mediator_status"`
+	Name           string  `json:"name"`
+	Progress       float64 `json:"progress"` // Only set while resyncing
+	Status         string  `json:"status"`
+}
+
+// ProtectionGroupResponseV2 is from /protection-groups
+type ProtectionGroupResponseV2 struct {
+	Name                string             `json:"name"`
+	ReplicationSchedule ScheduleResponseV2 `json:"replication_schedule"`
+	SnapshotSchedule    ScheduleResponseV2 `json:"snapshot_schedule"`
+	Source              ReferenceV2        `json:"source"`
+}
+
+// ProtectionGroupSnapshotResponseV2 is from /protection-group-snapshots
+type ProtectionGroupSnapshotResponseV2 struct {
+	Created int64       `json:"created"` // Milliseconds since epoch
+	Name    string      `json:"name"`
+	Source  ReferenceV2 `json:"source"`
+}
+
+// ProtectionGroupSnapshotTransferResponseV2 is from /protection-group-snapshots/transfer
+type ProtectionGroupSnapshotTransferResponseV2 struct {
+	Completed            int64   `json:"completed"` // Milliseconds since epoch
+	DataTransferred      uint64  `json:"data_transferred"`
+	Name                 string  `json:"name"`
+	PhysicalBytesWritten uint64  `json:"physical_bytes_written"`
+	Progress             float64 `json:"progress"`
+	Started              int64   `json:"started"` // Milliseconds since epoch
+}
+
+// ScheduleResponseV2 is the snapshot or replication schedule of a protection group
+type ScheduleResponseV2 struct {
+	Enabled   bool  `json:"enabled"`
+	Frequency int64 `json:"frequency"` // Milliseconds
+}
+
+// SessionResponseV2 is from /sessions
+type SessionResponseV2 struct {
+	Event         string `json:"event"`
+	Location      string `json:"location"`
+	Method        string `json:"method"`
+	Name          string `json:"name"`       // The session record number
+	StartTime     int64  `json:"start_time"` // Milliseconds since epoch
+	User          string `json:"user"`
+	UserInterface string `json:"user_interface"`
+}
+
+// SpaceResponseV2 is the space usage of an array or volume
+type SpaceResponseV2 struct {
+	DataReduction  float64 `json:"data_reduction"`
+	Shared         uint64  `json:"shared"`
+	Snapshots      uint64  `json:"snapshots"`
+	System         uint64  `json:"system"`
+	TotalPhysical  uint64  `json:"total_physical"`
+	TotalReduction float64 `json:"total_reduction"`
+	Unique         uint64  `json:"unique"`
+}
+
+// VolumeResponseV2 is from /volumes
+type VolumeResponseV2 struct {
+	Name        string          `json:"name"`
+	Provisioned uint64          `json:"provisioned"`
+	Space       SpaceResponseV2 `json:"space"`
+}
+
+// VolumeSnapshotResponseV2 is from /volume-snapshots
+type VolumeSnapshotResponseV2 struct {
+	Created     int64       `json:"created"` // Milliseconds
+	Name        string      `json:"name"`
+	Provisioned uint64      `json:"provisioned"`
+	Source      ReferenceV2 `json:"source"`
 }
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/util"
@@ -34,19 +35,29 @@ const (
 	ArraysEndpoint                  = "/arrays"
 	ArraysPerformanceEndpoint       = "/arrays/performance"
 	ArraysSpaceEndpoint             = "/arrays/space"
+	AuditsEndpoint                  = "/audits"
+	BladesEndpoint                  = "/blades"
+	BucketsEndpoint                 = "/buckets"
+	BucketsPerformanceEndpoint      = "/buckets/performance"
 	FileSystemCountEndpoint         = "/file-systems?limit=1"
 	FileSystemsEndpoint             = "/file-systems"
-	FileSystemsPerformanceEndpoint  = "/file-systems/performance?protocol=nfs&limit=5"
+	FileSystemsPerformanceEndpoint  = "/file-systems/performance"
 	FileSystemSnapshotCountEndpoint = "/file-system-snapshots?limit=1"
 	FileSystemSnapshotsEndpoint     = "/file-system-snapshots"
+	HardwareEndpoint                = "/hardware"
 	LoginEndpoint                   = "/api/login"
+	ObjectStoreAccountsEndpoint     = "/object-store-accounts"
+	ObjectStoreUsersEndpoint        = "/object-store-users"
 )
 
 // Other constants
 const (
 	APITokenHeader                  = "api-token"
+	AuditAPIVersion                 = "2.0" // Audit logs aren't available before REST 2.x (which takes the same session token)
 	AuthTokenHeader                 = "x-auth-token"
+	DefaultPerformancePageSize      = 5     // File system performance metrics per page when none is configured
 	FileSystemPerformanceResolution = 30000 // ms
+	ObjectStoreAPIVersion           = "1.9" // Bucket versioning and performance aren't available in the preferred API version
 	PreferredAPIVersion             = "1.5"
 	RequestAttemptCount             = 3
 	UserAgent                       = "Pure1 Unplugged FlashBlade Client v1.0"
@@ -54,34 +65,55 @@ const (
 )
 
 // NewClient creates a new FlashBlade client and initializes it by getting the API version,
-// refreshing a new session, and getting the array metadata
-func NewClient(displayName string, managementEndpoint string, apiToken string) (ArrayClient, error) {
-	// Ignore the verification for using HTTPS
-	resty.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+// refreshing a new session, and getting the array metadata. The management endpoints are tried
+// in order until one answers (a nil tlsConfig skips certificate verification)
+func NewClient(displayName string, managementEndpoints []string, apiToken string, tlsConfig *tls.Config) (ArrayClient, error) {
+	if len(managementEndpoints) == 0 {
+		return nil, fmt.Errorf("No management endpoint given for %s", displayName)
+	}
 
-	// Convert the endpoint to an IP
-	ip, err := util.ParseEndpoint(displayName, managementEndpoint)
-	if err != nil {
-		return nil, err
+	// Without a registered CA bundle or fingerprint, ignore the verification for using HTTPS
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
 	}
 
 	client := Client{
-		APIToken:     apiToken,
-		DisplayName:  displayName,
-		ManagementIP: ip,
+		APIToken:    apiToken,
+		DisplayName: displayName,
+		// Each client gets its own REST client so TLS settings (and sessions) don't leak between arrays
+		restClient: resty.New().SetTLSClientConfig(tlsConfig),
 	}
 
-	// Get the API Versions and verify the preferred one is supported
-	apiVersion, err := client.getAPIVersion()
-	if err != nil {
+	// Get the API Versions and verify the preferred one is supported, failing over between the endpoints.
+	// Host names are resolved again on every connection, so DNS changes are picked up.
+	var firstErr error
+	for _, endpoint := range managementEndpoints {
+		client.ManagementEndpoint = endpoint
+		apiVersion, err := client.getAPIVersion()
+		if err == nil {
+			client.APIVersion = apiVersion
+			firstErr = nil
+			break
+		}
+		log.WithFields(log.Fields{
+			"display_name": displayName,
+			"endpoint":     endpoint,
+			"error":        err,
+		}).Warn("Could not reach FlashBlade management endpoint, trying the next one")
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	// Report the primary endpoint's error if none of them answered, since that's the one expected to work
+	if firstErr != nil {
 		client.logCreationError()
-		return nil, err
+		return nil, firstErr
 	}
-	client.APIVersion = apiVersion
 
 	log.WithFields(log.Fields{
 		"api_version":  client.APIVersion,
 		"display_name": client.DisplayName,
+		"endpoint":     client.ManagementEndpoint,
 	}).Info("Successfully created FlashBlade Client")
 	return &client, nil
 }
@@ -134,6 +166,96 @@ func (client *Client) GetArrayPerformanceMetrics() (*ArrayPerformanceMetricsResp
 	return result.Items[0], nil
 }
 
+// GetArrayPerformanceHistory returns the performance metrics the array kept between the given times (Unix seconds)
+func (client *Client) GetArrayPerformanceHistory(start int64, end int64) ([]*ArrayPerformanceMetricsResponse, error) {
+	url := fmt.Sprintf("%s?resolution=%d&start_time=%d&end_time=%d",
+		client.createFullURL(ArraysPerformanceEndpoint), FileSystemPerformanceResolution, start*1000, end*1000)
+	response, _, err := client.performGet(url, ArrayPerformanceMetricsGenericResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*ArrayPerformanceMetricsGenericResponse)
+	return result.Items, nil
+}
+
+// GetAudits returns the audit log of the commands run on the array at or after the given time (Unix seconds).
+// Returns no audit records if the array doesn't support them.
+func (client *Client) GetAudits(since int64) ([]*AuditResponse, error) {
+	if !client.supportsAPIVersion(AuditAPIVersion) {
+		return []*AuditResponse{}, nil
+	}
+
+	baseURL := fmt.Sprintf("%s?filter=%s", client.createVersionedURL(AuditAPIVersion, AuditsEndpoint), url.QueryEscape(fmt.Sprintf("time>=%d", since*1000)))
+	var audits []*AuditResponse
+	continuationToken := ""
+	for {
+		fullURL := baseURL
+		if continuationToken != "" {
+			fullURL = fmt.Sprintf("%s&continuation_token=%s", baseURL, url.QueryEscape(continuationToken))
+		}
+		response, _, err := client.performGet(fullURL, AuditGenericResponse{})
+		if err != nil {
+			return nil, err
+		}
+		result := response.(*AuditGenericResponse)
+		audits = append(audits, result.Items...)
+
+		// An array handing back the same token again would otherwise keep this going forever
+		if result.ContinuationToken == "" || result.ContinuationToken == continuationToken {
+			break
+		}
+		continuationToken = result.ContinuationToken
+	}
+	return audits, nil
+}
+
+// GetBlades returns the status of every blade slot
+func (client *Client) GetBlades() ([]*BladeResponse, error) {
+	url := client.createFullURL(BladesEndpoint)
+	response, _, err := client.performGet(url, BladeGenericResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*BladeGenericResponse)
+	return result.Items, nil
+}
+
+// GetBucketPerformanceMetrics returns the current performance metrics for the buckets. Returns no metrics if the
+// array doesn't support them.
+func (client *Client) GetBucketPerformanceMetrics() ([]*FileSystemPerformanceMetricsResponse, error) {
+	if !client.supportsObjectStore() {
+		return []*FileSystemPerformanceMetricsResponse{}, nil
+	}
+
+	url := client.createVersionedURL(ObjectStoreAPIVersion, BucketsPerformanceEndpoint)
+	response, _, err := client.performGet(url, BucketPerformanceMetricsGenericResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*BucketPerformanceMetricsGenericResponse)
+	return result.Items, nil
+}
+
+// GetBuckets returns the capacity, object count and versioning state of the buckets. Returns no buckets if the
+// array doesn't support them.
+func (client *Client) GetBuckets() ([]*BucketResponse, error) {
+	if !client.supportsObjectStore() {
+		return []*BucketResponse{}, nil
+	}
+
+	url := client.createVersionedURL(ObjectStoreAPIVersion, BucketsEndpoint)
+	response, _, err := client.performGet(url, BucketGenericResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*BucketGenericResponse)
+	return result.Items, nil
+}
+
 // GetFileSystemCapacityMetrics returns the capacity metrics for the file systems
 func (client *Client) GetFileSystemCapacityMetrics() ([]*FileSystemCapacityMetricsResponse, error) {
 	url := client.createFullURL(FileSystemsEndpoint)
@@ -158,10 +280,14 @@ func (client *Client) GetFileSystemCount() (uint32, error) {
 	return result.PaginationInfo.TotalItemCount, nil
 }
 
-// GetFileSystemPerformanceMetrics returns the performance metrics for the file systems
-func (client *Client) GetFileSystemPerformanceMetrics(window int64) ([]*FileSystemPerformanceMetricsResponse, error) {
-	// File system performance metrics are limited to 5 per response, so multiple requests and the use of a continuation
+// GetFileSystemPerformanceMetrics returns the performance metrics for the file systems over the given protocol
+// (nfs, smb, http or s3), requesting the given number of metrics per page (DefaultPerformancePageSize if not positive)
+func (client *Client) GetFileSystemPerformanceMetrics(window int64, protocol string, pageSize int) ([]*FileSystemPerformanceMetricsResponse, error) {
+	// File system performance metrics are limited per response, so multiple requests and the use of a continuation
 	// token are needed to gather all metrics
+	if pageSize <= 0 {
+		pageSize = DefaultPerformancePageSize
+	}
 	baseURL := client.createFullURL(FileSystemsPerformanceEndpoint)
 	var metricsResponses []*FileSystemPerformanceMetricsResponse
 	var continuationToken string
@@ -170,8 +296,8 @@ func (client *Client) GetFileSystemPerformanceMetrics(window int64) ([]*FileSyst
 	endTime := time.Now()
 	// Subtract an extra second so we can be sure to encompass at least one data point
 	startTime := endTime.Add(time.Duration(-window-1) * time.Second)
-	baseURL = fmt.Sprintf("%s&resolution=%d&start_time=%d&end_time=%d",
-		baseURL, FileSystemPerformanceResolution, startTime.Unix()*1000, endTime.Unix()*1000)
+	baseURL = fmt.Sprintf("%s?protocol=%s&limit=%d&resolution=%d&start_time=%d&end_time=%d",
+		baseURL, url.QueryEscape(protocol), pageSize, FileSystemPerformanceResolution, startTime.Unix()*1000, endTime.Unix()*1000)
 
 	// Make initial request
 	responseItems, token, err := client.fetchFileSystemPerformanceMetrics(baseURL)
@@ -181,9 +307,20 @@ func (client *Client) GetFileSystemPerformanceMetrics(window int64) ([]*FileSyst
 	metricsResponses = append(metricsResponses, responseItems...)
 	continuationToken = token
 
-	// Make subsequent requests until the list is exhausted
+	// Make subsequent requests until the list is exhausted. Tokens are opaque, so they have to be escaped, and an
+	// array handing back the same token again would otherwise keep this going forever.
+	seenTokens := map[string]struct{}{}
 	for continuationToken != "" {
-		fullURL := fmt.Sprintf("%s&token=%s", baseURL, continuationToken)
+		if _, seen := seenTokens[continuationToken]; seen {
+			log.WithFields(log.Fields{
+				"display_name": client.DisplayName,
+				"protocol":     protocol,
+			}).Warn("File system performance continuation token repeated, stopping pagination")
+			break
+		}
+		seenTokens[continuationToken] = struct{}{}
+
+		fullURL := fmt.Sprintf("%s&token=%s", baseURL, url.QueryEscape(continuationToken))
 		responseItems, token, err := client.fetchFileSystemPerformanceMetrics(fullURL)
 		if err != nil {
 			return nil, err
@@ -219,10 +356,67 @@ func (client *Client) GetFileSystemSnapshots() ([]*FileSystemSnapshotResponse, e
 	return result.Items, nil
 }
 
+// GetManagementEndpoint returns the management endpoint the client connected to
+func (client *Client) GetManagementEndpoint() string {
+	return client.ManagementEndpoint
+}
+
+// GetObjectStoreAccounts returns the capacity and object count of the object store accounts. Returns no accounts
+// if the array doesn't support them.
+func (client *Client) GetObjectStoreAccounts() ([]*ObjectStoreAccountResponse, error) {
+	if !client.supportsObjectStore() {
+		return []*ObjectStoreAccountResponse{}, nil
+	}
+
+	url := client.createVersionedURL(ObjectStoreAPIVersion, ObjectStoreAccountsEndpoint)
+	response, _, err := client.performGet(url, ObjectStoreAccountGenericResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*ObjectStoreAccountGenericResponse)
+	return result.Items, nil
+}
+
+// GetObjectStoreUsers returns the object store users along with their accounts. Returns no users if the array
+// doesn't support them.
+func (client *Client) GetObjectStoreUsers() ([]*ObjectStoreUserResponse, error) {
+	if !client.supportsObjectStore() {
+		return []*ObjectStoreUserResponse{}, nil
+	}
+
+	url := client.createVersionedURL(ObjectStoreAPIVersion, ObjectStoreUsersEndpoint)
+	response, _, err := client.performGet(url, ObjectStoreUserGenericResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*ObjectStoreUserGenericResponse)
+	return result.Items, nil
+}
+
+// GetHardware returns the status of every hardware component (chassis, fabric modules, fans, power supplies, ...)
+func (client *Client) GetHardware() ([]*HardwareResponse, error) {
+	url := client.createFullURL(HardwareEndpoint)
+	response, _, err := client.performGet(url, HardwareGenericResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := response.(*HardwareGenericResponse)
+	return result.Items, nil
+}
+
 // createFullURL is a helper function that returns a URL for the specified endpoint/params with the
 // management endpoint and API version
 func (client *Client) createFullURL(endpoint string) string {
-	return fmt.Sprintf("https://%s%s/%s%s", client.ManagementIP, APIPrefix, client.APIVersion, endpoint)
+	return client.createVersionedURL(client.APIVersion, endpoint)
+}
+
+// createVersionedURL is a helper function that returns a URL for the specified endpoint/params with the
+// management endpoint and the given API version, for endpoints that need a different version
+func (client *Client) createVersionedURL(apiVersion string, endpoint string) string {
+	return fmt.Sprintf("https://%s%s/%s%s", util.EndpointURLHost(client.ManagementEndpoint), APIPrefix, apiVersion, endpoint)
 }
 
 // fetchFileSystemPerformanceMetrics is a helper function to make one single request to get file system performance
@@ -239,7 +433,7 @@ func (client *Client) fetchFileSystemPerformanceMetrics(fullURL string) ([]*File
 // getAPIVersion is a helper function that checks the available API versions and that the desired
 // version is available; it warns if it is not
 func (client *Client) getAPIVersion() (string, error) {
-	url := fmt.Sprintf("https://%s%s", client.ManagementIP, APIVersionEndpoint)
+	url := fmt.Sprintf("https://%s%s", util.EndpointURLHost(client.ManagementEndpoint), APIVersionEndpoint)
 	response, _, err := client.performGet(url, APIVersionResponse{})
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -250,6 +444,7 @@ func (client *Client) getAPIVersion() (string, error) {
 	}
 
 	result := response.(*APIVersionResponse)
+	client.apiVersions = result.Version
 
 	// If the preferred API version exists, we'll use that
 	for _, ver := range result.Version {
@@ -283,7 +478,8 @@ func (client *Client) performGet(url string, resultType interface{}) (interface{
 			"display_name": client.DisplayName,
 			"url":          url,
 		}).Trace("Making GET request")
-		response, err := resty.R().SetHeader(UserAgentHeader, UserAgent).SetHeader(AuthTokenHeader, client.AuthToken).SetResult(resultType).Get(url)
+		authToken, session := client.currentSession()
+		response, err := client.restClient.R().SetHeader(UserAgentHeader, UserAgent).SetHeader(AuthTokenHeader, authToken).SetResult(resultType).Get(url)
 
 		// If there was a client error we quit
 		if err != nil {
@@ -302,7 +498,7 @@ func (client *Client) performGet(url string, resultType interface{}) (interface{
 				"status_code":  response.StatusCode(),
 				"url":          url,
 			}).Trace("Session expired; refreshing session and retrying")
-			_ = client.refreshSession()
+			client.refreshExpiredSession(session)
 			continue
 		}
 		if response.StatusCode() == 500 {
@@ -328,16 +524,37 @@ func (client *Client) performGet(url string, resultType interface{}) (interface{
 	return nil, nil, errors.New("No successful GET request")
 }
 
+// currentSession is a helper function that returns the auth token to make requests with, along with the
+// number of the session it belongs to
+func (client *Client) currentSession() (string, int) {
+	client.sessionLock.RLock()
+	defer client.sessionLock.RUnlock()
+	return client.AuthToken, client.session
+}
+
+// refreshExpiredSession is a helper function that refreshes the session a request was rejected with, unless
+// another request (sharing this client) already refreshed it
+func (client *Client) refreshExpiredSession(expired int) {
+	client.sessionLock.Lock()
+	defer client.sessionLock.Unlock()
+	if client.session != expired {
+		return
+	}
+	if client.refreshSession() == nil {
+		client.session++
+	}
+}
+
 // refreshSession is a helper function that makes a POST request to refresh the client session
 // and saves the X-Auth-Token header
 func (client *Client) refreshSession() error {
 	// Make a request to create a new session
-	url := fmt.Sprintf("https://%s%s", client.ManagementIP.String(), LoginEndpoint)
+	url := fmt.Sprintf("https://%s%s", util.EndpointURLHost(client.ManagementEndpoint), LoginEndpoint)
 	log.WithFields(log.Fields{
 		"display_name": client.DisplayName,
 		"url":          url,
 	}).Trace("Making POST request")
-	response, err := resty.R().SetHeader(UserAgentHeader, UserAgent).SetHeader(APITokenHeader, client.APIToken).Post(url)
+	response, err := client.restClient.R().SetHeader(UserAgentHeader, UserAgent).SetHeader(APITokenHeader, client.APIToken).Post(url)
 
 	// Read the response
 	if err != nil {
@@ -368,3 +585,24 @@ func (client *Client) refreshSession() error {
 	client.AuthToken = tokenHeader
 	return nil
 }
+
+// supportsObjectStore is a helper function that returns whether the array supports the API version needed for
+// buckets and object store accounts
+func (client *Client) supportsObjectStore() bool {
+	return client.supportsAPIVersion(ObjectStoreAPIVersion)
+}
+
+// supportsAPIVersion is a helper function that returns whether the array supports the given API version, for
+// endpoints that aren't available in the preferred API version
+func (client *Client) supportsAPIVersion(apiVersion string) bool {
+	for _, ver := range client.apiVersions {
+		if ver == apiVersion {
+			return true
+		}
+	}
+	log.WithFields(log.Fields{
+		"api_version":  apiVersion,
+		"display_name": client.DisplayName,
+	}).Trace("Array doesn't support the API version, skipping")
+	return false
+}
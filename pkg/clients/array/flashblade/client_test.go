@@ -15,6 +15,10 @@
 package flashblade
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -27,19 +31,72 @@ const (
 )
 
 func TestFlashBladeClientWrongEndpoint(t *testing.T) {
-	_, err := NewClient("test-client", "101.103.45.103", TestArrayToken)
+	_, err := NewClient("test-client", []string{"101.103.45.103"}, TestArrayToken, nil)
 	assert.Error(t, err)
 }
 
 func TestFlashBladeClientInvalidEndpoint(t *testing.T) {
-	_, err := NewClient("test-client", "https://aaaaaa.com", TestArrayToken)
+	_, err := NewClient("test-client", []string{"https://aaaaaa.com"}, TestArrayToken, nil)
 	assert.Error(t, err)
 }
 
+func TestFlashBladeClientNoEndpoints(t *testing.T) {
+	_, err := NewClient("test-client", []string{}, TestArrayToken, nil)
+	assert.Error(t, err)
+}
+
+func TestFlashBladeClientEndpointFailover(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"versions": ["1.5", "1.9"]}`))
+	}))
+	defer server.Close()
+
+	// Nothing listens on the first endpoint, so the client should fail over to the second
+	client, err := NewClient("test-client", []string{"127.0.0.1:1", server.URL}, TestArrayToken, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL, client.GetManagementEndpoint())
+}
+
+func TestFlashBladeClientSharedSessionRefresh(t *testing.T) {
+	var logins int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == APIVersionEndpoint:
+			w.Write([]byte(`{"versions": ["1.5"]}`))
+		case r.URL.Path == LoginEndpoint:
+			atomic.AddInt32(&logins, 1)
+			w.Header().Set(AuthTokenHeader, "session-token")
+		case r.Header.Get(AuthTokenHeader) != "session-token":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Write([]byte(`{"items": []}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-client", []string{server.URL}, TestArrayToken, nil)
+	assert.NoError(t, err)
+
+	// Every request is rejected without a session, but only one of them should log in
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.GetBlades()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, atomic.LoadInt32(&logins))
+}
+
 func TestFlashBladeClientInvalidToken(t *testing.T) {
 	t.Skip("Waiting for mock interceptor")
 
-	client, err := NewClient("test-client", TestArrayEndpoint, "nope")
+	client, err := NewClient("test-client", []string{TestArrayEndpoint}, "nope", nil)
 	assert.NoError(t, err)
 
 	_, err = client.GetArrayInfo()
@@ -51,7 +108,7 @@ func TestFlashBladeClient(t *testing.T) {
 
 	logrus.SetLevel(logrus.TraceLevel)
 
-	client, err := NewClient("test-client", TestArrayEndpoint, TestArrayToken)
+	client, err := NewClient("test-client", []string{TestArrayEndpoint}, TestArrayToken, nil)
 	assert.NoError(t, err)
 
 	var response interface{}
@@ -82,7 +139,7 @@ func TestFlashBladeClient(t *testing.T) {
 	assert.NotNil(t, response)
 	assert.Equal(t, numFileSystems, response)
 
-	response, err = client.GetFileSystemPerformanceMetrics(120)
+	response, err = client.GetFileSystemPerformanceMetrics(120, "nfs", DefaultPerformancePageSize)
 	assert.NoError(t, err)
 	assert.NotNil(t, response)
 	// We expect 2 points per file system
@@ -15,6 +15,7 @@
 package flashblade
 
 import (
+	"crypto/tls"
 	"fmt"
 	"time"
 
@@ -26,11 +27,11 @@ import (
 )
 
 // NewCollector creates both a new array collector and its underlying array client
-func NewCollector(arrayID string, displayName string, managementEndpoint string, apiToken string, metaConnection resources.ArrayMetadata) (resources.ArrayCollector, error) {
+func NewCollector(arrayID string, displayName string, managementEndpoints []string, apiToken string, tlsConfig *tls.Config, metaConnection resources.ArrayMetadata, pageSize int) (resources.ArrayCollector, error) {
 	timer := timing.NewStageTimer("flashblade.NewCollector", log.Fields{"display_name": displayName})
 	defer timer.Finish()
 
-	arrayClient, err := NewClient(displayName, managementEndpoint, apiToken)
+	arrayClient, err := NewClient(displayName, managementEndpoints, apiToken, tlsConfig)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"display_name": displayName,
@@ -43,13 +44,15 @@ func NewCollector(arrayID string, displayName string, managementEndpoint string,
 		ArrayType:      common.FlashBlade,
 		Client:         arrayClient,
 		DisplayName:    displayName,
-		MgmtEndpoint:   managementEndpoint,
+		MgmtEndpoint:   arrayClient.GetManagementEndpoint(),
+		PageSize:       pageSize,
 		metaConnection: metaConnection,
 	}
 
 	log.WithFields(log.Fields{
 		"array_type":   collector.ArrayType,
 		"display_name": collector.DisplayName,
+		"endpoint":     collector.MgmtEndpoint,
 	}).Info("Successfully created FlashBlade Collector")
 	return &collector, nil
 }
@@ -153,7 +156,8 @@ func (collector *Collector) GetAllArrayData() (*metrics.AllArrayData, error) {
 	return &allArrayData, nil
 }
 
-// GetAllVolumeData makes multiple underlying requests to get metric data for all volumes (file systems)
+// GetAllVolumeData makes multiple underlying requests to get metric data for all volumes (file systems, and buckets
+// and object store accounts if the array serves S3)
 func (collector *Collector) GetAllVolumeData(timeWindow int64) (*metrics.AllVolumeData, error) {
 	log.WithFields(log.Fields{
 		"display_name": collector.DisplayName,
@@ -176,9 +180,17 @@ func (collector *Collector) GetAllVolumeData(timeWindow int64) (*metrics.AllVolu
 	}
 
 	timer.Stage("GetFileSystemPerformanceMetrics")
-	performanceResponse, err := collector.Client.GetFileSystemPerformanceMetrics(timeWindow)
-	if err != nil {
-		return nil, err // If we are missing performance data, it's too messy to tie things together
+	protocolResponses := make(map[string][]*FileSystemPerformanceMetricsResponse)
+	for _, protocol := range metrics.FileSystemProtocols {
+		response, err := collector.Client.GetFileSystemPerformanceMetrics(timeWindow, protocol, collector.PageSize)
+		if err != nil {
+			collector.logIncompleteData(err, fmt.Sprintf("GetFileSystemPerformanceMetrics(%s)", protocol))
+			continue
+		}
+		protocolResponses[protocol] = response
+	}
+	if len(protocolResponses) == 0 {
+		return nil, fmt.Errorf("Could not get file system performance metrics for any protocol") // If we are missing performance data, it's too messy to tie things together
 	}
 
 	timer.Stage("GetFileSystemSnapshots")
@@ -212,26 +224,85 @@ func (collector *Collector) GetAllVolumeData(timeWindow int64) (*metrics.AllVolu
 		capacityMetricsMap[response.Name] = metric
 	}
 
-	// Convert the performance metrics and combine the metrics together
+	// Convert the performance metrics and group each data point of a file system across the protocols
 	// Note: each file system may have multiple data points here, order does not matter
-	var combinedVolumeMetrics []*metrics.VolumeMetric
-	for _, response := range performanceResponse {
-		performanceMetric := convertVolumePerformanceMetricsResponse(response)
-		var capacityMetric *metrics.VolumeCapacityMetric
-		if _, ok := capacityMetricsMap[response.Name]; ok {
-			capacityMetric = capacityMetricsMap[response.Name]
+	type dataPoint struct {
+		name string
+		time uint64
+	}
+	var dataPoints []dataPoint
+	protocolMetricsMap := make(map[dataPoint]map[string]*metrics.VolumePerformanceMetric)
+	for _, protocol := range metrics.FileSystemProtocols {
+		for _, response := range protocolResponses[protocol] {
+			point := dataPoint{name: response.Name, time: response.Time}
+			if _, ok := protocolMetricsMap[point]; !ok {
+				protocolMetricsMap[point] = make(map[string]*metrics.VolumePerformanceMetric)
+				dataPoints = append(dataPoints, point)
+			}
+			protocolMetricsMap[point][protocol] = convertVolumePerformanceMetricsResponse(response)
 		}
+	}
+
+	// Combine the protocols and the capacity metrics together
+	var combinedVolumeMetrics []*metrics.VolumeMetric
+	for _, point := range dataPoints {
 		volumeMetric := &metrics.VolumeMetric{
-			VolumeCapacityMetric:    capacityMetric,
-			VolumePerformanceMetric: performanceMetric,
+			VolumeCapacityMetric:    capacityMetricsMap[point.name],
+			VolumePerformanceMetric: combineProtocolPerformance(protocolMetricsMap[point]),
 			ArrayDisplayName:        collector.DisplayName,
 			ArrayID:                 collector.ArrayID,
 			ArrayName:               arrayInfo.Name,
 			ArrayTags:               arrayTags,
-			CreatedAt:               int64(response.Time) / 1000, // sec
-			Type:                    "FileSystem",
-			VolumeName:              response.Name,
+			CreatedAt:               int64(point.time) / 1000, // sec
+			Type:                    metrics.VolumeMetricTypeFileSystem,
+			VolumeName:              point.name,
+			ProtocolPerformance:     protocolMetricsMap[point],
+		}
+		combinedVolumeMetrics = append(combinedVolumeMetrics, volumeMetric)
+	}
+
+	// Buckets and object store accounts go through the same pipeline, so capacity dashboards cover S3 tenants too
+	objectStoreBundle := collector.fetchObjectStore()
+	newObjectStoreMetric := func(metricType string, name string, createdAt int64) *metrics.VolumeMetric {
+		return &metrics.VolumeMetric{
+			ArrayDisplayName: collector.DisplayName,
+			ArrayID:          collector.ArrayID,
+			ArrayName:        arrayInfo.Name,
+			ArrayTags:        arrayTags,
+			CreatedAt:        createdAt,
+			Type:             metricType,
+			VolumeName:       name,
 		}
+	}
+	creationTime := time.Now().Unix()
+
+	bucketPerformanceMap := make(map[string]*FileSystemPerformanceMetricsResponse)
+	for _, response := range objectStoreBundle.PerformanceMetricsResponse {
+		bucketPerformanceMap[response.Name] = response
+	}
+	for _, response := range objectStoreBundle.BucketsResponse {
+		if response.Destroyed {
+			continue
+		}
+		volumeMetric := newObjectStoreMetric(metrics.VolumeMetricTypeBucket, response.Name, creationTime)
+		volumeMetric.VolumeCapacityMetric = convertObjectStoreCapacity(response.Space, response.ObjectCount)
+		volumeMetric.Account = response.Account.Name
+		volumeMetric.Versioning = response.Versioning
+		if performance, ok := bucketPerformanceMap[response.Name]; ok {
+			volumeMetric.VolumePerformanceMetric = convertVolumePerformanceMetricsResponse(performance)
+			volumeMetric.CreatedAt = int64(performance.Time) / 1000 // sec
+		}
+		combinedVolumeMetrics = append(combinedVolumeMetrics, volumeMetric)
+	}
+
+	accountUserCountMap := make(map[string]uint32)
+	for _, response := range objectStoreBundle.UsersResponse {
+		accountUserCountMap[response.Account.Name]++
+	}
+	for _, response := range objectStoreBundle.AccountsResponse {
+		volumeMetric := newObjectStoreMetric(metrics.VolumeMetricTypeObjectStoreAccount, response.Name, creationTime)
+		volumeMetric.VolumeCapacityMetric = convertObjectStoreCapacity(response.Space, response.ObjectCount)
+		volumeMetric.UserCount = accountUserCountMap[response.Name]
 		combinedVolumeMetrics = append(combinedVolumeMetrics, volumeMetric)
 	}
 
@@ -240,6 +311,225 @@ func (collector *Collector) GetAllVolumeData(timeWindow int64) (*metrics.AllVolu
 	}, nil
 }
 
+// Type guard: ensure this implements the interface
+var _ resources.HardwareCollector = (*Collector)(nil)
+
+// GetAllHardwareData makes multiple underlying requests to get the status of every blade, power supply, fan and
+// other hardware component of the array
+func (collector *Collector) GetAllHardwareData() (*metrics.AllHardwareData, error) {
+	log.WithFields(log.Fields{
+		"display_name": collector.DisplayName,
+	}).Trace("Getting all hardware data")
+	timer := timing.NewStageTimer("flashblade.Collector.GetAllHardwareData", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	// Get the basic array info
+	arrayInfo, err := collector.Client.GetArrayInfo()
+	if err != nil {
+		return nil, err // Can't mark the data without the array name
+	}
+
+	responseBundle := collector.fetchHardware()
+
+	// Fetch the array tags
+	arrayTags, err := collector.GetArrayTags()
+	if err != nil {
+		arrayTags = map[string]string{}
+	}
+
+	timer.Stage("parse_responses")
+
+	// Record the current time for the metrics
+	creationTime := time.Now().Unix()
+
+	newMetric := func(name string, componentType string, status string, details string, serial string) *metrics.HardwareMetric {
+		return &metrics.HardwareMetric{
+			ArrayDisplayName: collector.DisplayName,
+			ArrayID:          collector.ArrayID,
+			ArrayName:        arrayInfo.Name,
+			ArrayTags:        arrayTags,
+			CreatedAt:        creationTime,
+			ComponentName:    name,
+			ComponentType:    componentType,
+			Status:           status,
+			Healthy:          metrics.IsHealthyHardwareStatus(status),
+			Details:          details,
+			Serial:           serial,
+		}
+	}
+
+	var hardwareMetrics []*metrics.HardwareMetric
+	for _, response := range responseBundle.BladesResponse {
+		metric := newMetric(response.Name, metrics.HardwareComponentBlade, response.Status, response.Details, response.Serial)
+		metric.Capacity = response.RawCapacity
+		metric.Progress = response.Progress
+		hardwareMetrics = append(hardwareMetrics, metric)
+	}
+
+	for _, response := range responseBundle.HardwareResponse {
+		componentType, ok := classifyHardwareComponent(response.Type)
+		if !ok {
+			continue
+		}
+		metric := newMetric(response.Name, componentType, response.Status, response.Details, response.Serial)
+		metric.Model = response.Model
+		metric.Slot = response.Slot
+		metric.Speed = response.Speed
+		metric.Temperature = response.Temperature
+		hardwareMetrics = append(hardwareMetrics, metric)
+	}
+
+	return &metrics.AllHardwareData{
+		HardwareMetrics: hardwareMetrics,
+	}, nil
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.AuditCollector = (*Collector)(nil)
+
+// GetAuditEvents gets the commands run on the array at or after the given time (Unix seconds). The array doesn't
+// keep a log of sessions apart from its audit log.
+func (collector *Collector) GetAuditEvents(since int64) ([]*metrics.AuditEvent, error) {
+	log.WithFields(log.Fields{
+		"display_name": collector.DisplayName,
+		"since":        since,
+	}).Trace("Getting audit events")
+	timer := timing.NewStageTimer("flashblade.Collector.GetAuditEvents", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	// Get the basic array info
+	arrayInfo, err := collector.Client.GetArrayInfo()
+	if err != nil {
+		return nil, err // Can't mark the data without the array name
+	}
+
+	timer.Stage("GetAudits")
+	auditResponses, err := collector.Client.GetAudits(since)
+	if err != nil {
+		return nil, err
+	}
+
+	timer.Stage("parse_responses")
+
+	// Record the current time for the events
+	creationTime := time.Now().Unix()
+
+	auditEvents := make([]*metrics.AuditEvent, 0, len(auditResponses))
+	for _, response := range auditResponses {
+		auditEvents = append(auditEvents, &metrics.AuditEvent{
+			ArrayDisplayName: collector.DisplayName,
+			ArrayID:          collector.ArrayID,
+			ArrayName:        arrayInfo.Name,
+			ArrayType:        collector.ArrayType,
+			Arguments:        response.Arguments,
+			CollectedAt:      creationTime,
+			Command:          response.Command,
+			EventID:          response.ID,
+			Interface:        response.UserInterface,
+			Kind:             metrics.AuditEventCommand,
+			Subcommand:       response.Subcommand,
+			Time:             response.Time / 1000, // Milliseconds
+			User:             response.User,
+		})
+	}
+	return auditEvents, nil
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.SnapshotCollector = (*Collector)(nil)
+
+// GetSnapshots gets every file system snapshot on the array, along with the policy that took it (if any). The
+// array doesn't report the size of file system snapshots.
+func (collector *Collector) GetSnapshots() ([]*metrics.Snapshot, error) {
+	log.WithField("display_name", collector.DisplayName).Trace("Getting snapshot inventory")
+	timer := timing.NewStageTimer("flashblade.Collector.GetSnapshots", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	// Get the basic array info
+	arrayInfo, err := collector.Client.GetArrayInfo()
+	if err != nil {
+		return nil, err // Can't mark the data without the array name
+	}
+
+	timer.Stage("GetFileSystemSnapshots")
+	snapshotResponses, err := collector.Client.GetFileSystemSnapshots()
+	if err != nil {
+		return nil, err // A partial inventory would look like the missing snapshots were deleted
+	}
+
+	timer.Stage("parse_responses")
+
+	// Record the current time for the inventory
+	creationTime := time.Now().Unix()
+
+	snapshots := make([]*metrics.Snapshot, 0, len(snapshotResponses))
+	for _, response := range snapshotResponses {
+		snapshots = append(snapshots, &metrics.Snapshot{
+			ArrayDisplayName: collector.DisplayName,
+			ArrayID:          collector.ArrayID,
+			ArrayName:        arrayInfo.Name,
+			ArrayType:        collector.ArrayType,
+			CollectedAt:      creationTime,
+			Created:          response.Created / 1000, // Milliseconds
+			Managed:          response.Policy.Name != "",
+			Name:             response.Name,
+			Policy:           response.Policy.Name,
+			Source:           response.Source,
+			Suffix:           response.Suffix,
+		})
+	}
+	return snapshots, nil
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.HistoryCollector = (*Collector)(nil)
+
+// GetArrayPerformanceHistory gets the performance metrics the array kept between the given times (Unix seconds),
+// oldest first, to fill in metrics that weren't collected. Only the performance part of the metrics is set.
+func (collector *Collector) GetArrayPerformanceHistory(start int64, end int64) ([]*metrics.ArrayMetric, error) {
+	log.WithFields(log.Fields{
+		"display_name": collector.DisplayName,
+		"end":          end,
+		"start":        start,
+	}).Trace("Getting array performance history")
+	timer := timing.NewStageTimer("flashblade.Collector.GetArrayPerformanceHistory", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	// Get the basic array info
+	arrayInfo, err := collector.Client.GetArrayInfo()
+	if err != nil {
+		return nil, err // Can't mark the data without the array name
+	}
+
+	history, err := collector.Client.GetArrayPerformanceHistory(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Not fatal, just like for the collected metrics
+	arrayTags, err := collector.GetArrayTags()
+	if err != nil {
+		arrayTags = map[string]string{}
+	}
+
+	timer.Stage("parse_responses")
+
+	var arrayMetrics []*metrics.ArrayMetric
+	for _, response := range history {
+		arrayMetrics = append(arrayMetrics, &metrics.ArrayMetric{
+			ArrayPerformanceMetric: convertArrayPerformanceMetricsResponse(response),
+			ArrayID:                collector.ArrayID,
+			ArrayName:              arrayInfo.Name,
+			ArrayType:              collector.ArrayType,
+			Backfilled:             true,
+			CreatedAt:              int64(response.Time / 1000), // Milliseconds
+			DisplayName:            collector.DisplayName,
+			Tags:                   arrayTags,
+		})
+	}
+	return arrayMetrics, nil
+}
+
 // GetArrayID returns the ID of the array
 func (collector *Collector) GetArrayID() string {
 	return collector.ArrayID
@@ -293,6 +583,11 @@ func (collector *Collector) GetDisplayName() string {
 	return collector.DisplayName
 }
 
+// GetManagementEndpoint returns the management endpoint the collector is connected through
+func (collector *Collector) GetManagementEndpoint() string {
+	return collector.MgmtEndpoint
+}
+
 // fetchAllAlerts is a helper function that makes a large request for all alerts and adds them to the channel
 func (collector *Collector) fetchAllAlerts(alertsChan chan []*AlertResponse) {
 	timer := timing.NewStageTimer("flashblade.Collector.fetchAllAlerts", log.Fields{"display_name": collector.DisplayName})
@@ -353,6 +648,74 @@ func (collector *Collector) fetchObjectCounts(itemCountChan chan ObjectCountResp
 	itemCountChan <- responseBundle
 }
 
+// fetchObjectStore is a helper function that makes requests for the buckets, their performance, and the object
+// store accounts and users, and returns them bundled together
+func (collector *Collector) fetchObjectStore() ObjectStoreResponseBundle {
+	timer := timing.NewStageTimer("flashblade.Collector.fetchObjectStore", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	timer.Stage("GetBuckets")
+	bucketsResponse, err := collector.Client.GetBuckets()
+	if err != nil {
+		collector.logIncompleteData(err, "GetBuckets")
+		bucketsResponse = []*BucketResponse{}
+	}
+
+	timer.Stage("GetBucketPerformanceMetrics")
+	performanceResponse, err := collector.Client.GetBucketPerformanceMetrics()
+	if err != nil {
+		collector.logIncompleteData(err, "GetBucketPerformanceMetrics")
+		performanceResponse = []*FileSystemPerformanceMetricsResponse{}
+	}
+
+	timer.Stage("GetObjectStoreAccounts")
+	accountsResponse, err := collector.Client.GetObjectStoreAccounts()
+	if err != nil {
+		collector.logIncompleteData(err, "GetObjectStoreAccounts")
+		accountsResponse = []*ObjectStoreAccountResponse{}
+	}
+
+	timer.Stage("GetObjectStoreUsers")
+	usersResponse, err := collector.Client.GetObjectStoreUsers()
+	if err != nil {
+		collector.logIncompleteData(err, "GetObjectStoreUsers")
+		usersResponse = []*ObjectStoreUserResponse{}
+	}
+
+	return ObjectStoreResponseBundle{
+		AccountsResponse:           accountsResponse,
+		BucketsResponse:            bucketsResponse,
+		PerformanceMetricsResponse: performanceResponse,
+		UsersResponse:              usersResponse,
+	}
+}
+
+// fetchHardware is a helper function that makes requests for the blades and other hardware components, and returns
+// them bundled together
+func (collector *Collector) fetchHardware() HardwareResponseBundle {
+	timer := timing.NewStageTimer("flashblade.Collector.fetchHardware", log.Fields{"display_name": collector.DisplayName})
+	defer timer.Finish()
+
+	timer.Stage("GetBlades")
+	bladesResponse, err := collector.Client.GetBlades()
+	if err != nil {
+		collector.logIncompleteData(err, "GetBlades")
+		bladesResponse = []*BladeResponse{}
+	}
+
+	timer.Stage("GetHardware")
+	hardwareResponse, err := collector.Client.GetHardware()
+	if err != nil {
+		collector.logIncompleteData(err, "GetHardware")
+		hardwareResponse = []*HardwareResponse{}
+	}
+
+	return HardwareResponseBundle{
+		BladesResponse:   bladesResponse,
+		HardwareResponse: hardwareResponse,
+	}
+}
+
 // logIncompleteData is a helper function to log errors when data gathering failed at some stage
 func (collector *Collector) logIncompleteData(err error, subject string) {
 	log.WithFields(log.Fields{
@@ -379,6 +742,7 @@ func convertAlertsResponse(response *AlertResponse, arrayID string, arrayName st
 		Severity:         response.Severity,
 		State:            response.State,
 		Summary:          response.Subject,
+		Source:           metrics.AlertSourceArray,
 		Updated:          int64(response.Updated) / 1000, // Convert to seconds
 		Variables:        response.Variables,
 	}
@@ -443,3 +807,72 @@ func convertVolumePerformanceMetricsResponse(response *FileSystemPerformanceMetr
 		WriteLatency:   uint64(response.UsecPerWriteOp),
 	}
 }
+
+// combineProtocolPerformance is a helper function that adds up the performance of a file system over each protocol,
+// weighting the latency of each protocol by its operations. If there were too few operations to count, the highest
+// latency is used instead.
+func combineProtocolPerformance(protocolMetrics map[string]*metrics.VolumePerformanceMetric) *metrics.VolumePerformanceMetric {
+	combined := &metrics.VolumePerformanceMetric{}
+	var readLatencySum, writeLatencySum, otherLatencySum uint64
+	var maxReadLatency, maxWriteLatency, maxOtherLatency uint64
+	for _, metric := range protocolMetrics {
+		combined.ReadBandwidth += metric.ReadBandwidth
+		combined.WriteBandwidth += metric.WriteBandwidth
+		combined.ReadIOPS += metric.ReadIOPS
+		combined.WriteIOPS += metric.WriteIOPS
+		combined.OtherIOPS += metric.OtherIOPS
+		readLatencySum += metric.ReadLatency * metric.ReadIOPS
+		writeLatencySum += metric.WriteLatency * metric.WriteIOPS
+		otherLatencySum += metric.OtherLatency * metric.OtherIOPS
+		maxReadLatency = maxUint64(maxReadLatency, metric.ReadLatency)
+		maxWriteLatency = maxUint64(maxWriteLatency, metric.WriteLatency)
+		maxOtherLatency = maxUint64(maxOtherLatency, metric.OtherLatency)
+	}
+	combined.ReadLatency = weightedLatency(readLatencySum, combined.ReadIOPS, maxReadLatency)
+	combined.WriteLatency = weightedLatency(writeLatencySum, combined.WriteIOPS, maxWriteLatency)
+	combined.OtherLatency = weightedLatency(otherLatencySum, combined.OtherIOPS, maxOtherLatency)
+	return combined
+}
+
+// weightedLatency is a helper function that divides the operation-weighted latency sum by the operations, falling
+// back to the given latency if there were none
+func weightedLatency(latencySum uint64, operations uint64, fallback uint64) uint64 {
+	if operations == 0 {
+		return fallback
+	}
+	return latencySum / operations
+}
+
+// maxUint64 returns the larger of the two values
+func maxUint64(a uint64, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// convertObjectStoreCapacity converts the space of a bucket or object store account into the desired volume resource
+func convertObjectStoreCapacity(space FileSystemCapacitySpace, objectCount uint64) *metrics.VolumeCapacityMetric {
+	return &metrics.VolumeCapacityMetric{
+		DataReduction:    space.DataReduction,
+		ObjectCount:      objectCount,
+		ProvisionedSpace: 0, // Buckets and accounts have no provisioned size
+		TotalReduction:   0, // Does not apply to FlashBlade
+		UsedSpace:        space.TotalPhysical,
+	}
+}
+
+// classifyHardwareComponent is a helper function that works out the type of a /hardware component from its
+// reported type. Returns false for blades, which are reported with more detail by their own endpoint.
+func classifyHardwareComponent(hardwareType string) (string, bool) {
+	switch hardwareType {
+	case "fb":
+		return "", false
+	case "fan":
+		return metrics.HardwareComponentFan, true
+	case "pwr":
+		return metrics.HardwareComponentPowerSupply, true
+	default:
+		return metrics.HardwareComponentOther, true
+	}
+}
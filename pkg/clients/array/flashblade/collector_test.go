@@ -19,20 +19,21 @@ import (
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestFlashBladeCollectorInvalidEndpoint(t *testing.T) {
-	_, err := NewCollector("000000000000000000000000", "test-collector", "0.131.105.128", TestArrayToken, nil)
+	_, err := NewCollector("000000000000000000000000", "test-collector", []string{"0.131.105.128"}, TestArrayToken, nil, nil, DefaultPerformancePageSize)
 	assert.Error(t, err)
 }
 
 func TestFlashBladeCollectorInvalidToken(t *testing.T) {
 	t.Skip("Waiting for mock interceptor")
 
-	client, err := NewCollector("000000000000000000000000", "test-collector", TestArrayEndpoint, "nah", nil)
+	client, err := NewCollector("000000000000000000000000", "test-collector", []string{TestArrayEndpoint}, "nah", nil, nil, DefaultPerformancePageSize)
 	assert.NoError(t, err)
 
 	_, err = client.GetArrayName()
@@ -49,7 +50,7 @@ func TestFlashBladeCollector(t *testing.T) {
 		"tag1": "value1",
 	}, nil)
 
-	collector, err := NewCollector("000000000000000000000000", "test-collector", TestArrayEndpoint, TestArrayToken, metaInterface)
+	collector, err := NewCollector("000000000000000000000000", "test-collector", []string{TestArrayEndpoint}, TestArrayToken, nil, metaInterface, DefaultPerformancePageSize)
 	assert.NoError(t, err)
 
 	var response interface{}
@@ -106,7 +107,7 @@ func TestFlashBladeCollectorTagFetchError(t *testing.T) {
 	metaInterface := &mock.ArrayMetadataImpl{}
 	metaInterface.On("GetTags", "000000000000000000000000").Return(map[string]string{}, fmt.Errorf("Some error"))
 
-	collector, err := NewCollector("000000000000000000000000", "test-collector", TestArrayEndpoint, TestArrayToken, metaInterface)
+	collector, err := NewCollector("000000000000000000000000", "test-collector", []string{TestArrayEndpoint}, TestArrayToken, nil, metaInterface, DefaultPerformancePageSize)
 	assert.NoError(t, err)
 
 	var response interface{}
@@ -149,3 +150,303 @@ func TestFlashBladeCollectorTagFetchError(t *testing.T) {
 	response = collector.GetDisplayName()
 	assert.NotNil(t, "test-collector", response)
 }
+
+// hardwareTestClient stubs out the client requests made when collecting hardware data
+type hardwareTestClient struct {
+	ArrayClient
+}
+
+func (c *hardwareTestClient) GetArrayInfo() (*ArrayInfoResponse, error) {
+	return &ArrayInfoResponse{Name: "blade-1", Version: "2.3.0"}, nil
+}
+
+func (c *hardwareTestClient) GetBlades() ([]*BladeResponse, error) {
+	return []*BladeResponse{
+		{Name: "CH1.FB1", Status: "healthy", Serial: "abc", RawCapacity: 2048},
+		{Name: "CH1.FB2", Status: "unhealthy", Details: "Blade is not responding"},
+		{Name: "CH1.FB3", Status: "unused"},
+	}, nil
+}
+
+func (c *hardwareTestClient) GetHardware() ([]*HardwareResponse, error) {
+	return []*HardwareResponse{
+		{Name: "CH1", Type: "ch", Status: "healthy"},
+		{Name: "CH1.FB1", Type: "fb", Status: "healthy"},
+		{Name: "CH1.FAN1", Type: "fan", Status: "healthy", Speed: 4000},
+		{Name: "CH1.PWR1", Type: "pwr", Status: "critical", Model: "PS-1"},
+	}, nil
+}
+
+func TestFlashBladeCollectorHardwareData(t *testing.T) {
+	metaInterface := &mock.ArrayMetadataImpl{}
+	metaInterface.On("GetTags", "000000000000000000000000").Return(map[string]string{}, nil)
+
+	collector := &Collector{
+		ArrayID:        "000000000000000000000000",
+		ArrayType:      common.FlashBlade,
+		Client:         &hardwareTestClient{},
+		DisplayName:    "test-blade",
+		metaConnection: metaInterface,
+	}
+
+	hardwareData, err := collector.GetAllHardwareData()
+	assert.NoError(t, err)
+	// All three blades, and the chassis, fan and power supply (the blade from /hardware is skipped)
+	assert.Len(t, hardwareData.HardwareMetrics, 6)
+
+	byName := map[string]*metrics.HardwareMetric{}
+	for _, metric := range hardwareData.HardwareMetrics {
+		assert.Equal(t, "blade-1", metric.ArrayName)
+		byName[metric.ComponentType+"/"+metric.ComponentName] = metric
+	}
+
+	blade1 := byName["blade/CH1.FB1"]
+	assert.True(t, blade1.Healthy)
+	assert.Equal(t, uint64(2048), blade1.Capacity)
+	assert.Equal(t, "abc", blade1.Serial)
+	assert.False(t, byName["blade/CH1.FB2"].Healthy)
+	assert.True(t, byName["blade/CH1.FB3"].Healthy)
+
+	assert.Equal(t, uint64(4000), byName["fan/CH1.FAN1"].Speed)
+	power := byName["power_supply/CH1.PWR1"]
+	assert.False(t, power.Healthy)
+	assert.Equal(t, "PS-1", power.Model)
+	assert.NotNil(t, byName["other/CH1"])
+}
+
+// objectStoreTestClient stubs out the client requests made when collecting volume data from an array serving S3
+type objectStoreTestClient struct {
+	ArrayClient
+}
+
+func (c *objectStoreTestClient) GetArrayInfo() (*ArrayInfoResponse, error) {
+	return &ArrayInfoResponse{Name: "blade-1", Version: "2.3.0"}, nil
+}
+
+func (c *objectStoreTestClient) GetFileSystemCapacityMetrics() ([]*FileSystemCapacityMetricsResponse, error) {
+	return []*FileSystemCapacityMetricsResponse{}, nil
+}
+
+func (c *objectStoreTestClient) GetFileSystemPerformanceMetrics(window int64, protocol string, pageSize int) ([]*FileSystemPerformanceMetricsResponse, error) {
+	return []*FileSystemPerformanceMetricsResponse{}, nil
+}
+
+func (c *objectStoreTestClient) GetFileSystemSnapshots() ([]*FileSystemSnapshotResponse, error) {
+	return []*FileSystemSnapshotResponse{}, nil
+}
+
+func (c *objectStoreTestClient) GetBuckets() ([]*BucketResponse, error) {
+	return []*BucketResponse{
+		{Name: "bucket1", Account: ObjectStoreReference{Name: "account1"}, ObjectCount: 42, Versioning: "enabled", Space: FileSystemCapacitySpace{TotalPhysical: 2048, DataReduction: 1.5}},
+		{Name: "bucket2", Account: ObjectStoreReference{Name: "account1"}, ObjectCount: 1, Versioning: "none"},
+		{Name: "destroyed", Account: ObjectStoreReference{Name: "account1"}, Destroyed: true},
+	}, nil
+}
+
+func (c *objectStoreTestClient) GetBucketPerformanceMetrics() ([]*FileSystemPerformanceMetricsResponse, error) {
+	return []*FileSystemPerformanceMetricsResponse{{Name: "bucket1", ReadsPerSec: 10, ReadBytesPerSec: 4096, Time: 1000000}}, nil
+}
+
+func (c *objectStoreTestClient) GetObjectStoreAccounts() ([]*ObjectStoreAccountResponse, error) {
+	return []*ObjectStoreAccountResponse{{Name: "account1", ObjectCount: 43, Space: FileSystemCapacitySpace{TotalPhysical: 4096}}}, nil
+}
+
+func (c *objectStoreTestClient) GetObjectStoreUsers() ([]*ObjectStoreUserResponse, error) {
+	return []*ObjectStoreUserResponse{
+		{Name: "account1/user1", Account: ObjectStoreReference{Name: "account1"}},
+		{Name: "account1/user2", Account: ObjectStoreReference{Name: "account1"}},
+	}, nil
+}
+
+func TestFlashBladeCollectorObjectStoreData(t *testing.T) {
+	metaInterface := &mock.ArrayMetadataImpl{}
+	metaInterface.On("GetTags", "000000000000000000000000").Return(map[string]string{}, nil)
+
+	collector := &Collector{
+		ArrayID:        "000000000000000000000000",
+		ArrayType:      common.FlashBlade,
+		Client:         &objectStoreTestClient{},
+		DisplayName:    "test-blade",
+		metaConnection: metaInterface,
+	}
+
+	volumeData, err := collector.GetAllVolumeData(30)
+	assert.NoError(t, err)
+	// Both live buckets and the account (the destroyed bucket is skipped)
+	assert.Len(t, volumeData.VolumeMetricsTimeSeries, 3)
+
+	bucket1 := volumeData.VolumeMetricsTimeSeries[0]
+	assert.Equal(t, metrics.VolumeMetricTypeBucket, bucket1.Type)
+	assert.Equal(t, "bucket1", bucket1.VolumeName)
+	assert.Equal(t, "account1", bucket1.Account)
+	assert.Equal(t, "enabled", bucket1.Versioning)
+	assert.Equal(t, uint64(42), bucket1.ObjectCount)
+	assert.Equal(t, uint64(2048), bucket1.UsedSpace)
+	assert.Equal(t, uint64(10), bucket1.ReadIOPS)
+	assert.Equal(t, int64(1000), bucket1.CreatedAt)
+
+	bucket2 := volumeData.VolumeMetricsTimeSeries[1]
+	assert.Equal(t, "bucket2", bucket2.VolumeName)
+	assert.Nil(t, bucket2.VolumePerformanceMetric)
+
+	account := volumeData.VolumeMetricsTimeSeries[2]
+	assert.Equal(t, metrics.VolumeMetricTypeObjectStoreAccount, account.Type)
+	assert.Equal(t, "account1", account.VolumeName)
+	assert.Equal(t, uint64(43), account.ObjectCount)
+	assert.Equal(t, uint32(2), account.UserCount)
+}
+
+// protocolTestClient stubs out the client requests made when collecting volume data from an array serving a file
+// system over NFS and SMB, failing to get the HTTP performance
+type protocolTestClient struct {
+	objectStoreTestClient
+	pageSizes []int
+}
+
+func (c *protocolTestClient) GetBuckets() ([]*BucketResponse, error) {
+	return []*BucketResponse{}, nil
+}
+
+func (c *protocolTestClient) GetObjectStoreAccounts() ([]*ObjectStoreAccountResponse, error) {
+	return []*ObjectStoreAccountResponse{}, nil
+}
+
+func (c *protocolTestClient) GetFileSystemCapacityMetrics() ([]*FileSystemCapacityMetricsResponse, error) {
+	return []*FileSystemCapacityMetricsResponse{{Name: "fs1", Provisioned: 8192}}, nil
+}
+
+func (c *protocolTestClient) GetFileSystemPerformanceMetrics(window int64, protocol string, pageSize int) ([]*FileSystemPerformanceMetricsResponse, error) {
+	c.pageSizes = append(c.pageSizes, pageSize)
+	switch protocol {
+	case "nfs":
+		return []*FileSystemPerformanceMetricsResponse{{Name: "fs1", ReadsPerSec: 30, UsecPerReadOp: 100, ReadBytesPerSec: 1024, Time: 1000000}}, nil
+	case "smb":
+		return []*FileSystemPerformanceMetricsResponse{{Name: "fs1", ReadsPerSec: 10, UsecPerReadOp: 500, ReadBytesPerSec: 2048, UsecPerWriteOp: 40, Time: 1000000}}, nil
+	case "http":
+		return nil, fmt.Errorf("protocol not supported")
+	}
+	return []*FileSystemPerformanceMetricsResponse{}, nil
+}
+
+func TestFlashBladeCollectorProtocolPerformance(t *testing.T) {
+	metaInterface := &mock.ArrayMetadataImpl{}
+	metaInterface.On("GetTags", "000000000000000000000000").Return(map[string]string{}, nil)
+
+	client := &protocolTestClient{}
+	collector := &Collector{
+		ArrayID:        "000000000000000000000000",
+		ArrayType:      common.FlashBlade,
+		Client:         client,
+		DisplayName:    "test-blade",
+		PageSize:       20,
+		metaConnection: metaInterface,
+	}
+
+	volumeData, err := collector.GetAllVolumeData(30)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{20, 20, 20, 20}, client.pageSizes)
+	assert.Len(t, volumeData.VolumeMetricsTimeSeries, 1)
+
+	fileSystem := volumeData.VolumeMetricsTimeSeries[0]
+	assert.Equal(t, metrics.VolumeMetricTypeFileSystem, fileSystem.Type)
+	assert.Equal(t, uint64(8192), fileSystem.ProvisionedSpace)
+	assert.Equal(t, int64(1000), fileSystem.CreatedAt)
+	assert.Equal(t, uint64(40), fileSystem.ReadIOPS)
+	assert.Equal(t, uint64(3072), fileSystem.ReadBandwidth)
+	// Weighted by operations: (30 * 100 + 10 * 500) / 40
+	assert.Equal(t, uint64(200), fileSystem.ReadLatency)
+	// No writes were counted, so the highest latency is kept
+	assert.Equal(t, uint64(40), fileSystem.WriteLatency)
+
+	// Only the protocols with data points make up the breakdown
+	assert.Len(t, fileSystem.ProtocolPerformance, 2)
+	assert.Equal(t, uint64(30), fileSystem.ProtocolPerformance["nfs"].ReadIOPS)
+	assert.Equal(t, uint64(500), fileSystem.ProtocolPerformance["smb"].ReadLatency)
+}
+
+// auditTestClient stubs out the client requests made when collecting audit events
+type auditTestClient struct {
+	ArrayClient
+}
+
+func (c *auditTestClient) GetArrayInfo() (*ArrayInfoResponse, error) {
+	return &ArrayInfoResponse{Name: "blade-1", Version: "3.0.0"}, nil
+}
+
+func (c *auditTestClient) GetAudits(since int64) ([]*AuditResponse, error) {
+	return []*AuditResponse{
+		{ID: "7f2c", Time: 1551441600000, User: "pureuser", Command: "purefs", Subcommand: "create", Arguments: "fs1", UserInterface: "CLI"},
+	}, nil
+}
+
+func TestFlashBladeCollectorAuditEvents(t *testing.T) {
+	collector := &Collector{
+		ArrayID:     "000000000000000000000000",
+		ArrayType:   common.FlashBlade,
+		Client:      &auditTestClient{},
+		DisplayName: "test-blade",
+	}
+
+	events, err := collector.GetAuditEvents(0)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, metrics.AuditEvent{
+		ArrayDisplayName: "test-blade",
+		ArrayID:          "000000000000000000000000",
+		ArrayName:        "blade-1",
+		ArrayType:        common.FlashBlade,
+		Arguments:        "fs1",
+		CollectedAt:      events[0].CollectedAt,
+		Command:          "purefs",
+		EventID:          "7f2c",
+		Interface:        "CLI",
+		Kind:             metrics.AuditEventCommand,
+		Subcommand:       "create",
+		Time:             1551441600,
+		User:             "pureuser",
+	}, *events[0])
+}
+
+// snapshotTestClient stubs out the client requests made when collecting the snapshot inventory
+type snapshotTestClient struct {
+	ArrayClient
+}
+
+func (c *snapshotTestClient) GetArrayInfo() (*ArrayInfoResponse, error) {
+	return &ArrayInfoResponse{Name: "blade-1", Version: "3.0.0"}, nil
+}
+
+func (c *snapshotTestClient) GetFileSystemSnapshots() ([]*FileSystemSnapshotResponse, error) {
+	return []*FileSystemSnapshotResponse{
+		{Name: "fs1.daily-1", Source: "fs1", Suffix: "daily-1", Created: 1551441600000, Policy: PolicyReference{Name: "daily"}},
+		{Name: "fs1.before-upgrade", Source: "fs1", Suffix: "before-upgrade", Created: 1546300800000},
+	}, nil
+}
+
+func TestFlashBladeCollectorSnapshots(t *testing.T) {
+	collector := &Collector{
+		ArrayID:     "000000000000000000000000",
+		ArrayType:   common.FlashBlade,
+		Client:      &snapshotTestClient{},
+		DisplayName: "test-blade",
+	}
+
+	snapshots, err := collector.GetSnapshots()
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, metrics.Snapshot{
+		ArrayDisplayName: "test-blade",
+		ArrayID:          "000000000000000000000000",
+		ArrayName:        "blade-1",
+		ArrayType:        common.FlashBlade,
+		CollectedAt:      snapshots[0].CollectedAt,
+		Created:          1551441600,
+		Managed:          true,
+		Name:             "fs1.daily-1",
+		Policy:           "daily",
+		Source:           "fs1",
+		Suffix:           "daily-1",
+	}, *snapshots[0])
+	assert.False(t, snapshots[1].Managed)
+	assert.Equal(t, "", snapshots[1].Policy)
+}
@@ -0,0 +1,39 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flashblade
+
+import (
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/registry"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+)
+
+func init() {
+	registry.Register(registry.Device{
+		Type: common.FlashBlade,
+		Capabilities: []string{
+			registry.CapabilityAudit,
+			registry.CapabilityHardware,
+			registry.CapabilityPerformanceHistory,
+			registry.CapabilitySnapshots,
+		},
+		NewCollector: newRegisteredCollector,
+	})
+}
+
+// newRegisteredCollector is a helper function that creates a collector for the device registry
+func newRegisteredCollector(arrayInfo *resources.ArrayRegistrationInfo, options registry.CollectorOptions) (resources.ArrayCollector, error) {
+	return NewCollector(arrayInfo.ID, arrayInfo.Name, arrayInfo.GetMgmtEndpoints(), arrayInfo.APIToken, options.TLSConfig, options.MetaConnection, options.PageSize)
+}
@@ -15,9 +15,10 @@
 package flashblade
 
 import (
-	"net"
+	"sync"
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/go-resty/resty"
 )
 
 // Client and collector
@@ -27,21 +28,35 @@ type ArrayClient interface {
 	GetAlerts() ([]*AlertResponse, error)
 	GetArrayCapacityMetrics() (*ArrayCapacityMetricsResponse, error)
 	GetArrayInfo() (*ArrayInfoResponse, error)
+	GetArrayPerformanceHistory(start int64, end int64) ([]*ArrayPerformanceMetricsResponse, error)
 	GetArrayPerformanceMetrics() (*ArrayPerformanceMetricsResponse, error)
+	GetAudits(since int64) ([]*AuditResponse, error)
+	GetBlades() ([]*BladeResponse, error)
+	GetBucketPerformanceMetrics() ([]*FileSystemPerformanceMetricsResponse, error)
+	GetBuckets() ([]*BucketResponse, error)
 	GetFileSystemCapacityMetrics() ([]*FileSystemCapacityMetricsResponse, error)
 	GetFileSystemCount() (uint32, error)
-	GetFileSystemPerformanceMetrics(window int64) ([]*FileSystemPerformanceMetricsResponse, error)
+	GetFileSystemPerformanceMetrics(window int64, protocol string, pageSize int) ([]*FileSystemPerformanceMetricsResponse, error)
 	GetFileSystemSnapshotCount() (uint32, error)
 	GetFileSystemSnapshots() ([]*FileSystemSnapshotResponse, error)
+	GetHardware() ([]*HardwareResponse, error)
+	GetManagementEndpoint() string
+	GetObjectStoreAccounts() ([]*ObjectStoreAccountResponse, error)
+	GetObjectStoreUsers() ([]*ObjectStoreUserResponse, error)
 }
 
 // Client is a FlashBlade client that handles specific REST API requests
 type Client struct {
-	APIToken     string
-	APIVersion   string
-	AuthToken    string
-	DisplayName  string
-	ManagementIP net.IP
+	APIToken           string
+	APIVersion         string
+	AuthToken          string
+	DisplayName        string
+	ManagementEndpoint string // The management endpoint (FQDN, IPv4 or IPv6) that answered
+
+	apiVersions []string // Every API version the array supports
+	restClient  *resty.Client
+	session     int          // Counts session refreshes, so concurrent requests rejected with the same session refresh it once
+	sessionLock sync.RWMutex // Guards AuthToken and session
 }
 
 // Collector is a FlashBlade collector that uses the client to make requests
@@ -51,6 +66,7 @@ type Collector struct {
 	Client         ArrayClient
 	DisplayName    string
 	MgmtEndpoint   string
+	PageSize       int // File system performance metrics requested per page
 	metaConnection resources.ArrayMetadata
 }
 
@@ -62,6 +78,20 @@ type ArrayMetricsResponseBundle struct {
 	PerformanceMetricsResponse *ArrayPerformanceMetricsResponse
 }
 
+// ObjectStoreResponseBundle is used to return all bucket and object store account responses together
+type ObjectStoreResponseBundle struct {
+	AccountsResponse           []*ObjectStoreAccountResponse
+	BucketsResponse            []*BucketResponse
+	PerformanceMetricsResponse []*FileSystemPerformanceMetricsResponse
+	UsersResponse              []*ObjectStoreUserResponse
+}
+
+// HardwareResponseBundle is used to return all hardware component responses together
+type HardwareResponseBundle struct {
+	BladesResponse   []*BladeResponse
+	HardwareResponse []*HardwareResponse
+}
+
 // ObjectCountResponseBundle is used to return all object count responses together
 type ObjectCountResponseBundle struct {
 	FileSystemCount uint32
@@ -154,6 +184,59 @@ type ArrayPerformanceMetricsResponse struct {
 	WritesPerSec   float64 `json:"writes_per_sec"`
 }
 
+// AuditGenericResponse is from /audits (REST 2.x)
+type AuditGenericResponse struct {
+	ContinuationToken string           `json:"continuation_token"`
+	Items             []*AuditResponse `json:"items"`
+}
+
+// AuditResponse is a sub-object of /audits
+type AuditResponse struct {
+	Arguments     string `json:"arguments"`
+	Command       string `json:"command"`
+	ID            string `json:"id"`
+	Subcommand    string `json:"subcommand"`
+	Time          int64  `json:"time"` // Milliseconds since epoch
+	User          string `json:"user"`
+	UserInterface string `json:"user_interface"`
+}
+
+// BladeGenericResponse is from /blades
+type BladeGenericResponse struct {
+	Items []*BladeResponse `json:"items"`
+}
+
+// BladeResponse is a sub-object from /blades
+type BladeResponse struct {
+	Details     string  `json:"details"`
+	Name        string  `json:"name"`
+	Progress    float64 `json:"progress"`
+	RawCapacity uint64  `json:"raw_capacity"`
+	Serial      string  `json:"serial"`
+	Status      string  `json:"status"`
+}
+
+// BucketGenericResponse is from /buckets
+type BucketGenericResponse struct {
+	Items []*BucketResponse `json:"items"`
+}
+
+// BucketResponse is a sub-object from /buckets
+type BucketResponse struct {
+	Account     ObjectStoreReference    `json:"account"`
+	Destroyed   bool                    `json:"destroyed"`
+	Name        string                  `json:"name"`
+	ObjectCount uint64                  `json:"object_count"`
+	Space       FileSystemCapacitySpace `json:"space"` // Same fields as for file systems
+	Versioning  string                  `json:"versioning"`
+}
+
+// BucketPerformanceMetricsGenericResponse is from /buckets/performance, which has the same fields as
+// /file-systems/performance
+type BucketPerformanceMetricsGenericResponse struct {
+	Items []*FileSystemPerformanceMetricsResponse `json:"items"`
+}
+
 // FileSystemCapacityMetricsGenericResponse is from /file-systems
 type FileSystemCapacityMetricsGenericResponse struct {
 	Items          []*FileSystemCapacityMetricsResponse `json:"items"`
@@ -207,7 +290,62 @@ type FileSystemSnapshotGenericResponse struct {
 
 // FileSystemSnapshotResponse is a sub-object of /file-system-snapshots
 type FileSystemSnapshotResponse struct {
-	Source string `json:"source"`
+	Created int64           `json:"created"` // Milliseconds
+	Name    string          `json:"name"`
+	Policy  PolicyReference `json:"policy"` // Empty for snapshots not taken by a policy
+	Source  string          `json:"source"`
+	Suffix  string          `json:"suffix"`
+}
+
+// HardwareGenericResponse is from /hardware
+type HardwareGenericResponse struct {
+	Items []*HardwareResponse `json:"items"`
+}
+
+// HardwareResponse is a sub-object from /hardware
+type HardwareResponse struct {
+	Details     string `json:"details"`
+	Model       string `json:"model"`
+	Name        string `json:"name"`
+	Serial      string `json:"serial"`
+	Slot        int    `json:"slot"`
+	Speed       uint64 `json:"speed"`
+	Status      string `json:"status"`
+	Temperature int    `json:"temperature"`
+	Type        string `json:"type"`
+}
+
+// ObjectStoreAccountGenericResponse is from /object-store-accounts
+type ObjectStoreAccountGenericResponse struct {
+	Items []*ObjectStoreAccountResponse `json:"items"`
+}
+
+// ObjectStoreAccountResponse is a sub-object from /object-store-accounts
+type ObjectStoreAccountResponse struct {
+	Name        string                  `json:"name"`
+	ObjectCount uint64                  `json:"object_count"`
+	Space       FileSystemCapacitySpace `json:"space"` // Same fields as for file systems
+}
+
+// ObjectStoreReference is a sub-object referring to another object store resource (such as the account of a bucket)
+type ObjectStoreReference struct {
+	Name string `json:"name"`
+}
+
+// ObjectStoreUserGenericResponse is from /object-store-users
+type ObjectStoreUserGenericResponse struct {
+	Items []*ObjectStoreUserResponse `json:"items"`
+}
+
+// ObjectStoreUserResponse is a sub-object from /object-store-users
+type ObjectStoreUserResponse struct {
+	Account ObjectStoreReference `json:"account"`
+	Name    string               `json:"name"`
+}
+
+// PolicyReference is a sub-object referring to a snapshot policy
+type PolicyReference struct {
+	Name string `json:"name"`
 }
 
 // PaginationResponse is a part of responses from all endpoints
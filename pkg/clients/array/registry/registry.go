@@ -0,0 +1,98 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+)
+
+var (
+	devicesLock sync.RWMutex
+	devices     = map[string]Device{}
+)
+
+// Register adds a device type to the registry. It panics if the device is missing its type or collector
+// function, or if its type is already registered, since that's a programming error.
+func Register(device Device) {
+	if len(strings.TrimSpace(device.Type)) == 0 {
+		panic("registry: device type must not be empty")
+	}
+	if device.NewCollector == nil {
+		panic(fmt.Sprintf("registry: device type %s has no collector function", device.Type))
+	}
+
+	devicesLock.Lock()
+	defer devicesLock.Unlock()
+	if _, ok := devices[device.Type]; ok {
+		panic(fmt.Sprintf("registry: device type %s is already registered", device.Type))
+	}
+	devices[device.Type] = device
+}
+
+// Lookup returns the registered device of the given type
+func Lookup(deviceType string) (Device, bool) {
+	devicesLock.RLock()
+	defer devicesLock.RUnlock()
+	device, ok := devices[deviceType]
+	return device, ok
+}
+
+// DeviceTypes returns the registered device types, sorted
+func DeviceTypes() []string {
+	devicesLock.RLock()
+	defer devicesLock.RUnlock()
+	deviceTypes := make([]string, 0, len(devices))
+	for deviceType := range devices {
+		deviceTypes = append(deviceTypes, deviceType)
+	}
+	sort.Strings(deviceTypes)
+	return deviceTypes
+}
+
+// HasCapability checks if the given device type is registered with the given capability
+func HasCapability(deviceType string, capability string) bool {
+	device, ok := Lookup(deviceType)
+	if !ok {
+		return false
+	}
+	for _, deviceCapability := range device.Capabilities {
+		if deviceCapability == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRegistration checks that the array's device type is registered, listing the supported types if it
+// isn't, and that it supports API clients if any credentials are given, then runs the device's own validation
+// of the registration fields
+func ValidateRegistration(array *resources.Array) error {
+	device, ok := Lookup(array.DeviceType)
+	if !ok {
+		return fmt.Errorf("Unknown device type %s, supported device types are: %s", array.DeviceType, strings.Join(DeviceTypes(), ", "))
+	}
+	if array.APIClient.IsSet() && !HasCapability(array.DeviceType, CapabilityAPIClient) {
+		return fmt.Errorf("API client credentials are not supported for %s", array.DeviceType)
+	}
+	if device.ValidateRegistration == nil {
+		return nil
+	}
+	return device.ValidateRegistration(array)
+}
@@ -0,0 +1,90 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCollector(arrayInfo *resources.ArrayRegistrationInfo, options CollectorOptions) (resources.ArrayCollector, error) {
+	return nil, nil
+}
+
+func TestRegisterLookup(t *testing.T) {
+	Register(Device{Type: "TestLookup", Capabilities: []string{CapabilityHosts}, NewCollector: newTestCollector})
+
+	device, ok := Lookup("TestLookup")
+	assert.True(t, ok)
+	assert.Equal(t, "TestLookup", device.Type)
+	assert.Contains(t, DeviceTypes(), "TestLookup")
+
+	_, ok = Lookup("TestMissing")
+	assert.False(t, ok)
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	assert.Panics(t, func() { Register(Device{Type: " ", NewCollector: newTestCollector}) })
+	assert.Panics(t, func() { Register(Device{Type: "TestNoCollector"}) })
+
+	Register(Device{Type: "TestDuplicate", NewCollector: newTestCollector})
+	assert.Panics(t, func() { Register(Device{Type: "TestDuplicate", NewCollector: newTestCollector}) })
+}
+
+func TestHasCapability(t *testing.T) {
+	Register(Device{Type: "TestCapability", Capabilities: []string{CapabilityPods, CapabilitySnapshots}, NewCollector: newTestCollector})
+
+	assert.True(t, HasCapability("TestCapability", CapabilityPods))
+	assert.False(t, HasCapability("TestCapability", CapabilityHosts))
+	assert.False(t, HasCapability("TestMissing", CapabilityPods))
+}
+
+func TestValidateRegistrationUnknownType(t *testing.T) {
+	Register(Device{Type: "TestKnown", NewCollector: newTestCollector})
+
+	err := ValidateRegistration(&resources.Array{DeviceType: "TestUnknown"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "TestUnknown")
+	assert.Contains(t, err.Error(), "TestKnown")
+}
+
+func TestValidateRegistrationAPIClient(t *testing.T) {
+	Register(Device{Type: "TestAPIClient", Capabilities: []string{CapabilityAPIClient}, NewCollector: newTestCollector})
+	Register(Device{Type: "TestNoAPIClient", NewCollector: newTestCollector})
+
+	credentials := resources.APIClientCredentials{ClientID: "client"}
+	assert.NoError(t, ValidateRegistration(&resources.Array{DeviceType: "TestAPIClient", APIClient: credentials}))
+	assert.Error(t, ValidateRegistration(&resources.Array{DeviceType: "TestNoAPIClient", APIClient: credentials}))
+	assert.NoError(t, ValidateRegistration(&resources.Array{DeviceType: "TestNoAPIClient"}))
+}
+
+func TestValidateRegistrationHook(t *testing.T) {
+	Register(Device{
+		Type:         "TestValidated",
+		NewCollector: newTestCollector,
+		ValidateRegistration: func(array *resources.Array) error {
+			if len(array.CACertificate) == 0 {
+				return errors.New("Missing ca_certificate")
+			}
+			return nil
+		},
+	})
+
+	assert.Error(t, ValidateRegistration(&resources.Array{DeviceType: "TestValidated"}))
+	assert.NoError(t, ValidateRegistration(&resources.Array{DeviceType: "TestValidated", CACertificate: "cert"}))
+}
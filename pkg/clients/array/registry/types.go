@@ -0,0 +1,56 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"crypto/tls"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+)
+
+// Capabilities a device type can list, for the optional collections that only some devices support
+const (
+	CapabilityAPIClient          = "api_client"          // Authenticates with API client credentials (REST 2.x)
+	CapabilityAudit              = "audit"               // Collects audit and session logs
+	CapabilityHardware           = "hardware"            // Collects hardware component status
+	CapabilityHosts              = "hosts"               // Collects host and host group metrics
+	CapabilityPerformanceHistory = "performance_history" // Backfills array performance from the array's history
+	CapabilityPods               = "pods"                // Collects pod metrics
+	CapabilityProtectionGroups   = "protection_groups"   // Collects protection group and replication metrics
+	CapabilitySnapshots          = "snapshots"           // Collects the snapshot inventory
+)
+
+// Device describes a device type collectors can be created for. Device packages register one for each
+// type they support (usually from an init function), so the factory, the API server and the job scheduling
+// don't need to know about them.
+type Device struct {
+	Type         string   // The device_type arrays are registered with
+	Capabilities []string // Which of the optional collections the device supports
+	NewCollector NewCollectorFunc
+
+	// ValidateRegistration checks the registration fields of an array of this type when it's registered or
+	// patched (optional)
+	ValidateRegistration func(array *resources.Array) error
+}
+
+// NewCollectorFunc creates a collector (and its underlying client) for a registered array
+type NewCollectorFunc func(arrayInfo *resources.ArrayRegistrationInfo, options CollectorOptions) (resources.ArrayCollector, error)
+
+// CollectorOptions holds the settings the factory passes to every collector it creates
+type CollectorOptions struct {
+	MetaConnection resources.ArrayMetadata
+	PageSize       int         // Items requested per page by collectors that page through results (FlashBlade file systems)
+	TLSConfig      *tls.Config // Verifies the array certificate (nil skips verification)
+}
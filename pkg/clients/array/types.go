@@ -14,10 +14,43 @@
 
 package array
 
-import "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+import (
+	"sync"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+)
 
 // restFactory is an implementation of Factory that produces REST client implementations
 // of Collector, based on DeviceType
 type restFactory struct {
-	metaConnection resources.ArrayMetadata
+	metaConnection     resources.ArrayMetadata
+	flashBladePageSize int // File system performance metrics requested per page from FlashBlades
+}
+
+// CachingFactory wraps a collector factory, keeping the collectors it creates (along with their negotiated API
+// version and array session) so they are shared by every job for an array until its registration changes
+type CachingFactory struct {
+	factory resources.CollectorFactory
+
+	lock       sync.Mutex
+	collectors map[string]*cachedCollector // By array ID
+	hits       uint64
+	misses     uint64
+	evicted    uint64
+}
+
+// CacheStatus gives the number of cached collectors and how the cache has been used since startup
+type CacheStatus struct {
+	Collectors int    // Collectors currently cached
+	Hits       uint64 // Requests served by a cached collector
+	Misses     uint64 // Requests that had to create a collector
+	Evicted    uint64 // Collectors dropped because they failed, their array was deleted or its registration changed
+}
+
+// cachedCollector holds the collector of a single array. The lock is held while the collector is created,
+// so concurrent jobs for the same array wait for it instead of each opening a session.
+type cachedCollector struct {
+	lock      sync.Mutex
+	hash      string // Hash of the registration info (API token, credentials, endpoints, ...) it was created with
+	collector resources.ArrayCollector
 }
@@ -0,0 +1,121 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/http/errors"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guards: ensure this implements the interfaces
+var _ resources.AlertRuleDatabase = (*Client)(nil)
+var _ metrics.RuleAlertDatabase = (*Client)(nil)
+
+const (
+	// More rules than anyone should practically define, and more open rule alerts than
+	// there should practically be at once
+	maxAlertRules     = 1000
+	maxOpenRuleAlerts = 10000
+)
+
+// FindAlertRules gets the alert rules with the given IDs, or every alert rule if no IDs are given
+func (c *Client) FindAlertRules(ids []string) ([]*resources.AlertRule, error) {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	var query elastic.Query = elastic.NewMatchAllQuery()
+	if len(ids) > 0 {
+		query = elastic.NewIdsQuery(alertRulesIndexTypeName).Ids(ids...)
+	}
+
+	res, err := c.esclient.Search(alertRulesIndexName).Type(alertRulesIndexTypeName).Query(query).Size(maxAlertRules).Sort("Name", true).IgnoreUnavailable(true).AllowNoIndices(true).Do(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	results := []*resources.AlertRule{}
+	for _, res := range res.Each(reflect.TypeOf(&resources.AlertRule{})) {
+		results = append(results, res.(*resources.AlertRule))
+	}
+	return results, nil
+}
+
+// InsertAlertRule inserts the given alert rule into Elastic
+func (c *Client) InsertAlertRule(rule *resources.AlertRule) error {
+	return c.indexAlertRule(rule)
+}
+
+// UpdateAlertRule replaces the stored alert rule with the same ID as the given rule
+func (c *Client) UpdateAlertRule(rule *resources.AlertRule) error {
+	return c.indexAlertRule(rule)
+}
+
+// DeleteAlertRules deletes the alert rules with the given IDs from Elastic
+func (c *Client) DeleteAlertRules(ids []string) ([]string, error) {
+	ctx := context.Background()
+
+	// Get all the rules that exist first, so we only report the ones actually deleted
+	rules, err := c.FindAlertRules(ids)
+	if err != nil {
+		return nil, err
+	}
+	deletedIDs := []string{}
+	for _, rule := range rules {
+		deletedIDs = append(deletedIDs, rule.InternalID)
+	}
+	if len(deletedIDs) == 0 {
+		return deletedIDs, nil
+	}
+
+	_, err = c.esclient.DeleteByQuery(alertRulesIndexName).Type(alertRulesIndexTypeName).Query(elastic.NewIdsQuery(alertRulesIndexTypeName).Ids(deletedIDs...)).Do(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+	return deletedIDs, nil
+}
+
+// FindOpenRuleAlerts gets every alert raised by an alert rule that hasn't been closed yet
+func (c *Client) FindOpenRuleAlerts() ([]*metrics.Alert, error) {
+	return c.findOpenAlerts(metrics.AlertSourceRule, maxOpenRuleAlerts)
+}
+
+// indexAlertRule is a helper function that stores the given alert rule under its ID
+func (c *Client) indexAlertRule(rule *resources.AlertRule) error {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return errors.MakeInternalHTTPErr(err)
+	}
+
+	log.WithField("rule_id", rule.InternalID).Trace("Beginning to push alert rule to Elastic")
+	_, err = c.esclient.Index().Index(alertRulesIndexName).Type(alertRulesIndexTypeName).Id(rule.InternalID).BodyJson(rule).Do(ctx)
+	if err != nil {
+		return errors.MakeInternalHTTPErr(err)
+	}
+	log.WithField("rule_id", rule.InternalID).Trace("Alert rule pushed to Elastic successfully")
+	return nil
+}
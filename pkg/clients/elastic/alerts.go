@@ -0,0 +1,237 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/http/errors"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ resources.AlertDatabase = (*Client)(nil)
+
+// FindAlerts gets the alerts that match the given query, most recently created first
+func (c *Client) FindAlerts(query *resources.AlertQuery) ([]*metrics.Alert, error) {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	searchService := c.esclient.Search(alertsIndexName).Query(query.GenerateElasticQueryObject(time.Now())).From(query.Offset).Sort("Created", false).IgnoreUnavailable(true).AllowNoIndices(true)
+	if query.Limit > 0 {
+		searchService.Size(query.Limit)
+	} else {
+		// Default to 1000 results if not specified, the same as for arrays
+		searchService.Size(1000)
+	}
+
+	res, err := searchService.Do(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	alerts := []*metrics.Alert{}
+	if res.Hits == nil {
+		return alerts, nil
+	}
+	for _, hit := range res.Hits.Hits {
+		if hit.Source == nil {
+			continue
+		}
+		alert := &metrics.Alert{}
+		err = json.Unmarshal(*hit.Source, alert)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"id":    hit.Id,
+			}).Warn("Error parsing alert, skipping")
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// maxAlertLifecyclePatchAttempts is how many times an alert is read and patched again when it keeps changing between
+// being read and written
+const maxAlertLifecyclePatchAttempts = 5
+
+// PatchAlertLifecycles applies the given patch to each of the alerts with the given IDs and stores their lifecycles,
+// leaving the rest of the alerts as they are. An alert is only written if it hasn't changed since it was read, and is
+// read and patched again if it has, so concurrent patches (like two users adding notes) don't overwrite each other.
+// Errors from the patch are returned as they are, before anything is written.
+func (c *Client) PatchAlertLifecycles(ids []string, patch func(alert *metrics.Alert) error) ([]*metrics.Alert, error) {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	order := []string{}
+	patched := map[string]*metrics.Alert{}
+	pending := ids
+	for attempt := 0; attempt < maxAlertLifecyclePatchAttempts && len(pending) > 0; attempt++ {
+		query := &resources.AlertQuery{IDs: pending}
+		res, err := c.esclient.Search(alertsIndexName).Query(query.GenerateElasticQueryObject(time.Now())).Size(len(pending)).Version(true).IgnoreUnavailable(true).AllowNoIndices(true).Do(ctx)
+		if err != nil {
+			return nil, errors.MakeInternalHTTPErr(err)
+		}
+		if res.Hits == nil || len(res.Hits.Hits) == 0 {
+			pending = nil // Deleted since they were last read
+			break
+		}
+
+		alerts := map[string]*metrics.Alert{}
+		bulkService := c.esclient.Bulk().Refresh("wait_for") // So the changes show up in the next search
+		for _, hit := range res.Hits.Hits {
+			if hit.Source == nil || hit.Version == nil {
+				continue
+			}
+			alert := &metrics.Alert{}
+			err = json.Unmarshal(*hit.Source, alert)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"id":    hit.Id,
+				}).Warn("Error parsing alert, skipping")
+				continue
+			}
+			err = patch(alert)
+			if err != nil {
+				return nil, err
+			}
+
+			if attempt == 0 {
+				order = append(order, hit.Id)
+			}
+			alerts[hit.Id] = alert
+			bulkService.Add(elastic.NewBulkUpdateRequest().
+				Index(alertsIndexName).
+				Type(alertsIndexTypeName).
+				Id(hit.Id).
+				Version(*hit.Version). // Conflicts if the alert changed since it was read, including being pushed again
+				Doc(map[string]interface{}{"Lifecycle": alert.Lifecycle}))
+		}
+		if len(alerts) == 0 {
+			pending = nil
+			break
+		}
+
+		log.WithFields(log.Fields{
+			"attempt": attempt + 1,
+			"count":   len(alerts),
+		}).Trace("Beginning to push alert lifecycles to Elastic")
+		bulkRes, err := bulkService.Do(ctx)
+		if err != nil {
+			return nil, errors.MakeInternalHTTPErr(err)
+		}
+
+		pending = []string{}
+		failed := 0
+		for _, item := range bulkRes.Updated() {
+			if item.Status == http.StatusConflict {
+				pending = append(pending, item.Id)
+				continue
+			}
+			if item.Error != nil {
+				log.WithFields(log.Fields{
+					"id":    item.Id,
+					"error": item.Error,
+				}).Error("Error updating alert lifecycle")
+				failed++
+				continue
+			}
+			patched[item.Id] = alerts[item.Id]
+		}
+		if failed > 0 {
+			return nil, errors.MakeInternalHTTPErr(fmt.Errorf("Error updating the lifecycle of %d alert(s)", failed))
+		}
+		if len(pending) > 0 {
+			log.WithField("ids", pending).Debug("Alerts changed while their lifecycles were being updated, patching them again")
+		}
+	}
+	if len(pending) > 0 {
+		return nil, errors.MakeHTTPErr(http.StatusConflict, fmt.Errorf("%d alert(s) kept changing while being updated, try again", len(pending)))
+	}
+
+	alerts := []*metrics.Alert{}
+	for _, id := range order {
+		if alert, ok := patched[id]; ok {
+			alerts = append(alerts, alert)
+		}
+	}
+	log.WithField("count", len(alerts)).Trace("Alert lifecycles pushed to Elastic successfully")
+	return alerts, nil
+}
+
+// findOpenAlerts is a helper function that gets (up to the given number of) alerts from the given source
+// that haven't been closed yet
+func (c *Client) findOpenAlerts(source string, size int) ([]*metrics.Alert, error) {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := elastic.NewBoolQuery().Filter(
+		elastic.NewTermQuery("Source", source),
+		elastic.NewTermQuery("State", "open"),
+	)
+
+	var result *elastic.SearchResult
+	err = c.tryRepeatReturnErrorOnly(func() error {
+		var err error
+		result, err = c.esclient.Search(alertsIndexName).Query(query).Size(size).IgnoreUnavailable(true).AllowNoIndices(true).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := []*metrics.Alert{}
+	if result.Hits == nil {
+		return alerts, nil
+	}
+	for _, hit := range result.Hits.Hits {
+		if hit.Source == nil {
+			continue
+		}
+		alert := &metrics.Alert{}
+		err = json.Unmarshal(*hit.Source, alert)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"id":    hit.Id,
+			}).Warn("Error parsing alert, skipping")
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
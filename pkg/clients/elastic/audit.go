@@ -0,0 +1,238 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/timing"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/http/errors"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guards: ensure this implements the interfaces
+var _ metrics.AuditDatabase = (*Client)(nil)
+var _ resources.AuditEventDatabase = (*Client)(nil)
+
+const latestAuditEventTimeName = "latest_time"
+
+// AddAuditEvents adds the given audit events, each to the index for the day it happened on. Each event gets an ID
+// from its array, kind and number, so events that were already added are skipped.
+func (c *Client) AddAuditEvents(events []*metrics.AuditEvent) error {
+	if len(events) == 0 {
+		log.Debug("No audit events to push, skipping")
+		return nil
+	}
+
+	ctx := context.Background()
+
+	timer := timing.NewStageTimer("Client.AddAuditEvents", log.Fields{})
+	defer timer.Finish()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return err
+	}
+
+	timer.Stage("push_events")
+
+	requests := []elastic.BulkableRequest{}
+	arrayIDMap := map[string]struct{}{}
+
+	for _, event := range events {
+		requests = append(requests, elastic.NewBulkIndexRequest().
+			Index(getAuditEventsIndexName(time.Unix(event.Time, 0))).
+			Type(auditTimeSeriesTypeName).
+			Id(event.GetDocumentID()).
+			OpType("create").
+			Doc(event))
+		arrayIDMap[event.ArrayID] = struct{}{}
+	}
+	arrayIDs := []string{}
+	for id := range arrayIDMap {
+		arrayIDs = append(arrayIDs, id)
+	}
+
+	return c.tryRepeatReturnErrorOnly(func() error {
+		log.WithField("array_ids", arrayIDs).Trace("Beginning bulk request for audit events")
+		res, err := c.esclient.Bulk().Add(requests...).Do(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err":       err,
+				"array_ids": arrayIDs,
+			}).Error("Error pushing audit events (overall error, not individual document)")
+			return err
+		}
+
+		failed := 0
+		for _, failure := range res.Failed() {
+			// Already collected
+			if failure.Status == http.StatusConflict {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"type":   failure.Error.Type,
+				"id":     failure.Id,
+				"reason": failure.Error.Reason,
+			}).Error("Audit event failed to index in bulk request")
+			failed++
+		}
+		if failed > 0 {
+			log.WithField("array_ids", arrayIDs).Error("Not all audit events indexed successfully")
+			return fmt.Errorf("Some audit events failed in bulk request")
+		}
+
+		log.WithField("array_ids", arrayIDs).Trace("Audit events pushed successfully")
+		return nil
+	})
+}
+
+// GetLatestAuditEventTime gets the time (Unix seconds) of the latest audit event added for the given array, which
+// is where the next collection of its audit events picks up from. Returns 0 if there are none.
+func (c *Client) GetLatestAuditEventTime(arrayID string) (int64, error) {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var result *elastic.SearchResult
+	err = c.tryRepeatReturnErrorOnly(func() error {
+		var err error
+		result, err = c.esclient.Search(getAuditEventsIndexWildcard()).Query(elastic.NewTermQuery("ArrayID", arrayID)).Size(0).
+			Aggregation(latestAuditEventTimeName, elastic.NewMaxAggregation().Field("Time")).IgnoreUnavailable(true).AllowNoIndices(true).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	latest, found := result.Aggregations.Max(latestAuditEventTimeName)
+	if !found || latest.Value == nil {
+		return 0, nil
+	}
+	return int64(*latest.Value) / 1000, nil // Date aggregations are in milliseconds
+}
+
+// CleanAuditEvents deletes all audit event indices that are older than the given age in days. Unlike the metric
+// indices, older indices aren't marked read-only, since a newly registered array brings its whole audit trail.
+func (c *Client) CleanAuditEvents(maxAgeInDays int) error {
+	log.WithFields(log.Fields{
+		"max_age_in_days": maxAgeInDays,
+	}).Trace("Beginning audit events cleaning")
+
+	timer := timing.NewStageTimer("Client.CleanAuditEvents", log.Fields{})
+	defer timer.Finish()
+
+	indices, err := c.getAuditEventsIndices(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Error getting audit event indices")
+		return err
+	}
+	toDelete := []string{}
+
+	timer.Stage("process_index_names")
+
+	now := time.Now().UTC()
+	nowDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, index := range indices {
+		date, err := getTimeFromAuditEventsIndexName(index)
+		if err != nil {
+			log.WithField("index", index).Warn("Index has invalid date format, skipping; it will be retained")
+			continue
+		}
+		ageInHours := nowDate.Sub(date).Hours()
+		if ageInHours > float64(24*maxAgeInDays) {
+			log.WithFields(log.Fields{
+				"index":         index,
+				"age_hours":     ageInHours,
+				"max_age_hours": maxAgeInDays * 24,
+			}).Info("Index is past retention date, deleting")
+			toDelete = append(toDelete, index)
+		}
+	}
+
+	timer.Stage("delete_indices")
+
+	if len(toDelete) > 0 {
+		err = c.DeleteIndices(context.Background(), toDelete)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err":       err,
+				"to_delete": toDelete,
+			}).Error("Error deleting old indices")
+			return err
+		}
+		log.WithField("to_delete", toDelete).Trace("Audit event index deletion successful")
+	}
+
+	log.WithFields(log.Fields{
+		"max_age_in_days": maxAgeInDays,
+	}).Trace("Audit events cleaning finished")
+	return nil
+}
+
+// FindAuditEvents gets the audit events that match the given query, most recent first
+func (c *Client) FindAuditEvents(query *resources.AuditQuery) ([]*metrics.AuditEvent, error) {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	searchService := c.esclient.Search(getAuditEventsIndexWildcard()).Query(query.GenerateElasticQueryObject()).From(query.Offset).Sort("Time", false).IgnoreUnavailable(true).AllowNoIndices(true)
+	if query.Limit > 0 {
+		searchService.Size(query.Limit)
+	} else {
+		// Default to 1000 results if not specified, the same as for arrays
+		searchService.Size(1000)
+	}
+
+	res, err := searchService.Do(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	events := []*metrics.AuditEvent{}
+	if res.Hits == nil {
+		return events, nil
+	}
+	for _, hit := range res.Hits.Hits {
+		if hit.Source == nil {
+			continue
+		}
+		event := &metrics.AuditEvent{}
+		err = json.Unmarshal(*hit.Source, event)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"id":    hit.Id,
+			}).Warn("Error parsing audit event, skipping")
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/timing"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.BackfillDatabase = (*Client)(nil)
+
+const (
+	collectionTimesAggregationName = "collections"
+	collectionTimesInterval        = "1m" // Collections closer together than this are only seen as the first and last of them
+	collectionEarliestName         = "earliest"
+	collectionLatestName           = "latest"
+)
+
+// GetArrayMetricTimes gets the times (oldest first, Unix seconds) array metrics were collected for the given array since
+// the given time, starting with the latest collection before it if there is one
+func (c *Client) GetArrayMetricTimes(arrayID string, since time.Time) ([]int64, error) {
+	ctx := context.Background()
+
+	timer := timing.NewStageTimer("Client.GetArrayMetricTimes", log.Fields{"array_id": arrayID})
+	defer timer.Finish()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// First find the latest collection before the given time, which is where a gap reaching back past it starts
+	beforeQuery := elastic.NewBoolQuery().Filter(
+		elastic.NewTermQuery("ArrayID", arrayID),
+		elastic.NewRangeQuery("CreatedAt").Lt(since.Unix()).Format("epoch_second"),
+	)
+	var result *elastic.SearchResult
+	err = c.tryRepeatReturnErrorOnly(func() error {
+		var err error
+		result, err = c.esclient.Search(getArrayMetricsIndexWildcard()).Query(beforeQuery).Size(0).
+			Aggregation(collectionLatestName, elastic.NewMaxAggregation().Field("CreatedAt")).IgnoreUnavailable(true).AllowNoIndices(true).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	times := []int64{}
+	latest, found := result.Aggregations.Max(collectionLatestName)
+	if found && latest.Value != nil {
+		times = append(times, int64(*latest.Value)/1000) // Date aggregations are in milliseconds
+	}
+
+	timer.Stage("collection_times")
+
+	// Then find every collection since, or at least the first and last in each interval
+	sinceQuery := elastic.NewBoolQuery().Filter(
+		elastic.NewTermQuery("ArrayID", arrayID),
+		elastic.NewRangeQuery("CreatedAt").Gte(since.Unix()).Format("epoch_second"),
+	)
+	aggregation := elastic.NewDateHistogramAggregation().Field("CreatedAt").Interval(collectionTimesInterval).MinDocCount(1).
+		SubAggregation(collectionEarliestName, elastic.NewMinAggregation().Field("CreatedAt")).
+		SubAggregation(collectionLatestName, elastic.NewMaxAggregation().Field("CreatedAt"))
+	err = c.tryRepeatReturnErrorOnly(func() error {
+		var err error
+		result, err = c.esclient.Search(getArrayMetricsIndexWildcard()).Query(sinceQuery).Size(0).
+			Aggregation(collectionTimesAggregationName, aggregation).IgnoreUnavailable(true).AllowNoIndices(true).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	histogram, found := result.Aggregations.DateHistogram(collectionTimesAggregationName)
+	if !found {
+		return times, nil
+	}
+	for _, bucket := range histogram.Buckets {
+		earliest, earliestOk := bucket.Min(collectionEarliestName)
+		latest, latestOk := bucket.Max(collectionLatestName)
+		if !earliestOk || !latestOk || earliest.Value == nil || latest.Value == nil {
+			continue
+		}
+		times = append(times, int64(*earliest.Value)/1000)
+		if *latest.Value != *earliest.Value {
+			times = append(times, int64(*latest.Value)/1000)
+		}
+	}
+	return times, nil
+}
+
+// AddBackfilledArrayMetrics adds the given array metrics taken from the array's history, each to the index for the
+// day it was taken on. Each metric gets the same ID from its array and time as collected metrics, so metrics that were
+// already added are skipped. Backfilled metrics only have performance, and their capacity fields are left out of the
+// documents entirely (not zeroed), so capacity rollups and forecasts only count the collected metrics.
+// Days that have already been rolled up, and had metrics added to them, are rolled up again on the next rollup.
+func (c *Client) AddBackfilledArrayMetrics(metrics []*metrics.ArrayMetric) error {
+	if len(metrics) == 0 {
+		log.Debug("No backfilled device metrics to push, skipping")
+		return nil
+	}
+
+	ctx := context.Background()
+
+	timer := timing.NewStageTimer("Client.AddBackfilledArrayMetrics", log.Fields{})
+	defer timer.Finish()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return err
+	}
+
+	timer.Stage("push_metrics")
+
+	requests := []elastic.BulkableRequest{}
+	indexDays := map[string]time.Time{}
+	for _, metric := range metrics {
+		createdAt := time.Unix(metric.CreatedAt, 0).UTC()
+		indexName := getArrayMetricsIndexName(createdAt)
+		requests = append(requests, elastic.NewBulkIndexRequest().
+			Index(indexName).
+			Type(arraysTimeSeriesTypeName).
+			Id(getArrayMetricID(metric)).
+			OpType("create").
+			Doc(metric))
+		indexDays[indexName] = time.Date(createdAt.Year(), createdAt.Month(), createdAt.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	addedDays := map[time.Time]struct{}{}
+	err = c.tryRepeatReturnErrorOnly(func() error {
+		res, err := c.esclient.Bulk().Add(requests...).Do(ctx)
+		if err != nil {
+			log.WithError(err).Error("Error pushing backfilled device metrics (overall error, not individual document)")
+			return err
+		}
+
+		recordAddedDays(addedDays, res, indexDays)
+		failed := 0
+		rejected := 0
+		for _, failure := range res.Failed() {
+			// Already backfilled
+			if failure.Status == http.StatusConflict {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"type":   failure.Error.Type,
+				"id":     failure.Id,
+				"reason": failure.Error.Reason,
+			}).Error("Backfilled device metric failed to index in bulk request")
+			failed++
+			if isRejectedBulkStatus(failure.Status) {
+				rejected++
+			}
+		}
+		if failed > 0 && failed == rejected {
+			return newRejectedBulkError("backfilled device metrics", rejected)
+		}
+		if failed > 0 {
+			return fmt.Errorf("Some backfilled device metrics failed in bulk request")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	timer.Stage("unmark_rolled_up")
+	return c.unmarkPastDaysRolledUp(ctx, arrayRollupKind, addedDays)
+}
@@ -19,10 +19,17 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
 	"github.com/olivere/elastic"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	latestCollectionMaxAge          = time.Hour
+	latestCollectionAggregationName = "latest_by_array"
+	latestCollectionCreatedAtName   = "latest_created_at"
+)
+
 // tryRepeatReturnErrorOnly is a utility method that tries the given function
 // until it runs out of attempts or succeeds
 func (e *Client) tryRepeatReturnErrorOnly(fn errorReturnOnlyFunction) error {
@@ -35,6 +42,10 @@ func (e *Client) tryRepeatReturnErrorOnly(fn errorReturnOnlyFunction) error {
 		if err == nil {
 			return nil
 		}
+		// Trying again won't help if the request was rejected
+		if metrics.IsRejected(err) {
+			return err
+		}
 		lastError = err
 		time.Sleep(e.retryTime)
 		try++
@@ -98,6 +109,12 @@ func InitializeClient(host string, maxAttempts uint, attemptDelay time.Duration)
 	return client, nil
 }
 
+// SetMaxAttempts changes how many times each request is tried before giving up (0 retries forever).
+// This isn't safe to call while requests are in flight.
+func (e *Client) SetMaxAttempts(maxAttempts uint) {
+	e.maxAttempts = maxAttempts
+}
+
 // Connect will attempt to connect this Client instance to the Elastic server
 func (e *Client) Connect() error {
 	return e.tryRepeatReturnErrorOnly(func() error {
@@ -160,6 +177,76 @@ func (e *Client) DeleteByQuery(ctx context.Context, indexName string, query elas
 	})
 }
 
+// findLatestCollections gets the documents from the most recent collection of each of the given arrays in the
+// given time-series indices, relying on every document from a collection sharing its CreatedAt. Arrays that
+// haven't been collected within latestCollectionMaxAge are left out, since they're either unreachable or
+// no longer have anything to collect.
+func (e *Client) findLatestCollections(ctx context.Context, indexWildcard string, arrayIDs []string, size int) ([]*elastic.SearchHit, error) {
+	err := e.EnsureConnected(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []interface{}{}
+	for _, id := range arrayIDs {
+		ids = append(ids, id)
+	}
+	recentQuery := elastic.NewBoolQuery().Filter(
+		elastic.NewTermsQuery("ArrayID", ids...),
+		elastic.NewRangeQuery("CreatedAt").Gte(time.Now().Add(-latestCollectionMaxAge).Unix()).Format("epoch_second"),
+	)
+
+	// First find the latest collection of each array
+	aggregation := elastic.NewTermsAggregation().Field("ArrayID").Size(len(arrayIDs)).
+		SubAggregation(latestCollectionCreatedAtName, elastic.NewMaxAggregation().Field("CreatedAt"))
+	var result *elastic.SearchResult
+	err = e.tryRepeatReturnErrorOnly(func() error {
+		var err error
+		result, err = e.esclient.Search(indexWildcard).Query(recentQuery).Size(0).
+			Aggregation(latestCollectionAggregationName, aggregation).IgnoreUnavailable(true).AllowNoIndices(true).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	buckets, found := result.Aggregations.Terms(latestCollectionAggregationName)
+	if !found {
+		return []*elastic.SearchHit{}, nil
+	}
+
+	latestQueries := []elastic.Query{}
+	for _, bucket := range buckets.Buckets {
+		arrayID, ok := bucket.Key.(string)
+		latest, latestOk := bucket.Max(latestCollectionCreatedAtName)
+		if !ok || !latestOk || latest.Value == nil {
+			continue
+		}
+		createdAt := int64(*latest.Value) / 1000 // Date aggregations are in milliseconds
+		latestQueries = append(latestQueries, elastic.NewBoolQuery().Filter(
+			elastic.NewTermQuery("ArrayID", arrayID),
+			elastic.NewRangeQuery("CreatedAt").Gte(createdAt).Lte(createdAt).Format("epoch_second"),
+		))
+	}
+	if len(latestQueries) == 0 {
+		return []*elastic.SearchHit{}, nil
+	}
+
+	// Then fetch the documents from those collections
+	err = e.tryRepeatReturnErrorOnly(func() error {
+		var err error
+		result, err = e.esclient.Search(indexWildcard).Query(elastic.NewBoolQuery().Should(latestQueries...).MinimumNumberShouldMatch(1)).
+			Size(size).IgnoreUnavailable(true).AllowNoIndices(true).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Hits == nil {
+		return []*elastic.SearchHit{}, nil
+	}
+	return result.Hits.Hits, nil
+}
+
 // Client returns the inner Elastic client to perform standard Elasticsearch calls on
 func (e *Client) Client() *elastic.Client {
 	return e.esclient
@@ -0,0 +1,248 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/timing"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guards: ensure this implements the interfaces
+var _ metrics.ForecastDatabase = (*Client)(nil)
+var _ resources.CapacityForecastDatabase = (*Client)(nil)
+
+const (
+	capacityAggregationName = "capacity"
+	capacityDayKey          = "Day"
+	capacityUsedName        = "used"
+	capacityTotalName       = "total"
+	capacityPercentName     = "percent"
+)
+
+// GetArrayCapacityHistory gets the daily average capacity of every array since the given time. Daily rollups
+// cover the history past the raw metrics retention period, and raw metrics cover the days that haven't been
+// rolled up yet (raw metrics win for days that have both).
+func (c *Client) GetArrayCapacityHistory(since time.Time) (map[string][]*metrics.CapacitySample, error) {
+	ctx := context.Background()
+
+	timer := timing.NewStageTimer("Client.GetArrayCapacityHistory", log.Fields{})
+	defer timer.Finish()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := map[string]map[int64]*metrics.CapacitySample{}
+
+	timer.Stage("rollups")
+	err = c.getDailyCapacity(ctx, getMetricRollupsKindIndexWildcard(arrayRollupKind, metrics.DailyRollup), "StartTime",
+		"Values.UsedSpace.Avg", "Values.TotalSpace.Max", "Values.PercentFull.Avg", since, samples)
+	if err != nil {
+		return nil, err
+	}
+
+	timer.Stage("raw_metrics")
+	err = c.getDailyCapacity(ctx, getArrayMetricsIndexWildcard(), "CreatedAt", "UsedSpace", "TotalSpace", "PercentFull", since, samples)
+	if err != nil {
+		return nil, err
+	}
+
+	history := map[string][]*metrics.CapacitySample{}
+	for arrayID, days := range samples {
+		arrayHistory := []*metrics.CapacitySample{}
+		for _, sample := range days {
+			arrayHistory = append(arrayHistory, sample)
+		}
+		sort.Slice(arrayHistory, func(i, j int) bool {
+			return arrayHistory[i].Time < arrayHistory[j].Time
+		})
+		history[arrayID] = arrayHistory
+	}
+	return history, nil
+}
+
+// UpdateCapacityForecasts stores the given forecasts, one document per array
+func (c *Client) UpdateCapacityForecasts(forecasts []*metrics.CapacityForecast) error {
+	if len(forecasts) == 0 {
+		log.Debug("No capacity forecasts to store, skipping")
+		return nil
+	}
+
+	ctx := context.Background()
+
+	timer := timing.NewStageTimer("Client.UpdateCapacityForecasts", log.Fields{})
+	defer timer.Finish()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return err
+	}
+
+	requests := []elastic.BulkableRequest{}
+	for _, forecast := range forecasts {
+		requests = append(requests, elastic.NewBulkIndexRequest().Index(forecastsIndexName).Type(forecastsIndexTypeName).Id(forecast.ArrayID).Doc(forecast))
+	}
+
+	return c.tryRepeatReturnErrorOnly(func() error {
+		res, err := c.esclient.Bulk().Add(requests...).Do(ctx)
+		if err != nil {
+			log.WithError(err).Error("Error in bulk request for capacity forecasts (overall error, not single document)")
+			return err
+		}
+		failed := res.Failed()
+		for _, failure := range failed {
+			log.WithFields(log.Fields{
+				"type":   failure.Error.Type,
+				"id":     failure.Id,
+				"reason": failure.Error.Reason,
+			}).Error("Capacity forecast failed to index in bulk request")
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("Some capacity forecasts failed in bulk request")
+		}
+		return nil
+	})
+}
+
+// FindCapacityForecasts gets the latest capacity forecasts for the given arrays. Arrays that haven't been
+// forecast yet are left out.
+func (c *Client) FindCapacityForecasts(arrayIDs []string) ([]*metrics.CapacityForecast, error) {
+	forecasts := []*metrics.CapacityForecast{}
+	if len(arrayIDs) == 0 {
+		return forecasts, nil
+	}
+
+	ctx := context.Background()
+	var result *elastic.SearchResult
+	err := c.tryRepeatReturnErrorOnly(func() error {
+		var err error
+		result, err = c.esclient.Search(forecastsIndexName).
+			Query(elastic.NewIdsQuery(forecastsIndexTypeName).Ids(arrayIDs...)).
+			Size(len(arrayIDs)).
+			Do(ctx)
+		if elastic.IsNotFound(err) {
+			// Nothing has been forecast yet
+			result = nil
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || result.Hits == nil {
+		return forecasts, nil
+	}
+
+	for _, hit := range result.Hits.Hits {
+		if hit.Source == nil {
+			continue
+		}
+		forecast := &metrics.CapacityForecast{}
+		err = json.Unmarshal(*hit.Source, forecast)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"id":    hit.Id,
+			}).Warn("Error parsing capacity forecast, skipping")
+			continue
+		}
+		forecasts = append(forecasts, forecast)
+	}
+	return forecasts, nil
+}
+
+// getDailyCapacity is a helper function that aggregates the capacity fields of the given indices by array
+// and day, a page at a time, storing the results in the given map (keyed by array ID and day)
+func (c *Client) getDailyCapacity(ctx context.Context, index string, timeField string, usedField string, totalField string, percentField string,
+	since time.Time, samples map[string]map[int64]*metrics.CapacitySample) error {
+	// Backfilled metrics only have performance, so they're left out rather than counted as empty arrays (rollups
+	// don't have the field, so nothing is left out of them)
+	query := elastic.NewBoolQuery().
+		Filter(elastic.NewRangeQuery(timeField).Gte(since.Unix()).Format("epoch_second")).
+		MustNot(elastic.NewTermQuery("Backfilled", true))
+
+	var after map[string]interface{}
+	for {
+		aggregation := elastic.NewCompositeAggregation().Size(rollupPageSize).Sources(
+			elastic.NewCompositeAggregationTermsValuesSource("ArrayID").Field("ArrayID"),
+			elastic.NewCompositeAggregationDateHistogramValuesSource(capacityDayKey, "1d").Field(timeField),
+		).
+			SubAggregation(capacityUsedName, elastic.NewAvgAggregation().Field(usedField)).
+			SubAggregation(capacityTotalName, elastic.NewMaxAggregation().Field(totalField)).
+			SubAggregation(capacityPercentName, elastic.NewAvgAggregation().Field(percentField))
+		if after != nil {
+			aggregation = aggregation.AggregateAfter(after)
+		}
+
+		var result *elastic.SearchResult
+		err := c.tryRepeatReturnErrorOnly(func() error {
+			var err error
+			result, err = c.esclient.Search(index).Query(query).Size(0).Aggregation(capacityAggregationName, aggregation).Do(ctx)
+			return err
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"index": index,
+			}).Error("Error aggregating daily array capacity")
+			return err
+		}
+		items, found := result.Aggregations.Composite(capacityAggregationName)
+		if !found {
+			// No matching indices
+			return nil
+		}
+
+		for _, bucket := range items.Buckets {
+			arrayID, ok := bucket.Key["ArrayID"].(string)
+			day, dayOk := bucket.Key[capacityDayKey].(float64)
+			used, usedOk := bucket.Avg(capacityUsedName)
+			total, totalOk := bucket.Max(capacityTotalName)
+			if !ok || !dayOk || !usedOk || !totalOk || used.Value == nil || total.Value == nil {
+				continue
+			}
+			sample := &metrics.CapacitySample{
+				Time:       int64(day) / 1000, // Date histogram keys are in milliseconds
+				UsedSpace:  *used.Value,
+				TotalSpace: *total.Value,
+			}
+			percent, found := bucket.Avg(capacityPercentName)
+			if found && percent.Value != nil {
+				sample.PercentFull = *percent.Value
+			}
+			if _, ok := samples[arrayID]; !ok {
+				samples[arrayID] = map[int64]*metrics.CapacitySample{}
+			}
+			samples[arrayID][sample.Time] = sample
+		}
+
+		if len(items.Buckets) < rollupPageSize || len(items.AfterKey) == 0 {
+			return nil
+		}
+		after = items.AfterKey
+	}
+}
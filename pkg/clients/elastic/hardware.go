@@ -0,0 +1,211 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/timing"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guards: ensure this implements the interfaces
+var _ metrics.HardwareDatabase = (*Client)(nil)
+var _ resources.HardwareStatusDatabase = (*Client)(nil)
+
+// More hardware components than there should practically be across all arrays
+const maxLatestHardwareMetrics = 10000
+
+// AddHardwareMetrics adds the given hardware metrics to the time-series indices
+func (c *Client) AddHardwareMetrics(metrics []*metrics.HardwareMetric) error {
+	if len(metrics) == 0 {
+		log.Debug("No hardware metrics to push, skipping")
+		return nil
+	}
+
+	indexName := getHardwareMetricsIndexName(time.Now().UTC())
+	ctx := context.Background()
+
+	timer := timing.NewStageTimer("Client.AddHardwareMetrics", log.Fields{})
+	defer timer.Finish()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return err
+	}
+
+	timer.Stage("push_metrics")
+
+	requests := []elastic.BulkableRequest{}
+	arrayIDMap := map[string]struct{}{}
+
+	for _, metric := range metrics {
+		log.WithFields(log.Fields{
+			"array_name": metric.ArrayDisplayName,
+			"array_id":   metric.ArrayID,
+			"component":  metric.ComponentName,
+		}).Trace("Adding bulk request for hardware time series metric")
+		requests = append(requests, elastic.NewBulkIndexRequest().Index(indexName).Type(hardwareTimeSeriesTypeName).Doc(metric))
+		arrayIDMap[metric.ArrayID] = struct{}{}
+	}
+	arrayIDs := []string{}
+	for id := range arrayIDMap {
+		arrayIDs = append(arrayIDs, id)
+	}
+
+	return c.tryRepeatReturnErrorOnly(func() error {
+		log.WithField("array_ids", arrayIDs).Trace("Beginning bulk request for hardware time series metrics")
+		res, err := c.esclient.Bulk().Add(requests...).Do(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err":       err,
+				"array_ids": arrayIDs,
+			}).Error("Error pushing hardware time series metrics (overall error, not individual document)")
+			return err
+		}
+
+		failed := res.Failed()
+		for _, failure := range failed {
+			log.WithFields(log.Fields{
+				"type":   failure.Error.Type,
+				"id":     failure.Id,
+				"reason": failure.Error.Reason,
+			}).Error("Hardware component failed to index in bulk request")
+		}
+		if len(failed) > 0 {
+			log.WithField("array_ids", arrayIDs).Error("Not all hardware components indexed successfully")
+			return fmt.Errorf("Some hardware components failed in bulk request")
+		}
+
+		log.WithField("array_ids", arrayIDs).Trace("Hardware time series metrics pushed successfully")
+		return nil
+	})
+}
+
+// CleanHardwareMetrics deletes all hardware indices that are older than the given age in days and marks any older than today as read-only
+func (c *Client) CleanHardwareMetrics(maxAgeInDays int) error {
+	log.WithFields(log.Fields{
+		"max_age_in_days": maxAgeInDays,
+	}).Trace("Beginning hardware metrics cleaning")
+
+	timer := timing.NewStageTimer("Client.CleanHardwareMetrics", log.Fields{})
+	defer timer.Finish()
+
+	indices, err := c.getHardwareMetricsIndices(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Error getting hardware metrics indices")
+		return err
+	}
+	toDelete := []string{}
+	toReadOnly := []string{}
+
+	timer.Stage("process_index_names")
+
+	now := time.Now().UTC()
+	nowDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, index := range indices {
+		date, err := getTimeFromHardwareMetricsIndexName(index)
+		if err != nil {
+			log.WithField("index", index).Warn("Index has invalid date format, skipping; it will be retained")
+			continue
+		}
+		ageInHours := nowDate.Sub(date).Hours()
+		if ageInHours > float64(24*maxAgeInDays) {
+			log.WithFields(log.Fields{
+				"index":         index,
+				"age_hours":     ageInHours,
+				"max_age_hours": maxAgeInDays * 24,
+			}).Info("Index is past retention date, deleting")
+			toDelete = append(toDelete, index)
+		} else if ageInHours > 24 {
+			toReadOnly = append(toReadOnly, index)
+		}
+	}
+
+	timer.Stage("delete_indices")
+
+	if len(toDelete) > 0 {
+		err = c.DeleteIndices(context.Background(), toDelete)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err":       err,
+				"to_delete": toDelete,
+			}).Error("Error deleting old indices")
+			return err
+		}
+		log.WithField("to_delete", toDelete).Trace("Hardware metrics index deletion successful")
+	}
+
+	timer.Stage("mark_indices_read_only")
+
+	if len(toReadOnly) > 0 {
+		err = c.tryRepeatReturnErrorOnly(func() error {
+			_, err := c.esclient.IndexPutSettings(toReadOnly...).BodyJson(map[string]interface{}{
+				"index": map[string]interface{}{
+					"blocks": map[string]interface{}{
+						"read_only_allow_delete": true,
+					},
+				},
+			}).Do(context.Background())
+			return err
+		})
+		if err != nil {
+			log.WithError(err).WithField("to_read_only", toReadOnly).Error("Error marking indices as read-only, but continuing (non-fatal)")
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"max_age_in_days": maxAgeInDays,
+	}).Trace("Hardware metrics cleaning finished")
+	return nil
+}
+
+// FindLatestHardwareMetrics gets the hardware metrics from the most recent collection of each of the given arrays.
+// Arrays that haven't had their hardware collected recently are left out.
+func (c *Client) FindLatestHardwareMetrics(arrayIDs []string) ([]*metrics.HardwareMetric, error) {
+	hardwareMetrics := []*metrics.HardwareMetric{}
+	if len(arrayIDs) == 0 {
+		return hardwareMetrics, nil
+	}
+
+	hits, err := c.findLatestCollections(context.Background(), getHardwareMetricsIndexWildcard(), arrayIDs, maxLatestHardwareMetrics)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hit := range hits {
+		if hit.Source == nil {
+			continue
+		}
+		metric := &metrics.HardwareMetric{}
+		err = json.Unmarshal(*hit.Source, metric)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"id":    hit.Id,
+			}).Warn("Error parsing hardware metric, skipping")
+			continue
+		}
+		hardwareMetrics = append(hardwareMetrics, metric)
+	}
+	return hardwareMetrics, nil
+}
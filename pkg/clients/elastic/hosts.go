@@ -0,0 +1,175 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/timing"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.HostDatabase = (*Client)(nil)
+
+// AddHostMetrics adds the given host and host group metrics to the time-series indices
+func (c *Client) AddHostMetrics(metrics []*metrics.HostMetric) error {
+	if len(metrics) == 0 {
+		log.Debug("No host metrics to push, skipping")
+		return nil
+	}
+
+	indexName := getHostMetricsIndexName(time.Now().UTC())
+	ctx := context.Background()
+
+	timer := timing.NewStageTimer("Client.AddHostMetrics", log.Fields{})
+	defer timer.Finish()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return err
+	}
+
+	timer.Stage("push_metrics")
+
+	requests := []elastic.BulkableRequest{}
+	arrayIDMap := map[string]struct{}{}
+
+	for _, metric := range metrics {
+		log.WithFields(log.Fields{
+			"array_name": metric.ArrayDisplayName,
+			"array_id":   metric.ArrayID,
+			"host_name":  metric.HostName,
+			"type":       metric.Type,
+		}).Trace("Adding bulk request for host time series metric")
+		requests = append(requests, elastic.NewBulkIndexRequest().Index(indexName).Type(hostsTimeSeriesTypeName).Doc(metric))
+		arrayIDMap[metric.ArrayID] = struct{}{}
+	}
+	arrayIDs := []string{}
+	for id := range arrayIDMap {
+		arrayIDs = append(arrayIDs, id)
+	}
+
+	return c.tryRepeatReturnErrorOnly(func() error {
+		log.WithField("array_ids", arrayIDs).Trace("Beginning bulk request for host time series metrics")
+		res, err := c.esclient.Bulk().Add(requests...).Do(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err":       err,
+				"array_ids": arrayIDs,
+			}).Error("Error pushing host time series metrics (overall error, not individual document)")
+			return err
+		}
+
+		failed := res.Failed()
+		for _, failure := range failed {
+			log.WithFields(log.Fields{
+				"type":   failure.Error.Type,
+				"id":     failure.Id,
+				"reason": failure.Error.Reason,
+			}).Error("Host failed to index in bulk request")
+		}
+		if len(failed) > 0 {
+			log.WithField("array_ids", arrayIDs).Error("Not all hosts indexed successfully")
+			return fmt.Errorf("Some hosts failed in bulk request")
+		}
+
+		log.WithField("array_ids", arrayIDs).Trace("Host time series metrics pushed successfully")
+		return nil
+	})
+}
+
+// CleanHostMetrics deletes all host indices that are older than the given age in days and marks any older than today as read-only
+func (c *Client) CleanHostMetrics(maxAgeInDays int) error {
+	log.WithFields(log.Fields{
+		"max_age_in_days": maxAgeInDays,
+	}).Trace("Beginning host metrics cleaning")
+
+	timer := timing.NewStageTimer("Client.CleanHostMetrics", log.Fields{})
+	defer timer.Finish()
+
+	indices, err := c.getHostMetricsIndices(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Error getting host metrics indices")
+		return err
+	}
+	toDelete := []string{}
+	toReadOnly := []string{}
+
+	timer.Stage("process_index_names")
+
+	now := time.Now().UTC()
+	nowDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, index := range indices {
+		date, err := getTimeFromHostMetricsIndexName(index)
+		if err != nil {
+			log.WithField("index", index).Warn("Index has invalid date format, skipping; it will be retained")
+			continue
+		}
+		ageInHours := nowDate.Sub(date).Hours()
+		if ageInHours > float64(24*maxAgeInDays) {
+			log.WithFields(log.Fields{
+				"index":         index,
+				"age_hours":     ageInHours,
+				"max_age_hours": maxAgeInDays * 24,
+			}).Info("Index is past retention date, deleting")
+			toDelete = append(toDelete, index)
+		} else if ageInHours > 24 {
+			toReadOnly = append(toReadOnly, index)
+		}
+	}
+
+	timer.Stage("delete_indices")
+
+	if len(toDelete) > 0 {
+		err = c.DeleteIndices(context.Background(), toDelete)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err":       err,
+				"to_delete": toDelete,
+			}).Error("Error deleting old indices")
+			return err
+		}
+		log.WithField("to_delete", toDelete).Trace("Host metrics index deletion successful")
+	}
+
+	timer.Stage("mark_indices_read_only")
+
+	if len(toReadOnly) > 0 {
+		err = c.tryRepeatReturnErrorOnly(func() error {
+			_, err := c.esclient.IndexPutSettings(toReadOnly...).BodyJson(map[string]interface{}{
+				"index": map[string]interface{}{
+					"blocks": map[string]interface{}{
+						"read_only_allow_delete": true,
+					},
+				},
+			}).Do(context.Background())
+			return err
+		})
+		if err != nil {
+			log.WithError(err).WithField("to_read_only", toReadOnly).Error("Error marking indices as read-only, but continuing (non-fatal)")
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"max_age_in_days": maxAgeInDays,
+	}).Trace("Host metrics cleaning finished")
+	return nil
+}
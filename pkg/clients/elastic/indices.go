@@ -19,21 +19,97 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
-	arraysIndexName         = "pure-arrays"
-	arraysTimeSeriesPrefix  = "pure-arrays-metrics-"
-	volumesTimeSeriesPrefix = "pure-volumes-metrics-"
-	alertsIndexName         = "pure-alerts"
+	arraysIndexName                  = "pure-arrays"
+	arraysTimeSeriesPrefix           = "pure-arrays-metrics-"
+	volumesTimeSeriesPrefix          = "pure-volumes-metrics-"
+	alertsIndexName                  = "pure-alerts"
+	metricRollupsPrefix              = "pure1-unplugged-metrics-rollup-"
+	rollupStatusIndexName            = "pure1-unplugged-rollup-status"
+	forecastsIndexName               = "pure1-unplugged-capacity-forecasts"
+	snapshotsIndexName               = "pure1-unplugged-snapshots"
+	alertRulesIndexName              = "pure1-unplugged-alert-rules"
+	snmpDestinationsIndexName        = "pure1-unplugged-snmp-destinations"
+	maintenanceWindowsIndexName      = "pure1-unplugged-maintenance-windows"
+	hostsTimeSeriesPrefix            = "pure1-unplugged-hosts-"
+	protectionGroupsTimeSeriesPrefix = "pure1-unplugged-protection-groups-"
+	podsTimeSeriesPrefix             = "pure1-unplugged-pods-"
+	hardwareTimeSeriesPrefix         = "pure1-unplugged-hardware-"
+	auditTimeSeriesPrefix            = "pure1-unplugged-audit-"
 
 	arraysIndexTypeName = "_doc"
 	// These below should eventually be changed to _doc: leaving as-is for now for compatibility
-	arraysTimeSeriesTypeName  = "metrics"
-	volumesTimeSeriesTypeName = "metrics"
-	alertsIndexTypeName       = "alerts"
+	arraysTimeSeriesTypeName           = "metrics"
+	volumesTimeSeriesTypeName          = "metrics"
+	alertsIndexTypeName                = "alerts"
+	metricRollupsTypeName              = "_doc"
+	rollupStatusTypeName               = "_doc"
+	forecastsIndexTypeName             = "_doc"
+	snapshotsIndexTypeName             = "_doc"
+	alertRulesIndexTypeName            = "_doc"
+	snmpDestinationsIndexTypeName      = "_doc"
+	maintenanceWindowsIndexTypeName    = "_doc"
+	hostsTimeSeriesTypeName            = "_doc"
+	protectionGroupsTimeSeriesTypeName = "_doc"
+	podsTimeSeriesTypeName             = "_doc"
+	hardwareTimeSeriesTypeName         = "_doc"
+	auditTimeSeriesTypeName            = "_doc"
+
+	rollupHourlyDateFormat = "2006-01-02"
+	rollupDailyDateFormat  = "2006-01"
 )
 
+// alertLifecycleMapping is kept apart from the rest of the alerts template, since it's also added to the
+// alerts index directly for installs where the index was created before alerts had a lifecycle
+var alertLifecycleMapping = map[string]interface{}{
+	"properties": map[string]interface{}{
+		"Acknowledged": map[string]interface{}{
+			"type": "boolean",
+		},
+		"AcknowledgedBy": map[string]interface{}{
+			"type": "keyword",
+		},
+		"AcknowledgedAt": map[string]interface{}{
+			"type":   "date",
+			"format": "epoch_second",
+		},
+		"AssignedTo": map[string]interface{}{
+			"type": "keyword",
+		},
+		"SnoozedUntil": map[string]interface{}{
+			"type":   "date",
+			"format": "epoch_second",
+		},
+		"Notes": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"Author": map[string]interface{}{
+					"type": "keyword",
+				},
+				"Created": map[string]interface{}{
+					"type":   "date",
+					"format": "epoch_second",
+				},
+				"Text": map[string]interface{}{
+					"type": "text",
+				},
+			},
+		},
+		"UpdatedBy": map[string]interface{}{
+			"type": "keyword",
+		},
+		"Updated": map[string]interface{}{
+			"type":   "date",
+			"format": "epoch_second",
+		},
+	},
+}
+
 var (
 	arraysTemplate = map[string]interface{}{
 		"index_patterns": []string{
@@ -75,13 +151,48 @@ var (
 						},
 					},
 					"MgmtEndpoint": map[string]interface{}{
-						"type": "text",
+						"type":     "text",
+						"analyzer": "lowercase_analyzer",
+						"fields": map[string]interface{}{
+							"keyword": map[string]interface{}{
+								"type":       "keyword",
+								"normalizer": "lowercase_normalizer",
+							},
+						},
+					},
+					"MgmtEndpoints": map[string]interface{}{
+						"type":     "text",
+						"analyzer": "lowercase_analyzer",
+						"fields": map[string]interface{}{
+							"keyword": map[string]interface{}{
+								"type":       "keyword",
+								"normalizer": "lowercase_normalizer",
+							},
+						},
+					},
+					"ActiveEndpoint": map[string]interface{}{
+						"type":       "keyword",
+						"normalizer": "lowercase_normalizer",
 					},
 					"Status": map[string]interface{}{
-						"type": "text",
+						"type":     "text",
+						"analyzer": "lowercase_analyzer",
+						"fields": map[string]interface{}{
+							"keyword": map[string]interface{}{
+								"type":       "keyword",
+								"normalizer": "lowercase_normalizer",
+							},
+						},
 					},
 					"DeviceType": map[string]interface{}{
-						"type": "text",
+						"type":     "text",
+						"analyzer": "lowercase_analyzer",
+						"fields": map[string]interface{}{
+							"keyword": map[string]interface{}{
+								"type":       "keyword",
+								"normalizer": "lowercase_normalizer",
+							},
+						},
 					},
 					"Model": map[string]interface{}{
 						"type":     "text",
@@ -111,6 +222,13 @@ var (
 						"type":   "date",
 						"format": "date_hour_minute_second_millis",
 					},
+					"CACertificate": map[string]interface{}{
+						"type":  "text",
+						"index": false,
+					},
+					"CertificateFingerprint": map[string]interface{}{
+						"type": "keyword",
+					},
 					"Tags": map[string]interface{}{
 						"type": "nested",
 					},
@@ -142,6 +260,9 @@ var (
 					"ArrayType": map[string]interface{}{
 						"type": "keyword",
 					},
+					"Backfilled": map[string]interface{}{
+						"type": "boolean",
+					},
 					"BytesPerOp": map[string]interface{}{
 						"type": "long",
 					},
@@ -167,6 +288,9 @@ var (
 					"HostCount": map[string]interface{}{
 						"type": "long",
 					},
+					"InMaintenance": map[string]interface{}{
+						"type": "boolean",
+					},
 					"OtherIOPS": map[string]interface{}{
 						"type": "long",
 					},
@@ -248,6 +372,9 @@ var (
 		"mappings": map[string]interface{}{
 			volumesTimeSeriesTypeName: map[string]interface{}{
 				"properties": map[string]interface{}{
+					"Account": map[string]interface{}{
+						"type": "keyword",
+					},
 					"ArrayDisplayName": map[string]interface{}{
 						"type": "keyword",
 					},
@@ -269,12 +396,19 @@ var (
 					"DataReduction": map[string]interface{}{
 						"type": "double",
 					},
+					"InMaintenance": map[string]interface{}{
+						"type": "boolean",
+					},
+					"ObjectCount": map[string]interface{}{
+						"type": "double",
+					},
 					"OtherIOPS": map[string]interface{}{
 						"type": "double",
 					},
 					"OtherLatency": map[string]interface{}{
 						"type": "double",
 					},
+					"ProtocolPerformance": createProtocolPerformanceMapping(),
 					"ProvisionedSpace": map[string]interface{}{
 						"type": "double",
 					},
@@ -299,6 +433,12 @@ var (
 					"UsedSpace": map[string]interface{}{
 						"type": "double",
 					},
+					"UserCount": map[string]interface{}{
+						"type": "double",
+					},
+					"Versioning": map[string]interface{}{
+						"type": "keyword",
+					},
 					"VolumeName": map[string]interface{}{
 						"type": "keyword",
 					},
@@ -316,137 +456,1075 @@ var (
 		},
 	}
 
-	alertsTemplate = map[string]interface{}{
+	hostsTimeSeriesTemplate = map[string]interface{}{
 		"index_patterns": []string{
-			alertsIndexName,
+			getHostMetricsIndexWildcard(),
 		},
 		"settings": map[string]interface{}{
 			"number_of_shards":   1,
 			"number_of_replicas": 0,
-			"index": map[string]interface{}{
-				"max_inner_result_window": 10000,
-			},
-			"analysis": map[string]interface{}{
-				"analyzer": map[string]interface{}{
-					"lowercase_analyzer": map[string]interface{}{
-						"filter":    []string{"lowercase"},
-						"tokenizer": "keyword",
+		},
+		"mappings": map[string]interface{}{
+			hostsTimeSeriesTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"ArrayDisplayName": map[string]interface{}{
+						"type": "keyword",
 					},
-				},
-				"normalizer": map[string]interface{}{
-					"lowercase_normalizer": map[string]interface{}{
-						"type":        "custom",
-						"char_filter": []interface{}{},
-						"filter":      []string{"lowercase"},
+					"ArrayID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayTags": map[string]interface{}{
+						"type":    "object",
+						"dynamic": true,
+						"enabled": true,
+					},
+					"CreatedAt": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"HostGroup": map[string]interface{}{
+						"type": "keyword",
+					},
+					"HostName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Hosts": map[string]interface{}{
+						"type": "keyword",
+					},
+					"IQNs": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Personality": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ReadBandwidth": map[string]interface{}{
+						"type": "double",
+					},
+					"ReadIOPS": map[string]interface{}{
+						"type": "double",
+					},
+					"ReadLatency": map[string]interface{}{
+						"type": "double",
+					},
+					"Type": map[string]interface{}{
+						"type": "keyword",
+					},
+					"VolumeCount": map[string]interface{}{
+						"type": "double",
+					},
+					"Volumes": map[string]interface{}{
+						"type": "keyword",
+					},
+					"WriteBandwidth": map[string]interface{}{
+						"type": "double",
+					},
+					"WriteIOPS": map[string]interface{}{
+						"type": "double",
+					},
+					"WriteLatency": map[string]interface{}{
+						"type": "double",
+					},
+					"WWNs": map[string]interface{}{
+						"type": "keyword",
 					},
 				},
 			},
 		},
+	}
+
+	protectionGroupsTimeSeriesTemplate = map[string]interface{}{
+		"index_patterns": []string{
+			getProtectionGroupMetricsIndexWildcard(),
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+		},
 		"mappings": map[string]interface{}{
-			alertsIndexTypeName: map[string]interface{}{
+			protectionGroupsTimeSeriesTypeName: map[string]interface{}{
 				"properties": map[string]interface{}{
-					"Action": map[string]interface{}{
-						"type": "text",
-					},
-					"AlertID": map[string]interface{}{
-						"type": "integer",
+					"ArrayDisplayName": map[string]interface{}{
+						"type": "keyword",
 					},
 					"ArrayID": map[string]interface{}{
-						"type":       "keyword",
-						"normalizer": "lowercase_normalizer",
+						"type": "keyword",
 					},
-					"ArrayHostname": map[string]interface{}{
-						"type":       "keyword",
-						"normalizer": "lowercase_normalizer",
+					"ArrayName": map[string]interface{}{
+						"type": "keyword",
 					},
-					"ArrayDisplayName": map[string]interface{}{
-						"type":       "keyword",
-						"normalizer": "lowercase_normalizer",
+					"ArrayTags": map[string]interface{}{
+						"type":    "object",
+						"dynamic": true,
+						"enabled": true,
 					},
-					"ArrayName": map[string]interface{}{
-						"type":       "keyword",
-						"normalizer": "lowercase_normalizer",
+					"CreatedAt": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
 					},
-					"Code": map[string]interface{}{
-						"type": "integer",
+					"HostGroups": map[string]interface{}{
+						"type": "keyword",
 					},
-					"Component": map[string]interface{}{
-						"type": "text",
+					"Hosts": map[string]interface{}{
+						"type": "keyword",
 					},
-					"Created": map[string]interface{}{
+					"LastReplication": map[string]interface{}{
 						"type":   "date",
 						"format": "epoch_second",
 					},
-					"Description": map[string]interface{}{
-						"type": "text",
+					"LastSnapshot": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
 					},
-					"Flagged": map[string]interface{}{
+					"LastTransferDataBytes": map[string]interface{}{
+						"type": "double",
+					},
+					"LastTransferDuration": map[string]interface{}{
+						"type": "double",
+					},
+					"LastTransferPhysicalBytes": map[string]interface{}{
+						"type": "double",
+					},
+					"ProtectionGroupName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ReplicationEnabled": map[string]interface{}{
 						"type": "boolean",
 					},
-					"Notified": map[string]interface{}{
-						"type":   "date",
-						"format": "epoch_second",
+					"ReplicationFrequency": map[string]interface{}{
+						"type": "double",
 					},
-					"Severity": map[string]interface{}{
-						"type":      "text",
-						"fielddata": true,
+					"ReplicationLag": map[string]interface{}{
+						"type": "double",
 					},
-					"SeverityIndex": map[string]interface{}{
-						"type": "integer",
+					"SnapshotCount": map[string]interface{}{
+						"type": "double",
 					},
-					"State": map[string]interface{}{
-						"type":       "keyword",
-						"normalizer": "lowercase_normalizer",
+					"SnapshotEnabled": map[string]interface{}{
+						"type": "boolean",
 					},
-					"Summary": map[string]interface{}{
-						"type": "text",
-						"fields": map[string]interface{}{
-							"raw": map[string]interface{}{
-								"type":      "text",
-								"analyzer":  "lowercase_analyzer",
-								"fielddata": true,
+					"SnapshotFrequency": map[string]interface{}{
+						"type": "double",
+					},
+					"Source": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Targets": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"Allowed": map[string]interface{}{
+								"type": "boolean",
+							},
+							"Connected": map[string]interface{}{
+								"type": "boolean",
+							},
+							"Name": map[string]interface{}{
+								"type": "keyword",
 							},
 						},
 					},
-					"Updated": map[string]interface{}{
-						"type":   "date",
-						"format": "epoch_second",
+					"TransferProgress": map[string]interface{}{
+						"type": "float",
 					},
-					"Variables": map[string]interface{}{
-						"type": "object",
-						// Make sure Elastic doesn't actually parse the inner variables document.
-						// Reason (example use case): if two different alerts use different
-						// variable types for the same key, this would error out normally
-						// (since Elastic would have already made a dynamic mapping for it of the first type).
-						// This sidesteps that by just never mapping it in the first place (which is fine: querying
-						// by these variables is so sporadic probably nobody would ever use it and would add lots
-						// of complications).
-						"enabled": false,
+					"Volumes": map[string]interface{}{
+						"type": "keyword",
 					},
 				},
 			},
 		},
 	}
-)
-
-// CreateArrayTemplate creates the template for the array index
-func (c *Client) CreateArrayTemplate(ctx context.Context) error {
-	return c.createTemplate(ctx, fmt.Sprintf("%s-template", arraysIndexName), arraysTemplate)
-}
-
-// CreateArrayMetricsTemplate creates the template for the array metrics indices
-func (c *Client) CreateArrayMetricsTemplate(ctx context.Context) error {
-	return c.createTemplate(ctx, fmt.Sprintf("%stemplate", arraysTimeSeriesPrefix), arraysTimeSeriesTemplate)
-}
 
-// CreateVolumeMetricsTemplate creates the template for the volume metrics indices
+	podsTimeSeriesTemplate = map[string]interface{}{
+		"index_patterns": []string{
+			getPodMetricsIndexWildcard(),
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+		},
+		"mappings": map[string]interface{}{
+			podsTimeSeriesTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"ArrayDisplayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayTags": map[string]interface{}{
+						"type":    "object",
+						"dynamic": true,
+						"enabled": true,
+					},
+					"CreatedAt": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"Mediator": map[string]interface{}{
+						"type": "keyword",
+					},
+					"MediatorStatus": map[string]interface{}{
+						"type": "keyword",
+					},
+					"MediatorVersion": map[string]interface{}{
+						"type": "keyword",
+					},
+					"MemberCount": map[string]interface{}{
+						"type": "double",
+					},
+					"Members": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"ArrayID": map[string]interface{}{
+								"type": "keyword",
+							},
+							"ArrayName": map[string]interface{}{
+								"type": "keyword",
+							},
+							"MediatorStatus": map[string]interface{}{
+								"type": "keyword",
+							},
+							"ResyncProgress": map[string]interface{}{
+								"type": "float",
+							},
+							"Status": map[string]interface{}{
+								"type": "keyword",
+							},
+						},
+					},
+					"OnlineMemberCount": map[string]interface{}{
+						"type": "double",
+					},
+					"PodName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"PurityID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ResyncProgress": map[string]interface{}{
+						"type": "float",
+					},
+					"Source": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Status": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Stretched": map[string]interface{}{
+						"type": "boolean",
+					},
+				},
+			},
+		},
+	}
+
+	hardwareTimeSeriesTemplate = map[string]interface{}{
+		"index_patterns": []string{
+			getHardwareMetricsIndexWildcard(),
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+		},
+		"mappings": map[string]interface{}{
+			hardwareTimeSeriesTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"ArrayDisplayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayTags": map[string]interface{}{
+						"type":    "object",
+						"dynamic": true,
+						"enabled": true,
+					},
+					"Capacity": map[string]interface{}{
+						"type": "double",
+					},
+					"ComponentName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ComponentType": map[string]interface{}{
+						"type": "keyword",
+					},
+					"CreatedAt": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"Details": map[string]interface{}{
+						"type": "text",
+					},
+					"DriveType": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Healthy": map[string]interface{}{
+						"type": "boolean",
+					},
+					"LastEvacuation": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"LastFailure": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"Mode": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Model": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Progress": map[string]interface{}{
+						"type": "float",
+					},
+					"Protocol": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Serial": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Slot": map[string]interface{}{
+						"type": "integer",
+					},
+					"Speed": map[string]interface{}{
+						"type": "double",
+					},
+					"Status": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Temperature": map[string]interface{}{
+						"type": "integer",
+					},
+					"Version": map[string]interface{}{
+						"type": "keyword",
+					},
+				},
+			},
+		},
+	}
+
+	auditTimeSeriesTemplate = map[string]interface{}{
+		"index_patterns": []string{
+			getAuditEventsIndexWildcard(),
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+		},
+		"mappings": map[string]interface{}{
+			auditTimeSeriesTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"ArrayDisplayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayType": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Arguments": map[string]interface{}{
+						"type": "text",
+					},
+					"CollectedAt": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"Command": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Event": map[string]interface{}{
+						"type": "keyword",
+					},
+					"EventID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Interface": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Kind": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Location": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Method": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Subcommand": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Time": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"User": map[string]interface{}{
+						"type": "keyword",
+					},
+				},
+			},
+		},
+	}
+
+	alertsTemplate = map[string]interface{}{
+		"index_patterns": []string{
+			alertsIndexName,
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+			"index": map[string]interface{}{
+				"max_inner_result_window": 10000,
+			},
+			"analysis": map[string]interface{}{
+				"analyzer": map[string]interface{}{
+					"lowercase_analyzer": map[string]interface{}{
+						"filter":    []string{"lowercase"},
+						"tokenizer": "keyword",
+					},
+				},
+				"normalizer": map[string]interface{}{
+					"lowercase_normalizer": map[string]interface{}{
+						"type":        "custom",
+						"char_filter": []interface{}{},
+						"filter":      []string{"lowercase"},
+					},
+				},
+			},
+		},
+		"mappings": map[string]interface{}{
+			alertsIndexTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"Action": map[string]interface{}{
+						"type": "text",
+					},
+					"AlertID": map[string]interface{}{
+						"type": "integer",
+					},
+					"ArrayID": map[string]interface{}{
+						"type":       "keyword",
+						"normalizer": "lowercase_normalizer",
+					},
+					"ArrayHostname": map[string]interface{}{
+						"type":       "keyword",
+						"normalizer": "lowercase_normalizer",
+					},
+					"ArrayDisplayName": map[string]interface{}{
+						"type":       "keyword",
+						"normalizer": "lowercase_normalizer",
+					},
+					"ArrayName": map[string]interface{}{
+						"type":       "keyword",
+						"normalizer": "lowercase_normalizer",
+					},
+					"Code": map[string]interface{}{
+						"type": "integer",
+					},
+					"Component": map[string]interface{}{
+						"type": "text",
+					},
+					"Created": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"Description": map[string]interface{}{
+						"type": "text",
+					},
+					"Flagged": map[string]interface{}{
+						"type": "boolean",
+					},
+					"InMaintenance": map[string]interface{}{
+						"type": "boolean",
+					},
+					"Notified": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"Severity": map[string]interface{}{
+						"type":      "text",
+						"fielddata": true,
+					},
+					"SeverityIndex": map[string]interface{}{
+						"type": "integer",
+					},
+					"State": map[string]interface{}{
+						"type":       "keyword",
+						"normalizer": "lowercase_normalizer",
+					},
+					"Source": map[string]interface{}{
+						"type":       "keyword",
+						"normalizer": "lowercase_normalizer",
+					},
+					"Summary": map[string]interface{}{
+						"type": "text",
+						"fields": map[string]interface{}{
+							"raw": map[string]interface{}{
+								"type":      "text",
+								"analyzer":  "lowercase_analyzer",
+								"fielddata": true,
+							},
+						},
+					},
+					"Updated": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"Lifecycle": alertLifecycleMapping,
+					"Variables": map[string]interface{}{
+						"type": "object",
+						// Make sure Elastic doesn't actually parse the inner variables document.
+						// Reason (example use case): if two different alerts use different
+						// variable types for the same key, this would error out normally
+						// (since Elastic would have already made a dynamic mapping for it of the first type).
+						// This sidesteps that by just never mapping it in the first place (which is fine: querying
+						// by these variables is so sporadic probably nobody would ever use it and would add lots
+						// of complications).
+						"enabled": false,
+					},
+				},
+			},
+		},
+	}
+)
+
+var (
+	forecastsTemplate = map[string]interface{}{
+		"index_patterns": []string{
+			forecastsIndexName,
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+		},
+		"mappings": map[string]interface{}{
+			forecastsIndexTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"ArrayID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"GeneratedAt": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"HistoryStart": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"HistoryEnd": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"SampleCount": map[string]interface{}{
+						"type": "long",
+					},
+					"UsedSpace": map[string]interface{}{
+						"type": "double",
+					},
+					"TotalSpace": map[string]interface{}{
+						"type": "double",
+					},
+					"PercentFull": map[string]interface{}{
+						"type": "double",
+					},
+					"DailyGrowth": map[string]interface{}{
+						"type": "double",
+					},
+					"WeeklySeasonality": map[string]interface{}{
+						"type": "double",
+					},
+					"Projections": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"Percent": map[string]interface{}{
+								"type": "integer",
+							},
+							"LinearDate": map[string]interface{}{
+								"type":   "date",
+								"format": "epoch_second",
+							},
+							"SeasonalDate": map[string]interface{}{
+								"type":   "date",
+								"format": "epoch_second",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	snapshotsTemplate = map[string]interface{}{
+		"index_patterns": []string{
+			snapshotsIndexName,
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+		},
+		"mappings": map[string]interface{}{
+			snapshotsIndexTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"ArrayID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayDisplayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayType": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Name": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Source": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Suffix": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Created": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"Size": map[string]interface{}{
+						"type": "long",
+					},
+					"ProtectionGroup": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Policy": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Managed": map[string]interface{}{
+						"type": "boolean",
+					},
+					"CollectedAt": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+				},
+			},
+		},
+	}
+
+	alertRulesTemplate = map[string]interface{}{
+		"index_patterns": []string{
+			alertRulesIndexName,
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+		},
+		"mappings": map[string]interface{}{
+			alertRulesIndexTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"InternalID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Name": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Enabled": map[string]interface{}{
+						"type": "boolean",
+					},
+					"Resource": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Metric": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Operator": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Threshold": map[string]interface{}{
+						"type": "double",
+					},
+					"DurationSeconds": map[string]interface{}{
+						"type": "long",
+					},
+					"Severity": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayIDs": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayTags": map[string]interface{}{
+						"type":    "object",
+						"dynamic": true,
+						"enabled": true,
+					},
+					"VolumeNames": map[string]interface{}{
+						"type": "keyword",
+					},
+					"LastUpdated": map[string]interface{}{
+						"type": "date",
+					},
+				},
+			},
+		},
+	}
+	snmpDestinationsTemplate = map[string]interface{}{
+		"index_patterns": []string{
+			snmpDestinationsIndexName,
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+		},
+		"mappings": map[string]interface{}{
+			snmpDestinationsIndexTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"InternalID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Name": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Enabled": map[string]interface{}{
+						"type": "boolean",
+					},
+					"Address": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Version": map[string]interface{}{
+						"type": "keyword",
+					},
+					"EngineID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Username": map[string]interface{}{
+						"type": "keyword",
+					},
+					"AuthProtocol": map[string]interface{}{
+						"type": "keyword",
+					},
+					"PrivProtocol": map[string]interface{}{
+						"type": "keyword",
+					},
+					"LastUpdated": map[string]interface{}{
+						"type": "date",
+					},
+				},
+			},
+		},
+	}
+
+	maintenanceWindowsTemplate = map[string]interface{}{
+		"index_patterns": []string{
+			maintenanceWindowsIndexName,
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+		},
+		"mappings": map[string]interface{}{
+			maintenanceWindowsIndexTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"InternalID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayIDs": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayTags": map[string]interface{}{
+						"type":    "object",
+						"dynamic": true,
+						"enabled": true,
+					},
+					"Start": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"End": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"Reason": map[string]interface{}{
+						"type": "text",
+					},
+					"LastUpdated": map[string]interface{}{
+						"type": "date",
+					},
+				},
+			},
+		},
+	}
+)
+
+// createProtocolPerformanceMapping is a helper function that builds the mapping for the per-protocol performance
+// of file systems: like the rollups, every field is mapped as a double up front rather than left to a dynamic mapping
+func createProtocolPerformanceMapping() map[string]interface{} {
+	fields := map[string]interface{}{}
+	for _, field := range []string{"ReadBandwidth", "WriteBandwidth", "ReadIOPS", "WriteIOPS", "OtherIOPS", "ReadLatency", "WriteLatency", "OtherLatency"} {
+		fields[field] = map[string]interface{}{
+			"type": "double",
+		}
+	}
+	protocols := map[string]interface{}{}
+	for _, protocol := range metrics.FileSystemProtocols {
+		protocols[protocol] = map[string]interface{}{
+			"properties": fields,
+		}
+	}
+	return map[string]interface{}{
+		"properties": protocols,
+	}
+}
+
+// createMetricRollupsTemplate is a helper function that builds the template for the rollup indices: every
+// statistic of every rolled up field is mapped as a double up front, since a dynamic mapping would map
+// whole-number values as longs and truncate anything indexed afterwards
+func createMetricRollupsTemplate() map[string]interface{} {
+	statistics := map[string]interface{}{}
+	for _, statistic := range []string{"Min", "Avg", "Max", "P95"} {
+		statistics[statistic] = map[string]interface{}{
+			"type": "double",
+		}
+	}
+	values := map[string]interface{}{}
+	for _, fields := range [][]string{arrayRollupFields, volumeRollupFields} {
+		for _, field := range fields {
+			values[field] = map[string]interface{}{
+				"properties": statistics,
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"index_patterns": []string{
+			getMetricRollupsIndexWildcard(),
+		},
+		"settings": map[string]interface{}{
+			"number_of_shards":   1,
+			"number_of_replicas": 0,
+		},
+		"mappings": map[string]interface{}{
+			metricRollupsTypeName: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"ArrayID": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayDisplayName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayType": map[string]interface{}{
+						"type": "keyword",
+					},
+					"ArrayTags": map[string]interface{}{
+						"type":    "object",
+						"dynamic": true,
+						"enabled": true,
+					},
+					"VolumeName": map[string]interface{}{
+						"type": "keyword",
+					},
+					"VolumeType": map[string]interface{}{
+						"type": "keyword",
+					},
+					"Resolution": map[string]interface{}{
+						"type": "keyword",
+					},
+					"StartTime": map[string]interface{}{
+						"type":   "date",
+						"format": "epoch_second",
+					},
+					"SampleCount": map[string]interface{}{
+						"type": "long",
+					},
+					"Values": map[string]interface{}{
+						"properties": values,
+					},
+				},
+			},
+		},
+	}
+}
+
+// arrayKeywordFields are the array fields filters match against that were plain text fields before they
+// got keyword subfields, so an array index created before then doesn't have them
+var arrayKeywordFields = []string{"DeviceType", "MgmtEndpoint", "Status"}
+
+// CreateArrayTemplate creates the template for the array index, and adds the keyword subfields filters match
+// against to the array index if it already exists without them
+func (c *Client) CreateArrayTemplate(ctx context.Context) error {
+	err := c.createTemplate(ctx, fmt.Sprintf("%s-template", arraysIndexName), arraysTemplate)
+	if err != nil {
+		return err
+	}
+
+	fieldMappings, err := c.esclient.GetFieldMapping().Index(arraysIndexName).Type(arraysIndexTypeName).Field(arrayKeywordFields...).Do(ctx)
+	if elastic.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	properties := getMissingArrayKeywordFields(fieldMappings)
+	if len(properties) == 0 {
+		return nil
+	}
+
+	log.WithField("fields", properties).Info("Adding keyword fields to the existing array index")
+	_, err = c.esclient.PutMapping().Index(arraysIndexName).Type(arraysIndexTypeName).BodyJson(map[string]interface{}{"properties": properties}).Do(ctx)
+	if err != nil {
+		return err
+	}
+	// Updating every array in place indexes the new subfields from the values already there
+	_, err = c.esclient.UpdateByQuery(arraysIndexName).ProceedOnVersionConflict().Refresh("true").Do(ctx)
+	return err
+}
+
+// getMissingArrayKeywordFields is a helper function that takes the field mappings of the array keyword fields in the
+// array index, and returns the mappings to put to add the keyword subfields missing from them. Fields that exist keep
+// their analyzer (which can't be changed), fields that don't get the template mapping.
+func getMissingArrayKeywordFields(fieldMappings map[string]interface{}) map[string]interface{} {
+	existing := map[string]map[string]interface{}{}
+	for _, index := range fieldMappings {
+		mappings, _ := getMapValue(index, "mappings").(map[string]interface{})
+		for _, fields := range mappings {
+			fieldsMap, _ := fields.(map[string]interface{})
+			for field := range fieldsMap {
+				if mapping, ok := getMapValue(fieldsMap, field, "mapping", field).(map[string]interface{}); ok {
+					existing[field] = mapping
+				}
+			}
+		}
+	}
+
+	templateProperties := getMapValue(arraysTemplate, "mappings", arraysIndexTypeName, "properties")
+	properties := map[string]interface{}{}
+	for _, field := range arrayKeywordFields {
+		mapping, ok := existing[field]
+		if !ok {
+			properties[field] = getMapValue(templateProperties, field)
+			continue
+		}
+		if getMapValue(mapping, "fields", "keyword") != nil {
+			continue
+		}
+		added := map[string]interface{}{
+			"type":   mapping["type"],
+			"fields": getMapValue(templateProperties, field, "fields"),
+		}
+		if analyzer, ok := mapping["analyzer"]; ok {
+			added["analyzer"] = analyzer
+		}
+		properties[field] = added
+	}
+	return properties
+}
+
+// getMapValue is a helper function that gets a value nested in maps by its keys, or nil if there isn't one
+func getMapValue(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// CreateAlertRulesTemplate creates the template for the alert rules index
+func (c *Client) CreateAlertRulesTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%s-template", alertRulesIndexName), alertRulesTemplate)
+}
+
+// CreateSNMPDestinationsTemplate creates the template for the SNMP destinations index
+func (c *Client) CreateSNMPDestinationsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%s-template", snmpDestinationsIndexName), snmpDestinationsTemplate)
+}
+
+// CreateMaintenanceWindowsTemplate creates the template for the maintenance windows index
+func (c *Client) CreateMaintenanceWindowsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%s-template", maintenanceWindowsIndexName), maintenanceWindowsTemplate)
+}
+
+// CreateArrayMetricsTemplate creates the template for the array metrics indices
+func (c *Client) CreateArrayMetricsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%stemplate", arraysTimeSeriesPrefix), arraysTimeSeriesTemplate)
+}
+
+// CreateVolumeMetricsTemplate creates the template for the volume metrics indices
 func (c *Client) CreateVolumeMetricsTemplate(ctx context.Context) error {
 	return c.createTemplate(ctx, fmt.Sprintf("%stemplate", volumesTimeSeriesPrefix), volumesTimeSeriesTemplate)
 }
 
-// CreateAlertsTemplate creates the template for the alert index
+// CreateHardwareMetricsTemplate creates the template for the hardware metrics indices
+func (c *Client) CreateHardwareMetricsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%stemplate", hardwareTimeSeriesPrefix), hardwareTimeSeriesTemplate)
+}
+
+// CreateAuditEventsTemplate creates the template for the audit event indices
+func (c *Client) CreateAuditEventsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%stemplate", auditTimeSeriesPrefix), auditTimeSeriesTemplate)
+}
+
+// CreateHostMetricsTemplate creates the template for the host metrics indices
+func (c *Client) CreateHostMetricsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%stemplate", hostsTimeSeriesPrefix), hostsTimeSeriesTemplate)
+}
+
+// CreatePodMetricsTemplate creates the template for the pod metrics indices
+func (c *Client) CreatePodMetricsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%stemplate", podsTimeSeriesPrefix), podsTimeSeriesTemplate)
+}
+
+// CreateProtectionGroupMetricsTemplate creates the template for the protection group metrics indices
+func (c *Client) CreateProtectionGroupMetricsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%stemplate", protectionGroupsTimeSeriesPrefix), protectionGroupsTimeSeriesTemplate)
+}
+
+// CreateAlertsTemplate creates the template for the alert index, and adds the lifecycle mapping to the
+// alert index if it already exists
 func (c *Client) CreateAlertsTemplate(ctx context.Context) error {
-	return c.createTemplate(ctx, fmt.Sprintf("%s-template", alertsIndexName), alertsTemplate)
+	err := c.createTemplate(ctx, fmt.Sprintf("%s-template", alertsIndexName), alertsTemplate)
+	if err != nil {
+		return err
+	}
+
+	mapping := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"Lifecycle": alertLifecycleMapping,
+		},
+	}
+	_, err = c.esclient.PutMapping().Index(alertsIndexName).Type(alertsIndexTypeName).BodyJson(mapping).Do(ctx)
+	if err != nil && !elastic.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// CreateMetricRollupsTemplate creates the template for the hourly and daily metric rollup indices
+func (c *Client) CreateMetricRollupsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%stemplate", metricRollupsPrefix), createMetricRollupsTemplate())
+}
+
+// CreateSnapshotsTemplate creates the template for the snapshot inventory index
+func (c *Client) CreateSnapshotsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%s-template", snapshotsIndexName), snapshotsTemplate)
+}
+
+// CreateCapacityForecastsTemplate creates the template for the capacity forecasts index
+func (c *Client) CreateCapacityForecastsTemplate(ctx context.Context) error {
+	return c.createTemplate(ctx, fmt.Sprintf("%s-template", forecastsIndexName), forecastsTemplate)
 }
 
 func (c *Client) getArrayMetricsIndices(ctx context.Context) ([]string, error) {
@@ -477,6 +1555,76 @@ func (c *Client) getVolumeMetricsIndices(ctx context.Context) ([]string, error)
 	})
 }
 
+func (c *Client) getHostMetricsIndices(ctx context.Context) ([]string, error) {
+	return c.tryRepeatReturnStringSliceError(func() ([]string, error) {
+		indices, err := c.esclient.CatIndices().Columns("index").Index(getHostMetricsIndexWildcard()).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		foundIndices := []string{}
+		for _, index := range indices {
+			foundIndices = append(foundIndices, index.Index)
+		}
+		return foundIndices, nil
+	})
+}
+
+func (c *Client) getProtectionGroupMetricsIndices(ctx context.Context) ([]string, error) {
+	return c.tryRepeatReturnStringSliceError(func() ([]string, error) {
+		indices, err := c.esclient.CatIndices().Columns("index").Index(getProtectionGroupMetricsIndexWildcard()).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		foundIndices := []string{}
+		for _, index := range indices {
+			foundIndices = append(foundIndices, index.Index)
+		}
+		return foundIndices, nil
+	})
+}
+
+func (c *Client) getPodMetricsIndices(ctx context.Context) ([]string, error) {
+	return c.tryRepeatReturnStringSliceError(func() ([]string, error) {
+		indices, err := c.esclient.CatIndices().Columns("index").Index(getPodMetricsIndexWildcard()).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		foundIndices := []string{}
+		for _, index := range indices {
+			foundIndices = append(foundIndices, index.Index)
+		}
+		return foundIndices, nil
+	})
+}
+
+func (c *Client) getHardwareMetricsIndices(ctx context.Context) ([]string, error) {
+	return c.tryRepeatReturnStringSliceError(func() ([]string, error) {
+		indices, err := c.esclient.CatIndices().Columns("index").Index(getHardwareMetricsIndexWildcard()).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		foundIndices := []string{}
+		for _, index := range indices {
+			foundIndices = append(foundIndices, index.Index)
+		}
+		return foundIndices, nil
+	})
+}
+
+func (c *Client) getAuditEventsIndices(ctx context.Context) ([]string, error) {
+	return c.tryRepeatReturnStringSliceError(func() ([]string, error) {
+		indices, err := c.esclient.CatIndices().Columns("index").Index(getAuditEventsIndexWildcard()).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		foundIndices := []string{}
+		for _, index := range indices {
+			foundIndices = append(foundIndices, index.Index)
+		}
+		return foundIndices, nil
+	})
+}
+
 func getArrayMetricsIndexName(time time.Time) string {
 	return fmt.Sprintf("%s%s", arraysTimeSeriesPrefix, time.UTC().Format("2006-01-02"))
 }
@@ -493,6 +1641,46 @@ func getVolumeMetricsIndexWildcard() string {
 	return fmt.Sprintf("%s*", volumesTimeSeriesPrefix)
 }
 
+func getHostMetricsIndexName(time time.Time) string {
+	return fmt.Sprintf("%s%s", hostsTimeSeriesPrefix, time.UTC().Format("2006-01-02"))
+}
+
+func getHostMetricsIndexWildcard() string {
+	return fmt.Sprintf("%s*", hostsTimeSeriesPrefix)
+}
+
+func getProtectionGroupMetricsIndexName(time time.Time) string {
+	return fmt.Sprintf("%s%s", protectionGroupsTimeSeriesPrefix, time.UTC().Format("2006-01-02"))
+}
+
+func getProtectionGroupMetricsIndexWildcard() string {
+	return fmt.Sprintf("%s*", protectionGroupsTimeSeriesPrefix)
+}
+
+func getPodMetricsIndexName(time time.Time) string {
+	return fmt.Sprintf("%s%s", podsTimeSeriesPrefix, time.UTC().Format("2006-01-02"))
+}
+
+func getPodMetricsIndexWildcard() string {
+	return fmt.Sprintf("%s*", podsTimeSeriesPrefix)
+}
+
+func getHardwareMetricsIndexName(time time.Time) string {
+	return fmt.Sprintf("%s%s", hardwareTimeSeriesPrefix, time.UTC().Format("2006-01-02"))
+}
+
+func getHardwareMetricsIndexWildcard() string {
+	return fmt.Sprintf("%s*", hardwareTimeSeriesPrefix)
+}
+
+func getAuditEventsIndexName(time time.Time) string {
+	return fmt.Sprintf("%s%s", auditTimeSeriesPrefix, time.UTC().Format("2006-01-02"))
+}
+
+func getAuditEventsIndexWildcard() string {
+	return fmt.Sprintf("%s*", auditTimeSeriesPrefix)
+}
+
 func getTimeFromArrayMetricsIndexName(indexName string) (time.Time, error) {
 	// If this has the prefix, it'll go great. If it doesn't or it's malformed, the date parsing will fail
 	// and return an error
@@ -512,3 +1700,105 @@ func getTimeFromVolumeMetricsIndexName(indexName string) (time.Time, error) {
 	}
 	return parsed.UTC(), nil
 }
+
+func getTimeFromHostMetricsIndexName(indexName string) (time.Time, error) {
+	// If this has the prefix, it'll go great. If it doesn't or it's malformed, the date parsing will fail
+	// and return an error
+	parsed, err := time.Parse("2006-01-02", strings.TrimPrefix(indexName, hostsTimeSeriesPrefix))
+	if err != nil {
+		return time.Now(), err
+	}
+	return parsed.UTC(), nil
+}
+
+func getTimeFromProtectionGroupMetricsIndexName(indexName string) (time.Time, error) {
+	// If this has the prefix, it'll go great. If it doesn't or it's malformed, the date parsing will fail
+	// and return an error
+	parsed, err := time.Parse("2006-01-02", strings.TrimPrefix(indexName, protectionGroupsTimeSeriesPrefix))
+	if err != nil {
+		return time.Now(), err
+	}
+	return parsed.UTC(), nil
+}
+
+func getTimeFromPodMetricsIndexName(indexName string) (time.Time, error) {
+	// If this has the prefix, it'll go great. If it doesn't or it's malformed, the date parsing will fail
+	// and return an error
+	parsed, err := time.Parse("2006-01-02", strings.TrimPrefix(indexName, podsTimeSeriesPrefix))
+	if err != nil {
+		return time.Now(), err
+	}
+	return parsed.UTC(), nil
+}
+
+func getTimeFromHardwareMetricsIndexName(indexName string) (time.Time, error) {
+	// If this has the prefix, it'll go great. If it doesn't or it's malformed, the date parsing will fail
+	// and return an error
+	parsed, err := time.Parse("2006-01-02", strings.TrimPrefix(indexName, hardwareTimeSeriesPrefix))
+	if err != nil {
+		return time.Now(), err
+	}
+	return parsed.UTC(), nil
+}
+
+func getTimeFromAuditEventsIndexName(indexName string) (time.Time, error) {
+	// If this has the prefix, it'll go great. If it doesn't or it's malformed, the date parsing will fail
+	// and return an error
+	parsed, err := time.Parse("2006-01-02", strings.TrimPrefix(indexName, auditTimeSeriesPrefix))
+	if err != nil {
+		return time.Now(), err
+	}
+	return parsed.UTC(), nil
+}
+
+func (c *Client) getMetricRollupsIndices(ctx context.Context) ([]string, error) {
+	return c.tryRepeatReturnStringSliceError(func() ([]string, error) {
+		indices, err := c.esclient.CatIndices().Columns("index").Index(getMetricRollupsIndexWildcard()).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		foundIndices := []string{}
+		for _, index := range indices {
+			foundIndices = append(foundIndices, index.Index)
+		}
+		return foundIndices, nil
+	})
+}
+
+// getMetricRollupsIndexName gets the index holding rollups of the given kind and resolution for the given time:
+// hourly rollups get an index per day, daily rollups an index per month
+func getMetricRollupsIndexName(kind string, resolution string, time time.Time) string {
+	dateFormat := rollupHourlyDateFormat
+	if resolution == metrics.DailyRollup {
+		dateFormat = rollupDailyDateFormat
+	}
+	return fmt.Sprintf("%s%s-%s-%s", metricRollupsPrefix, resolution, kind, time.UTC().Format(dateFormat))
+}
+
+func getMetricRollupsIndexWildcard() string {
+	return fmt.Sprintf("%s*", metricRollupsPrefix)
+}
+
+// getMetricRollupsKindIndexWildcard matches the rollup indices of a single kind and resolution
+func getMetricRollupsKindIndexWildcard(kind string, resolution string) string {
+	return fmt.Sprintf("%s%s-%s-*", metricRollupsPrefix, resolution, kind)
+}
+
+// getRollupFromMetricRollupsIndexName parses the resolution and the start of the period covered by a rollup index
+func getRollupFromMetricRollupsIndexName(indexName string) (string, time.Time, error) {
+	parts := strings.SplitN(strings.TrimPrefix(indexName, metricRollupsPrefix), "-", 3)
+	if !strings.HasPrefix(indexName, metricRollupsPrefix) || len(parts) != 3 {
+		return "", time.Now(), fmt.Errorf("Index %s is not a metric rollup index", indexName)
+	}
+	dateFormat := rollupHourlyDateFormat
+	if parts[0] == metrics.DailyRollup {
+		dateFormat = rollupDailyDateFormat
+	} else if parts[0] != metrics.HourlyRollup {
+		return "", time.Now(), fmt.Errorf("Index %s has unknown rollup resolution %s", indexName, parts[0])
+	}
+	parsed, err := time.Parse(dateFormat, parts[2])
+	if err != nil {
+		return "", time.Now(), err
+	}
+	return parts[0], parsed.UTC(), nil
+}
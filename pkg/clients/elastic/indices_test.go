@@ -0,0 +1,63 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMissingArrayKeywordFields(t *testing.T) {
+	// An array index from before the keyword fields were added: Status is a plain text field, DeviceType already has
+	// its keyword field and MgmtEndpoint hasn't been mapped yet
+	var fieldMappings map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"pure-arrays": {
+			"mappings": {
+				"_doc": {
+					"Status": {"full_name": "Status", "mapping": {"Status": {"type": "text"}}},
+					"DeviceType": {"full_name": "DeviceType", "mapping": {"DeviceType": {"type": "text", "analyzer": "lowercase_analyzer",
+						"fields": {"keyword": {"type": "keyword", "normalizer": "lowercase_normalizer"}}}}}
+				}
+			}
+		}
+	}`), &fieldMappings)
+	assert.NoError(t, err)
+
+	properties := getMissingArrayKeywordFields(fieldMappings)
+	assert.Len(t, properties, 2)
+	assert.NotContains(t, properties, "DeviceType")
+
+	// The existing field keeps its (default) analyzer
+	status := properties["Status"].(map[string]interface{})
+	assert.Equal(t, "text", status["type"])
+	assert.NotContains(t, status, "analyzer")
+	assert.Equal(t, "lowercase_normalizer", getMapValue(status, "fields", "keyword", "normalizer"))
+
+	// The missing field gets the template mapping
+	assert.Equal(t, getMapValue(arraysTemplate, "mappings", arraysIndexTypeName, "properties", "MgmtEndpoint"), properties["MgmtEndpoint"])
+}
+
+func TestGetMissingArrayKeywordFieldsUpToDate(t *testing.T) {
+	fieldMappings := map[string]interface{}{"pure-arrays": map[string]interface{}{"mappings": map[string]interface{}{"_doc": map[string]interface{}{}}}}
+	for _, field := range arrayKeywordFields {
+		getMapValue(fieldMappings, "pure-arrays", "mappings", "_doc").(map[string]interface{})[field] = map[string]interface{}{
+			"mapping": map[string]interface{}{field: getMapValue(arraysTemplate, "mappings", arraysIndexTypeName, "properties", field)},
+		}
+	}
+	assert.Empty(t, getMissingArrayKeywordFields(fieldMappings))
+}
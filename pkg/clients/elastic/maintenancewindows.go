@@ -0,0 +1,110 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/http/errors"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ resources.MaintenanceWindowDatabase = (*Client)(nil)
+
+// More maintenance windows than anyone should practically schedule before cleaning up old ones
+const maxMaintenanceWindows = 1000
+
+// FindMaintenanceWindows gets the maintenance windows with the given IDs, or every window if no IDs are given
+func (c *Client) FindMaintenanceWindows(ids []string) ([]*resources.MaintenanceWindow, error) {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	var query elastic.Query = elastic.NewMatchAllQuery()
+	if len(ids) > 0 {
+		query = elastic.NewIdsQuery(maintenanceWindowsIndexTypeName).Ids(ids...)
+	}
+
+	res, err := c.esclient.Search(maintenanceWindowsIndexName).Type(maintenanceWindowsIndexTypeName).Query(query).Size(maxMaintenanceWindows).Sort("Start", false).IgnoreUnavailable(true).AllowNoIndices(true).Do(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	results := []*resources.MaintenanceWindow{}
+	for _, res := range res.Each(reflect.TypeOf(&resources.MaintenanceWindow{})) {
+		results = append(results, res.(*resources.MaintenanceWindow))
+	}
+	return results, nil
+}
+
+// InsertMaintenanceWindow inserts the given maintenance window into Elastic
+func (c *Client) InsertMaintenanceWindow(window *resources.MaintenanceWindow) error {
+	return c.indexMaintenanceWindow(window)
+}
+
+// UpdateMaintenanceWindow replaces the stored maintenance window with the same ID as the given window
+func (c *Client) UpdateMaintenanceWindow(window *resources.MaintenanceWindow) error {
+	return c.indexMaintenanceWindow(window)
+}
+
+// DeleteMaintenanceWindows deletes the maintenance windows with the given IDs from Elastic
+func (c *Client) DeleteMaintenanceWindows(ids []string) ([]string, error) {
+	ctx := context.Background()
+
+	// Get all the windows that exist first, so we only report the ones actually deleted
+	windows, err := c.FindMaintenanceWindows(ids)
+	if err != nil {
+		return nil, err
+	}
+	deletedIDs := []string{}
+	for _, window := range windows {
+		deletedIDs = append(deletedIDs, window.InternalID)
+	}
+	if len(deletedIDs) == 0 {
+		return deletedIDs, nil
+	}
+
+	_, err = c.esclient.DeleteByQuery(maintenanceWindowsIndexName).Type(maintenanceWindowsIndexTypeName).Query(elastic.NewIdsQuery(maintenanceWindowsIndexTypeName).Ids(deletedIDs...)).Do(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+	return deletedIDs, nil
+}
+
+// indexMaintenanceWindow is a helper function that stores the given maintenance window under its ID
+func (c *Client) indexMaintenanceWindow(window *resources.MaintenanceWindow) error {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return errors.MakeInternalHTTPErr(err)
+	}
+
+	log.WithField("window_id", window.InternalID).Trace("Beginning to push maintenance window to Elastic")
+	_, err = c.esclient.Index().Index(maintenanceWindowsIndexName).Type(maintenanceWindowsIndexTypeName).Id(window.InternalID).BodyJson(window).Do(ctx)
+	if err != nil {
+		return errors.MakeInternalHTTPErr(err)
+	}
+	log.WithField("window_id", window.InternalID).Trace("Maintenance window pushed to Elastic successfully")
+	return nil
+}
@@ -17,6 +17,7 @@ package elastic
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
@@ -29,14 +30,15 @@ import (
 // Type guard: ensure this implements the interface
 var _ metrics.Database = (*Client)(nil)
 
-// AddArrayMetrics adds the given metrics to both the time-series and latest indices
+// AddArrayMetrics adds the given metrics to the time-series indices, each to the index for the day it was collected
+// on. Each metric gets an ID from its array and time, so metrics that were already added (by an earlier attempt of a
+// spooled write that failed partway through) are skipped. Past days that get new metrics are rolled up again.
 func (c *Client) AddArrayMetrics(metrics []*metrics.ArrayMetric) error {
 	if len(metrics) == 0 {
 		log.Debug("No device metrics to push, skipping")
 		return nil
 	}
 
-	indexName := getArrayMetricsIndexName(time.Now().UTC())
 	ctx := context.Background()
 
 	timer := timing.NewStageTimer("Client.AddArrayMetrics", log.Fields{})
@@ -49,54 +51,80 @@ func (c *Client) AddArrayMetrics(metrics []*metrics.ArrayMetric) error {
 
 	timer.Stage("push_metrics")
 
+	requests := []elastic.BulkableRequest{}
+	indexDays := map[string]time.Time{}
 	for _, metric := range metrics {
-		// Try pushing time-series data
-		err = c.tryRepeatReturnErrorOnly(func() error {
-			log.WithFields(log.Fields{
-				// Log name and ID separately from the metric as well to make it easier to search
-				"array_name": metric.DisplayName,
-				"id":         metric.ArrayID,
-				"metric":     metric,
-			}).Trace("Beginning device time series metric push")
-			_, err = c.esclient.Index().
-				Index(indexName).
-				Type(arraysTimeSeriesTypeName).
-				BodyJson(metric).
-				Do(ctx)
-			if err == nil {
-				log.WithFields(log.Fields{
-					// Log name and ID separately from the metric as well to make it easier to search
-					"array_name": metric.DisplayName,
-					"id":         metric.ArrayID,
-					"metric":     metric,
-				}).Trace("Array time series metric push successful")
-			} else {
-				log.WithFields(log.Fields{
-					"err": err,
-					// Log name and ID separately from the metric as well to make it easier to search
-					"array_name": metric.DisplayName,
-					"id":         metric.ArrayID,
-					"metric":     metric,
-				}).Error("Error pushing array time series metrics")
-			}
-			return err
-		})
+		log.WithFields(log.Fields{
+			// Log name and ID separately from the metric as well to make it easier to search
+			"array_name": metric.DisplayName,
+			"id":         metric.ArrayID,
+			"metric":     metric,
+		}).Trace("Adding bulk request for device time series metric")
+		createdAt := time.Unix(metric.CreatedAt, 0).UTC()
+		indexName := getArrayMetricsIndexName(createdAt)
+		indexDays[indexName] = time.Date(createdAt.Year(), createdAt.Month(), createdAt.Day(), 0, 0, 0, 0, time.UTC)
+		requests = append(requests, elastic.NewBulkIndexRequest().
+			Index(indexName).
+			Type(arraysTimeSeriesTypeName).
+			Id(getArrayMetricID(metric)).
+			OpType("create").
+			Doc(metric))
+	}
+
+	// Try pushing time-series data
+	addedDays := map[time.Time]struct{}{}
+	err = c.tryRepeatReturnErrorOnly(func() error {
+		res, err := c.esclient.Bulk().Add(requests...).Do(ctx)
 		if err != nil {
+			log.WithError(err).Error("Error pushing array time series metrics (overall error, not individual document)")
 			return err
 		}
+
+		recordAddedDays(addedDays, res, indexDays)
+		failed := 0
+		rejected := 0
+		for _, failure := range res.Failed() {
+			// Already added
+			if failure.Status == http.StatusConflict {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"type":   failure.Error.Type,
+				"id":     failure.Id,
+				"reason": failure.Error.Reason,
+			}).Error("Array metric failed to index in bulk request")
+			failed++
+			if isRejectedBulkStatus(failure.Status) {
+				rejected++
+			}
+		}
+		if failed > 0 && failed == rejected {
+			return newRejectedBulkError("array metrics", rejected)
+		}
+		if failed > 0 {
+			return fmt.Errorf("Some array metrics failed in bulk request")
+		}
+		log.WithField("count", len(requests)).Trace("Array time series metrics pushed successfully")
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	return nil
+	timer.Stage("unmark_rolled_up")
+	return c.unmarkPastDaysRolledUp(ctx, arrayRollupKind, addedDays)
 }
 
-// AddVolumeMetrics adds the given volume metrics to both the time-series and latest indices
+// AddVolumeMetrics adds the given volume metrics to the time-series indices, each to the index for the day it was
+// collected on. Each metric gets an ID from its array, volume and time, so metrics that were already added (by an
+// earlier attempt of a spooled write that failed partway through) are skipped. Past days that get new metrics are
+// rolled up again.
 func (c *Client) AddVolumeMetrics(metrics []*metrics.VolumeMetric) error {
 	if len(metrics) == 0 {
 		log.Debug("No volume metrics to push, skipping")
 		return nil
 	}
 
-	indexName := getVolumeMetricsIndexName(time.Now().UTC())
 	ctx := context.Background()
 
 	timer := timing.NewStageTimer("Client.AddVolumeMetrics", log.Fields{})
@@ -110,6 +138,7 @@ func (c *Client) AddVolumeMetrics(metrics []*metrics.VolumeMetric) error {
 	timer.Stage("push_metrics")
 
 	requests := []elastic.BulkableRequest{}
+	indexDays := map[string]time.Time{}
 	arrayNameMap := map[string]struct{}{}
 	arrayIDMap := map[string]struct{}{}
 
@@ -120,7 +149,15 @@ func (c *Client) AddVolumeMetrics(metrics []*metrics.VolumeMetric) error {
 			"volume_name": metric.VolumeName,
 			"metric":      metric,
 		}).Trace("Adding bulk request for volume time series metric")
-		requests = append(requests, elastic.NewBulkIndexRequest().Index(indexName).Type(volumesTimeSeriesTypeName).Doc(metric))
+		createdAt := time.Unix(metric.CreatedAt, 0).UTC()
+		indexName := getVolumeMetricsIndexName(createdAt)
+		indexDays[indexName] = time.Date(createdAt.Year(), createdAt.Month(), createdAt.Day(), 0, 0, 0, 0, time.UTC)
+		requests = append(requests, elastic.NewBulkIndexRequest().
+			Index(indexName).
+			Type(volumesTimeSeriesTypeName).
+			Id(getVolumeMetricID(metric)).
+			OpType("create").
+			Doc(metric))
 		arrayNameMap[metric.ArrayDisplayName] = struct{}{}
 		arrayIDMap[metric.ArrayID] = struct{}{}
 	}
@@ -133,6 +170,7 @@ func (c *Client) AddVolumeMetrics(metrics []*metrics.VolumeMetric) error {
 		arrayIDs = append(arrayIDs, id)
 	}
 	// Try pushing time-series data
+	addedDays := map[time.Time]struct{}{}
 	err = c.tryRepeatReturnErrorOnly(func() error {
 		log.WithFields(log.Fields{
 			"array_names": arrayNames,
@@ -148,19 +186,32 @@ func (c *Client) AddVolumeMetrics(metrics []*metrics.VolumeMetric) error {
 			return err
 		}
 
-		failed := res.Failed()
-		for _, failure := range failed {
+		recordAddedDays(addedDays, res, indexDays)
+		failed := 0
+		rejected := 0
+		for _, failure := range res.Failed() {
+			// Already added
+			if failure.Status == http.StatusConflict {
+				continue
+			}
 			log.WithFields(log.Fields{
 				"type":   failure.Error.Type,
 				"id":     failure.Id,
 				"reason": failure.Error.Reason,
 			}).Error("Volume failed to index in bulk request")
+			failed++
+			if isRejectedBulkStatus(failure.Status) {
+				rejected++
+			}
 		}
-		if len(failed) > 0 {
+		if failed > 0 {
 			log.WithFields(log.Fields{
 				"array_names": arrayNames,
 				"array_ids":   arrayIDs,
 			}).Error("Not all volumes indexed successfully")
+			if failed == rejected {
+				return newRejectedBulkError("volume metrics", rejected)
+			}
 			return fmt.Errorf("Some volumes failed in bulk request")
 		}
 
@@ -170,8 +221,12 @@ func (c *Client) AddVolumeMetrics(metrics []*metrics.VolumeMetric) error {
 		}).Trace("Time series metrics pushed successfully")
 		return err
 	})
+	if err != nil {
+		return err
+	}
 
-	return nil
+	timer.Stage("unmark_rolled_up")
+	return c.unmarkPastDaysRolledUp(ctx, volumeRollupKind, addedDays)
 }
 
 // UpdateAlerts upserts the given alerts
@@ -203,7 +258,7 @@ func (c *Client) UpdateAlerts(alerts []*metrics.Alert) error {
 			log.WithField("alert", alert).Debug("Alert missing required field, skipping")
 			continue
 		}
-		id := fmt.Sprintf("%s-alert-%d", alert.ArrayID, alert.AlertID)
+		id := alert.GetDocumentID()
 
 		log.WithFields(log.Fields{
 			"array_name":  alert.ArrayName,
@@ -212,11 +267,15 @@ func (c *Client) UpdateAlerts(alerts []*metrics.Alert) error {
 			"alert":       alert,
 			"document_id": id,
 		}).Trace("Adding bulk request for alert")
+		// The lifecycle is only changed through PatchAlertLifecycles, so that a stale copy (like on an alert
+		// loaded back from Elastic by the alert rule evaluator) can't undo changes made in the meantime
+		doc := *alert
+		doc.Lifecycle = nil
 		requests = append(requests, elastic.NewBulkUpdateRequest().
 			Index(alertsIndexName).
 			Type(alertsIndexTypeName).
 			Id(id).
-			Doc(alert).
+			Doc(&doc).
 			RetryOnConflict(1). // Retry once on conflict, more than that will just be unnecessary lag
 			DocAsUpsert(true))
 		arrayNameMap[alert.ArrayDisplayName] = struct{}{}
@@ -247,18 +306,25 @@ func (c *Client) UpdateAlerts(alerts []*metrics.Alert) error {
 			return err
 		}
 		failed := res.Failed()
+		rejected := 0
 		for _, failure := range failed {
 			log.WithFields(log.Fields{
 				"type":   failure.Error.Type,
 				"id":     failure.Id,
 				"reason": failure.Error.Reason,
 			}).Error("Alert failed to upsert in bulk request")
+			if isRejectedBulkStatus(failure.Status) {
+				rejected++
+			}
 		}
 		if len(failed) > 0 {
 			log.WithFields(log.Fields{
 				"array_names": arrayNames,
 				"array_ids":   arrayIDs,
 			}).Error("Not all alerts upserted successfully")
+			if len(failed) == rejected {
+				return newRejectedBulkError("alerts", rejected)
+			}
 			return fmt.Errorf("Some alerts failed in bulk upsert")
 		}
 		log.WithFields(log.Fields{
@@ -324,6 +390,7 @@ func (c *Client) CleanArrayMetrics(maxAgeInDays int) error {
 
 	timer.Stage("delete_indices")
 
+	toDelete = c.filterRolledUpIndices(context.Background(), arrayRollupKind, toDelete, getTimeFromArrayMetricsIndexName)
 	if len(toDelete) > 0 {
 		err = c.DeleteIndices(context.Background(), toDelete)
 		if err != nil {
@@ -409,6 +476,7 @@ func (c *Client) CleanVolumeMetrics(maxAgeInDays int) error {
 
 	timer.Stage("delete_indices")
 
+	toDelete = c.filterRolledUpIndices(context.Background(), volumeRollupKind, toDelete, getTimeFromVolumeMetricsIndexName)
 	if len(toDelete) > 0 {
 		log.WithFields(log.Fields{
 			"to_delete": toDelete,
@@ -724,3 +792,27 @@ func (c *Client) CleanTimerLogs(maxAgeInDays int) error {
 	}).Trace("Timer log cleaning finished")
 	return nil
 }
+
+// isRejectedBulkStatus is a helper function that checks if a bulk request item failed in a way that trying it again
+// won't fix: any client error other than a conflict or too many requests
+func isRejectedBulkStatus(status int) bool {
+	return status >= 400 && status < 500 && status != http.StatusConflict && status != http.StatusTooManyRequests
+}
+
+// newRejectedBulkError is a helper function that returns the error for a bulk request whose only failures were
+// rejected items
+func newRejectedBulkError(kind string, rejected int) error {
+	return &metrics.RejectedError{Reason: fmt.Sprintf("%d %s were rejected in bulk request", rejected, kind)}
+}
+
+// getArrayMetricID is a helper function that returns the ID of an array metric, which is the same however many times
+// the metric is added
+func getArrayMetricID(metric *metrics.ArrayMetric) string {
+	return fmt.Sprintf("%s-%d", metric.ArrayID, metric.CreatedAt)
+}
+
+// getVolumeMetricID is a helper function that returns the ID of a volume metric, which is the same however many times
+// the metric is added
+func getVolumeMetricID(metric *metrics.VolumeMetric) string {
+	return fmt.Sprintf("%s-%s-%s-%d", metric.ArrayID, metric.Type, metric.VolumeName, metric.CreatedAt)
+}
@@ -0,0 +1,52 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"testing"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricIDsDeterministic(t *testing.T) {
+	array := &metrics.ArrayMetric{ArrayID: "array1", CreatedAt: 1552521600}
+	assert.Equal(t, getArrayMetricID(array), getArrayMetricID(&metrics.ArrayMetric{ArrayID: "array1", CreatedAt: 1552521600}))
+	assert.NotEqual(t, getArrayMetricID(array), getArrayMetricID(&metrics.ArrayMetric{ArrayID: "array1", CreatedAt: 1552521660}))
+
+	volume := &metrics.VolumeMetric{ArrayID: "array1", Type: "Volume", VolumeName: "vol1", CreatedAt: 1552521600}
+	assert.Equal(t, "array1-Volume-vol1-1552521600", getVolumeMetricID(volume))
+	assert.NotEqual(t, getVolumeMetricID(volume), getVolumeMetricID(&metrics.VolumeMetric{ArrayID: "array1", Type: "FileSystem", VolumeName: "vol1", CreatedAt: 1552521600}))
+}
+
+func TestBackfilledArrayMetricsHaveNoCapacity(t *testing.T) {
+	metric := &metrics.ArrayMetric{
+		ArrayPerformanceMetric: &metrics.ArrayPerformanceMetric{ReadIOPS: 100},
+		ArrayID:                "array1",
+		Backfilled:             true,
+		CreatedAt:              1552521600,
+	}
+	lines, err := elastic.NewBulkIndexRequest().Index("pure-arrays-metrics-2019-03-14").Type(arraysTimeSeriesTypeName).Doc(metric).Source()
+	assert.NoError(t, err)
+
+	// Left out rather than zeroed, so the capacity aggregations for rollups and forecasts skip the document
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[1], `"ReadIOPS":100`)
+		assert.NotContains(t, lines[1], "UsedSpace")
+		assert.NotContains(t, lines[1], "TotalSpace")
+		assert.NotContains(t, lines[1], "PercentFull")
+	}
+}
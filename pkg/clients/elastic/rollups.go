@@ -0,0 +1,562 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/timing"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.RollupDatabase = (*Client)(nil)
+
+const (
+	arrayRollupKind  = "arrays"
+	volumeRollupKind = "volumes"
+
+	// Number of rollups built per search request
+	rollupPageSize = 100
+
+	rollupAggregationName  = "rollups"
+	latestAggregationName  = "latest"
+	statsAggregationSuffix = "_stats"
+	p95AggregationSuffix   = "_p95"
+	bucketStartKey         = "StartTime"
+)
+
+var (
+	arrayRollupFields = []string{
+		"AlertMessageCount",
+		"BytesPerOp",
+		"BytesPerRead",
+		"BytesPerWrite",
+		"DataReduction",
+		"FileSystemCount",
+		"HostCount",
+		"OtherIOPS",
+		"OtherLatency",
+		"PercentFull",
+		"QueueDepth",
+		"ReadBandwidth",
+		"ReadIOPS",
+		"ReadLatency",
+		"SharedSpace",
+		"SnapshotCount",
+		"SnapshotSpace",
+		"SystemSpace",
+		"TotalReduction",
+		"TotalSpace",
+		"UsedSpace",
+		"VolumeCount",
+		"VolumePendingEradicationCount",
+		"VolumeSpace",
+		"WriteBandwidth",
+		"WriteIOPS",
+		"WriteLatency",
+	}
+
+	volumeRollupFields = []string{
+		"DataReduction",
+		"ObjectCount",
+		"OtherIOPS",
+		"OtherLatency",
+		"ProvisionedSpace",
+		"ReadBandwidth",
+		"ReadIOPS",
+		"ReadLatency",
+		"SnapshotCount",
+		"TotalReduction",
+		"UsedSpace",
+		"WriteBandwidth",
+		"WriteIOPS",
+		"WriteLatency",
+	}
+
+	rollupResolutions = map[string]string{
+		metrics.HourlyRollup: "1h",
+		metrics.DailyRollup:  "1d",
+	}
+)
+
+// RequireRollupsBeforeCleaning sets whether CleanArrayMetrics and CleanVolumeMetrics should keep raw
+// metrics indices past their retention age until they have been rolled up
+func (c *Client) RequireRollupsBeforeCleaning(required bool) {
+	c.requireRollups = required
+}
+
+// RollupMetrics aggregates every full day of raw array and volume metrics that hasn't been rolled up yet
+// into hourly and daily rollups, then marks the day as rolled up so the raw index can be cleaned
+func (c *Client) RollupMetrics() error {
+	ctx := context.Background()
+
+	timer := timing.NewStageTimer("Client.RollupMetrics", log.Fields{})
+	defer timer.Finish()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, kind := range c.getRollupKinds() {
+		timer.Stage(fmt.Sprintf("rollup_%s", kind.name))
+		err = c.rollupKind(ctx, kind)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CleanMetricRollups deletes hourly and daily rollup indices once the whole period they cover is past the
+// given retention age
+func (c *Client) CleanMetricRollups(hourlyMaxAgeInDays int, dailyMaxAgeInDays int) error {
+	log.WithFields(log.Fields{
+		"hourly_max_age_in_days": hourlyMaxAgeInDays,
+		"daily_max_age_in_days":  dailyMaxAgeInDays,
+	}).Trace("Beginning metric rollups cleaning")
+
+	timer := timing.NewStageTimer("Client.CleanMetricRollups", log.Fields{})
+	defer timer.Finish()
+
+	indices, err := c.getMetricRollupsIndices(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Error getting metric rollup indices")
+		return err
+	}
+
+	timer.Stage("process_index_names")
+
+	toDelete := []string{}
+	for _, index := range indices {
+		resolution, start, err := getRollupFromMetricRollupsIndexName(index)
+		if err != nil {
+			log.WithField("index", index).Warn("Index has invalid name format, skipping; it will be retained")
+			continue
+		}
+		if isRollupIndexExpired(resolution, start, hourlyMaxAgeInDays, dailyMaxAgeInDays, time.Now().UTC()) {
+			log.WithFields(log.Fields{
+				"index":      index,
+				"resolution": resolution,
+			}).Info("Rollup index is past retention date, deleting")
+			toDelete = append(toDelete, index)
+		}
+	}
+
+	timer.Stage("delete_indices")
+
+	if len(toDelete) > 0 {
+		err = c.DeleteIndices(context.Background(), toDelete)
+		if err != nil {
+			log.WithError(err).Error("Error deleting old rollup indices")
+			return err
+		}
+	}
+	log.Trace("Metric rollups cleaning finished")
+	return nil
+}
+
+// getRollupKinds is a helper function that describes how to roll up array and volume metrics
+func (c *Client) getRollupKinds() []rollupKind {
+	return []rollupKind{
+		{
+			name:            arrayRollupKind,
+			groupFields:     []string{"ArrayID"},
+			valueFields:     arrayRollupFields,
+			getRawIndices:   c.getArrayMetricsIndices,
+			getRawIndexTime: getTimeFromArrayMetricsIndexName,
+			readMetadata: func(source []byte, rollup *metrics.MetricRollup) error {
+				metric := metrics.ArrayMetric{}
+				err := json.Unmarshal(source, &metric)
+				if err != nil {
+					return err
+				}
+				rollup.ArrayID = metric.ArrayID
+				rollup.ArrayName = metric.ArrayName
+				rollup.ArrayDisplayName = metric.DisplayName
+				rollup.ArrayType = metric.ArrayType
+				rollup.ArrayTags = metric.Tags
+				return nil
+			},
+		},
+		{
+			name:            volumeRollupKind,
+			groupFields:     []string{"ArrayID", "Type", "VolumeName"}, // File systems, buckets and accounts can share names
+			valueFields:     volumeRollupFields,
+			getRawIndices:   c.getVolumeMetricsIndices,
+			getRawIndexTime: getTimeFromVolumeMetricsIndexName,
+			readMetadata: func(source []byte, rollup *metrics.MetricRollup) error {
+				metric := metrics.VolumeMetric{}
+				err := json.Unmarshal(source, &metric)
+				if err != nil {
+					return err
+				}
+				rollup.ArrayID = metric.ArrayID
+				rollup.ArrayName = metric.ArrayName
+				rollup.ArrayDisplayName = metric.ArrayDisplayName
+				rollup.ArrayTags = metric.ArrayTags
+				rollup.VolumeName = metric.VolumeName
+				rollup.VolumeType = metric.Type
+				return nil
+			},
+		},
+	}
+}
+
+// rollupKind is a helper function that rolls up every full day of raw metrics of the given kind, oldest first
+func (c *Client) rollupKind(ctx context.Context, kind rollupKind) error {
+	indices, err := kind.getRawIndices(ctx)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"kind":  kind.name,
+		}).Error("Error getting raw metrics indices to roll up")
+		return err
+	}
+	// The date in the index names sorts lexically
+	sort.Strings(indices)
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, index := range indices {
+		day, err := kind.getRawIndexTime(index)
+		if err != nil {
+			log.WithField("index", index).Warn("Index has invalid date format, skipping rollup")
+			continue
+		}
+		// Today's index is still being written to
+		if !day.Before(today) {
+			continue
+		}
+
+		rolledUp, err := c.isRolledUp(ctx, kind.name, day)
+		if err != nil {
+			return err
+		}
+		if rolledUp {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"index": index,
+			"kind":  kind.name,
+		}).Info("Rolling up raw metrics index")
+		for _, resolution := range []string{metrics.HourlyRollup, metrics.DailyRollup} {
+			err = c.rollupIndex(ctx, kind, index, day, resolution)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":      err,
+					"index":      index,
+					"resolution": resolution,
+				}).Error("Error rolling up raw metrics index")
+				return err
+			}
+		}
+
+		err = c.markRolledUp(ctx, kind.name, day)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollupIndex is a helper function that aggregates a raw metrics index into rollups of the given resolution,
+// a page of buckets at a time. Rollups have deterministic IDs, so rolling up a day again overwrites them.
+func (c *Client) rollupIndex(ctx context.Context, kind rollupKind, rawIndex string, day time.Time, resolution string) error {
+	targetIndex := getMetricRollupsIndexName(kind.name, resolution, day)
+
+	var after map[string]interface{}
+	for {
+		aggregation := newRollupAggregation(kind, rollupResolutions[resolution], after)
+
+		var result *elastic.SearchResult
+		err := c.tryRepeatReturnErrorOnly(func() error {
+			var err error
+			result, err = c.esclient.Search(rawIndex).Size(0).Aggregation(rollupAggregationName, aggregation).Do(ctx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		items, found := result.Aggregations.Composite(rollupAggregationName)
+		if !found {
+			return fmt.Errorf("Rollup aggregation missing from search result")
+		}
+
+		rollups, err := parseRollupBuckets(kind, resolution, items.Buckets)
+		if err != nil {
+			return err
+		}
+		err = c.indexRollups(ctx, targetIndex, rollups)
+		if err != nil {
+			return err
+		}
+
+		if len(items.Buckets) < rollupPageSize || len(items.AfterKey) == 0 {
+			return nil
+		}
+		after = items.AfterKey
+	}
+}
+
+// indexRollups is a helper function that bulk indexes the given rollups
+func (c *Client) indexRollups(ctx context.Context, index string, rollups []*metrics.MetricRollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	requests := []elastic.BulkableRequest{}
+	for _, rollup := range rollups {
+		requests = append(requests, elastic.NewBulkIndexRequest().Index(index).Type(metricRollupsTypeName).Id(getRollupDocumentID(rollup)).Doc(rollup))
+	}
+
+	return c.tryRepeatReturnErrorOnly(func() error {
+		res, err := c.esclient.Bulk().Add(requests...).Do(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"index": index,
+			}).Error("Error in bulk request for metric rollups (overall error, not single document)")
+			return err
+		}
+		failed := res.Failed()
+		for _, failure := range failed {
+			log.WithFields(log.Fields{
+				"type":   failure.Error.Type,
+				"id":     failure.Id,
+				"reason": failure.Error.Reason,
+			}).Error("Metric rollup failed to index in bulk request")
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("Some metric rollups failed in bulk request")
+		}
+		return nil
+	})
+}
+
+// isRolledUp is a helper function that checks if a day of raw metrics of the given kind has been rolled up
+func (c *Client) isRolledUp(ctx context.Context, kind string, day time.Time) (bool, error) {
+	return c.tryRepeatReturnBoolError(func() (bool, error) {
+		return c.esclient.Exists().Index(rollupStatusIndexName).Type(rollupStatusTypeName).Id(getRollupStatusID(kind, day)).Do(ctx)
+	})
+}
+
+// markRolledUp is a helper function that records that a day of raw metrics of the given kind has been rolled up
+func (c *Client) markRolledUp(ctx context.Context, kind string, day time.Time) error {
+	status := rollupStatus{
+		Kind:        kind,
+		Date:        day.Format(rollupHourlyDateFormat),
+		CompletedAt: time.Now().Unix(),
+	}
+	return c.tryRepeatReturnErrorOnly(func() error {
+		_, err := c.esclient.Index().
+			Index(rollupStatusIndexName).
+			Type(rollupStatusTypeName).
+			Id(getRollupStatusID(kind, day)).
+			BodyJson(status).
+			Do(ctx)
+		return err
+	})
+}
+
+// unmarkPastDaysRolledUp is a helper function that makes the given days of raw metrics of the given kind get rolled
+// up again, for metrics added after the day was over. Today is skipped, since it's only rolled up once it's over.
+func (c *Client) unmarkPastDaysRolledUp(ctx context.Context, kind string, days map[time.Time]struct{}) error {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	sortedDays := []time.Time{}
+	for day := range days {
+		if day.Before(today) {
+			sortedDays = append(sortedDays, day)
+		}
+	}
+	sort.Slice(sortedDays, func(i, j int) bool { return sortedDays[i].Before(sortedDays[j]) })
+	for _, day := range sortedDays {
+		err := c.unmarkRolledUp(ctx, kind, day)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"day":   day,
+				"error": err,
+				"kind":  kind,
+			}).Error("Error marking day to be rolled up again")
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarkRolledUp is a helper function that removes the record that a day of raw metrics of the given kind has been
+// rolled up (if there is one), so it gets rolled up again
+func (c *Client) unmarkRolledUp(ctx context.Context, kind string, day time.Time) error {
+	return c.tryRepeatReturnErrorOnly(func() error {
+		_, err := c.esclient.Delete().
+			Index(rollupStatusIndexName).
+			Type(rollupStatusTypeName).
+			Id(getRollupStatusID(kind, day)).
+			Do(ctx)
+		if elastic.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// recordAddedDays is a helper function that adds the days of the raw metrics indices a bulk request added
+// documents to, given the day of each index it wrote to, to the given days. Documents that already existed
+// don't count, so a retried request still needs the days recorded from its earlier attempts.
+func recordAddedDays(days map[time.Time]struct{}, res *elastic.BulkResponse, indexDays map[string]time.Time) {
+	for _, item := range res.Succeeded() {
+		if day, ok := indexDays[item.Index]; ok {
+			days[day] = struct{}{}
+		}
+	}
+}
+
+// filterRolledUpIndices is a helper function that leaves out raw metrics indices that haven't been rolled up
+// yet (if rollups are required before cleaning), so they aren't deleted
+func (c *Client) filterRolledUpIndices(ctx context.Context, kind string, indices []string, getRawIndexTime func(string) (time.Time, error)) []string {
+	if !c.requireRollups {
+		return indices
+	}
+
+	filtered := []string{}
+	for _, index := range indices {
+		day, err := getRawIndexTime(index)
+		if err != nil {
+			continue
+		}
+		rolledUp, err := c.isRolledUp(ctx, kind, day)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"index": index,
+			}).Error("Error checking if index has been rolled up, it will be retained")
+			continue
+		}
+		if !rolledUp {
+			log.WithField("index", index).Warn("Index is past retention date but hasn't been rolled up yet, it will be retained")
+			continue
+		}
+		filtered = append(filtered, index)
+	}
+	return filtered
+}
+
+// newRollupAggregation is a helper function that builds a page of the composite aggregation that rolls up raw
+// metrics: one bucket per group (array or volume) and time period, with statistics for every value field
+func newRollupAggregation(kind rollupKind, interval string, after map[string]interface{}) *elastic.CompositeAggregation {
+	sources := []elastic.CompositeAggregationValuesSource{}
+	for _, field := range kind.groupFields {
+		sources = append(sources, elastic.NewCompositeAggregationTermsValuesSource(field).Field(field))
+	}
+	sources = append(sources, elastic.NewCompositeAggregationDateHistogramValuesSource(bucketStartKey, interval).Field("CreatedAt"))
+
+	aggregation := elastic.NewCompositeAggregation().Size(rollupPageSize).Sources(sources...)
+	if after != nil {
+		aggregation = aggregation.AggregateAfter(after)
+	}
+	for _, field := range kind.valueFields {
+		aggregation = aggregation.SubAggregation(field+statsAggregationSuffix, elastic.NewStatsAggregation().Field(field))
+		aggregation = aggregation.SubAggregation(field+p95AggregationSuffix, elastic.NewPercentilesAggregation().Field(field).Percentiles(95))
+	}
+	// The most recent raw document in the bucket supplies names and tags
+	return aggregation.SubAggregation(latestAggregationName, elastic.NewTopHitsAggregation().Size(1).Sort("CreatedAt", false))
+}
+
+// parseRollupBuckets is a helper function that converts composite aggregation buckets into rollups
+func parseRollupBuckets(kind rollupKind, resolution string, buckets []*elastic.AggregationBucketCompositeItem) ([]*metrics.MetricRollup, error) {
+	rollups := []*metrics.MetricRollup{}
+	for _, bucket := range buckets {
+		start, ok := bucket.Key[bucketStartKey].(float64)
+		if !ok {
+			return nil, fmt.Errorf("Rollup bucket is missing its start time")
+		}
+
+		rollup := &metrics.MetricRollup{
+			Resolution:  resolution,
+			StartTime:   int64(start) / 1000, // Date histogram keys are in milliseconds
+			SampleCount: bucket.DocCount,
+			Values:      map[string]*metrics.RollupStatistics{},
+		}
+
+		latest, found := bucket.TopHits(latestAggregationName)
+		if !found || latest.Hits == nil || len(latest.Hits.Hits) == 0 || latest.Hits.Hits[0].Source == nil {
+			return nil, fmt.Errorf("Rollup bucket is missing its latest document")
+		}
+		err := kind.readMetadata(*latest.Hits.Hits[0].Source, rollup)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range kind.valueFields {
+			stats, found := bucket.Stats(field + statsAggregationSuffix)
+			// Fields that aren't in any of the raw documents (such as FlashBlade-only counts on a FlashArray) are left out
+			if !found || stats.Count == 0 || stats.Min == nil || stats.Max == nil || stats.Avg == nil {
+				continue
+			}
+			statistics := &metrics.RollupStatistics{
+				Min: *stats.Min,
+				Avg: *stats.Avg,
+				Max: *stats.Max,
+			}
+			percentiles, found := bucket.Percentiles(field + p95AggregationSuffix)
+			if found {
+				for key, value := range percentiles.Values {
+					if strings.HasPrefix(key, "95") {
+						statistics.P95 = value
+					}
+				}
+			}
+			rollup.Values[field] = statistics
+		}
+		rollups = append(rollups, rollup)
+	}
+	return rollups, nil
+}
+
+// getRollupDocumentID is a helper function that identifies a rollup by what it covers
+func getRollupDocumentID(rollup *metrics.MetricRollup) string {
+	if len(rollup.VolumeName) > 0 {
+		return fmt.Sprintf("%s-%s-%s-%s-%d", rollup.ArrayID, rollup.VolumeType, rollup.VolumeName, rollup.Resolution, rollup.StartTime)
+	}
+	return fmt.Sprintf("%s-%s-%d", rollup.ArrayID, rollup.Resolution, rollup.StartTime)
+}
+
+// getRollupStatusID is a helper function that identifies the status document for a day of raw metrics
+func getRollupStatusID(kind string, day time.Time) string {
+	return fmt.Sprintf("%s-%s", kind, day.UTC().Format(rollupHourlyDateFormat))
+}
+
+// isRollupIndexExpired is a helper function that checks if all of the period covered by a rollup index is past retention
+func isRollupIndexExpired(resolution string, start time.Time, hourlyMaxAgeInDays int, dailyMaxAgeInDays int, now time.Time) bool {
+	cutoff := now.AddDate(0, 0, -hourlyMaxAgeInDays)
+	end := start.AddDate(0, 0, 1)
+	if resolution == metrics.DailyRollup {
+		cutoff = now.AddDate(0, 0, -dailyMaxAgeInDays)
+		end = start.AddDate(0, 1, 0)
+	}
+	return !end.After(cutoff)
+}
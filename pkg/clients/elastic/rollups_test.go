@@ -0,0 +1,140 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricRollupsIndexNames(t *testing.T) {
+	day := time.Date(2019, time.March, 14, 0, 0, 0, 0, time.UTC)
+
+	hourly := getMetricRollupsIndexName(volumeRollupKind, metrics.HourlyRollup, day)
+	assert.Equal(t, "pure1-unplugged-metrics-rollup-hourly-volumes-2019-03-14", hourly)
+	resolution, start, err := getRollupFromMetricRollupsIndexName(hourly)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.HourlyRollup, resolution)
+	assert.Equal(t, day, start)
+
+	daily := getMetricRollupsIndexName(arrayRollupKind, metrics.DailyRollup, day)
+	assert.Equal(t, "pure1-unplugged-metrics-rollup-daily-arrays-2019-03", daily)
+	resolution, start, err = getRollupFromMetricRollupsIndexName(daily)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.DailyRollup, resolution)
+	assert.Equal(t, time.Date(2019, time.March, 1, 0, 0, 0, 0, time.UTC), start)
+
+	_, _, err = getRollupFromMetricRollupsIndexName("pure1-unplugged-metrics-rollup-weekly-arrays-2019-03")
+	assert.Error(t, err)
+	_, _, err = getRollupFromMetricRollupsIndexName("pure-arrays-metrics-2019-03-14")
+	assert.Error(t, err)
+}
+
+func TestIsRollupIndexExpired(t *testing.T) {
+	now := time.Date(2019, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	// Hourly indices cover a day
+	assert.True(t, isRollupIndexExpired(metrics.HourlyRollup, time.Date(2019, time.June, 4, 0, 0, 0, 0, time.UTC), 10, 365, now))
+	assert.False(t, isRollupIndexExpired(metrics.HourlyRollup, time.Date(2019, time.June, 5, 0, 0, 0, 0, time.UTC), 10, 365, now))
+
+	// Daily indices cover a month, so they're only deleted once the end of the month is past retention
+	assert.True(t, isRollupIndexExpired(metrics.DailyRollup, time.Date(2019, time.April, 1, 0, 0, 0, 0, time.UTC), 10, 40, now))
+	assert.False(t, isRollupIndexExpired(metrics.DailyRollup, time.Date(2019, time.May, 1, 0, 0, 0, 0, time.UTC), 10, 40, now))
+}
+
+func TestParseRollupBuckets(t *testing.T) {
+	response := `{
+		"buckets": [
+			{
+				"key": {"ArrayID": "array-1", "Type": "Volume", "VolumeName": "vol1", "StartTime": 1552521600000},
+				"doc_count": 120,
+				"UsedSpace_stats": {"count": 120, "min": 10, "max": 30, "avg": 20, "sum": 2400},
+				"UsedSpace_p95": {"values": {"95.0": 29}},
+				"ReadIOPS_stats": {"count": 0, "min": null, "max": null, "avg": null, "sum": 0},
+				"ReadIOPS_p95": {"values": {"95.0": null}},
+				"latest": {"hits": {"total": 120, "hits": [
+					{"_index": "pure-volumes-metrics-2019-03-14", "_id": "1", "_source": {
+						"ArrayID": "array-1", "ArrayName": "fa-1", "ArrayDisplayName": "Array One",
+						"ArrayTags": {"site": "east"}, "Type": "Volume", "VolumeName": "vol1", "CreatedAt": 1552525170
+					}}
+				]}}
+			}
+		],
+		"after_key": {"ArrayID": "array-1", "Type": "Volume", "VolumeName": "vol1", "StartTime": 1552521600000}
+	}`
+	items := &elastic.AggregationBucketCompositeItems{}
+	assert.NoError(t, json.Unmarshal([]byte(response), items))
+
+	var volumeKind rollupKind
+	for _, kind := range (&Client{}).getRollupKinds() {
+		if kind.name == volumeRollupKind {
+			volumeKind = kind
+		}
+	}
+
+	rollups, err := parseRollupBuckets(volumeKind, metrics.HourlyRollup, items.Buckets)
+	assert.NoError(t, err)
+	assert.Len(t, rollups, 1)
+
+	rollup := rollups[0]
+	assert.Equal(t, "array-1", rollup.ArrayID)
+	assert.Equal(t, "fa-1", rollup.ArrayName)
+	assert.Equal(t, "Array One", rollup.ArrayDisplayName)
+	assert.Equal(t, map[string]string{"site": "east"}, rollup.ArrayTags)
+	assert.Equal(t, "vol1", rollup.VolumeName)
+	assert.Equal(t, "Volume", rollup.VolumeType)
+	assert.Equal(t, metrics.HourlyRollup, rollup.Resolution)
+	assert.Equal(t, int64(1552521600), rollup.StartTime)
+	assert.Equal(t, int64(120), rollup.SampleCount)
+	assert.Equal(t, &metrics.RollupStatistics{Min: 10, Avg: 20, Max: 30, P95: 29}, rollup.Values["UsedSpace"])
+	// No samples had this field, so there's nothing to keep for it
+	assert.NotContains(t, rollup.Values, "ReadIOPS")
+
+	assert.Equal(t, "array-1-Volume-vol1-hourly-1552521600", getRollupDocumentID(rollup))
+
+	// A bucket with the same name as a file system is rolled up separately
+	bucket := *rollup
+	bucket.VolumeType = metrics.VolumeMetricTypeBucket
+	assert.NotEqual(t, getRollupDocumentID(rollup), getRollupDocumentID(&bucket))
+}
+
+func TestRecordAddedDays(t *testing.T) {
+	yesterday := time.Date(2019, time.March, 13, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2019, time.March, 14, 0, 0, 0, 0, time.UTC)
+	indexDays := map[string]time.Time{
+		getArrayMetricsIndexName(yesterday): yesterday,
+		getArrayMetricsIndexName(today):     today,
+	}
+	res := &elastic.BulkResponse{Items: []map[string]*elastic.BulkResponseItem{
+		{"create": {Index: getArrayMetricsIndexName(yesterday), Status: 409}}, // Already added
+		{"create": {Index: getArrayMetricsIndexName(today), Status: 201}},
+	}}
+
+	days := map[time.Time]struct{}{}
+	recordAddedDays(days, res, indexDays)
+	assert.Equal(t, map[time.Time]struct{}{today: {}}, days)
+
+	// A retry conflicting with what the first attempt added keeps the days from the first attempt
+	retried := &elastic.BulkResponse{Items: []map[string]*elastic.BulkResponseItem{
+		{"create": {Index: getArrayMetricsIndexName(today), Status: 409}},
+	}}
+	recordAddedDays(days, retried, indexDays)
+	assert.Equal(t, map[time.Time]struct{}{today: {}}, days)
+}
@@ -0,0 +1,139 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/timing"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/http/errors"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guards: ensure this implements the interfaces
+var _ metrics.SnapshotDatabase = (*Client)(nil)
+var _ resources.SnapshotInventoryDatabase = (*Client)(nil)
+
+// UpdateArraySnapshots stores the given snapshots, one document per snapshot, then deletes the snapshots of the
+// given array that weren't in this collection (since they've been deleted from the array)
+func (c *Client) UpdateArraySnapshots(arrayID string, collectedAt int64, snapshots []*metrics.Snapshot) error {
+	ctx := context.Background()
+
+	timer := timing.NewStageTimer("Client.UpdateArraySnapshots", log.Fields{"array_id": arrayID})
+	defer timer.Finish()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) > 0 {
+		timer.Stage("push_snapshots")
+
+		requests := []elastic.BulkableRequest{}
+		for _, snapshot := range snapshots {
+			requests = append(requests, elastic.NewBulkIndexRequest().Index(snapshotsIndexName).Type(snapshotsIndexTypeName).Id(snapshot.GetDocumentID()).Doc(snapshot))
+		}
+
+		err = c.tryRepeatReturnErrorOnly(func() error {
+			// Wait for the refresh, otherwise the deletion below can still see the old copies of these snapshots
+			res, err := c.esclient.Bulk().Add(requests...).Refresh("wait_for").Do(ctx)
+			if err != nil {
+				log.WithError(err).Error("Error in bulk request for snapshots (overall error, not single document)")
+				return err
+			}
+			failed := res.Failed()
+			for _, failure := range failed {
+				log.WithFields(log.Fields{
+					"type":   failure.Error.Type,
+					"id":     failure.Id,
+					"reason": failure.Error.Reason,
+				}).Error("Snapshot failed to index in bulk request")
+			}
+			if len(failed) > 0 {
+				return fmt.Errorf("Some snapshots failed in bulk request")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	timer.Stage("delete_removed")
+
+	removedQuery := elastic.NewBoolQuery().Filter(
+		elastic.NewTermQuery("ArrayID", arrayID),
+		elastic.NewRangeQuery("CollectedAt").Format("epoch_second").Lt(collectedAt),
+	)
+	return c.tryRepeatReturnErrorOnly(func() error {
+		_, err := c.esclient.DeleteByQuery(snapshotsIndexName).Type(snapshotsIndexTypeName).Query(removedQuery).ProceedOnVersionConflict().Do(ctx)
+		if elastic.IsNotFound(err) {
+			// Nothing has been collected yet
+			return nil
+		}
+		return err
+	})
+}
+
+// FindSnapshots gets the snapshots that match the given query, oldest first
+func (c *Client) FindSnapshots(query *resources.SnapshotQuery) ([]*metrics.Snapshot, error) {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	searchService := c.esclient.Search(snapshotsIndexName).Query(query.GenerateElasticQueryObject()).From(query.Offset).Sort("Created", true).IgnoreUnavailable(true).AllowNoIndices(true)
+	if query.Limit > 0 {
+		searchService.Size(query.Limit)
+	} else {
+		// Default to 1000 results if not specified, the same as for arrays
+		searchService.Size(1000)
+	}
+
+	res, err := searchService.Do(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	snapshots := []*metrics.Snapshot{}
+	if res.Hits == nil {
+		return snapshots, nil
+	}
+	for _, hit := range res.Hits.Hits {
+		if hit.Source == nil {
+			continue
+		}
+		snapshot := &metrics.Snapshot{}
+		err = json.Unmarshal(*hit.Source, snapshot)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"id":    hit.Id,
+			}).Warn("Error parsing snapshot, skipping")
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
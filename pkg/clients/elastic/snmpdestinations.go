@@ -0,0 +1,114 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/http/errors"
+
+	"github.com/olivere/elastic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ resources.SNMPDestinationDatabase = (*Client)(nil)
+
+// More trap destinations than anyone should practically configure
+const maxSNMPDestinations = 1000
+
+// FindSNMPDestinations gets the SNMP destinations with the given IDs, or every destination if no IDs are given.
+// The returned destinations don't include their credentials, since those aren't stored in Elastic.
+func (c *Client) FindSNMPDestinations(ids []string) ([]*resources.SNMPDestination, error) {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	var query elastic.Query = elastic.NewMatchAllQuery()
+	if len(ids) > 0 {
+		query = elastic.NewIdsQuery(snmpDestinationsIndexTypeName).Ids(ids...)
+	}
+
+	res, err := c.esclient.Search(snmpDestinationsIndexName).Type(snmpDestinationsIndexTypeName).Query(query).Size(maxSNMPDestinations).Sort("Name", true).IgnoreUnavailable(true).AllowNoIndices(true).Do(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	results := []*resources.SNMPDestination{}
+	for _, res := range res.Each(reflect.TypeOf(&resources.SNMPDestination{})) {
+		results = append(results, res.(*resources.SNMPDestination))
+	}
+	return results, nil
+}
+
+// InsertSNMPDestination inserts the given SNMP destination into Elastic
+func (c *Client) InsertSNMPDestination(destination *resources.SNMPDestination) error {
+	return c.indexSNMPDestination(destination)
+}
+
+// UpdateSNMPDestination replaces the stored SNMP destination with the same ID as the given destination
+func (c *Client) UpdateSNMPDestination(destination *resources.SNMPDestination) error {
+	return c.indexSNMPDestination(destination)
+}
+
+// DeleteSNMPDestinations deletes the SNMP destinations with the given IDs from Elastic
+func (c *Client) DeleteSNMPDestinations(ids []string) ([]string, error) {
+	ctx := context.Background()
+
+	// Get all the destinations that exist first, so we only report the ones actually deleted
+	destinations, err := c.FindSNMPDestinations(ids)
+	if err != nil {
+		return nil, err
+	}
+	deletedIDs := []string{}
+	for _, destination := range destinations {
+		deletedIDs = append(deletedIDs, destination.InternalID)
+	}
+	if len(deletedIDs) == 0 {
+		return deletedIDs, nil
+	}
+
+	_, err = c.esclient.DeleteByQuery(snmpDestinationsIndexName).Type(snmpDestinationsIndexTypeName).Query(elastic.NewIdsQuery(snmpDestinationsIndexTypeName).Ids(deletedIDs...)).Do(ctx)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+	return deletedIDs, nil
+}
+
+// indexSNMPDestination is a helper function that stores the given SNMP destination under its ID
+func (c *Client) indexSNMPDestination(destination *resources.SNMPDestination) error {
+	ctx := context.Background()
+
+	err := c.EnsureConnected(ctx)
+	if err != nil {
+		return errors.MakeInternalHTTPErr(err)
+	}
+
+	copied := *destination                             // Create a copy so we don't modify the original
+	copied.SetCredentials(resources.SNMPCredentials{}) // Clear the credentials so we don't store them in Elastic
+
+	log.WithField("destination_id", copied.InternalID).Trace("Beginning to push SNMP destination to Elastic")
+	_, err = c.esclient.Index().Index(snmpDestinationsIndexName).Type(snmpDestinationsIndexTypeName).Id(copied.InternalID).BodyJson(&copied).Do(ctx)
+	if err != nil {
+		return errors.MakeInternalHTTPErr(err)
+	}
+	log.WithField("destination_id", copied.InternalID).Trace("SNMP destination pushed to Elastic successfully")
+	return nil
+}
@@ -15,8 +15,11 @@
 package elastic
 
 import (
+	"context"
 	"time"
 
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+
 	"github.com/olivere/elastic"
 	log "github.com/sirupsen/logrus"
 )
@@ -39,4 +42,27 @@ type Client struct {
 
 	errorLog *log.Logger
 	infoLog  *log.Logger
+
+	// If set, raw metrics indices are only deleted once they have been rolled up
+	requireRollups bool
+}
+
+// rollupKind describes how to roll up one kind of raw metrics (arrays or volumes)
+type rollupKind struct {
+	name string
+	// Fields identifying what a rollup is for, in addition to the time bucket
+	groupFields []string
+	// Numeric fields to keep statistics for
+	valueFields     []string
+	getRawIndices   func(ctx context.Context) ([]string, error)
+	getRawIndexTime func(indexName string) (time.Time, error)
+	// Copies the identifying metadata of a raw metric document into a rollup
+	readMetadata func(source []byte, rollup *metrics.MetricRollup) error
+}
+
+// rollupStatus marks a day of raw metrics as rolled up
+type rollupStatus struct {
+	Kind        string `json:"Kind"`
+	Date        string `json:"Date"`
+	CompletedAt int64  `json:"CompletedAt"` // Unix seconds since epoch
 }
@@ -0,0 +1,220 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fanout
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.Database = (*Database)(nil)
+
+// NewDatabase creates a fan-out database over the given primary and secondary sinks. Each secondary
+// gets a queue of up to queueLength pending writes and its own worker to drain it.
+func NewDatabase(primary Sink, secondaries []Sink, queueLength int) *Database {
+	database := &Database{
+		primary: newSink(primary, nil),
+	}
+	database.primary.status.Primary = true
+
+	for _, secondary := range secondaries {
+		s := newSink(secondary, make(chan write, queueLength))
+		database.secondaries = append(database.secondaries, s)
+		go s.drain()
+	}
+	return database
+}
+
+// Close stops the secondary workers once their queues are empty. The database can't be used afterwards.
+func (d *Database) Close() {
+	for _, secondary := range d.secondaries {
+		close(secondary.writes)
+	}
+}
+
+// Statuses gets a snapshot of the write status of every sink, primary first
+func (d *Database) Statuses() []SinkStatus {
+	statuses := []SinkStatus{d.primary.getStatus()}
+	for _, secondary := range d.secondaries {
+		statuses = append(statuses, secondary.getStatus())
+	}
+	return statuses
+}
+
+// AddArrayMetrics adds the given metrics to all sinks
+func (d *Database) AddArrayMetrics(arrayMetrics []*metrics.ArrayMetric) error {
+	return d.fanOut("AddArrayMetrics", func(database metrics.Database) error {
+		return database.AddArrayMetrics(arrayMetrics)
+	})
+}
+
+// AddVolumeMetrics adds the given volume metrics to all sinks
+func (d *Database) AddVolumeMetrics(volumeMetrics []*metrics.VolumeMetric) error {
+	return d.fanOut("AddVolumeMetrics", func(database metrics.Database) error {
+		return database.AddVolumeMetrics(volumeMetrics)
+	})
+}
+
+// UpdateAlerts updates the given alerts in all sinks
+func (d *Database) UpdateAlerts(alerts []*metrics.Alert) error {
+	return d.fanOut("UpdateAlerts", func(database metrics.Database) error {
+		return database.UpdateAlerts(alerts)
+	})
+}
+
+// CleanArrayMetrics cleans old array metrics from all sinks
+func (d *Database) CleanArrayMetrics(maxAgeInDays int) error {
+	return d.fanOut("CleanArrayMetrics", func(database metrics.Database) error {
+		return database.CleanArrayMetrics(maxAgeInDays)
+	})
+}
+
+// CleanVolumeMetrics cleans old volume metrics from all sinks
+func (d *Database) CleanVolumeMetrics(maxAgeInDays int) error {
+	return d.fanOut("CleanVolumeMetrics", func(database metrics.Database) error {
+		return database.CleanVolumeMetrics(maxAgeInDays)
+	})
+}
+
+// CleanAlerts cleans old alerts from all sinks
+func (d *Database) CleanAlerts(maxAgeInDays int) error {
+	return d.fanOut("CleanAlerts", func(database metrics.Database) error {
+		return database.CleanAlerts(maxAgeInDays)
+	})
+}
+
+// CleanErrorLogs cleans old error logs from all sinks
+func (d *Database) CleanErrorLogs(maxAgeInDays int) error {
+	return d.fanOut("CleanErrorLogs", func(database metrics.Database) error {
+		return database.CleanErrorLogs(maxAgeInDays)
+	})
+}
+
+// CleanTimerLogs cleans old timer logs from all sinks
+func (d *Database) CleanTimerLogs(maxAgeInDays int) error {
+	return d.fanOut("CleanTimerLogs", func(database metrics.Database) error {
+		return database.CleanTimerLogs(maxAgeInDays)
+	})
+}
+
+// fanOut is a helper function that queues the write for every secondary, then performs it
+// on the primary and returns the result
+func (d *Database) fanOut(description string, execute func(database metrics.Database) error) error {
+	w := write{description: description, execute: execute}
+	for _, secondary := range d.secondaries {
+		secondary.enqueue(w)
+	}
+	return d.primary.perform(w)
+}
+
+func newSink(config Sink, writes chan write) *sink {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &sink{
+		Sink:   config,
+		writes: writes,
+		status: SinkStatus{Name: config.Name},
+	}
+}
+
+// enqueue is a helper function that queues a write without blocking, dropping it if the queue is full
+func (s *sink) enqueue(w write) {
+	select {
+	case s.writes <- w:
+	default:
+		s.statusLock.Lock()
+		s.status.Dropped++
+		s.statusLock.Unlock()
+		log.WithFields(log.Fields{
+			"sink":      s.Name,
+			"operation": w.description,
+		}).Warn("Sink queue is full, dropping write")
+	}
+}
+
+// drain is a helper function that performs queued writes until the queue is closed
+func (s *sink) drain() {
+	for w := range s.writes {
+		s.perform(w)
+	}
+}
+
+// perform is a helper function that tries the write up to MaxAttempts times, recording the outcome
+func (s *sink) perform(w write) error {
+	var err error
+	for attempt := 1; attempt <= s.MaxAttempts; attempt++ {
+		err = s.execute(w)
+		if err == nil {
+			s.recordSuccess()
+			return nil
+		}
+		log.WithFields(log.Fields{
+			"attempt":   attempt,
+			"error":     err,
+			"operation": w.description,
+			"sink":      s.Name,
+		}).Trace("Sink write failed")
+		if attempt < s.MaxAttempts {
+			time.Sleep(s.RetryTime)
+		}
+	}
+
+	s.recordFailure(err)
+	log.WithFields(log.Fields{
+		"attempts":  s.MaxAttempts,
+		"error":     err,
+		"operation": w.description,
+		"sink":      s.Name,
+	}).Warn("Sink write failed, giving up")
+	return err
+}
+
+// execute is a helper function that runs a single write attempt, converting a panic in a sink into an error
+func (s *sink) execute(w write) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Sink %s panicked: %v", s.Name, r)
+		}
+	}()
+	return w.execute(s.Database)
+}
+
+func (s *sink) recordSuccess() {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+	s.status.Succeeded++
+	s.status.LastSuccess = time.Now().UTC()
+}
+
+func (s *sink) recordFailure(err error) {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+	s.status.Failed++
+	s.status.LastError = err.Error()
+	s.status.LastFailure = time.Now().UTC()
+}
+
+func (s *sink) getStatus() SinkStatus {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+	status := s.status
+	status.QueueLength = len(s.writes)
+	return status
+}
@@ -0,0 +1,146 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fanout
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	clientmock "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// waitForStatus polls the statuses until the check passes, failing the test after a second
+func waitForStatus(t *testing.T, database *Database, check func(statuses []SinkStatus) bool) []SinkStatus {
+	for i := 0; i < 100; i++ {
+		statuses := database.Statuses()
+		if check(statuses) {
+			return statuses
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, "Timed out waiting for sink status")
+	return database.Statuses()
+}
+
+func TestFanOutWritesToAllSinks(t *testing.T) {
+	primary := &clientmock.MetricsDatabaseImpl{}
+	primary.On("AddArrayMetrics", mock.Anything).Return(nil)
+	secondary := &clientmock.MetricsDatabaseImpl{}
+	secondary.On("AddArrayMetrics", mock.Anything).Return(nil)
+
+	database := NewDatabase(Sink{Name: "elastic", Database: primary}, []Sink{{Name: "file", Database: secondary}}, 10)
+	defer database.Close()
+
+	err := database.AddArrayMetrics([]*metrics.ArrayMetric{{ArrayID: "a"}})
+	assert.NoError(t, err)
+
+	statuses := waitForStatus(t, database, func(statuses []SinkStatus) bool { return statuses[1].Succeeded == 1 })
+	assert.Equal(t, "elastic", statuses[0].Name)
+	assert.True(t, statuses[0].Primary)
+	assert.Equal(t, uint64(1), statuses[0].Succeeded)
+	assert.Equal(t, "file", statuses[1].Name)
+	assert.False(t, statuses[1].Primary)
+	primary.AssertExpectations(t)
+	secondary.AssertExpectations(t)
+}
+
+func TestFanOutReturnsPrimaryError(t *testing.T) {
+	primary := &clientmock.MetricsDatabaseImpl{}
+	primary.On("UpdateAlerts", mock.Anything).Return(fmt.Errorf("elastic is down"))
+	secondary := &clientmock.MetricsDatabaseImpl{}
+	secondary.On("UpdateAlerts", mock.Anything).Return(nil)
+
+	database := NewDatabase(Sink{Name: "elastic", Database: primary, MaxAttempts: 2}, []Sink{{Name: "file", Database: secondary}}, 10)
+	defer database.Close()
+
+	err := database.UpdateAlerts([]*metrics.Alert{})
+	assert.Error(t, err)
+
+	statuses := waitForStatus(t, database, func(statuses []SinkStatus) bool { return statuses[1].Succeeded == 1 })
+	assert.Equal(t, uint64(1), statuses[0].Failed)
+	assert.Equal(t, "elastic is down", statuses[0].LastError)
+	primary.AssertNumberOfCalls(t, "UpdateAlerts", 2)
+}
+
+func TestFanOutIgnoresSecondaryError(t *testing.T) {
+	primary := &clientmock.MetricsDatabaseImpl{}
+	primary.On("AddVolumeMetrics", mock.Anything).Return(nil)
+	secondary := &clientmock.MetricsDatabaseImpl{}
+	secondary.On("AddVolumeMetrics", mock.Anything).Return(fmt.Errorf("disk full"))
+
+	database := NewDatabase(Sink{Name: "elastic", Database: primary}, []Sink{{Name: "file", Database: secondary, MaxAttempts: 3}}, 10)
+	defer database.Close()
+
+	err := database.AddVolumeMetrics([]*metrics.VolumeMetric{})
+	assert.NoError(t, err)
+
+	statuses := waitForStatus(t, database, func(statuses []SinkStatus) bool { return statuses[1].Failed == 1 })
+	assert.Equal(t, "disk full", statuses[1].LastError)
+	secondary.AssertNumberOfCalls(t, "AddVolumeMetrics", 3)
+}
+
+func TestFanOutRetriesSecondary(t *testing.T) {
+	primary := &clientmock.MetricsDatabaseImpl{}
+	primary.On("CleanAlerts", 7).Return(nil)
+	secondary := &clientmock.MetricsDatabaseImpl{}
+	secondary.On("CleanAlerts", 7).Return(fmt.Errorf("timeout")).Once()
+	secondary.On("CleanAlerts", 7).Return(nil)
+
+	database := NewDatabase(Sink{Name: "elastic", Database: primary}, []Sink{{Name: "influxdb", Database: secondary, MaxAttempts: 2}}, 10)
+	defer database.Close()
+
+	assert.NoError(t, database.CleanAlerts(7))
+
+	statuses := waitForStatus(t, database, func(statuses []SinkStatus) bool { return statuses[1].Succeeded == 1 })
+	assert.Equal(t, uint64(0), statuses[1].Failed)
+}
+
+func TestFanOutSlowSecondaryDoesNotBlockPrimary(t *testing.T) {
+	release := make(chan time.Time)
+	defer close(release)
+
+	primary := &clientmock.MetricsDatabaseImpl{}
+	primary.On("AddArrayMetrics", mock.Anything).Return(nil)
+	secondary := &clientmock.MetricsDatabaseImpl{}
+	secondary.On("AddArrayMetrics", mock.Anything).Return(nil).WaitUntil(release)
+
+	database := NewDatabase(Sink{Name: "elastic", Database: primary}, []Sink{{Name: "influxdb", Database: secondary}}, 1)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, database.AddArrayMetrics([]*metrics.ArrayMetric{}))
+	}
+	assert.True(t, time.Since(start) < time.Second)
+	primary.AssertNumberOfCalls(t, "AddArrayMetrics", 5)
+
+	// One write is in progress and one is queued, so the rest must have been dropped
+	statuses := waitForStatus(t, database, func(statuses []SinkStatus) bool { return statuses[1].Dropped >= 3 })
+	assert.Equal(t, uint64(5), statuses[0].Succeeded)
+}
+
+func TestFanOutRecoversSinkPanic(t *testing.T) {
+	primary := &clientmock.MetricsDatabaseImpl{}
+	primary.On("CleanTimerLogs", 1).Run(func(args mock.Arguments) { panic("boom") })
+
+	database := NewDatabase(Sink{Name: "elastic", Database: primary}, nil, 10)
+
+	err := database.CleanTimerLogs(1)
+	assert.Error(t, err)
+	assert.Equal(t, uint64(1), database.Statuses()[0].Failed)
+}
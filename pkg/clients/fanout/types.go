@@ -0,0 +1,66 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fanout
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+// Database is a metrics.Database that writes to a primary database and any number of
+// secondary ones. Writes to the primary happen inline and its errors are returned to the caller;
+// writes to secondaries are queued and retried in the background, so a slow or broken secondary
+// never blocks or fails writes to the primary.
+type Database struct {
+	primary     *sink
+	secondaries []*sink
+}
+
+// Sink is a named database to fan out to
+type Sink struct {
+	Name     string
+	Database metrics.Database
+	// Number of times to try each write before giving up (less than one is treated as one)
+	MaxAttempts int
+	RetryTime   time.Duration
+}
+
+// SinkStatus records the outcome of writes to a single sink
+type SinkStatus struct {
+	Name        string
+	Primary     bool
+	Succeeded   uint64
+	Failed      uint64
+	Dropped     uint64 // Writes discarded without being tried because the sink's queue was full
+	QueueLength int
+	LastError   string
+	LastSuccess time.Time
+	LastFailure time.Time
+}
+
+type write struct {
+	description string
+	execute     func(database metrics.Database) error
+}
+
+type sink struct {
+	Sink
+	writes chan write // Nil for the primary, which is written to inline
+
+	statusLock sync.Mutex
+	status     SinkStatus
+}
@@ -0,0 +1,24 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import "sync"
+
+// Writer is a metrics.Database that appends metrics and alerts as JSON lines to daily
+// files in a directory (array-metrics-2019-01-02.json, alerts-2019-01-02.json, etc.)
+type Writer struct {
+	directory string
+	lock      sync.Mutex
+}
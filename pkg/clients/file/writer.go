@@ -0,0 +1,166 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.Database = (*Writer)(nil)
+
+const (
+	arrayMetricsPrefix  = "array-metrics-"
+	volumeMetricsPrefix = "volume-metrics-"
+	alertsPrefix        = "alerts-"
+
+	fileDateFormat = "2006-01-02"
+	fileExtension  = ".json"
+)
+
+// NewWriter creates a Writer for the given directory, creating the directory if needed
+func NewWriter(directory string) (*Writer, error) {
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{directory: directory}, nil
+}
+
+// AddArrayMetrics appends the given metrics to today's array metrics file
+func (w *Writer) AddArrayMetrics(arrayMetrics []*metrics.ArrayMetric) error {
+	lines := make([]interface{}, 0, len(arrayMetrics))
+	for _, metric := range arrayMetrics {
+		lines = append(lines, metric)
+	}
+	return w.appendLines(arrayMetricsPrefix, lines)
+}
+
+// AddVolumeMetrics appends the given metrics to today's volume metrics file
+func (w *Writer) AddVolumeMetrics(volumeMetrics []*metrics.VolumeMetric) error {
+	lines := make([]interface{}, 0, len(volumeMetrics))
+	for _, metric := range volumeMetrics {
+		lines = append(lines, metric)
+	}
+	return w.appendLines(volumeMetricsPrefix, lines)
+}
+
+// UpdateAlerts appends the given alerts to today's alerts file. Since the file is append-only,
+// an alert appears once for every time it was updated: the last line for an alert is current.
+func (w *Writer) UpdateAlerts(alerts []*metrics.Alert) error {
+	lines := make([]interface{}, 0, len(alerts))
+	for _, alert := range alerts {
+		lines = append(lines, alert)
+	}
+	return w.appendLines(alertsPrefix, lines)
+}
+
+// CleanArrayMetrics deletes array metrics files older than the given number of days
+func (w *Writer) CleanArrayMetrics(maxAgeInDays int) error {
+	return w.deleteOldFiles(arrayMetricsPrefix, maxAgeInDays)
+}
+
+// CleanVolumeMetrics deletes volume metrics files older than the given number of days
+func (w *Writer) CleanVolumeMetrics(maxAgeInDays int) error {
+	return w.deleteOldFiles(volumeMetricsPrefix, maxAgeInDays)
+}
+
+// CleanAlerts deletes alerts files older than the given number of days
+func (w *Writer) CleanAlerts(maxAgeInDays int) error {
+	return w.deleteOldFiles(alertsPrefix, maxAgeInDays)
+}
+
+// CleanErrorLogs does nothing: logs aren't written to files
+func (w *Writer) CleanErrorLogs(maxAgeInDays int) error {
+	return nil
+}
+
+// CleanTimerLogs does nothing: logs aren't written to files
+func (w *Writer) CleanTimerLogs(maxAgeInDays int) error {
+	return nil
+}
+
+// appendLines is a helper function that writes each value as a line of JSON to today's file with the given prefix
+func (w *Writer) appendLines(prefix string, lines []interface{}) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	var builder strings.Builder
+	for _, line := range lines {
+		marshalled, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		builder.Write(marshalled)
+		builder.WriteString("\n")
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	path := filepath.Join(w.directory, fmt.Sprintf("%s%s%s", prefix, time.Now().UTC().Format(fileDateFormat), fileExtension))
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(builder.String())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// deleteOldFiles is a helper function that deletes files with the given prefix dated before the cutoff
+func (w *Writer) deleteOldFiles(prefix string, maxAgeInDays int) error {
+	cutoff := time.Now().UTC().AddDate(0, 0, -maxAgeInDays).Format(fileDateFormat)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	files, err := ioutil.ReadDir(w.directory)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, fileExtension) {
+			continue
+		}
+		date := strings.TrimSuffix(strings.TrimPrefix(name, prefix), fileExtension)
+		if _, err := time.Parse(fileDateFormat, date); err != nil {
+			continue
+		}
+		// The date format sorts lexically, so it can be compared as a string
+		if date >= cutoff {
+			continue
+		}
+		log.WithField("file", name).Debug("Deleting old metrics file")
+		err = os.Remove(filepath.Join(w.directory, name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
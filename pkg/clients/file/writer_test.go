@@ -0,0 +1,73 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterAppendsLines(t *testing.T) {
+	directory, err := ioutil.TempDir("", "file-writer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	writer, err := NewWriter(directory)
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.UpdateAlerts([]*metrics.Alert{{AlertID: 1}, {AlertID: 2}}))
+	assert.NoError(t, writer.UpdateAlerts([]*metrics.Alert{{AlertID: 3}}))
+
+	contents, err := ioutil.ReadFile(filepath.Join(directory, "alerts-"+time.Now().UTC().Format(fileDateFormat)+".json"))
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	assert.Len(t, lines, 3)
+
+	alert := metrics.Alert{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[2]), &alert))
+	assert.Equal(t, uint64(3), alert.AlertID)
+}
+
+func TestWriterCleansOldFiles(t *testing.T) {
+	directory, err := ioutil.TempDir("", "file-writer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	writer, err := NewWriter(directory)
+	assert.NoError(t, err)
+
+	oldFile := filepath.Join(directory, "array-metrics-2000-01-01.json")
+	otherFile := filepath.Join(directory, "volume-metrics-2000-01-01.json")
+	assert.NoError(t, ioutil.WriteFile(oldFile, []byte("{}\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(otherFile, []byte("{}\n"), 0644))
+	assert.NoError(t, writer.AddArrayMetrics([]*metrics.ArrayMetric{{ArrayID: "a"}}))
+
+	assert.NoError(t, writer.CleanArrayMetrics(1))
+
+	_, err = os.Stat(oldFile)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(otherFile)
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(directory, "array-metrics-"+time.Now().UTC().Format(fileDateFormat)+".json"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,290 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/go-resty/resty"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.Database = (*Client)(nil)
+
+const (
+	arrayMeasurement  = "pure_array"
+	volumeMeasurement = "pure_volume"
+	alertMeasurement  = "pure_alert"
+)
+
+// NewClient creates a client that writes to the given database of the InfluxDB server at the given URL
+func NewClient(url string, database string, timeout time.Duration) *Client {
+	return &Client{
+		url:        strings.TrimSuffix(url, "/"),
+		database:   database,
+		restClient: resty.New().SetTimeout(timeout),
+	}
+}
+
+// AddArrayMetrics writes the given metrics as pure_array points
+func (c *Client) AddArrayMetrics(arrayMetrics []*metrics.ArrayMetric) error {
+	lines := []string{}
+	for _, metric := range arrayMetrics {
+		tags := map[string]string{
+			"array_id":     metric.ArrayID,
+			"array_name":   metric.ArrayName,
+			"array_type":   metric.ArrayType,
+			"display_name": metric.DisplayName,
+		}
+		addArrayTags(tags, metric.Tags)
+
+		fields := []field{}
+		if capacity := metric.ArrayCapacityMetric; capacity != nil {
+			fields = append(fields,
+				field{"data_reduction", capacity.DataReduction},
+				field{"percent_full", capacity.PercentFull},
+				field{"shared_space", capacity.SharedSpace},
+				field{"snapshot_space", capacity.SnapshotSpace},
+				field{"system_space", capacity.SystemSpace},
+				field{"total_reduction", capacity.TotalReduction},
+				field{"total_space", capacity.TotalSpace},
+				field{"used_space", capacity.UsedSpace},
+				field{"volume_space", capacity.VolumeSpace},
+			)
+		}
+		if performance := metric.ArrayPerformanceMetric; performance != nil {
+			fields = append(fields,
+				field{"bytes_per_op", performance.BytesPerOp},
+				field{"bytes_per_read", performance.BytesPerRead},
+				field{"bytes_per_write", performance.BytesPerWrite},
+				field{"other_iops", performance.OtherIOPS},
+				field{"other_latency", performance.OtherLatency},
+				field{"queue_depth", uint64(performance.QueueDepth)},
+				field{"read_bandwidth", performance.ReadBandwidth},
+				field{"read_iops", performance.ReadIOPS},
+				field{"read_latency", performance.ReadLatency},
+				field{"write_bandwidth", performance.WriteBandwidth},
+				field{"write_iops", performance.WriteIOPS},
+				field{"write_latency", performance.WriteLatency},
+			)
+		}
+		if objects := metric.ArrayObjectsMetric; objects != nil {
+			fields = append(fields,
+				field{"alert_message_count", uint64(objects.AlertMessageCount)},
+				field{"file_system_count", uint64(objects.FileSystemCount)},
+				field{"host_count", uint64(objects.HostCount)},
+				field{"snapshot_count", uint64(objects.SnapshotCount)},
+				field{"volume_count", uint64(objects.VolumeCount)},
+				field{"volume_pending_eradication_count", uint64(objects.VolumePendingEradicationCount)},
+			)
+		}
+
+		if line, ok := formatLine(arrayMeasurement, tags, fields, metric.CreatedAt); ok {
+			lines = append(lines, line)
+		}
+	}
+	return c.write(lines)
+}
+
+// AddVolumeMetrics writes the given metrics as pure_volume points
+func (c *Client) AddVolumeMetrics(volumeMetrics []*metrics.VolumeMetric) error {
+	lines := []string{}
+	for _, metric := range volumeMetrics {
+		tags := map[string]string{
+			"array_id":     metric.ArrayID,
+			"array_name":   metric.ArrayName,
+			"display_name": metric.ArrayDisplayName,
+			"volume_name":  metric.VolumeName,
+			"volume_type":  metric.Type,
+		}
+		addArrayTags(tags, metric.ArrayTags)
+
+		fields := []field{}
+		if capacity := metric.VolumeCapacityMetric; capacity != nil {
+			fields = append(fields,
+				field{"data_reduction", capacity.DataReduction},
+				field{"object_count", capacity.ObjectCount},
+				field{"provisioned_space", capacity.ProvisionedSpace},
+				field{"snapshot_count", uint64(capacity.SnapshotCount)},
+				field{"total_reduction", capacity.TotalReduction},
+				field{"used_space", capacity.UsedSpace},
+			)
+		}
+		if performance := metric.VolumePerformanceMetric; performance != nil {
+			fields = append(fields,
+				field{"other_iops", performance.OtherIOPS},
+				field{"other_latency", performance.OtherLatency},
+				field{"read_bandwidth", performance.ReadBandwidth},
+				field{"read_iops", performance.ReadIOPS},
+				field{"read_latency", performance.ReadLatency},
+				field{"write_bandwidth", performance.WriteBandwidth},
+				field{"write_iops", performance.WriteIOPS},
+				field{"write_latency", performance.WriteLatency},
+			)
+		}
+
+		if line, ok := formatLine(volumeMeasurement, tags, fields, metric.CreatedAt); ok {
+			lines = append(lines, line)
+		}
+	}
+	return c.write(lines)
+}
+
+// UpdateAlerts writes the given alerts as pure_alert points. Points are keyed by alert ID and
+// created time, so updating an alert overwrites its previous point.
+func (c *Client) UpdateAlerts(alerts []*metrics.Alert) error {
+	lines := []string{}
+	for _, alert := range alerts {
+		tags := map[string]string{
+			"alert_id":     strconv.FormatUint(alert.AlertID, 10),
+			"array_id":     alert.ArrayID,
+			"array_name":   alert.ArrayName,
+			"display_name": alert.ArrayDisplayName,
+			"severity":     strings.ToLower(alert.Severity),
+		}
+		fields := []field{
+			{"code", uint64(alert.Code)},
+			{"component", alert.Component},
+			{"flagged", alert.Flagged},
+			{"severity_index", uint64(alert.SeverityIndex)},
+			{"state", alert.State},
+			{"summary", alert.Summary},
+			{"updated", alert.Updated},
+		}
+		if line, ok := formatLine(alertMeasurement, tags, fields, alert.Created); ok {
+			lines = append(lines, line)
+		}
+	}
+	return c.write(lines)
+}
+
+// CleanArrayMetrics does nothing: InfluxDB retention policies handle this
+func (c *Client) CleanArrayMetrics(maxAgeInDays int) error {
+	return nil
+}
+
+// CleanVolumeMetrics does nothing: InfluxDB retention policies handle this
+func (c *Client) CleanVolumeMetrics(maxAgeInDays int) error {
+	return nil
+}
+
+// CleanAlerts does nothing: InfluxDB retention policies handle this
+func (c *Client) CleanAlerts(maxAgeInDays int) error {
+	return nil
+}
+
+// CleanErrorLogs does nothing: logs aren't written to InfluxDB
+func (c *Client) CleanErrorLogs(maxAgeInDays int) error {
+	return nil
+}
+
+// CleanTimerLogs does nothing: logs aren't written to InfluxDB
+func (c *Client) CleanTimerLogs(maxAgeInDays int) error {
+	return nil
+}
+
+// write is a helper function that posts the given lines to the write endpoint
+func (c *Client) write(lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	url := fmt.Sprintf("%s/write", c.url)
+	log.WithFields(log.Fields{
+		"line_count": len(lines),
+		"url":        url,
+	}).Trace("Writing points to InfluxDB")
+	response, err := c.restClient.R().
+		SetQueryParams(map[string]string{"db": c.database, "precision": "s"}).
+		SetBody(strings.Join(lines, "\n")).
+		Post(url)
+	if err != nil {
+		return err
+	}
+	if response.IsError() {
+		return fmt.Errorf("Error writing to InfluxDB: %s %s", response.Status(), strings.TrimSpace(response.String()))
+	}
+	return nil
+}
+
+// addArrayTags is a helper function that adds the array tags to the point tags, prefixed with "tag_"
+func addArrayTags(tags map[string]string, arrayTags map[string]string) {
+	for key, value := range arrayTags {
+		tags["tag_"+key] = value
+	}
+}
+
+// formatLine is a helper function that formats a single point in the line protocol. Returns false if
+// the point has no fields, since InfluxDB rejects those.
+func formatLine(measurement string, tags map[string]string, fields []field, timestamp int64) (string, bool) {
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	var builder strings.Builder
+	builder.WriteString(measurementEscaper.Replace(measurement))
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // InfluxDB recommends sorted tags for performance
+	for _, key := range keys {
+		// Empty tag values aren't allowed
+		if len(tags[key]) == 0 {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf(",%s=%s", keyEscaper.Replace(key), keyEscaper.Replace(tags[key])))
+	}
+
+	formattedFields := make([]string, 0, len(fields))
+	for _, f := range fields {
+		formattedFields = append(formattedFields, fmt.Sprintf("%s=%s", keyEscaper.Replace(f.key), formatFieldValue(f.value)))
+	}
+	builder.WriteString(" ")
+	builder.WriteString(strings.Join(formattedFields, ","))
+	builder.WriteString(fmt.Sprintf(" %d", timestamp))
+	return builder.String(), true
+}
+
+// formatFieldValue is a helper function that formats a field value: integers get an "i" suffix and strings are quoted
+func formatFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return fmt.Sprintf("%di", v)
+	case uint64:
+		return fmt.Sprintf("%di", v)
+	case string:
+		return fmt.Sprintf("\"%s\"", stringFieldEscaper.Replace(v))
+	default:
+		return fmt.Sprintf("\"%s\"", stringFieldEscaper.Replace(fmt.Sprint(v)))
+	}
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	keyEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+	stringFieldEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
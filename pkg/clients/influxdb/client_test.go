@@ -0,0 +1,85 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLine(t *testing.T) {
+	line, ok := formatLine("pure_array", map[string]string{"b": "x y", "a": "1,2", "empty": ""}, []field{
+		{"used_space", uint64(10)},
+		{"ratio", 1.5},
+		{"summary", `say "hi"`},
+		{"flagged", true},
+	}, 1000)
+	assert.True(t, ok)
+	assert.Equal(t, `pure_array,a=1\,2,b=x\ y used_space=10i,ratio=1.5,summary="say \"hi\"",flagged=true 1000`, line)
+}
+
+func TestFormatLineNoFields(t *testing.T) {
+	_, ok := formatLine("pure_array", map[string]string{"a": "b"}, []field{}, 1000)
+	assert.False(t, ok)
+}
+
+func TestAddArrayMetrics(t *testing.T) {
+	var body string
+	var query map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read, _ := ioutil.ReadAll(r.Body)
+		body = string(read)
+		query = r.URL.Query()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "pure", time.Second)
+	err := client.AddArrayMetrics([]*metrics.ArrayMetric{
+		{
+			ArrayCapacityMetric: &metrics.ArrayCapacityMetric{UsedSpace: 42},
+			ArrayID:             "a",
+			ArrayName:           "array-a",
+			ArrayType:           "FlashArray",
+			CreatedAt:           1500,
+			Tags:                map[string]string{"site": "dc1"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pure"}, query["db"])
+	assert.Equal(t, []string{"s"}, query["precision"])
+	assert.Contains(t, body, "pure_array,array_id=a,array_name=array-a,array_type=FlashArray,tag_site=dc1 ")
+	assert.Contains(t, body, "used_space=42i")
+	assert.NotContains(t, body, "read_iops")
+}
+
+func TestWriteError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"database not found: \"pure\""}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "pure", time.Second)
+	err := client.UpdateAlerts([]*metrics.Alert{{AlertID: 1, ArrayID: "a", Severity: "warning", State: "open"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "database not found")
+}
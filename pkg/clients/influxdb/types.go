@@ -0,0 +1,31 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import "github.com/go-resty/resty"
+
+// Client is a metrics.Database that writes metrics and alerts to an InfluxDB database
+// using the line protocol. Retention is left to the database's retention policy.
+type Client struct {
+	url        string
+	database   string
+	restClient *resty.Client
+}
+
+// field is a single field of a line protocol point
+type field struct {
+	key   string
+	value interface{} // bool, float64, int64, uint64 or string
+}
@@ -32,8 +32,10 @@ const (
 	// The key the data is stored in inside the secret
 	secretDataKey = "value"
 
-	// The name of the secret to save/load from. Note: must be lowercase alphanumeric, periods, or hyphens
-	deviceTokenSecretName = "pure1-unplugged-device-token-secret"
+	// The names of the secrets to save/load from. Note: must be lowercase alphanumeric, periods, or hyphens
+	deviceTokenSecretName     = "pure1-unplugged-device-token-secret"
+	snmpCredentialsSecretName = "pure1-unplugged-snmp-credentials-secret"
+	apiClientsSecretName      = "pure1-unplugged-api-clients-secret"
 )
 
 // GetKubeSecretInterface creates a SecretInterface hooked up to the API server of
@@ -57,6 +59,22 @@ func GetKubeSecretInterface(namespace string) (typev1.SecretInterface, error) {
 // easily be added with a secret watch, but isn't implemented yet since it's a relatively niche
 // use case.
 func NewKubeSecretAPITokenStore(secretAccess typev1.SecretInterface) resources.APITokenStorage {
+	return newKubeSecretTokenStore(secretAccess, deviceTokenSecretName)
+}
+
+// NewKubeSecretSNMPCredentialStore generates a token store like NewKubeSecretAPITokenStore, but
+// for the JSON encoded credentials of SNMP destinations (keyed by destination ID), kept in their own secret
+func NewKubeSecretSNMPCredentialStore(secretAccess typev1.SecretInterface) resources.APITokenStorage {
+	return newKubeSecretTokenStore(secretAccess, snmpCredentialsSecretName)
+}
+
+// NewKubeSecretAPIClientCredentialStore generates a token store like NewKubeSecretAPITokenStore, but
+// for the JSON encoded API client credentials of FlashArrays (keyed by array ID), kept in their own secret
+func NewKubeSecretAPIClientCredentialStore(secretAccess typev1.SecretInterface) resources.APITokenStorage {
+	return newKubeSecretTokenStore(secretAccess, apiClientsSecretName)
+}
+
+func newKubeSecretTokenStore(secretAccess typev1.SecretInterface, secretName string) resources.APITokenStorage {
 	secretChan := make(chan bool, 1) // Buffer of 1 since we can write without blocking
 	toReturn := kubeSecretDeviceTokenStorage{
 		secretAccess:    secretAccess,
@@ -66,18 +84,18 @@ func NewKubeSecretAPITokenStore(secretAccess typev1.SecretInterface) resources.A
 	}
 	// Ignore any errors we get back: if it errored out, it's handled in the next call to ensure
 	// maps are initialized with empty values
-	err := toReturn.parseFromSecret(deviceTokenSecretName)
+	err := toReturn.parseFromSecret(secretName)
 	if err == nil {
-		log.Debug("Loaded device token data successfully from secret!")
+		log.WithField("secret", secretName).Debug("Loaded token data successfully from secret!")
 	} else {
-		log.WithError(err).Error("Error parsing data from secret: continuing with blank device token info")
+		log.WithError(err).WithField("secret", secretName).Error("Error parsing data from secret: continuing with blank token info")
 	}
 	// Ensure map is initialized
 	if toReturn.tokens == nil {
 		toReturn.tokens = map[string]string{}
 	}
 
-	go toReturn.secretSaveLoop(deviceTokenSecretName)
+	go toReturn.secretSaveLoop(secretName)
 
 	return &toReturn
 }
@@ -116,7 +134,7 @@ func (k *kubeSecretDeviceTokenStorage) secretSaveLoop(secretName string) {
 				// not worth crashing over or anything. If it's a temporary error, it'll be rewritten with the next time anything changes (like a device being updated, deleted, etc.),
 				// or if it's a permanent error then they'll see this and can debug more.
 				if err != nil {
-					log.WithError(err).Error("Error saving device token data to secret. No action is being taken, but be aware that device API tokens may be lost in case of a crash")
+					log.WithError(err).WithField("secret", secretName).Error("Error saving token data to secret. No action is being taken, but be aware that tokens may be lost in case of a crash")
 				} else {
 					log.Debug("Saved to secret successfully")
 				}
@@ -0,0 +1,168 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.Database = (*Marker)(nil)
+
+// openState is the state of alerts that haven't been resolved yet
+const openState = "open"
+
+// NewMarker creates a marker in front of the given database. Windows are fetched from the given discovery
+// service, along with the tags of every array from the given tag discovery service, and nothing is marked until
+// RefreshWindows is called (or Run is started).
+func NewMarker(database metrics.Database, discovery resources.MaintenanceWindowDiscovery, tagDiscovery resources.ArrayTagDiscovery) *Marker {
+	return &Marker{
+		database:     database,
+		discovery:    discovery,
+		tagDiscovery: tagDiscovery,
+		windows:      []*resources.MaintenanceWindow{},
+		arrayTags:    map[string]map[string]string{},
+	}
+}
+
+// Run refreshes the windows right away, then every period for as long as the process runs
+func (m *Marker) Run(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		err := m.RefreshWindows()
+		if err != nil {
+			log.WithError(err).Warn("Failed to refresh maintenance windows, will try again later")
+		}
+		<-ticker.C
+	}
+}
+
+// RefreshWindows fetches the current maintenance windows, and the tags of every array so that tag selectors
+// match arrays that haven't had their metrics collected yet (like ones that are only raising alerts)
+func (m *Marker) RefreshWindows() error {
+	windows, err := m.discovery.GetMaintenanceWindows()
+	if err != nil {
+		return err
+	}
+	// The windows are used regardless, with the tags from the latest metrics if these can't be fetched
+	allTags, err := m.tagDiscovery.GetAllTags()
+	if err != nil {
+		err = fmt.Errorf("Error fetching array tags: %v", err)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.windows = windows
+	for arrayID, tags := range allTags {
+		m.arrayTags[arrayID] = tags
+	}
+	log.WithFields(log.Fields{
+		"windows": len(windows),
+		"arrays":  len(allTags),
+	}).Trace("Refreshed maintenance windows")
+	return err
+}
+
+// AddArrayMetrics marks the metrics collected during a maintenance window, then writes them to the wrapped database
+func (m *Marker) AddArrayMetrics(arrayMetrics []*metrics.ArrayMetric) error {
+	m.lock.Lock()
+	for _, metric := range arrayMetrics {
+		if metric == nil {
+			continue
+		}
+		m.arrayTags[metric.ArrayID] = metric.Tags
+		metric.InMaintenance = m.inMaintenance(metric.ArrayID, metric.Tags, metric.CreatedAt)
+	}
+	m.lock.Unlock()
+
+	return m.database.AddArrayMetrics(arrayMetrics)
+}
+
+// AddVolumeMetrics marks the metrics collected during a maintenance window, then writes them to the wrapped database
+func (m *Marker) AddVolumeMetrics(volumeMetrics []*metrics.VolumeMetric) error {
+	m.lock.Lock()
+	for _, metric := range volumeMetrics {
+		if metric == nil {
+			continue
+		}
+		m.arrayTags[metric.ArrayID] = metric.ArrayTags
+		metric.InMaintenance = m.inMaintenance(metric.ArrayID, metric.ArrayTags, metric.CreatedAt)
+	}
+	m.lock.Unlock()
+
+	return m.database.AddVolumeMetrics(volumeMetrics)
+}
+
+// UpdateAlerts marks the alerts that are being collected during a maintenance window, or were raised during one
+// and are no longer open, then writes them to the wrapped database. Alerts raised during a window that are still
+// open once it's over aren't marked, so they're treated like any other open alert from then on. Alerts don't carry
+// the array's tags, so the tags from the API server or the array's latest metrics are used to match tag selectors.
+func (m *Marker) UpdateAlerts(alerts []*metrics.Alert) error {
+	now := time.Now().Unix()
+
+	m.lock.Lock()
+	for _, alert := range alerts {
+		if alert == nil {
+			continue
+		}
+		tags := m.arrayTags[alert.ArrayID]
+		alert.InMaintenance = m.inMaintenance(alert.ArrayID, tags, now) || (alert.State != openState && m.inMaintenance(alert.ArrayID, tags, alert.Created))
+	}
+	m.lock.Unlock()
+
+	return m.database.UpdateAlerts(alerts)
+}
+
+// CleanArrayMetrics passes through to the wrapped database
+func (m *Marker) CleanArrayMetrics(maxAgeInDays int) error {
+	return m.database.CleanArrayMetrics(maxAgeInDays)
+}
+
+// CleanVolumeMetrics passes through to the wrapped database
+func (m *Marker) CleanVolumeMetrics(maxAgeInDays int) error {
+	return m.database.CleanVolumeMetrics(maxAgeInDays)
+}
+
+// CleanAlerts passes through to the wrapped database
+func (m *Marker) CleanAlerts(maxAgeInDays int) error {
+	return m.database.CleanAlerts(maxAgeInDays)
+}
+
+// CleanErrorLogs passes through to the wrapped database
+func (m *Marker) CleanErrorLogs(maxAgeInDays int) error {
+	return m.database.CleanErrorLogs(maxAgeInDays)
+}
+
+// CleanTimerLogs passes through to the wrapped database
+func (m *Marker) CleanTimerLogs(maxAgeInDays int) error {
+	return m.database.CleanTimerLogs(maxAgeInDays)
+}
+
+// inMaintenance is a helper function to check if the given array is in any maintenance window at the given
+// time (in Unix seconds). Must be called with the lock held.
+func (m *Marker) inMaintenance(arrayID string, arrayTags map[string]string, at int64) bool {
+	for _, window := range m.windows {
+		if window.IsActiveAt(at) && window.Matches(arrayID, arrayTags) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	clientmock "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// createMarker creates a marker with the given windows in front of a database that accepts every write
+func createMarker(t *testing.T, windows []*resources.MaintenanceWindow) (*Marker, *clientmock.MetricsDatabaseImpl) {
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("AddArrayMetrics", mock.Anything).Return(nil)
+	database.On("AddVolumeMetrics", mock.Anything).Return(nil)
+	database.On("UpdateAlerts", mock.Anything).Return(nil)
+
+	discovery := &clientmock.MaintenanceWindowDiscoveryImpl{}
+	discovery.On("GetMaintenanceWindows").Return(windows, nil)
+	tagDiscovery := &clientmock.ArrayTagDiscoveryImpl{}
+	tagDiscovery.On("GetAllTags").Return(map[string]map[string]string{"array4": {"site": "east"}}, nil)
+
+	marker := NewMarker(database, discovery, tagDiscovery)
+	assert.NoError(t, marker.RefreshWindows())
+	return marker, database
+}
+
+func TestMarkerArrayMetrics(t *testing.T) {
+	marker, database := createMarker(t, []*resources.MaintenanceWindow{
+		{ArrayIDs: []string{"array1"}, Start: 1000, End: 2000},
+		{ArrayTags: map[string]string{"site": "east"}, Start: 5000, End: 6000},
+	})
+
+	arrayMetrics := []*metrics.ArrayMetric{
+		{ArrayID: "array1", CreatedAt: 1500},
+		{ArrayID: "array1", CreatedAt: 2500},
+		{ArrayID: "array2", CreatedAt: 1500},
+		{ArrayID: "array2", CreatedAt: 5500, Tags: map[string]string{"site": "east"}},
+		{ArrayID: "array3", CreatedAt: 5500, Tags: map[string]string{"site": "west"}},
+	}
+	assert.NoError(t, marker.AddArrayMetrics(arrayMetrics))
+	assert.True(t, arrayMetrics[0].InMaintenance)
+	assert.False(t, arrayMetrics[1].InMaintenance)
+	assert.False(t, arrayMetrics[2].InMaintenance)
+	assert.True(t, arrayMetrics[3].InMaintenance)
+	assert.False(t, arrayMetrics[4].InMaintenance)
+	database.AssertCalled(t, "AddArrayMetrics", arrayMetrics)
+}
+
+func TestMarkerVolumeMetrics(t *testing.T) {
+	marker, database := createMarker(t, []*resources.MaintenanceWindow{
+		{ArrayTags: map[string]string{"site": "east"}, Start: 1000, End: 2000},
+	})
+
+	volumeMetrics := []*metrics.VolumeMetric{
+		{ArrayID: "array1", VolumeName: "vol1", CreatedAt: 1500, ArrayTags: map[string]string{"site": "east"}},
+		{ArrayID: "array2", VolumeName: "vol1", CreatedAt: 1500, ArrayTags: map[string]string{}},
+	}
+	assert.NoError(t, marker.AddVolumeMetrics(volumeMetrics))
+	assert.True(t, volumeMetrics[0].InMaintenance)
+	assert.False(t, volumeMetrics[1].InMaintenance)
+	database.AssertCalled(t, "AddVolumeMetrics", volumeMetrics)
+}
+
+func TestMarkerAlerts(t *testing.T) {
+	now := time.Now().Unix()
+	marker, database := createMarker(t, []*resources.MaintenanceWindow{
+		{ArrayIDs: []string{"array1"}, Start: 1000, End: 2000},
+		{ArrayTags: map[string]string{"site": "east"}, Start: now - 60, End: now + 3600},
+	})
+
+	// Tags are learned from the array's metrics
+	assert.NoError(t, marker.AddArrayMetrics([]*metrics.ArrayMetric{{ArrayID: "array2", CreatedAt: now, Tags: map[string]string{"site": "east"}}}))
+
+	alerts := []*metrics.Alert{
+		{ArrayID: "array1", AlertID: 1, Created: 1500, State: "closed"}, // Raised and resolved during a window that's over
+		{ArrayID: "array1", AlertID: 2, Created: 2500, State: "closed"},
+		{ArrayID: "array2", AlertID: 3, Created: 500, State: "open"}, // Raised before the window, but collected during it
+		{ArrayID: "array3", AlertID: 4, Created: now, State: "open"},
+		{ArrayID: "array1", AlertID: 5, Created: 1500, State: "open"}, // Raised during a window that's over, but still open
+		{ArrayID: "array4", AlertID: 6, Created: now, State: "open"},  // No metrics yet, but tagged on the API server
+	}
+	assert.NoError(t, marker.UpdateAlerts(alerts))
+	assert.True(t, alerts[0].InMaintenance)
+	assert.False(t, alerts[1].InMaintenance)
+	assert.True(t, alerts[2].InMaintenance)
+	assert.False(t, alerts[3].InMaintenance)
+	assert.False(t, alerts[4].InMaintenance)
+	assert.True(t, alerts[5].InMaintenance)
+	database.AssertCalled(t, "UpdateAlerts", alerts)
+}
+
+func TestMarkerNoWindows(t *testing.T) {
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("AddArrayMetrics", mock.Anything).Return(nil)
+	marker := NewMarker(database, &clientmock.MaintenanceWindowDiscoveryImpl{}, &clientmock.ArrayTagDiscoveryImpl{})
+
+	// Nothing is marked before the windows have been fetched
+	arrayMetrics := []*metrics.ArrayMetric{{ArrayID: "array1", CreatedAt: 1500}}
+	assert.NoError(t, marker.AddArrayMetrics(arrayMetrics))
+	assert.False(t, arrayMetrics[0].InMaintenance)
+}
+
+func TestMarkerRefreshWindowsWithoutTags(t *testing.T) {
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("UpdateAlerts", mock.Anything).Return(nil)
+	discovery := &clientmock.MaintenanceWindowDiscoveryImpl{}
+	discovery.On("GetMaintenanceWindows").Return([]*resources.MaintenanceWindow{{ArrayIDs: []string{"array1"}, Start: 1000, End: 2000}}, nil)
+	tagDiscovery := &clientmock.ArrayTagDiscoveryImpl{}
+	tagDiscovery.On("GetAllTags").Return(map[string]map[string]string(nil), fmt.Errorf("API server unavailable"))
+
+	// The windows are still used when the tags can't be fetched
+	marker := NewMarker(database, discovery, tagDiscovery)
+	assert.Error(t, marker.RefreshWindows())
+	alerts := []*metrics.Alert{{ArrayID: "array1", AlertID: 1, Created: 1500, State: "closed"}}
+	assert.NoError(t, marker.UpdateAlerts(alerts))
+	assert.True(t, alerts[0].InMaintenance)
+}
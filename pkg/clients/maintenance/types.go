@@ -0,0 +1,35 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"sync"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+// Marker is a metrics.Database that wraps another one, marking the metrics and alerts of arrays that are in
+// a maintenance window as InMaintenance before passing them through. Anything behind it (the alert rule
+// evaluator, notifier and sinks) can then suppress or shade them.
+type Marker struct {
+	database     metrics.Database
+	discovery    resources.MaintenanceWindowDiscovery
+	tagDiscovery resources.ArrayTagDiscovery
+
+	lock      sync.Mutex
+	windows   []*resources.MaintenanceWindow
+	arrayTags map[string]map[string]string // Keyed by array ID, from the API server or the latest metrics
+}
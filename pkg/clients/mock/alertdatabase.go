@@ -0,0 +1,42 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock
+
+import (
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+// Type guard: ensure this implements the interface
+var _ resources.AlertDatabase = (*AlertDatabaseImpl)(nil)
+
+// FindAlerts is a mocked implementation
+func (a *AlertDatabaseImpl) FindAlerts(query *resources.AlertQuery) ([]*metrics.Alert, error) {
+	args := a.Called(query)
+	return args.Get(0).([]*metrics.Alert), args.Error(1)
+}
+
+// PatchAlertLifecycles is a mocked implementation, which applies the patch to the alerts it's set up to return
+func (a *AlertDatabaseImpl) PatchAlertLifecycles(ids []string, patch func(alert *metrics.Alert) error) ([]*metrics.Alert, error) {
+	args := a.Called(ids)
+	alerts := args.Get(0).([]*metrics.Alert)
+	for _, alert := range alerts {
+		err := patch(alert)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return alerts, args.Error(1)
+}
@@ -0,0 +1,65 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock
+
+import (
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+// Type guard: ensure this implements the interface
+var _ resources.AlertRuleDatabase = (*AlertRuleDatabaseImpl)(nil)
+
+// FindAlertRules is a mocked implementation
+func (a *AlertRuleDatabaseImpl) FindAlertRules(ids []string) ([]*resources.AlertRule, error) {
+	args := a.Called(ids)
+	return args.Get(0).([]*resources.AlertRule), args.Error(1)
+}
+
+// InsertAlertRule is a mocked implementation
+func (a *AlertRuleDatabaseImpl) InsertAlertRule(rule *resources.AlertRule) error {
+	args := a.Called(rule)
+	return args.Error(0)
+}
+
+// UpdateAlertRule is a mocked implementation
+func (a *AlertRuleDatabaseImpl) UpdateAlertRule(rule *resources.AlertRule) error {
+	args := a.Called(rule)
+	return args.Error(0)
+}
+
+// DeleteAlertRules is a mocked implementation
+func (a *AlertRuleDatabaseImpl) DeleteAlertRules(ids []string) ([]string, error) {
+	args := a.Called(ids)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.AlertRuleDiscovery = (*AlertRuleDiscoveryImpl)(nil)
+
+// GetAlertRules is a mocked implementation
+func (a *AlertRuleDiscoveryImpl) GetAlertRules() ([]*resources.AlertRule, error) {
+	args := a.Called()
+	return args.Get(0).([]*resources.AlertRule), args.Error(1)
+}
+
+// Type guard: ensure this implements the interface
+var _ metrics.RuleAlertDatabase = (*RuleAlertDatabaseImpl)(nil)
+
+// FindOpenRuleAlerts is a mocked implementation
+func (r *RuleAlertDatabaseImpl) FindOpenRuleAlerts() ([]*metrics.Alert, error) {
+	args := r.Called()
+	return args.Get(0).([]*metrics.Alert), args.Error(1)
+}
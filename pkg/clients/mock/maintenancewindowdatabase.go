@@ -0,0 +1,64 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock
+
+import (
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+)
+
+// Type guard: ensure this implements the interface
+var _ resources.MaintenanceWindowDatabase = (*MaintenanceWindowDatabaseImpl)(nil)
+
+// FindMaintenanceWindows is a mocked implementation
+func (m *MaintenanceWindowDatabaseImpl) FindMaintenanceWindows(ids []string) ([]*resources.MaintenanceWindow, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]*resources.MaintenanceWindow), args.Error(1)
+}
+
+// InsertMaintenanceWindow is a mocked implementation
+func (m *MaintenanceWindowDatabaseImpl) InsertMaintenanceWindow(window *resources.MaintenanceWindow) error {
+	args := m.Called(window)
+	return args.Error(0)
+}
+
+// UpdateMaintenanceWindow is a mocked implementation
+func (m *MaintenanceWindowDatabaseImpl) UpdateMaintenanceWindow(window *resources.MaintenanceWindow) error {
+	args := m.Called(window)
+	return args.Error(0)
+}
+
+// DeleteMaintenanceWindows is a mocked implementation
+func (m *MaintenanceWindowDatabaseImpl) DeleteMaintenanceWindows(ids []string) ([]string, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.MaintenanceWindowDiscovery = (*MaintenanceWindowDiscoveryImpl)(nil)
+
+// GetMaintenanceWindows is a mocked implementation
+func (m *MaintenanceWindowDiscoveryImpl) GetMaintenanceWindows() ([]*resources.MaintenanceWindow, error) {
+	args := m.Called()
+	return args.Get(0).([]*resources.MaintenanceWindow), args.Error(1)
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.ArrayTagDiscovery = (*ArrayTagDiscoveryImpl)(nil)
+
+// GetAllTags is a mocked implementation
+func (a *ArrayTagDiscoveryImpl) GetAllTags() (map[string]map[string]string, error) {
+	args := a.Called()
+	return args.Get(0).(map[string]map[string]string), args.Error(1)
+}
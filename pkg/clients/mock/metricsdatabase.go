@@ -0,0 +1,68 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock
+
+import "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+
+// Type guard: ensure this implements the interface
+var _ metrics.Database = (*MetricsDatabaseImpl)(nil)
+
+// AddArrayMetrics is a mocked implementation
+func (m *MetricsDatabaseImpl) AddArrayMetrics(arrayMetrics []*metrics.ArrayMetric) error {
+	args := m.Called(arrayMetrics)
+	return args.Error(0)
+}
+
+// AddVolumeMetrics is a mocked implementation
+func (m *MetricsDatabaseImpl) AddVolumeMetrics(volumeMetrics []*metrics.VolumeMetric) error {
+	args := m.Called(volumeMetrics)
+	return args.Error(0)
+}
+
+// UpdateAlerts is a mocked implementation
+func (m *MetricsDatabaseImpl) UpdateAlerts(alerts []*metrics.Alert) error {
+	args := m.Called(alerts)
+	return args.Error(0)
+}
+
+// CleanArrayMetrics is a mocked implementation
+func (m *MetricsDatabaseImpl) CleanArrayMetrics(maxAgeInDays int) error {
+	args := m.Called(maxAgeInDays)
+	return args.Error(0)
+}
+
+// CleanVolumeMetrics is a mocked implementation
+func (m *MetricsDatabaseImpl) CleanVolumeMetrics(maxAgeInDays int) error {
+	args := m.Called(maxAgeInDays)
+	return args.Error(0)
+}
+
+// CleanAlerts is a mocked implementation
+func (m *MetricsDatabaseImpl) CleanAlerts(maxAgeInDays int) error {
+	args := m.Called(maxAgeInDays)
+	return args.Error(0)
+}
+
+// CleanErrorLogs is a mocked implementation
+func (m *MetricsDatabaseImpl) CleanErrorLogs(maxAgeInDays int) error {
+	args := m.Called(maxAgeInDays)
+	return args.Error(0)
+}
+
+// CleanTimerLogs is a mocked implementation
+func (m *MetricsDatabaseImpl) CleanTimerLogs(maxAgeInDays int) error {
+	args := m.Called(maxAgeInDays)
+	return args.Error(0)
+}
@@ -0,0 +1,64 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock
+
+import (
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+)
+
+// Type guard: ensure this implements the interface
+var _ resources.SNMPDestinationDatabase = (*SNMPDestinationDatabaseImpl)(nil)
+
+// FindSNMPDestinations is a mocked implementation
+func (s *SNMPDestinationDatabaseImpl) FindSNMPDestinations(ids []string) ([]*resources.SNMPDestination, error) {
+	args := s.Called(ids)
+	return args.Get(0).([]*resources.SNMPDestination), args.Error(1)
+}
+
+// InsertSNMPDestination is a mocked implementation
+func (s *SNMPDestinationDatabaseImpl) InsertSNMPDestination(destination *resources.SNMPDestination) error {
+	args := s.Called(destination)
+	return args.Error(0)
+}
+
+// UpdateSNMPDestination is a mocked implementation
+func (s *SNMPDestinationDatabaseImpl) UpdateSNMPDestination(destination *resources.SNMPDestination) error {
+	args := s.Called(destination)
+	return args.Error(0)
+}
+
+// DeleteSNMPDestinations is a mocked implementation
+func (s *SNMPDestinationDatabaseImpl) DeleteSNMPDestinations(ids []string) ([]string, error) {
+	args := s.Called(ids)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.SNMPDestinationDiscovery = (*SNMPDestinationDiscoveryImpl)(nil)
+
+// GetSNMPDestinations is a mocked implementation
+func (s *SNMPDestinationDiscoveryImpl) GetSNMPDestinations() ([]*resources.SNMPDestination, error) {
+	args := s.Called()
+	return args.Get(0).([]*resources.SNMPDestination), args.Error(1)
+}
+
+// Type guard: ensure this implements the interface
+var _ resources.SNMPTestTrapSender = (*SNMPTestTrapSenderImpl)(nil)
+
+// SendTestTrap is a mocked implementation
+func (s *SNMPTestTrapSenderImpl) SendTestTrap(destination *resources.SNMPDestination) error {
+	args := s.Called(destination)
+	return args.Error(0)
+}
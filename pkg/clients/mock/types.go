@@ -30,3 +30,93 @@ type APITokenStorageImpl struct {
 type ArrayMetadataImpl struct {
 	mock.Mock
 }
+
+// MetricsDatabaseImpl provides a mocked implementation of the metrics.Database interface for testing
+type MetricsDatabaseImpl struct {
+	mock.Mock
+}
+
+// CapacityForecastDatabaseImpl provides a mocked implementation of the resources.CapacityForecastDatabase interface for testing
+type CapacityForecastDatabaseImpl struct {
+	mock.Mock
+}
+
+// HardwareStatusDatabaseImpl provides a mocked implementation of the resources.HardwareStatusDatabase interface for testing
+type HardwareStatusDatabaseImpl struct {
+	mock.Mock
+}
+
+// PodStatusDatabaseImpl provides a mocked implementation of the resources.PodStatusDatabase interface for testing
+type PodStatusDatabaseImpl struct {
+	mock.Mock
+}
+
+// AlertRuleDatabaseImpl provides a mocked implementation of the resources.AlertRuleDatabase interface for testing
+type AlertRuleDatabaseImpl struct {
+	mock.Mock
+}
+
+// AlertRuleDiscoveryImpl provides a mocked implementation of the resources.AlertRuleDiscovery interface for testing
+type AlertRuleDiscoveryImpl struct {
+	mock.Mock
+}
+
+// RuleAlertDatabaseImpl provides a mocked implementation of the metrics.RuleAlertDatabase interface for testing
+type RuleAlertDatabaseImpl struct {
+	mock.Mock
+}
+
+// MaintenanceWindowDatabaseImpl provides a mocked implementation of the resources.MaintenanceWindowDatabase interface for testing
+type MaintenanceWindowDatabaseImpl struct {
+	mock.Mock
+}
+
+// ArrayTagDiscoveryImpl provides a mocked implementation of the resources.ArrayTagDiscovery interface for testing
+type ArrayTagDiscoveryImpl struct {
+	mock.Mock
+}
+
+// MaintenanceWindowDiscoveryImpl provides a mocked implementation of the resources.MaintenanceWindowDiscovery interface for testing
+type MaintenanceWindowDiscoveryImpl struct {
+	mock.Mock
+}
+
+// SNMPDestinationDatabaseImpl provides a mocked implementation of the resources.SNMPDestinationDatabase interface for testing
+type SNMPDestinationDatabaseImpl struct {
+	mock.Mock
+}
+
+// SNMPDestinationDiscoveryImpl provides a mocked implementation of the resources.SNMPDestinationDiscovery interface for testing
+type SNMPDestinationDiscoveryImpl struct {
+	mock.Mock
+}
+
+// SNMPTestTrapSenderImpl provides a mocked implementation of the resources.SNMPTestTrapSender interface for testing
+type SNMPTestTrapSenderImpl struct {
+	mock.Mock
+}
+
+// AlertDatabaseImpl provides a mocked implementation of the resources.AlertDatabase interface for testing
+type AlertDatabaseImpl struct {
+	mock.Mock
+}
+
+// ProtectionGroupDatabaseImpl provides a mocked implementation of the metrics.ProtectionGroupDatabase interface for testing
+type ProtectionGroupDatabaseImpl struct {
+	mock.Mock
+}
+
+// ReplicationAlertDatabaseImpl provides a mocked implementation of the metrics.ReplicationAlertDatabase interface for testing
+type ReplicationAlertDatabaseImpl struct {
+	mock.Mock
+}
+
+// AuditEventDatabaseImpl provides a mocked implementation of the resources.AuditEventDatabase interface for testing
+type AuditEventDatabaseImpl struct {
+	mock.Mock
+}
+
+// SnapshotInventoryDatabaseImpl provides a mocked implementation of the resources.SnapshotInventoryDatabase interface for testing
+type SnapshotInventoryDatabaseImpl struct {
+	mock.Mock
+}
@@ -0,0 +1,212 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestNotification() *Notification {
+	return &Notification{
+		Event: EventNew,
+		Alert: &metrics.Alert{
+			AlertID:          42,
+			ArrayID:          "array-1",
+			ArrayName:        "array1",
+			ArrayDisplayName: "Array \"One\"",
+			Created:          1546300800,
+			Severity:         "critical",
+			State:            "open",
+			Summary:          "Controller\nfailed",
+			Source:           metrics.AlertSourceArray,
+		},
+		ArrayTags: map[string]string{"site": "dc1"},
+	}
+}
+
+func TestParseTemplateFallsBackToDefault(t *testing.T) {
+	parsed, err := ParseTemplate("message", "  ", DefaultMessageTemplate)
+	assert.NoError(t, err)
+	rendered, err := render(parsed, newTestNotification())
+	assert.NoError(t, err)
+	assert.Equal(t, "CRITICAL alert 42 on Array \"One\" is open: Controller\nfailed", rendered)
+
+	parsed, err = ParseTemplate("message", "{{.Alert.ArrayName}} since {{formatTime .Alert.Created}}", DefaultMessageTemplate)
+	assert.NoError(t, err)
+	rendered, err = render(parsed, newTestNotification())
+	assert.NoError(t, err)
+	assert.Equal(t, "array1 since 2019-01-01T00:00:00Z", rendered)
+
+	_, err = ParseTemplate("message", "{{.Alert.Summary", DefaultMessageTemplate)
+	assert.Error(t, err)
+}
+
+func TestSMTPChannelSendsEmail(t *testing.T) {
+	subject, _ := ParseTemplate("subject", "", DefaultSubjectTemplate)
+	body, _ := ParseTemplate("body", "", DefaultBodyTemplate)
+	channel, err := NewSMTPChannel("mail.example.com:25", "", "", "unplugged@example.com", []string{"a@example.com", "b@example.com"}, subject, body)
+	assert.NoError(t, err)
+
+	var sentTo []string
+	var sent string
+	channel.sendMail = func(address string, auth smtp.Auth, from string, to []string, message []byte) error {
+		assert.Equal(t, "mail.example.com:25", address)
+		assert.Nil(t, auth)
+		assert.Equal(t, "unplugged@example.com", from)
+		sentTo = to
+		sent = string(message)
+		return nil
+	}
+
+	err = channel.Send(newTestNotification())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, sentTo)
+	assert.Contains(t, sent, "To: a@example.com, b@example.com\r\n")
+	assert.Contains(t, sent, "Subject: [Pure1 Unplugged] CRITICAL alert on Array \"One\": Controller failed (open)\r\n")
+	assert.Contains(t, sent, "\r\n\r\nA new alert was raised on array Array \"One\" (array1).\r\n")
+	assert.Contains(t, sent, "Created:   2019-01-01T00:00:00Z\r\n")
+	assert.NotContains(t, strings.Replace(sent, "\r\n", "", -1), "\n")
+}
+
+func TestNewSMTPChannelValidation(t *testing.T) {
+	subject, _ := ParseTemplate("subject", "", DefaultSubjectTemplate)
+	body, _ := ParseTemplate("body", "", DefaultBodyTemplate)
+
+	_, err := NewSMTPChannel("mail.example.com", "", "", "unplugged@example.com", []string{"a@example.com"}, subject, body)
+	assert.Error(t, err)
+	_, err = NewSMTPChannel("mail.example.com:25", "", "", "", []string{"a@example.com"}, subject, body)
+	assert.Error(t, err)
+	_, err = NewSMTPChannel("mail.example.com:25", "", "", "unplugged@example.com", nil, subject, body)
+	assert.Error(t, err)
+
+	channel, err := NewSMTPChannel("mail.example.com:587", "user", "password", "unplugged@example.com", []string{"a@example.com"}, subject, body)
+	assert.NoError(t, err)
+	assert.NotNil(t, channel.auth)
+}
+
+func TestWebhookChannelPostsJSON(t *testing.T) {
+	var received webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	message, _ := ParseTemplate("message", "{{.Alert.Summary}}", DefaultMessageTemplate)
+	channel := NewWebhookChannel(server.URL, message, time.Second)
+	err := channel.Send(newTestNotification())
+	assert.NoError(t, err)
+	assert.Equal(t, EventNew, received.Event)
+	assert.Equal(t, "Controller\nfailed", received.Message)
+	assert.Equal(t, uint64(42), received.Alert.AlertID)
+	assert.Equal(t, map[string]string{"site": "dc1"}, received.ArrayTags)
+}
+
+func TestWebhookChannelReturnsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try later", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	message, _ := ParseTemplate("message", "", DefaultMessageTemplate)
+	channel := NewWebhookChannel(server.URL, message, time.Second)
+	err := channel.Send(newTestNotification())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "try later")
+}
+
+func TestSyslogChannelFormatsRFC5424(t *testing.T) {
+	message, _ := ParseTemplate("message", "", DefaultMessageTemplate)
+	channel, err := NewSyslogChannel("udp", "localhost:514", 16, "pure1 unplugged", message, time.Second)
+	assert.NoError(t, err)
+	channel.hostname = "collector"
+
+	formatted, err := channel.formatMessage(newTestNotification(), time.Date(2019, 1, 1, 12, 30, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	// local0 (16) * 8 + critical (2)
+	assert.True(t, strings.HasPrefix(formatted, "<130>1 2019-01-01T12:30:00.000Z collector pure1unplugged "), formatted)
+	assert.Contains(t, formatted, ` alert [alert@40482 event="new" array_id="array-1" array_name="array1" alert_id="42" severity="critical" state="open" source="array"] `)
+	assert.True(t, strings.HasSuffix(formatted, syslogBOM+"CRITICAL alert 42 on Array \"One\" is open: Controller failed"), formatted)
+
+	closed := newTestNotification()
+	closed.Alert.State = "closed"
+	formatted, err = channel.formatMessage(closed, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(formatted, "<133>1 "), formatted)
+}
+
+func TestSyslogChannelSendsOverTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		connection, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		data, _ := ioutil.ReadAll(connection)
+		received <- string(data)
+	}()
+
+	message, _ := ParseTemplate("message", "", DefaultMessageTemplate)
+	channel, err := NewSyslogChannel("tcp", listener.Addr().String(), 16, "pure1-unplugged", message, time.Second)
+	assert.NoError(t, err)
+	assert.NoError(t, channel.Send(newTestNotification()))
+
+	select {
+	case data := <-received:
+		parts := strings.SplitN(data, " ", 2)
+		if assert.Len(t, parts, 2) {
+			// Octet counting framing: the length of the message in bytes, then the message
+			assert.Equal(t, len(parts[1]), atoi(t, parts[0]))
+			assert.True(t, strings.HasPrefix(parts[1], "<130>1 "))
+		}
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timed out waiting for syslog message")
+	}
+}
+
+func TestNewSyslogChannelValidation(t *testing.T) {
+	message, _ := ParseTemplate("message", "", DefaultMessageTemplate)
+	_, err := NewSyslogChannel("unix", "/dev/log", 16, "pure1-unplugged", message, time.Second)
+	assert.Error(t, err)
+	_, err = NewSyslogChannel("udp", "localhost:514", 24, "pure1-unplugged", message, time.Second)
+	assert.Error(t, err)
+}
+
+func atoi(t *testing.T, value string) int {
+	var parsed int
+	_, err := fmt.Sscan(value, &parsed)
+	assert.NoError(t, err)
+	return parsed
+}
@@ -0,0 +1,438 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.Database = (*Notifier)(nil)
+
+const (
+	// How long an alert that's no longer pushed is remembered for. Arrays keep reporting closed alerts
+	// for a while, so this needs to be long enough that they aren't notified as new again.
+	forgetPeriod = 7 * 24 * time.Hour
+	prunePeriod  = time.Hour
+	// How often the tags of every array are fetched, at most, when alerts come in for arrays without metrics yet
+	tagRefreshPeriod = time.Minute
+)
+
+// NewNotifier creates a notifier that delivers the alerts matching the given filter to every given channel.
+// Each channel gets a queue of up to queueLength pending notifications and its own worker to drain it.
+// Alerts that were last created or updated before the notifier was created aren't notified when they're
+// first seen, so restarting the metrics client doesn't notify every open alert again. The lifecycles of alerts
+// are looked up in the given database (if there is one), to hold back notifications for snoozed and
+// acknowledged alerts, and the tags of arrays that haven't had their metrics written yet are fetched from the
+// given tag discovery service (if there is one).
+func NewNotifier(filter Filter, alerts resources.AlertDatabase, tagDiscovery resources.ArrayTagDiscovery, channels []Channel, backoff Backoff, queueLength int) *Notifier {
+	if backoff.MaxAttempts < 1 {
+		backoff.MaxAttempts = 1
+	}
+	n := &Notifier{
+		filter:       filter,
+		alerts:       alerts,
+		tagDiscovery: tagDiscovery,
+		started:      time.Now().Unix(),
+		notified:     map[string]*notifiedAlert{},
+		arrayTags:    map[string]map[string]string{},
+		lastPrune:    time.Now(),
+	}
+	for _, c := range channels {
+		ch := &channel{
+			Channel:       c,
+			backoff:       backoff,
+			notifications: make(chan *Notification, queueLength),
+			status:        ChannelStatus{Name: c.Name()},
+		}
+		n.channels = append(n.channels, ch)
+		go ch.drain()
+	}
+	return n
+}
+
+// Close stops the channel workers once their queues are empty. The notifier can't be used afterwards.
+func (n *Notifier) Close() {
+	for _, c := range n.channels {
+		close(c.notifications)
+	}
+}
+
+// Statuses gets a snapshot of the delivery status of every channel
+func (n *Notifier) Statuses() []ChannelStatus {
+	statuses := []ChannelStatus{}
+	for _, c := range n.channels {
+		statuses = append(statuses, c.getStatus())
+	}
+	return statuses
+}
+
+// AddArrayMetrics records the tags of the arrays the metrics are for, for filtering alerts by
+func (n *Notifier) AddArrayMetrics(arrayMetrics []*metrics.ArrayMetric) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, metric := range arrayMetrics {
+		n.arrayTags[metric.ArrayID] = copyTags(metric.Tags)
+	}
+	return nil
+}
+
+// AddVolumeMetrics records the tags of the arrays the metrics are for, for filtering alerts by
+func (n *Notifier) AddVolumeMetrics(volumeMetrics []*metrics.VolumeMetric) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, metric := range volumeMetrics {
+		n.arrayTags[metric.ArrayID] = copyTags(metric.ArrayTags)
+	}
+	return nil
+}
+
+// UpdateAlerts queues notifications for the given alerts that are new or have changed, and match the filter
+func (n *Notifier) UpdateAlerts(alerts []*metrics.Alert) error {
+	for _, notification := range n.getNotifications(alerts, time.Now()) {
+		for _, c := range n.channels {
+			c.enqueue(notification)
+		}
+	}
+	return nil
+}
+
+// CleanArrayMetrics does nothing, there's nothing to clean
+func (n *Notifier) CleanArrayMetrics(maxAgeInDays int) error {
+	return nil
+}
+
+// CleanVolumeMetrics does nothing, there's nothing to clean
+func (n *Notifier) CleanVolumeMetrics(maxAgeInDays int) error {
+	return nil
+}
+
+// CleanAlerts does nothing, there's nothing to clean
+func (n *Notifier) CleanAlerts(maxAgeInDays int) error {
+	return nil
+}
+
+// CleanErrorLogs does nothing, there's nothing to clean
+func (n *Notifier) CleanErrorLogs(maxAgeInDays int) error {
+	return nil
+}
+
+// CleanTimerLogs does nothing, there's nothing to clean
+func (n *Notifier) CleanTimerLogs(maxAgeInDays int) error {
+	return nil
+}
+
+// getNotifications is a helper function that records the given alerts as seen, and builds the notifications
+// for the ones that are new or have changed since they were last seen, and match the filter. Changes to alerts
+// that are snoozed or acknowledged are held back rather than recorded, so they're notified once the alert is
+// unsnoozed (or unacknowledged) if it's still different from what was last notified.
+func (n *Notifier) getNotifications(alerts []*metrics.Alert, now time.Time) []*Notification {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if len(n.filter.ArrayTags) > 0 {
+		n.refreshUnknownTags(alerts, now)
+	}
+
+	candidates := []*Notification{}
+	for _, alert := range alerts {
+		if alert == nil {
+			continue
+		}
+
+		tags, tagsKnown := n.arrayTags[alert.ArrayID]
+		if len(n.filter.ArrayTags) > 0 && !tagsKnown {
+			// Neither the API server nor the array's metrics know about the array yet, so leave the alert until they do
+			continue
+		}
+
+		key := getAlertKey(alert)
+		previous := n.notified[key]
+		if previous != nil && previous.state == alert.State && previous.severity == alert.Severity {
+			previous.lastSeen = now
+			continue
+		}
+
+		if previous == nil && getLastActivity(alert) < n.started {
+			n.notified[key] = &notifiedAlert{state: alert.State, severity: alert.Severity, lastSeen: now}
+			continue
+		}
+		if alert.InMaintenance {
+			// Held back rather than recorded, so an alert that's still open once the maintenance is over is
+			// notified then. Alerts resolved during maintenance stay marked, and are never notified.
+			if previous != nil {
+				previous.lastSeen = now
+			}
+			continue
+		}
+		if !n.filter.Matches(alert, tags) {
+			n.notified[key] = &notifiedAlert{state: alert.State, severity: alert.Severity, lastSeen: now}
+			continue
+		}
+
+		notification := &Notification{Event: EventNew, Alert: alert, ArrayTags: tags}
+		if previous != nil {
+			notification.Event = EventChanged
+			notification.PreviousState = previous.state
+			notification.PreviousSeverity = previous.severity
+		}
+		candidates = append(candidates, notification)
+	}
+
+	lifecycles := n.getLifecycles(candidates)
+	notifications := []*Notification{}
+	for _, notification := range candidates {
+		key := getAlertKey(notification.Alert)
+		if isHeldBack(lifecycles[key], now) {
+			if previous := n.notified[key]; previous != nil {
+				previous.lastSeen = now
+			}
+			continue
+		}
+		n.notified[key] = &notifiedAlert{state: notification.Alert.State, severity: notification.Alert.Severity, lastSeen: now}
+		notifications = append(notifications, notification)
+	}
+
+	if now.Sub(n.lastPrune) >= prunePeriod {
+		n.prune(now)
+	}
+	return notifications
+}
+
+// refreshUnknownTags is a helper function that fetches the tags of every array from the API server if any of the
+// given alerts are for arrays whose tags aren't known yet, at most once every tagRefreshPeriod. Must be called with
+// the lock held.
+func (n *Notifier) refreshUnknownTags(alerts []*metrics.Alert, now time.Time) {
+	if n.tagDiscovery == nil || now.Sub(n.tagsRefreshed) < tagRefreshPeriod {
+		return
+	}
+	unknown := false
+	for _, alert := range alerts {
+		if alert == nil {
+			continue
+		}
+		if _, ok := n.arrayTags[alert.ArrayID]; !ok {
+			unknown = true
+			break
+		}
+	}
+	if !unknown {
+		return
+	}
+
+	n.tagsRefreshed = now
+	allTags, err := n.tagDiscovery.GetAllTags()
+	if err != nil {
+		log.WithError(err).Warn("Error fetching array tags, alerts for arrays without metrics yet won't be notified")
+		return
+	}
+	for arrayID, tags := range allTags {
+		if _, ok := n.arrayTags[arrayID]; !ok {
+			// The latest metrics are just as fresh, so only fill in the arrays they haven't covered
+			n.arrayTags[arrayID] = tags
+		}
+	}
+}
+
+// getLifecycles is a helper function that looks up the lifecycles of the alerts of the given notifications,
+// keyed by getAlertKey. Collectors never set the lifecycle, so it has to come from the alert database. If it
+// can't be looked up the alerts are notified as if they had no lifecycle, rather than risk missing them.
+func (n *Notifier) getLifecycles(notifications []*Notification) map[string]*metrics.AlertLifecycle {
+	lifecycles := map[string]*metrics.AlertLifecycle{}
+	if n.alerts == nil || len(notifications) == 0 {
+		return lifecycles
+	}
+
+	ids := []string{}
+	for _, notification := range notifications {
+		ids = append(ids, getAlertKey(notification.Alert))
+	}
+	alerts, err := n.alerts.FindAlerts(&resources.AlertQuery{IDs: ids, Limit: len(ids)})
+	if err != nil {
+		log.WithError(err).Warn("Error looking up alert lifecycles, notifying regardless of snoozes and acknowledgements")
+		return lifecycles
+	}
+	for _, alert := range alerts {
+		if alert.Lifecycle != nil {
+			lifecycles[getAlertKey(alert)] = alert.Lifecycle
+		}
+	}
+	return lifecycles
+}
+
+// prune is a helper function that forgets alerts that haven't been seen for a while. Must be called with the lock held.
+func (n *Notifier) prune(now time.Time) {
+	for key, alert := range n.notified {
+		if now.Sub(alert.lastSeen) > forgetPeriod {
+			delete(n.notified, key)
+		}
+	}
+	n.lastPrune = now
+}
+
+// Matches checks if the given alert, raised by an array with the given tags, passes the filter
+func (f Filter) Matches(alert *metrics.Alert, arrayTags map[string]string) bool {
+	if alert.SeverityIndex < f.MinSeverityIndex {
+		return false
+	}
+	if len(f.States) > 0 {
+		matched := false
+		for _, state := range f.States {
+			if strings.EqualFold(state, alert.State) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for key, value := range f.ArrayTags {
+		if tag, ok := arrayTags[key]; !ok || tag != value {
+			return false
+		}
+	}
+	return true
+}
+
+// enqueue is a helper function that queues a notification without blocking, dropping it if the queue is full
+func (c *channel) enqueue(notification *Notification) {
+	select {
+	case c.notifications <- notification:
+	default:
+		c.statusLock.Lock()
+		c.status.Dropped++
+		c.statusLock.Unlock()
+		log.WithFields(log.Fields{
+			"channel":  c.Name(),
+			"array_id": notification.Alert.ArrayID,
+			"alert_id": notification.Alert.AlertID,
+		}).Warn("Notification queue is full, dropping notification")
+	}
+}
+
+// drain is a helper function that delivers queued notifications until the queue is closed
+func (c *channel) drain() {
+	for notification := range c.notifications {
+		c.deliver(notification)
+	}
+}
+
+// deliver is a helper function that tries to send the notification up to MaxAttempts times, backing off
+// exponentially between attempts, and records the outcome
+func (c *channel) deliver(notification *Notification) {
+	delay := c.backoff.InitialDelay
+	var err error
+	for attempt := 1; attempt <= c.backoff.MaxAttempts; attempt++ {
+		err = c.send(notification)
+		if err == nil {
+			c.recordSuccess()
+			return
+		}
+		log.WithFields(log.Fields{
+			"attempt":  attempt,
+			"error":    err,
+			"channel":  c.Name(),
+			"array_id": notification.Alert.ArrayID,
+			"alert_id": notification.Alert.AlertID,
+		}).Trace("Notification delivery failed")
+		if attempt < c.backoff.MaxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+			if c.backoff.MaxDelay > 0 && delay > c.backoff.MaxDelay {
+				delay = c.backoff.MaxDelay
+			}
+		}
+	}
+
+	c.recordFailure(err)
+	log.WithFields(log.Fields{
+		"attempts": c.backoff.MaxAttempts,
+		"error":    err,
+		"channel":  c.Name(),
+		"array_id": notification.Alert.ArrayID,
+		"alert_id": notification.Alert.AlertID,
+	}).Warn("Notification delivery failed, giving up")
+}
+
+// send is a helper function that makes a single delivery attempt, converting a panic in a channel into an error
+func (c *channel) send(notification *Notification) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Channel %s panicked: %v", c.Name(), r)
+		}
+	}()
+	return c.Send(notification)
+}
+
+func (c *channel) recordSuccess() {
+	c.statusLock.Lock()
+	defer c.statusLock.Unlock()
+	c.status.Sent++
+	c.status.LastSuccess = time.Now().UTC()
+}
+
+func (c *channel) recordFailure(err error) {
+	c.statusLock.Lock()
+	defer c.statusLock.Unlock()
+	c.status.Failed++
+	c.status.LastError = err.Error()
+	c.status.LastFailure = time.Now().UTC()
+}
+
+func (c *channel) getStatus() ChannelStatus {
+	c.statusLock.Lock()
+	defer c.statusLock.Unlock()
+	status := c.status
+	status.QueueLength = len(c.notifications)
+	return status
+}
+
+// getAlertKey is a helper function that identifies an alert across collection cycles. Rule and replication
+// alerts are kept apart from array alerts, since their IDs are generated independently (as in Elastic).
+func getAlertKey(alert *metrics.Alert) string {
+	return alert.GetDocumentID()
+}
+
+// getLastActivity is a helper function that gets when the alert was last created or updated (Unix seconds)
+func getLastActivity(alert *metrics.Alert) int64 {
+	if alert.Updated > alert.Created {
+		return alert.Updated
+	}
+	return alert.Created
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	copied := make(map[string]string, len(tags))
+	for key, value := range tags {
+		copied[key] = value
+	}
+	return copied
+}
+
+// isHeldBack returns whether notifications for an alert with the given lifecycle are held back at the given time,
+// because it's acknowledged or snoozed
+func isHeldBack(lifecycle *metrics.AlertLifecycle, now time.Time) bool {
+	if lifecycle == nil {
+		return false
+	}
+	return lifecycle.Acknowledged || lifecycle.SnoozedUntil > now.Unix()
+}
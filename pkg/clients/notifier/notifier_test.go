@@ -0,0 +1,339 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	clientmock "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// recordingChannel records the notifications sent to it, failing the first failures attempts
+type recordingChannel struct {
+	lock          sync.Mutex
+	failures      int
+	attempts      int
+	notifications []*Notification
+}
+
+func (r *recordingChannel) Name() string {
+	return "recording"
+}
+
+func (r *recordingChannel) Send(notification *Notification) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.attempts++
+	if r.attempts <= r.failures {
+		return fmt.Errorf("attempt %d failed", r.attempts)
+	}
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+func (r *recordingChannel) getNotifications() []*Notification {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]*Notification{}, r.notifications...)
+}
+
+// waitForStatus polls the statuses until the check passes, failing the test after a second
+func waitForStatus(t *testing.T, notifier *Notifier, check func(status ChannelStatus) bool) ChannelStatus {
+	for i := 0; i < 100; i++ {
+		status := notifier.Statuses()[0]
+		if check(status) {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, "Timed out waiting for channel status")
+	return notifier.Statuses()[0]
+}
+
+func newTestAlert(alertID uint64, state string, severity string) *metrics.Alert {
+	alert := &metrics.Alert{
+		AlertID:          alertID,
+		ArrayID:          "array-1",
+		ArrayDisplayName: "Array 1",
+		Created:          time.Now().Unix(),
+		Severity:         severity,
+		State:            state,
+		Summary:          "Something happened",
+		Source:           metrics.AlertSourceArray,
+	}
+	alert.PopulateSeverityIndex()
+	return alert
+}
+
+func TestNotifierDeduplicatesAlerts(t *testing.T) {
+	notifier := NewNotifier(Filter{}, nil, nil, nil, Backoff{}, 10)
+
+	notifications := notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "warning")}, time.Now())
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, EventNew, notifications[0].Event)
+
+	// Pushed again by the next collection cycle, with a different update time
+	repeated := newTestAlert(1, "open", "warning")
+	repeated.Updated = time.Now().Unix() + 60
+	notifications = notifier.getNotifications([]*metrics.Alert{repeated}, time.Now())
+	assert.Empty(t, notifications)
+
+	// Same alert ID on another array is a different alert
+	other := newTestAlert(1, "open", "warning")
+	other.ArrayID = "array-2"
+	notifications = notifier.getNotifications([]*metrics.Alert{other}, time.Now())
+	assert.Len(t, notifications, 1)
+}
+
+func TestNotifierNotifiesChangedAlerts(t *testing.T) {
+	notifier := NewNotifier(Filter{}, nil, nil, nil, Backoff{}, 10)
+	notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "warning")}, time.Now())
+
+	notifications := notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "critical")}, time.Now())
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, EventChanged, notifications[0].Event)
+	assert.Equal(t, "warning", notifications[0].PreviousSeverity)
+
+	notifications = notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "closed", "critical")}, time.Now())
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, EventChanged, notifications[0].Event)
+	assert.Equal(t, "open", notifications[0].PreviousState)
+}
+
+func TestNotifierSkipsAlertsFromBeforeStartup(t *testing.T) {
+	notifier := NewNotifier(Filter{}, nil, nil, nil, Backoff{}, 10)
+
+	old := newTestAlert(1, "open", "warning")
+	old.Created = notifier.started - 3600
+	notifications := notifier.getNotifications([]*metrics.Alert{old}, time.Now())
+	assert.Empty(t, notifications)
+
+	// But changes to it are still notified
+	closed := newTestAlert(1, "closed", "warning")
+	closed.Created = old.Created
+	notifications = notifier.getNotifications([]*metrics.Alert{closed}, time.Now())
+	assert.Len(t, notifications, 1)
+
+	// As are old alerts that were updated since
+	updated := newTestAlert(2, "open", "warning")
+	updated.Created = notifier.started - 3600
+	updated.Updated = notifier.started
+	notifications = notifier.getNotifications([]*metrics.Alert{updated}, time.Now())
+	assert.Len(t, notifications, 1)
+}
+
+func TestNotifierSuppressesAlertsInMaintenance(t *testing.T) {
+	notifier := NewNotifier(Filter{}, nil, nil, nil, Backoff{}, 10)
+
+	alert := newTestAlert(1, "open", "warning")
+	alert.InMaintenance = true
+	notifications := notifier.getNotifications([]*metrics.Alert{alert}, time.Now())
+	assert.Empty(t, notifications)
+
+	// Still open once the maintenance is over, so it's notified then, and only then
+	alert = newTestAlert(1, "open", "warning")
+	notifications = notifier.getNotifications([]*metrics.Alert{alert}, time.Now())
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, EventNew, notifications[0].Event)
+	}
+	notifications = notifier.getNotifications([]*metrics.Alert{alert}, time.Now())
+	assert.Empty(t, notifications)
+
+	// A notified alert that changes during maintenance is notified of the change once it's over
+	alert = newTestAlert(1, "open", "critical")
+	alert.InMaintenance = true
+	notifications = notifier.getNotifications([]*metrics.Alert{alert}, time.Now())
+	assert.Empty(t, notifications)
+	alert = newTestAlert(1, "open", "critical")
+	notifications = notifier.getNotifications([]*metrics.Alert{alert}, time.Now())
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, EventChanged, notifications[0].Event)
+		assert.Equal(t, "warning", notifications[0].PreviousSeverity)
+	}
+}
+
+func TestNotifierFiltersBySeverityAndState(t *testing.T) {
+	notifier := NewNotifier(Filter{MinSeverityIndex: 2, States: []string{"Open"}}, nil, nil, nil, Backoff{}, 10)
+
+	notifications := notifier.getNotifications([]*metrics.Alert{
+		newTestAlert(1, "open", "info"),
+		newTestAlert(2, "open", "warning"),
+		newTestAlert(3, "open", "critical"),
+		newTestAlert(4, "closed", "critical"),
+	}, time.Now())
+	if assert.Len(t, notifications, 2) {
+		assert.Equal(t, uint64(2), notifications[0].Alert.AlertID)
+		assert.Equal(t, uint64(3), notifications[1].Alert.AlertID)
+	}
+
+	// Closing a notified alert doesn't match the state filter either
+	notifications = notifier.getNotifications([]*metrics.Alert{newTestAlert(2, "closed", "warning")}, time.Now())
+	assert.Empty(t, notifications)
+}
+
+func TestNotifierFiltersByArrayTags(t *testing.T) {
+	notifier := NewNotifier(Filter{ArrayTags: map[string]string{"site": "dc1"}}, nil, nil, nil, Backoff{}, 10)
+
+	// Nothing is known about the array yet, so the alert is left until it is
+	notifications := notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "warning")}, time.Now())
+	assert.Empty(t, notifications)
+
+	notifier.AddArrayMetrics([]*metrics.ArrayMetric{{ArrayID: "array-1", Tags: map[string]string{"site": "dc1"}}})
+	notifier.AddArrayMetrics([]*metrics.ArrayMetric{{ArrayID: "array-2", Tags: map[string]string{"site": "dc2"}}})
+
+	other := newTestAlert(2, "open", "warning")
+	other.ArrayID = "array-2"
+	notifications = notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "warning"), other}, time.Now())
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, "array-1", notifications[0].Alert.ArrayID)
+		assert.Equal(t, map[string]string{"site": "dc1"}, notifications[0].ArrayTags)
+	}
+}
+
+func TestNotifierFetchesTagsOfArraysWithoutMetrics(t *testing.T) {
+	tagDiscovery := &clientmock.ArrayTagDiscoveryImpl{}
+	notifier := NewNotifier(Filter{ArrayTags: map[string]string{"site": "dc1"}}, nil, tagDiscovery, nil, Backoff{}, 10)
+	now := time.Now()
+
+	tagDiscovery.On("GetAllTags").Return(map[string]map[string]string{"array-1": {"site": "dc1"}}, nil).Once()
+	notifications := notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "warning")}, now)
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, map[string]string{"site": "dc1"}, notifications[0].ArrayTags)
+	}
+
+	// Arrays still unknown to the API server don't cause a fetch every time
+	other := newTestAlert(1, "open", "warning")
+	other.ArrayID = "array-2"
+	notifications = notifier.getNotifications([]*metrics.Alert{other}, now.Add(time.Second))
+	assert.Empty(t, notifications)
+	tagDiscovery.AssertNumberOfCalls(t, "GetAllTags", 1)
+}
+
+func TestNotifierForgetsOldAlerts(t *testing.T) {
+	notifier := NewNotifier(Filter{}, nil, nil, nil, Backoff{}, 10)
+	now := time.Now()
+	notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "warning")}, now)
+	assert.Len(t, notifier.notified, 1)
+
+	notifier.getNotifications([]*metrics.Alert{}, now.Add(forgetPeriod+time.Hour))
+	assert.Empty(t, notifier.notified)
+}
+
+func TestNotifierDeliversToAllChannels(t *testing.T) {
+	first := &recordingChannel{}
+	second := &recordingChannel{}
+	notifier := NewNotifier(Filter{}, nil, nil, []Channel{first, second}, Backoff{MaxAttempts: 1}, 10)
+	defer notifier.Close()
+
+	err := notifier.UpdateAlerts([]*metrics.Alert{newTestAlert(1, "open", "warning")})
+	assert.NoError(t, err)
+
+	waitForStatus(t, notifier, func(status ChannelStatus) bool { return status.Sent == 1 })
+	for i := 0; i < 100 && len(second.getNotifications()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Len(t, first.getNotifications(), 1)
+	assert.Len(t, second.getNotifications(), 1)
+}
+
+func TestNotifierRetriesWithBackoff(t *testing.T) {
+	channel := &recordingChannel{failures: 2}
+	notifier := NewNotifier(Filter{}, nil, nil, []Channel{channel}, Backoff{MaxAttempts: 3, InitialDelay: 10 * time.Millisecond, MaxDelay: 15 * time.Millisecond}, 10)
+	defer notifier.Close()
+
+	start := time.Now()
+	notifier.UpdateAlerts([]*metrics.Alert{newTestAlert(1, "open", "warning")})
+
+	status := waitForStatus(t, notifier, func(status ChannelStatus) bool { return status.Sent == 1 })
+	assert.Equal(t, uint64(0), status.Failed)
+	assert.Equal(t, 3, channel.attempts)
+	// 10ms, then 15ms (capped rather than 20ms)
+	assert.True(t, time.Since(start) >= 25*time.Millisecond)
+}
+
+func TestNotifierGivesUpAfterMaxAttempts(t *testing.T) {
+	channel := &recordingChannel{failures: 5}
+	notifier := NewNotifier(Filter{}, nil, nil, []Channel{channel}, Backoff{MaxAttempts: 2, InitialDelay: time.Millisecond}, 10)
+	defer notifier.Close()
+
+	notifier.UpdateAlerts([]*metrics.Alert{newTestAlert(1, "open", "warning")})
+
+	status := waitForStatus(t, notifier, func(status ChannelStatus) bool { return status.Failed == 1 })
+	assert.Equal(t, uint64(0), status.Sent)
+	assert.Equal(t, "attempt 2 failed", status.LastError)
+	assert.Empty(t, channel.getNotifications())
+}
+
+func TestNotifierHoldsBackSnoozedAlerts(t *testing.T) {
+	alerts := &clientmock.AlertDatabaseImpl{}
+	notifier := NewNotifier(Filter{}, alerts, nil, nil, Backoff{}, 10)
+	now := time.Now()
+	alerts.On("FindAlerts", mock.Anything).Return([]*metrics.Alert{}, nil).Once()
+	notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "warning")}, now)
+
+	// Escalated while snoozed, so held back until the snooze ends
+	snoozed := newTestAlert(1, "open", "critical")
+	snoozed.Lifecycle = &metrics.AlertLifecycle{SnoozedUntil: now.Add(time.Hour).Unix()}
+	query := &resources.AlertQuery{IDs: []string{"array-1-alert-1"}, Limit: 1}
+	alerts.On("FindAlerts", query).Return([]*metrics.Alert{snoozed}, nil)
+	notifications := notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "critical")}, now)
+	assert.Empty(t, notifications)
+	notifications = notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "critical")}, now.Add(30*time.Minute))
+	assert.Empty(t, notifications)
+
+	notifications = notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "critical")}, now.Add(2*time.Hour))
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, EventChanged, notifications[0].Event)
+		assert.Equal(t, "warning", notifications[0].PreviousSeverity)
+	}
+	notifications = notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "critical")}, now.Add(3*time.Hour))
+	assert.Empty(t, notifications)
+}
+
+func TestNotifierHoldsBackAcknowledgedAlerts(t *testing.T) {
+	alerts := &clientmock.AlertDatabaseImpl{}
+	notifier := NewNotifier(Filter{}, alerts, nil, nil, Backoff{}, 10)
+
+	acknowledged := newTestAlert(1, "open", "warning")
+	acknowledged.Lifecycle = &metrics.AlertLifecycle{Acknowledged: true}
+	alerts.On("FindAlerts", mock.Anything).Return([]*metrics.Alert{acknowledged}, nil).Once()
+	notifications := notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "warning"), newTestAlert(2, "open", "warning")}, time.Now())
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, uint64(2), notifications[0].Alert.AlertID)
+	}
+
+	// Notified as new once it's unacknowledged, as it never was before
+	alerts.On("FindAlerts", mock.Anything).Return([]*metrics.Alert{newTestAlert(1, "open", "warning")}, nil).Once()
+	notifications = notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "warning")}, time.Now())
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, EventNew, notifications[0].Event)
+	}
+}
+
+func TestNotifierNotifiesWhenLifecyclesCantBeLookedUp(t *testing.T) {
+	alerts := &clientmock.AlertDatabaseImpl{}
+	notifier := NewNotifier(Filter{}, alerts, nil, nil, Backoff{}, 10)
+
+	alerts.On("FindAlerts", mock.Anything).Return([]*metrics.Alert{}, fmt.Errorf("Elastic is down"))
+	notifications := notifier.getNotifications([]*metrics.Alert{newTestAlert(1, "open", "warning")}, time.Now())
+	assert.Len(t, notifications, 1)
+}
@@ -0,0 +1,99 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Type guard: ensure this implements the interface
+var _ Channel = (*SMTPChannel)(nil)
+
+// NewSMTPChannel creates a channel that emails notifications to the given recipients through the SMTP
+// server at the given address (host:port). STARTTLS is used if the server supports it. If a username is
+// given, PLAIN authentication is used, which requires TLS (or a server on localhost).
+func NewSMTPChannel(address string, username string, password string, from string, to []string, subject *template.Template, body *template.Template) (*SMTPChannel, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid SMTP server address %s: %v", address, err)
+	}
+	if len(from) == 0 {
+		return nil, fmt.Errorf("An SMTP sender address is required")
+	}
+	if len(to) == 0 {
+		return nil, fmt.Errorf("At least one SMTP recipient address is required")
+	}
+
+	channel := &SMTPChannel{
+		address:  address,
+		from:     from,
+		to:       to,
+		subject:  subject,
+		body:     body,
+		sendMail: smtp.SendMail,
+	}
+	if len(username) > 0 {
+		channel.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return channel, nil
+}
+
+// Name gets the name of this channel
+func (s *SMTPChannel) Name() string {
+	return "smtp"
+}
+
+// Send emails the given notification
+func (s *SMTPChannel) Send(notification *Notification) error {
+	message, err := s.buildMessage(notification, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.sendMail(s.address, s.auth, s.from, s.to, message)
+}
+
+// buildMessage is a helper function that renders the notification as a plain text email
+func (s *SMTPChannel) buildMessage(notification *Notification, now time.Time) ([]byte, error) {
+	subject, err := render(s.subject, notification)
+	if err != nil {
+		return nil, fmt.Errorf("Error rendering email subject: %v", err)
+	}
+	body, err := render(s.body, notification)
+	if err != nil {
+		return nil, fmt.Errorf("Error rendering email body: %v", err)
+	}
+
+	// Header values can't contain line breaks
+	subject = strings.Join(strings.Fields(subject), " ")
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", s.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.Replace(strings.Replace(body, "\r\n", "\n", -1), "\n", "\r\n", -1))
+	return message.Bytes(), nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/snmp"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guards: ensure this implements the interfaces
+var _ Channel = (*SNMPChannel)(nil)
+var _ resources.SNMPTestTrapSender = (*SNMPChannel)(nil)
+
+// NewSNMPChannel creates a channel that sends traps to the SNMP destinations from the given discovery, which
+// may be nil if the channel is only used to send test traps. Destinations aren't fetched until RefreshDestinations
+// is called (or Run is started).
+func NewSNMPChannel(discovery resources.SNMPDestinationDiscovery, timeout time.Duration) *SNMPChannel {
+	return &SNMPChannel{
+		discovery:    discovery,
+		sender:       snmp.NewSender(timeout),
+		destinations: []*resources.SNMPDestination{},
+	}
+}
+
+// Run refreshes the destinations every period, forever
+func (s *SNMPChannel) Run(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		err := s.RefreshDestinations()
+		if err != nil {
+			log.WithError(err).Warn("Failed to refresh SNMP destinations, will try again later")
+		}
+		<-ticker.C
+	}
+}
+
+// RefreshDestinations fetches the current destinations, keeping the enabled ones
+func (s *SNMPChannel) RefreshDestinations() error {
+	destinations, err := s.discovery.GetSNMPDestinations()
+	if err != nil {
+		return err
+	}
+
+	enabled := []*resources.SNMPDestination{}
+	for _, destination := range destinations {
+		if destination.Enabled {
+			enabled = append(enabled, destination)
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.destinations = enabled
+	return nil
+}
+
+// Name gets the name of this channel
+func (s *SNMPChannel) Name() string {
+	return "snmp"
+}
+
+// Send sends a trap for the given notification to every destination. New and closed alerts are always sent,
+// but changed alerts are only sent if they were closed, reopened or escalated to a higher severity. An error
+// is only returned if the trap couldn't be sent to any destination, so a retry doesn't duplicate the trap
+// at the destinations that did get it.
+func (s *SNMPChannel) Send(notification *Notification) error {
+	trap := getAlertTrap(notification)
+	if trap == nil {
+		return nil
+	}
+
+	s.lock.Lock()
+	destinations := s.destinations
+	s.lock.Unlock()
+
+	errs := []string{}
+	for _, destination := range destinations {
+		err := s.sendTrap(destination, trap)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":          err,
+				"destination_id": destination.InternalID,
+				"alert_id":       notification.Alert.AlertID,
+				"array_id":       notification.Alert.ArrayID,
+			}).Warn("Error sending SNMP trap")
+			errs = append(errs, fmt.Sprintf("%s: %v", destination.Name, err))
+		}
+	}
+	if len(errs) > 0 && len(errs) == len(destinations) {
+		return fmt.Errorf("Error sending SNMP traps: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// SendTestTrap sends a test trap to the given destination, whether it's enabled or not
+func (s *SNMPChannel) SendTestTrap(destination *resources.SNMPDestination) error {
+	return s.sendTrap(destination, snmp.NewTestTrap(destination.Name))
+}
+
+// sendTrap is a helper function that sends the given trap to a single destination
+func (s *SNMPChannel) sendTrap(destination *resources.SNMPDestination, trap *snmp.Trap) error {
+	target, err := destination.ConvertToSNMPTarget()
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(target, trap)
+}
+
+// getAlertTrap is a helper function that creates the trap to send for the given notification, or returns
+// nil if the change isn't one that traps are sent for
+func getAlertTrap(notification *Notification) *snmp.Trap {
+	alert := notification.Alert
+	closed := strings.EqualFold(alert.State, "closed")
+
+	if notification.Event == EventNew {
+		if closed {
+			return snmp.NewAlertTrap(snmp.AlertClosedNotificationOID, alert, "")
+		}
+		return snmp.NewAlertTrap(snmp.AlertNewNotificationOID, alert, "")
+	}
+
+	wasClosed := strings.EqualFold(notification.PreviousState, "closed")
+	if closed && !wasClosed {
+		return snmp.NewAlertTrap(snmp.AlertClosedNotificationOID, alert, "")
+	}
+	if !closed && wasClosed {
+		// A reopened alert is treated like a new one
+		return snmp.NewAlertTrap(snmp.AlertNewNotificationOID, alert, "")
+	}
+	if !closed && metrics.GetSeverityIndex(alert.Severity) > metrics.GetSeverityIndex(notification.PreviousSeverity) {
+		return snmp.NewAlertTrap(snmp.AlertEscalatedNotificationOID, alert, notification.PreviousSeverity)
+	}
+	return nil
+}
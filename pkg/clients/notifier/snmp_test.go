@@ -0,0 +1,115 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/snmp"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAlertTrap(t *testing.T) {
+	notification := newTestNotification()
+	assert.Equal(t, snmp.AlertNewNotificationOID, getAlertTrap(notification).OID)
+
+	notification.Alert.State = "closed"
+	assert.Equal(t, snmp.AlertClosedNotificationOID, getAlertTrap(notification).OID)
+
+	// Closed after being open
+	notification.Event = EventChanged
+	notification.PreviousState = "open"
+	notification.PreviousSeverity = "critical"
+	assert.Equal(t, snmp.AlertClosedNotificationOID, getAlertTrap(notification).OID)
+
+	// Reopened
+	notification.Alert.State = "open"
+	notification.PreviousState = "closed"
+	assert.Equal(t, snmp.AlertNewNotificationOID, getAlertTrap(notification).OID)
+
+	// Escalated
+	notification.PreviousState = "open"
+	notification.PreviousSeverity = "warning"
+	trap := getAlertTrap(notification)
+	if assert.NotNil(t, trap) {
+		assert.Equal(t, snmp.AlertEscalatedNotificationOID, trap.OID)
+	}
+
+	// De-escalated, which isn't sent
+	notification.Alert.Severity = "info"
+	assert.Nil(t, getAlertTrap(notification))
+}
+
+func TestSNMPChannelSendsToEnabledDestinations(t *testing.T) {
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer connection.Close()
+
+	discovery := &mock.SNMPDestinationDiscoveryImpl{}
+	discovery.On("GetSNMPDestinations").Return([]*resources.SNMPDestination{
+		&resources.SNMPDestination{InternalID: "aaaa", Name: "NOC", Enabled: true, Address: connection.LocalAddr().String(), Version: snmp.Version2c, Community: "enabled-community"},
+		&resources.SNMPDestination{InternalID: "aaab", Name: "Lab", Enabled: false, Address: connection.LocalAddr().String(), Version: snmp.Version2c, Community: "disabled-community"},
+	}, nil)
+
+	channel := NewSNMPChannel(discovery, time.Second)
+	assert.NoError(t, channel.RefreshDestinations())
+	assert.NoError(t, channel.Send(newTestNotification()))
+
+	buffer := make([]byte, 65535)
+	connection.SetReadDeadline(time.Now().Add(time.Second))
+	length, _, err := connection.ReadFrom(buffer)
+	if assert.NoError(t, err) {
+		assert.True(t, bytes.Contains(buffer[:length], []byte("enabled-community")))
+		assert.True(t, bytes.Contains(buffer[:length], []byte("Controller\nfailed")))
+	}
+
+	// Only the enabled destination should have been sent a trap
+	connection.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err = connection.ReadFrom(buffer)
+	assert.Error(t, err)
+}
+
+func TestSNMPChannelErrorsWhenEveryDestinationFails(t *testing.T) {
+	discovery := &mock.SNMPDestinationDiscoveryImpl{}
+	discovery.On("GetSNMPDestinations").Return([]*resources.SNMPDestination{
+		&resources.SNMPDestination{InternalID: "aaaa", Name: "NOC", Enabled: true, Address: "127.0.0.1:162", Version: snmp.Version3, EngineID: "not hex", Username: "pure1"},
+	}, nil)
+
+	channel := NewSNMPChannel(discovery, time.Second)
+	assert.NoError(t, channel.RefreshDestinations())
+	assert.Error(t, channel.Send(newTestNotification()))
+}
+
+func TestSNMPChannelSendTestTrap(t *testing.T) {
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer connection.Close()
+
+	channel := NewSNMPChannel(nil, time.Second)
+	err = channel.SendTestTrap(&resources.SNMPDestination{Name: "NOC", Address: connection.LocalAddr().String(), Version: snmp.Version2c, Community: "public"})
+	assert.NoError(t, err)
+
+	buffer := make([]byte, 65535)
+	connection.SetReadDeadline(time.Now().Add(time.Second))
+	length, _, err := connection.ReadFrom(buffer)
+	if assert.NoError(t, err) {
+		assert.True(t, bytes.Contains(buffer[:length], []byte("SNMP destination NOC")))
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+// Type guard: ensure this implements the interface
+var _ Channel = (*SyslogChannel)(nil)
+
+const (
+	// Structured data ID for alert details, under the Pure Storage private enterprise number
+	syslogStructuredDataID = "alert@40482"
+	syslogMessageID        = "alert"
+	// The message is always UTF-8, which RFC 5424 says to mark with a byte order mark
+	syslogBOM = "\ufeff"
+	// Syslog severities (RFC 5424 section 6.2.1)
+	syslogCritical = 2
+	syslogWarning  = 4
+	syslogNotice   = 5
+	syslogInfo     = 6
+)
+
+// NewSyslogChannel creates a channel that sends notifications as RFC 5424 messages to the syslog server at the
+// given address (host:port) over the given network ("udp" or "tcp", which uses octet counting framing). The
+// message template is rendered into the message part, and the alert details are sent as structured data.
+func NewSyslogChannel(network string, address string, facility int, appName string, message *template.Template, timeout time.Duration) (*SyslogChannel, error) {
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("Syslog network must be udp or tcp, not %s", network)
+	}
+	if facility < 0 || facility > 23 {
+		return nil, fmt.Errorf("Syslog facility must be between 0 and 23, not %d", facility)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || len(hostname) == 0 {
+		hostname = "-"
+	}
+	return &SyslogChannel{
+		network:  network,
+		address:  address,
+		facility: facility,
+		hostname: hostname,
+		appName:  appName,
+		message:  message,
+		timeout:  timeout,
+	}, nil
+}
+
+// Name gets the name of this channel
+func (s *SyslogChannel) Name() string {
+	return "syslog"
+}
+
+// Send sends the given notification to the syslog server
+func (s *SyslogChannel) Send(notification *Notification) error {
+	message, err := s.formatMessage(notification, time.Now())
+	if err != nil {
+		return err
+	}
+	if s.network == "tcp" {
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+
+	connection, err := net.DialTimeout(s.network, s.address, s.timeout)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	err = connection.SetWriteDeadline(time.Now().Add(s.timeout))
+	if err != nil {
+		return err
+	}
+	_, err = connection.Write([]byte(message))
+	return err
+}
+
+// formatMessage is a helper function that renders the notification as an RFC 5424 message (without framing)
+func (s *SyslogChannel) formatMessage(notification *Notification, now time.Time) (string, error) {
+	message, err := render(s.message, notification)
+	if err != nil {
+		return "", fmt.Errorf("Error rendering syslog message: %v", err)
+	}
+	// Keep each notification to a single line, since plenty of receivers split on line breaks
+	message = strings.Join(strings.Fields(message), " ")
+
+	alert := notification.Alert
+	priority := s.facility*8 + getSyslogSeverity(alert)
+	structuredData := fmt.Sprintf(`[%s event="%s" array_id="%s" array_name="%s" alert_id="%d" severity="%s" state="%s" source="%s"]`,
+		syslogStructuredDataID,
+		escapeSyslogParam(notification.Event),
+		escapeSyslogParam(alert.ArrayID),
+		escapeSyslogParam(alert.ArrayName),
+		alert.AlertID,
+		escapeSyslogParam(alert.Severity),
+		escapeSyslogParam(alert.State),
+		escapeSyslogParam(alert.Source))
+
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA BOM MSG
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s%s",
+		priority,
+		now.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		getSyslogHeaderField(s.hostname, 255),
+		getSyslogHeaderField(s.appName, 48),
+		os.Getpid(),
+		syslogMessageID,
+		structuredData,
+		syslogBOM,
+		message), nil
+}
+
+// getSyslogSeverity is a helper function that maps the alert severity to a syslog severity. Closed
+// alerts are always sent as notices.
+func getSyslogSeverity(alert *metrics.Alert) int {
+	if strings.EqualFold(alert.State, "closed") {
+		return syslogNotice
+	}
+	switch strings.ToLower(alert.Severity) {
+	case "critical":
+		return syslogCritical
+	case "warning":
+		return syslogWarning
+	case "info":
+		return syslogInfo
+	default:
+		return syslogNotice
+	}
+}
+
+// getSyslogHeaderField is a helper function that makes the value safe to use as a header field: printable
+// ASCII without spaces, no longer than the given length, or "-" if empty
+func getSyslogHeaderField(value string, maxLength int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if len(field) > maxLength {
+		field = field[:maxLength]
+	}
+	if len(field) == 0 {
+		return "-"
+	}
+	return field
+}
+
+// escapeSyslogParam is a helper function that escapes a structured data parameter value
+func escapeSyslogParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
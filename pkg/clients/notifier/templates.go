@@ -0,0 +1,69 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default templates, used when no custom template is configured. Templates are executed with a *Notification.
+const (
+	DefaultSubjectTemplate = `[Pure1 Unplugged] {{upper .Alert.Severity}} alert on {{.Alert.ArrayDisplayName}}: {{.Alert.Summary}} ({{.Alert.State}})`
+	DefaultMessageTemplate = `{{upper .Alert.Severity}} alert {{.Alert.AlertID}} on {{.Alert.ArrayDisplayName}} is {{.Alert.State}}: {{.Alert.Summary}}`
+	DefaultBodyTemplate    = `{{if eq .Event "new"}}A new alert was raised{{else}}An alert changed{{end}} on array {{.Alert.ArrayDisplayName}} ({{.Alert.ArrayName}}).
+
+Summary:   {{.Alert.Summary}}
+Severity:  {{.Alert.Severity}}{{if and .PreviousSeverity (ne .PreviousSeverity .Alert.Severity)}} (was {{.PreviousSeverity}}){{end}}
+State:     {{.Alert.State}}{{if and .PreviousState (ne .PreviousState .Alert.State)}} (was {{.PreviousState}}){{end}}
+Alert ID:  {{.Alert.AlertID}}
+Component: {{.Alert.Component}}
+Created:   {{formatTime .Alert.Created}}
+{{if .Alert.Description}}
+{{.Alert.Description}}
+{{end}}{{if .Alert.Action}}
+Suggested action: {{.Alert.Action}}
+{{end}}`
+)
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// formatTime formats Unix seconds as RFC 3339 in UTC
+	"formatTime": func(unix int64) string {
+		return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+	},
+}
+
+// ParseTemplate parses a notification template, falling back to the given default if text is empty.
+// Besides the standard functions, templates can use upper, lower and formatTime (for Unix timestamps).
+func ParseTemplate(name string, text string, defaultText string) (*template.Template, error) {
+	if len(strings.TrimSpace(text)) == 0 {
+		text = defaultText
+	}
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+// render is a helper function that executes the template for the given notification
+func render(t *template.Template, notification *Notification) (string, error) {
+	var buffer bytes.Buffer
+	err := t.Execute(&buffer, notification)
+	if err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"net/smtp"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/snmp"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/go-resty/resty"
+)
+
+// Notifier is a metrics.Database that delivers notifications for new and changed alerts to any number of
+// channels (email, webhooks, syslog). Alerts are deduplicated by array and alert ID, so an alert that's
+// pushed again by every collection cycle is only notified when it first appears and whenever its state or
+// severity changes. Changes to alerts marked as in maintenance, snoozed or acknowledged are held back until
+// they no longer are, so an alert that's still open after a maintenance window is notified once it's over.
+// Metric writes are only used to learn the tags of each array, and cleanups are ignored.
+// Deliveries are queued per channel and retried with exponential backoff, so they never block writes.
+type Notifier struct {
+	filter       Filter
+	alerts       resources.AlertDatabase     // For looking up alert lifecycles, may be nil
+	tagDiscovery resources.ArrayTagDiscovery // For looking up the tags of arrays without metrics, may be nil
+	channels     []*channel
+	started      int64 // Unix seconds
+
+	lock          sync.Mutex
+	notified      map[string]*notifiedAlert    // Keyed by getAlertKey
+	arrayTags     map[string]map[string]string // Keyed by array ID, from the latest metrics or the API server
+	tagsRefreshed time.Time
+	lastPrune     time.Time
+}
+
+// Filter selects the alerts to notify about. Empty fields match every alert.
+type Filter struct {
+	MinSeverityIndex byte              // See metrics.Alert.PopulateSeverityIndex
+	States           []string          // Alert states to notify about, such as "open" or "closed"
+	ArrayTags        map[string]string // Tags the alert's array must have (all of them)
+}
+
+// Backoff controls how failed deliveries are retried: the delay starts at InitialDelay and doubles
+// after every failed attempt, up to MaxDelay
+type Backoff struct {
+	MaxAttempts  int // Less than one is treated as one
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// Channel is a destination that notifications can be delivered to
+type Channel interface {
+	Name() string
+	Send(notification *Notification) error
+}
+
+// Events that cause a notification
+const (
+	EventNew     = "new"     // The alert hasn't been seen before
+	EventChanged = "changed" // The state or severity of the alert changed
+)
+
+// Notification is a single alert event to deliver, and the data message templates are executed with
+type Notification struct {
+	Event            string
+	Alert            *metrics.Alert
+	ArrayTags        map[string]string
+	PreviousState    string // Only set for changed alerts
+	PreviousSeverity string // Only set for changed alerts
+}
+
+// ChannelStatus records the outcome of deliveries to a single channel
+type ChannelStatus struct {
+	Name        string
+	Sent        uint64
+	Failed      uint64 // Notifications given up on after every attempt failed
+	Dropped     uint64 // Notifications discarded without being tried because the channel's queue was full
+	QueueLength int
+	LastError   string
+	LastSuccess time.Time
+	LastFailure time.Time
+}
+
+// notifiedAlert is the last version of an alert that was seen (or that was notified, while changes to it are held
+// back), used to detect changes
+type notifiedAlert struct {
+	state    string
+	severity string
+	lastSeen time.Time
+}
+
+type channel struct {
+	Channel
+	backoff       Backoff
+	notifications chan *Notification
+
+	statusLock sync.Mutex
+	status     ChannelStatus
+}
+
+// SMTPChannel delivers notifications as plain text emails
+type SMTPChannel struct {
+	address  string // host:port
+	auth     smtp.Auth
+	from     string
+	to       []string
+	subject  *template.Template
+	body     *template.Template
+	sendMail func(address string, auth smtp.Auth, from string, to []string, message []byte) error
+}
+
+// WebhookChannel delivers notifications by POSTing them to a URL as JSON
+type WebhookChannel struct {
+	url        string
+	message    *template.Template
+	restClient *resty.Client
+}
+
+// webhookPayload is the JSON document posted by a WebhookChannel
+type webhookPayload struct {
+	Event            string            `json:"event"`
+	Message          string            `json:"message"`
+	Alert            *metrics.Alert    `json:"alert"`
+	ArrayTags        map[string]string `json:"array_tags"`
+	PreviousState    string            `json:"previous_state,omitempty"`
+	PreviousSeverity string            `json:"previous_severity,omitempty"`
+}
+
+// SyslogChannel delivers notifications as RFC 5424 syslog messages over UDP or TCP
+type SyslogChannel struct {
+	network  string // "udp" or "tcp"
+	address  string // host:port
+	facility int
+	hostname string
+	appName  string
+	message  *template.Template
+	timeout  time.Duration
+}
+
+// SNMPChannel delivers notifications as SNMP traps to every enabled destination configured through the API server
+type SNMPChannel struct {
+	discovery resources.SNMPDestinationDiscovery
+	sender    *snmp.Sender
+
+	lock         sync.Mutex
+	destinations []*resources.SNMPDestination
+}
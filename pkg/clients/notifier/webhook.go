@@ -0,0 +1,70 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-resty/resty"
+)
+
+// Type guard: ensure this implements the interface
+var _ Channel = (*WebhookChannel)(nil)
+
+// NewWebhookChannel creates a channel that POSTs notifications as JSON to the given URL. The message
+// template is rendered into the "message" field, alongside the alert itself.
+func NewWebhookChannel(url string, message *template.Template, timeout time.Duration) *WebhookChannel {
+	return &WebhookChannel{
+		url:        url,
+		message:    message,
+		restClient: resty.New().SetTimeout(timeout),
+	}
+}
+
+// Name gets the name of this channel
+func (w *WebhookChannel) Name() string {
+	return "webhook"
+}
+
+// Send posts the given notification to the webhook
+func (w *WebhookChannel) Send(notification *Notification) error {
+	message, err := render(w.message, notification)
+	if err != nil {
+		return fmt.Errorf("Error rendering webhook message: %v", err)
+	}
+
+	payload := webhookPayload{
+		Event:            notification.Event,
+		Message:          message,
+		Alert:            notification.Alert,
+		ArrayTags:        notification.ArrayTags,
+		PreviousState:    notification.PreviousState,
+		PreviousSeverity: notification.PreviousSeverity,
+	}
+	response, err := w.restClient.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(payload).
+		Post(w.url)
+	if err != nil {
+		return err
+	}
+	if response.IsError() {
+		return fmt.Errorf("Error posting to webhook: %s %s", response.Status(), strings.TrimSpace(response.String()))
+	}
+	return nil
+}
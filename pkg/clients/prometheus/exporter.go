@@ -0,0 +1,356 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guards: ensure this implements the interfaces
+var _ metrics.Database = (*Exporter)(nil)
+var _ http.Handler = (*Exporter)(nil)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// NewExporter creates an empty Exporter. Anything that isn't refreshed within staleAfter
+// (such as a deleted volume or unregistered array) is dropped from the output.
+func NewExporter(staleAfter time.Duration) *Exporter {
+	return &Exporter{
+		staleAfter: staleAfter,
+		arrays:     map[string]*arrayEntry{},
+		volumes:    map[string]*volumeEntry{},
+		alerts:     map[string]*alertEntry{},
+	}
+}
+
+// AddArrayMetrics replaces the latest metric of each given array
+func (e *Exporter) AddArrayMetrics(arrayMetrics []*metrics.ArrayMetric) error {
+	now := time.Now()
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, metric := range arrayMetrics {
+		if metric == nil {
+			continue
+		}
+		existing, ok := e.arrays[metric.ArrayID]
+		if ok && existing.metric.CreatedAt > metric.CreatedAt {
+			continue
+		}
+		e.arrays[metric.ArrayID] = &arrayEntry{metric: metric, received: now}
+	}
+	return nil
+}
+
+// AddVolumeMetrics replaces the latest metric of each given volume. Volume metrics are
+// pushed as a time series, so only the newest point of each volume is kept.
+func (e *Exporter) AddVolumeMetrics(volumeMetrics []*metrics.VolumeMetric) error {
+	now := time.Now()
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, metric := range volumeMetrics {
+		if metric == nil {
+			continue
+		}
+		// File systems, buckets and accounts on a FlashBlade can share names
+		key := fmt.Sprintf("%s-volume-%s-%s", metric.ArrayID, metric.Type, metric.VolumeName)
+		existing, ok := e.volumes[key]
+		if ok && existing.metric.CreatedAt > metric.CreatedAt {
+			continue
+		}
+		e.volumes[key] = &volumeEntry{metric: metric, received: now}
+	}
+	return nil
+}
+
+// UpdateAlerts tracks the given alerts while they're open, and forgets them once they close
+func (e *Exporter) UpdateAlerts(alerts []*metrics.Alert) error {
+	now := time.Now()
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, alert := range alerts {
+		if alert == nil {
+			continue
+		}
+		key := fmt.Sprintf("%s-alert-%d", alert.ArrayID, alert.AlertID)
+		if alert.State != "open" {
+			delete(e.alerts, key)
+			continue
+		}
+		e.alerts[key] = &alertEntry{alert: alert, received: now}
+	}
+	return nil
+}
+
+// CleanArrayMetrics forgets array metrics that haven't been refreshed in the given number of days
+func (e *Exporter) CleanArrayMetrics(maxAgeInDays int) error {
+	cutoff := getCutoff(maxAgeInDays)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for key, entry := range e.arrays {
+		if entry.received.Before(cutoff) {
+			delete(e.arrays, key)
+		}
+	}
+	return nil
+}
+
+// CleanVolumeMetrics forgets volume metrics that haven't been refreshed in the given number of days
+func (e *Exporter) CleanVolumeMetrics(maxAgeInDays int) error {
+	cutoff := getCutoff(maxAgeInDays)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for key, entry := range e.volumes {
+		if entry.received.Before(cutoff) {
+			delete(e.volumes, key)
+		}
+	}
+	return nil
+}
+
+// CleanAlerts forgets alerts that haven't been refreshed in the given number of days
+func (e *Exporter) CleanAlerts(maxAgeInDays int) error {
+	cutoff := getCutoff(maxAgeInDays)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for key, entry := range e.alerts {
+		if entry.received.Before(cutoff) {
+			delete(e.alerts, key)
+		}
+	}
+	return nil
+}
+
+// CleanErrorLogs does nothing: logs aren't exported
+func (e *Exporter) CleanErrorLogs(maxAgeInDays int) error {
+	return nil
+}
+
+// CleanTimerLogs does nothing: logs aren't exported
+func (e *Exporter) CleanTimerLogs(maxAgeInDays int) error {
+	return nil
+}
+
+// ServeHTTP writes all current metrics in the Prometheus text exposition format
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buffer bytes.Buffer
+	e.WriteTo(&buffer)
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(buffer.Bytes()); err != nil {
+		log.WithError(err).Warn("Error writing Prometheus metrics response")
+	}
+}
+
+// WriteTo writes all current metrics in the Prometheus text exposition format to the given writer
+func (e *Exporter) WriteTo(w io.Writer) (int64, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	staleCutoff := time.Now().Add(-e.staleAfter)
+	var written int64
+
+	for _, gauge := range arrayGauges {
+		samples := []sample{}
+		for _, entry := range e.arrays {
+			if entry.received.Before(staleCutoff) {
+				continue
+			}
+			if value, ok := gauge.value(entry.metric); ok {
+				samples = append(samples, sample{labels: arrayLabels(entry.metric), value: value})
+			}
+		}
+		n, err := writeFamily(w, gauge.name, gauge.help, samples)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	for _, gauge := range volumeGauges {
+		samples := []sample{}
+		for _, entry := range e.volumes {
+			if entry.received.Before(staleCutoff) {
+				continue
+			}
+			if value, ok := gauge.value(entry.metric); ok {
+				samples = append(samples, sample{labels: e.volumeLabels(entry.metric), value: value})
+			}
+		}
+		n, err := writeFamily(w, gauge.name, gauge.help, samples)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	n, err := writeFamily(w, openAlertsName, openAlertsHelp, e.openAlertSamples(staleCutoff))
+	written += n
+	return written, err
+}
+
+// openAlertSamples is a helper function that counts the open alerts by array and severity
+func (e *Exporter) openAlertSamples(staleCutoff time.Time) []sample {
+	counts := map[string]*sample{}
+	for _, entry := range e.alerts {
+		if entry.received.Before(staleCutoff) {
+			continue
+		}
+		key := fmt.Sprintf("%s-%s", entry.alert.ArrayID, strings.ToLower(entry.alert.Severity))
+		if count, ok := counts[key]; ok {
+			count.value++
+			continue
+		}
+
+		labels := []label{
+			{"array_id", entry.alert.ArrayID},
+			{"array_name", entry.alert.ArrayName},
+			{"display_name", entry.alert.ArrayDisplayName},
+			{"severity", strings.ToLower(entry.alert.Severity)},
+		}
+		// Alerts don't carry the array type or tags, so borrow them from the latest array metric
+		if array, ok := e.arrays[entry.alert.ArrayID]; ok {
+			labels = append(labels, label{"array_type", array.metric.ArrayType})
+			labels = append(labels, tagLabels(array.metric.Tags)...)
+		}
+		counts[key] = &sample{labels: labels, value: 1}
+	}
+
+	samples := []sample{}
+	for _, count := range counts {
+		samples = append(samples, *count)
+	}
+	return samples
+}
+
+// volumeLabels is a helper function that builds the labels of a volume metric
+func (e *Exporter) volumeLabels(metric *metrics.VolumeMetric) []label {
+	labels := []label{
+		{"array_id", metric.ArrayID},
+		{"array_name", metric.ArrayName},
+		{"display_name", metric.ArrayDisplayName},
+		{"volume_name", metric.VolumeName},
+		{"volume_type", metric.Type},
+	}
+	// Volume metrics don't carry the array type, so borrow it from the latest array metric
+	if array, ok := e.arrays[metric.ArrayID]; ok {
+		labels = append(labels, label{"array_type", array.metric.ArrayType})
+	}
+	return append(labels, tagLabels(metric.ArrayTags)...)
+}
+
+// arrayLabels is a helper function that builds the labels of an array metric
+func arrayLabels(metric *metrics.ArrayMetric) []label {
+	labels := []label{
+		{"array_id", metric.ArrayID},
+		{"array_name", metric.ArrayName},
+		{"array_type", metric.ArrayType},
+		{"display_name", metric.DisplayName},
+	}
+	return append(labels, tagLabels(metric.Tags)...)
+}
+
+// tagLabels is a helper function that converts array tags to labels, prefixing each
+// key with "tag_" and replacing any characters Prometheus doesn't allow in label names
+func tagLabels(tags map[string]string) []label {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	labels := []label{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		name := "tag_" + sanitizeLabelName(key)
+		// Two keys may sanitize to the same name: keep the first so the output stays valid
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		labels = append(labels, label{name, tags[key]})
+	}
+	return labels
+}
+
+// sanitizeLabelName is a helper function that replaces any character that isn't allowed in
+// a label name with an underscore (leading digits are allowed, since tag names are prefixed)
+func sanitizeLabelName(name string) string {
+	sanitized := []rune(name)
+	for i, r := range sanitized {
+		if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_' || (r >= '0' && r <= '9')) {
+			sanitized[i] = '_'
+		}
+	}
+	return string(sanitized)
+}
+
+// writeFamily is a helper function that writes a metric family (if it has any samples), sorted
+// by labels so the output is stable between scrapes
+func writeFamily(w io.Writer, name string, help string, samples []sample) (int64, error) {
+	if len(samples) == 0 {
+		return 0, nil
+	}
+
+	lines := make([]string, 0, len(samples))
+	for _, s := range samples {
+		lines = append(lines, fmt.Sprintf("%s%s %s\n", name, formatLabels(s.labels), strconv.FormatFloat(s.value, 'g', -1, 64)))
+	}
+	sort.Strings(lines)
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(&buffer, "# TYPE %s gauge\n", name)
+	for _, line := range lines {
+		buffer.WriteString(line)
+	}
+	return buffer.WriteTo(w)
+}
+
+// formatLabels is a helper function that formats labels as {name="value",...}, escaping the values
+func formatLabels(labels []label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	formatted := make([]string, 0, len(labels))
+	for _, l := range labels {
+		formatted = append(formatted, fmt.Sprintf("%s=\"%s\"", l.name, labelValueEscaper.Replace(l.value)))
+	}
+	return "{" + strings.Join(formatted, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// getCutoff is a helper function that gets the time the given number of days ago
+func getCutoff(maxAgeInDays int) time.Time {
+	return time.Now().Add(-time.Duration(maxAgeInDays) * 24 * time.Hour)
+}
@@ -0,0 +1,165 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func testArrayMetric(createdAt int64, usedSpace uint64) *metrics.ArrayMetric {
+	return &metrics.ArrayMetric{
+		ArrayCapacityMetric: &metrics.ArrayCapacityMetric{UsedSpace: usedSpace},
+		ArrayID:             "000000000000000000000000",
+		ArrayName:           "array-1",
+		ArrayType:           "FlashArray",
+		CreatedAt:           createdAt,
+		DisplayName:         "Array 1",
+		Tags:                map[string]string{"site": "dc1", "cost-center": "a\"b"},
+	}
+}
+
+func writeToString(e *Exporter) string {
+	var buffer bytes.Buffer
+	e.WriteTo(&buffer)
+	return buffer.String()
+}
+
+func TestExporterArrayMetrics(t *testing.T) {
+	exporter := NewExporter(time.Hour)
+	assert.NoError(t, exporter.AddArrayMetrics([]*metrics.ArrayMetric{testArrayMetric(100, 1024)}))
+
+	output := writeToString(exporter)
+	assert.Contains(t, output, "# TYPE pure_array_used_space_bytes gauge\n")
+	assert.Contains(t, output, `pure_array_used_space_bytes{array_id="000000000000000000000000",array_name="array-1",array_type="FlashArray",display_name="Array 1",tag_cost_center="a\"b",tag_site="dc1"} 1024`)
+	// Performance wasn't collected, so it shouldn't be exported
+	assert.NotContains(t, output, "pure_array_read_iops")
+}
+
+func TestExporterKeepsLatestArrayMetric(t *testing.T) {
+	exporter := NewExporter(time.Hour)
+	exporter.AddArrayMetrics([]*metrics.ArrayMetric{testArrayMetric(200, 2048)})
+	exporter.AddArrayMetrics([]*metrics.ArrayMetric{testArrayMetric(100, 1024)})
+
+	output := writeToString(exporter)
+	assert.Contains(t, output, "} 2048\n")
+	assert.NotContains(t, output, "} 1024\n")
+}
+
+func TestExporterVolumeMetrics(t *testing.T) {
+	exporter := NewExporter(time.Hour)
+	exporter.AddArrayMetrics([]*metrics.ArrayMetric{testArrayMetric(100, 1024)})
+	exporter.AddVolumeMetrics([]*metrics.VolumeMetric{
+		{
+			VolumePerformanceMetric: &metrics.VolumePerformanceMetric{ReadIOPS: 10},
+			ArrayID:                 "000000000000000000000000",
+			ArrayName:               "array-1",
+			CreatedAt:               100,
+			Type:                    "Volume",
+			VolumeName:              "vol-1",
+		},
+		{
+			VolumePerformanceMetric: &metrics.VolumePerformanceMetric{ReadIOPS: 20},
+			ArrayID:                 "000000000000000000000000",
+			ArrayName:               "array-1",
+			CreatedAt:               130,
+			Type:                    "Volume",
+			VolumeName:              "vol-1",
+		},
+	})
+
+	output := writeToString(exporter)
+	assert.Contains(t, output, `pure_volume_read_iops{array_id="000000000000000000000000",array_name="array-1",display_name="",volume_name="vol-1",volume_type="Volume",array_type="FlashArray"} 20`)
+	assert.Equal(t, 1, strings.Count(output, "pure_volume_read_iops{"))
+}
+
+func TestExporterVolumeMetricsOfDifferentTypes(t *testing.T) {
+	exporter := NewExporter(time.Hour)
+	exporter.AddVolumeMetrics([]*metrics.VolumeMetric{
+		{
+			VolumePerformanceMetric: &metrics.VolumePerformanceMetric{ReadIOPS: 10},
+			ArrayID:                 "000000000000000000000000",
+			ArrayName:               "array-1",
+			CreatedAt:               100,
+			Type:                    metrics.VolumeMetricTypeFileSystem,
+			VolumeName:              "data",
+		},
+		{
+			VolumePerformanceMetric: &metrics.VolumePerformanceMetric{ReadIOPS: 20},
+			ArrayID:                 "000000000000000000000000",
+			ArrayName:               "array-1",
+			CreatedAt:               100,
+			Type:                    metrics.VolumeMetricTypeBucket,
+			VolumeName:              "data",
+		},
+	})
+
+	// A file system and a bucket can share a name, and are exported separately
+	output := writeToString(exporter)
+	assert.Contains(t, output, `volume_name="data",volume_type="FileSystem"`)
+	assert.Contains(t, output, `volume_name="data",volume_type="Bucket"`)
+	assert.Equal(t, 2, strings.Count(output, "pure_volume_read_iops{"))
+}
+
+func TestExporterOpenAlerts(t *testing.T) {
+	exporter := NewExporter(time.Hour)
+	exporter.UpdateAlerts([]*metrics.Alert{
+		{AlertID: 1, ArrayID: "a", ArrayName: "array-a", Severity: "Warning", State: "open"},
+		{AlertID: 2, ArrayID: "a", ArrayName: "array-a", Severity: "warning", State: "open"},
+		{AlertID: 3, ArrayID: "a", ArrayName: "array-a", Severity: "critical", State: "closed"},
+	})
+
+	output := writeToString(exporter)
+	assert.Contains(t, output, `pure_array_open_alerts{array_id="a",array_name="array-a",display_name="",severity="warning"} 2`)
+	assert.NotContains(t, output, `severity="critical"`)
+
+	// Closing an alert should remove it from the count
+	exporter.UpdateAlerts([]*metrics.Alert{{AlertID: 1, ArrayID: "a", ArrayName: "array-a", Severity: "warning", State: "closed"}})
+	assert.Contains(t, writeToString(exporter), `severity="warning"} 1`)
+}
+
+func TestExporterDropsStaleMetrics(t *testing.T) {
+	exporter := NewExporter(time.Hour)
+	exporter.AddArrayMetrics([]*metrics.ArrayMetric{testArrayMetric(100, 1024)})
+	exporter.arrays["000000000000000000000000"].received = time.Now().Add(-2 * time.Hour)
+
+	assert.Empty(t, writeToString(exporter))
+
+	assert.NoError(t, exporter.CleanArrayMetrics(0))
+	assert.Empty(t, exporter.arrays)
+}
+
+func TestExporterServeHTTP(t *testing.T) {
+	exporter := NewExporter(time.Hour)
+	exporter.AddArrayMetrics([]*metrics.ArrayMetric{testArrayMetric(100, 1024)})
+
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "pure_array_used_space_bytes{")
+}
+
+func TestSanitizeLabelName(t *testing.T) {
+	assert.Equal(t, "cost_center", sanitizeLabelName("cost-center"))
+	assert.Equal(t, "1st", sanitizeLabelName("1st"))
+	assert.Equal(t, "a_b_c", sanitizeLabelName("a.b/c"))
+}
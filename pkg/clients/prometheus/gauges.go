@@ -0,0 +1,126 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+
+// arrayGauges lists every metric family exported for each array
+var arrayGauges = []arrayGauge{
+	{"pure_array_data_reduction_ratio", "Data reduction ratio of the array", arrayCapacity(func(m *metrics.ArrayCapacityMetric) float64 { return m.DataReduction })},
+	{"pure_array_full_ratio", "Fraction of the array capacity that is used", arrayCapacity(func(m *metrics.ArrayCapacityMetric) float64 { return m.PercentFull })},
+	{"pure_array_shared_space_bytes", "Space used by deduplicated data shared between volumes", arrayCapacity(func(m *metrics.ArrayCapacityMetric) float64 { return float64(m.SharedSpace) })},
+	{"pure_array_snapshot_space_bytes", "Space used by snapshots", arrayCapacity(func(m *metrics.ArrayCapacityMetric) float64 { return float64(m.SnapshotSpace) })},
+	{"pure_array_system_space_bytes", "Space used by the system", arrayCapacity(func(m *metrics.ArrayCapacityMetric) float64 { return float64(m.SystemSpace) })},
+	{"pure_array_total_reduction_ratio", "Total reduction ratio of the array (including thin provisioning)", arrayCapacity(func(m *metrics.ArrayCapacityMetric) float64 { return m.TotalReduction })},
+	{"pure_array_total_space_bytes", "Usable capacity of the array", arrayCapacity(func(m *metrics.ArrayCapacityMetric) float64 { return float64(m.TotalSpace) })},
+	{"pure_array_used_space_bytes", "Space used on the array", arrayCapacity(func(m *metrics.ArrayCapacityMetric) float64 { return float64(m.UsedSpace) })},
+	{"pure_array_volume_space_bytes", "Space used by volume data", arrayCapacity(func(m *metrics.ArrayCapacityMetric) float64 { return float64(m.VolumeSpace) })},
+
+	{"pure_array_bytes_per_op", "Average size of an IO operation", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.BytesPerOp) })},
+	{"pure_array_bytes_per_read", "Average size of a read operation", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.BytesPerRead) })},
+	{"pure_array_bytes_per_write", "Average size of a write operation", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.BytesPerWrite) })},
+	{"pure_array_other_iops", "Other (non read/write) operations per second", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.OtherIOPS) })},
+	{"pure_array_other_latency_microseconds", "Average latency of other (non read/write) operations", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.OtherLatency) })},
+	{"pure_array_queue_depth", "Average queue depth", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.QueueDepth) })},
+	{"pure_array_read_bandwidth_bytes_per_second", "Bytes read per second", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.ReadBandwidth) })},
+	{"pure_array_read_iops", "Read operations per second", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.ReadIOPS) })},
+	{"pure_array_read_latency_microseconds", "Average latency of read operations", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.ReadLatency) })},
+	{"pure_array_write_bandwidth_bytes_per_second", "Bytes written per second", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.WriteBandwidth) })},
+	{"pure_array_write_iops", "Write operations per second", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.WriteIOPS) })},
+	{"pure_array_write_latency_microseconds", "Average latency of write operations", arrayPerformance(func(m *metrics.ArrayPerformanceMetric) float64 { return float64(m.WriteLatency) })},
+
+	{"pure_array_alert_messages", "Number of open and flagged alert messages", arrayObjects(func(m *metrics.ArrayObjectsMetric) float64 { return float64(m.AlertMessageCount) })},
+	{"pure_array_file_systems", "Number of file systems", arrayObjects(func(m *metrics.ArrayObjectsMetric) float64 { return float64(m.FileSystemCount) })},
+	{"pure_array_hosts", "Number of hosts", arrayObjects(func(m *metrics.ArrayObjectsMetric) float64 { return float64(m.HostCount) })},
+	{"pure_array_snapshots", "Number of snapshots", arrayObjects(func(m *metrics.ArrayObjectsMetric) float64 { return float64(m.SnapshotCount) })},
+	{"pure_array_volumes", "Number of volumes", arrayObjects(func(m *metrics.ArrayObjectsMetric) float64 { return float64(m.VolumeCount) })},
+	{"pure_array_volumes_pending_eradication", "Number of destroyed volumes pending eradication", arrayObjects(func(m *metrics.ArrayObjectsMetric) float64 { return float64(m.VolumePendingEradicationCount) })},
+
+	{"pure_array_last_collected_timestamp_seconds", "Time the array metrics were collected, in seconds since the epoch", func(m *metrics.ArrayMetric) (float64, bool) { return float64(m.CreatedAt), true }},
+}
+
+// volumeGauges lists every metric family exported for each volume (or file system)
+var volumeGauges = []volumeGauge{
+	{"pure_volume_data_reduction_ratio", "Data reduction ratio of the volume", volumeCapacity(func(m *metrics.VolumeCapacityMetric) float64 { return m.DataReduction })},
+	{"pure_volume_objects", "Number of objects in the bucket or object store account", volumeCapacity(func(m *metrics.VolumeCapacityMetric) float64 { return float64(m.ObjectCount) })},
+	{"pure_volume_provisioned_space_bytes", "Provisioned size of the volume", volumeCapacity(func(m *metrics.VolumeCapacityMetric) float64 { return float64(m.ProvisionedSpace) })},
+	{"pure_volume_snapshots", "Number of snapshots of the volume", volumeCapacity(func(m *metrics.VolumeCapacityMetric) float64 { return float64(m.SnapshotCount) })},
+	{"pure_volume_total_reduction_ratio", "Total reduction ratio of the volume (including thin provisioning)", volumeCapacity(func(m *metrics.VolumeCapacityMetric) float64 { return m.TotalReduction })},
+	{"pure_volume_used_space_bytes", "Space used by the volume", volumeCapacity(func(m *metrics.VolumeCapacityMetric) float64 { return float64(m.UsedSpace) })},
+
+	{"pure_volume_other_iops", "Other (non read/write) operations per second", volumePerformance(func(m *metrics.VolumePerformanceMetric) float64 { return float64(m.OtherIOPS) })},
+	{"pure_volume_other_latency_microseconds", "Average latency of other (non read/write) operations", volumePerformance(func(m *metrics.VolumePerformanceMetric) float64 { return float64(m.OtherLatency) })},
+	{"pure_volume_read_bandwidth_bytes_per_second", "Bytes read per second", volumePerformance(func(m *metrics.VolumePerformanceMetric) float64 { return float64(m.ReadBandwidth) })},
+	{"pure_volume_read_iops", "Read operations per second", volumePerformance(func(m *metrics.VolumePerformanceMetric) float64 { return float64(m.ReadIOPS) })},
+	{"pure_volume_read_latency_microseconds", "Average latency of read operations", volumePerformance(func(m *metrics.VolumePerformanceMetric) float64 { return float64(m.ReadLatency) })},
+	{"pure_volume_write_bandwidth_bytes_per_second", "Bytes written per second", volumePerformance(func(m *metrics.VolumePerformanceMetric) float64 { return float64(m.WriteBandwidth) })},
+	{"pure_volume_write_iops", "Write operations per second", volumePerformance(func(m *metrics.VolumePerformanceMetric) float64 { return float64(m.WriteIOPS) })},
+	{"pure_volume_write_latency_microseconds", "Average latency of write operations", volumePerformance(func(m *metrics.VolumePerformanceMetric) float64 { return float64(m.WriteLatency) })},
+
+	{"pure_volume_last_collected_timestamp_seconds", "Time the volume metrics were collected, in seconds since the epoch", func(m *metrics.VolumeMetric) (float64, bool) { return float64(m.CreatedAt), true }},
+}
+
+const (
+	openAlertsName = "pure_array_open_alerts"
+	openAlertsHelp = "Number of open alerts on the array, by severity"
+)
+
+// The embedded metrics are pointers that may not have been filled in (if that part of
+// the collection failed), so these helpers skip the value in that case
+
+func arrayCapacity(value func(*metrics.ArrayCapacityMetric) float64) func(*metrics.ArrayMetric) (float64, bool) {
+	return func(m *metrics.ArrayMetric) (float64, bool) {
+		if m.ArrayCapacityMetric == nil {
+			return 0, false
+		}
+		return value(m.ArrayCapacityMetric), true
+	}
+}
+
+func arrayPerformance(value func(*metrics.ArrayPerformanceMetric) float64) func(*metrics.ArrayMetric) (float64, bool) {
+	return func(m *metrics.ArrayMetric) (float64, bool) {
+		if m.ArrayPerformanceMetric == nil {
+			return 0, false
+		}
+		return value(m.ArrayPerformanceMetric), true
+	}
+}
+
+func arrayObjects(value func(*metrics.ArrayObjectsMetric) float64) func(*metrics.ArrayMetric) (float64, bool) {
+	return func(m *metrics.ArrayMetric) (float64, bool) {
+		if m.ArrayObjectsMetric == nil {
+			return 0, false
+		}
+		return value(m.ArrayObjectsMetric), true
+	}
+}
+
+func volumeCapacity(value func(*metrics.VolumeCapacityMetric) float64) func(*metrics.VolumeMetric) (float64, bool) {
+	return func(m *metrics.VolumeMetric) (float64, bool) {
+		if m.VolumeCapacityMetric == nil {
+			return 0, false
+		}
+		return value(m.VolumeCapacityMetric), true
+	}
+}
+
+func volumePerformance(value func(*metrics.VolumePerformanceMetric) float64) func(*metrics.VolumeMetric) (float64, bool) {
+	return func(m *metrics.VolumeMetric) (float64, bool) {
+		if m.VolumePerformanceMetric == nil {
+			return 0, false
+		}
+		return value(m.VolumePerformanceMetric), true
+	}
+}
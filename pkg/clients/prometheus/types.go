@@ -0,0 +1,75 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+// Exporter keeps the latest array metrics, volume metrics and open alert counts in memory and
+// serves them on an HTTP handler in the Prometheus text exposition format. It implements the
+// metrics.Database interface, so it receives the same pushes the Elastic client does.
+type Exporter struct {
+	lock sync.RWMutex
+	// Anything not refreshed within this duration is left out of the output (deleted volumes, arrays, etc.)
+	staleAfter time.Duration
+
+	arrays  map[string]*arrayEntry  // Keyed by array ID
+	volumes map[string]*volumeEntry // Keyed by array ID and volume name
+	alerts  map[string]*alertEntry  // Keyed by array ID and alert ID, only holds open alerts
+}
+
+type arrayEntry struct {
+	metric   *metrics.ArrayMetric
+	received time.Time
+}
+
+type volumeEntry struct {
+	metric   *metrics.VolumeMetric
+	received time.Time
+}
+
+type alertEntry struct {
+	alert    *metrics.Alert
+	received time.Time
+}
+
+// arrayGauge describes a single array metric family and how to read its value
+type arrayGauge struct {
+	name  string
+	help  string
+	value func(metric *metrics.ArrayMetric) (float64, bool) // False if the metric doesn't have this value
+}
+
+// volumeGauge describes a single volume metric family and how to read its value
+type volumeGauge struct {
+	name  string
+	help  string
+	value func(metric *metrics.VolumeMetric) (float64, bool) // False if the metric doesn't have this value
+}
+
+// sample is a single labelled value of a metric family
+type sample struct {
+	labels []label
+	value  float64
+}
+
+type label struct {
+	name  string
+	value string
+}
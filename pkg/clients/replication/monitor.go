@@ -0,0 +1,216 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replication
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.ProtectionGroupDatabase = (*LagMonitor)(nil)
+
+const (
+	openState   = "open"
+	closedState = "closed"
+
+	lagAlertSeverity = "warning"
+)
+
+// NewLagMonitor creates a lag monitor in front of the given database. Alerts are written to the given alert
+// database, and any alerts left open by a previous run are looked up in the given replication alert database.
+func NewLagMonitor(database metrics.ProtectionGroupDatabase, alertDatabase metrics.Database, alerts metrics.ReplicationAlertDatabase, gracePeriod time.Duration) *LagMonitor {
+	return &LagMonitor{
+		database:      database,
+		alertDatabase: alertDatabase,
+		alerts:        alerts,
+		gracePeriod:   int64(gracePeriod.Seconds()),
+		lagging:       map[string]*metrics.Alert{},
+	}
+}
+
+// AddProtectionGroupMetrics writes the given metrics to the wrapped database, then checks the replication lag of
+// every protection group in them
+func (m *LagMonitor) AddProtectionGroupMetrics(protectionGroupMetrics []*metrics.ProtectionGroupMetric) error {
+	err := m.database.AddProtectionGroupMetrics(protectionGroupMetrics)
+
+	loadErr := m.loadOpenAlerts()
+	if loadErr != nil {
+		log.WithError(loadErr).Warn("Failed to load open replication lag alerts, will try again with the next metrics")
+		return err
+	}
+	m.writeAlerts(m.check(protectionGroupMetrics))
+
+	return err
+}
+
+// CleanProtectionGroupMetrics passes through to the wrapped database
+func (m *LagMonitor) CleanProtectionGroupMetrics(maxAgeInDays int) error {
+	return m.database.CleanProtectionGroupMetrics(maxAgeInDays)
+}
+
+// loadOpenAlerts is a helper function that loads the alerts left open by a previous run, if they haven't been loaded yet
+func (m *LagMonitor) loadOpenAlerts() error {
+	m.lock.Lock()
+	loaded := m.loaded
+	m.lock.Unlock()
+	if loaded {
+		return nil
+	}
+
+	openAlerts, err := m.alerts.FindOpenReplicationAlerts()
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if !m.loaded {
+		for _, alert := range openAlerts {
+			m.lagging[getLagKey(alert.ArrayID, alert.Component)] = alert
+		}
+		m.loaded = true
+		log.WithField("open_alerts", len(openAlerts)).Info("Loaded open replication lag alerts")
+	}
+	return nil
+}
+
+// check is a helper function that checks the replication lag of the given protection groups, returning the
+// alerts that were opened, updated or closed
+func (m *LagMonitor) check(protectionGroupMetrics []*metrics.ProtectionGroupMetric) []*metrics.Alert {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	alerts := []*metrics.Alert{}
+	arrayIDs := map[string]bool{}
+	seen := map[string]bool{}
+	var lastCreatedAt int64
+	for _, metric := range protectionGroupMetrics {
+		if metric == nil {
+			continue
+		}
+		arrayIDs[metric.ArrayID] = true
+		key := getLagKey(metric.ArrayID, metric.ProtectionGroupName)
+		seen[key] = true
+		lastCreatedAt = metric.CreatedAt
+
+		existing, lagging := m.lagging[key]
+		if !m.isLagging(metric) {
+			if lagging {
+				delete(m.lagging, key)
+				alerts = append(alerts, closeAlert(existing, metric.CreatedAt))
+			}
+			continue
+		}
+
+		since := metric.CreatedAt
+		if lagging {
+			since = existing.Created
+		}
+		m.lagging[key] = newLagAlert(metric, since)
+		alerts = append(alerts, m.lagging[key])
+	}
+
+	// Protection groups that are gone from an array can't lag any more
+	for key, existing := range m.lagging {
+		if arrayIDs[existing.ArrayID] && !seen[key] {
+			delete(m.lagging, key)
+			alerts = append(alerts, closeAlert(existing, lastCreatedAt))
+		}
+	}
+	return alerts
+}
+
+// isLagging is a helper function that checks if the newest replicated snapshot of a protection group is older than
+// its replication schedule allows. Only groups that replicate from this array to others are checked (groups replicated
+// to this array are checked on their source array), and only once they've replicated at least once.
+func (m *LagMonitor) isLagging(metric *metrics.ProtectionGroupMetric) bool {
+	if !metric.ReplicationEnabled || metric.ReplicationFrequency <= 0 || len(metric.Targets) == 0 || metric.LastReplication == 0 {
+		return false
+	}
+	return metric.ReplicationLag > metric.ReplicationFrequency+m.gracePeriod
+}
+
+// writeAlerts is a helper function to write the given alerts to the alert database
+func (m *LagMonitor) writeAlerts(alerts []*metrics.Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	err := m.alertDatabase.UpdateAlerts(alerts)
+	if err != nil {
+		log.WithError(err).WithField("alerts", len(alerts)).Error("Error writing replication lag alerts")
+	}
+}
+
+// newLagAlert is a helper function to create the (open) alert for a protection group that has been lagging since the given time
+func newLagAlert(metric *metrics.ProtectionGroupMetric, since int64) *metrics.Alert {
+	lag := time.Duration(metric.ReplicationLag) * time.Second
+	frequency := time.Duration(metric.ReplicationFrequency) * time.Second
+
+	alert := &metrics.Alert{
+		AlertID:          getLagAlertID(metric.ArrayID, metric.ProtectionGroupName, since),
+		ArrayDisplayName: metric.ArrayDisplayName,
+		ArrayID:          metric.ArrayID,
+		ArrayName:        metric.ArrayName,
+		Component:        metric.ProtectionGroupName,
+		Created:          since,
+		Description: fmt.Sprintf("The newest snapshot of protection group %s on array %s that has been replicated is %s old, but the group is scheduled to replicate every %s",
+			metric.ProtectionGroupName, metric.ArrayDisplayName, lag, frequency),
+		Flagged:  true,
+		Severity: lagAlertSeverity,
+		Source:   metrics.AlertSourceReplication,
+		State:    openState,
+		Summary:  fmt.Sprintf("Replication of protection group %s is behind schedule", metric.ProtectionGroupName),
+		Updated:  metric.CreatedAt,
+		Variables: map[string]interface{}{
+			"protection_group":      metric.ProtectionGroupName,
+			"replication_lag":       metric.ReplicationLag,
+			"replication_frequency": metric.ReplicationFrequency,
+			"last_replication":      metric.LastReplication,
+		},
+	}
+	alert.PopulateSeverityIndex()
+	return alert
+}
+
+// closeAlert is a helper function to create a closed copy of the given alert
+func closeAlert(alert *metrics.Alert, closedAt int64) *metrics.Alert {
+	closed := *alert
+	closed.State = closedState
+	closed.Flagged = false
+	closed.Updated = closedAt
+	return &closed
+}
+
+// getLagAlertID is a helper function to derive the ID of the alert for a single period of lag, so the same period always
+// maps to the same alert (even across restarts). Alert IDs are mapped as integers in Elastic, so this has to fit in 31 bits.
+func getLagAlertID(arrayID string, protectionGroupName string, since int64) uint64 {
+	hash := fnv.New32a()
+	hash.Write([]byte(fmt.Sprintf("%s/%s/%d", arrayID, protectionGroupName, since)))
+	id := uint64(hash.Sum32() & 0x7fffffff)
+	if id == 0 {
+		// Zero is treated as a missing ID
+		id = 1
+	}
+	return id
+}
+
+func getLagKey(arrayID string, protectionGroupName string) string {
+	return fmt.Sprintf("%s/%s", arrayID, protectionGroupName)
+}
@@ -0,0 +1,128 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replication
+
+import (
+	"testing"
+	"time"
+
+	clientmock "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// createMonitor creates a lag monitor with a 5 minute grace period (and the given alerts left open by a previous run)
+// in front of databases that record the alerts written to them
+func createMonitor(openAlerts []*metrics.Alert) (*LagMonitor, *[]*metrics.Alert) {
+	written := []*metrics.Alert{}
+	database := &clientmock.ProtectionGroupDatabaseImpl{}
+	database.On("AddProtectionGroupMetrics", mock.Anything).Return(nil)
+	alertDatabase := &clientmock.MetricsDatabaseImpl{}
+	alertDatabase.On("UpdateAlerts", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		written = append(written, args.Get(0).([]*metrics.Alert)...)
+	})
+	alerts := &clientmock.ReplicationAlertDatabaseImpl{}
+	alerts.On("FindOpenReplicationAlerts").Return(openAlerts, nil)
+
+	return NewLagMonitor(database, alertDatabase, alerts, 5*time.Minute), &written
+}
+
+func protectionGroupMetric(name string, lag int64, createdAt int64) *metrics.ProtectionGroupMetric {
+	return &metrics.ProtectionGroupMetric{
+		ArrayID:              "array1",
+		ArrayName:            "array-1",
+		ArrayDisplayName:     "Array 1",
+		CreatedAt:            createdAt,
+		ProtectionGroupName:  name,
+		Source:               "array-1",
+		Targets:              []*metrics.ProtectionGroupTarget{{Name: "array-2", Allowed: true, Connected: true}},
+		ReplicationEnabled:   true,
+		ReplicationFrequency: 3600,
+		LastReplication:      createdAt - lag + 60,
+		ReplicationLag:       lag,
+	}
+}
+
+func TestLagAlertOpensUpdatesAndCloses(t *testing.T) {
+	monitor, written := createMonitor([]*metrics.Alert{})
+
+	// Within the schedule plus the grace period
+	assert.NoError(t, monitor.AddProtectionGroupMetrics([]*metrics.ProtectionGroupMetric{protectionGroupMetric("pg1", 3800, 10000)}))
+	assert.Len(t, *written, 0)
+
+	// Behind schedule
+	assert.NoError(t, monitor.AddProtectionGroupMetrics([]*metrics.ProtectionGroupMetric{protectionGroupMetric("pg1", 4000, 10200)}))
+	assert.Len(t, *written, 1)
+	opened := (*written)[0]
+	assert.Equal(t, openState, opened.State)
+	assert.Equal(t, metrics.AlertSourceReplication, opened.Source)
+	assert.Equal(t, "pg1", opened.Component)
+	assert.Equal(t, int64(10200), opened.Created)
+	assert.Equal(t, byte(2), opened.SeverityIndex)
+	assert.Equal(t, int64(4000), opened.Variables["replication_lag"])
+
+	// Still behind, so the same alert is updated
+	assert.NoError(t, monitor.AddProtectionGroupMetrics([]*metrics.ProtectionGroupMetric{protectionGroupMetric("pg1", 4200, 10400)}))
+	assert.Len(t, *written, 2)
+	updated := (*written)[1]
+	assert.Equal(t, opened.AlertID, updated.AlertID)
+	assert.Equal(t, int64(10200), updated.Created)
+	assert.Equal(t, int64(10400), updated.Updated)
+
+	// Caught up
+	assert.NoError(t, monitor.AddProtectionGroupMetrics([]*metrics.ProtectionGroupMetric{protectionGroupMetric("pg1", 600, 10600)}))
+	assert.Len(t, *written, 3)
+	closed := (*written)[2]
+	assert.Equal(t, opened.AlertID, closed.AlertID)
+	assert.Equal(t, closedState, closed.State)
+	assert.False(t, closed.Flagged)
+	assert.Equal(t, int64(10600), closed.Updated)
+
+	// Nothing left to close
+	assert.NoError(t, monitor.AddProtectionGroupMetrics([]*metrics.ProtectionGroupMetric{protectionGroupMetric("pg1", 800, 10800)}))
+	assert.Len(t, *written, 3)
+}
+
+func TestLagAlertIgnoresGroupsThatDontReplicate(t *testing.T) {
+	monitor, written := createMonitor([]*metrics.Alert{})
+
+	disabled := protectionGroupMetric("disabled", 8000, 10000)
+	disabled.ReplicationEnabled = false
+	noTargets := protectionGroupMetric("replicated-here", 8000, 10000)
+	noTargets.Targets = []*metrics.ProtectionGroupTarget{}
+	neverReplicated := protectionGroupMetric("new", 0, 10000)
+	neverReplicated.LastReplication = 0
+
+	assert.NoError(t, monitor.AddProtectionGroupMetrics([]*metrics.ProtectionGroupMetric{disabled, noTargets, neverReplicated}))
+	assert.Len(t, *written, 0)
+}
+
+func TestLagAlertLeftOpenIsClosed(t *testing.T) {
+	openAlerts := []*metrics.Alert{
+		newLagAlert(protectionGroupMetric("pg1", 4000, 9000), 9000),
+		newLagAlert(protectionGroupMetric("deleted", 4000, 9000), 9000),
+	}
+	monitor, written := createMonitor(openAlerts)
+
+	// The first group caught up while the monitor was down, and the other group was deleted
+	assert.NoError(t, monitor.AddProtectionGroupMetrics([]*metrics.ProtectionGroupMetric{protectionGroupMetric("pg1", 600, 10000)}))
+	assert.Len(t, *written, 2)
+	for _, alert := range *written {
+		assert.Equal(t, closedState, alert.State)
+		assert.Equal(t, int64(10000), alert.Updated)
+	}
+	assert.ElementsMatch(t, []uint64{openAlerts[0].AlertID, openAlerts[1].AlertID}, []uint64{(*written)[0].AlertID, (*written)[1].AlertID})
+}
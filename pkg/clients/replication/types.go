@@ -0,0 +1,38 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replication
+
+import (
+	"sync"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+// LagMonitor is a metrics.ProtectionGroupDatabase that wraps another one, passing every write through to it and
+// checking the replication lag of the protection groups written. Once a protection group's replication falls
+// behind its schedule (by more than the grace period), an alert with the replication source is written to the
+// alert database, which is updated while the lag lasts and closed once the group catches up or is deleted.
+type LagMonitor struct {
+	database      metrics.ProtectionGroupDatabase
+	alertDatabase metrics.Database
+	alerts        metrics.ReplicationAlertDatabase
+	gracePeriod   int64 // Seconds
+
+	lock    sync.Mutex
+	lagging map[string]*metrics.Alert // The last version of each open alert, keyed by array and protection group (see getLagKey)
+	// Nothing is checked until the alerts left open by a previous run have been loaded, so they
+	// aren't opened a second time
+	loaded bool
+}
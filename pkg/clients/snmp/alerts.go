@@ -0,0 +1,78 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"fmt"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+// OIDs of PURE1-UNPLUGGED-MIB (see deploy/snmp/PURE1-UNPLUGGED-MIB.txt)
+const (
+	pure1UnpluggedOID = "1.3.6.1.4.1.40482.100"
+
+	AlertNewNotificationOID       = pure1UnpluggedOID + ".0.1"
+	AlertEscalatedNotificationOID = pure1UnpluggedOID + ".0.2"
+	AlertClosedNotificationOID    = pure1UnpluggedOID + ".0.3"
+	TestNotificationOID           = pure1UnpluggedOID + ".0.4"
+
+	alertObjectsOID          = pure1UnpluggedOID + ".1"
+	alertArrayIDOID          = alertObjectsOID + ".1.0"
+	alertArrayNameOID        = alertObjectsOID + ".2.0"
+	alertArrayDisplayNameOID = alertObjectsOID + ".3.0"
+	alertIDOID               = alertObjectsOID + ".4.0"
+	alertCodeOID             = alertObjectsOID + ".5.0"
+	alertSeverityOID         = alertObjectsOID + ".6.0"
+	alertPreviousSeverityOID = alertObjectsOID + ".7.0"
+	alertStateOID            = alertObjectsOID + ".8.0"
+	alertComponentOID        = alertObjectsOID + ".9.0"
+	alertSummaryOID          = alertObjectsOID + ".10.0"
+	alertSourceOID           = alertObjectsOID + ".11.0"
+	alertCreatedOID          = alertObjectsOID + ".12.0"
+	testMessageOID           = alertObjectsOID + ".13.0"
+)
+
+// NewAlertTrap creates a trap for the given alert with the given notification OID (one of the Alert*NotificationOIDs).
+// The previous severity is only meaningful for escalations, and should be empty otherwise.
+func NewAlertTrap(notificationOID string, alert *metrics.Alert, previousSeverity string) *Trap {
+	return &Trap{
+		OID: notificationOID,
+		Varbinds: []Varbind{
+			{OID: alertArrayIDOID, Value: alert.ArrayID},
+			{OID: alertArrayNameOID, Value: alert.ArrayName},
+			{OID: alertArrayDisplayNameOID, Value: alert.ArrayDisplayName},
+			{OID: alertIDOID, Value: uint32(alert.AlertID)},
+			{OID: alertCodeOID, Value: uint32(alert.Code)},
+			{OID: alertSeverityOID, Value: int(metrics.GetSeverityIndex(alert.Severity))},
+			{OID: alertPreviousSeverityOID, Value: int(metrics.GetSeverityIndex(previousSeverity))},
+			{OID: alertStateOID, Value: alert.State},
+			{OID: alertComponentOID, Value: alert.Component},
+			{OID: alertSummaryOID, Value: alert.Summary},
+			{OID: alertSourceOID, Value: alert.Source},
+			{OID: alertCreatedOID, Value: uint32(alert.Created)},
+		},
+	}
+}
+
+// NewTestTrap creates a trap for checking that a receiver is set up correctly
+func NewTestTrap(destinationName string) *Trap {
+	return &Trap{
+		OID: TestNotificationOID,
+		Varbinds: []Varbind{
+			{OID: testMessageOID, Value: fmt.Sprintf("Test notification from Pure1 Unplugged for SNMP destination %s", destinationName)},
+		},
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER tags used by SNMP
+const (
+	tagInteger          = 0x02
+	tagOctetString      = 0x04
+	tagNull             = 0x05
+	tagObjectIdentifier = 0x06
+	tagSequence         = 0x30
+	tagGauge32          = 0x42
+	tagTimeTicks        = 0x43
+	tagSNMPv2Trap       = 0xa7
+)
+
+// encodeTLV is a helper function that encodes a BER type-length-value
+func encodeTLV(tag byte, value []byte) []byte {
+	encoded := []byte{tag}
+	encoded = append(encoded, encodeLength(len(value))...)
+	return append(encoded, value...)
+}
+
+// encodeLength is a helper function that encodes a BER length in the short form if possible,
+// and the long form otherwise
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	bytes := []byte{}
+	for length > 0 {
+		bytes = append([]byte{byte(length)}, bytes...)
+		length >>= 8
+	}
+	return append([]byte{0x80 | byte(len(bytes))}, bytes...)
+}
+
+// encodeSequence is a helper function that encodes the given encoded values as a sequence
+func encodeSequence(tag byte, values ...[]byte) []byte {
+	content := []byte{}
+	for _, value := range values {
+		content = append(content, value...)
+	}
+	return encodeTLV(tag, content)
+}
+
+// encodeInteger is a helper function that encodes a signed integer in as few bytes as possible
+func encodeInteger(value int64) []byte {
+	bytes := []byte{byte(value)}
+	for (value > 0x7f || value < -0x80) && len(bytes) < 8 {
+		value >>= 8
+		bytes = append([]byte{byte(value)}, bytes...)
+	}
+	return encodeTLV(tagInteger, bytes)
+}
+
+// encodeUnsigned is a helper function that encodes an unsigned 32-bit integer with the given application tag
+func encodeUnsigned(tag byte, value uint32) []byte {
+	bytes := []byte{byte(value)}
+	for value > 0xff {
+		value >>= 8
+		bytes = append([]byte{byte(value)}, bytes...)
+	}
+	if bytes[0]&0x80 != 0 {
+		// Keep it from being read as negative
+		bytes = append([]byte{0}, bytes...)
+	}
+	return encodeTLV(tag, bytes)
+}
+
+// encodeOctetString is a helper function that encodes an octet string
+func encodeOctetString(value []byte) []byte {
+	return encodeTLV(tagOctetString, value)
+}
+
+// encodeObjectIdentifier is a helper function that encodes a dotted OID such as "1.3.6.1.2.1.1.3.0"
+func encodeObjectIdentifier(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("Invalid OID %s: must have at least two arcs", oid)
+	}
+	arcs := make([]uint64, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid OID %s: %v", oid, err)
+		}
+		arcs[i] = arc
+	}
+	if arcs[0] > 2 || (arcs[0] < 2 && arcs[1] > 39) {
+		return nil, fmt.Errorf("Invalid OID %s: first arcs out of range", oid)
+	}
+
+	content := encodeBase128(arcs[0]*40 + arcs[1])
+	for _, arc := range arcs[2:] {
+		content = append(content, encodeBase128(arc)...)
+	}
+	return encodeTLV(tagObjectIdentifier, content), nil
+}
+
+// encodeBase128 is a helper function that encodes an OID arc, seven bits per byte with the high bit
+// set on every byte but the last
+func encodeBase128(value uint64) []byte {
+	bytes := []byte{byte(value & 0x7f)}
+	value >>= 7
+	for value > 0 {
+		bytes = append([]byte{byte(value&0x7f) | 0x80}, bytes...)
+		value >>= 7
+	}
+	return bytes
+}
+
+// encodeVarbind is a helper function that encodes a variable binding
+func encodeVarbind(varbind Varbind) ([]byte, error) {
+	name, err := encodeObjectIdentifier(varbind.OID)
+	if err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	switch v := varbind.Value.(type) {
+	case nil:
+		value = encodeTLV(tagNull, nil)
+	case int:
+		value = encodeInteger(int64(v))
+	case string:
+		value = encodeOctetString([]byte(v))
+	case []byte:
+		value = encodeOctetString(v)
+	case uint32:
+		value = encodeUnsigned(tagGauge32, v)
+	case TimeTicks:
+		value = encodeUnsigned(tagTimeTicks, uint32(v))
+	case ObjectIdentifier:
+		value, err = encodeObjectIdentifier(string(v))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported value type %T for varbind %s", varbind.Value, varbind.OID)
+	}
+	return encodeSequence(tagSequence, name, value), nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// These tests decode traps with the standard library's ASN.1 parser and check the SNMPv3 security with the standard
+// library's HMAC and ciphers directly, so the encoding isn't only checked against the decoder in sender_test.go. The
+// ASN.1 parser is strict DER, which SNMP messages with minimal lengths and integers are.
+
+// asn1Message is an SNMPv2c message (RFC 3416 section 3)
+type asn1Message struct {
+	Version   int
+	Community []byte
+	PDU       asn1.RawValue
+}
+
+// asn1TrapPDU is an SNMPv2-Trap-PDU, context specific tag 7 (RFC 3416 section 3)
+type asn1TrapPDU struct {
+	RequestID   int
+	ErrorStatus int
+	ErrorIndex  int
+	Varbinds    []asn1Varbind
+}
+
+type asn1Varbind struct {
+	Name  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// asn1V3Message is an SNMPv3 message (RFC 3412 section 6)
+type asn1V3Message struct {
+	Version            int
+	Header             asn1V3Header
+	SecurityParameters []byte
+	Data               asn1.RawValue
+}
+
+type asn1V3Header struct {
+	MsgID         int
+	MaxSize       int
+	Flags         []byte
+	SecurityModel int
+}
+
+// asn1USMParameters are the user-based security model parameters (RFC 3414 section 2.4)
+type asn1USMParameters struct {
+	EngineID   []byte
+	EngineBoot int
+	EngineTime int
+	Username   []byte
+	AuthParams []byte
+	PrivParams []byte
+}
+
+// asn1ScopedPDU is the scoped PDU of an SNMPv3 message (RFC 3412 section 6)
+type asn1ScopedPDU struct {
+	ContextEngineID []byte
+	ContextName     []byte
+	PDU             asn1.RawValue
+}
+
+// unmarshalTrapPDU decodes a trap PDU, returning its varbind values by OID
+func unmarshalTrapPDU(t *testing.T, pdu asn1.RawValue) map[string]asn1.RawValue {
+	var trapPDU asn1TrapPDU
+	_, err := asn1.UnmarshalWithParams(pdu.FullBytes, &trapPDU, "tag:7")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, 0, trapPDU.ErrorStatus)
+	assert.Equal(t, 0, trapPDU.ErrorIndex)
+
+	varbinds := map[string]asn1.RawValue{}
+	for _, varbind := range trapPDU.Varbinds {
+		varbinds[varbind.Name.String()] = varbind.Value
+	}
+	if assert.True(t, len(trapPDU.Varbinds) >= 2) {
+		assert.Equal(t, sysUpTimeOID, trapPDU.Varbinds[0].Name.String())
+		assert.Equal(t, snmpTrapOIDOID, trapPDU.Varbinds[1].Name.String())
+	}
+	return varbinds
+}
+
+// checkTrapVarbinds checks the varbinds of newTestTrap decoded with the standard library
+func checkTrapVarbinds(t *testing.T, varbinds map[string]asn1.RawValue) {
+	var uptime int64
+	_, err := asn1.UnmarshalWithParams(varbinds[sysUpTimeOID].FullBytes, &uptime, "application,tag:3")
+	assert.NoError(t, err, "sysUpTime must be TimeTicks")
+
+	var trapOID asn1.ObjectIdentifier
+	_, err = asn1.Unmarshal(varbinds[snmpTrapOIDOID].FullBytes, &trapOID)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.3.6.1.4.1.40482.100.0.1", trapOID.String())
+
+	var integer int
+	_, err = asn1.Unmarshal(varbinds["1.3.6.1.4.1.40482.100.1.1"].FullBytes, &integer)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, integer)
+
+	var text []byte
+	_, err = asn1.Unmarshal(varbinds["1.3.6.1.4.1.40482.100.1.2"].FullBytes, &text)
+	assert.NoError(t, err)
+	assert.Equal(t, "Controller failed", string(text))
+
+	var gauge int64
+	_, err = asn1.UnmarshalWithParams(varbinds["1.3.6.1.4.1.40482.100.1.3"].FullBytes, &gauge, "application,tag:2")
+	assert.NoError(t, err, "Gauge32 must be application tag 2")
+	assert.Equal(t, int64(3000000000), gauge)
+}
+
+func TestInteropV2cTrap(t *testing.T) {
+	message, err := NewSender(time.Second).encode(&Target{Version: Version2c, Community: "public"}, newTestTrap())
+	assert.NoError(t, err)
+
+	var decoded asn1Message
+	rest, err := asn1.Unmarshal(message, &decoded)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Empty(t, rest)
+	assert.Equal(t, snmpVersion2c, decoded.Version)
+	assert.Equal(t, "public", string(decoded.Community))
+	checkTrapVarbinds(t, unmarshalTrapPDU(t, decoded.PDU))
+}
+
+func TestInteropV3Trap(t *testing.T) {
+	engineID, _ := hex.DecodeString("80009e2205a1b2c3d4e5f60718")
+	targets := []*Target{
+		{Version: Version3, EngineID: engineID, Username: "noauth", AuthProtocol: AuthNone, PrivProtocol: PrivNone},
+		{Version: Version3, EngineID: engineID, Username: "md5nopriv", AuthProtocol: AuthMD5, AuthPassphrase: "authpassphrase", PrivProtocol: PrivNone},
+		{Version: Version3, EngineID: engineID, Username: "md5des", AuthProtocol: AuthMD5, AuthPassphrase: "authpassphrase", PrivProtocol: PrivDES, PrivPassphrase: "privpassphrase"},
+		{Version: Version3, EngineID: engineID, Username: "shaaes", AuthProtocol: AuthSHA, AuthPassphrase: "authpassphrase", PrivProtocol: PrivAES, PrivPassphrase: "privpassphrase"},
+	}
+
+	sender := NewSender(time.Second)
+	for _, target := range targets {
+		message, err := sender.encode(target, newTestTrap())
+		if !assert.NoError(t, err, target.Username) {
+			continue
+		}
+
+		var decoded asn1V3Message
+		rest, err := asn1.Unmarshal(message, &decoded)
+		if !assert.NoError(t, err, target.Username) {
+			continue
+		}
+		assert.Empty(t, rest)
+		assert.Equal(t, snmpVersion3, decoded.Version)
+		assert.Equal(t, usmSecurityModel, decoded.Header.SecurityModel)
+		flags := decoded.Header.Flags[0]
+
+		var usm asn1USMParameters
+		_, err = asn1.Unmarshal(decoded.SecurityParameters, &usm)
+		if !assert.NoError(t, err, target.Username) {
+			continue
+		}
+		assert.Equal(t, engineID, usm.EngineID)
+		assert.Equal(t, target.Username, string(usm.Username))
+
+		// Keys are localized with localizeKey, which is checked against the RFC 3414 test vectors in sender_test.go
+		if target.AuthProtocol == AuthNone {
+			assert.Equal(t, byte(0), flags&msgFlagAuth, target.Username)
+			assert.Empty(t, usm.AuthParams)
+		} else {
+			assert.NotEqual(t, byte(0), flags&msgFlagAuth, target.Username)
+			newHash := func() hash.Hash { return sha1.New() }
+			if target.AuthProtocol == AuthMD5 {
+				newHash = func() hash.Hash { return md5.New() }
+			}
+			// RFC 3414 sections 6.3.1 and 7.3.1: HMAC over the whole message with the authentication parameters zeroed
+			zeroed := bytes.Replace(message, usm.AuthParams, make([]byte, len(usm.AuthParams)), 1)
+			mac := hmac.New(newHash, localizeKey(target.AuthProtocol, target.AuthPassphrase, engineID))
+			mac.Write(zeroed)
+			assert.Equal(t, mac.Sum(nil)[:12], usm.AuthParams, target.Username)
+		}
+
+		scopedPDUBytes := decoded.Data.FullBytes
+		if target.PrivProtocol == PrivNone {
+			assert.Equal(t, byte(0), flags&msgFlagPriv, target.Username)
+		} else {
+			assert.NotEqual(t, byte(0), flags&msgFlagPriv, target.Username)
+			var encrypted []byte
+			_, err = asn1.Unmarshal(decoded.Data.FullBytes, &encrypted)
+			if !assert.NoError(t, err, target.Username) {
+				continue
+			}
+			key := localizeKey(target.AuthProtocol, target.PrivPassphrase, engineID)
+			scopedPDUBytes = make([]byte, len(encrypted))
+			if target.PrivProtocol == PrivDES {
+				// RFC 3414 section 8.1.1.1: the IV is the pre-IV (the second half of the key) XORed with the salt
+				block, _ := des.NewCipher(key[:8])
+				iv := make([]byte, des.BlockSize)
+				for i := range iv {
+					iv[i] = key[8+i] ^ usm.PrivParams[i]
+				}
+				cipher.NewCBCDecrypter(block, iv).CryptBlocks(scopedPDUBytes, encrypted)
+			} else {
+				// RFC 3826 section 3.1.2.1: the IV is the engine boots, engine time and salt
+				block, _ := aes.NewCipher(key[:16])
+				iv := make([]byte, aes.BlockSize)
+				binary.BigEndian.PutUint32(iv[:4], uint32(usm.EngineBoot))
+				binary.BigEndian.PutUint32(iv[4:8], uint32(usm.EngineTime))
+				copy(iv[8:], usm.PrivParams)
+				cipher.NewCFBDecrypter(block, iv).XORKeyStream(scopedPDUBytes, encrypted)
+			}
+		}
+
+		// DES pads the scoped PDU, and receivers ignore anything after it
+		var scopedPDU asn1ScopedPDU
+		_, err = asn1.Unmarshal(scopedPDUBytes, &scopedPDU)
+		if !assert.NoError(t, err, target.Username) {
+			continue
+		}
+		assert.Equal(t, engineID, scopedPDU.ContextEngineID)
+		checkTrapVarbinds(t, unmarshalTrapPDU(t, scopedPDU.PDU))
+	}
+}
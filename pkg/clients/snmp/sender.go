@@ -0,0 +1,242 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// OIDs every SNMPv2 trap starts with (RFC 3416 section 4.2.6)
+const (
+	sysUpTimeOID   = "1.3.6.1.2.1.1.3.0"
+	snmpTrapOIDOID = "1.3.6.1.6.3.1.1.4.1.0"
+)
+
+const (
+	snmpVersion2c      = 1
+	snmpVersion3       = 3
+	usmSecurityModel   = 3
+	maxMessageSize     = 65507 // Largest UDP payload
+	msgFlagAuth        = 0x01
+	msgFlagPriv        = 0x02
+	engineBoots        = 1
+	enterpriseEngineID = 0x80009e22 // Pure Storage private enterprise number (40482), with the RFC 3411 format bit set
+)
+
+// NewSender creates a trap sender. The uptime in traps (and the SNMPv3 engine time) counts from when it's created.
+func NewSender(timeout time.Duration) *Sender {
+	sender := &Sender{
+		started: time.Now(),
+		timeout: timeout,
+		keys:    map[string]*usmKeys{},
+	}
+	// Start the salt at a random point, so it isn't reused across restarts (RFC 3826 section 3.1.1.1)
+	var seed [8]byte
+	rand.Read(seed[:])
+	sender.nextSalt = binary.BigEndian.Uint64(seed[:])
+	return sender
+}
+
+// NewEngineID generates a random SNMPv3 engine ID, in the RFC 3411 format under the Pure Storage enterprise number
+func NewEngineID() []byte {
+	engineID := make([]byte, 13)
+	binary.BigEndian.PutUint32(engineID, enterpriseEngineID)
+	engineID[4] = 0x05 // Octets, administratively assigned
+	rand.Read(engineID[5:])
+	return engineID
+}
+
+// ParseEngineID parses a hex encoded SNMPv3 engine ID (optionally starting with 0x)
+func ParseEngineID(engineID string) ([]byte, error) {
+	parsed, err := hex.DecodeString(trimHexPrefix(engineID))
+	if err != nil {
+		return nil, fmt.Errorf("SNMPv3 engine ID must be hex encoded: %v", err)
+	}
+	if len(parsed) < 5 || len(parsed) > 32 {
+		return nil, fmt.Errorf("SNMPv3 engine ID must be between 5 and 32 bytes long")
+	}
+	return parsed, nil
+}
+
+// Send sends the given trap to the given target
+func (s *Sender) Send(target *Target, trap *Trap) error {
+	message, err := s.encode(target, trap)
+	if err != nil {
+		return err
+	}
+
+	connection, err := net.DialTimeout("udp", target.Address, s.timeout)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	err = connection.SetWriteDeadline(time.Now().Add(s.timeout))
+	if err != nil {
+		return err
+	}
+	_, err = connection.Write(message)
+	return err
+}
+
+// encode is a helper function that encodes the trap as a message for the given target
+func (s *Sender) encode(target *Target, trap *Trap) ([]byte, error) {
+	uptime := TimeTicks(time.Since(s.started) / (10 * time.Millisecond))
+	varbinds := append([]Varbind{
+		{OID: sysUpTimeOID, Value: uptime},
+		{OID: snmpTrapOIDOID, Value: ObjectIdentifier(trap.OID)},
+	}, trap.Varbinds...)
+
+	encodedVarbinds := [][]byte{}
+	for _, varbind := range varbinds {
+		encoded, err := encodeVarbind(varbind)
+		if err != nil {
+			return nil, err
+		}
+		encodedVarbinds = append(encodedVarbinds, encoded)
+	}
+
+	requestID := int64(atomic.AddUint32(&s.nextRequestID, 1) & 0x7fffffff)
+	pdu := encodeSequence(tagSNMPv2Trap,
+		encodeInteger(requestID),
+		encodeInteger(0), // error-status
+		encodeInteger(0), // error-index
+		encodeSequence(tagSequence, encodedVarbinds...),
+	)
+
+	switch target.Version {
+	case Version2c:
+		return encodeSequence(tagSequence,
+			encodeInteger(snmpVersion2c),
+			encodeOctetString([]byte(target.Community)),
+			pdu,
+		), nil
+	case Version3:
+		return s.encodeV3(target, requestID, pdu)
+	default:
+		return nil, fmt.Errorf("Unsupported SNMP version %s", target.Version)
+	}
+}
+
+// encodeV3 is a helper function that wraps the PDU in an SNMPv3 message secured with the user-based security model
+// (RFC 3412 section 6 and RFC 3414 section 2.4)
+func (s *Sender) encodeV3(target *Target, messageID int64, pdu []byte) ([]byte, error) {
+	err := ValidateUSM(target.AuthProtocol, target.AuthPassphrase, target.PrivProtocol, target.PrivPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(target.EngineID) == 0 {
+		return nil, fmt.Errorf("An SNMPv3 engine ID is required")
+	}
+
+	engineTime := int32(time.Since(s.started) / time.Second)
+	keys := s.getKeys(target)
+
+	var flags byte
+	scopedPDU := encodeSequence(tagSequence,
+		encodeOctetString(target.EngineID), // contextEngineID
+		encodeOctetString(nil),             // contextName
+		pdu,
+	)
+	msgData := scopedPDU
+	authParams := []byte{}
+	privParams := []byte{}
+	if target.AuthProtocol != AuthNone {
+		flags |= msgFlagAuth
+		authParams = make([]byte, authParamsLength) // Zeroed while the HMAC is computed
+	}
+	if target.PrivProtocol != PrivNone {
+		flags |= msgFlagPriv
+		var encrypted []byte
+		encrypted, privParams, err = encrypt(target.PrivProtocol, keys.priv, engineBoots, engineTime, atomic.AddUint64(&s.nextSalt, 1), scopedPDU)
+		if err != nil {
+			return nil, err
+		}
+		msgData = encodeOctetString(encrypted)
+	}
+
+	securityParams := encodeSequence(tagSequence,
+		encodeOctetString(target.EngineID),
+		encodeInteger(engineBoots),
+		encodeInteger(int64(engineTime)),
+		encodeOctetString([]byte(target.Username)),
+		encodeOctetString(authParams),
+		encodeOctetString(privParams),
+	)
+	message := encodeSequence(tagSequence,
+		encodeInteger(snmpVersion3),
+		encodeSequence(tagSequence,
+			encodeInteger(messageID),
+			encodeInteger(maxMessageSize),
+			encodeOctetString([]byte{flags}),
+			encodeInteger(usmSecurityModel),
+		),
+		encodeOctetString(securityParams),
+		msgData,
+	)
+
+	if target.AuthProtocol != AuthNone {
+		// The authentication parameters are the last 12 zero bytes before the privacy parameters
+		// in the security parameters, which come before anything else variable in the message
+		securityParamsOffset := bytes.Index(message, securityParams)
+		authOffset := securityParamsOffset + len(securityParams) - len(encodeOctetString(privParams)) - authParamsLength
+		copy(message[authOffset:], authenticate(target.AuthProtocol, keys.auth, message))
+	}
+	return message, nil
+}
+
+// getKeys is a helper function that gets the localized keys of the target's user, from the cache if possible
+func (s *Sender) getKeys(target *Target) *usmKeys {
+	cacheKey := getKeysCacheKey(target)
+
+	s.keysLock.Lock()
+	defer s.keysLock.Unlock()
+	if keys, ok := s.keys[cacheKey]; ok {
+		return keys
+	}
+
+	keys := &usmKeys{}
+	if target.AuthProtocol != AuthNone {
+		keys.auth = localizeKey(target.AuthProtocol, target.AuthPassphrase, target.EngineID)
+	}
+	if target.PrivProtocol != PrivNone {
+		// Privacy keys are localized with the authentication protocol's hash function
+		keys.priv = localizeKey(target.AuthProtocol, target.PrivPassphrase, target.EngineID)
+	}
+	s.keys[cacheKey] = keys
+	return keys
+}
+
+// getKeysCacheKey is a helper function that identifies the keys of a target's user. The passphrases are hashed
+// so they aren't kept around in the clear any more than they already are.
+func getKeysCacheKey(target *Target) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%x\x00%s\x00%s\x00%s\x00%s", target.EngineID, target.AuthProtocol, target.AuthPassphrase, target.PrivProtocol, target.PrivPassphrase)))
+	return hex.EncodeToString(sum[:])
+}
+
+func trimHexPrefix(value string) string {
+	if len(value) > 1 && value[0] == '0' && (value[1] == 'x' || value[1] == 'X') {
+		return value[2:]
+	}
+	return value
+}
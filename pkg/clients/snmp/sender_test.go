@@ -0,0 +1,338 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tlv is a decoded BER value, for checking encoded messages
+type tlv struct {
+	tag      byte
+	value    []byte
+	children []*tlv // For constructed values
+	raw      []byte // The whole encoding, tag and length included
+}
+
+// decodeTLV decodes a single BER value (and its children, for constructed values)
+func decodeTLV(t *testing.T, data []byte) (*tlv, []byte) {
+	if !assert.True(t, len(data) >= 2, "BER value too short") {
+		t.FailNow()
+	}
+	tag := data[0]
+	length := int(data[1])
+	offset := 2
+	if length&0x80 != 0 {
+		count := length & 0x7f
+		length = 0
+		for i := 0; i < count; i++ {
+			length = length<<8 | int(data[2+i])
+		}
+		offset += count
+	}
+	if !assert.True(t, len(data) >= offset+length, "BER value truncated") {
+		t.FailNow()
+	}
+
+	decoded := &tlv{tag: tag, value: data[offset : offset+length], raw: data[:offset+length]}
+	if tag&0x20 != 0 {
+		rest := decoded.value
+		for len(rest) > 0 {
+			var child *tlv
+			child, rest = decodeTLV(t, rest)
+			decoded.children = append(decoded.children, child)
+		}
+	}
+	return decoded, data[offset+length:]
+}
+
+func decodeInteger(value []byte) int64 {
+	var decoded int64
+	if len(value) > 0 && value[0]&0x80 != 0 {
+		decoded = -1
+	}
+	for _, b := range value {
+		decoded = decoded<<8 | int64(b)
+	}
+	return decoded
+}
+
+func decodeObjectIdentifier(value []byte) string {
+	arcs := []uint64{}
+	var arc uint64
+	for _, b := range value {
+		arc = arc<<7 | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			arcs = append(arcs, arc)
+			arc = 0
+		}
+	}
+	oid := fmt.Sprintf("%d.%d", arcs[0]/40, arcs[0]%40)
+	for _, arc := range arcs[1:] {
+		oid += fmt.Sprintf(".%d", arc)
+	}
+	return oid
+}
+
+// checkTrapPDU checks the PDU of a trap message, returning its varbinds by OID
+func checkTrapPDU(t *testing.T, pdu *tlv) map[string]*tlv {
+	assert.Equal(t, byte(tagSNMPv2Trap), pdu.tag)
+	if !assert.Len(t, pdu.children, 4) {
+		t.FailNow()
+	}
+	assert.Equal(t, int64(0), decodeInteger(pdu.children[1].value))
+	assert.Equal(t, int64(0), decodeInteger(pdu.children[2].value))
+
+	varbinds := map[string]*tlv{}
+	order := []string{}
+	for _, varbind := range pdu.children[3].children {
+		oid := decodeObjectIdentifier(varbind.children[0].value)
+		varbinds[oid] = varbind.children[1]
+		order = append(order, oid)
+	}
+	// sysUpTime.0 and snmpTrapOID.0 always come first
+	assert.Equal(t, []string{sysUpTimeOID, snmpTrapOIDOID}, order[:2])
+	assert.Equal(t, byte(tagTimeTicks), varbinds[sysUpTimeOID].tag)
+	return varbinds
+}
+
+func newTestTrap() *Trap {
+	return &Trap{
+		OID: "1.3.6.1.4.1.40482.100.0.1",
+		Varbinds: []Varbind{
+			{OID: "1.3.6.1.4.1.40482.100.1.1", Value: 42},
+			{OID: "1.3.6.1.4.1.40482.100.1.2", Value: "Controller failed"},
+			{OID: "1.3.6.1.4.1.40482.100.1.3", Value: uint32(3000000000)},
+		},
+	}
+}
+
+func TestEncodeInteger(t *testing.T) {
+	cases := map[int64]string{
+		0:       "020100",
+		127:     "02017f",
+		128:     "02020080",
+		256:     "02020100",
+		-1:      "0201ff",
+		-128:    "020180",
+		-129:    "0202ff7f",
+		65507:   "020300ffe3",
+		1 << 31: "02050080000000",
+	}
+	for value, expected := range cases {
+		assert.Equal(t, expected, hex.EncodeToString(encodeInteger(value)), "encoding %d", value)
+	}
+}
+
+func TestEncodeObjectIdentifier(t *testing.T) {
+	encoded, err := encodeObjectIdentifier("1.3.6.1.4.1.40482")
+	assert.NoError(t, err)
+	assert.Equal(t, "06082b0601040182bc22", hex.EncodeToString(encoded))
+
+	_, err = encodeObjectIdentifier("1")
+	assert.Error(t, err)
+	_, err = encodeObjectIdentifier("1.3.six")
+	assert.Error(t, err)
+	_, err = encodeObjectIdentifier("1.40.1")
+	assert.Error(t, err)
+}
+
+func TestEncodeLength(t *testing.T) {
+	assert.Equal(t, []byte{0x7f}, encodeLength(127))
+	assert.Equal(t, []byte{0x81, 0x80}, encodeLength(128))
+	assert.Equal(t, []byte{0x82, 0x01, 0x00}, encodeLength(256))
+}
+
+func TestLocalizeKey(t *testing.T) {
+	// Test vectors from RFC 3414 appendix A.3
+	engineID, _ := hex.DecodeString("000000000000000000000002")
+	assert.Equal(t, "526f5eed9fcce26f8964c2930787d82b", hex.EncodeToString(localizeKey(AuthMD5, "maplesyrup", engineID)))
+	assert.Equal(t, "6695febc9288e36282235fc7151f128497b38f3f", hex.EncodeToString(localizeKey(AuthSHA, "maplesyrup", engineID)))
+}
+
+func TestValidateUSM(t *testing.T) {
+	assert.NoError(t, ValidateUSM(AuthNone, "", PrivNone, ""))
+	assert.NoError(t, ValidateUSM(AuthSHA, "authpassphrase", PrivAES, "privpassphrase"))
+	assert.Error(t, ValidateUSM(AuthNone, "", PrivAES, "privpassphrase"))
+	assert.Error(t, ValidateUSM(AuthSHA, "short", PrivNone, ""))
+	assert.Error(t, ValidateUSM(AuthSHA, "authpassphrase", PrivDES, "short"))
+	assert.Error(t, ValidateUSM("sha256", "authpassphrase", PrivNone, ""))
+	assert.Error(t, ValidateUSM(AuthSHA, "authpassphrase", "3des", "privpassphrase"))
+}
+
+func TestParseEngineID(t *testing.T) {
+	engineID, err := ParseEngineID("0x80009e2205a1b2c3d4")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x80, 0x00, 0x9e, 0x22, 0x05, 0xa1, 0xb2, 0xc3, 0xd4}, engineID)
+
+	_, err = ParseEngineID("8000")
+	assert.Error(t, err)
+	_, err = ParseEngineID("not hex")
+	assert.Error(t, err)
+
+	generated := NewEngineID()
+	assert.Len(t, generated, 13)
+	assert.Equal(t, []byte{0x80, 0x00, 0x9e, 0x22, 0x05}, generated[:5])
+	assert.NotEqual(t, generated, NewEngineID())
+}
+
+func TestEncodeV2cTrap(t *testing.T) {
+	sender := NewSender(time.Second)
+	message, err := sender.encode(&Target{Version: Version2c, Community: "public"}, newTestTrap())
+	assert.NoError(t, err)
+
+	decoded, rest := decodeTLV(t, message)
+	assert.Empty(t, rest)
+	assert.Len(t, decoded.children, 3)
+	assert.Equal(t, int64(snmpVersion2c), decodeInteger(decoded.children[0].value))
+	assert.Equal(t, "public", string(decoded.children[1].value))
+
+	varbinds := checkTrapPDU(t, decoded.children[2])
+	assert.Equal(t, "1.3.6.1.4.1.40482.100.0.1", decodeObjectIdentifier(varbinds[snmpTrapOIDOID].value))
+	assert.Equal(t, int64(42), decodeInteger(varbinds["1.3.6.1.4.1.40482.100.1.1"].value))
+	assert.Equal(t, "Controller failed", string(varbinds["1.3.6.1.4.1.40482.100.1.2"].value))
+	gauge := varbinds["1.3.6.1.4.1.40482.100.1.3"]
+	assert.Equal(t, byte(tagGauge32), gauge.tag)
+	assert.Equal(t, int64(3000000000), decodeInteger(gauge.value))
+}
+
+func TestEncodeRejectsBadTraps(t *testing.T) {
+	sender := NewSender(time.Second)
+	_, err := sender.encode(&Target{Version: "v1"}, newTestTrap())
+	assert.Error(t, err)
+
+	_, err = sender.encode(&Target{Version: Version2c}, &Trap{OID: "1.3.6.1", Varbinds: []Varbind{{OID: "1.3.6.1.1", Value: 1.5}}})
+	assert.Error(t, err)
+
+	_, err = sender.encode(&Target{Version: Version3, Username: "user", AuthProtocol: AuthNone, PrivProtocol: PrivNone}, newTestTrap())
+	assert.Error(t, err, "engine ID is required")
+}
+
+// checkV3Message decodes an SNMPv3 message, checking its authentication and decrypting it, and returns its scoped PDU
+func checkV3Message(t *testing.T, target *Target, message []byte) *tlv {
+	decoded, rest := decodeTLV(t, message)
+	assert.Empty(t, rest)
+	if !assert.Len(t, decoded.children, 4) {
+		t.FailNow()
+	}
+	assert.Equal(t, int64(snmpVersion3), decodeInteger(decoded.children[0].value))
+
+	header := decoded.children[1]
+	flags := header.children[2].value[0]
+	assert.Equal(t, int64(usmSecurityModel), decodeInteger(header.children[3].value))
+	assert.Equal(t, target.AuthProtocol != AuthNone, flags&msgFlagAuth != 0)
+	assert.Equal(t, target.PrivProtocol != PrivNone, flags&msgFlagPriv != 0)
+
+	securityParams, _ := decodeTLV(t, decoded.children[2].value)
+	assert.Equal(t, target.EngineID, securityParams.children[0].value)
+	engineBoots := decodeInteger(securityParams.children[1].value)
+	engineTime := decodeInteger(securityParams.children[2].value)
+	assert.Equal(t, target.Username, string(securityParams.children[3].value))
+	authParams := securityParams.children[4].value
+	privParams := securityParams.children[5].value
+
+	if target.AuthProtocol != AuthNone {
+		assert.Len(t, authParams, authParamsLength)
+		zeroed := append([]byte{}, message...)
+		offset := bytes.Index(zeroed, authParams)
+		copy(zeroed[offset:], make([]byte, authParamsLength))
+		key := localizeKey(target.AuthProtocol, target.AuthPassphrase, target.EngineID)
+		assert.Equal(t, authenticate(target.AuthProtocol, key, zeroed), authParams, "HMAC must match")
+	} else {
+		assert.Empty(t, authParams)
+	}
+
+	data := decoded.children[3]
+	if target.PrivProtocol == PrivNone {
+		assert.Empty(t, privParams)
+		return data
+	}
+
+	assert.Equal(t, byte(tagOctetString), data.tag)
+	key := localizeKey(target.AuthProtocol, target.PrivPassphrase, target.EngineID)
+	plaintext := make([]byte, len(data.value))
+	switch target.PrivProtocol {
+	case PrivDES:
+		block, _ := des.NewCipher(key[:8])
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = key[8+i] ^ privParams[i]
+		}
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, data.value)
+	case PrivAES:
+		block, _ := aes.NewCipher(key[:16])
+		iv := make([]byte, 16)
+		iv[3] = byte(engineBoots)
+		iv[7] = byte(engineTime)
+		copy(iv[8:], privParams)
+		cipher.NewCFBDecrypter(block, iv).XORKeyStream(plaintext, data.value)
+	}
+	scopedPDU, _ := decodeTLV(t, plaintext)
+	return scopedPDU
+}
+
+func TestEncodeV3Trap(t *testing.T) {
+	engineID, _ := hex.DecodeString("80009e2205a1b2c3d4e5f60718")
+	targets := []*Target{
+		{Version: Version3, EngineID: engineID, Username: "noauth", AuthProtocol: AuthNone, PrivProtocol: PrivNone},
+		{Version: Version3, EngineID: engineID, Username: "md5des", AuthProtocol: AuthMD5, AuthPassphrase: "authpassphrase", PrivProtocol: PrivDES, PrivPassphrase: "privpassphrase"},
+		{Version: Version3, EngineID: engineID, Username: "shaaes", AuthProtocol: AuthSHA, AuthPassphrase: "authpassphrase", PrivProtocol: PrivAES, PrivPassphrase: "privpassphrase"},
+		{Version: Version3, EngineID: engineID, Username: "shanopriv", AuthProtocol: AuthSHA, AuthPassphrase: "authpassphrase", PrivProtocol: PrivNone},
+	}
+
+	sender := NewSender(time.Second)
+	for _, target := range targets {
+		message, err := sender.encode(target, newTestTrap())
+		if !assert.NoError(t, err, target.Username) {
+			continue
+		}
+
+		scopedPDU := checkV3Message(t, target, message)
+		if assert.Len(t, scopedPDU.children, 3, target.Username) {
+			assert.Equal(t, engineID, scopedPDU.children[0].value)
+			varbinds := checkTrapPDU(t, scopedPDU.children[2])
+			assert.Equal(t, "Controller failed", string(varbinds["1.3.6.1.4.1.40482.100.1.2"].value), target.Username)
+		}
+	}
+}
+
+func TestSendToLocalReceiver(t *testing.T) {
+	receiver, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer receiver.Close()
+
+	sender := NewSender(time.Second)
+	err = sender.Send(&Target{Address: receiver.LocalAddr().String(), Version: Version2c, Community: "public"}, newTestTrap())
+	assert.NoError(t, err)
+
+	buffer := make([]byte, maxMessageSize)
+	receiver.SetReadDeadline(time.Now().Add(time.Second))
+	length, _, err := receiver.ReadFrom(buffer)
+	assert.NoError(t, err)
+
+	decoded, _ := decodeTLV(t, buffer[:length])
+	varbinds := checkTrapPDU(t, decoded.children[2])
+	assert.Equal(t, "1.3.6.1.4.1.40482.100.0.1", decodeObjectIdentifier(varbinds[snmpTrapOIDOID].value))
+}
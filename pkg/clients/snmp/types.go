@@ -0,0 +1,93 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"sync"
+	"time"
+)
+
+// SNMP versions
+const (
+	Version2c = "v2c"
+	Version3  = "v3"
+)
+
+// SNMPv3 authentication protocols
+const (
+	AuthNone = "none"
+	AuthMD5  = "md5" // HMAC-MD5-96
+	AuthSHA  = "sha" // HMAC-SHA-96
+)
+
+// SNMPv3 privacy protocols
+const (
+	PrivNone = "none"
+	PrivDES  = "des" // CBC-DES
+	PrivAES  = "aes" // CFB128-AES-128
+)
+
+// Target is a trap receiver, and the credentials to send traps to it with
+type Target struct {
+	Address string // host:port
+	Version string
+	// SNMPv2c only
+	Community string
+	// SNMPv3 only (the sender is the authoritative engine for traps, so the receiver needs the same
+	// engine ID configured for the user)
+	EngineID       []byte
+	Username       string
+	AuthProtocol   string
+	AuthPassphrase string
+	PrivProtocol   string
+	PrivPassphrase string
+}
+
+// Trap is an SNMPv2 notification: the notification OID and the variable bindings that go with it
+// (sysUpTime.0 and snmpTrapOID.0 are added when the trap is sent)
+type Trap struct {
+	OID      string
+	Varbinds []Varbind
+}
+
+// Varbind is a single variable binding of a trap. Values can be int (INTEGER), string or []byte
+// (OCTET STRING), uint32 (Gauge32), TimeTicks or ObjectIdentifier.
+type Varbind struct {
+	OID   string
+	Value interface{}
+}
+
+// TimeTicks is a time in hundredths of a second
+type TimeTicks uint32
+
+// ObjectIdentifier is a dotted OID value, such as "1.3.6.1.4.1.40482"
+type ObjectIdentifier string
+
+// Sender sends traps to targets over UDP
+type Sender struct {
+	started       time.Time
+	timeout       time.Duration
+	nextRequestID uint32 // Accessed atomically
+	nextSalt      uint64 // Accessed atomically
+
+	keysLock sync.Mutex
+	keys     map[string]*usmKeys // Keyed by getKeysCacheKey, since localizing keys is deliberately slow
+}
+
+// usmKeys are the keys of an SNMPv3 user, localized to an engine ID
+type usmKeys struct {
+	auth []byte
+	priv []byte
+}
@@ -0,0 +1,168 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+const (
+	// Length of the truncated HMAC in msgAuthenticationParameters (RFC 3414 sections 6 and 7)
+	authParamsLength = 12
+	// Length of the salt in msgPrivacyParameters (RFC 3414 section 8 and RFC 3826)
+	privParamsLength = 8
+	// Amount of repeated passphrase hashed to make a key (RFC 3414 appendix A.2)
+	passphraseExpansionLength = 1048576
+	// Shortest passphrase allowed (RFC 3414 section 11.2)
+	minPassphraseLength = 8
+)
+
+// ValidateUSM checks that the given SNMPv3 protocols and passphrases can be used together
+func ValidateUSM(authProtocol string, authPassphrase string, privProtocol string, privPassphrase string) error {
+	switch authProtocol {
+	case AuthNone:
+		if privProtocol != PrivNone {
+			return fmt.Errorf("SNMPv3 privacy requires authentication")
+		}
+	case AuthMD5, AuthSHA:
+		if len(authPassphrase) < minPassphraseLength {
+			return fmt.Errorf("SNMPv3 authentication passphrase must be at least %d characters", minPassphraseLength)
+		}
+	default:
+		return fmt.Errorf("SNMPv3 authentication protocol must be one of %s, %s or %s", AuthNone, AuthMD5, AuthSHA)
+	}
+
+	switch privProtocol {
+	case PrivNone:
+	case PrivDES, PrivAES:
+		if len(privPassphrase) < minPassphraseLength {
+			return fmt.Errorf("SNMPv3 privacy passphrase must be at least %d characters", minPassphraseLength)
+		}
+	default:
+		return fmt.Errorf("SNMPv3 privacy protocol must be one of %s, %s or %s", PrivNone, PrivDES, PrivAES)
+	}
+	return nil
+}
+
+// newAuthHash is a helper function that gets the hash function of the given authentication protocol
+func newAuthHash(authProtocol string) func() hash.Hash {
+	if authProtocol == AuthMD5 {
+		return md5.New
+	}
+	return sha1.New
+}
+
+// localizeKey is a helper function that turns a passphrase into a key localized to the given engine
+// ID, with the hash function of the given authentication protocol (RFC 3414 appendix A.2)
+func localizeKey(authProtocol string, passphrase string, engineID []byte) []byte {
+	newHash := newAuthHash(authProtocol)
+
+	expanded := newHash()
+	chunk := make([]byte, 64)
+	index := 0
+	for count := 0; count < passphraseExpansionLength; count += len(chunk) {
+		for i := range chunk {
+			chunk[i] = passphrase[index%len(passphrase)]
+			index++
+		}
+		expanded.Write(chunk)
+	}
+	key := expanded.Sum(nil)
+
+	localized := newHash()
+	localized.Write(key)
+	localized.Write(engineID)
+	localized.Write(key)
+	return localized.Sum(nil)
+}
+
+// authenticate is a helper function that computes the truncated HMAC of a whole message, which must have
+// its authentication parameters zeroed
+func authenticate(authProtocol string, key []byte, message []byte) []byte {
+	mac := hmac.New(newAuthHash(authProtocol), key)
+	mac.Write(message)
+	return mac.Sum(nil)[:authParamsLength]
+}
+
+// encrypt is a helper function that encrypts a scoped PDU, returning the encrypted PDU and the privacy
+// parameters (salt) to send with it
+func encrypt(privProtocol string, key []byte, engineBoots int32, engineTime int32, salt uint64, scopedPDU []byte) ([]byte, []byte, error) {
+	switch privProtocol {
+	case PrivDES:
+		return encryptDES(key, engineBoots, uint32(salt), scopedPDU)
+	case PrivAES:
+		return encryptAES(key, engineBoots, engineTime, salt, scopedPDU)
+	default:
+		return nil, nil, fmt.Errorf("Unsupported SNMPv3 privacy protocol %s", privProtocol)
+	}
+}
+
+// encryptDES is a helper function that encrypts with CBC-DES (RFC 3414 section 8.1.1)
+func encryptDES(key []byte, engineBoots int32, salt uint32, plaintext []byte) ([]byte, []byte, error) {
+	if len(key) < 16 {
+		return nil, nil, fmt.Errorf("DES privacy key is too short")
+	}
+	block, err := des.NewCipher(key[:8])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privParams := make([]byte, privParamsLength)
+	binary.BigEndian.PutUint32(privParams[:4], uint32(engineBoots))
+	binary.BigEndian.PutUint32(privParams[4:], salt)
+	iv := make([]byte, des.BlockSize)
+	for i := range iv {
+		iv[i] = key[8+i] ^ privParams[i]
+	}
+
+	// The receiver ignores anything after the end of the scoped PDU, so the padding can be anything
+	padded := append([]byte{}, plaintext...)
+	if remainder := len(padded) % des.BlockSize; remainder != 0 {
+		padded = append(padded, make([]byte, des.BlockSize-remainder)...)
+	}
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+	return ciphertext, privParams, nil
+}
+
+// encryptAES is a helper function that encrypts with CFB128-AES-128 (RFC 3826 section 3.1)
+func encryptAES(key []byte, engineBoots int32, engineTime int32, salt uint64, plaintext []byte) ([]byte, []byte, error) {
+	if len(key) < 16 {
+		return nil, nil, fmt.Errorf("AES privacy key is too short")
+	}
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privParams := make([]byte, privParamsLength)
+	binary.BigEndian.PutUint64(privParams, salt)
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint32(iv[:4], uint32(engineBoots))
+	binary.BigEndian.PutUint32(iv[4:8], uint32(engineTime))
+	copy(iv[8:], privParams)
+
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(ciphertext, plaintext)
+	return ciphertext, privParams, nil
+}
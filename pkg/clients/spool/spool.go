@@ -0,0 +1,414 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ metrics.Database = (*Database)(nil)
+
+const (
+	arrayMetricsKind  = "array_metrics"
+	volumeMetricsKind = "volume_metrics"
+	alertsKind        = "alerts"
+
+	batchExtension     = ".json"
+	temporaryExtension = ".tmp"
+
+	healthCheckTimeout = 10 * time.Second
+
+	// A batch that still fails after this many replays (while the database is healthy) is dropped, so it can't
+	// hold up the batches behind it forever
+	maxReplayAttempts = 10
+)
+
+// Outcomes of removing a batch from the spool
+const (
+	batchReplayed = iota
+	batchEvicted
+	batchDropped
+)
+
+// NewDatabase creates a spool in the given directory (creating it if needed) in front of the given database.
+// Any batches left in the directory by a previous run are picked up, so they'll be replayed too.
+func NewDatabase(database metrics.Database, healthCheck func(ctx context.Context) bool, directory string, maxBytes int64) (*Database, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("Spool size limit must be positive, got %d", maxBytes)
+	}
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Database{
+		database:     database,
+		healthCheck:  healthCheck,
+		directory:    directory,
+		maxBytes:     maxBytes,
+		nextSequence: 1,
+	}
+	err = d.load()
+	if err != nil {
+		return nil, err
+	}
+	if len(d.batches) > 0 {
+		log.WithFields(log.Fields{
+			"directory": directory,
+			"batches":   len(d.batches),
+			"bytes":     d.bytes,
+		}).Info("Found spooled metrics from a previous run")
+	}
+	return d, nil
+}
+
+// AddArrayMetrics writes the given metrics to the wrapped database, spooling them if that fails
+func (d *Database) AddArrayMetrics(arrayMetrics []*metrics.ArrayMetric) error {
+	return d.write(&batch{Kind: arrayMetricsKind, ArrayMetrics: arrayMetrics})
+}
+
+// AddVolumeMetrics writes the given metrics to the wrapped database, spooling them if that fails
+func (d *Database) AddVolumeMetrics(volumeMetrics []*metrics.VolumeMetric) error {
+	return d.write(&batch{Kind: volumeMetricsKind, VolumeMetrics: volumeMetrics})
+}
+
+// UpdateAlerts writes the given alerts to the wrapped database, spooling them if that fails
+func (d *Database) UpdateAlerts(alerts []*metrics.Alert) error {
+	return d.write(&batch{Kind: alertsKind, Alerts: alerts})
+}
+
+// CleanArrayMetrics passes through to the wrapped database
+func (d *Database) CleanArrayMetrics(maxAgeInDays int) error {
+	return d.database.CleanArrayMetrics(maxAgeInDays)
+}
+
+// CleanVolumeMetrics passes through to the wrapped database
+func (d *Database) CleanVolumeMetrics(maxAgeInDays int) error {
+	return d.database.CleanVolumeMetrics(maxAgeInDays)
+}
+
+// CleanAlerts passes through to the wrapped database
+func (d *Database) CleanAlerts(maxAgeInDays int) error {
+	return d.database.CleanAlerts(maxAgeInDays)
+}
+
+// CleanErrorLogs passes through to the wrapped database
+func (d *Database) CleanErrorLogs(maxAgeInDays int) error {
+	return d.database.CleanErrorLogs(maxAgeInDays)
+}
+
+// CleanTimerLogs passes through to the wrapped database
+func (d *Database) CleanTimerLogs(maxAgeInDays int) error {
+	return d.database.CleanTimerLogs(maxAgeInDays)
+}
+
+// Status returns the current spool depth and counters
+func (d *Database) Status() Status {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return Status{
+		Batches:  len(d.batches),
+		Bytes:    d.bytes,
+		Spooled:  d.spooled,
+		Replayed: d.replayed,
+		Evicted:  d.evicted,
+		Dropped:  d.dropped,
+	}
+}
+
+// Run replays the spool every period for as long as the process runs
+func (d *Database) Run(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for range ticker.C {
+		err := d.Replay()
+		if err != nil {
+			log.WithError(err).Warn("Failed to replay metrics spool, will try again later")
+		}
+	}
+}
+
+// Replay writes spooled batches to the wrapped database in the order they were spooled, as long as the
+// wrapped database is healthy. It stops at the first batch that fails, leaving it at the front of the spool,
+// unless the database rejected it or it has failed too many times, in which case it's dropped.
+func (d *Database) Replay() error {
+	d.replayLock.Lock()
+	defer d.replayLock.Unlock()
+
+	if d.Status().Batches == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	healthy := d.healthCheck(ctx)
+	cancel()
+	if !healthy {
+		return fmt.Errorf("Database is not healthy, not replaying spool")
+	}
+
+	replayed := 0
+	for {
+		oldest, ok := d.oldest()
+		if !ok {
+			break
+		}
+
+		spooled, err := readBatch(oldest.path)
+		if err != nil {
+			// A missing file was most likely evicted while we were reading it, in which case removing it is a no-op
+			if !os.IsNotExist(err) {
+				log.WithFields(log.Fields{
+					"error": err,
+					"path":  oldest.path,
+				}).Error("Failed to read spooled batch, discarding it")
+			}
+			d.remove(oldest, batchEvicted)
+			continue
+		}
+
+		err = spooled.apply(d.database)
+		if err != nil {
+			attempts := d.recordFailedReplay(oldest)
+			if metrics.IsRejected(err) || attempts >= maxReplayAttempts {
+				log.WithFields(log.Fields{
+					"error":    err,
+					"attempts": attempts,
+					"kind":     spooled.Kind,
+					"path":     oldest.path,
+				}).Error("Spooled batch can't be replayed, dropping it")
+				d.remove(oldest, batchDropped)
+				continue
+			}
+			log.WithFields(log.Fields{
+				"replayed": replayed,
+				"attempts": attempts,
+				"kind":     spooled.Kind,
+			}).Warn("Failed to replay spooled batch")
+			return err
+		}
+		d.remove(oldest, batchReplayed)
+		replayed++
+	}
+
+	if replayed > 0 {
+		log.WithField("replayed", replayed).Info("Replayed spooled metrics")
+	}
+	return nil
+}
+
+// write is a helper function that writes the batch to the wrapped database, spooling it if that fails.
+// Anything written while the spool isn't empty goes straight to the back of the spool, so batches reach
+// the database in the order they were written.
+func (d *Database) write(b *batch) error {
+	if d.Status().Batches == 0 {
+		err := b.apply(d.database)
+		if err == nil {
+			return nil
+		}
+		// Spooling it would only hold up the batches after it
+		if metrics.IsRejected(err) {
+			d.lock.Lock()
+			d.dropped++
+			d.lock.Unlock()
+			return err
+		}
+		log.WithFields(log.Fields{
+			"error": err,
+			"kind":  b.Kind,
+		}).Warn("Failed to write to database, spooling batch")
+	}
+	return d.append(b)
+}
+
+// append is a helper function that writes the batch to a new file at the back of the spool, evicting
+// the oldest batches if needed to stay under the size limit
+func (d *Database) append(b *batch) error {
+	marshalled, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	size := int64(len(marshalled))
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if size > d.maxBytes {
+		d.evicted++
+		return fmt.Errorf("Batch of %d bytes is larger than the spool size limit of %d bytes", size, d.maxBytes)
+	}
+
+	for len(d.batches) > 0 && d.bytes+size > d.maxBytes {
+		oldest := d.batches[0]
+		err = os.Remove(oldest.path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		d.batches = d.batches[1:]
+		d.bytes -= oldest.size
+		d.evicted++
+		log.WithField("path", oldest.path).Warn("Metrics spool is full, evicted oldest batch")
+	}
+
+	sequence := d.nextSequence
+	path := filepath.Join(d.directory, batchFileName(sequence))
+	// Write to a temporary file first, so a crash never leaves a partial batch in the spool
+	temporaryPath := path + temporaryExtension
+	err = ioutil.WriteFile(temporaryPath, marshalled, 0644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(temporaryPath, path)
+	if err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+
+	d.nextSequence++
+	d.batches = append(d.batches, spooledBatch{sequence: sequence, path: path, size: size})
+	d.bytes += size
+	d.spooled++
+	return nil
+}
+
+// oldest is a helper function that returns the batch at the front of the spool, if there is one
+func (d *Database) oldest() (spooledBatch, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if len(d.batches) == 0 {
+		return spooledBatch{}, false
+	}
+	return d.batches[0], true
+}
+
+// recordFailedReplay is a helper function that counts a failed replay of the given batch (if it's still at the front
+// of the spool), returning how many times it has failed. The count isn't kept across restarts.
+func (d *Database) recordFailedReplay(failed spooledBatch) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.batches) == 0 || d.batches[0].sequence != failed.sequence {
+		return failed.attempts + 1
+	}
+	d.batches[0].attempts++
+	return d.batches[0].attempts
+}
+
+// remove is a helper function that deletes the given batch from the spool (unless it has already been evicted)
+func (d *Database) remove(removed spooledBatch, outcome int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.batches) == 0 || d.batches[0].sequence != removed.sequence {
+		return
+	}
+	err := os.Remove(removed.path)
+	if err != nil && !os.IsNotExist(err) {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  removed.path,
+		}).Error("Failed to delete spooled batch")
+	}
+	d.batches = d.batches[1:]
+	d.bytes -= removed.size
+	switch outcome {
+	case batchReplayed:
+		d.replayed++
+	case batchEvicted:
+		d.evicted++
+	case batchDropped:
+		d.dropped++
+	}
+}
+
+// load is a helper function that picks up batches already in the spool directory
+func (d *Database) load() error {
+	files, err := ioutil.ReadDir(d.directory)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := file.Name()
+		path := filepath.Join(d.directory, name)
+		if strings.HasSuffix(name, temporaryExtension) {
+			// Left over from a crash part way through spooling a batch
+			os.Remove(path)
+			continue
+		}
+		if !strings.HasSuffix(name, batchExtension) {
+			continue
+		}
+		sequence, err := strconv.ParseUint(strings.TrimSuffix(name, batchExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+		d.batches = append(d.batches, spooledBatch{sequence: sequence, path: path, size: file.Size()})
+		d.bytes += file.Size()
+		if sequence >= d.nextSequence {
+			d.nextSequence = sequence + 1
+		}
+	}
+	sort.Slice(d.batches, func(i, j int) bool {
+		return d.batches[i].sequence < d.batches[j].sequence
+	})
+	return nil
+}
+
+// apply writes the batch to the given database
+func (b *batch) apply(database metrics.Database) error {
+	switch b.Kind {
+	case arrayMetricsKind:
+		return database.AddArrayMetrics(b.ArrayMetrics)
+	case volumeMetricsKind:
+		return database.AddVolumeMetrics(b.VolumeMetrics)
+	case alertsKind:
+		return database.UpdateAlerts(b.Alerts)
+	}
+	return fmt.Errorf("Unknown spooled batch kind: %s", b.Kind)
+}
+
+// readBatch is a helper function that reads a spooled batch from disk
+func readBatch(path string) (*batch, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := &batch{}
+	err = json.Unmarshal(content, b)
+	if err != nil {
+		return nil, err
+	}
+	if b.Kind != arrayMetricsKind && b.Kind != volumeMetricsKind && b.Kind != alertsKind {
+		return nil, fmt.Errorf("Unknown spooled batch kind: %s", b.Kind)
+	}
+	return b, nil
+}
+
+// batchFileName is a helper function that names batch files so that they sort in spool order
+func batchFileName(sequence uint64) string {
+	return fmt.Sprintf("%020d%s", sequence, batchExtension)
+}
@@ -0,0 +1,295 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spool
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	clientmock "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func healthy(ctx context.Context) bool {
+	return true
+}
+
+func unhealthy(ctx context.Context) bool {
+	return false
+}
+
+func createSpoolDirectory(t *testing.T) string {
+	directory, err := ioutil.TempDir("", "spool")
+	assert.NoError(t, err)
+	return directory
+}
+
+func TestWritePassesThroughWhenHealthy(t *testing.T) {
+	directory := createSpoolDirectory(t)
+	defer os.RemoveAll(directory)
+
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("AddArrayMetrics", mock.Anything).Return(nil)
+
+	spool, err := NewDatabase(database, healthy, directory, 1024*1024)
+	assert.NoError(t, err)
+
+	err = spool.AddArrayMetrics([]*metrics.ArrayMetric{{ArrayID: "a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, Status{}, spool.Status())
+	database.AssertExpectations(t)
+}
+
+func TestFailedWritesAreSpooledAndReplayedInOrder(t *testing.T) {
+	directory := createSpoolDirectory(t)
+	defer os.RemoveAll(directory)
+
+	failing := &clientmock.MetricsDatabaseImpl{}
+	failing.On("AddArrayMetrics", mock.Anything).Return(fmt.Errorf("elastic is down"))
+
+	spool, err := NewDatabase(failing, unhealthy, directory, 1024*1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, spool.AddArrayMetrics([]*metrics.ArrayMetric{{ArrayID: "a"}}))
+	// The spool isn't empty, so these go straight to the spool without trying the database
+	assert.NoError(t, spool.AddVolumeMetrics([]*metrics.VolumeMetric{{ArrayID: "a", VolumeName: "vol1"}}))
+	assert.NoError(t, spool.UpdateAlerts([]*metrics.Alert{{ArrayID: "a", AlertID: 12}}))
+	failing.AssertNumberOfCalls(t, "AddArrayMetrics", 1)
+
+	status := spool.Status()
+	assert.Equal(t, 3, status.Batches)
+	assert.Equal(t, uint64(3), status.Spooled)
+	assert.True(t, status.Bytes > 0)
+
+	// Unhealthy, so nothing is replayed
+	assert.Error(t, spool.Replay())
+	assert.Equal(t, 3, spool.Status().Batches)
+
+	calls := []string{}
+	recovered := &clientmock.MetricsDatabaseImpl{}
+	recovered.On("AddArrayMetrics", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		calls = append(calls, "array:"+args.Get(0).([]*metrics.ArrayMetric)[0].ArrayID)
+	})
+	recovered.On("AddVolumeMetrics", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		calls = append(calls, "volume:"+args.Get(0).([]*metrics.VolumeMetric)[0].VolumeName)
+	})
+	recovered.On("UpdateAlerts", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		calls = append(calls, fmt.Sprintf("alert:%d", args.Get(0).([]*metrics.Alert)[0].AlertID))
+	})
+	spool.database = recovered
+	spool.healthCheck = healthy
+
+	assert.NoError(t, spool.Replay())
+	assert.Equal(t, []string{"array:a", "volume:vol1", "alert:12"}, calls)
+
+	status = spool.Status()
+	assert.Equal(t, 0, status.Batches)
+	assert.Equal(t, int64(0), status.Bytes)
+	assert.Equal(t, uint64(3), status.Replayed)
+
+	files, err := ioutil.ReadDir(directory)
+	assert.NoError(t, err)
+	assert.Len(t, files, 0)
+}
+
+func TestReplayStopsAtFirstFailure(t *testing.T) {
+	directory := createSpoolDirectory(t)
+	defer os.RemoveAll(directory)
+
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("UpdateAlerts", mock.Anything).Return(fmt.Errorf("elastic is down"))
+
+	spool, err := NewDatabase(database, healthy, directory, 1024*1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, spool.UpdateAlerts([]*metrics.Alert{{AlertID: 1}}))
+	assert.NoError(t, spool.UpdateAlerts([]*metrics.Alert{{AlertID: 2}}))
+
+	assert.Error(t, spool.Replay())
+	// One failed write, then only the first spooled batch is tried
+	database.AssertNumberOfCalls(t, "UpdateAlerts", 2)
+	assert.Equal(t, 2, spool.Status().Batches)
+}
+
+func TestReplayDropsRejectedBatch(t *testing.T) {
+	directory := createSpoolDirectory(t)
+	defer os.RemoveAll(directory)
+
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("UpdateAlerts", mock.Anything).Return(fmt.Errorf("elastic is down")).Once()
+	database.On("UpdateAlerts", mock.Anything).Return(&metrics.RejectedError{Reason: "mapping error"}).Once()
+	database.On("AddArrayMetrics", mock.Anything).Return(nil)
+
+	spool, err := NewDatabase(database, healthy, directory, 1024*1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, spool.UpdateAlerts([]*metrics.Alert{{AlertID: 1}}))
+	assert.NoError(t, spool.AddArrayMetrics([]*metrics.ArrayMetric{{ArrayID: "a"}}))
+
+	// The rejected batch is dropped straight away, so the one behind it still gets replayed
+	assert.NoError(t, spool.Replay())
+	status := spool.Status()
+	assert.Equal(t, 0, status.Batches)
+	assert.Equal(t, uint64(1), status.Dropped)
+	assert.Equal(t, uint64(1), status.Replayed)
+}
+
+func TestReplayDropsBatchAfterMaxAttempts(t *testing.T) {
+	directory := createSpoolDirectory(t)
+	defer os.RemoveAll(directory)
+
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("UpdateAlerts", mock.Anything).Return(fmt.Errorf("some failure"))
+
+	spool, err := NewDatabase(database, healthy, directory, 1024*1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, spool.UpdateAlerts([]*metrics.Alert{{AlertID: 1}}))
+	for i := 1; i < maxReplayAttempts; i++ {
+		assert.Error(t, spool.Replay())
+		assert.Equal(t, 1, spool.Status().Batches)
+	}
+
+	assert.NoError(t, spool.Replay())
+	assert.Equal(t, 0, spool.Status().Batches)
+	assert.Equal(t, uint64(1), spool.Status().Dropped)
+}
+
+func TestRejectedWriteIsNotSpooled(t *testing.T) {
+	directory := createSpoolDirectory(t)
+	defer os.RemoveAll(directory)
+
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("AddArrayMetrics", mock.Anything).Return(&metrics.RejectedError{Reason: "mapping error"})
+
+	spool, err := NewDatabase(database, healthy, directory, 1024*1024)
+	assert.NoError(t, err)
+
+	assert.Error(t, spool.AddArrayMetrics([]*metrics.ArrayMetric{{ArrayID: "a"}}))
+	assert.Equal(t, Status{Dropped: 1}, spool.Status())
+}
+
+func TestSpoolEvictsOldestWhenFull(t *testing.T) {
+	directory := createSpoolDirectory(t)
+	defer os.RemoveAll(directory)
+
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("UpdateAlerts", mock.Anything).Return(fmt.Errorf("elastic is down"))
+
+	// Work out how big a single batch is, then only leave room for two of them
+	sizing, err := NewDatabase(database, unhealthy, directory, 1024*1024)
+	assert.NoError(t, err)
+	assert.NoError(t, sizing.UpdateAlerts([]*metrics.Alert{{AlertID: 1}}))
+	batchSize := sizing.Status().Bytes
+	assert.NoError(t, os.RemoveAll(directory))
+
+	spool, err := NewDatabase(database, unhealthy, directory, batchSize*2)
+	assert.NoError(t, err)
+	for i := uint64(1); i <= 4; i++ {
+		assert.NoError(t, spool.UpdateAlerts([]*metrics.Alert{{AlertID: i}}))
+	}
+
+	status := spool.Status()
+	assert.Equal(t, 2, status.Batches)
+	assert.Equal(t, uint64(4), status.Spooled)
+	assert.Equal(t, uint64(2), status.Evicted)
+
+	alertIDs := []uint64{}
+	recovered := &clientmock.MetricsDatabaseImpl{}
+	recovered.On("UpdateAlerts", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		alertIDs = append(alertIDs, args.Get(0).([]*metrics.Alert)[0].AlertID)
+	})
+	spool.database = recovered
+	spool.healthCheck = healthy
+
+	assert.NoError(t, spool.Replay())
+	assert.Equal(t, []uint64{3, 4}, alertIDs)
+}
+
+func TestSpoolRejectsBatchLargerThanLimit(t *testing.T) {
+	directory := createSpoolDirectory(t)
+	defer os.RemoveAll(directory)
+
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("UpdateAlerts", mock.Anything).Return(fmt.Errorf("elastic is down"))
+
+	spool, err := NewDatabase(database, unhealthy, directory, 10)
+	assert.NoError(t, err)
+
+	assert.Error(t, spool.UpdateAlerts([]*metrics.Alert{{AlertID: 1}}))
+	status := spool.Status()
+	assert.Equal(t, 0, status.Batches)
+	assert.Equal(t, uint64(1), status.Evicted)
+}
+
+func TestSpoolSurvivesRestart(t *testing.T) {
+	directory := createSpoolDirectory(t)
+	defer os.RemoveAll(directory)
+
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("AddVolumeMetrics", mock.Anything).Return(fmt.Errorf("elastic is down"))
+
+	spool, err := NewDatabase(database, unhealthy, directory, 1024*1024)
+	assert.NoError(t, err)
+	assert.NoError(t, spool.AddVolumeMetrics([]*metrics.VolumeMetric{{VolumeName: "vol1"}}))
+	assert.NoError(t, spool.AddVolumeMetrics([]*metrics.VolumeMetric{{VolumeName: "vol2"}}))
+	// Leftovers from a crash part way through spooling shouldn't be picked up
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "00000000000000000003.json.tmp"), []byte("{"), 0644))
+
+	restarted, err := NewDatabase(database, healthy, directory, 1024*1024)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, restarted.Status().Batches)
+
+	volumeNames := []string{}
+	recovered := &clientmock.MetricsDatabaseImpl{}
+	recovered.On("AddVolumeMetrics", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		volumeNames = append(volumeNames, args.Get(0).([]*metrics.VolumeMetric)[0].VolumeName)
+	})
+	recovered.On("AddArrayMetrics", mock.Anything).Return(nil)
+	restarted.database = recovered
+
+	// New batches are numbered after the ones already on disk
+	restarted.lock.Lock()
+	assert.Equal(t, uint64(3), restarted.nextSequence)
+	restarted.lock.Unlock()
+
+	assert.NoError(t, restarted.Replay())
+	assert.Equal(t, []string{"vol1", "vol2"}, volumeNames)
+
+	_, err = os.Stat(directory + "/00000000000000000003.json.tmp")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCleanPassesThrough(t *testing.T) {
+	directory := createSpoolDirectory(t)
+	defer os.RemoveAll(directory)
+
+	database := &clientmock.MetricsDatabaseImpl{}
+	database.On("CleanArrayMetrics", 31).Return(nil)
+	database.On("CleanAlerts", 365).Return(fmt.Errorf("elastic is down"))
+
+	spool, err := NewDatabase(database, healthy, directory, 1024*1024)
+	assert.NoError(t, err)
+
+	assert.NoError(t, spool.CleanArrayMetrics(31))
+	assert.Error(t, spool.CleanAlerts(365))
+	database.AssertExpectations(t)
+}
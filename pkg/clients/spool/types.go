@@ -0,0 +1,72 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spool
+
+import (
+	"context"
+	"sync"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+// Database is a metrics.Database that wraps another one (normally Elastic) with a write-ahead spool
+// on disk. Writes that fail are appended to the spool instead of being lost, and are replayed in order
+// once the wrapped database reports healthy again. Whole batches are spooled, even if some of the batch
+// was written, so the wrapped database has to skip metrics it already has. The spool is bounded in size:
+// when it's full, the oldest batches are evicted to make room for new ones.
+type Database struct {
+	database    metrics.Database
+	healthCheck func(ctx context.Context) bool
+	directory   string
+	maxBytes    int64
+
+	lock         sync.Mutex
+	batches      []spooledBatch // Oldest first
+	bytes        int64
+	nextSequence uint64
+	spooled      uint64
+	replayed     uint64
+	evicted      uint64
+	dropped      uint64
+
+	// Only one replay runs at a time, so batches are replayed in order
+	replayLock sync.Mutex
+}
+
+// Status gives the current depth of the spool and what's happened to it since startup
+type Status struct {
+	Batches  int    // Batches currently waiting to be replayed
+	Bytes    int64  // Size of the batches waiting to be replayed
+	Spooled  uint64 // Batches written to the spool
+	Replayed uint64 // Batches successfully replayed to the wrapped database
+	Evicted  uint64 // Batches discarded to keep the spool under its size limit (or because they couldn't be read)
+	Dropped  uint64 // Batches discarded because the wrapped database rejected them or they failed to replay too often
+}
+
+// spooledBatch tracks a batch file on disk
+type spooledBatch struct {
+	sequence uint64
+	path     string
+	size     int64
+	attempts int // Failed replays since startup
+}
+
+// batch is the on-disk format of a single spooled write
+type batch struct {
+	Kind          string                  `json:"kind"`
+	ArrayMetrics  []*metrics.ArrayMetric  `json:"array_metrics,omitempty"`
+	VolumeMetrics []*metrics.VolumeMetric `json:"volume_metrics,omitempty"`
+	Alerts        []*metrics.Alert        `json:"alerts,omitempty"`
+}
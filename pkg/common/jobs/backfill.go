@@ -0,0 +1,139 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/timing"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/workerpool"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ workerpool.Job = (*ArrayBackfillJob)(nil)
+
+// Description gets a string description of this job
+func (m *ArrayBackfillJob) Description() string {
+	return fmt.Sprintf("Array metric backfill job for array %s", getDeviceSummary(m.TargetArray))
+}
+
+// Execute finds the gaps in the metrics collected for the given array, and fills them in from the array's
+// performance history (which goes straight to the database, so it never reaches the current metric gauges)
+func (m *ArrayBackfillJob) Execute() {
+	if m.TargetArray == nil {
+		log.Error("Tried to backfill metrics for nil array, stopping")
+		return
+	}
+
+	arrayID := m.TargetArray.ID
+	arrayName := m.TargetArray.Name
+
+	if m.TargetDatabase == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to backfill metrics, but database was nil, stopping (nowhere to put data)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayBackfillJob.Execute", log.Fields{
+		"array_id":   arrayID,
+		"array_name": arrayName,
+	})
+	defer timer.Finish()
+
+	now := time.Now()
+	since := now.Add(-time.Duration(m.MaxAgeInHours) * time.Hour)
+	collectionTimes, err := m.TargetDatabase.GetArrayMetricTimes(arrayID, since)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Error getting metric collection times, stopping")
+		return
+	}
+
+	gaps := metrics.FindMetricGaps(collectionTimes, since.Unix(), now.Unix(), m.MaxInterval)
+	if len(gaps) == 0 {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Trace("No gaps in collected metrics, stopping")
+		return
+	}
+
+	timer.Stage("initializing")
+
+	log.WithField("array", *m.TargetArray).Trace("Instantiating connection for array")
+	connection, err := m.CollectorFactory.InitializeCollector(m.TargetArray)
+	if err != nil {
+		log.WithError(err).Error("Error instantiating connection for array, stopping")
+		return
+	}
+
+	historyConnection, ok := connection.(resources.HistoryCollector)
+	if !ok {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Trace("Array type has no performance history to backfill from, stopping")
+		return
+	}
+
+	timer.Stage("collecting")
+
+	backfilled := []*metrics.ArrayMetric{}
+	for _, gap := range gaps {
+		history, err := historyConnection.GetArrayPerformanceHistory(gap.Start, gap.End)
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{
+				"array_id":   arrayID,
+				"array_name": arrayName,
+				"end":        gap.End,
+				"start":      gap.Start,
+			}).Error("Error collecting performance history for metric gap")
+			continue
+		}
+		for _, metric := range history {
+			// Both ends of the gap were collected already
+			if metric.CreatedAt > gap.Start && metric.CreatedAt < gap.End {
+				backfilled = append(backfilled, metric)
+			}
+		}
+	}
+
+	timer.Stage("pushing")
+
+	err = m.TargetDatabase.AddBackfilledArrayMetrics(backfilled)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Error pushing backfilled metrics")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"array_id":   arrayID,
+		"array_name": arrayName,
+		"gaps":       len(gaps),
+		"metrics":    len(backfilled),
+	}).Info("Backfilled gaps in collected metrics")
+}
@@ -62,6 +62,151 @@ func (m *MetricCleanupJob) Execute() {
 	log.Trace("Completed device metrics cleanup job")
 }
 
+// Type guard: ensure this implements the interface
+var _ workerpool.Job = (*HostMetricCleanupJob)(nil)
+
+// Description gets a string description of this job
+func (m *HostMetricCleanupJob) Description() string {
+	return fmt.Sprintf("Device host metrics cleanup job")
+}
+
+// Execute cleans up the old host metrics in the given database
+func (m *HostMetricCleanupJob) Execute() {
+	if m.TargetDatabase == nil {
+		log.Error("Tried to cleanup host metrics in nil database, stopping")
+		return
+	}
+
+	log.Trace("Starting to cleanup device host metrics")
+	timer := timing.NewStageTimer("HostMetricCleanupJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	err := m.TargetDatabase.CleanHostMetrics(m.MaxAgeInDays)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Error cleaning device host metrics, stopping")
+		return
+	}
+	log.Trace("Completed device host metrics cleanup job")
+}
+
+// Type guard: ensure this implements the interface
+var _ workerpool.Job = (*ProtectionGroupMetricCleanupJob)(nil)
+
+// Description gets a string description of this job
+func (m *ProtectionGroupMetricCleanupJob) Description() string {
+	return fmt.Sprintf("Device protection group metrics cleanup job")
+}
+
+// Execute cleans up the old protection group metrics in the given database
+func (m *ProtectionGroupMetricCleanupJob) Execute() {
+	if m.TargetDatabase == nil {
+		log.Error("Tried to cleanup protection group metrics in nil database, stopping")
+		return
+	}
+
+	log.Trace("Starting to cleanup device protection group metrics")
+	timer := timing.NewStageTimer("ProtectionGroupMetricCleanupJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	err := m.TargetDatabase.CleanProtectionGroupMetrics(m.MaxAgeInDays)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Error cleaning device protection group metrics, stopping")
+		return
+	}
+	log.Trace("Completed device protection group metrics cleanup job")
+}
+
+// Type guard: ensure this implements the interface
+var _ workerpool.Job = (*PodMetricCleanupJob)(nil)
+
+// Description gets a string description of this job
+func (m *PodMetricCleanupJob) Description() string {
+	return fmt.Sprintf("Device pod metrics cleanup job")
+}
+
+// Execute cleans up the old pod metrics in the given database
+func (m *PodMetricCleanupJob) Execute() {
+	if m.TargetDatabase == nil {
+		log.Error("Tried to cleanup pod metrics in nil database, stopping")
+		return
+	}
+
+	log.Trace("Starting to cleanup device pod metrics")
+	timer := timing.NewStageTimer("PodMetricCleanupJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	err := m.TargetDatabase.CleanPodMetrics(m.MaxAgeInDays)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Error cleaning device pod metrics, stopping")
+		return
+	}
+	log.Trace("Completed device pod metrics cleanup job")
+}
+
+// Type guard: ensure this implements the interface
+var _ workerpool.Job = (*HardwareMetricCleanupJob)(nil)
+
+// Description gets a string description of this job
+func (m *HardwareMetricCleanupJob) Description() string {
+	return fmt.Sprintf("Device hardware metrics cleanup job")
+}
+
+// Execute cleans up the old hardware metrics in the given database
+func (m *HardwareMetricCleanupJob) Execute() {
+	if m.TargetDatabase == nil {
+		log.Error("Tried to cleanup hardware metrics in nil database, stopping")
+		return
+	}
+
+	log.Trace("Starting to cleanup device hardware metrics")
+	timer := timing.NewStageTimer("HardwareMetricCleanupJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	err := m.TargetDatabase.CleanHardwareMetrics(m.MaxAgeInDays)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Error cleaning device hardware metrics, stopping")
+		return
+	}
+	log.Trace("Completed device hardware metrics cleanup job")
+}
+
+// Type guard: ensure this implements the interface
+var _ workerpool.Job = (*AuditEventCleanupJob)(nil)
+
+// Description gets a string description of this job
+func (m *AuditEventCleanupJob) Description() string {
+	return fmt.Sprintf("Device audit events cleanup job")
+}
+
+// Execute cleans up the old audit events in the given database
+func (m *AuditEventCleanupJob) Execute() {
+	if m.TargetDatabase == nil {
+		log.Error("Tried to cleanup audit events in nil database, stopping")
+		return
+	}
+
+	log.Trace("Starting to cleanup device audit events")
+	timer := timing.NewStageTimer("AuditEventCleanupJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	err := m.TargetDatabase.CleanAuditEvents(m.MaxAgeInDays)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Error cleaning device audit events, stopping")
+		return
+	}
+	log.Trace("Completed device audit events cleanup job")
+}
+
 // Type guard: ensure this implements the interface
 var _ workerpool.Job = (*AlertCleanupJob)(nil)
 
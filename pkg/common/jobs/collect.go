@@ -32,6 +32,16 @@ func getDeviceSummary(a *resources.ArrayRegistrationInfo) string {
 	return fmt.Sprintf("ID %s (display name %s)", a.ID, a.Name)
 }
 
+// Shared helper between all device-operating jobs to drop a cached collector that failed, so the next
+// job connects again (failing over to another management endpoint if needed)
+func evictCollector(factory resources.CollectorFactory, a *resources.ArrayRegistrationInfo) {
+	cache, ok := factory.(resources.CollectorCache)
+	if !ok || a == nil {
+		return
+	}
+	cache.EvictCollector(a.ID)
+}
+
 // Type guard: ensure this implements the interface
 //var _ workerpool.Job = (*ArrayMetricCollectJob)(nil)
 
@@ -87,6 +97,7 @@ func (m *ArrayMetricCollectJob) Execute() {
 			"array_id":   arrayID,
 			"array_name": arrayName,
 		}).Error("Error collecting array metrics")
+		evictCollector(m.CollectorFactory, m.TargetArray)
 		return
 	}
 
@@ -156,6 +167,7 @@ func (m *ArrayVolumeMetricCollectJob) Execute() {
 			"array_id":   arrayID,
 			"array_name": arrayName,
 		}).Error("Error collecting volume metrics")
+		evictCollector(m.CollectorFactory, m.TargetArray)
 		return
 	}
 
@@ -167,3 +179,466 @@ func (m *ArrayVolumeMetricCollectJob) Execute() {
 
 	m.TargetPool.Enqueue(volumePushJob, 60*time.Second)
 }
+
+// Description gets a string description of this job
+func (m *ArrayHostMetricCollectJob) Description() string {
+	return fmt.Sprintf("Array host collection job for array %s", getDeviceSummary(m.TargetArray))
+}
+
+// Execute fetches the host metrics for the given array and enqueues a job to push them
+func (m *ArrayHostMetricCollectJob) Execute() {
+	if m.TargetArray == nil {
+		log.Error("Tried to fetch host metrics for nil array, stopping")
+		return
+	}
+
+	arrayID := m.TargetArray.ID
+	arrayName := m.TargetArray.Name
+
+	if m.TargetDatabase == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch host metrics, but database was nil, stopping (nowhere to put data)")
+		return
+	}
+
+	if m.TargetPool == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch host metrics, but worker pool was nil, stopping (nowhere to put data push jobs)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayHostMetricCollectJob.Execute", log.Fields{
+		"array_id":   arrayID,
+		"array_name": arrayName,
+	})
+	defer timer.Finish()
+
+	log.WithField("array", *m.TargetArray).Trace("Instantiating connection for array")
+	connection, err := m.CollectorFactory.InitializeCollector(m.TargetArray)
+	if err != nil {
+		log.WithError(err).Error("Error instantiating connection for array, stopping")
+		return
+	}
+
+	hostConnection, ok := connection.(resources.HostCollector)
+	if !ok {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Trace("Array type has no hosts to collect, stopping")
+		return
+	}
+
+	timer.Stage("collecting")
+
+	hostData, err := hostConnection.GetAllHostData()
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Error collecting host metrics")
+		evictCollector(m.CollectorFactory, m.TargetArray)
+		return
+	}
+
+	// Dispatch pushing job
+	hostPushJob := &ArrayHostMetricPushJob{
+		Metrics:        hostData.HostMetrics,
+		TargetDatabase: m.TargetDatabase,
+	}
+
+	m.TargetPool.Enqueue(hostPushJob, 60*time.Second)
+}
+
+// Description gets a string description of this job
+func (m *ArrayProtectionGroupCollectJob) Description() string {
+	return fmt.Sprintf("Array protection group collection job for array %s", getDeviceSummary(m.TargetArray))
+}
+
+// Execute fetches the protection group metrics for the given array and enqueues a job to push them
+func (m *ArrayProtectionGroupCollectJob) Execute() {
+	if m.TargetArray == nil {
+		log.Error("Tried to fetch protection group metrics for nil array, stopping")
+		return
+	}
+
+	arrayID := m.TargetArray.ID
+	arrayName := m.TargetArray.Name
+
+	if m.TargetDatabase == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch protection group metrics, but database was nil, stopping (nowhere to put data)")
+		return
+	}
+
+	if m.TargetPool == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch protection group metrics, but worker pool was nil, stopping (nowhere to put data push jobs)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayProtectionGroupCollectJob.Execute", log.Fields{
+		"array_id":   arrayID,
+		"array_name": arrayName,
+	})
+	defer timer.Finish()
+
+	log.WithField("array", *m.TargetArray).Trace("Instantiating connection for array")
+	connection, err := m.CollectorFactory.InitializeCollector(m.TargetArray)
+	if err != nil {
+		log.WithError(err).Error("Error instantiating connection for array, stopping")
+		return
+	}
+
+	protectionGroupConnection, ok := connection.(resources.ProtectionGroupCollector)
+	if !ok {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Trace("Array type has no protection groups to collect, stopping")
+		return
+	}
+
+	timer.Stage("collecting")
+
+	protectionGroupData, err := protectionGroupConnection.GetAllProtectionGroupData()
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Error collecting protection group metrics")
+		evictCollector(m.CollectorFactory, m.TargetArray)
+		return
+	}
+
+	// Dispatch pushing job
+	protectionGroupPushJob := &ArrayProtectionGroupPushJob{
+		Metrics:        protectionGroupData.ProtectionGroupMetrics,
+		TargetDatabase: m.TargetDatabase,
+	}
+
+	m.TargetPool.Enqueue(protectionGroupPushJob, 60*time.Second)
+}
+
+// Description gets a string description of this job
+func (m *ArrayPodCollectJob) Description() string {
+	return fmt.Sprintf("Array pod collection job for array %s", getDeviceSummary(m.TargetArray))
+}
+
+// Execute fetches the pod metrics for the given array and enqueues a job to push them
+func (m *ArrayPodCollectJob) Execute() {
+	if m.TargetArray == nil {
+		log.Error("Tried to fetch pod metrics for nil array, stopping")
+		return
+	}
+
+	arrayID := m.TargetArray.ID
+	arrayName := m.TargetArray.Name
+
+	if m.TargetDatabase == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch pod metrics, but database was nil, stopping (nowhere to put data)")
+		return
+	}
+
+	if m.TargetPool == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch pod metrics, but worker pool was nil, stopping (nowhere to put data push jobs)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayPodCollectJob.Execute", log.Fields{
+		"array_id":   arrayID,
+		"array_name": arrayName,
+	})
+	defer timer.Finish()
+
+	log.WithField("array", *m.TargetArray).Trace("Instantiating connection for array")
+	connection, err := m.CollectorFactory.InitializeCollector(m.TargetArray)
+	if err != nil {
+		log.WithError(err).Error("Error instantiating connection for array, stopping")
+		return
+	}
+
+	podConnection, ok := connection.(resources.PodCollector)
+	if !ok {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Trace("Array type has no pods to collect, stopping")
+		return
+	}
+
+	timer.Stage("collecting")
+
+	podData, err := podConnection.GetAllPodData()
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Error collecting pod metrics")
+		evictCollector(m.CollectorFactory, m.TargetArray)
+		return
+	}
+
+	// Dispatch pushing job
+	podPushJob := &ArrayPodPushJob{
+		Metrics:        podData.PodMetrics,
+		TargetDatabase: m.TargetDatabase,
+	}
+
+	m.TargetPool.Enqueue(podPushJob, 60*time.Second)
+}
+
+// Description gets a string description of this job
+func (m *ArrayHardwareCollectJob) Description() string {
+	return fmt.Sprintf("Array hardware collection job for array %s", getDeviceSummary(m.TargetArray))
+}
+
+// Execute fetches the hardware metrics for the given array and enqueues a job to push them
+func (m *ArrayHardwareCollectJob) Execute() {
+	if m.TargetArray == nil {
+		log.Error("Tried to fetch hardware metrics for nil array, stopping")
+		return
+	}
+
+	arrayID := m.TargetArray.ID
+	arrayName := m.TargetArray.Name
+
+	if m.TargetDatabase == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch hardware metrics, but database was nil, stopping (nowhere to put data)")
+		return
+	}
+
+	if m.TargetPool == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch hardware metrics, but worker pool was nil, stopping (nowhere to put data push jobs)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayHardwareCollectJob.Execute", log.Fields{
+		"array_id":   arrayID,
+		"array_name": arrayName,
+	})
+	defer timer.Finish()
+
+	log.WithField("array", *m.TargetArray).Trace("Instantiating connection for array")
+	connection, err := m.CollectorFactory.InitializeCollector(m.TargetArray)
+	if err != nil {
+		log.WithError(err).Error("Error instantiating connection for array, stopping")
+		return
+	}
+
+	hardwareConnection, ok := connection.(resources.HardwareCollector)
+	if !ok {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Trace("Array type has no hardware to collect, stopping")
+		return
+	}
+
+	timer.Stage("collecting")
+
+	hardwareData, err := hardwareConnection.GetAllHardwareData()
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Error collecting hardware metrics")
+		evictCollector(m.CollectorFactory, m.TargetArray)
+		return
+	}
+
+	// Dispatch pushing job
+	hardwarePushJob := &ArrayHardwarePushJob{
+		Metrics:        hardwareData.HardwareMetrics,
+		TargetDatabase: m.TargetDatabase,
+	}
+
+	m.TargetPool.Enqueue(hardwarePushJob, 60*time.Second)
+}
+
+// Description gets a string description of this job
+func (m *ArrayAuditCollectJob) Description() string {
+	return fmt.Sprintf("Array audit collection job for array %s", getDeviceSummary(m.TargetArray))
+}
+
+// Execute fetches the audit events the given array has logged since the latest one in the database, and enqueues
+// a job to push them
+func (m *ArrayAuditCollectJob) Execute() {
+	if m.TargetArray == nil {
+		log.Error("Tried to fetch audit events for nil array, stopping")
+		return
+	}
+
+	arrayID := m.TargetArray.ID
+	arrayName := m.TargetArray.Name
+
+	if m.TargetDatabase == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch audit events, but database was nil, stopping (nowhere to put data)")
+		return
+	}
+
+	if m.TargetPool == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch audit events, but worker pool was nil, stopping (nowhere to put data push jobs)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayAuditCollectJob.Execute", log.Fields{
+		"array_id":   arrayID,
+		"array_name": arrayName,
+	})
+	defer timer.Finish()
+
+	log.WithField("array", *m.TargetArray).Trace("Instantiating connection for array")
+	connection, err := m.CollectorFactory.InitializeCollector(m.TargetArray)
+	if err != nil {
+		log.WithError(err).Error("Error instantiating connection for array, stopping")
+		return
+	}
+
+	auditConnection, ok := connection.(resources.AuditCollector)
+	if !ok {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Trace("Array type has no audit events to collect, stopping")
+		return
+	}
+
+	timer.Stage("find_latest")
+
+	// Pick up from the latest event already collected. Events in that same second are fetched again, but they
+	// keep their IDs, so they aren't added twice.
+	since, err := m.TargetDatabase.GetLatestAuditEventTime(arrayID)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Error finding latest audit event, stopping")
+		return
+	}
+
+	timer.Stage("collecting")
+
+	events, err := auditConnection.GetAuditEvents(since)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+			"since":      since,
+		}).Error("Error collecting audit events")
+		evictCollector(m.CollectorFactory, m.TargetArray)
+		return
+	}
+
+	// Dispatch pushing job
+	auditPushJob := &ArrayAuditPushJob{
+		Events:         events,
+		TargetDatabase: m.TargetDatabase,
+	}
+
+	m.TargetPool.Enqueue(auditPushJob, 60*time.Second)
+}
+
+// Description gets a string description of this job
+func (m *ArraySnapshotCollectJob) Description() string {
+	return fmt.Sprintf("Array snapshot inventory collection job for array %s", getDeviceSummary(m.TargetArray))
+}
+
+// Execute fetches the snapshot inventory of the given array and enqueues a job to push it
+func (m *ArraySnapshotCollectJob) Execute() {
+	if m.TargetArray == nil {
+		log.Error("Tried to fetch snapshots for nil array, stopping")
+		return
+	}
+
+	arrayID := m.TargetArray.ID
+	arrayName := m.TargetArray.Name
+
+	if m.TargetDatabase == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch snapshots, but database was nil, stopping (nowhere to put data)")
+		return
+	}
+
+	if m.TargetPool == nil {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Tried to fetch snapshots, but worker pool was nil, stopping (nowhere to put data push jobs)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArraySnapshotCollectJob.Execute", log.Fields{
+		"array_id":   arrayID,
+		"array_name": arrayName,
+	})
+	defer timer.Finish()
+
+	log.WithField("array", *m.TargetArray).Trace("Instantiating connection for array")
+	connection, err := m.CollectorFactory.InitializeCollector(m.TargetArray)
+	if err != nil {
+		log.WithError(err).Error("Error instantiating connection for array, stopping")
+		return
+	}
+
+	snapshotConnection, ok := connection.(resources.SnapshotCollector)
+	if !ok {
+		log.WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Trace("Array type has no snapshots to collect, stopping")
+		return
+	}
+
+	timer.Stage("collecting")
+
+	// Anything in the inventory from before this collection has since been deleted from the array
+	collectedAt := time.Now().Unix()
+	snapshots, err := snapshotConnection.GetSnapshots()
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"array_id":   arrayID,
+			"array_name": arrayName,
+		}).Error("Error collecting snapshots")
+		evictCollector(m.CollectorFactory, m.TargetArray)
+		return
+	}
+
+	// Dispatch pushing job
+	snapshotPushJob := &ArraySnapshotPushJob{
+		ArrayID:        arrayID,
+		CollectedAt:    collectedAt,
+		Snapshots:      snapshots,
+		TargetDatabase: m.TargetDatabase,
+	}
+
+	m.TargetPool.Enqueue(snapshotPushJob, 60*time.Second)
+}
@@ -0,0 +1,77 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/timing"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/workerpool"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ workerpool.Job = (*CapacityForecastJob)(nil)
+
+// Description gets a string description of this job
+func (c *CapacityForecastJob) Description() string {
+	return fmt.Sprintf("Capacity forecast job")
+}
+
+// Execute reads the capacity history of every array, forecasts its growth and stores the forecasts
+func (c *CapacityForecastJob) Execute() {
+	if c.TargetDatabase == nil {
+		log.Error("Tried to forecast capacity from nil database, stopping")
+		return
+	}
+
+	timer := timing.NewStageTimer("CapacityForecastJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	now := time.Now().UTC()
+	log.WithField("history_days", c.HistoryDays).Trace("Starting to fetch array capacity history")
+	history, err := c.TargetDatabase.GetArrayCapacityHistory(now.AddDate(0, 0, -c.HistoryDays))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Error fetching array capacity history, stopping")
+		return
+	}
+	timer.Stage("forecast")
+
+	forecasts := []*metrics.CapacityForecast{}
+	for arrayID, samples := range history {
+		forecast := metrics.ForecastCapacity(arrayID, samples, now)
+		log.WithFields(log.Fields{
+			"array_id":     arrayID,
+			"sample_count": forecast.SampleCount,
+			"daily_growth": forecast.DailyGrowth,
+		}).Trace("Forecast array capacity")
+		forecasts = append(forecasts, forecast)
+	}
+	timer.Stage("store_forecasts")
+
+	err = c.TargetDatabase.UpdateCapacityForecasts(forecasts)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Error storing capacity forecasts, stopping")
+		return
+	}
+	log.WithField("array_count", len(forecasts)).Info("Completed capacity forecast job")
+}
@@ -16,10 +16,12 @@ package jobs
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/workerpool"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/util"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -28,7 +30,7 @@ var _ workerpool.Job = (*MonitorCheckJob)(nil)
 
 // Description gets a string description of this job
 func (m *MonitorCheckJob) Description() string {
-	return fmt.Sprintf("Monitor check job for device ID %s (display name %s) at %s", m.DeviceInfo.ID, m.DeviceInfo.Name, m.DeviceInfo.MgmtEndpoint)
+	return fmt.Sprintf("Monitor check job for device ID %s (display name %s) at %s", m.DeviceInfo.ID, m.DeviceInfo.Name, strings.Join(m.DeviceInfo.GetMgmtEndpoints(), ", "))
 }
 
 // Execute attempts to connect to the device and pulls metadata, pushing it to the API server
@@ -49,7 +51,7 @@ func (m *MonitorCheckJob) Execute() {
 	if err != nil {
 		log.WithFields(m.DeviceInfo.GetLogFields(true)).WithError(err).Error("Error initializing array backend")
 		patchErr := m.Metadata.Patch(m.DeviceInfo.ID, &resources.ArrayPatchInfo{
-			Status: fmt.Sprintf("Unable to connect. Error: %s", err),
+			Status: connectionErrorStatus(err),
 		})
 		if patchErr != nil {
 			log.WithFields(m.DeviceInfo.GetLogFields(true)).WithFields(log.Fields{
@@ -64,8 +66,10 @@ func (m *MonitorCheckJob) Execute() {
 	model, err := backend.GetArrayModel()
 	if err != nil {
 		log.WithFields(m.DeviceInfo.GetLogFields(true)).WithError(err).Error("Error making model request to array backend")
+		evictCollector(m.DeviceFactory, m.DeviceInfo)
 		patchErr := m.Metadata.Patch(m.DeviceInfo.ID, &resources.ArrayPatchInfo{
-			Status: fmt.Sprintf("Unable to connect. Error: %s", err),
+			Status:         connectionErrorStatus(err),
+			ActiveEndpoint: backend.GetManagementEndpoint(),
 		})
 		if patchErr != nil {
 			log.WithFields(m.DeviceInfo.GetLogFields(true)).WithFields(log.Fields{
@@ -80,8 +84,10 @@ func (m *MonitorCheckJob) Execute() {
 	version, err := backend.GetArrayVersion()
 	if err != nil {
 		log.WithFields(m.DeviceInfo.GetLogFields(true)).WithError(err).Error("Error making version request to array backend")
+		evictCollector(m.DeviceFactory, m.DeviceInfo)
 		patchErr := m.Metadata.Patch(m.DeviceInfo.ID, &resources.ArrayPatchInfo{
-			Status: fmt.Sprintf("Unable to connect. Error: %s", err),
+			Status:         connectionErrorStatus(err),
+			ActiveEndpoint: backend.GetManagementEndpoint(),
 		})
 		if patchErr != nil {
 			log.WithFields(m.DeviceInfo.GetLogFields(true)).WithFields(log.Fields{
@@ -98,6 +104,8 @@ func (m *MonitorCheckJob) Execute() {
 		Model:   model,
 		Version: version,
 		AsOf:    time.Now().UTC().Format("2006-01-02T15:04:05.000"),
+		// Shows which of the array's management endpoints the collector failed over to (if any)
+		ActiveEndpoint: backend.GetManagementEndpoint(),
 	})
 	if err != nil {
 		log.WithFields(m.DeviceInfo.GetLogFields(true)).WithFields(log.Fields{
@@ -107,7 +115,17 @@ func (m *MonitorCheckJob) Execute() {
 		return
 	}
 	log.WithFields(m.DeviceInfo.GetLogFields(true)).WithFields(log.Fields{
-		"array_model":   model,
-		"array_version": version,
+		"active_endpoint": backend.GetManagementEndpoint(),
+		"array_model":     model,
+		"array_version":   version,
 	}).Trace("Finished monitor checking array and patched successfully")
 }
+
+// connectionErrorStatus is a helper function that formats the status shown for an array we couldn't talk to,
+// calling out certificate mismatches separately so they aren't mistaken for the array being down
+func connectionErrorStatus(err error) string {
+	if util.IsCertificateMismatch(err) {
+		return fmt.Sprintf("Certificate mismatch. Error: %s", err)
+	}
+	return fmt.Sprintf("Unable to connect. Error: %s", err)
+}
@@ -25,6 +25,12 @@ import (
 var _ workerpool.Job = (*ArrayMetricPushJob)(nil)
 var _ workerpool.Job = (*ArrayVolumeMetricPushJob)(nil)
 var _ workerpool.Job = (*ArrayAlertPushJob)(nil)
+var _ workerpool.Job = (*ArrayHostMetricPushJob)(nil)
+var _ workerpool.Job = (*ArrayProtectionGroupPushJob)(nil)
+var _ workerpool.Job = (*ArrayPodPushJob)(nil)
+var _ workerpool.Job = (*ArrayHardwarePushJob)(nil)
+var _ workerpool.Job = (*ArrayAuditPushJob)(nil)
+var _ workerpool.Job = (*ArraySnapshotPushJob)(nil)
 
 // Description gets a string description of this job
 func (a *ArrayMetricPushJob) Description() string {
@@ -126,3 +132,183 @@ func (a *ArrayAlertPushJob) Execute() {
 
 	log.Trace("Successfully pushed alerts")
 }
+
+// Description gets a string description of this job
+func (a *ArrayHostMetricPushJob) Description() string {
+	return "Array host metric push job"
+}
+
+// Execute pushes the given host metrics to the given database
+func (a *ArrayHostMetricPushJob) Execute() {
+	if a.Metrics == nil {
+		log.Trace("Tried to push nil host metrics array, stopping")
+		return
+	}
+
+	if a.TargetDatabase == nil {
+		log.WithField("metrics", a.Metrics).Error("Tried to push host metrics to nil database, stopping (nowhere to put data)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayHostMetricPushJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	log.Trace("Starting to push host metrics")
+	err := a.TargetDatabase.AddHostMetrics(a.Metrics)
+	if err != nil {
+		log.WithError(err).Error("Error pushing host metrics to database")
+		return
+	}
+
+	log.Trace("Successfully pushed host metrics")
+}
+
+// Description gets a string description of this job
+func (a *ArrayProtectionGroupPushJob) Description() string {
+	return "Array protection group metric push job"
+}
+
+// Execute pushes the given protection group metrics to the given database
+func (a *ArrayProtectionGroupPushJob) Execute() {
+	if a.Metrics == nil {
+		log.Trace("Tried to push nil protection group metrics array, stopping")
+		return
+	}
+
+	if a.TargetDatabase == nil {
+		log.WithField("metrics", a.Metrics).Error("Tried to push protection group metrics to nil database, stopping (nowhere to put data)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayProtectionGroupPushJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	log.Trace("Starting to push protection group metrics")
+	err := a.TargetDatabase.AddProtectionGroupMetrics(a.Metrics)
+	if err != nil {
+		log.WithError(err).Error("Error pushing protection group metrics to database")
+		return
+	}
+
+	log.Trace("Successfully pushed protection group metrics")
+}
+
+// Description gets a string description of this job
+func (a *ArrayPodPushJob) Description() string {
+	return "Array pod metric push job"
+}
+
+// Execute pushes the given pod metrics to the given database
+func (a *ArrayPodPushJob) Execute() {
+	if a.Metrics == nil {
+		log.Trace("Tried to push nil pod metrics array, stopping")
+		return
+	}
+
+	if a.TargetDatabase == nil {
+		log.WithField("metrics", a.Metrics).Error("Tried to push pod metrics to nil database, stopping (nowhere to put data)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayPodPushJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	log.Trace("Starting to push pod metrics")
+	err := a.TargetDatabase.AddPodMetrics(a.Metrics)
+	if err != nil {
+		log.WithError(err).Error("Error pushing pod metrics to database")
+		return
+	}
+
+	log.Trace("Successfully pushed pod metrics")
+}
+
+// Description gets a string description of this job
+func (a *ArrayHardwarePushJob) Description() string {
+	return "Array hardware metric push job"
+}
+
+// Execute pushes the given hardware metrics to the given database
+func (a *ArrayHardwarePushJob) Execute() {
+	if a.Metrics == nil {
+		log.Trace("Tried to push nil hardware metrics array, stopping")
+		return
+	}
+
+	if a.TargetDatabase == nil {
+		log.WithField("metrics", a.Metrics).Error("Tried to push hardware metrics to nil database, stopping (nowhere to put data)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayHardwarePushJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	log.Trace("Starting to push hardware metrics")
+	err := a.TargetDatabase.AddHardwareMetrics(a.Metrics)
+	if err != nil {
+		log.WithError(err).Error("Error pushing hardware metrics to database")
+		return
+	}
+
+	log.Trace("Successfully pushed hardware metrics")
+}
+
+// Description gets a string description of this job
+func (a *ArrayAuditPushJob) Description() string {
+	return "Array audit event push job"
+}
+
+// Execute pushes the given audit events to the given database
+func (a *ArrayAuditPushJob) Execute() {
+	if a.Events == nil {
+		log.Trace("Tried to push nil audit events array, stopping")
+		return
+	}
+
+	if a.TargetDatabase == nil {
+		log.WithField("events", a.Events).Error("Tried to push audit events to nil database, stopping (nowhere to put data)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArrayAuditPushJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	log.Trace("Starting to push audit events")
+	err := a.TargetDatabase.AddAuditEvents(a.Events)
+	if err != nil {
+		log.WithError(err).Error("Error pushing audit events to database")
+		return
+	}
+
+	log.Trace("Successfully pushed audit events")
+}
+
+// Description gets a string description of this job
+func (a *ArraySnapshotPushJob) Description() string {
+	return "Array snapshot inventory push job"
+}
+
+// Execute replaces the snapshot inventory of the given array in the given database
+func (a *ArraySnapshotPushJob) Execute() {
+	if a.Snapshots == nil {
+		log.Trace("Tried to push nil snapshots array, stopping")
+		return
+	}
+
+	if a.TargetDatabase == nil {
+		log.WithField("array_id", a.ArrayID).Error("Tried to push snapshots to nil database, stopping (nowhere to put data)")
+		return
+	}
+
+	timer := timing.NewStageTimer("ArraySnapshotPushJob.Execute", log.Fields{"array_id": a.ArrayID})
+	defer timer.Finish()
+
+	log.WithField("array_id", a.ArrayID).Trace("Starting to push snapshots")
+	err := a.TargetDatabase.UpdateArraySnapshots(a.ArrayID, a.CollectedAt, a.Snapshots)
+	if err != nil {
+		log.WithError(err).WithField("array_id", a.ArrayID).Error("Error pushing snapshots to database")
+		return
+	}
+
+	log.WithField("array_id", a.ArrayID).Trace("Successfully pushed snapshots")
+}
@@ -0,0 +1,63 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/timing"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/workerpool"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Type guard: ensure this implements the interface
+var _ workerpool.Job = (*MetricRollupJob)(nil)
+
+// Description gets a string description of this job
+func (m *MetricRollupJob) Description() string {
+	return fmt.Sprintf("Device metrics rollup job")
+}
+
+// Execute rolls up raw metrics in the given database, then cleans up old rollups
+func (m *MetricRollupJob) Execute() {
+	if m.TargetDatabase == nil {
+		log.Error("Tried to roll up metrics in nil database, stopping")
+		return
+	}
+
+	timer := timing.NewStageTimer("MetricRollupJob.Execute", log.Fields{})
+	defer timer.Finish()
+
+	log.Trace("Starting to roll up device metrics")
+	err := m.TargetDatabase.RollupMetrics()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Error rolling up device metrics, stopping")
+		return
+	}
+	log.Trace("Finished rolling up device metrics, starting rollup cleanup")
+	timer.Stage("rollup_cleanup")
+
+	err = m.TargetDatabase.CleanMetricRollups(m.HourlyMaxAgeInDays, m.DailyMaxAgeInDays)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Error cleaning device metric rollups, stopping")
+		return
+	}
+	log.Trace("Completed device metrics rollup job")
+}
@@ -49,6 +49,74 @@ type ArrayVolumeMetricCollectJob struct {
 	TimeWindow       int64
 }
 
+// ArrayHostMetricCollectJob is a Job used to fetch the host and host group metrics for a given array
+// which then kicks off another job to push the metrics to the given database
+// (Does nothing for arrays without hosts, such as FlashBlade)
+type ArrayHostMetricCollectJob struct {
+	TargetArray      *resources.ArrayRegistrationInfo
+	CollectorFactory resources.CollectorFactory
+	TargetDatabase   metrics.HostDatabase
+	TargetPool       *workerpool.Pool
+}
+
+// ArrayProtectionGroupCollectJob is a Job used to fetch the protection group metrics for a given array
+// which then kicks off another job to push the metrics to the given database
+// (Does nothing for arrays without protection groups, such as FlashBlade)
+type ArrayProtectionGroupCollectJob struct {
+	TargetArray      *resources.ArrayRegistrationInfo
+	CollectorFactory resources.CollectorFactory
+	TargetDatabase   metrics.ProtectionGroupDatabase
+	TargetPool       *workerpool.Pool
+}
+
+// ArrayPodCollectJob is a Job used to fetch the ActiveCluster pod metrics for a given array
+// which then kicks off another job to push the metrics to the given database
+// (Does nothing for arrays without pods, such as FlashBlade)
+type ArrayPodCollectJob struct {
+	TargetArray      *resources.ArrayRegistrationInfo
+	CollectorFactory resources.CollectorFactory
+	TargetDatabase   metrics.PodDatabase
+	TargetPool       *workerpool.Pool
+}
+
+// ArrayHardwareCollectJob is a Job used to fetch the hardware component metrics for a given array
+// which then kicks off another job to push the metrics to the given database
+type ArrayHardwareCollectJob struct {
+	TargetArray      *resources.ArrayRegistrationInfo
+	CollectorFactory resources.CollectorFactory
+	TargetDatabase   metrics.HardwareDatabase
+	TargetPool       *workerpool.Pool
+}
+
+// ArrayAuditCollectJob is a Job used to fetch the audit events an array has logged since the latest one already
+// in the given database, which then kicks off another job to push them to that database
+type ArrayAuditCollectJob struct {
+	TargetArray      *resources.ArrayRegistrationInfo
+	CollectorFactory resources.CollectorFactory
+	TargetDatabase   metrics.AuditDatabase
+	TargetPool       *workerpool.Pool
+}
+
+// ArraySnapshotCollectJob is a Job used to fetch the snapshot inventory of a given array which then kicks off
+// another job to replace the inventory in the given database
+type ArraySnapshotCollectJob struct {
+	TargetArray      *resources.ArrayRegistrationInfo
+	CollectorFactory resources.CollectorFactory
+	TargetDatabase   metrics.SnapshotDatabase
+	TargetPool       *workerpool.Pool
+}
+
+// ArrayBackfillJob is a Job used to find gaps in the metrics collected for a given array, and to fill them in
+// from the performance history the array keeps
+// (Does nothing for arrays without performance history)
+type ArrayBackfillJob struct {
+	TargetArray      *resources.ArrayRegistrationInfo
+	CollectorFactory resources.CollectorFactory
+	TargetDatabase   metrics.BackfillDatabase
+	MaxAgeInHours    int   // How far back to look for gaps
+	MaxInterval      int64 // Longest time between collections (in seconds) that isn't a gap
+}
+
 // ArrayMetricPushJob pushes the given metric to the given database
 type ArrayMetricPushJob struct {
 	TargetDatabase metrics.Database
@@ -61,6 +129,44 @@ type ArrayVolumeMetricPushJob struct {
 	Metrics        []*metrics.VolumeMetric
 }
 
+// ArrayHostMetricPushJob pushes the given host metrics to the given database
+type ArrayHostMetricPushJob struct {
+	TargetDatabase metrics.HostDatabase
+	Metrics        []*metrics.HostMetric
+}
+
+// ArrayProtectionGroupPushJob pushes the given protection group metrics to the given database
+type ArrayProtectionGroupPushJob struct {
+	TargetDatabase metrics.ProtectionGroupDatabase
+	Metrics        []*metrics.ProtectionGroupMetric
+}
+
+// ArrayPodPushJob pushes the given pod metrics to the given database
+type ArrayPodPushJob struct {
+	TargetDatabase metrics.PodDatabase
+	Metrics        []*metrics.PodMetric
+}
+
+// ArrayHardwarePushJob pushes the given hardware metrics to the given database
+type ArrayHardwarePushJob struct {
+	TargetDatabase metrics.HardwareDatabase
+	Metrics        []*metrics.HardwareMetric
+}
+
+// ArrayAuditPushJob pushes the given audit events to the given database
+type ArrayAuditPushJob struct {
+	TargetDatabase metrics.AuditDatabase
+	Events         []*metrics.AuditEvent
+}
+
+// ArraySnapshotPushJob replaces the snapshot inventory of the given array in the given database
+type ArraySnapshotPushJob struct {
+	TargetDatabase metrics.SnapshotDatabase
+	ArrayID        string
+	CollectedAt    int64 // Unix seconds, snapshots collected before this are no longer on the array
+	Snapshots      []*metrics.Snapshot
+}
+
 // ArrayAlertPushJob pushes the given alerts to the given database
 type ArrayAlertPushJob struct {
 	TargetDatabase metrics.Database
@@ -85,6 +191,49 @@ type MetricCleanupJob struct {
 	MaxAgeInDays   int
 }
 
+// HostMetricCleanupJob is a Job used to cleanup old host metrics in the given database
+type HostMetricCleanupJob struct {
+	TargetDatabase metrics.HostDatabase
+	MaxAgeInDays   int
+}
+
+// ProtectionGroupMetricCleanupJob is a Job used to cleanup old protection group metrics in the given database
+type ProtectionGroupMetricCleanupJob struct {
+	TargetDatabase metrics.ProtectionGroupDatabase
+	MaxAgeInDays   int
+}
+
+// PodMetricCleanupJob is a Job used to cleanup old pod metrics in the given database
+type PodMetricCleanupJob struct {
+	TargetDatabase metrics.PodDatabase
+	MaxAgeInDays   int
+}
+
+// HardwareMetricCleanupJob is a Job used to cleanup old hardware metrics in the given database
+type HardwareMetricCleanupJob struct {
+	TargetDatabase metrics.HardwareDatabase
+	MaxAgeInDays   int
+}
+
+// AuditEventCleanupJob is a Job used to cleanup old audit events in the given database
+type AuditEventCleanupJob struct {
+	TargetDatabase metrics.AuditDatabase
+	MaxAgeInDays   int
+}
+
+// CapacityForecastJob is a Job used to forecast the capacity growth of every array from its capacity history
+type CapacityForecastJob struct {
+	TargetDatabase metrics.ForecastDatabase
+	HistoryDays    int
+}
+
+// MetricRollupJob is a Job used to roll up raw array metrics into hourly and daily rollups, and to clean up old rollups
+type MetricRollupJob struct {
+	TargetDatabase     metrics.RollupDatabase
+	HourlyMaxAgeInDays int
+	DailyMaxAgeInDays  int
+}
+
 // TimerLogCleanupJob is a job used to clean up stage timer logs in the given database
 type TimerLogCleanupJob struct {
 	TargetDatabase metrics.Database
@@ -0,0 +1,199 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/olivere/elastic"
+)
+
+// GenerateElasticQueryObject converts this query into an elastic.Query, with alerts snoozed past the given time
+// counting as snoozed
+func (q *AlertQuery) GenerateElasticQueryObject(now time.Time) elastic.Query {
+	filters := []elastic.Query{}
+	if len(q.IDs) > 0 {
+		filters = append(filters, generateIdsQueryObject(q.IDs...))
+	}
+	if len(q.ArrayIDs) > 0 {
+		filters = append(filters, elastic.NewTermsQuery("ArrayID", convertToInterfaceSlice(q.ArrayIDs)...))
+	}
+	if len(q.States) > 0 {
+		filters = append(filters, elastic.NewTermsQuery("State", convertToInterfaceSlice(q.States)...))
+	}
+	if len(q.AssignedTo) > 0 {
+		filters = append(filters, elastic.NewTermQuery("Lifecycle.AssignedTo", q.AssignedTo))
+	}
+
+	query := elastic.NewBoolQuery().Filter(filters...)
+	if q.Acknowledged != nil {
+		acknowledged := elastic.NewTermQuery("Lifecycle.Acknowledged", true)
+		if *q.Acknowledged {
+			query.Filter(acknowledged)
+		} else {
+			query.MustNot(acknowledged)
+		}
+	}
+	if q.Snoozed != nil {
+		snoozed := elastic.NewRangeQuery("Lifecycle.SnoozedUntil").Gt(now.Unix())
+		if *q.Snoozed {
+			query.Filter(snoozed)
+		} else {
+			query.MustNot(snoozed)
+		}
+	}
+	return query
+}
+
+// ConvertToAlertMap converts the given alert into a string->interface map suitable for marshalling, with
+// its lifecycle flattened in alongside the fields reported by the array
+func ConvertToAlertMap(alert *metrics.Alert) map[string]interface{} {
+	lifecycle := alert.Lifecycle
+	if lifecycle == nil {
+		lifecycle = &metrics.AlertLifecycle{}
+	}
+
+	notes := []map[string]interface{}{}
+	for _, note := range lifecycle.Notes {
+		notes = append(notes, map[string]interface{}{
+			"author":  note.Author,
+			"created": convertUnixTime(note.Created),
+			"text":    note.Text,
+		})
+	}
+
+	return map[string]interface{}{
+		"id":                   alert.GetDocumentID(),
+		"alert_id":             alert.AlertID,
+		"array_id":             alert.ArrayID,
+		"array_name":           alert.ArrayName,
+		"array_display_name":   alert.ArrayDisplayName,
+		"code":                 alert.Code,
+		"severity":             alert.Severity,
+		"state":                alert.State,
+		"summary":              alert.Summary,
+		"component":            alert.Component,
+		"source":               alert.Source,
+		"flagged":              alert.Flagged,
+		"in_maintenance":       alert.InMaintenance,
+		"created":              convertUnixTime(alert.Created),
+		"updated":              convertUnixTime(alert.Updated),
+		"acknowledged":         lifecycle.Acknowledged,
+		"acknowledged_by":      lifecycle.AcknowledgedBy,
+		"acknowledged_at":      convertUnixTime(lifecycle.AcknowledgedAt),
+		"assigned_to":          lifecycle.AssignedTo,
+		"snoozed_until":        convertUnixTime(lifecycle.SnoozedUntil),
+		"notes":                notes,
+		"lifecycle_updated_by": lifecycle.UpdatedBy,
+		"_lifecycle_updated":   convertUnixTime(lifecycle.Updated),
+	}
+}
+
+// ApplyAlertLifecyclePatch applies the given patch to the lifecycle of the given alert on behalf of the given user,
+// with the map in the format accepted by the REST API ("acknowledged", "assigned_to", "note" and "snoozed_until").
+// Notes are added to the existing ones rather than replacing them, and a null or empty "snoozed_until" unsnoozes the alert.
+func ApplyAlertLifecyclePatch(alert *metrics.Alert, m map[string]interface{}, user string, now time.Time) error {
+	for key := range m {
+		switch key {
+		case "acknowledged", "assigned_to", "note", "snoozed_until":
+		default:
+			return fmt.Errorf("Key %s cannot be changed, only acknowledged, assigned_to, note and snoozed_until can", key)
+		}
+	}
+
+	lifecycle := metrics.AlertLifecycle{}
+	if alert.Lifecycle != nil {
+		lifecycle = *alert.Lifecycle
+		lifecycle.Notes = append([]*metrics.AlertNote{}, alert.Lifecycle.Notes...) // So the original isn't appended to
+	}
+
+	if value, ok := m["acknowledged"]; ok {
+		acknowledged, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("Key acknowledged must be a boolean")
+		}
+		if !acknowledged {
+			lifecycle.Acknowledged = false
+			lifecycle.AcknowledgedBy = ""
+			lifecycle.AcknowledgedAt = 0
+		} else if !lifecycle.Acknowledged {
+			// Acknowledging again keeps whoever acknowledged it first
+			lifecycle.Acknowledged = true
+			lifecycle.AcknowledgedBy = user
+			lifecycle.AcknowledgedAt = now.Unix()
+		}
+	}
+	if value, ok := m["assigned_to"]; ok {
+		assignedTo, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key assigned_to must be a string")
+		}
+		lifecycle.AssignedTo = strings.TrimSpace(assignedTo)
+	}
+	if value, ok := m["note"]; ok {
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key note must be a string")
+		}
+		text = strings.TrimSpace(text)
+		if len(text) == 0 {
+			return fmt.Errorf("Key note must not be empty")
+		}
+		lifecycle.Notes = append(lifecycle.Notes, &metrics.AlertNote{Author: user, Created: now.Unix(), Text: text})
+	}
+	if value, ok := m["snoozed_until"]; ok {
+		snoozedUntil, ok := value.(string)
+		if value != nil && !ok {
+			return fmt.Errorf("Key snoozed_until must be a string")
+		}
+		if len(snoozedUntil) == 0 {
+			lifecycle.SnoozedUntil = 0
+		} else {
+			parsed, err := time.Parse(time.RFC3339, snoozedUntil)
+			if err != nil {
+				return fmt.Errorf("Key snoozed_until must be an RFC 3339 time, such as 2019-01-02T15:04:05Z")
+			}
+			if !parsed.After(now) {
+				return fmt.Errorf("Key snoozed_until must be in the future")
+			}
+			lifecycle.SnoozedUntil = parsed.Unix()
+		}
+	}
+
+	lifecycle.UpdatedBy = user
+	lifecycle.Updated = now.Unix()
+	alert.Lifecycle = &lifecycle
+	return nil
+}
+
+// convertUnixTime is a helper function that converts Unix seconds into a UTC time, or nil if unset
+func convertUnixTime(seconds int64) interface{} {
+	if seconds == 0 {
+		return nil
+	}
+	return time.Unix(seconds, 0).UTC()
+}
+
+// convertToInterfaceSlice is a helper function for building terms queries
+func convertToInterfaceSlice(values []string) []interface{} {
+	converted := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		converted = append(converted, value)
+	}
+	return converted
+}
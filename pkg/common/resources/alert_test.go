@@ -0,0 +1,145 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyAlertLifecyclePatch(t *testing.T) {
+	now := time.Date(2019, 1, 2, 15, 4, 5, 0, time.UTC)
+	alert := &metrics.Alert{ArrayID: "array-1", AlertID: 42}
+
+	err := ApplyAlertLifecyclePatch(alert, map[string]interface{}{
+		"acknowledged":  true,
+		"assigned_to":   " someone@example.com ",
+		"note":          "Replacing the controller",
+		"snoozed_until": "2019-01-03T00:00:00Z",
+	}, "admin@example.com", now)
+	assert.NoError(t, err)
+	if assert.NotNil(t, alert.Lifecycle) {
+		assert.True(t, alert.Lifecycle.Acknowledged)
+		assert.Equal(t, "admin@example.com", alert.Lifecycle.AcknowledgedBy)
+		assert.Equal(t, now.Unix(), alert.Lifecycle.AcknowledgedAt)
+		assert.Equal(t, "someone@example.com", alert.Lifecycle.AssignedTo)
+		assert.Equal(t, time.Date(2019, 1, 3, 0, 0, 0, 0, time.UTC).Unix(), alert.Lifecycle.SnoozedUntil)
+		assert.Equal(t, []*metrics.AlertNote{{Author: "admin@example.com", Created: now.Unix(), Text: "Replacing the controller"}}, alert.Lifecycle.Notes)
+		assert.Equal(t, "admin@example.com", alert.Lifecycle.UpdatedBy)
+		assert.Equal(t, now.Unix(), alert.Lifecycle.Updated)
+	}
+
+	// Notes are added to, acknowledging again keeps the first acknowledgement, and snoozes can be cleared
+	later := now.Add(time.Hour)
+	err = ApplyAlertLifecyclePatch(alert, map[string]interface{}{
+		"acknowledged":  true,
+		"note":          "Controller replaced",
+		"snoozed_until": nil,
+	}, "someone@example.com", later)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin@example.com", alert.Lifecycle.AcknowledgedBy)
+	assert.Equal(t, now.Unix(), alert.Lifecycle.AcknowledgedAt)
+	assert.Equal(t, int64(0), alert.Lifecycle.SnoozedUntil)
+	assert.Len(t, alert.Lifecycle.Notes, 2)
+	assert.Equal(t, "someone@example.com", alert.Lifecycle.Notes[1].Author)
+	assert.Equal(t, "someone@example.com", alert.Lifecycle.UpdatedBy)
+
+	err = ApplyAlertLifecyclePatch(alert, map[string]interface{}{"acknowledged": false}, "someone@example.com", later)
+	assert.NoError(t, err)
+	assert.False(t, alert.Lifecycle.Acknowledged)
+	assert.Empty(t, alert.Lifecycle.AcknowledgedBy)
+	assert.Equal(t, int64(0), alert.Lifecycle.AcknowledgedAt)
+}
+
+func TestApplyAlertLifecyclePatchInvalid(t *testing.T) {
+	now := time.Date(2019, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	invalidPatches := []map[string]interface{}{
+		{"state": "closed"},
+		{"acknowledged": "yes"},
+		{"assigned_to": 12},
+		{"note": "  "},
+		{"snoozed_until": "tomorrow"},
+		{"snoozed_until": "2019-01-01T00:00:00Z"}, // In the past
+		{"snoozed_until": 1546300800},
+	}
+	for _, patch := range invalidPatches {
+		alert := &metrics.Alert{ArrayID: "array-1", AlertID: 42}
+		assert.Error(t, ApplyAlertLifecyclePatch(alert, patch, "admin@example.com", now), "patch %v", patch)
+		assert.Nil(t, alert.Lifecycle)
+	}
+}
+
+func TestApplyAlertLifecyclePatchDoesNotModifyOriginalNotes(t *testing.T) {
+	original := &metrics.AlertLifecycle{Notes: []*metrics.AlertNote{{Author: "admin@example.com", Text: "First"}}}
+	alert := &metrics.Alert{ArrayID: "array-1", AlertID: 42, Lifecycle: original}
+
+	err := ApplyAlertLifecyclePatch(alert, map[string]interface{}{"note": "Second"}, "admin@example.com", time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, alert.Lifecycle.Notes, 2)
+	assert.Len(t, original.Notes, 1)
+}
+
+func TestConvertToAlertMap(t *testing.T) {
+	alert := &metrics.Alert{ArrayID: "array-1", AlertID: 42, Source: metrics.AlertSourceArray, Created: 1546300800, State: "open"}
+	converted := ConvertToAlertMap(alert)
+	assert.Equal(t, "array-1-alert-42", converted["id"])
+	assert.Equal(t, time.Unix(1546300800, 0).UTC(), converted["created"])
+	assert.Nil(t, converted["updated"])
+	assert.Equal(t, false, converted["acknowledged"])
+	assert.Equal(t, false, converted["in_maintenance"])
+	assert.Nil(t, converted["snoozed_until"])
+	assert.Equal(t, []map[string]interface{}{}, converted["notes"])
+
+	alert.Source = metrics.AlertSourceRule
+	alert.Lifecycle = &metrics.AlertLifecycle{
+		AssignedTo: "someone@example.com",
+		Notes:      []*metrics.AlertNote{{Author: "admin@example.com", Created: 1546300800, Text: "Looking into it"}},
+	}
+	converted = ConvertToAlertMap(alert)
+	assert.Equal(t, "array-1-rule-alert-42", converted["id"])
+	assert.Equal(t, "someone@example.com", converted["assigned_to"])
+	assert.Equal(t, []map[string]interface{}{{"author": "admin@example.com", "created": time.Unix(1546300800, 0).UTC(), "text": "Looking into it"}}, converted["notes"])
+}
+
+func TestAlertQueryGenerateElasticQueryObject(t *testing.T) {
+	acknowledged := false
+	snoozed := true
+	query := AlertQuery{
+		ArrayIDs:     []string{"array-1"},
+		States:       []string{"open"},
+		AssignedTo:   "someone@example.com",
+		Acknowledged: &acknowledged,
+		Snoozed:      &snoozed,
+	}
+
+	source, err := query.GenerateElasticQueryObject(time.Unix(1546300800, 0)).Source()
+	assert.NoError(t, err)
+	encoded, err := json.Marshal(source)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{
+		"filter":[
+			{"terms":{"ArrayID":["array-1"]}},
+			{"terms":{"State":["open"]}},
+			{"term":{"Lifecycle.AssignedTo":"someone@example.com"}},
+			{"range":{"Lifecycle.SnoozedUntil":{"from":1546300800,"include_lower":false,"include_upper":true,"to":null}}}
+		],
+		"must_not":{"term":{"Lifecycle.Acknowledged":true}}
+	}}`, string(encoded))
+}
@@ -0,0 +1,286 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+)
+
+var (
+	alertRuleOperators  = []string{">", ">=", "<", "<="}
+	alertRuleSeverities = []string{"info", "warning", "critical"}
+)
+
+// NewAlertRuleFromREST creates an alert rule from a map in the format of the REST API call (lower_case),
+// filling in the defaults for any optional keys that are missing
+func NewAlertRuleFromREST(m map[string]interface{}) (*AlertRule, error) {
+	rule := &AlertRule{
+		Enabled:     true,
+		Severity:    "warning",
+		ArrayIDs:    []string{},
+		ArrayTags:   map[string]string{},
+		VolumeNames: []string{},
+	}
+	err := rule.ApplyPatch(m)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ConvertToAlertRuleMap converts this rule into a string->interface map suitable for marshalling
+func (r *AlertRule) ConvertToAlertRuleMap() map[string]interface{} {
+	arrayIDs := r.ArrayIDs
+	if arrayIDs == nil {
+		arrayIDs = []string{}
+	}
+	arrayTags := r.ArrayTags
+	if arrayTags == nil {
+		arrayTags = map[string]string{}
+	}
+	volumeNames := r.VolumeNames
+	if volumeNames == nil {
+		volumeNames = []string{}
+	}
+
+	return map[string]interface{}{
+		"id":            r.InternalID,
+		"name":          r.Name,
+		"enabled":       r.Enabled,
+		"resource":      r.Resource,
+		"metric":        r.Metric,
+		"operator":      r.Operator,
+		"threshold":     r.Threshold,
+		"duration":      r.GetDuration().String(),
+		"severity":      r.Severity,
+		"array_ids":     arrayIDs,
+		"array_tags":    arrayTags,
+		"volume_names":  volumeNames,
+		"_last_updated": r.LastUpdated,
+	}
+}
+
+// ApplyPatch applies the given patches to this rule, with the given map in the format
+// accepted by the REST API ("metric", "array_tags", etc.). The rule should be validated afterwards.
+func (r *AlertRule) ApplyPatch(m map[string]interface{}) error {
+	if value, ok := m["id"]; ok {
+		id, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key id must be a string")
+		}
+		err := ValidateHexObjectID(id)
+		if err != nil {
+			return err
+		}
+		r.InternalID = id
+	}
+	if value, ok := m["name"]; ok {
+		name, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key name must be a string")
+		}
+		r.Name = strings.TrimSpace(name)
+	}
+	if value, ok := m["enabled"]; ok {
+		enabled, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("Key enabled must be a boolean")
+		}
+		r.Enabled = enabled
+	}
+	if value, ok := m["resource"]; ok {
+		resource, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key resource must be a string")
+		}
+		r.Resource = strings.ToLower(strings.TrimSpace(resource))
+	}
+	if value, ok := m["metric"]; ok {
+		metric, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key metric must be a string")
+		}
+		r.Metric = strings.TrimSpace(metric)
+	}
+	if value, ok := m["operator"]; ok {
+		operator, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key operator must be a string")
+		}
+		r.Operator = strings.TrimSpace(operator)
+	}
+	if value, ok := m["threshold"]; ok {
+		threshold, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("Key threshold must be a number")
+		}
+		r.Threshold = threshold
+	}
+	if value, ok := m["duration"]; ok {
+		durationString, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key duration must be a string, such as \"15m\"")
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(durationString))
+		if err != nil {
+			return err
+		}
+		r.DurationSeconds = int64(duration.Seconds())
+	}
+	if value, ok := m["severity"]; ok {
+		severity, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key severity must be a string")
+		}
+		r.Severity = strings.ToLower(strings.TrimSpace(severity))
+	}
+	if value, ok := m["array_ids"]; ok {
+		arrayIDs, err := parseStringList("array_ids", value)
+		if err != nil {
+			return err
+		}
+		for _, id := range arrayIDs {
+			err = ValidateHexObjectID(id)
+			if err != nil {
+				return err
+			}
+		}
+		r.ArrayIDs = arrayIDs
+	}
+	if value, ok := m["array_tags"]; ok {
+		tagMap, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Key array_tags must be a map of tag keys to values")
+		}
+		arrayTags := map[string]string{}
+		for key, tagValue := range tagMap {
+			stringValue, ok := tagValue.(string)
+			if !ok {
+				return fmt.Errorf("Error converting tag value into string. Offending key-value pair is '%s: %v'", key, tagValue)
+			}
+			arrayTags[key] = stringValue
+		}
+		r.ArrayTags = arrayTags
+	}
+	if value, ok := m["volume_names"]; ok {
+		volumeNames, err := parseStringList("volume_names", value)
+		if err != nil {
+			return err
+		}
+		r.VolumeNames = volumeNames
+	}
+	return nil
+}
+
+// Validate checks that this rule has all required fields filled in, and that it can be evaluated
+func (r *AlertRule) Validate() error {
+	if len(r.Name) == 0 {
+		return fmt.Errorf("Alert rule is missing name")
+	}
+
+	var fields []string
+	switch r.Resource {
+	case AlertRuleArrayResource:
+		fields = metrics.ArrayMetricFields()
+		if len(r.VolumeNames) > 0 {
+			return fmt.Errorf("Key volume_names can only be given for volume alert rules")
+		}
+	case AlertRuleVolumeResource:
+		fields = metrics.VolumeMetricFields()
+	default:
+		return fmt.Errorf("Key resource must be one of %s or %s", AlertRuleArrayResource, AlertRuleVolumeResource)
+	}
+	if !containsString(fields, r.Metric) {
+		return fmt.Errorf("Key metric must be one of %s for %s alert rules", strings.Join(fields, ", "), r.Resource)
+	}
+
+	if !containsString(alertRuleOperators, r.Operator) {
+		return fmt.Errorf("Key operator must be one of %s", strings.Join(alertRuleOperators, ", "))
+	}
+	if !containsString(alertRuleSeverities, r.Severity) {
+		return fmt.Errorf("Key severity must be one of %s", strings.Join(alertRuleSeverities, ", "))
+	}
+	if r.DurationSeconds < 0 {
+		return fmt.Errorf("Key duration cannot be negative")
+	}
+	return nil
+}
+
+// GetDuration gets how long the threshold must be breached for before alerting
+func (r *AlertRule) GetDuration() time.Duration {
+	return time.Duration(r.DurationSeconds) * time.Second
+}
+
+// Matches checks if this rule applies to the given array (and volume, for volume rules)
+func (r *AlertRule) Matches(arrayID string, arrayTags map[string]string, volumeName string) bool {
+	if len(r.ArrayIDs) > 0 && !containsString(r.ArrayIDs, arrayID) {
+		return false
+	}
+	for key, value := range r.ArrayTags {
+		if tagValue, ok := arrayTags[key]; !ok || tagValue != value {
+			return false
+		}
+	}
+	if r.Resource == AlertRuleVolumeResource && len(r.VolumeNames) > 0 && !containsString(r.VolumeNames, volumeName) {
+		return false
+	}
+	return true
+}
+
+// IsBreachedBy checks if the given metric value breaches this rule's threshold
+func (r *AlertRule) IsBreachedBy(value float64) bool {
+	switch r.Operator {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	default:
+		return false
+	}
+}
+
+// parseStringList is a helper function to convert a JSON list of strings from a REST API call
+func parseStringList(key string, value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Key %s must be a list of strings", key)
+	}
+	parsed := []string{}
+	for _, item := range list {
+		stringItem, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("Key %s must be a list of strings", key)
+		}
+		parsed = append(parsed, stringItem)
+	}
+	return parsed, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAlertRuleFromRESTDefaults(t *testing.T) {
+	rule, err := NewAlertRuleFromREST(map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.True(t, rule.Enabled)
+	assert.Equal(t, "warning", rule.Severity)
+	assert.Equal(t, int64(0), rule.DurationSeconds)
+	assert.Empty(t, rule.ArrayIDs)
+	assert.Empty(t, rule.ArrayTags)
+	assert.Empty(t, rule.VolumeNames)
+}
+
+func TestNewAlertRuleFromRESTSetAll(t *testing.T) {
+	rule, err := NewAlertRuleFromREST(map[string]interface{}{
+		"id":           "1234567890abcdefedcba098",
+		"name":         " Nearly full ",
+		"enabled":      false,
+		"resource":     "Array",
+		"metric":       "PercentFull",
+		"operator":     ">",
+		"threshold":    0.85,
+		"duration":     "15m",
+		"severity":     "Critical",
+		"array_ids":    []interface{}{"1234567890abcdefedcba099"},
+		"array_tags":   map[string]interface{}{"env": "prod"},
+		"volume_names": []interface{}{},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890abcdefedcba098", rule.InternalID)
+	assert.Equal(t, "Nearly full", rule.Name)
+	assert.False(t, rule.Enabled)
+	assert.Equal(t, AlertRuleArrayResource, rule.Resource)
+	assert.Equal(t, "PercentFull", rule.Metric)
+	assert.Equal(t, ">", rule.Operator)
+	assert.Equal(t, 0.85, rule.Threshold)
+	assert.Equal(t, int64(900), rule.DurationSeconds)
+	assert.Equal(t, 15*time.Minute, rule.GetDuration())
+	assert.Equal(t, "critical", rule.Severity)
+	assert.Equal(t, []string{"1234567890abcdefedcba099"}, rule.ArrayIDs)
+	assert.Equal(t, map[string]string{"env": "prod"}, rule.ArrayTags)
+	assert.NoError(t, rule.Validate())
+}
+
+func TestNewAlertRuleFromRESTBadTypes(t *testing.T) {
+	badMaps := []map[string]interface{}{
+		{"id": "not-an-id"},
+		{"name": 5},
+		{"enabled": "yes"},
+		{"threshold": "0.85"},
+		{"duration": 900},
+		{"duration": "15 minutes"},
+		{"array_ids": "1234567890abcdefedcba099"},
+		{"array_ids": []interface{}{"a"}},
+		{"array_tags": map[string]interface{}{"env": 1}},
+		{"volume_names": []interface{}{1}},
+	}
+	for _, m := range badMaps {
+		_, err := NewAlertRuleFromREST(m)
+		assert.Error(t, err, "%v", m)
+	}
+}
+
+func TestAlertRuleValidate(t *testing.T) {
+	valid := AlertRule{Name: "rule", Resource: AlertRuleVolumeResource, Metric: "WriteLatency", Operator: ">=", Severity: "info", VolumeNames: []string{"vol1"}}
+	assert.NoError(t, valid.Validate())
+
+	missingName := valid
+	missingName.Name = ""
+	assert.Error(t, missingName.Validate())
+
+	badResource := valid
+	badResource.Resource = "host"
+	assert.Error(t, badResource.Validate())
+
+	badMetric := valid
+	badMetric.Metric = "PercentFull" // Only exists for arrays
+	assert.Error(t, badMetric.Validate())
+
+	badOperator := valid
+	badOperator.Operator = "=="
+	assert.Error(t, badOperator.Validate())
+
+	badSeverity := valid
+	badSeverity.Severity = "fatal"
+	assert.Error(t, badSeverity.Validate())
+
+	negativeDuration := valid
+	negativeDuration.DurationSeconds = -1
+	assert.Error(t, negativeDuration.Validate())
+
+	arrayWithVolumes := valid
+	arrayWithVolumes.Resource = AlertRuleArrayResource
+	arrayWithVolumes.Metric = "PercentFull"
+	assert.Error(t, arrayWithVolumes.Validate())
+}
+
+func TestAlertRuleMatches(t *testing.T) {
+	rule := AlertRule{Resource: AlertRuleArrayResource}
+	assert.True(t, rule.Matches("a", nil, ""))
+
+	rule.ArrayIDs = []string{"a", "b"}
+	assert.True(t, rule.Matches("b", nil, ""))
+	assert.False(t, rule.Matches("c", nil, ""))
+
+	rule.ArrayTags = map[string]string{"env": "prod"}
+	assert.True(t, rule.Matches("a", map[string]string{"env": "prod", "site": "x"}, ""))
+	assert.False(t, rule.Matches("a", map[string]string{"env": "dev"}, ""))
+	assert.False(t, rule.Matches("a", map[string]string{}, ""))
+
+	volumeRule := AlertRule{Resource: AlertRuleVolumeResource, VolumeNames: []string{"vol1"}}
+	assert.True(t, volumeRule.Matches("a", nil, "vol1"))
+	assert.False(t, volumeRule.Matches("a", nil, "vol2"))
+}
+
+func TestAlertRuleIsBreachedBy(t *testing.T) {
+	rule := AlertRule{Threshold: 10}
+
+	rule.Operator = ">"
+	assert.True(t, rule.IsBreachedBy(11))
+	assert.False(t, rule.IsBreachedBy(10))
+
+	rule.Operator = ">="
+	assert.True(t, rule.IsBreachedBy(10))
+	assert.False(t, rule.IsBreachedBy(9))
+
+	rule.Operator = "<"
+	assert.True(t, rule.IsBreachedBy(9))
+	assert.False(t, rule.IsBreachedBy(10))
+
+	rule.Operator = "<="
+	assert.True(t, rule.IsBreachedBy(10))
+	assert.False(t, rule.IsBreachedBy(11))
+
+	rule.Operator = "=="
+	assert.False(t, rule.IsBreachedBy(10))
+}
+
+func TestConvertToAlertRuleMap(t *testing.T) {
+	rule := AlertRule{InternalID: "aaaa", Name: "rule", Resource: AlertRuleArrayResource, DurationSeconds: 90}
+	mapped := rule.ConvertToAlertRuleMap()
+	assert.Equal(t, "aaaa", mapped["id"])
+	assert.Equal(t, "1m30s", mapped["duration"])
+	assert.Equal(t, []string{}, mapped["array_ids"])
+	assert.Equal(t, map[string]string{}, mapped["array_tags"])
+	assert.Equal(t, []string{}, mapped["volume_names"])
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/util"
 )
 
 const (
@@ -29,29 +31,43 @@ const (
 )
 
 // ConvertToArrayMap converts this array into a string->interface map
-// suitable for marshalling, with only the array properties (sans-tags)
+// suitable for marshalling, with only the array properties (sans-tags).
+// API client credentials are only included if set.
 func (s *Array) ConvertToArrayMap() map[string]interface{} {
-	return map[string]interface{}{
-		"id":            s.InternalID,
-		"name":          s.Name,
-		"mgmt_endpoint": s.MgmtEndPoint,
-		"api_token":     s.APIToken,
-		"status":        s.Status,
-		"device_type":   s.DeviceType,
-		"model":         s.Model,
-		"version":       s.Version,
-		"_as_of":        s.Lastseen,
-		"_last_updated": s.Lastupdated,
+	toReturn := map[string]interface{}{
+		"id":                      s.InternalID,
+		"name":                    s.Name,
+		"mgmt_endpoint":           s.MgmtEndPoint,
+		"mgmt_endpoints":          s.GetMgmtEndpoints(),
+		"active_endpoint":         s.ActiveEndpoint,
+		"api_token":               s.APIToken,
+		"status":                  s.Status,
+		"device_type":             s.DeviceType,
+		"model":                   s.Model,
+		"version":                 s.Version,
+		"_as_of":                  s.Lastseen,
+		"_last_updated":           s.Lastupdated,
+		"ca_certificate":          s.CACertificate,
+		"certificate_fingerprint": s.CertificateFingerprint,
+	}
+	if s.APIClient.IsSet() {
+		toReturn["api_client_id"] = s.APIClient.ClientID
+		toReturn["api_client_key_id"] = s.APIClient.KeyID
+		toReturn["api_client_private_key"] = s.APIClient.PrivateKey
+		toReturn["api_client_issuer"] = s.APIClient.Issuer
+		toReturn["api_client_username"] = s.APIClient.Username
 	}
+	return toReturn
 }
 
 // ConvertToStatusMap converts this array into a string->interface map
 // suitable for marshalling, with only the array ID and status
 func (s *Array) ConvertToStatusMap() map[string]interface{} {
 	return map[string]interface{}{
-		"id":     s.InternalID,
-		"status": s.Status,
-		"_as_of": s.Lastseen,
+		"id":              s.InternalID,
+		"status":          s.Status,
+		"active_endpoint": s.ActiveEndpoint,
+		"_as_of":          s.Lastseen,
 	}
 }
 
@@ -78,7 +94,7 @@ func (s *Array) ApplyPatch(m map[string]interface{}) error {
 	}
 
 	// Used to mark if anything *consequential* was changed:
-	// specifically display name, mgmt_endpoint, device_type, or api_token
+	// specifically display name, mgmt_endpoint(s), device_type, api_token, or the API client credentials
 	changed := false
 
 	if _, ok := m["name"]; ok {
@@ -92,7 +108,21 @@ func (s *Array) ApplyPatch(m map[string]interface{}) error {
 		if len(strings.TrimSpace(m["mgmt_endpoint"].(string))) == 0 {
 			return fmt.Errorf("Key mgmt_endpoint cannot be empty")
 		}
-		s.MgmtEndPoint = m["mgmt_endpoint"].(string)
+		// Replacing the primary endpoint keeps the failover endpoints behind it
+		s.MgmtEndpoints = normalizeMgmtEndpoints(m["mgmt_endpoint"].(string), s.GetMgmtEndpoints())
+		s.MgmtEndPoint = s.MgmtEndpoints[0]
+		changed = true
+	}
+	if _, ok := m["mgmt_endpoints"]; ok {
+		endpoints, err := parseMgmtEndpoints(m["mgmt_endpoints"])
+		if err != nil {
+			return err
+		}
+		if len(endpoints) == 0 {
+			return fmt.Errorf("Key mgmt_endpoints cannot be empty")
+		}
+		s.MgmtEndpoints = normalizeMgmtEndpoints("", endpoints)
+		s.MgmtEndPoint = s.MgmtEndpoints[0]
 		changed = true
 	}
 	if _, ok := m["device_type"]; ok {
@@ -109,10 +139,47 @@ func (s *Array) ApplyPatch(m map[string]interface{}) error {
 		s.APIToken = m["api_token"].(string)
 		changed = true
 	}
+	for _, field := range s.APIClient.restFields() {
+		if _, ok := m[field.key]; ok {
+			if len(strings.TrimSpace(m[field.key].(string))) == 0 {
+				return fmt.Errorf("Key %s cannot be empty", field.key)
+			}
+			*field.value = m[field.key].(string)
+			changed = true
+		}
+	}
+	if _, ok := m["ca_certificate"]; ok {
+		// This is valid to be empty (clears the CA bundle)
+		caCertificate := strings.TrimSpace(m["ca_certificate"].(string))
+		if len(caCertificate) > 0 {
+			if err := util.ValidateCACertificate(caCertificate); err != nil {
+				return err
+			}
+		}
+		s.CACertificate = caCertificate
+		changed = true
+	}
+	if _, ok := m["certificate_fingerprint"]; ok {
+		// This is valid to be empty (clears the pinned fingerprint)
+		fingerprint := strings.TrimSpace(m["certificate_fingerprint"].(string))
+		if len(fingerprint) > 0 {
+			normalized, err := util.NormalizeCertificateFingerprint(fingerprint)
+			if err != nil {
+				return err
+			}
+			fingerprint = normalized
+		}
+		s.CertificateFingerprint = fingerprint
+		changed = true
+	}
 	if _, ok := m["status"]; ok {
 		// This is valid to be empty
 		s.Status = m["status"].(string)
 	}
+	if _, ok := m["active_endpoint"]; ok {
+		// This is valid to be empty
+		s.ActiveEndpoint = m["active_endpoint"].(string)
+	}
 	if _, ok := m["model"]; ok {
 		if len(strings.TrimSpace(m["model"].(string))) == 0 {
 			return fmt.Errorf("Key model cannot be empty")
@@ -240,15 +307,43 @@ func ParseArrayFromREST(m map[string]interface{}) (Array, error) {
 	if _, ok := m["mgmt_endpoint"]; ok {
 		toReturn.MgmtEndPoint = m["mgmt_endpoint"].(string)
 	}
+	if _, ok := m["mgmt_endpoints"]; ok {
+		endpoints, err := parseMgmtEndpoints(m["mgmt_endpoints"])
+		if err != nil {
+			return Array{}, err
+		}
+		toReturn.MgmtEndpoints = endpoints
+	}
+	// If both are given, mgmt_endpoint is tried first
+	if len(strings.TrimSpace(toReturn.MgmtEndPoint)) != 0 || len(toReturn.MgmtEndpoints) != 0 {
+		toReturn.MgmtEndpoints = normalizeMgmtEndpoints(toReturn.MgmtEndPoint, toReturn.MgmtEndpoints)
+		if len(toReturn.MgmtEndpoints) != 0 {
+			toReturn.MgmtEndPoint = toReturn.MgmtEndpoints[0]
+		}
+	}
 	if _, ok := m["api_token"]; ok {
 		toReturn.APIToken = m["api_token"].(string)
 	}
+	for _, field := range toReturn.APIClient.restFields() {
+		if _, ok := m[field.key]; ok {
+			*field.value = m[field.key].(string)
+		}
+	}
 	if _, ok := m["status"]; ok {
 		toReturn.Status = m["status"].(string)
 	}
+	if _, ok := m["active_endpoint"]; ok {
+		toReturn.ActiveEndpoint = m["active_endpoint"].(string)
+	}
 	if _, ok := m["device_type"]; ok {
 		toReturn.DeviceType = m["device_type"].(string)
 	}
+	if _, ok := m["ca_certificate"]; ok {
+		toReturn.CACertificate = m["ca_certificate"].(string)
+	}
+	if _, ok := m["certificate_fingerprint"]; ok {
+		toReturn.CertificateFingerprint = m["certificate_fingerprint"].(string)
+	}
 	if _, ok := m["_as_of"]; ok {
 		lastseen, err := time.Parse("2006-01-02T15:04:05.000", m["_as_of"].(string)) // yyyy-MM-dd'T'HH:mm:ss.SSS
 		if err != nil {
@@ -267,6 +362,51 @@ func ParseArrayFromREST(m map[string]interface{}) (Array, error) {
 	return toReturn, nil
 }
 
+// GetMgmtEndpoints returns the management endpoints of this array in the order they should be tried,
+// falling back to the single management endpoint for arrays stored before multiple endpoints were supported
+func (s *Array) GetMgmtEndpoints() []string {
+	if len(s.MgmtEndpoints) != 0 {
+		return s.MgmtEndpoints
+	}
+	if len(strings.TrimSpace(s.MgmtEndPoint)) == 0 {
+		return []string{}
+	}
+	return []string{s.MgmtEndPoint}
+}
+
+// parseMgmtEndpoints is a helper function that reads the mgmt_endpoints list of a REST request
+func parseMgmtEndpoints(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Key mgmt_endpoints must be a list of endpoints")
+	}
+	endpoints := []string{}
+	for _, item := range list {
+		endpoint, ok := item.(string)
+		if !ok || len(strings.TrimSpace(endpoint)) == 0 {
+			return nil, fmt.Errorf("Key mgmt_endpoints must only contain non-empty endpoints")
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+// normalizeMgmtEndpoints is a helper function that puts the primary endpoint (if given) in front of the
+// other endpoints, dropping blanks and duplicates while keeping the order
+func normalizeMgmtEndpoints(primary string, endpoints []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, endpoint := range append([]string{primary}, endpoints...) {
+		endpoint = strings.TrimSpace(endpoint)
+		if len(endpoint) == 0 || seen[endpoint] {
+			continue
+		}
+		seen[endpoint] = true
+		normalized = append(normalized, endpoint)
+	}
+	return normalized
+}
+
 // HasRequiredPostFields checks to ensure that a storage array has all required fields filled in
 func (s *Array) HasRequiredPostFields() error {
 	if len(strings.TrimSpace(s.InternalID)) == 0 {
@@ -278,8 +418,8 @@ func (s *Array) HasRequiredPostFields() error {
 	if len(strings.TrimSpace(s.MgmtEndPoint)) == 0 {
 		return fmt.Errorf("Array is missing management endpoint")
 	}
-	if len(strings.TrimSpace(s.APIToken)) == 0 {
-		return fmt.Errorf("Array is missing API token")
+	if len(strings.TrimSpace(s.APIToken)) == 0 && !s.APIClient.IsSet() {
+		return fmt.Errorf("Array is missing API token or API client credentials")
 	}
 	if len(strings.TrimSpace(s.DeviceType)) == 0 {
 		return fmt.Errorf("Array is missing device type")
@@ -287,6 +427,66 @@ func (s *Array) HasRequiredPostFields() error {
 	return nil
 }
 
+// ValidateCertificateFields checks that the CA certificate and certificate fingerprint (if given) are
+// well formed, and normalizes the fingerprint so it can be compared against the array certificate
+func (s *Array) ValidateCertificateFields() error {
+	s.CACertificate = strings.TrimSpace(s.CACertificate)
+	if len(s.CACertificate) > 0 {
+		if err := util.ValidateCACertificate(s.CACertificate); err != nil {
+			return err
+		}
+	}
+	if len(strings.TrimSpace(s.CertificateFingerprint)) > 0 {
+		normalized, err := util.NormalizeCertificateFingerprint(s.CertificateFingerprint)
+		if err != nil {
+			return err
+		}
+		s.CertificateFingerprint = normalized
+	} else {
+		s.CertificateFingerprint = ""
+	}
+	return nil
+}
+
+// ValidateAPIClientFields checks that the API client credentials (if given) are complete and that the
+// private key is a well formed RSA key. Whether the device type supports API clients at all is up to
+// the device registry.
+func (s *Array) ValidateAPIClientFields() error {
+	if !s.APIClient.IsSet() {
+		return nil
+	}
+	for _, field := range s.APIClient.restFields() {
+		*field.value = strings.TrimSpace(*field.value)
+		if len(*field.value) == 0 {
+			return fmt.Errorf("Array is missing %s", field.key)
+		}
+	}
+	_, err := util.ParseRSAPrivateKey(s.APIClient.PrivateKey)
+	return err
+}
+
+// IsSet checks if any of the API client credentials are given
+func (c *APIClientCredentials) IsSet() bool {
+	return len(c.ClientID) > 0 || len(c.KeyID) > 0 || len(c.PrivateKey) > 0 || len(c.Issuer) > 0 || len(c.Username) > 0
+}
+
+// restField pairs the REST API key of a credential with its field
+type restField struct {
+	key   string
+	value *string
+}
+
+// restFields is a helper function that lists the REST API key and field of each credential
+func (c *APIClientCredentials) restFields() []restField {
+	return []restField{
+		{key: "api_client_id", value: &c.ClientID},
+		{key: "api_client_key_id", value: &c.KeyID},
+		{key: "api_client_private_key", value: &c.PrivateKey},
+		{key: "api_client_issuer", value: &c.Issuer},
+		{key: "api_client_username", value: &c.Username},
+	}
+}
+
 // MarshalJSON provides a custom marshal override which
 // formats dates in the format Elastic expects
 func (s *Array) MarshalJSON() ([]byte, error) {
@@ -15,6 +15,10 @@
 package resources
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
 	"time"
 
@@ -58,6 +62,37 @@ func TestParseFromMapSetAll(t *testing.T) {
 	assert.EqualValues(t, 1548868766, parsed.Lastupdated.Unix())
 }
 
+func TestParseFromMapMgmtEndpoints(t *testing.T) {
+	parsed, err := ParseArrayFromREST(map[string]interface{}{
+		"mgmt_endpoints": []interface{}{"array01.example.com", "2001:db8::10", "192.168.99.100"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "array01.example.com", parsed.MgmtEndPoint)
+	assert.Equal(t, []string{"array01.example.com", "2001:db8::10", "192.168.99.100"}, parsed.MgmtEndpoints)
+}
+
+func TestParseFromMapMgmtEndpointFirst(t *testing.T) {
+	parsed, err := ParseArrayFromREST(map[string]interface{}{
+		"mgmt_endpoint":  "192.168.99.100",
+		"mgmt_endpoints": []interface{}{"array01.example.com", "192.168.99.100"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.99.100", parsed.MgmtEndPoint)
+	assert.Equal(t, []string{"192.168.99.100", "array01.example.com"}, parsed.MgmtEndpoints)
+}
+
+func TestParseFromMapBadMgmtEndpoints(t *testing.T) {
+	_, err := ParseArrayFromREST(map[string]interface{}{
+		"mgmt_endpoints": "192.168.99.100",
+	})
+	assert.Error(t, err)
+
+	_, err = ParseArrayFromREST(map[string]interface{}{
+		"mgmt_endpoints": []interface{}{"192.168.99.100", " "},
+	})
+	assert.Error(t, err)
+}
+
 func TestParseFromMapBadID(t *testing.T) {
 	_, err := ParseArrayFromREST(map[string]interface{}{
 		"id": "asdf",
@@ -108,6 +143,48 @@ func TestApplyPatchFull(t *testing.T) {
 	assert.EqualValues(t, 1548868766, array.Lastseen.Unix())
 }
 
+func TestApplyPatchMgmtEndpoints(t *testing.T) {
+	array := Array{MgmtEndPoint: "192.168.99.100"}
+	err := array.ApplyPatch(map[string]interface{}{
+		"mgmt_endpoints": []interface{}{"array01.example.com", "2001:db8::10"},
+	})
+	assert.NoError(t, err)
+	assert.NotEqual(t, time.Time{}, array.Lastupdated)
+	assert.Equal(t, "array01.example.com", array.MgmtEndPoint)
+	assert.Equal(t, []string{"array01.example.com", "2001:db8::10"}, array.MgmtEndpoints)
+
+	// Changing the primary endpoint keeps the rest as failover endpoints
+	err = array.ApplyPatch(map[string]interface{}{
+		"mgmt_endpoint": "2001:db8::10",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "2001:db8::10", array.MgmtEndPoint)
+	assert.Equal(t, []string{"2001:db8::10", "array01.example.com"}, array.MgmtEndpoints)
+}
+
+func TestApplyPatchEmptyMgmtEndpoints(t *testing.T) {
+	array := Array{}
+	err := array.ApplyPatch(map[string]interface{}{
+		"mgmt_endpoints": []interface{}{},
+	})
+	assert.Error(t, err)
+}
+
+func TestApplyPatchActiveEndpoint(t *testing.T) {
+	array := Array{}
+	err := array.ApplyPatch(map[string]interface{}{
+		"active_endpoint": "2001:db8::10",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "2001:db8::10", array.ActiveEndpoint)
+	assert.Equal(t, time.Time{}, array.Lastupdated)
+}
+
+func TestGetMgmtEndpointsFallback(t *testing.T) {
+	assert.Equal(t, []string{"192.168.99.100"}, (&Array{MgmtEndPoint: "192.168.99.100"}).GetMgmtEndpoints())
+	assert.Equal(t, []string{}, (&Array{}).GetMgmtEndpoints())
+}
+
 func TestApplyPatchEmptyName(t *testing.T) {
 	array := Array{}
 	err := array.ApplyPatch(map[string]interface{}{
@@ -140,6 +217,25 @@ func TestApplyPatchEmptyAPIToken(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestApplyPatchAPIClient(t *testing.T) {
+	array := Array{APIClient: APIClientCredentials{ClientID: "client", KeyID: "key"}}
+	err := array.ApplyPatch(map[string]interface{}{
+		"api_client_key_id": "new-key",
+	})
+	assert.NoError(t, err)
+	assert.NotEqual(t, time.Time{}, array.Lastupdated)
+	assert.Equal(t, "client", array.APIClient.ClientID) // Untouched
+	assert.Equal(t, "new-key", array.APIClient.KeyID)
+}
+
+func TestApplyPatchEmptyAPIClientIssuer(t *testing.T) {
+	array := Array{}
+	err := array.ApplyPatch(map[string]interface{}{
+		"api_client_issuer": "  ",
+	})
+	assert.Error(t, err)
+}
+
 func TestApplyPatchEmptyAsOf(t *testing.T) {
 	array := Array{}
 	err := array.ApplyPatch(map[string]interface{}{
@@ -391,6 +487,52 @@ func TestHasRequiredFieldsMissingApiToken(t *testing.T) {
 	assert.Error(t, array.HasRequiredPostFields())
 }
 
+func TestHasRequiredFieldsAPIClientInsteadOfToken(t *testing.T) {
+	array := Array{
+		InternalID:   "asdf",
+		Name:         "test_dev1",
+		MgmtEndPoint: "192.168.99.100",
+		DeviceType:   common.FlashArray,
+		APIClient:    APIClientCredentials{ClientID: "client"},
+	}
+	assert.NoError(t, array.HasRequiredPostFields())
+}
+
+// generateTestAPIClient creates complete API client credentials with a freshly generated key
+func generateTestAPIClient(t *testing.T) APIClientCredentials {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	return APIClientCredentials{
+		ClientID:   "client",
+		KeyID:      "key",
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		Issuer:     "pure1-unplugged",
+		Username:   "pureuser",
+	}
+}
+
+func TestValidateAPIClientFields(t *testing.T) {
+	array := Array{DeviceType: common.FlashArray, APIClient: generateTestAPIClient(t)}
+	array.APIClient.Issuer = " pure1-unplugged "
+	assert.NoError(t, array.ValidateAPIClientFields())
+	assert.Equal(t, "pure1-unplugged", array.APIClient.Issuer)
+
+	// Nothing to validate without any credentials
+	assert.NoError(t, (&Array{DeviceType: common.FlashBlade}).ValidateAPIClientFields())
+}
+
+func TestValidateAPIClientFieldsIncomplete(t *testing.T) {
+	array := Array{DeviceType: common.FlashArray, APIClient: generateTestAPIClient(t)}
+	array.APIClient.Username = ""
+	assert.EqualError(t, array.ValidateAPIClientFields(), "Array is missing api_client_username")
+}
+
+func TestValidateAPIClientFieldsInvalidKey(t *testing.T) {
+	array := Array{DeviceType: common.FlashArray, APIClient: generateTestAPIClient(t)}
+	array.APIClient.PrivateKey = "not a key"
+	assert.Error(t, array.ValidateAPIClientFields())
+}
+
 func TestValidateHexValid(t *testing.T) {
 	assert.NoError(t, ValidateHexObjectID("1234567890abcdefedcba098")) // All valid hex characters
 }
@@ -0,0 +1,71 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/olivere/elastic"
+)
+
+// GenerateElasticQueryObject converts this query into an elastic.Query
+func (q *AuditQuery) GenerateElasticQueryObject() elastic.Query {
+	filters := []elastic.Query{}
+	if len(q.ArrayIDs) > 0 {
+		filters = append(filters, elastic.NewTermsQuery("ArrayID", convertToInterfaceSlice(q.ArrayIDs)...))
+	}
+	if len(q.Kinds) > 0 {
+		filters = append(filters, elastic.NewTermsQuery("Kind", convertToInterfaceSlice(q.Kinds)...))
+	}
+	if len(q.Users) > 0 {
+		filters = append(filters, elastic.NewTermsQuery("User", convertToInterfaceSlice(q.Users)...))
+	}
+	if len(q.Commands) > 0 {
+		filters = append(filters, elastic.NewTermsQuery("Command", convertToInterfaceSlice(q.Commands)...))
+	}
+	if q.StartTime > 0 || q.EndTime > 0 {
+		timeRange := elastic.NewRangeQuery("Time").Format("epoch_second")
+		if q.StartTime > 0 {
+			timeRange.Gte(q.StartTime)
+		}
+		if q.EndTime > 0 {
+			timeRange.Lte(q.EndTime)
+		}
+		filters = append(filters, timeRange)
+	}
+	return elastic.NewBoolQuery().Filter(filters...)
+}
+
+// ConvertToAuditEventMap converts the given audit event into a string->interface map suitable for marshalling
+func ConvertToAuditEventMap(event *metrics.AuditEvent) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 event.GetDocumentID(),
+		"array_id":           event.ArrayID,
+		"array_name":         event.ArrayName,
+		"array_display_name": event.ArrayDisplayName,
+		"device_type":        event.ArrayType,
+		"kind":               event.Kind,
+		"event_id":           event.EventID,
+		"time":               convertUnixTime(event.Time),
+		"user":               event.User,
+		"interface":          event.Interface,
+		"command":            event.Command,
+		"subcommand":         event.Subcommand,
+		"arguments":          event.Arguments,
+		"event":              event.Event,
+		"location":           event.Location,
+		"method":             event.Method,
+		"_as_of":             convertUnixTime(event.CollectedAt),
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToAuditEventMap(t *testing.T) {
+	event := &metrics.AuditEvent{
+		ArrayID:    "array-1",
+		Kind:       metrics.AuditEventCommand,
+		EventID:    "1234",
+		Time:       1546300800,
+		User:       "pureuser",
+		Command:    "purevol",
+		Subcommand: "create",
+		Arguments:  "--size 1G vol1",
+	}
+	converted := ConvertToAuditEventMap(event)
+	assert.Equal(t, "array-1-command-1234", converted["id"])
+	assert.Equal(t, time.Unix(1546300800, 0).UTC(), converted["time"])
+	assert.Equal(t, "purevol", converted["command"])
+	assert.Equal(t, "", converted["location"])
+	assert.Nil(t, converted["_as_of"])
+}
+
+func TestAuditQueryGenerateElasticQueryObject(t *testing.T) {
+	query := AuditQuery{
+		ArrayIDs:  []string{"array-1"},
+		Users:     []string{"pureuser"},
+		Commands:  []string{"purevol", "purehost"},
+		StartTime: 1546300800,
+	}
+
+	source, err := query.GenerateElasticQueryObject().Source()
+	assert.NoError(t, err)
+	encoded, err := json.Marshal(source)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{
+		"filter":[
+			{"terms":{"ArrayID":["array-1"]}},
+			{"terms":{"User":["pureuser"]}},
+			{"terms":{"Command":["purevol","purehost"]}},
+			{"range":{"Time":{"format":"epoch_second","from":1546300800,"include_lower":true,"include_upper":true,"to":null}}}
+		]
+	}}`, string(encoded))
+}
@@ -0,0 +1,478 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/olivere/elastic"
+)
+
+const (
+	// FilterAnd is the operator of a FilterLogicalExpression that requires all operands to match
+	FilterAnd = "and"
+	// FilterOr is the operator of a FilterLogicalExpression that requires any operand to match
+	FilterOr = "or"
+	// FilterIn is the operator of a FilterComparison that matches any of several values
+	FilterIn = "in"
+
+	// filterTagPrefix marks a field as referring to an array tag ("tags.site")
+	filterTagPrefix = "tags."
+)
+
+const (
+	filterFieldID      = iota // Matched against the document ID
+	filterFieldKeyword        // Keyword field with the lowercase normalizer, supports wildcards
+	filterFieldDate           // Date field, supports comparisons
+)
+
+type filterField struct {
+	elasticField string
+	kind         int
+}
+
+// filterFields maps the field names accepted in a filter (the same names as the REST API uses)
+// to the Elastic field they are matched against
+var filterFields = map[string]filterField{
+	"id":            {"", filterFieldID},
+	"name":          {"Name.keyword", filterFieldKeyword},
+	"model":         {"Model.keyword", filterFieldKeyword},
+	"version":       {"Version.keyword", filterFieldKeyword},
+	"status":        {"Status.keyword", filterFieldKeyword},
+	"device_type":   {"DeviceType.keyword", filterFieldKeyword},
+	"mgmt_endpoint": {"MgmtEndpoint.keyword", filterFieldKeyword},
+	"_as_of":        {"AsOf", filterFieldDate},
+	"_last_updated": {"LastUpdated", filterFieldDate},
+}
+
+// filterDateFormats are the accepted formats for date values, from most to least specific
+var filterDateFormats = []string{DateTimeFormat, "2006-01-02T15:04:05", "2006-01-02"}
+
+// ParseFilter parses and validates a filter expression, such as
+//
+//	model='FA-X70' and tags.site='dc1' and status!='Connected'
+//
+// Comparisons take the form <field> <op> '<value>', where op is one of =, !=, <, <=, > or >=
+// (the last four only on _as_of and _last_updated), or <field> in ('<value>', ...). String values
+// may contain * and ? wildcards. Comparisons can be combined with and, or, not and parentheses.
+func ParseFilter(filter string) (FilterExpression, error) {
+	tokens, err := tokenizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := filterParser{tokens: tokens}
+	expression, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := parser.peek(); next.kind != filterTokenEOF {
+		return nil, fmt.Errorf("Invalid filter: unexpected %s at position %d", next.describe(), next.position)
+	}
+	return expression, nil
+}
+
+// ElasticQuery converts this expression into a bool query
+func (f *FilterLogicalExpression) ElasticQuery() elastic.Query {
+	queries := []elastic.Query{}
+	for _, operand := range f.Operands {
+		queries = append(queries, operand.ElasticQuery())
+	}
+	if f.Operator == FilterOr {
+		return elastic.NewBoolQuery().Should(queries...).MinimumNumberShouldMatch(1)
+	}
+	return elastic.NewBoolQuery().Must(queries...)
+}
+
+// ElasticQuery converts this expression into a bool query
+func (f *FilterNotExpression) ElasticQuery() elastic.Query {
+	return elastic.NewBoolQuery().MustNot(f.Operand.ElasticQuery())
+}
+
+// ElasticQuery converts this comparison into an Elastic query. Values are assumed
+// to have been validated and normalized by ParseFilter.
+func (f *FilterComparison) ElasticQuery() elastic.Query {
+	var matchQuery elastic.Query
+	if strings.HasPrefix(f.Field, filterTagPrefix) {
+		matchQuery = f.tagQuery(strings.TrimPrefix(f.Field, filterTagPrefix))
+	} else {
+		field := filterFields[f.Field]
+		switch field.kind {
+		case filterFieldID:
+			matchQuery = generateIdsQueryObject(f.Values...)
+		case filterFieldDate:
+			matchQuery = f.dateQuery(field.elasticField)
+		default:
+			matchQuery = generateValuesQueryObject(field.elasticField, f.Values...)
+		}
+	}
+
+	if f.Operator == "!=" {
+		return elastic.NewBoolQuery().MustNot(matchQuery)
+	}
+	return matchQuery
+}
+
+// tagQuery is a helper function that matches arrays with the given tag key set to any of the values
+func (f *FilterComparison) tagQuery(key string) elastic.Query {
+	return elastic.NewNestedQuery("Tags", elastic.NewBoolQuery().Must(
+		elastic.NewTermQuery("Tags.key.keyword", key),
+		generateValuesQueryObject("Tags.value.keyword", f.Values...),
+	))
+}
+
+// dateQuery is a helper function that converts a comparison on a date field to range queries
+func (f *FilterComparison) dateQuery(elasticField string) elastic.Query {
+	switch f.Operator {
+	case "<":
+		return elastic.NewRangeQuery(elasticField).Lt(f.Values[0])
+	case "<=":
+		return elastic.NewRangeQuery(elasticField).Lte(f.Values[0])
+	case ">":
+		return elastic.NewRangeQuery(elasticField).Gt(f.Values[0])
+	case ">=":
+		return elastic.NewRangeQuery(elasticField).Gte(f.Values[0])
+	}
+
+	queries := []elastic.Query{}
+	for _, value := range f.Values {
+		queries = append(queries, elastic.NewRangeQuery(elasticField).Gte(value).Lte(value))
+	}
+	if len(queries) == 1 {
+		return queries[0]
+	}
+	return elastic.NewBoolQuery().Should(queries...).MinimumNumberShouldMatch(1)
+}
+
+// generateValuesQueryObject is a helper function that matches any of the given values exactly,
+// or as a wildcard pattern if the value contains * or ?
+func generateValuesQueryObject(elasticField string, values ...string) elastic.Query {
+	queries := []elastic.Query{}
+	for _, value := range values {
+		if strings.ContainsAny(value, "*?") {
+			queries = append(queries, elastic.NewWildcardQuery(elasticField, value))
+		} else {
+			queries = append(queries, elastic.NewTermQuery(elasticField, value))
+		}
+	}
+	if len(queries) == 1 {
+		return queries[0]
+	}
+	return elastic.NewBoolQuery().Should(queries...).MinimumNumberShouldMatch(1)
+}
+
+// validateFilterComparison is a helper function that checks the field and operator of a comparison
+// are compatible, and normalizes the values to what Elastic expects
+func validateFilterComparison(comparison *FilterComparison, position int) error {
+	if strings.HasPrefix(comparison.Field, filterTagPrefix) {
+		if len(strings.TrimPrefix(comparison.Field, filterTagPrefix)) == 0 {
+			return fmt.Errorf("Invalid filter: missing tag key at position %d", position)
+		}
+		if !isFilterEqualityOperator(comparison.Operator) {
+			return fmt.Errorf("Invalid filter: operator %s is not supported on tags (at position %d)", comparison.Operator, position)
+		}
+		return nil
+	}
+
+	field, ok := filterFields[comparison.Field]
+	if !ok {
+		return fmt.Errorf("Invalid filter: unknown field %q at position %d", comparison.Field, position)
+	}
+
+	switch field.kind {
+	case filterFieldID:
+		if !isFilterEqualityOperator(comparison.Operator) {
+			return fmt.Errorf("Invalid filter: operator %s is not supported on field id (at position %d)", comparison.Operator, position)
+		}
+		for _, value := range comparison.Values {
+			if err := ValidateHexObjectID(value); err != nil {
+				return fmt.Errorf("Invalid filter: %v (at position %d)", err, position)
+			}
+		}
+	case filterFieldKeyword:
+		if !isFilterEqualityOperator(comparison.Operator) {
+			return fmt.Errorf("Invalid filter: operator %s is not supported on field %s (at position %d)", comparison.Operator, comparison.Field, position)
+		}
+		// These fields are stored through the lowercase normalizer, which isn't applied to wildcard queries
+		for i, value := range comparison.Values {
+			comparison.Values[i] = strings.ToLower(value)
+		}
+	case filterFieldDate:
+		for i, value := range comparison.Values {
+			parsed, err := parseFilterDate(value)
+			if err != nil {
+				return fmt.Errorf("Invalid filter: %q is not a valid date for field %s (at position %d)", value, comparison.Field, position)
+			}
+			comparison.Values[i] = parsed.Format(DateTimeFormat)
+		}
+	}
+	return nil
+}
+
+func isFilterEqualityOperator(operator string) bool {
+	return operator == "=" || operator == "!=" || operator == FilterIn
+}
+
+func parseFilterDate(value string) (time.Time, error) {
+	var err error
+	for _, format := range filterDateFormats {
+		var parsed time.Time
+		parsed, err = time.Parse(format, value)
+		if err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+	return time.Time{}, err
+}
+
+const (
+	filterTokenEOF = iota
+	filterTokenIdentifier
+	filterTokenString
+	filterTokenOperator
+	filterTokenLeftParen
+	filterTokenRightParen
+	filterTokenComma
+)
+
+type filterToken struct {
+	kind     int
+	text     string
+	position int // 1-based offset into the expression, for error messages
+}
+
+// describe gives a human readable description of this token for error messages
+func (t filterToken) describe() string {
+	switch t.kind {
+	case filterTokenEOF:
+		return "end of filter"
+	case filterTokenString:
+		return fmt.Sprintf("value '%s'", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// isKeyword checks if this token is the given keyword (keywords are case insensitive)
+func (t filterToken) isKeyword(keyword string) bool {
+	return t.kind == filterTokenIdentifier && strings.EqualFold(t.text, keyword)
+}
+
+// tokenizeFilter is a helper function that splits a filter expression into tokens
+func tokenizeFilter(filter string) ([]filterToken, error) {
+	runes := []rune(filter)
+	tokens := []filterToken{}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{filterTokenLeftParen, "(", i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{filterTokenRightParen, ")", i + 1})
+			i++
+		case r == ',':
+			tokens = append(tokens, filterToken{filterTokenComma, ",", i + 1})
+			i++
+		case r == '=':
+			tokens = append(tokens, filterToken{filterTokenOperator, "=", i + 1})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, filterToken{filterTokenOperator, string(runes[i : i+2]), i + 1})
+				i += 2
+			} else if r == '!' {
+				return nil, fmt.Errorf("Invalid filter: expected != at position %d", i+1)
+			} else {
+				tokens = append(tokens, filterToken{filterTokenOperator, string(r), i + 1})
+				i++
+			}
+		case r == '\'' || r == '"':
+			start := i
+			value := []rune{}
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				// A backslash escapes the next character (so quotes can be used inside values)
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				value = append(value, runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("Invalid filter: unterminated value starting at position %d", start+1)
+			}
+			tokens = append(tokens, filterToken{filterTokenString, string(value), start + 1})
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.' || runes[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, filterToken{filterTokenIdentifier, string(runes[start:i]), start + 1})
+		default:
+			return nil, fmt.Errorf("Invalid filter: unexpected character %q at position %d", r, i+1)
+		}
+	}
+
+	return append(tokens, filterToken{filterTokenEOF, "", len(runes) + 1}), nil
+}
+
+// filterParser is a recursive descent parser over the tokens of a filter expression:
+//
+//	or         := and ("or" and)*
+//	and        := not ("and" not)*
+//	not        := "not" not | primary
+//	primary    := "(" or ")" | comparison
+//	comparison := field operator value | field "in" "(" value ("," value)* ")"
+type filterParser struct {
+	tokens  []filterToken
+	current int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.current]
+}
+
+func (p *filterParser) next() filterToken {
+	token := p.tokens[p.current]
+	if token.kind != filterTokenEOF {
+		p.current++
+	}
+	return token
+}
+
+func (p *filterParser) parseOr() (FilterExpression, error) {
+	return p.parseLogical(FilterOr, p.parseAnd)
+}
+
+func (p *filterParser) parseAnd() (FilterExpression, error) {
+	return p.parseLogical(FilterAnd, p.parseNot)
+}
+
+// parseLogical is a helper function that parses one or more operands separated by the given keyword
+func (p *filterParser) parseLogical(operator string, parseOperand func() (FilterExpression, error)) (FilterExpression, error) {
+	first, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	operands := []FilterExpression{first}
+	for p.peek().isKeyword(operator) {
+		p.next()
+		operand, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+	if len(operands) == 1 {
+		return first, nil
+	}
+	return &FilterLogicalExpression{Operator: operator, Operands: operands}, nil
+}
+
+func (p *filterParser) parseNot() (FilterExpression, error) {
+	if p.peek().isKeyword("not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &FilterNotExpression{Operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (FilterExpression, error) {
+	if p.peek().kind == filterTokenLeftParen {
+		p.next()
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != filterTokenRightParen {
+			return nil, fmt.Errorf("Invalid filter: expected \")\" but found %s at position %d", closing.describe(), closing.position)
+		}
+		return expression, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (FilterExpression, error) {
+	field := p.next()
+	if field.kind != filterTokenIdentifier || field.isKeyword(FilterAnd) || field.isKeyword(FilterOr) || field.isKeyword(FilterIn) {
+		return nil, fmt.Errorf("Invalid filter: expected a field name but found %s at position %d", field.describe(), field.position)
+	}
+	// Field names are case insensitive, but tag keys aren't
+	fieldName := strings.ToLower(field.text)
+	if strings.HasPrefix(fieldName, filterTagPrefix) {
+		fieldName = filterTagPrefix + field.text[len(filterTagPrefix):]
+	}
+	comparison := &FilterComparison{Field: fieldName}
+
+	operator := p.next()
+	switch {
+	case operator.kind == filterTokenOperator:
+		comparison.Operator = operator.text
+		value := p.next()
+		if value.kind != filterTokenString {
+			return nil, fmt.Errorf("Invalid filter: expected a quoted value but found %s at position %d", value.describe(), value.position)
+		}
+		comparison.Values = []string{value.text}
+	case operator.isKeyword(FilterIn):
+		comparison.Operator = FilterIn
+		values, err := p.parseValueList()
+		if err != nil {
+			return nil, err
+		}
+		comparison.Values = values
+	default:
+		return nil, fmt.Errorf("Invalid filter: expected an operator but found %s at position %d", operator.describe(), operator.position)
+	}
+
+	if err := validateFilterComparison(comparison, field.position); err != nil {
+		return nil, err
+	}
+	return comparison, nil
+}
+
+// parseValueList is a helper function that parses the parenthesized list of values after "in"
+func (p *filterParser) parseValueList() ([]string, error) {
+	if open := p.next(); open.kind != filterTokenLeftParen {
+		return nil, fmt.Errorf("Invalid filter: expected \"(\" after in but found %s at position %d", open.describe(), open.position)
+	}
+
+	values := []string{}
+	for {
+		value := p.next()
+		if value.kind != filterTokenString {
+			return nil, fmt.Errorf("Invalid filter: expected a quoted value but found %s at position %d", value.describe(), value.position)
+		}
+		values = append(values, value.text)
+
+		separator := p.next()
+		if separator.kind == filterTokenRightParen {
+			return values, nil
+		}
+		if separator.kind != filterTokenComma {
+			return nil, fmt.Errorf("Invalid filter: expected \",\" or \")\" but found %s at position %d", separator.describe(), separator.position)
+		}
+	}
+}
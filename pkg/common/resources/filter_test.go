@@ -0,0 +1,178 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilterSingleComparison(t *testing.T) {
+	expression, err := ParseFilter("model='FA-X70'")
+	assert.NoError(t, err)
+	assert.Equal(t, &FilterComparison{Field: "model", Operator: "=", Values: []string{"fa-x70"}}, expression)
+}
+
+func TestParseFilterAndChain(t *testing.T) {
+	expression, err := ParseFilter("model='FA-X70' and tags.site='dc1' and status!='Connected'")
+	assert.NoError(t, err)
+
+	logical, ok := expression.(*FilterLogicalExpression)
+	assert.True(t, ok)
+	assert.Equal(t, FilterAnd, logical.Operator)
+	assert.Len(t, logical.Operands, 3)
+	assert.Equal(t, &FilterComparison{Field: "tags.site", Operator: "=", Values: []string{"dc1"}}, logical.Operands[1])
+	assert.Equal(t, &FilterComparison{Field: "status", Operator: "!=", Values: []string{"connected"}}, logical.Operands[2])
+}
+
+func TestParseFilterPrecedence(t *testing.T) {
+	expression, err := ParseFilter("name='a*' or name='b*' and not (version='5.*')")
+	assert.NoError(t, err)
+
+	logical, ok := expression.(*FilterLogicalExpression)
+	assert.True(t, ok)
+	assert.Equal(t, FilterOr, logical.Operator)
+	assert.Len(t, logical.Operands, 2)
+
+	inner, ok := logical.Operands[1].(*FilterLogicalExpression)
+	assert.True(t, ok)
+	assert.Equal(t, FilterAnd, inner.Operator)
+	assert.IsType(t, &FilterNotExpression{}, inner.Operands[1])
+}
+
+func TestParseFilterIn(t *testing.T) {
+	expression, err := ParseFilter("device_type IN ('FlashArray', \"FlashBlade\")")
+	assert.NoError(t, err)
+	assert.Equal(t, &FilterComparison{Field: "device_type", Operator: FilterIn, Values: []string{"flasharray", "flashblade"}}, expression)
+}
+
+func TestParseFilterDateComparison(t *testing.T) {
+	expression, err := ParseFilter("_as_of >= '2019-03-01'")
+	assert.NoError(t, err)
+	assert.Equal(t, &FilterComparison{Field: "_as_of", Operator: ">=", Values: []string{"2019-03-01T00:00:00.000"}}, expression)
+}
+
+func TestParseFilterTagKeyCaseSensitive(t *testing.T) {
+	expression, err := ParseFilter("TAGS.Site='DC1'")
+	assert.NoError(t, err)
+	assert.Equal(t, &FilterComparison{Field: "tags.Site", Operator: "=", Values: []string{"DC1"}}, expression)
+}
+
+func TestParseFilterEscapedQuote(t *testing.T) {
+	expression, err := ParseFilter(`tags.owner='o\'brien'`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"o'brien"}, expression.(*FilterComparison).Values)
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"model",
+		"model=",
+		"model=FA-X70",
+		"model='FA-X70",
+		"model='a' and",
+		"model='a' model='b'",
+		"(model='a'",
+		"model in 'a'",
+		"model in ('a',)",
+		"unknown='a'",
+		"model<'a'",
+		"tags.site>'a'",
+		"tags.='a'",
+		"id='abc'",
+		"_as_of>'yesterday'",
+		"model ! 'a'",
+		"model='a' # comment",
+	}
+	for _, filter := range invalid {
+		_, err := ParseFilter(filter)
+		assert.Error(t, err, filter)
+	}
+}
+
+func TestFilterElasticQueryComparison(t *testing.T) {
+	expression, err := ParseFilter("model='FA-X*'")
+	assert.NoError(t, err)
+
+	source, err := expression.ElasticQuery().Source()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"wildcard": map[string]interface{}{"Model.keyword": map[string]interface{}{"wildcard": "fa-x*"}},
+	}, source)
+}
+
+func TestFilterElasticQueryNotEqual(t *testing.T) {
+	expression, err := ParseFilter("status!='Connected'")
+	assert.NoError(t, err)
+
+	source, err := expression.ElasticQuery().Source()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must_not": map[string]interface{}{
+				"term": map[string]interface{}{"Status.keyword": "connected"},
+			},
+		},
+	}, source)
+}
+
+func TestFilterElasticQueryTag(t *testing.T) {
+	expression, err := ParseFilter("tags.site='dc1'")
+	assert.NoError(t, err)
+
+	source, err := expression.ElasticQuery().Source()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"nested": map[string]interface{}{
+			"path": "Tags",
+			"query": map[string]interface{}{
+				"bool": map[string]interface{}{
+					"must": []interface{}{
+						map[string]interface{}{"term": map[string]interface{}{"Tags.key.keyword": "site"}},
+						map[string]interface{}{"term": map[string]interface{}{"Tags.value.keyword": "dc1"}},
+					},
+				},
+			},
+		},
+	}, source)
+}
+
+func TestFilterElasticQueryDateRange(t *testing.T) {
+	expression, err := ParseFilter("_last_updated < '2019-03-01T12:00:00'")
+	assert.NoError(t, err)
+
+	source, err := expression.ElasticQuery().Source()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"range": map[string]interface{}{
+			"LastUpdated": map[string]interface{}{
+				"from":          nil,
+				"include_lower": true,
+				"include_upper": false,
+				"to":            "2019-03-01T12:00:00.000",
+			},
+		},
+	}, source)
+}
+
+func TestQueryEmptyWithFilter(t *testing.T) {
+	expression, err := ParseFilter("name='a'")
+	assert.NoError(t, err)
+
+	query := ArrayQuery{Filter: expression}
+	assert.False(t, query.IsEmpty())
+}
@@ -0,0 +1,174 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NewMaintenanceWindowFromREST creates a maintenance window from a map in the format of the REST API call (lower_case)
+func NewMaintenanceWindowFromREST(m map[string]interface{}) (*MaintenanceWindow, error) {
+	window := &MaintenanceWindow{
+		ArrayIDs:  []string{},
+		ArrayTags: map[string]string{},
+	}
+	err := window.ApplyPatch(m)
+	if err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// ConvertToMaintenanceWindowMap converts this window into a string->interface map suitable for marshalling
+func (w *MaintenanceWindow) ConvertToMaintenanceWindowMap() map[string]interface{} {
+	arrayIDs := w.ArrayIDs
+	if arrayIDs == nil {
+		arrayIDs = []string{}
+	}
+	arrayTags := w.ArrayTags
+	if arrayTags == nil {
+		arrayTags = map[string]string{}
+	}
+
+	return map[string]interface{}{
+		"id":            w.InternalID,
+		"array_ids":     arrayIDs,
+		"array_tags":    arrayTags,
+		"start":         time.Unix(w.Start, 0).UTC(),
+		"end":           time.Unix(w.End, 0).UTC(),
+		"reason":        w.Reason,
+		"_last_updated": w.LastUpdated,
+	}
+}
+
+// ApplyPatch applies the given patches to this window, with the given map in the format accepted by
+// the REST API ("array_ids", "start", etc.). The window should be validated afterwards.
+func (w *MaintenanceWindow) ApplyPatch(m map[string]interface{}) error {
+	if value, ok := m["id"]; ok {
+		id, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key id must be a string")
+		}
+		err := ValidateHexObjectID(id)
+		if err != nil {
+			return err
+		}
+		w.InternalID = id
+	}
+	if value, ok := m["array_ids"]; ok {
+		arrayIDs, err := parseStringList("array_ids", value)
+		if err != nil {
+			return err
+		}
+		for _, id := range arrayIDs {
+			err = ValidateHexObjectID(id)
+			if err != nil {
+				return err
+			}
+		}
+		w.ArrayIDs = arrayIDs
+	}
+	if value, ok := m["array_tags"]; ok {
+		tagMap, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Key array_tags must be a map of tag keys to values")
+		}
+		arrayTags := map[string]string{}
+		for key, tagValue := range tagMap {
+			stringValue, ok := tagValue.(string)
+			if !ok {
+				return fmt.Errorf("Error converting tag value into string. Offending key-value pair is '%s: %v'", key, tagValue)
+			}
+			arrayTags[key] = stringValue
+		}
+		w.ArrayTags = arrayTags
+	}
+	if value, ok := m["start"]; ok {
+		start, err := parseTimestamp("start", value)
+		if err != nil {
+			return err
+		}
+		w.Start = start
+	}
+	if value, ok := m["end"]; ok {
+		end, err := parseTimestamp("end", value)
+		if err != nil {
+			return err
+		}
+		w.End = end
+	}
+	if value, ok := m["reason"]; ok {
+		reason, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key reason must be a string")
+		}
+		w.Reason = strings.TrimSpace(reason)
+	}
+	return nil
+}
+
+// Validate checks that this window has all required fields filled in
+func (w *MaintenanceWindow) Validate() error {
+	if len(w.ArrayIDs) == 0 && len(w.ArrayTags) == 0 {
+		// An empty selector would silence every array, which is much more likely to be a mistake
+		return fmt.Errorf("Maintenance window must have at least one of array_ids or array_tags")
+	}
+	if w.Start == 0 {
+		return fmt.Errorf("Maintenance window is missing start")
+	}
+	if w.End <= w.Start {
+		return fmt.Errorf("Maintenance window end must be after its start")
+	}
+	if len(w.Reason) == 0 {
+		return fmt.Errorf("Maintenance window is missing reason")
+	}
+	return nil
+}
+
+// Matches checks if this window applies to the given array, either by ID or because it has every tag in the selector
+func (w *MaintenanceWindow) Matches(arrayID string, arrayTags map[string]string) bool {
+	if containsString(w.ArrayIDs, arrayID) {
+		return true
+	}
+	if len(w.ArrayTags) == 0 {
+		return false
+	}
+	for key, value := range w.ArrayTags {
+		if tagValue, ok := arrayTags[key]; !ok || tagValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// IsActiveAt checks if the given time (in Unix seconds) falls within this window
+func (w *MaintenanceWindow) IsActiveAt(at int64) bool {
+	return at >= w.Start && at < w.End
+}
+
+// parseTimestamp is a helper function to convert an RFC 3339 time from a REST API call into Unix seconds
+func parseTimestamp(key string, value interface{}) (int64, error) {
+	timeString, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("Key %s must be a string in RFC 3339 format, such as \"2019-01-02T15:04:05Z\"", key)
+	}
+	parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(timeString))
+	if err != nil {
+		return 0, fmt.Errorf("Key %s must be a string in RFC 3339 format, such as \"2019-01-02T15:04:05Z\"", key)
+	}
+	return parsed.Unix(), nil
+}
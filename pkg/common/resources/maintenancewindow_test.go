@@ -0,0 +1,110 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMaintenanceWindowFromRESTSetAll(t *testing.T) {
+	window, err := NewMaintenanceWindowFromREST(map[string]interface{}{
+		"id":         "1234567890abcdefedcba098",
+		"array_ids":  []interface{}{"1234567890abcdefedcba099"},
+		"array_tags": map[string]interface{}{"site": "east"},
+		"start":      "2019-01-02T15:00:00Z",
+		"end":        "2019-01-02T17:00:00+01:00",
+		"reason":     " Purity upgrade ",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890abcdefedcba098", window.InternalID)
+	assert.Equal(t, []string{"1234567890abcdefedcba099"}, window.ArrayIDs)
+	assert.Equal(t, map[string]string{"site": "east"}, window.ArrayTags)
+	assert.Equal(t, time.Date(2019, 1, 2, 15, 0, 0, 0, time.UTC).Unix(), window.Start)
+	assert.Equal(t, time.Date(2019, 1, 2, 16, 0, 0, 0, time.UTC).Unix(), window.End)
+	assert.Equal(t, "Purity upgrade", window.Reason)
+	assert.NoError(t, window.Validate())
+
+	converted := window.ConvertToMaintenanceWindowMap()
+	assert.Equal(t, time.Date(2019, 1, 2, 15, 0, 0, 0, time.UTC), converted["start"])
+	assert.Equal(t, time.Date(2019, 1, 2, 16, 0, 0, 0, time.UTC), converted["end"])
+	assert.Equal(t, "Purity upgrade", converted["reason"])
+}
+
+func TestNewMaintenanceWindowFromRESTInvalid(t *testing.T) {
+	invalidWindows := []map[string]interface{}{
+		{"id": "not an ID"},
+		{"array_ids": []interface{}{"not an ID"}},
+		{"array_ids": "1234567890abcdefedcba099"},
+		{"array_tags": map[string]interface{}{"site": 1}},
+		{"start": "tomorrow"},
+		{"end": 1546441200},
+		{"reason": false},
+	}
+	for _, m := range invalidWindows {
+		_, err := NewMaintenanceWindowFromREST(m)
+		assert.Error(t, err, "window %v", m)
+	}
+}
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	valid := MaintenanceWindow{ArrayIDs: []string{"1234567890abcdefedcba099"}, Start: 100, End: 200, Reason: "Host migration"}
+	assert.NoError(t, valid.Validate())
+
+	noSelector := valid
+	noSelector.ArrayIDs = []string{}
+	assert.Error(t, noSelector.Validate())
+
+	tagSelector := noSelector
+	tagSelector.ArrayTags = map[string]string{"site": "east"}
+	assert.NoError(t, tagSelector.Validate())
+
+	noStart := valid
+	noStart.Start = 0
+	assert.Error(t, noStart.Validate())
+
+	endBeforeStart := valid
+	endBeforeStart.End = 100
+	assert.Error(t, endBeforeStart.Validate())
+
+	noReason := valid
+	noReason.Reason = ""
+	assert.Error(t, noReason.Validate())
+}
+
+func TestMaintenanceWindowMatches(t *testing.T) {
+	byID := MaintenanceWindow{ArrayIDs: []string{"array-1"}}
+	assert.True(t, byID.Matches("array-1", nil))
+	assert.False(t, byID.Matches("array-2", map[string]string{"site": "east"}))
+
+	byTags := MaintenanceWindow{ArrayTags: map[string]string{"site": "east", "env": "prod"}}
+	assert.True(t, byTags.Matches("array-2", map[string]string{"site": "east", "env": "prod", "rack": "4"}))
+	assert.False(t, byTags.Matches("array-2", map[string]string{"site": "east"}))
+
+	both := MaintenanceWindow{ArrayIDs: []string{"array-1"}, ArrayTags: map[string]string{"site": "east"}}
+	assert.True(t, both.Matches("array-1", nil))
+	assert.True(t, both.Matches("array-2", map[string]string{"site": "east"}))
+	assert.False(t, both.Matches("array-3", map[string]string{"site": "west"}))
+}
+
+func TestMaintenanceWindowIsActiveAt(t *testing.T) {
+	window := MaintenanceWindow{Start: 100, End: 200}
+	assert.False(t, window.IsActiveAt(99))
+	assert.True(t, window.IsActiveAt(100))
+	assert.True(t, window.IsActiveAt(199))
+	assert.False(t, window.IsActiveAt(200))
+}
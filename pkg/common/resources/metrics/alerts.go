@@ -14,23 +14,38 @@
 
 package metrics
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // PopulateSeverityIndex sets the SeverityIndex field
 // of this alert based on the value of Severity
 func (a *Alert) PopulateSeverityIndex() {
-	switch strings.ToLower(a.Severity) {
+	a.SeverityIndex = GetSeverityIndex(a.Severity)
+}
+
+// GetDocumentID gets the ID this alert is stored under. Alerts that aren't raised by the array itself (such
+// as the alerts of alert rules) get a namespace of IDs per source, so they can never overwrite the alerts
+// raised by the array.
+func (a *Alert) GetDocumentID() string {
+	if len(a.Source) > 0 && a.Source != AlertSourceArray {
+		return fmt.Sprintf("%s-%s-alert-%d", a.ArrayID, a.Source, a.AlertID)
+	}
+	return fmt.Sprintf("%s-alert-%d", a.ArrayID, a.AlertID)
+}
+
+// GetSeverityIndex gets the index of the given severity, from 1 (info) to 3 (critical),
+// or 0 if it isn't a known severity
+func GetSeverityIndex(severity string) byte {
+	switch strings.ToLower(severity) {
 	case "info":
-		a.SeverityIndex = 1
-		return
+		return 1
 	case "warning":
-		a.SeverityIndex = 2
-		return
+		return 2
 	case "critical":
-		a.SeverityIndex = 3
-		return
+		return 3
 	default:
-		a.SeverityIndex = 0
-		return
+		return 0
 	}
 }
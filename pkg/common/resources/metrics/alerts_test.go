@@ -91,3 +91,10 @@ func TestPopulateSeverityIndexCriticalUppercase(t *testing.T) {
 
 	assert.Equal(t, byte(3), alert.SeverityIndex)
 }
+
+func TestGetDocumentID(t *testing.T) {
+	assert.Equal(t, "array-1-alert-12", (&Alert{ArrayID: "array-1", AlertID: 12}).GetDocumentID())
+	assert.Equal(t, "array-1-alert-12", (&Alert{ArrayID: "array-1", AlertID: 12, Source: AlertSourceArray}).GetDocumentID())
+	assert.Equal(t, "array-1-rule-alert-12", (&Alert{ArrayID: "array-1", AlertID: 12, Source: AlertSourceRule}).GetDocumentID())
+	assert.Equal(t, "array-1-replication-alert-12", (&Alert{ArrayID: "array-1", AlertID: 12, Source: AlertSourceReplication}).GetDocumentID())
+}
@@ -0,0 +1,28 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditEventGetDocumentID(t *testing.T) {
+	command := &AuditEvent{ArrayID: "array-1", Kind: AuditEventCommand, EventID: "1234"}
+	session := &AuditEvent{ArrayID: "array-1", Kind: AuditEventSession, EventID: "1234"}
+	assert.Equal(t, "array-1-command-1234", command.GetDocumentID())
+	assert.Equal(t, "array-1-session-1234", session.GetDocumentID())
+}
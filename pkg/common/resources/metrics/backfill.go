@@ -0,0 +1,51 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+// MetricGap is a span of time an array's metrics weren't collected for (Unix seconds, both ends exclusive)
+type MetricGap struct {
+	Start int64 // The latest collection before the gap
+	End   int64 // The first collection after the gap, or when the gap was found if collection hasn't resumed yet
+}
+
+// FindMetricGaps finds the spans longer than maxInterval (in seconds) between the given collection times (oldest
+// first, Unix seconds) and now. Gaps reaching back before since are cut off at since, as that's as far back as
+// they can be filled in. Arrays that have never been collected have no gaps.
+func FindMetricGaps(collectionTimes []int64, since int64, now int64, maxInterval int64) []*MetricGap {
+	gaps := []*MetricGap{}
+	if len(collectionTimes) == 0 {
+		return gaps
+	}
+
+	previous := collectionTimes[0]
+	for i := 1; i <= len(collectionTimes); i++ {
+		next := now
+		if i < len(collectionTimes) {
+			next = collectionTimes[i]
+		}
+
+		start := previous
+		if start < since {
+			start = since
+		}
+		if next-start > maxInterval {
+			gaps = append(gaps, &MetricGap{Start: start, End: next})
+		}
+		if next > previous {
+			previous = next
+		}
+	}
+	return gaps
+}
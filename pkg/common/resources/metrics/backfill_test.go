@@ -0,0 +1,76 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	backfillNow = int64(1552600000)
+	backfillDay = int64(24 * 60 * 60)
+)
+
+// createCollectionTimes is a helper that builds collection times every period seconds from start up to (not including) end
+func createCollectionTimes(start int64, end int64, period int64) []int64 {
+	times := []int64{}
+	for t := start; t < end; t += period {
+		times = append(times, t)
+	}
+	return times
+}
+
+func TestFindMetricGapsNone(t *testing.T) {
+	times := createCollectionTimes(backfillNow-3600, backfillNow, 30)
+	assert.Empty(t, FindMetricGaps(times, backfillNow-backfillDay, backfillNow, 90))
+}
+
+func TestFindMetricGapsNeverCollected(t *testing.T) {
+	assert.Empty(t, FindMetricGaps([]int64{}, backfillNow-backfillDay, backfillNow, 90))
+}
+
+func TestFindMetricGapsCollectionStopped(t *testing.T) {
+	// The metrics client was down for the last hour
+	times := createCollectionTimes(backfillNow-7200, backfillNow-3600, 30)
+	gaps := FindMetricGaps(times, backfillNow-backfillDay, backfillNow, 90)
+	assert.Len(t, gaps, 1)
+	assert.Equal(t, &MetricGap{Start: backfillNow - 3630, End: backfillNow}, gaps[0])
+}
+
+func TestFindMetricGapsCollectionResumed(t *testing.T) {
+	// Elastic was down for an hour, and collection has resumed since
+	times := append(createCollectionTimes(backfillNow-7200, backfillNow-5400, 30), createCollectionTimes(backfillNow-1800, backfillNow, 30)...)
+	gaps := FindMetricGaps(times, backfillNow-backfillDay, backfillNow, 90)
+	assert.Len(t, gaps, 1)
+	assert.Equal(t, &MetricGap{Start: backfillNow - 5430, End: backfillNow - 1800}, gaps[0])
+}
+
+func TestFindMetricGapsBeforeSince(t *testing.T) {
+	// The latest collection before since comes first, but the gap can only be filled in from since
+	times := append([]int64{backfillNow - 2*backfillDay}, createCollectionTimes(backfillNow-3600, backfillNow, 30)...)
+	gaps := FindMetricGaps(times, backfillNow-backfillDay, backfillNow, 90)
+	assert.Len(t, gaps, 1)
+	assert.Equal(t, &MetricGap{Start: backfillNow - backfillDay, End: backfillNow - 3600}, gaps[0])
+}
+
+func TestFindMetricGapsMaxInterval(t *testing.T) {
+	// A single missed collection isn't worth filling in, but a couple are
+	times := []int64{backfillNow - 300, backfillNow - 270, backfillNow - 180, backfillNow - 150, backfillNow - 30}
+	gaps := FindMetricGaps(times, backfillNow-backfillDay, backfillNow, 90)
+	assert.Len(t, gaps, 1)
+	assert.Equal(t, &MetricGap{Start: backfillNow - 150, End: backfillNow - 30}, gaps[0])
+}
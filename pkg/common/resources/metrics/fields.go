@@ -0,0 +1,97 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ArrayMetricFields gets the names of the numeric capacity, object count and performance fields of an array
+// metric, such as PercentFull
+func ArrayMetricFields() []string {
+	return getNumericFieldNames(reflect.TypeOf(ArrayMetric{}))
+}
+
+// VolumeMetricFields gets the names of the numeric capacity and performance fields of a volume metric, such
+// as WriteLatency
+func VolumeMetricFields() []string {
+	return getNumericFieldNames(reflect.TypeOf(VolumeMetric{}))
+}
+
+// GetFieldValue gets the value of the numeric field with the given name. Returns false if there is no such
+// field, or if the group of metrics it belongs to wasn't collected.
+func (m *ArrayMetric) GetFieldValue(field string) (float64, bool) {
+	return getNumericFieldValue(reflect.ValueOf(m).Elem(), field)
+}
+
+// GetFieldValue gets the value of the numeric field with the given name. Returns false if there is no such
+// field, or if the group of metrics it belongs to wasn't collected.
+func (m *VolumeMetric) GetFieldValue(field string) (float64, bool) {
+	return getNumericFieldValue(reflect.ValueOf(m).Elem(), field)
+}
+
+// getNumericFieldNames is a helper function to list the numeric fields of the metric groups (such as
+// ArrayCapacityMetric) embedded in a metric struct. Metadata like CreatedAt isn't included.
+func getNumericFieldNames(structType reflect.Type) []string {
+	names := []string{}
+	for i := 0; i < structType.NumField(); i++ {
+		group, ok := getEmbeddedGroupType(structType.Field(i))
+		if !ok {
+			continue
+		}
+		for j := 0; j < group.NumField(); j++ {
+			if isNumericKind(group.Field(j).Type.Kind()) {
+				names = append(names, group.Field(j).Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getNumericFieldValue is a helper function to find a numeric field of the metric groups embedded in a
+// metric struct by name, skipping the groups that are nil
+func getNumericFieldValue(value reflect.Value, name string) (float64, bool) {
+	for i := 0; i < value.NumField(); i++ {
+		if _, ok := getEmbeddedGroupType(value.Type().Field(i)); !ok || value.Field(i).IsNil() {
+			continue
+		}
+		field := value.Field(i).Elem().FieldByName(name)
+		if !field.IsValid() || !isNumericKind(field.Kind()) {
+			continue
+		}
+		switch {
+		case field.Kind() >= reflect.Int && field.Kind() <= reflect.Int64:
+			return float64(field.Int()), true
+		case field.Kind() >= reflect.Uint && field.Kind() <= reflect.Uint64:
+			return float64(field.Uint()), true
+		default:
+			return field.Float(), true
+		}
+	}
+	return 0, false
+}
+
+func getEmbeddedGroupType(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous || field.Type.Kind() != reflect.Ptr || field.Type.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+	return field.Type.Elem(), true
+}
+
+func isNumericKind(kind reflect.Kind) bool {
+	return (kind >= reflect.Int && kind <= reflect.Uint64) || kind == reflect.Float32 || kind == reflect.Float64
+}
@@ -0,0 +1,81 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrayMetricFields(t *testing.T) {
+	fields := ArrayMetricFields()
+	assert.Contains(t, fields, "PercentFull")
+	assert.Contains(t, fields, "WriteLatency")
+	assert.Contains(t, fields, "VolumeCount")
+	assert.NotContains(t, fields, "CreatedAt")
+	assert.NotContains(t, fields, "ArrayID")
+}
+
+func TestVolumeMetricFields(t *testing.T) {
+	fields := VolumeMetricFields()
+	assert.Contains(t, fields, "UsedSpace")
+	assert.Contains(t, fields, "WriteLatency")
+	assert.NotContains(t, fields, "PercentFull")
+	assert.NotContains(t, fields, "CreatedAt")
+}
+
+func TestArrayMetricGetFieldValue(t *testing.T) {
+	metric := &ArrayMetric{
+		ArrayCapacityMetric:    &ArrayCapacityMetric{PercentFull: 0.9, UsedSpace: 100},
+		ArrayPerformanceMetric: &ArrayPerformanceMetric{QueueDepth: 4},
+		CreatedAt:              1000,
+	}
+
+	value, ok := metric.GetFieldValue("PercentFull")
+	assert.True(t, ok)
+	assert.Equal(t, 0.9, value)
+
+	value, ok = metric.GetFieldValue("UsedSpace")
+	assert.True(t, ok)
+	assert.Equal(t, float64(100), value)
+
+	value, ok = metric.GetFieldValue("QueueDepth")
+	assert.True(t, ok)
+	assert.Equal(t, float64(4), value)
+
+	// Object counts weren't collected
+	_, ok = metric.GetFieldValue("VolumeCount")
+	assert.False(t, ok)
+
+	_, ok = metric.GetFieldValue("CreatedAt")
+	assert.False(t, ok)
+
+	_, ok = metric.GetFieldValue("NotAField")
+	assert.False(t, ok)
+}
+
+func TestVolumeMetricGetFieldValue(t *testing.T) {
+	metric := &VolumeMetric{
+		VolumePerformanceMetric: &VolumePerformanceMetric{WriteLatency: 2500},
+	}
+
+	value, ok := metric.GetFieldValue("WriteLatency")
+	assert.True(t, ok)
+	assert.Equal(t, float64(2500), value)
+
+	_, ok = metric.GetFieldValue("UsedSpace")
+	assert.False(t, ok)
+}
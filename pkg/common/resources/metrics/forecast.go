@@ -0,0 +1,243 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"time"
+)
+
+// ForecastThresholds are the percentages full that capacity forecasts project dates for
+var ForecastThresholds = []int{80, 90, 100}
+
+const (
+	// How far ahead projections are made: anything further out is treated as never
+	forecastHorizonDays = 5 * 365
+	secondsPerDay       = 24 * 60 * 60
+	daysPerWeek         = 7
+	// A weekly pattern is only fitted with at least this much history, so every day of the week is seen twice
+	minimumSeasonalHistoryDays = 2 * daysPerWeek
+)
+
+// ForecastCapacity fits a linear trend to the given daily capacity samples (oldest first), plus a weekly
+// seasonal trend if there's enough history, and projects when the array will reach each of the ForecastThresholds
+func ForecastCapacity(arrayID string, samples []*CapacitySample, now time.Time) *CapacityForecast {
+	forecast := &CapacityForecast{
+		ArrayID:     arrayID,
+		GeneratedAt: now.Unix(),
+		SampleCount: len(samples),
+		Projections: []*CapacityProjection{},
+	}
+	if len(samples) == 0 {
+		for _, percent := range ForecastThresholds {
+			forecast.Projections = append(forecast.Projections, &CapacityProjection{Percent: percent})
+		}
+		return forecast
+	}
+
+	first := samples[0]
+	latest := samples[len(samples)-1]
+	forecast.HistoryStart = first.Time
+	forecast.HistoryEnd = latest.Time
+	forecast.UsedSpace = latest.UsedSpace
+	forecast.TotalSpace = latest.TotalSpace
+	// Samples are a fraction, like the collected metrics
+	forecast.PercentFull = latest.PercentFull * 100
+
+	var slope, intercept float64
+	var seasonality []float64
+	if len(samples) >= 2 {
+		slope, intercept = fitLinearTrend(samples)
+		forecast.DailyGrowth = slope
+		if (latest.Time-first.Time)/secondsPerDay >= minimumSeasonalHistoryDays {
+			seasonality = fitWeeklySeasonality(samples, slope, intercept)
+			forecast.WeeklySeasonality = seasonality
+		}
+	}
+
+	horizon := now.Unix() + forecastHorizonDays*secondsPerDay
+	for _, percent := range ForecastThresholds {
+		projection := &CapacityProjection{Percent: percent}
+		target := latest.TotalSpace * float64(percent) / 100
+		if target > 0 && latest.UsedSpace >= target {
+			// Already there
+			projection.LinearDate = int64Pointer(latest.Time)
+			projection.SeasonalDate = int64Pointer(latest.Time)
+		} else if target > 0 && slope > 0 {
+			projection.LinearDate = projectLinearDate(first.Time, latest.Time, slope, intercept, target, horizon)
+			if seasonality != nil {
+				projection.SeasonalDate = projectSeasonalDate(first.Time, latest.Time, slope, intercept, seasonality, target, horizon)
+			}
+		}
+		forecast.Projections = append(forecast.Projections, projection)
+	}
+	return forecast
+}
+
+// AggregateCapacityForecasts combines the forecasts of several arrays into one for the group as a whole:
+// space and growth are summed, and dates are projected from the summed linear trend
+func AggregateCapacityForecasts(forecasts []*CapacityForecast, now time.Time) *CapacityForecast {
+	aggregate := &CapacityForecast{
+		GeneratedAt: now.Unix(),
+		Projections: []*CapacityProjection{},
+	}
+	for _, forecast := range forecasts {
+		if aggregate.HistoryStart == 0 || forecast.HistoryStart < aggregate.HistoryStart {
+			aggregate.HistoryStart = forecast.HistoryStart
+		}
+		if forecast.HistoryEnd > aggregate.HistoryEnd {
+			aggregate.HistoryEnd = forecast.HistoryEnd
+		}
+		aggregate.SampleCount += forecast.SampleCount
+		aggregate.UsedSpace += forecast.UsedSpace
+		aggregate.TotalSpace += forecast.TotalSpace
+		aggregate.DailyGrowth += forecast.DailyGrowth
+	}
+	if aggregate.TotalSpace > 0 {
+		aggregate.PercentFull = aggregate.UsedSpace / aggregate.TotalSpace * 100
+	}
+
+	horizon := now.Unix() + forecastHorizonDays*secondsPerDay
+	for _, percent := range ForecastThresholds {
+		projection := &CapacityProjection{Percent: percent}
+		target := aggregate.TotalSpace * float64(percent) / 100
+		if target > 0 && aggregate.UsedSpace >= target {
+			projection.LinearDate = int64Pointer(aggregate.HistoryEnd)
+		} else if target > 0 && aggregate.DailyGrowth > 0 {
+			// Project from the current usage rather than the fitted intercept, since each array's history starts at a different time
+			projection.LinearDate = projectLinearDate(aggregate.HistoryEnd, aggregate.HistoryEnd, aggregate.DailyGrowth, aggregate.UsedSpace, target, horizon)
+		}
+		aggregate.Projections = append(aggregate.Projections, projection)
+	}
+	return aggregate
+}
+
+// ConvertToForecastMap converts this forecast into a string->interface map suitable for marshalling
+// in the REST API format
+func (f *CapacityForecast) ConvertToForecastMap() map[string]interface{} {
+	projections := []map[string]interface{}{}
+	for _, projection := range f.Projections {
+		projections = append(projections, map[string]interface{}{
+			"percent":       projection.Percent,
+			"linear_date":   unixToTime(projection.LinearDate),
+			"seasonal_date": unixToTime(projection.SeasonalDate),
+		})
+	}
+	seasonality := f.WeeklySeasonality
+	if seasonality == nil {
+		seasonality = []float64{}
+	}
+
+	return map[string]interface{}{
+		"generated_at":       time.Unix(f.GeneratedAt, 0).UTC(),
+		"history_start":      time.Unix(f.HistoryStart, 0).UTC(),
+		"history_end":        time.Unix(f.HistoryEnd, 0).UTC(),
+		"sample_count":       f.SampleCount,
+		"used_space":         f.UsedSpace,
+		"total_space":        f.TotalSpace,
+		"percent_full":       f.PercentFull,
+		"daily_growth":       f.DailyGrowth,
+		"weekly_seasonality": seasonality,
+		"projections":        projections,
+	}
+}
+
+// fitLinearTrend is a helper function that fits used space against days since the first sample by least squares
+func fitLinearTrend(samples []*CapacitySample) (slope float64, intercept float64) {
+	first := samples[0].Time
+	n := float64(len(samples))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, sample := range samples {
+		x := float64(sample.Time-first) / secondsPerDay
+		sumX += x
+		sumY += sample.UsedSpace
+		sumXY += x * sample.UsedSpace
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		// All samples on the same day: no trend
+		return 0, sumY / n
+	}
+	slope = (n*sumXY - sumX*sumY) / denominator
+	intercept = (sumY - slope*sumX) / n
+	return slope, intercept
+}
+
+// fitWeeklySeasonality is a helper function that averages the deviation from the linear trend for each day of the week
+func fitWeeklySeasonality(samples []*CapacitySample, slope float64, intercept float64) []float64 {
+	first := samples[0].Time
+	sums := make([]float64, daysPerWeek)
+	counts := make([]int, daysPerWeek)
+	for _, sample := range samples {
+		x := float64(sample.Time-first) / secondsPerDay
+		weekday := time.Unix(sample.Time, 0).UTC().Weekday()
+		sums[weekday] += sample.UsedSpace - (intercept + slope*x)
+		counts[weekday]++
+	}
+
+	seasonality := make([]float64, daysPerWeek)
+	var total float64
+	for day := range seasonality {
+		if counts[day] > 0 {
+			seasonality[day] = sums[day] / float64(counts[day])
+		}
+		total += seasonality[day]
+	}
+	// Center the pattern so it doesn't shift the trend as a whole
+	mean := total / daysPerWeek
+	for day := range seasonality {
+		seasonality[day] -= mean
+	}
+	return seasonality
+}
+
+// projectLinearDate is a helper function that solves the linear trend for the target, returning nil if that's past the horizon.
+// A trend that has already passed the target (but actual usage hasn't) projects to the latest sample.
+func projectLinearDate(origin int64, latest int64, slope float64, intercept float64, target float64, horizon int64) *int64 {
+	date := origin + int64((target-intercept)/slope*secondsPerDay)
+	if date < latest {
+		date = latest
+	}
+	if date > horizon {
+		return nil
+	}
+	return int64Pointer(date)
+}
+
+// projectSeasonalDate is a helper function that steps forward a day at a time from the latest sample until the linear
+// trend plus the weekly pattern reaches the target, returning nil if that doesn't happen before the horizon
+func projectSeasonalDate(origin int64, latest int64, slope float64, intercept float64, seasonality []float64, target float64, horizon int64) *int64 {
+	for date := latest + secondsPerDay; date <= horizon; date += secondsPerDay {
+		x := float64(date-origin) / secondsPerDay
+		weekday := time.Unix(date, 0).UTC().Weekday()
+		if intercept+slope*x+seasonality[weekday] >= target {
+			return int64Pointer(date)
+		}
+	}
+	return nil
+}
+
+// unixToTime is a helper function that converts an optional timestamp, keeping nil as nil
+func unixToTime(seconds *int64) *time.Time {
+	if seconds == nil {
+		return nil
+	}
+	converted := time.Unix(*seconds, 0).UTC()
+	return &converted
+}
+
+func int64Pointer(value int64) *int64 {
+	return &value
+}
@@ -0,0 +1,154 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	// A Sunday, so day offsets line up with weekdays
+	forecastStart = time.Date(2019, time.March, 3, 0, 0, 0, 0, time.UTC)
+)
+
+// createDailySamples is a helper that builds a sample per day from a function of the day number
+func createDailySamples(days int, totalSpace float64, usedSpace func(day int) float64) []*CapacitySample {
+	samples := []*CapacitySample{}
+	for day := 0; day < days; day++ {
+		used := usedSpace(day)
+		samples = append(samples, &CapacitySample{
+			Time:        forecastStart.AddDate(0, 0, day).Unix(),
+			UsedSpace:   used,
+			TotalSpace:  totalSpace,
+			PercentFull: used / totalSpace,
+		})
+	}
+	return samples
+}
+
+func TestForecastCapacityLinear(t *testing.T) {
+	// 10 units a day from 100, out of 1000: 80% on day 70, 90% on day 80, 100% on day 90
+	samples := createDailySamples(10, 1000, func(day int) float64 { return 100 + 10*float64(day) })
+	now := forecastStart.AddDate(0, 0, 10)
+
+	forecast := ForecastCapacity("array-1", samples, now)
+	assert.Equal(t, "array-1", forecast.ArrayID)
+	assert.Equal(t, 10, forecast.SampleCount)
+	assert.Equal(t, forecastStart.Unix(), forecast.HistoryStart)
+	assert.Equal(t, float64(190), forecast.UsedSpace)
+	assert.Equal(t, float64(1000), forecast.TotalSpace)
+	assert.InDelta(t, 19, forecast.PercentFull, 0.0001)
+	assert.InDelta(t, 10, forecast.DailyGrowth, 0.0001)
+	// Not enough history for a weekly pattern
+	assert.Nil(t, forecast.WeeklySeasonality)
+
+	assert.Len(t, forecast.Projections, 3)
+	for i, day := range []int{70, 80, 90} {
+		assert.Equal(t, ForecastThresholds[i], forecast.Projections[i].Percent)
+		assert.Equal(t, forecastStart.AddDate(0, 0, day).Unix(), *forecast.Projections[i].LinearDate)
+		assert.Nil(t, forecast.Projections[i].SeasonalDate)
+	}
+}
+
+func TestForecastCapacitySeasonal(t *testing.T) {
+	// Usage jumps by 50 every Saturday (backups) and drops back on Sunday, on top of 10 a day
+	samples := createDailySamples(28, 1000, func(day int) float64 {
+		used := 100 + 10*float64(day)
+		if day%7 == 6 {
+			used += 50
+		}
+		return used
+	})
+	now := forecastStart.AddDate(0, 0, 28)
+
+	forecast := ForecastCapacity("array-1", samples, now)
+	assert.Len(t, forecast.WeeklySeasonality, 7)
+	assert.True(t, forecast.WeeklySeasonality[time.Saturday] > 40)
+	assert.True(t, forecast.WeeklySeasonality[time.Monday] < 0)
+
+	// The weekly spike reaches 100% a few days before the trend does, on a Saturday
+	projection := forecast.Projections[2]
+	assert.NotNil(t, projection.LinearDate)
+	assert.NotNil(t, projection.SeasonalDate)
+	assert.True(t, *projection.SeasonalDate < *projection.LinearDate)
+	assert.Equal(t, time.Saturday, time.Unix(*projection.SeasonalDate, 0).UTC().Weekday())
+}
+
+func TestForecastCapacityNotGrowing(t *testing.T) {
+	samples := createDailySamples(30, 1000, func(day int) float64 { return 500 - float64(day) })
+
+	forecast := ForecastCapacity("array-1", samples, forecastStart.AddDate(0, 0, 30))
+	assert.True(t, forecast.DailyGrowth < 0)
+	for _, projection := range forecast.Projections {
+		assert.Nil(t, projection.LinearDate)
+		assert.Nil(t, projection.SeasonalDate)
+	}
+}
+
+func TestForecastCapacityAlreadyFull(t *testing.T) {
+	samples := createDailySamples(5, 1000, func(day int) float64 { return 850 + float64(day) })
+	latest := samples[len(samples)-1].Time
+
+	forecast := ForecastCapacity("array-1", samples, forecastStart.AddDate(0, 0, 5))
+	assert.Equal(t, latest, *forecast.Projections[0].LinearDate)
+	assert.Equal(t, latest, *forecast.Projections[0].SeasonalDate)
+	assert.NotEqual(t, latest, *forecast.Projections[1].LinearDate)
+}
+
+func TestForecastCapacityPastHorizon(t *testing.T) {
+	// One unit a day will take far longer than the horizon to fill
+	samples := createDailySamples(10, 1000000, func(day int) float64 { return float64(day) })
+
+	forecast := ForecastCapacity("array-1", samples, forecastStart.AddDate(0, 0, 10))
+	assert.Nil(t, forecast.Projections[0].LinearDate)
+}
+
+func TestForecastCapacityNoSamples(t *testing.T) {
+	forecast := ForecastCapacity("array-1", []*CapacitySample{}, forecastStart)
+	assert.Equal(t, 0, forecast.SampleCount)
+	assert.Len(t, forecast.Projections, 3)
+	assert.Nil(t, forecast.Projections[0].LinearDate)
+}
+
+func TestAggregateCapacityForecasts(t *testing.T) {
+	end := forecastStart.Unix()
+	forecasts := []*CapacityForecast{
+		{ArrayID: "a", HistoryStart: end - 10*secondsPerDay, HistoryEnd: end, SampleCount: 10, UsedSpace: 300, TotalSpace: 1000, DailyGrowth: 5},
+		{ArrayID: "b", HistoryStart: end - 20*secondsPerDay, HistoryEnd: end, SampleCount: 20, UsedSpace: 100, TotalSpace: 1000, DailyGrowth: 15},
+	}
+
+	aggregate := AggregateCapacityForecasts(forecasts, forecastStart)
+	assert.Equal(t, end-20*secondsPerDay, aggregate.HistoryStart)
+	assert.Equal(t, 30, aggregate.SampleCount)
+	assert.Equal(t, float64(400), aggregate.UsedSpace)
+	assert.Equal(t, float64(2000), aggregate.TotalSpace)
+	assert.Equal(t, float64(20), aggregate.PercentFull)
+	assert.Equal(t, float64(20), aggregate.DailyGrowth)
+	// 1600 (80%) is 1200 away at 20 a day
+	assert.Equal(t, end+60*secondsPerDay, *aggregate.Projections[0].LinearDate)
+	assert.Nil(t, aggregate.Projections[0].SeasonalDate)
+}
+
+func TestAggregateCapacityForecastsPercentFullScale(t *testing.T) {
+	samples := createDailySamples(10, 1000, func(day int) float64 { return 100 + 10*float64(day) })
+	forecast := ForecastCapacity("array-1", samples, forecastStart.AddDate(0, 0, 10))
+
+	// A group of one array is as full as the array itself
+	aggregate := AggregateCapacityForecasts([]*CapacityForecast{forecast}, forecastStart.AddDate(0, 0, 10))
+	assert.InDelta(t, forecast.PercentFull, aggregate.PercentFull, 0.0001)
+}
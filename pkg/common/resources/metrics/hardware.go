@@ -0,0 +1,130 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// healthyHardwareStatuses are the component statuses (as reported by either device type) that don't need attention.
+// Empty and unused slots are healthy: a missing drive or blade is not a failed one.
+var healthyHardwareStatuses = map[string]struct{}{
+	"ok":            {},
+	"healthy":       {},
+	"ready":         {},
+	"not_installed": {},
+	"empty":         {},
+	"unused":        {},
+	"identifying":   {},
+	"updating":      {},
+}
+
+// IsHealthyHardwareStatus returns whether the given component status means the component is working
+func IsHealthyHardwareStatus(status string) bool {
+	_, ok := healthyHardwareStatuses[strings.ToLower(status)]
+	return ok
+}
+
+// BuildArrayHardware groups hardware component metrics by the array they belong to, sorted by array name.
+// Components are sorted by type then name so that the output is stable between collections.
+func BuildArrayHardware(hardwareMetrics []*HardwareMetric) []*ArrayHardware {
+	byArray := map[string]*ArrayHardware{}
+	for _, metric := range hardwareMetrics {
+		array, ok := byArray[metric.ArrayID]
+		if !ok {
+			array = &ArrayHardware{
+				ArrayID:          metric.ArrayID,
+				ArrayName:        metric.ArrayName,
+				ArrayDisplayName: metric.ArrayDisplayName,
+				Healthy:          true,
+				Components:       []*HardwareMetric{},
+			}
+			byArray[metric.ArrayID] = array
+		}
+		array.Components = append(array.Components, metric)
+		if !metric.Healthy {
+			array.Healthy = false
+			array.UnhealthyCount++
+		}
+		if metric.CreatedAt > array.UpdatedAt {
+			array.UpdatedAt = metric.CreatedAt
+		}
+	}
+
+	arrays := []*ArrayHardware{}
+	for _, array := range byArray {
+		sort.Slice(array.Components, func(i, j int) bool {
+			if array.Components[i].ComponentType != array.Components[j].ComponentType {
+				return array.Components[i].ComponentType < array.Components[j].ComponentType
+			}
+			return array.Components[i].ComponentName < array.Components[j].ComponentName
+		})
+		arrays = append(arrays, array)
+	}
+
+	sort.Slice(arrays, func(i, j int) bool {
+		if arrays[i].ArrayName != arrays[j].ArrayName {
+			return arrays[i].ArrayName < arrays[j].ArrayName
+		}
+		return arrays[i].ArrayID < arrays[j].ArrayID
+	})
+	return arrays
+}
+
+// ConvertToArrayHardwareMap converts this array's hardware into a string->interface map suitable for marshalling
+// in the REST API format
+func (a *ArrayHardware) ConvertToArrayHardwareMap() map[string]interface{} {
+	components := []map[string]interface{}{}
+	for _, component := range a.Components {
+		components = append(components, component.ConvertToHardwareComponentMap())
+	}
+
+	return map[string]interface{}{
+		"array_id":           a.ArrayID,
+		"array_name":         a.ArrayName,
+		"array_display_name": a.ArrayDisplayName,
+		"healthy":            a.Healthy,
+		"unhealthy_count":    a.UnhealthyCount,
+		"components":         components,
+		"updated_at":         time.Unix(a.UpdatedAt, 0).UTC(),
+	}
+}
+
+// ConvertToHardwareComponentMap converts this component into a string->interface map suitable for marshalling
+// in the REST API format
+func (h *HardwareMetric) ConvertToHardwareComponentMap() map[string]interface{} {
+	return map[string]interface{}{
+		"name":            h.ComponentName,
+		"type":            h.ComponentType,
+		"status":          h.Status,
+		"healthy":         h.Healthy,
+		"details":         h.Details,
+		"model":           h.Model,
+		"serial":          h.Serial,
+		"slot":            h.Slot,
+		"mode":            h.Mode,
+		"version":         h.Version,
+		"speed":           h.Speed,
+		"temperature":     h.Temperature,
+		"capacity":        h.Capacity,
+		"drive_type":      h.DriveType,
+		"protocol":        h.Protocol,
+		"last_failure":    h.LastFailure,
+		"last_evacuation": h.LastEvacuation,
+		"progress":        h.Progress,
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsHealthyHardwareStatus(t *testing.T) {
+	assert.True(t, IsHealthyHardwareStatus("ok"))
+	assert.True(t, IsHealthyHardwareStatus("healthy"))
+	assert.True(t, IsHealthyHardwareStatus("not_installed"))
+	assert.True(t, IsHealthyHardwareStatus("OK"))
+	assert.False(t, IsHealthyHardwareStatus("critical"))
+	assert.False(t, IsHealthyHardwareStatus("failed"))
+	assert.False(t, IsHealthyHardwareStatus("unhealthy"))
+	assert.False(t, IsHealthyHardwareStatus(""))
+}
+
+func TestBuildArrayHardware(t *testing.T) {
+	hardwareMetrics := []*HardwareMetric{
+		{ArrayID: "b", ArrayName: "array-b", CreatedAt: 100, ComponentName: "CH0.BAY1", ComponentType: HardwareComponentDrive, Status: "healthy", Healthy: true},
+		{ArrayID: "a", ArrayName: "array-a", CreatedAt: 100, ComponentName: "CT1", ComponentType: HardwareComponentController, Status: "ready", Healthy: true},
+		{ArrayID: "a", ArrayName: "array-a", CreatedAt: 120, ComponentName: "CH0.PWR1", ComponentType: HardwareComponentPowerSupply, Status: "critical", Healthy: false},
+		{ArrayID: "a", ArrayName: "array-a", CreatedAt: 120, ComponentName: "CT0", ComponentType: HardwareComponentController, Status: "ready", Healthy: true},
+	}
+
+	arrays := BuildArrayHardware(hardwareMetrics)
+	assert.Len(t, arrays, 2)
+
+	assert.Equal(t, "a", arrays[0].ArrayID)
+	assert.False(t, arrays[0].Healthy)
+	assert.Equal(t, 1, arrays[0].UnhealthyCount)
+	assert.Equal(t, int64(120), arrays[0].UpdatedAt)
+	assert.Len(t, arrays[0].Components, 3)
+	assert.Equal(t, "CT0", arrays[0].Components[0].ComponentName)
+	assert.Equal(t, "CT1", arrays[0].Components[1].ComponentName)
+	assert.Equal(t, "CH0.PWR1", arrays[0].Components[2].ComponentName)
+
+	assert.Equal(t, "b", arrays[1].ArrayID)
+	assert.True(t, arrays[1].Healthy)
+	assert.Equal(t, 0, arrays[1].UnhealthyCount)
+}
+
+func TestBuildArrayHardwareEmpty(t *testing.T) {
+	assert.Empty(t, BuildArrayHardware([]*HardwareMetric{}))
+}
+
+func TestConvertToArrayHardwareMap(t *testing.T) {
+	arrays := BuildArrayHardware([]*HardwareMetric{
+		{ArrayID: "a", ArrayName: "array-a", CreatedAt: 100, ComponentName: "FB1", ComponentType: HardwareComponentBlade, Status: "unhealthy", Healthy: false, Capacity: 1024},
+	})
+	converted := arrays[0].ConvertToArrayHardwareMap()
+	assert.Equal(t, "a", converted["array_id"])
+	assert.Equal(t, false, converted["healthy"])
+	assert.Equal(t, 1, converted["unhealthy_count"])
+
+	components := converted["components"].([]map[string]interface{})
+	assert.Len(t, components, 1)
+	assert.Equal(t, "FB1", components[0]["name"])
+	assert.Equal(t, HardwareComponentBlade, components[0]["type"])
+	assert.Equal(t, uint64(1024), components[0]["capacity"])
+}
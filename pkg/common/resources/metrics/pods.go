@@ -0,0 +1,188 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// BuildPodTopologies correlates the pod metrics reported by each array into one topology per pod. Metrics
+// are for the same pod if they have the same pod name and share a member array, so unrelated pods that
+// happen to have the same name on different arrays are kept apart.
+func BuildPodTopologies(podMetrics []*PodMetric) []*PodTopology {
+	byName := map[string][]*PodMetric{}
+	for _, metric := range podMetrics {
+		byName[metric.PodName] = append(byName[metric.PodName], metric)
+	}
+
+	topologies := []*PodTopology{}
+	for _, named := range byName {
+		for _, group := range groupPodMetrics(named) {
+			topologies = append(topologies, buildPodTopology(group))
+		}
+	}
+
+	sort.Slice(topologies, func(i, j int) bool {
+		if topologies[i].PodName != topologies[j].PodName {
+			return topologies[i].PodName < topologies[j].PodName
+		}
+		return topologies[i].Members[0].ArrayID < topologies[j].Members[0].ArrayID
+	})
+	return topologies
+}
+
+// ConvertToPodTopologyMap converts this topology into a string->interface map suitable for marshalling
+// in the REST API format
+func (t *PodTopology) ConvertToPodTopologyMap() map[string]interface{} {
+	members := []map[string]interface{}{}
+	for _, member := range t.Members {
+		members = append(members, map[string]interface{}{
+			"array_id":           member.ArrayID,
+			"array_name":         member.ArrayName,
+			"reporting":          member.Reporting,
+			"registered_id":      member.RegisteredID,
+			"array_display_name": member.ArrayDisplayName,
+			"status":             member.Status,
+			"mediator_status":    member.MediatorStatus,
+			"resync_progress":    member.ResyncProgress,
+		})
+	}
+
+	return map[string]interface{}{
+		"name":             t.PodName,
+		"mediator":         t.Mediator,
+		"mediator_version": t.MediatorVersion,
+		"members":          members,
+		"stretched":        t.Stretched,
+		"mediator_outage":  t.MediatorOutage,
+		"split_brain_risk": t.SplitBrainRisk,
+		"updated_at":       time.Unix(t.UpdatedAt, 0).UTC(),
+	}
+}
+
+// groupPodMetrics is a helper function that splits the metrics of pods with the same name into groups
+// that share member arrays
+func groupPodMetrics(podMetrics []*PodMetric) [][]*PodMetric {
+	groups := [][]*PodMetric{}
+	groupMembers := []map[string]struct{}{}
+	for _, metric := range podMetrics {
+		memberIDs := getPodMemberIDs(metric)
+
+		// Merge every group this metric shares a member with, since it links them together
+		merged := []*PodMetric{metric}
+		mergedMembers := memberIDs
+		remainingGroups := [][]*PodMetric{}
+		remainingMembers := []map[string]struct{}{}
+		for i, members := range groupMembers {
+			if sharesPodMember(members, memberIDs) {
+				merged = append(merged, groups[i]...)
+				for id := range members {
+					mergedMembers[id] = struct{}{}
+				}
+			} else {
+				remainingGroups = append(remainingGroups, groups[i])
+				remainingMembers = append(remainingMembers, members)
+			}
+		}
+		groups = append(remainingGroups, merged)
+		groupMembers = append(remainingMembers, mergedMembers)
+	}
+	return groups
+}
+
+// buildPodTopology is a helper function that combines the metrics reported for one pod. Each member's
+// state is taken from its own metrics if there are any, and from the newest metrics that mention it otherwise.
+func buildPodTopology(podMetrics []*PodMetric) *PodTopology {
+	// Newest first, so the first view of each member is the most recent one
+	sort.Slice(podMetrics, func(i, j int) bool {
+		return podMetrics[i].CreatedAt > podMetrics[j].CreatedAt
+	})
+
+	newest := podMetrics[0]
+	topology := &PodTopology{
+		PodName:         newest.PodName,
+		Mediator:        newest.Mediator,
+		MediatorVersion: newest.MediatorVersion,
+		UpdatedAt:       newest.CreatedAt,
+	}
+
+	members := map[string]*PodTopologyMember{}
+	for _, metric := range podMetrics {
+		if _, ok := members[metric.PurityID]; !ok {
+			members[metric.PurityID] = &PodTopologyMember{
+				ArrayID:          metric.PurityID,
+				ArrayName:        metric.ArrayName,
+				Reporting:        true,
+				RegisteredID:     metric.ArrayID,
+				ArrayDisplayName: metric.ArrayDisplayName,
+				Status:           metric.Status,
+				MediatorStatus:   metric.MediatorStatus,
+				ResyncProgress:   metric.ResyncProgress,
+			}
+		}
+	}
+	for _, metric := range podMetrics {
+		for _, member := range metric.Members {
+			if _, ok := members[member.ArrayID]; ok {
+				continue
+			}
+			members[member.ArrayID] = &PodTopologyMember{
+				ArrayID:        member.ArrayID,
+				ArrayName:      member.ArrayName,
+				Status:         member.Status,
+				MediatorStatus: member.MediatorStatus,
+				ResyncProgress: member.ResyncProgress,
+			}
+		}
+	}
+
+	onlineCount := 0
+	for _, member := range members {
+		topology.Members = append(topology.Members, member)
+		if member.Status == PodStatusOnline {
+			onlineCount++
+		}
+		if member.MediatorStatus != "" && member.MediatorStatus != MediatorStatusOnline {
+			topology.MediatorOutage = true
+		}
+	}
+	sort.Slice(topology.Members, func(i, j int) bool {
+		return topology.Members[i].ArrayID < topology.Members[j].ArrayID
+	})
+	topology.Stretched = len(topology.Members) > 1
+	topology.SplitBrainRisk = topology.MediatorOutage && onlineCount > 1
+	return topology
+}
+
+// getPodMemberIDs is a helper function that returns the IDs of the arrays the given pod metric says are
+// members, including the reporting array
+func getPodMemberIDs(metric *PodMetric) map[string]struct{} {
+	memberIDs := map[string]struct{}{metric.PurityID: {}}
+	for _, member := range metric.Members {
+		memberIDs[member.ArrayID] = struct{}{}
+	}
+	return memberIDs
+}
+
+// sharesPodMember is a helper function that checks whether the given sets of array IDs intersect
+func sharesPodMember(first map[string]struct{}, second map[string]struct{}) bool {
+	for id := range second {
+		if _, ok := first[id]; ok {
+			return true
+		}
+	}
+	return false
+}
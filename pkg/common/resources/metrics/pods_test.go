@@ -0,0 +1,131 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createPodMetric is a helper that builds the metric an array reports for a pod, with every member in the given status
+func createPodMetric(podName string, arrayID string, createdAt int64, status string, mediatorStatus string, memberIDs ...string) *PodMetric {
+	metric := &PodMetric{
+		ArrayID:        "registered-" + arrayID,
+		ArrayName:      "name-" + arrayID,
+		PurityID:       arrayID,
+		CreatedAt:      createdAt,
+		PodName:        podName,
+		Mediator:       "purestorage",
+		Status:         status,
+		MediatorStatus: mediatorStatus,
+	}
+	for _, memberID := range memberIDs {
+		metric.Members = append(metric.Members, &PodMember{
+			ArrayID:        memberID,
+			ArrayName:      "name-" + memberID,
+			Status:         status,
+			MediatorStatus: mediatorStatus,
+		})
+	}
+	return metric
+}
+
+func TestBuildPodTopologiesStretched(t *testing.T) {
+	podMetrics := []*PodMetric{
+		createPodMetric("pod1", "array-1", 100, PodStatusOnline, MediatorStatusOnline, "array-1", "array-2"),
+		createPodMetric("pod1", "array-2", 110, PodStatusOnline, MediatorStatusOnline, "array-1", "array-2"),
+	}
+
+	topologies := BuildPodTopologies(podMetrics)
+	assert.Len(t, topologies, 1)
+	topology := topologies[0]
+	assert.Equal(t, "pod1", topology.PodName)
+	assert.Equal(t, "purestorage", topology.Mediator)
+	assert.Equal(t, int64(110), topology.UpdatedAt)
+	assert.True(t, topology.Stretched)
+	assert.False(t, topology.MediatorOutage)
+	assert.False(t, topology.SplitBrainRisk)
+	assert.Len(t, topology.Members, 2)
+	assert.Equal(t, "array-1", topology.Members[0].ArrayID)
+	assert.True(t, topology.Members[0].Reporting)
+	assert.Equal(t, "array-2", topology.Members[1].ArrayID)
+	assert.True(t, topology.Members[1].Reporting)
+}
+
+func TestBuildPodTopologiesSameNameDifferentPods(t *testing.T) {
+	// Two unrelated local pods that happen to share a name
+	podMetrics := []*PodMetric{
+		createPodMetric("pod1", "array-2", 100, PodStatusOnline, "", "array-2"),
+		createPodMetric("pod1", "array-1", 100, PodStatusOnline, "", "array-1"),
+	}
+
+	topologies := BuildPodTopologies(podMetrics)
+	assert.Len(t, topologies, 2)
+	assert.Equal(t, "array-1", topologies[0].Members[0].ArrayID)
+	assert.False(t, topologies[0].Stretched)
+	assert.Equal(t, "array-2", topologies[1].Members[0].ArrayID)
+	assert.False(t, topologies[1].Stretched)
+}
+
+func TestBuildPodTopologiesMemberNotReporting(t *testing.T) {
+	// Only one member is registered (or reachable), so the other's state comes from its view
+	podMetric := createPodMetric("pod1", "array-1", 100, PodStatusOnline, MediatorStatusOnline, "array-1", "array-2")
+	podMetric.Members[1].Status = PodStatusResyncing
+	podMetric.Members[1].ResyncProgress = 0.5
+
+	topologies := BuildPodTopologies([]*PodMetric{podMetric})
+	assert.Len(t, topologies, 1)
+	assert.Len(t, topologies[0].Members, 2)
+	assert.True(t, topologies[0].Members[0].Reporting)
+	assert.Equal(t, "registered-array-1", topologies[0].Members[0].RegisteredID)
+	assert.False(t, topologies[0].Members[1].Reporting)
+	assert.Equal(t, "", topologies[0].Members[1].RegisteredID)
+	assert.Equal(t, PodStatusResyncing, topologies[0].Members[1].Status)
+	assert.Equal(t, 0.5, topologies[0].Members[1].ResyncProgress)
+}
+
+func TestBuildPodTopologiesMediatorOutage(t *testing.T) {
+	podMetrics := []*PodMetric{
+		createPodMetric("pod1", "array-1", 100, PodStatusOnline, MediatorStatusUnreachable, "array-1", "array-2"),
+		createPodMetric("pod1", "array-2", 100, PodStatusOnline, MediatorStatusOnline, "array-1", "array-2"),
+	}
+
+	topology := BuildPodTopologies(podMetrics)[0]
+	assert.True(t, topology.MediatorOutage)
+	assert.True(t, topology.SplitBrainRisk)
+
+	// Once a member has gone offline there's nothing left to split
+	podMetrics[1].Status = PodStatusOffline
+	topology = BuildPodTopologies(podMetrics)[0]
+	assert.True(t, topology.MediatorOutage)
+	assert.False(t, topology.SplitBrainRisk)
+}
+
+func TestConvertToPodTopologyMap(t *testing.T) {
+	podMetrics := []*PodMetric{
+		createPodMetric("pod1", "array-1", 100, PodStatusOnline, MediatorStatusOnline, "array-1", "array-2"),
+	}
+
+	topologyMap := BuildPodTopologies(podMetrics)[0].ConvertToPodTopologyMap()
+	assert.Equal(t, "pod1", topologyMap["name"])
+	assert.Equal(t, true, topologyMap["stretched"])
+	members := topologyMap["members"].([]map[string]interface{})
+	assert.Len(t, members, 2)
+	assert.Equal(t, "array-1", members[0]["array_id"])
+	assert.Equal(t, "name-array-1", members[0]["array_name"])
+	assert.Equal(t, true, members[0]["reporting"])
+	assert.Equal(t, false, members[1]["reporting"])
+}
@@ -14,6 +14,10 @@
 
 package metrics
 
+import (
+	"time"
+)
+
 // Database represents a generic connection to a backend that stores metrics data
 type Database interface {
 	// Bulk add array metrics (usually not necessary, usually just one at a time, but just in case)
@@ -36,6 +40,99 @@ type Database interface {
 	CleanTimerLogs(maxAgeInDays int) error
 }
 
+// RollupDatabase represents a backend that can downsample raw metrics into longer-lived rollups
+type RollupDatabase interface {
+	// Roll up every full day of raw array and volume metrics that hasn't been rolled up yet into hourly and daily rollups
+	RollupMetrics() error
+	// Delete hourly and daily rollups older than their respective ages in days
+	CleanMetricRollups(hourlyMaxAgeInDays int, dailyMaxAgeInDays int) error
+}
+
+// ForecastDatabase represents a backend that can provide array capacity history and store capacity forecasts
+type ForecastDatabase interface {
+	// Get daily capacity samples (oldest first) for every array since the given time, keyed by array ID
+	GetArrayCapacityHistory(since time.Time) (map[string][]*CapacitySample, error)
+	// Store the given forecasts, replacing any previous forecast for the same array
+	UpdateCapacityForecasts(forecasts []*CapacityForecast) error
+}
+
+// BackfillDatabase represents a backend that can find gaps in the collected array metrics and fill them in
+type BackfillDatabase interface {
+	// Get the times (oldest first, Unix seconds) array metrics were collected for the given array since the given time,
+	// starting with the latest collection before it if there is one
+	GetArrayMetricTimes(arrayID string, since time.Time) ([]int64, error)
+	// Bulk add array metrics taken from the array's history, each to the index for the time it was taken at,
+	// skipping any that were already added
+	AddBackfilledArrayMetrics(metrics []*ArrayMetric) error
+}
+
+// HostDatabase represents a backend that stores host and host group metrics
+type HostDatabase interface {
+	// Bulk add host and host group metrics
+	AddHostMetrics(metrics []*HostMetric) error
+	// Clean old host metrics by age: metrics older than the given age in days will be deleted, metrics from before today will be marked read-only
+	CleanHostMetrics(maxAgeInDays int) error
+}
+
+// ProtectionGroupDatabase represents a backend that stores protection group metrics
+type ProtectionGroupDatabase interface {
+	// Bulk add protection group metrics
+	AddProtectionGroupMetrics(metrics []*ProtectionGroupMetric) error
+	// Clean old protection group metrics by age: metrics older than the given age in days will be deleted, metrics from before today will be marked read-only
+	CleanProtectionGroupMetrics(maxAgeInDays int) error
+}
+
+// HardwareDatabase represents a backend that stores hardware component health metrics
+type HardwareDatabase interface {
+	// Bulk add hardware component metrics
+	AddHardwareMetrics(metrics []*HardwareMetric) error
+	// Clean old hardware metrics by age: metrics older than the given age in days will be deleted, metrics from before today will be marked read-only
+	CleanHardwareMetrics(maxAgeInDays int) error
+}
+
+// PodDatabase represents a backend that stores ActiveCluster pod metrics
+type PodDatabase interface {
+	// Bulk add pod metrics
+	AddPodMetrics(metrics []*PodMetric) error
+	// Clean old pod metrics by age: metrics older than the given age in days will be deleted, metrics from before today will be marked read-only
+	CleanPodMetrics(maxAgeInDays int) error
+}
+
+// AuditDatabase represents a backend that stores the audit trails and session logs of arrays
+type AuditDatabase interface {
+	// Bulk add audit events, skipping any that were already added
+	AddAuditEvents(events []*AuditEvent) error
+	// Get the time (Unix seconds) of the latest audit event added for the given array, or 0 if there are none
+	GetLatestAuditEventTime(arrayID string) (int64, error)
+	// Clean old audit events by age: events older than the given age in days will be deleted
+	CleanAuditEvents(maxAgeInDays int) error
+}
+
+// SnapshotDatabase represents a backend that stores the snapshot inventory of every array
+type SnapshotDatabase interface {
+	// Replace the snapshot inventory of the given array with the given snapshots, collected at the given time (Unix seconds)
+	UpdateArraySnapshots(arrayID string, collectedAt int64, snapshots []*Snapshot) error
+}
+
+// ReplicationAlertDatabase represents a backend that can look up the alerts raised for replication lag
+type ReplicationAlertDatabase interface {
+	// Get every replication lag alert that hasn't been closed yet
+	FindOpenReplicationAlerts() ([]*Alert, error)
+}
+
+// RuleAlertDatabase represents a backend that can look up the alerts raised by alert rules
+type RuleAlertDatabase interface {
+	// Get every alert raised by an alert rule that hasn't been closed yet
+	FindOpenRuleAlerts() ([]*Alert, error)
+}
+
+// Sources of alerts
+const (
+	AlertSourceArray       = "array"       // Raised by the array itself
+	AlertSourceRule        = "rule"        // Raised by a user-defined alert rule
+	AlertSourceReplication = "replication" // Raised when a protection group's replication falls behind its schedule
+)
+
 // Alert is unified between FlashArray and FlashBlade and stores all relevant information
 type Alert struct {
 	AlertID          uint64 `json:"AlertID"`
@@ -49,6 +146,7 @@ type Alert struct {
 	SeverityIndex    byte   `json:"SeverityIndex"`
 	State            string `json:"State"`
 	Summary          string `json:"Summary"`
+	Source           string `json:"Source"`
 	// Optional params depending on array type
 	Action      string                 `json:"Action"`
 	Component   string                 `json:"Component"`
@@ -57,6 +155,30 @@ type Alert struct {
 	Notified    int64                  `json:"Notified"`
 	Updated     int64                  `json:"Updated"`
 	Variables   map[string]interface{} `json:"Variables"`
+	// Set if the alert is being collected during a maintenance window for the array, or was raised and resolved during one
+	InMaintenance bool `json:"InMaintenance"`
+	// Managed through the API server rather than reported by the array: never set by collectors, so
+	// that pushing an alert again doesn't overwrite it
+	Lifecycle *AlertLifecycle `json:"Lifecycle,omitempty"`
+}
+
+// AlertLifecycle records how an alert is being handled. Times are in Unix seconds, like the rest of the alert.
+type AlertLifecycle struct {
+	Acknowledged   bool         `json:"Acknowledged"`
+	AcknowledgedBy string       `json:"AcknowledgedBy"`
+	AcknowledgedAt int64        `json:"AcknowledgedAt"`
+	AssignedTo     string       `json:"AssignedTo"`
+	SnoozedUntil   int64        `json:"SnoozedUntil"` // 0 if the alert isn't snoozed
+	Notes          []*AlertNote `json:"Notes"`
+	UpdatedBy      string       `json:"UpdatedBy"`
+	Updated        int64        `json:"Updated"`
+}
+
+// AlertNote is a comment left on an alert
+type AlertNote struct {
+	Author  string `json:"Author"`
+	Created int64  `json:"Created"`
+	Text    string `json:"Text"`
 }
 
 // AllArrayData represents all metrics for the array and alerts in one response
@@ -70,6 +192,26 @@ type AllVolumeData struct {
 	VolumeMetricsTimeSeries []*VolumeMetric
 }
 
+// AllHostData represents all metrics for the hosts and host groups of an array in one response
+type AllHostData struct {
+	HostMetrics []*HostMetric
+}
+
+// AllHardwareData represents the health of all hardware components of an array in one response
+type AllHardwareData struct {
+	HardwareMetrics []*HardwareMetric
+}
+
+// AllPodData represents all metrics for the pods of an array in one response
+type AllPodData struct {
+	PodMetrics []*PodMetric
+}
+
+// AllProtectionGroupData represents all metrics for the protection groups of an array in one response
+type AllProtectionGroupData struct {
+	ProtectionGroupMetrics []*ProtectionGroupMetric
+}
+
 // ArrayMetric represents a full array metric (capacity, performance, counts, and metadata)
 type ArrayMetric struct {
 	*ArrayCapacityMetric
@@ -81,6 +223,10 @@ type ArrayMetric struct {
 	CreatedAt   int64             `json:"CreatedAt"` // Unix seconds since epoch
 	DisplayName string            `json:"DisplayName"`
 	Tags        map[string]string `json:"Tags"`
+	// Set if the metric was collected during a maintenance window for the array
+	InMaintenance bool `json:"InMaintenance"`
+	// Set if the metric was filled in later from the performance history the array keeps, rather than collected
+	Backfilled bool `json:"Backfilled"`
 }
 
 // ArrayCapacityMetric represents all relevant capacity metrics for an array
@@ -125,6 +271,7 @@ type ArrayObjectsMetric struct {
 // VolumeCapacityMetric represents all relevant capacity information for a device volume
 type VolumeCapacityMetric struct {
 	DataReduction    float64 `json:"DataReduction"`
+	ObjectCount      uint64  `json:"ObjectCount"` // Buckets and object store accounts only
 	ProvisionedSpace uint64  `json:"ProvisionedSpace"`
 	SnapshotCount    uint32  `json:"SnapshotCount"`
 	TotalReduction   float64 `json:"TotalReduction"`
@@ -143,6 +290,17 @@ type VolumePerformanceMetric struct {
 	WriteLatency   uint64 `json:"WriteLatency"`
 }
 
+// Types of volume metrics
+const (
+	VolumeMetricTypeVolume             = "Volume"
+	VolumeMetricTypeFileSystem         = "FileSystem"
+	VolumeMetricTypeBucket             = "Bucket"
+	VolumeMetricTypeObjectStoreAccount = "ObjectStoreAccount"
+)
+
+// FileSystemProtocols are the protocols file system performance is broken down by
+var FileSystemProtocols = []string{"nfs", "smb", "http", "s3"}
+
 // VolumeMetric represents a full volume metric (capacity, performance, and metadata)
 type VolumeMetric struct {
 	*VolumeCapacityMetric
@@ -154,4 +312,305 @@ type VolumeMetric struct {
 	CreatedAt        int64             `json:"CreatedAt"` // Unix seconds since epoch
 	Type             string            `json:"Type"`
 	VolumeName       string            `json:"VolumeName"`
+	Account          string            `json:"Account,omitempty"`    // Buckets only: the object store account the bucket belongs to
+	Versioning       string            `json:"Versioning,omitempty"` // Buckets only: none, enabled or suspended
+	UserCount        uint32            `json:"UserCount,omitempty"`  // Object store accounts only
+	// FlashBlade file systems only: the performance over each protocol (nfs, smb, http and s3), which adds up
+	// to the performance of the file system
+	ProtocolPerformance map[string]*VolumePerformanceMetric `json:"ProtocolPerformance,omitempty"`
+	// Set if the metric was collected during a maintenance window for the array
+	InMaintenance bool `json:"InMaintenance"`
+}
+
+// Types of host metrics
+const (
+	HostMetricTypeHost      = "Host"
+	HostMetricTypeHostGroup = "HostGroup"
+)
+
+// HostPerformanceMetric represents all performance metrics for a host or host group
+type HostPerformanceMetric struct {
+	ReadBandwidth  uint64 `json:"ReadBandwidth"`
+	ReadIOPS       uint64 `json:"ReadIOPS"`
+	ReadLatency    uint64 `json:"ReadLatency"`
+	WriteBandwidth uint64 `json:"WriteBandwidth"`
+	WriteIOPS      uint64 `json:"WriteIOPS"`
+	WriteLatency   uint64 `json:"WriteLatency"`
+}
+
+// HostMetric represents a full host or host group metric (inventory, performance, and metadata)
+type HostMetric struct {
+	*HostPerformanceMetric
+	ArrayID          string            `json:"ArrayID"`
+	ArrayName        string            `json:"ArrayName"`
+	ArrayDisplayName string            `json:"ArrayDisplayName"`
+	ArrayTags        map[string]string `json:"ArrayTags"`
+	CreatedAt        int64             `json:"CreatedAt"` // Unix seconds since epoch
+	Type             string            `json:"Type"`      // Host or HostGroup
+	HostName         string            `json:"HostName"`  // Name of the host or host group
+	HostGroup        string            `json:"HostGroup"` // Host group the host belongs to, empty for host groups
+	Hosts            []string          `json:"Hosts"`     // Member hosts, only set for host groups
+	Personality      string            `json:"Personality"`
+	WWNs             []string          `json:"WWNs"`
+	IQNs             []string          `json:"IQNs"`
+	Volumes          []string          `json:"Volumes"` // Volumes connected to the host, privately or through its host group
+	VolumeCount      uint32            `json:"VolumeCount"`
+}
+
+// ProtectionGroupTarget is an array that a protection group replicates to
+type ProtectionGroupTarget struct {
+	Name      string `json:"Name"`
+	Allowed   bool   `json:"Allowed"`   // Whether the target array allows the replication
+	Connected bool   `json:"Connected"` // Whether the target array is currently connected
+}
+
+// ProtectionGroupMetric represents a protection group along with its schedule and the state of its snapshots and replication
+type ProtectionGroupMetric struct {
+	ArrayID              string                   `json:"ArrayID"`
+	ArrayName            string                   `json:"ArrayName"`
+	ArrayDisplayName     string                   `json:"ArrayDisplayName"`
+	ArrayTags            map[string]string        `json:"ArrayTags"`
+	CreatedAt            int64                    `json:"CreatedAt"` // Unix seconds since epoch
+	ProtectionGroupName  string                   `json:"ProtectionGroupName"`
+	Source               string                   `json:"Source"` // Array the protection group belongs to, which is another array for groups replicated to this one
+	Hosts                []string                 `json:"Hosts"`
+	HostGroups           []string                 `json:"HostGroups"`
+	Volumes              []string                 `json:"Volumes"`
+	Targets              []*ProtectionGroupTarget `json:"Targets"`
+	SnapshotEnabled      bool                     `json:"SnapshotEnabled"`
+	SnapshotFrequency    int64                    `json:"SnapshotFrequency"` // Seconds
+	ReplicationEnabled   bool                     `json:"ReplicationEnabled"`
+	ReplicationFrequency int64                    `json:"ReplicationFrequency"` // Seconds
+	SnapshotCount        uint32                   `json:"SnapshotCount"`
+	LastSnapshot         int64                    `json:"LastSnapshot"`    // Unix seconds, 0 if there are no snapshots
+	LastReplication      int64                    `json:"LastReplication"` // When the newest replicated snapshot finished transferring (Unix seconds), 0 if none has
+	// Seconds between the creation of the newest replicated snapshot and the time of collection, i.e. how far
+	// behind the targets' copy of the data is. 0 if no snapshot has been replicated yet.
+	ReplicationLag            int64   `json:"ReplicationLag"`
+	LastTransferDuration      int64   `json:"LastTransferDuration"` // Seconds taken to transfer the newest replicated snapshot
+	LastTransferDataBytes     uint64  `json:"LastTransferDataBytes"`
+	LastTransferPhysicalBytes uint64  `json:"LastTransferPhysicalBytes"`
+	TransferProgress          float64 `json:"TransferProgress"` // Progress of the snapshot currently being replicated (0 to 1), 0 if none is
+}
+
+// Types of hardware components
+const (
+	HardwareComponentController        = "controller"
+	HardwareComponentDrive             = "drive"
+	HardwareComponentPowerSupply       = "power_supply"
+	HardwareComponentFan               = "fan"
+	HardwareComponentTemperatureSensor = "temperature_sensor"
+	HardwareComponentBlade             = "blade"
+	HardwareComponentOther             = "other" // Chassis, ports, fabric modules and so on
+)
+
+// HardwareMetric represents the health of a single hardware component of an array. Fields that don't apply to
+// the type of component are left empty.
+type HardwareMetric struct {
+	ArrayID          string            `json:"ArrayID"`
+	ArrayName        string            `json:"ArrayName"`
+	ArrayDisplayName string            `json:"ArrayDisplayName"`
+	ArrayTags        map[string]string `json:"ArrayTags"`
+	CreatedAt        int64             `json:"CreatedAt"` // Unix seconds since epoch
+	ComponentName    string            `json:"ComponentName"`
+	ComponentType    string            `json:"ComponentType"`
+	Status           string            `json:"Status"`  // As reported by the array
+	Healthy          bool              `json:"Healthy"` // Whether the status means the component is working (or just not installed)
+	Details          string            `json:"Details"`
+	Model            string            `json:"Model"`
+	Serial           string            `json:"Serial"`
+	Slot             int               `json:"Slot"`
+	Mode             string            `json:"Mode"`        // Controllers only (primary, secondary, ...)
+	Version          string            `json:"Version"`     // Controllers only
+	Speed            uint64            `json:"Speed"`       // Fans only, in RPM
+	Temperature      int               `json:"Temperature"` // Degrees Celsius
+	Capacity         uint64            `json:"Capacity"`    // Drives and blades only, in bytes
+	DriveType        string            `json:"DriveType"`
+	Protocol         string            `json:"Protocol"`
+	LastFailure      int64             `json:"LastFailure"`    // Drives only, Unix seconds, 0 if it's never failed
+	LastEvacuation   int64             `json:"LastEvacuation"` // Drives only, Unix seconds when the last evacuation completed, 0 if none has
+	Progress         float64           `json:"Progress"`       // Blades only, progress of an evacuation or addition (0 to 1)
+}
+
+// ArrayHardware represents the latest health of all hardware components of a single array
+type ArrayHardware struct {
+	ArrayID          string
+	ArrayName        string
+	ArrayDisplayName string
+	Healthy          bool
+	UnhealthyCount   int
+	Components       []*HardwareMetric
+	UpdatedAt        int64 // Unix seconds since epoch
+}
+
+// States of a pod on an array, and of an array's connection to the pod's mediator
+const (
+	PodStatusOnline           = "online"
+	PodStatusOffline          = "offline"
+	PodStatusResyncing        = "resyncing"
+	PodStatusUnknown          = "unknown"
+	MediatorStatusOnline      = "online"
+	MediatorStatusUnreachable = "unreachable"
+	MediatorStatusFlummoxed   = "flummoxed" // Reachable, but the mediator doesn't recognize the array
+	MediatorStatusUnknown     = "unknown"
+)
+
+// PodMember is an array a pod is stretched across, as seen by the array reporting the pod
+type PodMember struct {
+	ArrayID        string  `json:"ArrayID"`
+	ArrayName      string  `json:"ArrayName"`
+	Status         string  `json:"Status"`
+	MediatorStatus string  `json:"MediatorStatus"`
+	ResyncProgress float64 `json:"ResyncProgress"` // Progress of the resync to the array (0 to 1), only set while resyncing
+}
+
+// PodMetric represents an ActiveCluster pod as seen by one of its member arrays
+type PodMetric struct {
+	ArrayID           string            `json:"ArrayID"`
+	ArrayName         string            `json:"ArrayName"`
+	ArrayDisplayName  string            `json:"ArrayDisplayName"`
+	ArrayTags         map[string]string `json:"ArrayTags"`
+	CreatedAt         int64             `json:"CreatedAt"` // Unix seconds since epoch
+	PurityID          string            `json:"PurityID"`  // ID the array reports itself, which pod members are identified by
+	PodName           string            `json:"PodName"`
+	Source            string            `json:"Source"` // Pod the pod was cloned from, if any
+	Mediator          string            `json:"Mediator"`
+	MediatorVersion   string            `json:"MediatorVersion"`
+	Status            string            `json:"Status"`         // Status of the pod on the reporting array
+	MediatorStatus    string            `json:"MediatorStatus"` // Whether the reporting array can reach the mediator
+	ResyncProgress    float64           `json:"ResyncProgress"` // Progress of the resync to the reporting array (0 to 1), only set while resyncing
+	Members           []*PodMember      `json:"Members"`
+	MemberCount       uint32            `json:"MemberCount"`
+	OnlineMemberCount uint32            `json:"OnlineMemberCount"`
+	Stretched         bool              `json:"Stretched"` // Whether the pod has more than one member
+}
+
+// PodTopologyMember is a member array of a pod, with the pod's state on it
+type PodTopologyMember struct {
+	ArrayID          string // ID the array reports itself
+	ArrayName        string
+	Reporting        bool   // Whether the array's own metrics were found, otherwise its state is as seen by the other members
+	RegisteredID     string // ID the array is registered with, only set if it's reporting
+	ArrayDisplayName string // Only set if it's reporting
+	Status           string
+	MediatorStatus   string
+	ResyncProgress   float64
+}
+
+// PodTopology is an ActiveCluster pod correlated across the arrays it's stretched over
+type PodTopology struct {
+	PodName         string
+	Mediator        string
+	MediatorVersion string
+	Members         []*PodTopologyMember
+	Stretched       bool
+	MediatorOutage  bool  // Some member can't reach the mediator
+	SplitBrainRisk  bool  // The pod is online on more than one member, but the mediator can't arbitrate between them
+	UpdatedAt       int64 // Unix seconds, when the newest of the correlated metrics was collected
+}
+
+// Kinds of audit events
+const (
+	AuditEventCommand = "command" // A command run on the array (through the CLI, GUI or REST API)
+	AuditEventSession = "session" // A login, logout or failed authentication
+)
+
+// AuditEvent is an entry in the audit trail or session log of an array, unified between FlashArray and FlashBlade.
+// Fields that don't apply to the kind of event are left empty.
+type AuditEvent struct {
+	ArrayID          string `json:"ArrayID"`
+	ArrayName        string `json:"ArrayName"`
+	ArrayDisplayName string `json:"ArrayDisplayName"`
+	ArrayType        string `json:"ArrayType"`
+	Kind             string `json:"Kind"`
+	EventID          string `json:"EventID"` // As numbered by the array, unique for the array and kind of event
+	Time             int64  `json:"Time"`    // Unix seconds since epoch
+	User             string `json:"User"`
+	Interface        string `json:"Interface"`  // CLI, GUI or REST, if the array reports it
+	Command          string `json:"Command"`    // Commands only, such as purevol
+	Subcommand       string `json:"Subcommand"` // Commands only, such as create
+	Arguments        string `json:"Arguments"`  // Commands only
+	Event            string `json:"Event"`      // Sessions only, such as login or failed authentication
+	Location         string `json:"Location"`   // Sessions only, the address the user connected from
+	Method           string `json:"Method"`     // Sessions only, how the user authenticated
+	CollectedAt      int64  `json:"CollectedAt"`
+}
+
+// Snapshot is a single FlashArray volume snapshot or FlashBlade file system snapshot in the inventory of an array
+type Snapshot struct {
+	ArrayID          string `json:"ArrayID"`
+	ArrayName        string `json:"ArrayName"`
+	ArrayDisplayName string `json:"ArrayDisplayName"`
+	ArrayType        string `json:"ArrayType"`
+	Name             string `json:"Name"`
+	Source           string `json:"Source"` // The volume or file system the snapshot was taken of
+	Suffix           string `json:"Suffix"`
+	Created          int64  `json:"Created"`         // Unix seconds since epoch
+	Size             uint64 `json:"Size"`            // Provisioned size of the source when the snapshot was taken, 0 if the array doesn't report it
+	ProtectionGroup  string `json:"ProtectionGroup"` // FlashArray only, empty for snapshots taken of the volume alone
+	Policy           string `json:"Policy"`          // FlashBlade only, empty for snapshots not taken by a policy
+	Managed          bool   `json:"Managed"`         // Whether the snapshot was taken by a protection group or policy
+	CollectedAt      int64  `json:"CollectedAt"`
+}
+
+// Resolutions of metric rollups
+const (
+	HourlyRollup = "hourly"
+	DailyRollup  = "daily"
+)
+
+// MetricRollup summarizes the raw metrics of a single array (or volume, if VolumeName is set) over an hour or a day
+type MetricRollup struct {
+	ArrayID          string                       `json:"ArrayID"`
+	ArrayName        string                       `json:"ArrayName"`
+	ArrayDisplayName string                       `json:"ArrayDisplayName"`
+	ArrayType        string                       `json:"ArrayType,omitempty"`
+	ArrayTags        map[string]string            `json:"ArrayTags"`
+	VolumeName       string                       `json:"VolumeName,omitempty"`
+	VolumeType       string                       `json:"VolumeType,omitempty"`
+	Resolution       string                       `json:"Resolution"`
+	StartTime        int64                        `json:"StartTime"` // Unix seconds since epoch
+	SampleCount      int64                        `json:"SampleCount"`
+	Values           map[string]*RollupStatistics `json:"Values"` // Keyed by the raw metric field name, such as UsedSpace
+}
+
+// RollupStatistics are the statistics kept for a single metric field in a rollup
+type RollupStatistics struct {
+	Min float64 `json:"Min"`
+	Avg float64 `json:"Avg"`
+	Max float64 `json:"Max"`
+	P95 float64 `json:"P95"`
+}
+
+// CapacitySample is the capacity of an array averaged over a day
+type CapacitySample struct {
+	Time        int64   `json:"Time"` // Unix seconds since epoch, start of the day
+	UsedSpace   float64 `json:"UsedSpace"`
+	TotalSpace  float64 `json:"TotalSpace"`
+	PercentFull float64 `json:"PercentFull"` // Fraction from 0 to 1, as collected
+}
+
+// CapacityForecast is the projected capacity growth of an array (or a group of arrays)
+type CapacityForecast struct {
+	ArrayID      string  `json:"ArrayID"`
+	GeneratedAt  int64   `json:"GeneratedAt"`  // Unix seconds since epoch
+	HistoryStart int64   `json:"HistoryStart"` // Unix seconds since epoch
+	HistoryEnd   int64   `json:"HistoryEnd"`   // Unix seconds since epoch
+	SampleCount  int     `json:"SampleCount"`
+	UsedSpace    float64 `json:"UsedSpace"` // Latest values
+	TotalSpace   float64 `json:"TotalSpace"`
+	PercentFull  float64 `json:"PercentFull"` // Percentage from 0 to 100
+	DailyGrowth  float64 `json:"DailyGrowth"` // Bytes per day, from the linear trend
+	// Average deviation from the linear trend by day of the week (Sunday first), in bytes. Empty if there
+	// isn't enough history to fit a weekly pattern.
+	WeeklySeasonality []float64             `json:"WeeklySeasonality,omitempty"`
+	Projections       []*CapacityProjection `json:"Projections"`
+}
+
+// CapacityProjection is when an array is projected to reach a given percentage full
+type CapacityProjection struct {
+	Percent int `json:"Percent"`
+	// Unix seconds since epoch, or nil if capacity isn't projected to reach the percentage within the forecast horizon
+	LinearDate   *int64 `json:"LinearDate,omitempty"`
+	SeasonalDate *int64 `json:"SeasonalDate,omitempty"`
 }
@@ -24,7 +24,7 @@ import (
 // IsEmpty returns whether this query has any filter parameters set or not
 // (it doesn't care about sorting or pagination)
 func (q *ArrayQuery) IsEmpty() bool {
-	return len(q.Ids) == 0 && len(q.Names) == 0 && len(q.Models) == 0 && len(q.Versions) == 0
+	return len(q.Ids) == 0 && len(q.Names) == 0 && len(q.Models) == 0 && len(q.Versions) == 0 && q.Filter == nil
 }
 
 func removeNilQueries(queries ...elastic.Query) []elastic.Query {
@@ -89,6 +89,9 @@ func (q *ArrayQuery) GenerateElasticQueryObject() elastic.Query {
 	queries = append(queries, generateTermQueryObject("Name.keyword", q.Names...))
 	queries = append(queries, generateTermQueryObject("Model.keyword", q.Models...))
 	queries = append(queries, generateTermQueryObject("Version.keyword", q.Versions...))
+	if q.Filter != nil {
+		queries = append(queries, q.Filter.ElasticQuery())
+	}
 	queries = removeNilQueries(queries...)
 
 	outerQuery := elastic.NewBoolQuery().Must(queries...)
@@ -25,15 +25,53 @@ func (a *ArrayRegistrationInfo) GetLogFields(includeEndpoint bool) log.Fields {
 	}
 	if includeEndpoint {
 		fields["device_mgmt_endpoint"] = a.MgmtEndpoint
+		if len(a.MgmtEndpoints) > 1 {
+			fields["device_mgmt_endpoints"] = a.MgmtEndpoints
+		}
 	}
 	return fields
 }
 
+// GetMgmtEndpoints returns the management endpoints to try, in order, falling back to the
+// single management endpoint if the list wasn't given
+func (a *ArrayRegistrationInfo) GetMgmtEndpoints() []string {
+	if len(a.MgmtEndpoints) != 0 {
+		return a.MgmtEndpoints
+	}
+	return []string{a.MgmtEndpoint}
+}
+
 // IsEqual compares this struct to the given one
 func (a *ArrayRegistrationInfo) IsEqual(other *ArrayRegistrationInfo) bool {
 	return a.ID == other.ID &&
 		a.DeviceType == other.DeviceType &&
 		a.MgmtEndpoint == other.MgmtEndpoint &&
+		endpointsEqual(a.GetMgmtEndpoints(), other.GetMgmtEndpoints()) &&
 		a.APIToken == other.APIToken &&
-		a.Name == other.Name
+		a.Name == other.Name &&
+		a.CACertificate == other.CACertificate &&
+		a.CertificateFingerprint == other.CertificateFingerprint &&
+		a.APIClientCredentials == other.APIClientCredentials
+}
+
+// endpointsEqual is a helper function that checks if both endpoint lists hold the same endpoints in the same order
+func endpointsEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetAPIClient returns the API client credentials to authenticate with, or nil if
+// the array was registered with an API token only
+func (a *ArrayRegistrationInfo) GetAPIClient() *APIClientCredentials {
+	if !a.APIClientCredentials.IsSet() {
+		return nil
+	}
+	return &a.APIClientCredentials
 }
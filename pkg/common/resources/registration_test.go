@@ -142,3 +142,24 @@ func TestLogFieldsEndpoint(t *testing.T) {
 	assert.Equal(t, "test-device", fields["device_name"])
 	assert.Equal(t, "8.8.8.8", fields["device_mgmt_endpoint"])
 }
+
+func TestIsEqualDifferentMgmtEndpoints(t *testing.T) {
+	one := &ArrayRegistrationInfo{
+		ID:            "12345",
+		MgmtEndpoint:  "8.8.8.8",
+		MgmtEndpoints: []string{"8.8.8.8", "1.1.1.1"},
+	}
+	two := &ArrayRegistrationInfo{
+		ID:            "12345",
+		MgmtEndpoint:  "8.8.8.8",
+		MgmtEndpoints: []string{"8.8.8.8", "2001:db8::10"},
+	}
+	assert.False(t, one.IsEqual(two))
+	assert.True(t, one.IsEqual(one))
+}
+
+func TestGetMgmtEndpointsFallbackToEndpoint(t *testing.T) {
+	info := &ArrayRegistrationInfo{MgmtEndpoint: "8.8.8.8"}
+	assert.Equal(t, []string{"8.8.8.8"}, info.GetMgmtEndpoints())
+	assert.True(t, info.IsEqual(&ArrayRegistrationInfo{MgmtEndpoint: "8.8.8.8", MgmtEndpoints: []string{"8.8.8.8"}}))
+}
@@ -0,0 +1,59 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/olivere/elastic"
+)
+
+// GenerateElasticQueryObject converts this query into an elastic.Query
+func (q *SnapshotQuery) GenerateElasticQueryObject() elastic.Query {
+	query := elastic.NewBoolQuery()
+	if len(q.ArrayIDs) > 0 {
+		query.Filter(elastic.NewTermsQuery("ArrayID", convertToInterfaceSlice(q.ArrayIDs)...))
+	}
+	if len(q.Sources) > 0 {
+		query.Filter(elastic.NewTermsQuery("Source", convertToInterfaceSlice(q.Sources)...))
+	}
+	if q.StaleBefore > 0 {
+		// Either reason is enough for a snapshot to be stale
+		query.Filter(elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(
+			elastic.NewRangeQuery("Created").Format("epoch_second").Lt(q.StaleBefore),
+			elastic.NewTermQuery("Managed", false),
+		))
+	}
+	return query
+}
+
+// ConvertToSnapshotMap converts the given snapshot into a string->interface map suitable for marshalling
+func ConvertToSnapshotMap(snapshot *metrics.Snapshot) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 snapshot.GetDocumentID(),
+		"array_id":           snapshot.ArrayID,
+		"array_name":         snapshot.ArrayName,
+		"array_display_name": snapshot.ArrayDisplayName,
+		"device_type":        snapshot.ArrayType,
+		"name":               snapshot.Name,
+		"source":             snapshot.Source,
+		"suffix":             snapshot.Suffix,
+		"created":            convertUnixTime(snapshot.Created),
+		"size":               snapshot.Size,
+		"protection_group":   snapshot.ProtectionGroup,
+		"policy":             snapshot.Policy,
+		"managed":            snapshot.Managed,
+		"_as_of":             convertUnixTime(snapshot.CollectedAt),
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToSnapshotMap(t *testing.T) {
+	snapshot := &metrics.Snapshot{
+		ArrayID:         "array-1",
+		Name:            "pg1.42.vol1",
+		Source:          "vol1",
+		Suffix:          "42",
+		Created:         1546300800,
+		Size:            1073741824,
+		ProtectionGroup: "pg1",
+		Managed:         true,
+	}
+	converted := ConvertToSnapshotMap(snapshot)
+	assert.Equal(t, "array-1-pg1.42.vol1", converted["id"])
+	assert.Equal(t, time.Unix(1546300800, 0).UTC(), converted["created"])
+	assert.Equal(t, uint64(1073741824), converted["size"])
+	assert.Equal(t, true, converted["managed"])
+	assert.Nil(t, converted["_as_of"])
+}
+
+func TestSnapshotQueryGenerateElasticQueryObject(t *testing.T) {
+	query := SnapshotQuery{
+		ArrayIDs:    []string{"array-1"},
+		StaleBefore: 1546300800,
+	}
+
+	source, err := query.GenerateElasticQueryObject().Source()
+	assert.NoError(t, err)
+	encoded, err := json.Marshal(source)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{
+		"filter":[
+			{"terms":{"ArrayID":["array-1"]}},
+			{"bool":{
+				"minimum_should_match":"1",
+				"should":[
+					{"range":{"Created":{"format":"epoch_second","from":null,"include_lower":true,"include_upper":false,"to":1546300800}}},
+					{"term":{"Managed":false}}
+				]
+			}}
+		]
+	}}`, string(encoded))
+}
@@ -0,0 +1,202 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/snmp"
+)
+
+const defaultSNMPTrapPort = "162"
+
+// NewSNMPDestinationFromREST creates an SNMP destination from a map in the format of the REST API call (lower_case),
+// filling in the defaults for any optional keys that are missing
+func NewSNMPDestinationFromREST(m map[string]interface{}) (*SNMPDestination, error) {
+	destination := &SNMPDestination{
+		Enabled:      true,
+		Version:      snmp.Version2c,
+		AuthProtocol: snmp.AuthSHA,
+		PrivProtocol: snmp.PrivAES,
+	}
+	err := destination.ApplyPatch(m)
+	if err != nil {
+		return nil, err
+	}
+	return destination, nil
+}
+
+// ConvertToSNMPDestinationMap converts this destination into a string->interface map suitable for marshalling
+func (d *SNMPDestination) ConvertToSNMPDestinationMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":              d.InternalID,
+		"name":            d.Name,
+		"enabled":         d.Enabled,
+		"address":         d.Address,
+		"version":         d.Version,
+		"community":       d.Community,
+		"engine_id":       d.EngineID,
+		"username":        d.Username,
+		"auth_protocol":   d.AuthProtocol,
+		"auth_passphrase": d.AuthPassphrase,
+		"priv_protocol":   d.PrivProtocol,
+		"priv_passphrase": d.PrivPassphrase,
+		"_last_updated":   d.LastUpdated,
+	}
+}
+
+// ApplyPatch applies the given patches to this destination, with the given map in the format
+// accepted by the REST API ("address", "auth_protocol", etc.). The destination should be validated afterwards.
+func (d *SNMPDestination) ApplyPatch(m map[string]interface{}) error {
+	if value, ok := m["id"]; ok {
+		id, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key id must be a string")
+		}
+		err := ValidateHexObjectID(id)
+		if err != nil {
+			return err
+		}
+		d.InternalID = id
+	}
+	if value, ok := m["enabled"]; ok {
+		enabled, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("Key enabled must be a boolean")
+		}
+		d.Enabled = enabled
+	}
+
+	stringFields := []struct {
+		key    string
+		target *string
+		lower  bool
+	}{
+		{"name", &d.Name, false},
+		{"address", &d.Address, false},
+		{"version", &d.Version, true},
+		{"community", &d.Community, false},
+		{"engine_id", &d.EngineID, true},
+		{"username", &d.Username, false},
+		{"auth_protocol", &d.AuthProtocol, true},
+		{"auth_passphrase", &d.AuthPassphrase, false},
+		{"priv_protocol", &d.PrivProtocol, true},
+		{"priv_passphrase", &d.PrivPassphrase, false},
+	}
+	for _, field := range stringFields {
+		value, ok := m[field.key]
+		if !ok {
+			continue
+		}
+		stringValue, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Key %s must be a string", field.key)
+		}
+		stringValue = strings.TrimSpace(stringValue)
+		if field.lower {
+			stringValue = strings.ToLower(stringValue)
+		}
+		*field.target = stringValue
+	}
+
+	if _, ok := m["address"]; ok && len(d.Address) > 0 {
+		if _, _, err := net.SplitHostPort(d.Address); err != nil {
+			d.Address = net.JoinHostPort(strings.Trim(d.Address, "[]"), defaultSNMPTrapPort)
+		}
+	}
+	if _, ok := m["engine_id"]; ok {
+		d.EngineID = strings.TrimPrefix(d.EngineID, "0x")
+	}
+	return nil
+}
+
+// Validate checks that this destination has all required fields filled in, and that traps can be sent to it
+func (d *SNMPDestination) Validate() error {
+	if len(d.Name) == 0 {
+		return fmt.Errorf("SNMP destination is missing name")
+	}
+	host, _, err := net.SplitHostPort(d.Address)
+	if err != nil || len(host) == 0 {
+		return fmt.Errorf("Key address must be a host name or IP address, optionally followed by a port")
+	}
+
+	switch d.Version {
+	case snmp.Version2c:
+		if len(d.Community) == 0 {
+			return fmt.Errorf("Key community is required for SNMP %s destinations", snmp.Version2c)
+		}
+	case snmp.Version3:
+		if len(d.Username) == 0 {
+			return fmt.Errorf("Key username is required for SNMP %s destinations", snmp.Version3)
+		}
+		_, err = snmp.ParseEngineID(d.EngineID)
+		if err != nil {
+			return err
+		}
+		err = snmp.ValidateUSM(d.AuthProtocol, d.AuthPassphrase, d.PrivProtocol, d.PrivPassphrase)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Key version must be one of %s or %s", snmp.Version2c, snmp.Version3)
+	}
+	return nil
+}
+
+// GetCredentials gets the secret parts of this destination
+func (d *SNMPDestination) GetCredentials() SNMPCredentials {
+	return SNMPCredentials{
+		Community:      d.Community,
+		AuthPassphrase: d.AuthPassphrase,
+		PrivPassphrase: d.PrivPassphrase,
+	}
+}
+
+// SetCredentials sets the secret parts of this destination
+func (d *SNMPDestination) SetCredentials(credentials SNMPCredentials) {
+	d.Community = credentials.Community
+	d.AuthPassphrase = credentials.AuthPassphrase
+	d.PrivPassphrase = credentials.PrivPassphrase
+}
+
+// ConvertToSNMPTarget converts this destination into a target traps can be sent to
+func (d *SNMPDestination) ConvertToSNMPTarget() (*snmp.Target, error) {
+	target := &snmp.Target{
+		Address:        d.Address,
+		Version:        d.Version,
+		Community:      d.Community,
+		Username:       d.Username,
+		AuthProtocol:   d.AuthProtocol,
+		AuthPassphrase: d.AuthPassphrase,
+		PrivProtocol:   d.PrivProtocol,
+		PrivPassphrase: d.PrivPassphrase,
+	}
+	if d.Version == snmp.Version3 {
+		engineID, err := snmp.ParseEngineID(d.EngineID)
+		if err != nil {
+			return nil, err
+		}
+		target.EngineID = engineID
+	}
+	return target, nil
+}
+
+// NewSNMPEngineID generates a hex encoded engine ID for a new destination
+func NewSNMPEngineID() string {
+	return hex.EncodeToString(snmp.NewEngineID())
+}
@@ -0,0 +1,129 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSNMPDestinationFromRESTDefaults(t *testing.T) {
+	destination, err := NewSNMPDestinationFromREST(map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.True(t, destination.Enabled)
+	assert.Equal(t, "v2c", destination.Version)
+	assert.Equal(t, "sha", destination.AuthProtocol)
+	assert.Equal(t, "aes", destination.PrivProtocol)
+}
+
+func TestNewSNMPDestinationFromRESTSetAll(t *testing.T) {
+	destination, err := NewSNMPDestinationFromREST(map[string]interface{}{
+		"id":              "1234567890abcdefedcba098",
+		"name":            " NOC ",
+		"enabled":         false,
+		"address":         "fd00::1",
+		"version":         "V3",
+		"engine_id":       "0x80009E220501020304",
+		"username":        "pure1",
+		"auth_protocol":   "MD5",
+		"auth_passphrase": "authpass123",
+		"priv_protocol":   "DES",
+		"priv_passphrase": "privpass123",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890abcdefedcba098", destination.InternalID)
+	assert.Equal(t, "NOC", destination.Name)
+	assert.False(t, destination.Enabled)
+	assert.Equal(t, "[fd00::1]:162", destination.Address)
+	assert.Equal(t, "v3", destination.Version)
+	assert.Equal(t, "80009e220501020304", destination.EngineID)
+	assert.Equal(t, "md5", destination.AuthProtocol)
+	assert.Equal(t, "des", destination.PrivProtocol)
+	assert.NoError(t, destination.Validate())
+}
+
+func TestNewSNMPDestinationFromRESTBadTypes(t *testing.T) {
+	_, err := NewSNMPDestinationFromREST(map[string]interface{}{"enabled": "yes"})
+	assert.Error(t, err)
+	_, err = NewSNMPDestinationFromREST(map[string]interface{}{"address": 162})
+	assert.Error(t, err)
+	_, err = NewSNMPDestinationFromREST(map[string]interface{}{"id": "not an id"})
+	assert.Error(t, err)
+}
+
+func TestSNMPDestinationValidate(t *testing.T) {
+	v2c := SNMPDestination{Name: "NOC", Address: "10.0.0.1:162", Version: "v2c", Community: "public"}
+	assert.NoError(t, v2c.Validate())
+
+	missingName := v2c
+	missingName.Name = ""
+	assert.Error(t, missingName.Validate())
+
+	missingPort := v2c
+	missingPort.Address = "10.0.0.1"
+	assert.Error(t, missingPort.Validate())
+
+	missingCommunity := v2c
+	missingCommunity.Community = ""
+	assert.Error(t, missingCommunity.Validate())
+
+	badVersion := v2c
+	badVersion.Version = "v1"
+	assert.Error(t, badVersion.Validate())
+
+	v3 := SNMPDestination{Name: "NOC", Address: "10.0.0.1:162", Version: "v3", EngineID: "80009e220501020304", Username: "pure1",
+		AuthProtocol: "sha", AuthPassphrase: "authpass123", PrivProtocol: "aes", PrivPassphrase: "privpass123"}
+	assert.NoError(t, v3.Validate())
+
+	missingUsername := v3
+	missingUsername.Username = ""
+	assert.Error(t, missingUsername.Validate())
+
+	badEngineID := v3
+	badEngineID.EngineID = "8000"
+	assert.Error(t, badEngineID.Validate())
+
+	shortPassphrase := v3
+	shortPassphrase.AuthPassphrase = "short"
+	assert.Error(t, shortPassphrase.Validate())
+
+	privWithoutAuth := v3
+	privWithoutAuth.AuthProtocol = "none"
+	assert.Error(t, privWithoutAuth.Validate())
+}
+
+func TestSNMPDestinationCredentials(t *testing.T) {
+	destination := SNMPDestination{Name: "NOC", Community: "public", AuthPassphrase: "authpass123", PrivPassphrase: "privpass123"}
+	credentials := destination.GetCredentials()
+	assert.Equal(t, SNMPCredentials{Community: "public", AuthPassphrase: "authpass123", PrivPassphrase: "privpass123"}, credentials)
+
+	destination.SetCredentials(SNMPCredentials{})
+	assert.Empty(t, destination.Community)
+	assert.Empty(t, destination.AuthPassphrase)
+	assert.Empty(t, destination.PrivPassphrase)
+}
+
+func TestConvertToSNMPTarget(t *testing.T) {
+	destination := SNMPDestination{Name: "NOC", Address: "10.0.0.1:162", Version: "v3", EngineID: "80009e220501020304", Username: "pure1"}
+	target, err := destination.ConvertToSNMPTarget()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x80, 0x00, 0x9e, 0x22, 0x05, 0x01, 0x02, 0x03, 0x04}, target.EngineID)
+	assert.Equal(t, "pure1", target.Username)
+
+	destination.EngineID = "not hex"
+	_, err = destination.ConvertToSNMPTarget()
+	assert.Error(t, err)
+}
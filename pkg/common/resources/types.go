@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
+	"github.com/olivere/elastic"
 )
 
 // ArrayDatabase provides an interface to Elastic (or mocked data, or something else) to store
@@ -30,6 +31,83 @@ type ArrayDatabase interface {
 	DeleteArray(query *ArrayQuery) ([]string, error) // Returns list of IDs deleted
 }
 
+// CapacityForecastDatabase provides an interface to look up the capacity forecasts made for arrays
+type CapacityForecastDatabase interface {
+	FindCapacityForecasts(arrayIDs []string) ([]*metrics.CapacityForecast, error)
+}
+
+// HardwareStatusDatabase provides an interface to look up the latest health of the hardware components of arrays
+type HardwareStatusDatabase interface {
+	FindLatestHardwareMetrics(arrayIDs []string) ([]*metrics.HardwareMetric, error)
+}
+
+// PodStatusDatabase provides an interface to look up the latest state of the ActiveCluster pods on arrays
+type PodStatusDatabase interface {
+	FindLatestPodMetrics(arrayIDs []string) ([]*metrics.PodMetric, error)
+}
+
+// AlertRuleDatabase provides an interface to store and access user-defined alert rules
+type AlertRuleDatabase interface {
+	FindAlertRules(ids []string) ([]*AlertRule, error) // Returns every rule if no IDs are given
+	InsertAlertRule(rule *AlertRule) error
+	UpdateAlertRule(rule *AlertRule) error
+	DeleteAlertRules(ids []string) ([]string, error) // Returns list of IDs deleted
+}
+
+// AlertRuleDiscovery represents a connection to fetch the list of alert rules to evaluate
+// from an external source
+type AlertRuleDiscovery interface {
+	GetAlertRules() ([]*AlertRule, error)
+}
+
+// MaintenanceWindowDatabase provides an interface to store and access maintenance windows
+type MaintenanceWindowDatabase interface {
+	FindMaintenanceWindows(ids []string) ([]*MaintenanceWindow, error) // Returns every window if no IDs are given
+	InsertMaintenanceWindow(window *MaintenanceWindow) error
+	UpdateMaintenanceWindow(window *MaintenanceWindow) error
+	DeleteMaintenanceWindows(ids []string) ([]string, error) // Returns list of IDs deleted
+}
+
+// MaintenanceWindowDiscovery represents a connection to fetch the maintenance windows from an external source
+type MaintenanceWindowDiscovery interface {
+	GetMaintenanceWindows() ([]*MaintenanceWindow, error)
+}
+
+// AlertDatabase represents a backend that alerts can be searched in, and their lifecycle (acknowledgement,
+// assignment, notes and snoozing) updated through
+type AlertDatabase interface {
+	FindAlerts(query *AlertQuery) ([]*metrics.Alert, error)
+	PatchAlertLifecycles(ids []string, patch func(alert *metrics.Alert) error) ([]*metrics.Alert, error)
+}
+
+// AuditEventDatabase represents a backend that the audit trails and session logs of arrays can be searched in
+type AuditEventDatabase interface {
+	FindAuditEvents(query *AuditQuery) ([]*metrics.AuditEvent, error)
+}
+
+// SnapshotInventoryDatabase represents a backend that the snapshot inventories of arrays can be searched in
+type SnapshotInventoryDatabase interface {
+	FindSnapshots(query *SnapshotQuery) ([]*metrics.Snapshot, error)
+}
+
+// SNMPDestinationDatabase represents a backend that stores SNMP trap destinations (without their credentials)
+type SNMPDestinationDatabase interface {
+	FindSNMPDestinations(ids []string) ([]*SNMPDestination, error)
+	InsertSNMPDestination(destination *SNMPDestination) error
+	UpdateSNMPDestination(destination *SNMPDestination) error
+	DeleteSNMPDestinations(ids []string) ([]string, error)
+}
+
+// SNMPDestinationDiscovery represents a connection to fetch the SNMP trap destinations, credentials included
+type SNMPDestinationDiscovery interface {
+	GetSNMPDestinations() ([]*SNMPDestination, error)
+}
+
+// SNMPTestTrapSender represents a way to send a test trap to an SNMP destination
+type SNMPTestTrapSender interface {
+	SendTestTrap(destination *SNMPDestination) error
+}
+
 // APITokenStorage defines a type that can be used to save array API tokens
 // with their ID. This should ideally be separate from the main API
 // server database, as the whole intent is for API tokens to be protected.
@@ -67,6 +145,49 @@ type ArrayCollector interface {
 	GetArrayType() string
 	GetArrayVersion() (string, error)
 	GetDisplayName() string
+	GetManagementEndpoint() string
+}
+
+// HostCollector is implemented by array collectors that can also collect metrics for the hosts
+// and host groups connected to the array (FlashArray only)
+type HostCollector interface {
+	GetAllHostData() (*metrics.AllHostData, error)
+}
+
+// ProtectionGroupCollector is implemented by array collectors that can also collect metrics for the
+// protection groups (and their replication) of the array (FlashArray only)
+type ProtectionGroupCollector interface {
+	GetAllProtectionGroupData() (*metrics.AllProtectionGroupData, error)
+}
+
+// AuditCollector is implemented by array collectors that can also collect the audit trail and session log
+// of the array
+type AuditCollector interface {
+	GetAuditEvents(since int64) ([]*metrics.AuditEvent, error) // Events at or after the given time (Unix seconds)
+}
+
+// SnapshotCollector is implemented by array collectors that can also collect the full snapshot inventory of
+// the array
+type SnapshotCollector interface {
+	GetSnapshots() ([]*metrics.Snapshot, error)
+}
+
+// HardwareCollector is implemented by array collectors that can also collect the health of the hardware
+// components of the array
+type HardwareCollector interface {
+	GetAllHardwareData() (*metrics.AllHardwareData, error)
+}
+
+// HistoryCollector is implemented by array collectors that can also get the performance history the array
+// keeps itself, to fill in metrics that weren't collected
+type HistoryCollector interface {
+	GetArrayPerformanceHistory(start int64, end int64) ([]*metrics.ArrayMetric, error)
+}
+
+// PodCollector is implemented by array collectors that can also collect the ActiveCluster pods of the
+// array (FlashArray only)
+type PodCollector interface {
+	GetAllPodData() (*metrics.AllPodData, error)
 }
 
 // ArrayDiscovery represents a connection to fetch a list of arrays
@@ -78,6 +199,12 @@ type ArrayDiscovery interface {
 	GetArrays() ([]*ArrayRegistrationInfo, error)
 }
 
+// ArrayTagDiscovery represents a connection to fetch the tags of every array, for matching tag selectors
+// against arrays that haven't had their metrics collected yet
+type ArrayTagDiscovery interface {
+	GetAllTags() (map[string]map[string]string, error) // Keyed by array ID
+}
+
 // CollectorFactory represents a factory that converts from an array
 // metadata struct into a backend connection. Mainly a way to allow
 // passing mocks for testing easier.
@@ -85,6 +212,13 @@ type CollectorFactory interface {
 	InitializeCollector(*ArrayRegistrationInfo) (ArrayCollector, error)
 }
 
+// CollectorCache is implemented by collector factories that reuse collectors (and their array sessions)
+// across collection cycles, so a collector that stopped working can be dropped and created again
+type CollectorCache interface {
+	EvictCollector(arrayID string)
+	PruneCollectors(arrays []*ArrayRegistrationInfo)
+}
+
 // ArrayMetadata represents a connection to modify a array or get more information
 // about it from an external source, whether it's a json file, a database, or
 // a server.
@@ -103,38 +237,178 @@ type ArrayQuery struct {
 	SortDescending bool // false: ascending, true: descending
 	Offset         int
 	Limit          int
+	Filter         FilterExpression // Parsed from the "filter" query parameter, nil if not given
+}
+
+// AlertQuery provides a struct holding possible query parameters for an alert request
+type AlertQuery struct {
+	IDs          []string // Alert document IDs (see metrics.Alert.GetDocumentID)
+	ArrayIDs     []string
+	States       []string
+	AssignedTo   string
+	Acknowledged *bool // nil to match alerts whether they're acknowledged or not
+	Snoozed      *bool // nil to match alerts whether they're snoozed or not
+	Offset       int
+	Limit        int
+}
+
+// AuditQuery provides a struct holding possible query parameters for an audit event request
+type AuditQuery struct {
+	ArrayIDs  []string
+	Kinds     []string // metrics.AuditEventCommand or metrics.AuditEventSession
+	Users     []string
+	Commands  []string
+	StartTime int64 // Unix seconds, 0 for no lower bound
+	EndTime   int64 // Unix seconds, 0 for no upper bound
+	Offset    int
+	Limit     int
+}
+
+// SnapshotQuery provides a struct holding possible query parameters for a snapshot inventory request
+type SnapshotQuery struct {
+	ArrayIDs    []string
+	Sources     []string
+	StaleBefore int64 // If set, only snapshots created before this time (Unix seconds), or not taken by any protection group or policy
+	Offset      int
+	Limit       int
+}
+
+// FilterExpression is a node in a parsed filter expression (see ParseFilter)
+// that can be converted into an Elastic query
+type FilterExpression interface {
+	ElasticQuery() elastic.Query
+}
+
+// FilterLogicalExpression combines two or more expressions with "and" or "or"
+type FilterLogicalExpression struct {
+	Operator string // FilterAnd or FilterOr
+	Operands []FilterExpression
+}
+
+// FilterNotExpression negates the expression it wraps
+type FilterNotExpression struct {
+	Operand FilterExpression
+}
+
+// FilterComparison compares a single array field against one or more values
+type FilterComparison struct {
+	Field    string // The field as written in the expression: "model", "tags.site", etc.
+	Operator string // One of =, !=, <, <=, >, >= or in
+	Values   []string
 }
 
 // Array provides a struct for unified FlashArray/FlashBlade metadata
 type Array struct {
-	InternalID   string              `json:"InternalID,omitempty"`
-	Name         string              `json:"Name,omitempty"`
-	MgmtEndPoint string              `json:"MgmtEndpoint,omitempty"`
-	APIToken     string              `json:"APIToken,omitempty"`
-	Status       string              `json:"Status,omitempty"`
-	Lastseen     time.Time           `json:"AsOf,omitempty"`
-	Lastupdated  time.Time           `json:"LastUpdated,omitempty"`
-	DeviceType   string              `json:"DeviceType,omitempty"`
-	Version      string              `json:"Version,omitempty"`
-	Model        string              `json:"Model,omitempty"`
-	Tags         []map[string]string `json:"Tags,omitempty"`
+	InternalID             string              `json:"InternalID,omitempty"`
+	Name                   string              `json:"Name,omitempty"`
+	MgmtEndPoint           string              `json:"MgmtEndpoint,omitempty"`
+	MgmtEndpoints          []string            `json:"MgmtEndpoints,omitempty"`  // Ordered management endpoints (FQDN, IPv4 or IPv6), starting with MgmtEndPoint
+	ActiveEndpoint         string              `json:"ActiveEndpoint,omitempty"` // Management endpoint the array was last reached at
+	APIToken               string              `json:"APIToken,omitempty"`
+	Status                 string              `json:"Status,omitempty"`
+	Lastseen               time.Time           `json:"AsOf,omitempty"`
+	Lastupdated            time.Time           `json:"LastUpdated,omitempty"`
+	DeviceType             string              `json:"DeviceType,omitempty"`
+	Version                string              `json:"Version,omitempty"`
+	Model                  string              `json:"Model,omitempty"`
+	CACertificate          string              `json:"CACertificate,omitempty"`          // PEM bundle used to verify the management certificate
+	CertificateFingerprint string              `json:"CertificateFingerprint,omitempty"` // Pinned SHA-256 fingerprint of the management certificate
+	Tags                   []map[string]string `json:"Tags,omitempty"`
+	// Used instead of the API token for FlashArray REST 2.x if set. Kept in its own secret, never in Elastic.
+	APIClient APIClientCredentials `json:"-"`
+}
+
+// Resources an alert rule can be evaluated against
+const (
+	AlertRuleArrayResource  = "array"
+	AlertRuleVolumeResource = "volume"
+)
+
+// AlertRule is a user-defined threshold on an array or volume metric, which raises an alert once the
+// metric has breached the threshold for the given duration
+type AlertRule struct {
+	InternalID      string            `json:"InternalID,omitempty"`
+	Name            string            `json:"Name"`
+	Enabled         bool              `json:"Enabled"`
+	Resource        string            `json:"Resource"`        // AlertRuleArrayResource or AlertRuleVolumeResource
+	Metric          string            `json:"Metric"`          // Metric field name, such as PercentFull or WriteLatency
+	Operator        string            `json:"Operator"`        // One of >, >=, < or <=
+	Threshold       float64           `json:"Threshold"`       // In the units the metric is stored in (fractions, bytes, microseconds, ...)
+	DurationSeconds int64             `json:"DurationSeconds"` // How long the threshold must be breached before alerting
+	Severity        string            `json:"Severity"`
+	ArrayIDs        []string          `json:"ArrayIDs"`    // Only evaluate against these arrays (all arrays if empty)
+	ArrayTags       map[string]string `json:"ArrayTags"`   // Only evaluate against arrays with all of these tag keys and values
+	VolumeNames     []string          `json:"VolumeNames"` // Only evaluate against these volumes (all volumes if empty)
+	LastUpdated     time.Time         `json:"LastUpdated"`
+}
+
+// MaintenanceWindow is a period of planned work on some arrays, during which notifications and alert rules
+// are suppressed for them, and the metrics and alerts collected from them are marked as in maintenance
+type MaintenanceWindow struct {
+	InternalID  string            `json:"InternalID,omitempty"`
+	ArrayIDs    []string          `json:"ArrayIDs"`  // Arrays the window applies to
+	ArrayTags   map[string]string `json:"ArrayTags"` // Arrays with all of these tag keys and values are also in the window
+	Start       int64             `json:"Start"`     // Unix seconds since epoch
+	End         int64             `json:"End"`       // Unix seconds since epoch
+	Reason      string            `json:"Reason"`
+	LastUpdated time.Time         `json:"LastUpdated"`
+}
+
+// SNMPDestination is a receiver that traps are sent to for new, escalated and closed alerts. The community
+// and passphrases are stored apart from the rest of the destination, in the same way as array API tokens.
+type SNMPDestination struct {
+	InternalID     string    `json:"InternalID,omitempty"`
+	Name           string    `json:"Name"`
+	Enabled        bool      `json:"Enabled"`
+	Address        string    `json:"Address"` // host:port
+	Version        string    `json:"Version"` // snmp.Version2c or snmp.Version3
+	Community      string    `json:"Community,omitempty"`
+	EngineID       string    `json:"EngineID"` // Hex encoded, SNMPv3 only
+	Username       string    `json:"Username"`
+	AuthProtocol   string    `json:"AuthProtocol"`
+	AuthPassphrase string    `json:"AuthPassphrase,omitempty"`
+	PrivProtocol   string    `json:"PrivProtocol"`
+	PrivPassphrase string    `json:"PrivPassphrase,omitempty"`
+	LastUpdated    time.Time `json:"LastUpdated"`
+}
+
+// SNMPCredentials holds the secret parts of an SNMP destination, as they're stored
+type SNMPCredentials struct {
+	Community      string `json:"community,omitempty"`
+	AuthPassphrase string `json:"auth_passphrase,omitempty"`
+	PrivPassphrase string `json:"priv_passphrase,omitempty"`
+}
+
+// APIClientCredentials holds the credentials of an API client registered on a FlashArray, as they're stored.
+// They're used instead of an API token to authenticate with REST 2.x through an OAuth2 token exchange.
+type APIClientCredentials struct {
+	ClientID   string `json:"api_client_id,omitempty"`
+	KeyID      string `json:"api_client_key_id,omitempty"`
+	PrivateKey string `json:"api_client_private_key,omitempty"` // PEM encoded RSA private key
+	Issuer     string `json:"api_client_issuer,omitempty"`
+	Username   string `json:"api_client_username,omitempty"` // The array user the API client acts as
 }
 
 // ArrayPatchInfo provides the data that is commonly patched on
 // the API server
 type ArrayPatchInfo struct {
-	Status  string `json:"status,omitempty"`
-	Model   string `json:"model,omitempty"`
-	Version string `json:"version,omitempty"`
-	AsOf    string `json:"_as_of,omitempty"`
+	Status         string `json:"status,omitempty"`
+	Model          string `json:"model,omitempty"`
+	Version        string `json:"version,omitempty"`
+	AsOf           string `json:"_as_of,omitempty"`
+	ActiveEndpoint string `json:"active_endpoint,omitempty"` // Management endpoint the array was reached at
 }
 
 // ArrayRegistrationInfo provides all the info needed to open a
 // connection with an array.
 type ArrayRegistrationInfo struct {
-	ID           string `json:"id"`
-	Name         string `json:"name"`
-	MgmtEndpoint string `json:"mgmt_endpoint"`
-	APIToken     string `json:"api_token"`
-	DeviceType   string `json:"device_type"`
+	ID                     string   `json:"id"`
+	Name                   string   `json:"name"`
+	MgmtEndpoint           string   `json:"mgmt_endpoint"`
+	MgmtEndpoints          []string `json:"mgmt_endpoints"`
+	APIToken               string   `json:"api_token"`
+	DeviceType             string   `json:"device_type"`
+	CACertificate          string   `json:"ca_certificate"`
+	CertificateFingerprint string   `json:"certificate_fingerprint"`
+	APIClientCredentials
 }
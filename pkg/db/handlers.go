@@ -15,10 +15,15 @@
 package db
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/registry"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/http/errors"
 	"gopkg.in/mgo.v2/bson"
 
@@ -44,6 +49,7 @@ func (h *MetadataConnection) GetArrays(query resources.ArrayQuery) (BulkResponse
 	arrayMaps := []map[string]interface{}{}
 	for _, array := range results {
 		h.populateAPIToken(array)
+		h.populateAPIClientCredentials(array)
 		arrayMaps = append(arrayMaps, array.ConvertToArrayMap())
 	}
 
@@ -80,6 +86,127 @@ func (h *MetadataConnection) GetArrayTags(query resources.ArrayQuery) (BulkRespo
 	return BulkResponse{Response: tagMaps}, nil
 }
 
+// GetArrayHardware fetches the latest hardware component health of all the arrays that match the given query.
+// Arrays that haven't had their hardware collected recently are left out.
+func (h *MetadataConnection) GetArrayHardware(query resources.ArrayQuery) (BulkResponse, error) {
+	arrays, err := h.DAO.FindArrays(&query)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	arrayIDs := []string{}
+	deviceTypes := map[string]string{}
+	for _, array := range arrays {
+		arrayIDs = append(arrayIDs, array.InternalID)
+		deviceTypes[array.InternalID] = array.DeviceType
+	}
+	hardwareMetrics, err := h.Hardware.FindLatestHardwareMetrics(arrayIDs)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	hardwareMaps := []map[string]interface{}{}
+	for _, arrayHardware := range metrics.BuildArrayHardware(hardwareMetrics) {
+		hardwareMap := arrayHardware.ConvertToArrayHardwareMap()
+		hardwareMap["device_type"] = deviceTypes[arrayHardware.ArrayID]
+		hardwareMaps = append(hardwareMaps, hardwareMap)
+	}
+
+	return BulkResponse{Response: hardwareMaps}, nil
+}
+
+// GetCapacityForecasts fetches the capacity forecasts of all the arrays that match the given query, and
+// combines them into a forecast for every tag (namespace, key and value) those arrays have
+func (h *MetadataConnection) GetCapacityForecasts(query resources.ArrayQuery) (ForecastResponse, error) {
+	arrays, err := h.DAO.FindArrays(&query)
+	if err != nil {
+		return ForecastResponse{}, err
+	}
+
+	arrayIDs := []string{}
+	for _, array := range arrays {
+		arrayIDs = append(arrayIDs, array.InternalID)
+	}
+	forecasts, err := h.Forecasts.FindCapacityForecasts(arrayIDs)
+	if err != nil {
+		return ForecastResponse{}, err
+	}
+	forecastsByID := map[string]*metrics.CapacityForecast{}
+	for _, forecast := range forecasts {
+		forecastsByID[forecast.ArrayID] = forecast
+	}
+
+	now := time.Now().UTC()
+	arrayMaps := []map[string]interface{}{}
+	tagForecasts := map[string][]*metrics.CapacityForecast{}
+	tagArrayIDs := map[string][]string{}
+	tagsByKey := map[string]map[string]string{}
+	for _, array := range arrays {
+		forecast, ok := forecastsByID[array.InternalID]
+		if !ok {
+			// Not forecast yet
+			continue
+		}
+
+		forecastMap := forecast.ConvertToForecastMap()
+		forecastMap["id"] = array.InternalID
+		forecastMap["name"] = array.Name
+		forecastMap["device_type"] = array.DeviceType
+		arrayMaps = append(arrayMaps, forecastMap)
+
+		for _, tag := range array.Tags {
+			tagKey := strings.Join([]string{tag["namespace"], tag["key"], tag["value"]}, "\x00")
+			tagsByKey[tagKey] = tag
+			tagForecasts[tagKey] = append(tagForecasts[tagKey], forecast)
+			tagArrayIDs[tagKey] = append(tagArrayIDs[tagKey], array.InternalID)
+		}
+	}
+
+	tagKeys := []string{}
+	for tagKey := range tagForecasts {
+		tagKeys = append(tagKeys, tagKey)
+	}
+	// Keys start with the namespace, so this groups tags by namespace
+	sort.Strings(tagKeys)
+
+	tagMaps := []map[string]interface{}{}
+	for _, tagKey := range tagKeys {
+		tagMap := metrics.AggregateCapacityForecasts(tagForecasts[tagKey], now).ConvertToForecastMap()
+		tagMap["namespace"] = tagsByKey[tagKey]["namespace"]
+		tagMap["key"] = tagsByKey[tagKey]["key"]
+		tagMap["value"] = tagsByKey[tagKey]["value"]
+		tagMap["array_ids"] = tagArrayIDs[tagKey]
+		tagMaps = append(tagMaps, tagMap)
+	}
+
+	return ForecastResponse{Response: arrayMaps, Tags: tagMaps}, nil
+}
+
+// GetPods fetches the latest pod metrics of all the arrays that match the given query, and correlates them
+// into the ActiveCluster topology of each pod
+func (h *MetadataConnection) GetPods(query resources.ArrayQuery) (BulkResponse, error) {
+	arrays, err := h.DAO.FindArrays(&query)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	arrayIDs := []string{}
+	for _, array := range arrays {
+		arrayIDs = append(arrayIDs, array.InternalID)
+	}
+	podMetrics, err := h.Pods.FindLatestPodMetrics(arrayIDs)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	podMaps := []map[string]interface{}{}
+	for _, topology := range metrics.BuildPodTopologies(podMetrics) {
+		podMaps = append(podMaps, topology.ConvertToPodTopologyMap())
+	}
+
+	return BulkResponse{Response: podMaps}, nil
+}
+
 // PostArray registers a new array to the given database
 func (h *MetadataConnection) PostArray(m map[string]interface{}) (map[string]interface{}, error) {
 	parsed, err := resources.ParseArrayFromREST(m)
@@ -97,11 +224,31 @@ func (h *MetadataConnection) PostArray(m map[string]interface{}) (map[string]int
 		return nil, errors.MakeBadRequestHTTPErr(err)
 	}
 
+	err = parsed.ValidateCertificateFields()
+	if err != nil {
+		return nil, errors.MakeBadRequestHTTPErr(err)
+	}
+
+	// Rejects device types nothing is registered for (listing the supported ones), and runs the device's own
+	// checks (such as the FlashArray API client credentials)
+	err = registry.ValidateRegistration(&parsed)
+	if err != nil {
+		return nil, errors.MakeBadRequestHTTPErr(err)
+	}
+
+	// Arrays registered with only API client credentials still get an (empty) token, so they're handled like the rest
 	err = h.Tokens.SaveToken(parsed.InternalID, parsed.APIToken)
 	if err != nil {
 		return nil, errors.MakeInternalHTTPErr(err)
 	}
 
+	if parsed.APIClient.IsSet() {
+		err = h.saveAPIClientCredentials(&parsed)
+		if err != nil {
+			return nil, errors.MakeInternalHTTPErr(err)
+		}
+	}
+
 	err = h.DAO.InsertArray(&parsed)
 	if err != nil {
 		return nil, err
@@ -120,10 +267,19 @@ func (h *MetadataConnection) PatchArrays(query resources.ArrayQuery, m map[strin
 
 	// Apply the patch locally, checking for errors as we do
 	for _, array := range arrays {
+		// Fill in the stored credentials first, so patching some of them keeps the rest
+		err = h.populateAPIClientCredentials(array)
+		if err != nil {
+			return BulkResponse{}, errors.MakeInternalHTTPErr(err)
+		}
 		err = array.ApplyPatch(m)
 		if err != nil {
 			return BulkResponse{}, errors.MakeBadRequestHTTPErr(err)
 		}
+		err = registry.ValidateRegistration(array)
+		if err != nil {
+			return BulkResponse{}, errors.MakeBadRequestHTTPErr(err)
+		}
 		// Copy the patched array over to the new list
 		patchedArrays = append(patchedArrays, array)
 	}
@@ -140,6 +296,12 @@ func (h *MetadataConnection) PatchArrays(query resources.ArrayQuery, m map[strin
 				return BulkResponse{}, errors.MakeInternalHTTPErr(err)
 			}
 		}
+		if array.APIClient.IsSet() {
+			err = h.saveAPIClientCredentials(array)
+			if err != nil {
+				return BulkResponse{}, errors.MakeInternalHTTPErr(err)
+			}
+		}
 
 		newArray, err := h.DAO.PatchArray(array)
 		if err != nil {
@@ -152,6 +314,7 @@ func (h *MetadataConnection) PatchArrays(query resources.ArrayQuery, m map[strin
 		}
 
 		newArray.APIToken = fetchedToken
+		newArray.APIClient = array.APIClient
 		responses = append(responses, newArray.ConvertToArrayMap())
 	}
 
@@ -207,6 +370,13 @@ func (h *MetadataConnection) DeleteArrays(query resources.ArrayQuery) (int, erro
 				"array_id": id,
 			}).Error("Error deleting token for device: continuing to delete the rest of the tokens, but this call to DeleteArrays will fail at the end")
 		}
+		err = h.APIClients.DeleteToken(id)
+		if err != nil {
+			lastTokenErr = err
+			log.WithError(err).WithFields(log.Fields{
+				"array_id": id,
+			}).Error("Error deleting API client credentials for device: continuing to delete the rest of the tokens, but this call to DeleteArrays will fail at the end")
+		}
 	}
 
 	if lastTokenErr != nil {
@@ -244,3 +414,447 @@ func (h *MetadataConnection) DeleteArrayTags(query resources.ArrayQuery, tags []
 
 	return BulkResponse{Response: responses}, nil
 }
+
+// GetAlertRules fetches the alert rules with the given IDs, or every alert rule if no IDs are given
+func (h *MetadataConnection) GetAlertRules(ids []string) (BulkResponse, error) {
+	rules, err := h.AlertRules.FindAlertRules(ids)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	ruleMaps := []map[string]interface{}{}
+	for _, rule := range rules {
+		ruleMaps = append(ruleMaps, rule.ConvertToAlertRuleMap())
+	}
+
+	return BulkResponse{Response: ruleMaps}, nil
+}
+
+// PostAlertRule creates a new alert rule in the given database
+func (h *MetadataConnection) PostAlertRule(m map[string]interface{}) (map[string]interface{}, error) {
+	rule, err := resources.NewAlertRuleFromREST(m)
+	if err != nil {
+		return nil, errors.MakeBadRequestHTTPErr(err)
+	}
+
+	rule.InternalID = bson.NewObjectId().Hex()
+	rule.LastUpdated = time.Now().UTC()
+
+	err = rule.Validate()
+	if err != nil {
+		return nil, errors.MakeBadRequestHTTPErr(err)
+	}
+
+	err = h.AlertRules.InsertAlertRule(rule)
+	if err != nil {
+		return nil, err
+	}
+	return rule.ConvertToAlertRuleMap(), nil
+}
+
+// PatchAlertRules updates the alert rules with the given IDs
+func (h *MetadataConnection) PatchAlertRules(ids []string, m map[string]interface{}) (BulkResponse, error) {
+	if _, ok := m["id"]; ok {
+		return BulkResponse{}, errors.MakeBadRequestHTTPErr(fmt.Errorf("Key id cannot be changed"))
+	}
+
+	rules, err := h.AlertRules.FindAlertRules(ids)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	// Apply the patch locally, checking for errors as we do
+	for _, rule := range rules {
+		err = rule.ApplyPatch(m)
+		if err != nil {
+			return BulkResponse{}, errors.MakeBadRequestHTTPErr(err)
+		}
+		err = rule.Validate()
+		if err != nil {
+			return BulkResponse{}, errors.MakeBadRequestHTTPErr(err)
+		}
+		rule.LastUpdated = time.Now().UTC()
+	}
+
+	responses := []map[string]interface{}{}
+
+	// Push the patched rules to the backing database
+	for _, rule := range rules {
+		err = h.AlertRules.UpdateAlertRule(rule)
+		if err != nil {
+			return BulkResponse{}, err
+		}
+		responses = append(responses, rule.ConvertToAlertRuleMap())
+	}
+
+	return BulkResponse{Response: responses}, nil
+}
+
+// DeleteAlertRules deletes the alert rules with the given IDs, and returns the count of rules deleted
+func (h *MetadataConnection) DeleteAlertRules(ids []string) (int, error) {
+	deleted, err := h.AlertRules.DeleteAlertRules(ids)
+	if err != nil {
+		return 0, err
+	}
+	return len(deleted), nil
+}
+
+// GetMaintenanceWindows fetches the maintenance windows with the given IDs, or every window if no IDs are given
+func (h *MetadataConnection) GetMaintenanceWindows(ids []string) (BulkResponse, error) {
+	windows, err := h.MaintenanceWindows.FindMaintenanceWindows(ids)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	windowMaps := []map[string]interface{}{}
+	for _, window := range windows {
+		windowMaps = append(windowMaps, window.ConvertToMaintenanceWindowMap())
+	}
+
+	return BulkResponse{Response: windowMaps}, nil
+}
+
+// PostMaintenanceWindow creates a new maintenance window in the given database
+func (h *MetadataConnection) PostMaintenanceWindow(m map[string]interface{}) (map[string]interface{}, error) {
+	window, err := resources.NewMaintenanceWindowFromREST(m)
+	if err != nil {
+		return nil, errors.MakeBadRequestHTTPErr(err)
+	}
+
+	window.InternalID = bson.NewObjectId().Hex()
+	window.LastUpdated = time.Now().UTC()
+
+	err = window.Validate()
+	if err != nil {
+		return nil, errors.MakeBadRequestHTTPErr(err)
+	}
+
+	err = h.MaintenanceWindows.InsertMaintenanceWindow(window)
+	if err != nil {
+		return nil, err
+	}
+	return window.ConvertToMaintenanceWindowMap(), nil
+}
+
+// PatchMaintenanceWindows updates the maintenance windows with the given IDs
+func (h *MetadataConnection) PatchMaintenanceWindows(ids []string, m map[string]interface{}) (BulkResponse, error) {
+	if _, ok := m["id"]; ok {
+		return BulkResponse{}, errors.MakeBadRequestHTTPErr(fmt.Errorf("Key id cannot be changed"))
+	}
+
+	windows, err := h.MaintenanceWindows.FindMaintenanceWindows(ids)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	// Apply the patch locally, checking for errors as we do
+	for _, window := range windows {
+		err = window.ApplyPatch(m)
+		if err != nil {
+			return BulkResponse{}, errors.MakeBadRequestHTTPErr(err)
+		}
+		err = window.Validate()
+		if err != nil {
+			return BulkResponse{}, errors.MakeBadRequestHTTPErr(err)
+		}
+		window.LastUpdated = time.Now().UTC()
+	}
+
+	responses := []map[string]interface{}{}
+
+	// Push the patched windows to the backing database
+	for _, window := range windows {
+		err = h.MaintenanceWindows.UpdateMaintenanceWindow(window)
+		if err != nil {
+			return BulkResponse{}, err
+		}
+		responses = append(responses, window.ConvertToMaintenanceWindowMap())
+	}
+
+	return BulkResponse{Response: responses}, nil
+}
+
+// DeleteMaintenanceWindows deletes the maintenance windows with the given IDs, and returns the count of windows deleted
+func (h *MetadataConnection) DeleteMaintenanceWindows(ids []string) (int, error) {
+	deleted, err := h.MaintenanceWindows.DeleteMaintenanceWindows(ids)
+	if err != nil {
+		return 0, err
+	}
+	return len(deleted), nil
+}
+
+// GetAlerts fetches the alerts that match the given query
+func (h *MetadataConnection) GetAlerts(query *resources.AlertQuery) (BulkResponse, error) {
+	alerts, err := h.Alerts.FindAlerts(query)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	alertMaps := []map[string]interface{}{}
+	for _, alert := range alerts {
+		alertMaps = append(alertMaps, resources.ConvertToAlertMap(alert))
+	}
+
+	return BulkResponse{Response: alertMaps}, nil
+}
+
+// PatchAlerts updates the lifecycle of the alerts with the given IDs on behalf of the given user
+func (h *MetadataConnection) PatchAlerts(ids []string, m map[string]interface{}, user string) (BulkResponse, error) {
+	// The patch is applied to the stored alerts as they're written, so that it isn't applied to a stale copy
+	now := time.Now()
+	alerts, err := h.Alerts.PatchAlertLifecycles(ids, func(alert *metrics.Alert) error {
+		err := resources.ApplyAlertLifecyclePatch(alert, m, user, now)
+		if err != nil {
+			return errors.MakeBadRequestHTTPErr(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	responses := []map[string]interface{}{}
+	for _, alert := range alerts {
+		responses = append(responses, resources.ConvertToAlertMap(alert))
+	}
+	return BulkResponse{Response: responses}, nil
+}
+
+// GetAuditEvents fetches the audit events that match the given query, most recent first
+func (h *MetadataConnection) GetAuditEvents(query *resources.AuditQuery) (BulkResponse, error) {
+	events, err := h.Audit.FindAuditEvents(query)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	eventMaps := []map[string]interface{}{}
+	for _, event := range events {
+		eventMaps = append(eventMaps, resources.ConvertToAuditEventMap(event))
+	}
+
+	return BulkResponse{Response: eventMaps}, nil
+}
+
+// GetSnapshots fetches the snapshots that match the given query, oldest first, along with how many whole days
+// old each one is
+func (h *MetadataConnection) GetSnapshots(query *resources.SnapshotQuery) (BulkResponse, error) {
+	snapshots, err := h.Snapshots.FindSnapshots(query)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	now := time.Now().Unix()
+	snapshotMaps := []map[string]interface{}{}
+	for _, snapshot := range snapshots {
+		snapshotMap := resources.ConvertToSnapshotMap(snapshot)
+		snapshotMap["age_days"] = (now - snapshot.Created) / (24 * 60 * 60)
+		snapshotMaps = append(snapshotMaps, snapshotMap)
+	}
+
+	return BulkResponse{Response: snapshotMaps}, nil
+}
+
+// GetSNMPDestinations fetches the SNMP destinations with the given IDs, or every destination if no IDs are given
+func (h *MetadataConnection) GetSNMPDestinations(ids []string) (BulkResponse, error) {
+	destinations, err := h.SNMPDestinations.FindSNMPDestinations(ids)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	destinationMaps := []map[string]interface{}{}
+	for _, destination := range destinations {
+		err = h.populateSNMPCredentials(destination)
+		if err != nil {
+			return BulkResponse{}, errors.MakeInternalHTTPErr(err)
+		}
+		destinationMaps = append(destinationMaps, destination.ConvertToSNMPDestinationMap())
+	}
+
+	return BulkResponse{Response: destinationMaps}, nil
+}
+
+// PostSNMPDestination creates a new SNMP destination in the given database, with its credentials kept in the credential store
+func (h *MetadataConnection) PostSNMPDestination(m map[string]interface{}) (map[string]interface{}, error) {
+	destination, err := resources.NewSNMPDestinationFromREST(m)
+	if err != nil {
+		return nil, errors.MakeBadRequestHTTPErr(err)
+	}
+
+	destination.InternalID = bson.NewObjectId().Hex()
+	destination.LastUpdated = time.Now().UTC()
+	if len(destination.EngineID) == 0 {
+		// Receivers need to be configured with the engine ID we send with, so generate one up front
+		destination.EngineID = resources.NewSNMPEngineID()
+	}
+
+	err = destination.Validate()
+	if err != nil {
+		return nil, errors.MakeBadRequestHTTPErr(err)
+	}
+
+	err = h.saveSNMPCredentials(destination)
+	if err != nil {
+		return nil, errors.MakeInternalHTTPErr(err)
+	}
+
+	err = h.SNMPDestinations.InsertSNMPDestination(destination)
+	if err != nil {
+		return nil, err
+	}
+	return destination.ConvertToSNMPDestinationMap(), nil
+}
+
+// PatchSNMPDestinations updates the SNMP destinations with the given IDs
+func (h *MetadataConnection) PatchSNMPDestinations(ids []string, m map[string]interface{}) (BulkResponse, error) {
+	if _, ok := m["id"]; ok {
+		return BulkResponse{}, errors.MakeBadRequestHTTPErr(fmt.Errorf("Key id cannot be changed"))
+	}
+
+	destinations, err := h.SNMPDestinations.FindSNMPDestinations(ids)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	// Apply the patch locally, checking for errors as we do
+	for _, destination := range destinations {
+		err = h.populateSNMPCredentials(destination)
+		if err != nil {
+			return BulkResponse{}, errors.MakeInternalHTTPErr(err)
+		}
+		err = destination.ApplyPatch(m)
+		if err != nil {
+			return BulkResponse{}, errors.MakeBadRequestHTTPErr(err)
+		}
+		err = destination.Validate()
+		if err != nil {
+			return BulkResponse{}, errors.MakeBadRequestHTTPErr(err)
+		}
+		destination.LastUpdated = time.Now().UTC()
+	}
+
+	responses := []map[string]interface{}{}
+
+	// Push the patched destinations to the backing database
+	for _, destination := range destinations {
+		err = h.saveSNMPCredentials(destination)
+		if err != nil {
+			return BulkResponse{}, errors.MakeInternalHTTPErr(err)
+		}
+		err = h.SNMPDestinations.UpdateSNMPDestination(destination)
+		if err != nil {
+			return BulkResponse{}, err
+		}
+		responses = append(responses, destination.ConvertToSNMPDestinationMap())
+	}
+
+	return BulkResponse{Response: responses}, nil
+}
+
+// DeleteSNMPDestinations deletes the SNMP destinations with the given IDs along with their credentials, and
+// returns the count of destinations deleted
+func (h *MetadataConnection) DeleteSNMPDestinations(ids []string) (int, error) {
+	deleted, err := h.SNMPDestinations.DeleteSNMPDestinations(ids)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range deleted {
+		err = h.SNMPCredentials.DeleteToken(id)
+		if err != nil {
+			log.WithError(err).WithField("destination_id", id).Warn("Error deleting SNMP destination credentials")
+		}
+	}
+	return len(deleted), nil
+}
+
+// TestSNMPDestinations sends a test trap to each of the SNMP destinations with the given IDs (whether they're
+// enabled or not), and reports which of them it could be sent to
+func (h *MetadataConnection) TestSNMPDestinations(ids []string) (BulkResponse, error) {
+	destinations, err := h.SNMPDestinations.FindSNMPDestinations(ids)
+	if err != nil {
+		return BulkResponse{}, err
+	}
+
+	responses := []map[string]interface{}{}
+	for _, destination := range destinations {
+		err = h.populateSNMPCredentials(destination)
+		if err != nil {
+			return BulkResponse{}, errors.MakeInternalHTTPErr(err)
+		}
+
+		result := map[string]interface{}{
+			"id":    destination.InternalID,
+			"name":  destination.Name,
+			"sent":  true,
+			"error": "",
+		}
+		err = h.SNMPTestTraps.SendTestTrap(destination)
+		if err != nil {
+			log.WithError(err).WithField("destination_id", destination.InternalID).Warn("Error sending SNMP test trap")
+			result["sent"] = false
+			result["error"] = err.Error()
+		}
+		responses = append(responses, result)
+	}
+
+	return BulkResponse{Response: responses}, nil
+}
+
+// populateAPIClientCredentials is a helper function that fills in the given array's API client credentials from the
+// credential store, if it has any
+func (h *MetadataConnection) populateAPIClientCredentials(array *resources.Array) error {
+	hasCredentials, err := h.APIClients.HasToken(array.InternalID)
+	if err != nil || !hasCredentials {
+		return err
+	}
+	encoded, err := h.APIClients.GetToken(array.InternalID)
+	if err != nil {
+		return err
+	}
+
+	credentials := resources.APIClientCredentials{}
+	err = json.Unmarshal([]byte(encoded), &credentials)
+	if err != nil {
+		return fmt.Errorf("Error parsing API client credentials of array %s: %v", array.InternalID, err)
+	}
+	array.APIClient = credentials
+	return nil
+}
+
+// saveAPIClientCredentials is a helper function that stores the given array's API client credentials in the credential store
+func (h *MetadataConnection) saveAPIClientCredentials(array *resources.Array) error {
+	encoded, err := json.Marshal(array.APIClient)
+	if err != nil {
+		return err
+	}
+	return h.APIClients.SaveToken(array.InternalID, string(encoded))
+}
+
+// populateSNMPCredentials is a helper function that fills in the given destination's credentials from the credential store
+func (h *MetadataConnection) populateSNMPCredentials(destination *resources.SNMPDestination) error {
+	hasCredentials, err := h.SNMPCredentials.HasToken(destination.InternalID)
+	if err != nil || !hasCredentials {
+		return err
+	}
+	encoded, err := h.SNMPCredentials.GetToken(destination.InternalID)
+	if err != nil {
+		return err
+	}
+
+	credentials := resources.SNMPCredentials{}
+	err = json.Unmarshal([]byte(encoded), &credentials)
+	if err != nil {
+		return fmt.Errorf("Error parsing credentials of SNMP destination %s: %v", destination.InternalID, err)
+	}
+	destination.SetCredentials(credentials)
+	return nil
+}
+
+// saveSNMPCredentials is a helper function that stores the given destination's credentials in the credential store
+func (h *MetadataConnection) saveSNMPCredentials(destination *resources.SNMPDestination) error {
+	encoded, err := json.Marshal(destination.GetCredentials())
+	if err != nil {
+		return err
+	}
+	return h.SNMPCredentials.SaveToken(destination.InternalID, string(encoded))
+}
@@ -15,15 +15,25 @@
 package db
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/memory"
 	clientmock "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/mock"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common"
 	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources"
+	"github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/common/resources/metrics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+
+	// Registers the device types arrays can be registered with
+	_ "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flasharray"
+	_ "github.com/PureStorage-OpenConnect/pure1-unplugged/pkg/clients/array/flashblade"
 )
 
 var (
@@ -34,7 +44,7 @@ func TestGetArrays(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 	tokenStorage := clientmock.APITokenStorageImpl{}
 
-	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage}
+	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage, APIClients: memory.NewInMemoryTokenStorage()}
 
 	lastSeenTime := time.Unix(1000, 0).UTC()
 
@@ -137,6 +147,174 @@ func TestGetArrayStatusesError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestGetCapacityForecasts(t *testing.T) {
+	mockImpl := clientmock.ArrayDatabaseImpl{}
+	forecastImpl := clientmock.CapacityForecastDatabaseImpl{}
+
+	handler := MetadataConnection{DAO: &mockImpl, Forecasts: &forecastImpl}
+
+	arrays := []*resources.Array{
+		&resources.Array{InternalID: "aaaa", Name: "test_dev1", DeviceType: common.FlashArray, Tags: []map[string]string{
+			map[string]string{
+				"key":       "site",
+				"value":     "east",
+				"namespace": "test_ns",
+			},
+		}},
+		&resources.Array{InternalID: "aaab", Name: "test_dev2", DeviceType: common.FlashBlade, Tags: []map[string]string{
+			map[string]string{
+				"key":       "site",
+				"value":     "east",
+				"namespace": "test_ns",
+			},
+			map[string]string{
+				"key":       "owner",
+				"value":     "finance",
+				"namespace": "another_ns",
+			},
+		}},
+		// Not forecast yet
+		&resources.Array{InternalID: "aaac", Name: "test_dev3", DeviceType: common.FlashArray},
+	}
+	forecasts := []*metrics.CapacityForecast{
+		{ArrayID: "aaaa", UsedSpace: 100, TotalSpace: 1000, DailyGrowth: 10, Projections: []*metrics.CapacityProjection{}},
+		{ArrayID: "aaab", UsedSpace: 300, TotalSpace: 1000, DailyGrowth: 5, Projections: []*metrics.CapacityProjection{}},
+	}
+
+	mockImpl.On("FindArrays", &emptyQuery).Return(arrays, nil)
+	forecastImpl.On("FindCapacityForecasts", []string{"aaaa", "aaab", "aaac"}).Return(forecasts, nil)
+
+	res, err := handler.GetCapacityForecasts(emptyQuery)
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 2)
+	assert.Equal(t, "aaaa", res.Response[0]["id"])
+	assert.Equal(t, "test_dev1", res.Response[0]["name"])
+	assert.Equal(t, common.FlashArray, res.Response[0]["device_type"])
+	assert.Equal(t, float64(100), res.Response[0]["used_space"])
+	assert.Equal(t, "aaab", res.Response[1]["id"])
+
+	// Sorted by namespace
+	assert.Len(t, res.Tags, 2)
+	assert.Equal(t, "another_ns", res.Tags[0]["namespace"])
+	assert.Equal(t, []string{"aaab"}, res.Tags[0]["array_ids"])
+	assert.Equal(t, "test_ns", res.Tags[1]["namespace"])
+	assert.Equal(t, "site", res.Tags[1]["key"])
+	assert.Equal(t, "east", res.Tags[1]["value"])
+	assert.Equal(t, []string{"aaaa", "aaab"}, res.Tags[1]["array_ids"])
+	assert.Equal(t, float64(400), res.Tags[1]["used_space"])
+	assert.Equal(t, float64(2000), res.Tags[1]["total_space"])
+	assert.Equal(t, float64(15), res.Tags[1]["daily_growth"])
+}
+
+func TestGetCapacityForecastsError(t *testing.T) {
+	mockImpl := clientmock.ArrayDatabaseImpl{}
+	forecastImpl := clientmock.CapacityForecastDatabaseImpl{}
+
+	handler := MetadataConnection{DAO: &mockImpl, Forecasts: &forecastImpl}
+
+	mockImpl.On("FindArrays", &emptyQuery).Return([]*resources.Array{&resources.Array{InternalID: "aaaa"}}, nil)
+	forecastImpl.On("FindCapacityForecasts", []string{"aaaa"}).Return([]*metrics.CapacityForecast{}, fmt.Errorf("Some error"))
+
+	_, err := handler.GetCapacityForecasts(emptyQuery)
+	assert.Error(t, err)
+}
+
+func TestGetPods(t *testing.T) {
+	mockImpl := clientmock.ArrayDatabaseImpl{}
+	podImpl := clientmock.PodStatusDatabaseImpl{}
+
+	handler := MetadataConnection{DAO: &mockImpl, Pods: &podImpl}
+
+	arrays := []*resources.Array{
+		&resources.Array{InternalID: "aaaa", Name: "test_dev1", DeviceType: common.FlashArray},
+		&resources.Array{InternalID: "aaab", Name: "test_dev2", DeviceType: common.FlashArray},
+	}
+	members := []*metrics.PodMember{
+		{ArrayID: "purity-1", ArrayName: "array-1", Status: metrics.PodStatusOnline, MediatorStatus: metrics.MediatorStatusOnline},
+		{ArrayID: "purity-2", ArrayName: "array-2", Status: metrics.PodStatusOnline, MediatorStatus: metrics.MediatorStatusUnreachable},
+	}
+	podMetrics := []*metrics.PodMetric{
+		{ArrayID: "aaaa", ArrayDisplayName: "test_dev1", PurityID: "purity-1", PodName: "pod1", Status: metrics.PodStatusOnline, MediatorStatus: metrics.MediatorStatusOnline, Members: members},
+		{ArrayID: "aaab", ArrayDisplayName: "test_dev2", PurityID: "purity-2", PodName: "pod1", Status: metrics.PodStatusOnline, MediatorStatus: metrics.MediatorStatusUnreachable, Members: members},
+	}
+
+	mockImpl.On("FindArrays", &emptyQuery).Return(arrays, nil)
+	podImpl.On("FindLatestPodMetrics", []string{"aaaa", "aaab"}).Return(podMetrics, nil)
+
+	res, err := handler.GetPods(emptyQuery)
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 1)
+	assert.Equal(t, "pod1", res.Response[0]["name"])
+	assert.Equal(t, true, res.Response[0]["stretched"])
+	assert.Equal(t, true, res.Response[0]["mediator_outage"])
+	assert.Equal(t, true, res.Response[0]["split_brain_risk"])
+	podMembers := res.Response[0]["members"].([]map[string]interface{})
+	assert.Len(t, podMembers, 2)
+	assert.Equal(t, "purity-1", podMembers[0]["array_id"])
+	assert.Equal(t, "aaaa", podMembers[0]["registered_id"])
+	assert.Equal(t, "test_dev1", podMembers[0]["array_display_name"])
+	assert.Equal(t, "purity-2", podMembers[1]["array_id"])
+	assert.Equal(t, "aaab", podMembers[1]["registered_id"])
+}
+
+func TestGetPodsError(t *testing.T) {
+	mockImpl := clientmock.ArrayDatabaseImpl{}
+	podImpl := clientmock.PodStatusDatabaseImpl{}
+
+	handler := MetadataConnection{DAO: &mockImpl, Pods: &podImpl}
+
+	mockImpl.On("FindArrays", &emptyQuery).Return([]*resources.Array{&resources.Array{InternalID: "aaaa"}}, nil)
+	podImpl.On("FindLatestPodMetrics", []string{"aaaa"}).Return([]*metrics.PodMetric{}, fmt.Errorf("Some error"))
+
+	_, err := handler.GetPods(emptyQuery)
+	assert.Error(t, err)
+}
+
+func TestGetArrayHardware(t *testing.T) {
+	mockImpl := clientmock.ArrayDatabaseImpl{}
+	hardwareImpl := clientmock.HardwareStatusDatabaseImpl{}
+
+	handler := MetadataConnection{DAO: &mockImpl, Hardware: &hardwareImpl}
+
+	arrays := []*resources.Array{
+		&resources.Array{InternalID: "aaaa", Name: "test_dev1", DeviceType: common.FlashArray},
+		&resources.Array{InternalID: "aaab", Name: "test_dev2", DeviceType: common.FlashBlade},
+	}
+	hardwareMetrics := []*metrics.HardwareMetric{
+		{ArrayID: "aaaa", ArrayName: "array-1", ComponentName: "CT0", ComponentType: metrics.HardwareComponentController, Status: "ready", Healthy: true},
+		{ArrayID: "aaaa", ArrayName: "array-1", ComponentName: "CH0.PWR0", ComponentType: metrics.HardwareComponentPowerSupply, Status: "critical", Healthy: false},
+		{ArrayID: "aaab", ArrayName: "blade-1", ComponentName: "CH1.FB1", ComponentType: metrics.HardwareComponentBlade, Status: "healthy", Healthy: true},
+	}
+
+	mockImpl.On("FindArrays", &emptyQuery).Return(arrays, nil)
+	hardwareImpl.On("FindLatestHardwareMetrics", []string{"aaaa", "aaab"}).Return(hardwareMetrics, nil)
+
+	res, err := handler.GetArrayHardware(emptyQuery)
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 2)
+	assert.Equal(t, "aaaa", res.Response[0]["array_id"])
+	assert.Equal(t, common.FlashArray, res.Response[0]["device_type"])
+	assert.Equal(t, false, res.Response[0]["healthy"])
+	assert.Equal(t, 1, res.Response[0]["unhealthy_count"])
+	assert.Len(t, res.Response[0]["components"], 2)
+	assert.Equal(t, "aaab", res.Response[1]["array_id"])
+	assert.Equal(t, common.FlashBlade, res.Response[1]["device_type"])
+	assert.Equal(t, true, res.Response[1]["healthy"])
+}
+
+func TestGetArrayHardwareError(t *testing.T) {
+	mockImpl := clientmock.ArrayDatabaseImpl{}
+	hardwareImpl := clientmock.HardwareStatusDatabaseImpl{}
+
+	handler := MetadataConnection{DAO: &mockImpl, Hardware: &hardwareImpl}
+
+	mockImpl.On("FindArrays", &emptyQuery).Return([]*resources.Array{&resources.Array{InternalID: "aaaa"}}, nil)
+	hardwareImpl.On("FindLatestHardwareMetrics", []string{"aaaa"}).Return([]*metrics.HardwareMetric{}, fmt.Errorf("Some error"))
+
+	_, err := handler.GetArrayHardware(emptyQuery)
+	assert.Error(t, err)
+}
+
 func TestGetArrayTags(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 
@@ -216,7 +394,7 @@ func TestPostArray(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 	tokenStorage := clientmock.APITokenStorageImpl{}
 
-	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage}
+	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage, APIClients: memory.NewInMemoryTokenStorage()}
 
 	mockImpl.On("InsertArray", mock.AnythingOfType("*resources.Array")).Return(nil)
 	tokenStorage.On("SaveToken", mock.AnythingOfType("string"), "asdf").Return(nil)
@@ -236,6 +414,66 @@ func TestPostArray(t *testing.T) {
 	assert.NotEqual(t, time.Time{}, res["_last_updated"])
 }
 
+func TestPostArrayAPIClient(t *testing.T) {
+	mockImpl := clientmock.ArrayDatabaseImpl{}
+	tokenStorage := clientmock.APITokenStorageImpl{}
+	apiClientStorage := memory.NewInMemoryTokenStorage()
+
+	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage, APIClients: apiClientStorage}
+
+	mockImpl.On("InsertArray", mock.AnythingOfType("*resources.Array")).Return(nil)
+	tokenStorage.On("SaveToken", mock.AnythingOfType("string"), "").Return(nil)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	res, err := handler.PostArray(map[string]interface{}{
+		"name":                   "test_dev1",
+		"mgmt_endpoint":          "192.168.99.100",
+		"device_type":            common.FlashArray,
+		"api_client_id":          "client",
+		"api_client_key_id":      "key",
+		"api_client_private_key": privateKey,
+		"api_client_issuer":      "pure1-unplugged",
+		"api_client_username":    "pureuser",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "client", res["api_client_id"])
+	assert.Equal(t, "pureuser", res["api_client_username"])
+
+	// The credentials should be stored together, keyed by the new array ID
+	stored, err := apiClientStorage.GetToken(res["id"].(string))
+	assert.NoError(t, err)
+	assert.Contains(t, stored, `"api_client_key_id":"key"`)
+}
+
+func TestPostArrayIncompleteAPIClient(t *testing.T) {
+	handler := MetadataConnection{}
+
+	_, err := handler.PostArray(map[string]interface{}{
+		"name":          "test_dev1",
+		"mgmt_endpoint": "192.168.99.100",
+		"device_type":   common.FlashArray,
+		"api_client_id": "client",
+	})
+	assert.Error(t, err)
+}
+
+func TestPostArrayFlashBladeAPIClient(t *testing.T) {
+	handler := MetadataConnection{}
+
+	_, err := handler.PostArray(map[string]interface{}{
+		"name":                "test_dev1",
+		"mgmt_endpoint":       "192.168.99.100",
+		"device_type":         common.FlashBlade,
+		"api_client_id":       "client",
+		"api_client_username": "pureuser",
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported for FlashBlade")
+}
+
 func TestPostArrayBadParse(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 
@@ -265,11 +503,24 @@ func TestPostArrayMissingField(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestPostArrayUnknownDeviceType(t *testing.T) {
+	handler := MetadataConnection{}
+
+	_, err := handler.PostArray(map[string]interface{}{
+		"name":          "test_dev1",
+		"mgmt_endpoint": "192.168.99.100",
+		"device_type":   "FlashStack",
+		"api_token":     "asdf",
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "FlashArray, FlashBlade")
+}
+
 func TestPostArrayInsertError(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 	tokenStorage := clientmock.APITokenStorageImpl{}
 
-	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage}
+	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage, APIClients: memory.NewInMemoryTokenStorage()}
 
 	tokenStorage.On("SaveToken", mock.AnythingOfType("string"), "asdf").Return(nil)
 	mockImpl.On("InsertArray", mock.AnythingOfType("*resources.Array")).Return(fmt.Errorf("Some error"))
@@ -287,7 +538,7 @@ func TestPatchArray(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 	tokenStorage := clientmock.APITokenStorageImpl{}
 
-	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage}
+	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage, APIClients: memory.NewInMemoryTokenStorage()}
 
 	fetchedArrays := []*resources.Array{
 		&resources.Array{
@@ -343,7 +594,7 @@ func TestPatchArrayErrorPatching(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 	tokenStorage := clientmock.APITokenStorageImpl{}
 
-	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage}
+	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage, APIClients: memory.NewInMemoryTokenStorage()}
 
 	fetchedArrays := []*resources.Array{
 		&resources.Array{
@@ -367,7 +618,7 @@ func TestPatchArrayPushError(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 	tokenStorage := clientmock.APITokenStorageImpl{}
 
-	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage}
+	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage, APIClients: memory.NewInMemoryTokenStorage()}
 
 	fetchedArrays := []*resources.Array{
 		&resources.Array{
@@ -401,7 +652,7 @@ func TestPatchArrayTokenSaveError(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 	tokenStorage := clientmock.APITokenStorageImpl{}
 
-	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage}
+	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage, APIClients: memory.NewInMemoryTokenStorage()}
 
 	fetchedArrays := []*resources.Array{
 		&resources.Array{
@@ -438,7 +689,7 @@ func TestPatchArrayTokenGetError(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 	tokenStorage := clientmock.APITokenStorageImpl{}
 
-	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage}
+	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage, APIClients: memory.NewInMemoryTokenStorage()}
 
 	fetchedArrays := []*resources.Array{
 		&resources.Array{
@@ -597,7 +848,7 @@ func TestDeleteArrays(t *testing.T) {
 	mockImpl := clientmock.ArrayDatabaseImpl{}
 	tokenStorage := clientmock.APITokenStorageImpl{}
 
-	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage}
+	handler := MetadataConnection{DAO: &mockImpl, Tokens: &tokenStorage, APIClients: memory.NewInMemoryTokenStorage()}
 
 	mockImpl.On("DeleteArray", &emptyQuery).Return([]string{"dev1"}, nil)
 	tokenStorage.On("DeleteToken", "dev1").Return(nil)
@@ -696,3 +947,535 @@ func TestDeleteArrayTagsPatchError(t *testing.T) {
 	_, err := handler.DeleteArrayTags(emptyQuery, []string{"test_key"})
 	assert.Error(t, err)
 }
+
+func TestGetAlertRules(t *testing.T) {
+	mockImpl := clientmock.AlertRuleDatabaseImpl{}
+
+	handler := MetadataConnection{AlertRules: &mockImpl}
+
+	rules := []*resources.AlertRule{
+		&resources.AlertRule{InternalID: "aaaa", Name: "Nearly full", Resource: resources.AlertRuleArrayResource, Metric: "PercentFull", Operator: ">", Threshold: 0.85, DurationSeconds: 900},
+	}
+	mockImpl.On("FindAlertRules", []string{}).Return(rules, nil)
+
+	res, err := handler.GetAlertRules([]string{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 1)
+	assert.Equal(t, "aaaa", res.Response[0]["id"])
+	assert.Equal(t, "Nearly full", res.Response[0]["name"])
+	assert.Equal(t, 0.85, res.Response[0]["threshold"])
+	assert.Equal(t, "15m0s", res.Response[0]["duration"])
+}
+
+func TestGetAlertRulesError(t *testing.T) {
+	mockImpl := clientmock.AlertRuleDatabaseImpl{}
+
+	handler := MetadataConnection{AlertRules: &mockImpl}
+
+	mockImpl.On("FindAlertRules", []string{}).Return([]*resources.AlertRule{}, fmt.Errorf("Some error"))
+
+	_, err := handler.GetAlertRules([]string{})
+	assert.Error(t, err)
+}
+
+func TestPostAlertRule(t *testing.T) {
+	mockImpl := clientmock.AlertRuleDatabaseImpl{}
+
+	handler := MetadataConnection{AlertRules: &mockImpl}
+
+	mockImpl.On("InsertAlertRule", mock.AnythingOfType("*resources.AlertRule")).Return(nil)
+
+	res, err := handler.PostAlertRule(map[string]interface{}{
+		"name":       "Nearly full",
+		"resource":   "array",
+		"metric":     "PercentFull",
+		"operator":   ">",
+		"threshold":  0.85,
+		"duration":   "15m",
+		"array_tags": map[string]interface{}{"env": "prod"},
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res["id"])
+	assert.Equal(t, true, res["enabled"])
+	assert.Equal(t, "warning", res["severity"])
+	assert.Equal(t, map[string]string{"env": "prod"}, res["array_tags"])
+	assert.NotEqual(t, time.Time{}, res["_last_updated"])
+}
+
+func TestPostAlertRuleInvalid(t *testing.T) {
+	handler := MetadataConnection{}
+
+	_, err := handler.PostAlertRule(map[string]interface{}{
+		"name":      "Nearly full",
+		"resource":  "array",
+		"metric":    "NotAMetric",
+		"operator":  ">",
+		"threshold": 0.85,
+	})
+	assert.Error(t, err)
+}
+
+func TestPostAlertRuleInsertError(t *testing.T) {
+	mockImpl := clientmock.AlertRuleDatabaseImpl{}
+
+	handler := MetadataConnection{AlertRules: &mockImpl}
+
+	mockImpl.On("InsertAlertRule", mock.AnythingOfType("*resources.AlertRule")).Return(fmt.Errorf("Some error"))
+
+	_, err := handler.PostAlertRule(map[string]interface{}{
+		"name":      "Nearly full",
+		"resource":  "array",
+		"metric":    "PercentFull",
+		"operator":  ">",
+		"threshold": 0.85,
+	})
+	assert.Error(t, err)
+}
+
+func TestPatchAlertRules(t *testing.T) {
+	mockImpl := clientmock.AlertRuleDatabaseImpl{}
+
+	handler := MetadataConnection{AlertRules: &mockImpl}
+
+	rules := []*resources.AlertRule{
+		&resources.AlertRule{InternalID: "aaaa", Name: "Nearly full", Enabled: true, Resource: resources.AlertRuleArrayResource, Metric: "PercentFull", Operator: ">", Threshold: 0.85, Severity: "warning"},
+	}
+	mockImpl.On("FindAlertRules", []string{"aaaa"}).Return(rules, nil)
+	mockImpl.On("UpdateAlertRule", rules[0]).Return(nil)
+
+	res, err := handler.PatchAlertRules([]string{"aaaa"}, map[string]interface{}{
+		"enabled":   false,
+		"threshold": 0.9,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 1)
+	assert.Equal(t, false, res.Response[0]["enabled"])
+	assert.Equal(t, 0.9, res.Response[0]["threshold"])
+	mockImpl.AssertCalled(t, "UpdateAlertRule", rules[0])
+}
+
+func TestPatchAlertRulesInvalid(t *testing.T) {
+	mockImpl := clientmock.AlertRuleDatabaseImpl{}
+
+	handler := MetadataConnection{AlertRules: &mockImpl}
+
+	rules := []*resources.AlertRule{
+		&resources.AlertRule{InternalID: "aaaa", Name: "Nearly full", Enabled: true, Resource: resources.AlertRuleArrayResource, Metric: "PercentFull", Operator: ">", Threshold: 0.85, Severity: "warning"},
+	}
+	mockImpl.On("FindAlertRules", []string{"aaaa"}).Return(rules, nil)
+
+	_, err := handler.PatchAlertRules([]string{"aaaa"}, map[string]interface{}{
+		"operator": "==",
+	})
+	assert.Error(t, err)
+	mockImpl.AssertNotCalled(t, "UpdateAlertRule", mock.Anything)
+
+	_, err = handler.PatchAlertRules([]string{"aaaa"}, map[string]interface{}{
+		"id": "000000000000000000000000",
+	})
+	assert.Error(t, err)
+}
+
+func TestDeleteAlertRules(t *testing.T) {
+	mockImpl := clientmock.AlertRuleDatabaseImpl{}
+
+	handler := MetadataConnection{AlertRules: &mockImpl}
+
+	mockImpl.On("DeleteAlertRules", []string{"aaaa", "aaab"}).Return([]string{"aaaa"}, nil)
+
+	count, err := handler.DeleteAlertRules([]string{"aaaa", "aaab"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestGetMaintenanceWindows(t *testing.T) {
+	mockImpl := clientmock.MaintenanceWindowDatabaseImpl{}
+
+	handler := MetadataConnection{MaintenanceWindows: &mockImpl}
+
+	windows := []*resources.MaintenanceWindow{
+		&resources.MaintenanceWindow{InternalID: "aaaa", ArrayIDs: []string{"bbbb"}, Start: 1546441200, End: 1546448400, Reason: "Purity upgrade"},
+	}
+	mockImpl.On("FindMaintenanceWindows", []string{}).Return(windows, nil)
+
+	res, err := handler.GetMaintenanceWindows([]string{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 1)
+	assert.Equal(t, "aaaa", res.Response[0]["id"])
+	assert.Equal(t, time.Unix(1546441200, 0).UTC(), res.Response[0]["start"])
+	assert.Equal(t, map[string]string{}, res.Response[0]["array_tags"])
+}
+
+func TestPostMaintenanceWindow(t *testing.T) {
+	mockImpl := clientmock.MaintenanceWindowDatabaseImpl{}
+
+	handler := MetadataConnection{MaintenanceWindows: &mockImpl}
+
+	mockImpl.On("InsertMaintenanceWindow", mock.AnythingOfType("*resources.MaintenanceWindow")).Return(nil)
+
+	res, err := handler.PostMaintenanceWindow(map[string]interface{}{
+		"array_tags": map[string]interface{}{"site": "east"},
+		"start":      "2019-01-02T15:00:00Z",
+		"end":        "2019-01-02T17:00:00Z",
+		"reason":     "Host migration",
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res["id"])
+	assert.Equal(t, []string{}, res["array_ids"])
+	assert.Equal(t, time.Date(2019, 1, 2, 17, 0, 0, 0, time.UTC), res["end"])
+	assert.NotEqual(t, time.Time{}, res["_last_updated"])
+}
+
+func TestPostMaintenanceWindowInvalid(t *testing.T) {
+	handler := MetadataConnection{}
+
+	_, err := handler.PostMaintenanceWindow(map[string]interface{}{
+		"array_tags": map[string]interface{}{"site": "east"},
+		"start":      "2019-01-02T17:00:00Z",
+		"end":        "2019-01-02T15:00:00Z",
+		"reason":     "Host migration",
+	})
+	assert.Error(t, err)
+}
+
+func TestPatchMaintenanceWindows(t *testing.T) {
+	mockImpl := clientmock.MaintenanceWindowDatabaseImpl{}
+
+	handler := MetadataConnection{MaintenanceWindows: &mockImpl}
+
+	windows := []*resources.MaintenanceWindow{
+		&resources.MaintenanceWindow{InternalID: "aaaa", ArrayIDs: []string{"bbbb"}, Start: 1546441200, End: 1546448400, Reason: "Purity upgrade"},
+	}
+	mockImpl.On("FindMaintenanceWindows", []string{"aaaa"}).Return(windows, nil)
+	mockImpl.On("UpdateMaintenanceWindow", windows[0]).Return(nil)
+
+	res, err := handler.PatchMaintenanceWindows([]string{"aaaa"}, map[string]interface{}{"end": "2019-01-02T18:00:00Z"})
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 1)
+	assert.Equal(t, time.Date(2019, 1, 2, 18, 0, 0, 0, time.UTC), res.Response[0]["end"])
+	assert.Equal(t, "Purity upgrade", res.Response[0]["reason"])
+}
+
+func TestPatchMaintenanceWindowsInvalid(t *testing.T) {
+	mockImpl := clientmock.MaintenanceWindowDatabaseImpl{}
+
+	handler := MetadataConnection{MaintenanceWindows: &mockImpl}
+
+	windows := []*resources.MaintenanceWindow{
+		&resources.MaintenanceWindow{InternalID: "aaaa", ArrayIDs: []string{"bbbb"}, Start: 1546441200, End: 1546448400, Reason: "Purity upgrade"},
+	}
+	mockImpl.On("FindMaintenanceWindows", []string{"aaaa"}).Return(windows, nil)
+
+	// Removing the only selector isn't allowed
+	_, err := handler.PatchMaintenanceWindows([]string{"aaaa"}, map[string]interface{}{"array_ids": []interface{}{}})
+	assert.Error(t, err)
+
+	_, err = handler.PatchMaintenanceWindows([]string{"aaaa"}, map[string]interface{}{"id": "cccc"})
+	assert.Error(t, err)
+	mockImpl.AssertNotCalled(t, "UpdateMaintenanceWindow", mock.Anything)
+}
+
+func TestDeleteMaintenanceWindows(t *testing.T) {
+	mockImpl := clientmock.MaintenanceWindowDatabaseImpl{}
+
+	handler := MetadataConnection{MaintenanceWindows: &mockImpl}
+
+	mockImpl.On("DeleteMaintenanceWindows", []string{"aaaa", "aaab"}).Return([]string{"aaaa"}, nil)
+
+	count, err := handler.DeleteMaintenanceWindows([]string{"aaaa", "aaab"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestGetAlerts(t *testing.T) {
+	mockImpl := clientmock.AlertDatabaseImpl{}
+
+	handler := MetadataConnection{Alerts: &mockImpl}
+
+	query := &resources.AlertQuery{States: []string{"open"}}
+	alerts := []*metrics.Alert{
+		&metrics.Alert{ArrayID: "array-1", AlertID: 1, State: "open"},
+		&metrics.Alert{ArrayID: "array-1", AlertID: 2, State: "open", Lifecycle: &metrics.AlertLifecycle{AssignedTo: "someone@example.com"}},
+	}
+	mockImpl.On("FindAlerts", query).Return(alerts, nil)
+
+	res, err := handler.GetAlerts(query)
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 2)
+	assert.Equal(t, "array-1-alert-1", res.Response[0]["id"])
+	assert.Equal(t, "someone@example.com", res.Response[1]["assigned_to"])
+}
+
+func TestPatchAlerts(t *testing.T) {
+	mockImpl := clientmock.AlertDatabaseImpl{}
+
+	handler := MetadataConnection{Alerts: &mockImpl}
+
+	alerts := []*metrics.Alert{
+		&metrics.Alert{ArrayID: "array-1", AlertID: 1, State: "open"},
+	}
+	mockImpl.On("PatchAlertLifecycles", []string{"array-1-alert-1"}).Return(alerts, nil)
+
+	res, err := handler.PatchAlerts([]string{"array-1-alert-1"}, map[string]interface{}{
+		"acknowledged": true,
+		"note":         "Looking into it",
+	}, "admin@example.com")
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 1)
+	assert.Equal(t, true, res.Response[0]["acknowledged"])
+	assert.Equal(t, "admin@example.com", res.Response[0]["acknowledged_by"])
+	assert.Equal(t, "admin@example.com", alerts[0].Lifecycle.Notes[0].Author)
+}
+
+func TestPatchAlertsInvalid(t *testing.T) {
+	mockImpl := clientmock.AlertDatabaseImpl{}
+
+	handler := MetadataConnection{Alerts: &mockImpl}
+
+	alerts := []*metrics.Alert{
+		&metrics.Alert{ArrayID: "array-1", AlertID: 1, State: "open"},
+	}
+	mockImpl.On("PatchAlertLifecycles", []string{"array-1-alert-1"}).Return(alerts, nil)
+
+	_, err := handler.PatchAlerts([]string{"array-1-alert-1"}, map[string]interface{}{"state": "closed"}, "admin@example.com")
+	assert.Error(t, err)
+	assert.Nil(t, alerts[0].Lifecycle)
+}
+
+func TestGetAuditEvents(t *testing.T) {
+	mockImpl := clientmock.AuditEventDatabaseImpl{}
+
+	handler := MetadataConnection{Audit: &mockImpl}
+
+	query := &resources.AuditQuery{Users: []string{"pureuser"}}
+	events := []*metrics.AuditEvent{
+		&metrics.AuditEvent{ArrayID: "array-1", Kind: metrics.AuditEventCommand, EventID: "12", User: "pureuser", Command: "purevol", Subcommand: "create"},
+		&metrics.AuditEvent{ArrayID: "array-1", Kind: metrics.AuditEventSession, EventID: "3", User: "pureuser", Event: "login"},
+	}
+	mockImpl.On("FindAuditEvents", query).Return(events, nil)
+
+	res, err := handler.GetAuditEvents(query)
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 2)
+	assert.Equal(t, "array-1-command-12", res.Response[0]["id"])
+	assert.Equal(t, "login", res.Response[1]["event"])
+}
+
+func TestGetAuditEventsError(t *testing.T) {
+	mockImpl := clientmock.AuditEventDatabaseImpl{}
+
+	handler := MetadataConnection{Audit: &mockImpl}
+
+	query := &resources.AuditQuery{}
+	mockImpl.On("FindAuditEvents", query).Return([]*metrics.AuditEvent{}, fmt.Errorf("Some error"))
+
+	_, err := handler.GetAuditEvents(query)
+	assert.Error(t, err)
+}
+
+func TestGetSnapshots(t *testing.T) {
+	mockImpl := clientmock.SnapshotInventoryDatabaseImpl{}
+
+	handler := MetadataConnection{Snapshots: &mockImpl}
+
+	created := time.Now().Add(-50 * time.Hour).Unix()
+	query := &resources.SnapshotQuery{ArrayIDs: []string{"array-1"}}
+	snapshots := []*metrics.Snapshot{
+		&metrics.Snapshot{ArrayID: "array-1", Name: "vol1.manual", Source: "vol1", Created: created},
+	}
+	mockImpl.On("FindSnapshots", query).Return(snapshots, nil)
+
+	res, err := handler.GetSnapshots(query)
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 1)
+	assert.Equal(t, "array-1-vol1.manual", res.Response[0]["id"])
+	assert.Equal(t, int64(2), res.Response[0]["age_days"])
+	assert.Equal(t, false, res.Response[0]["managed"])
+}
+
+func TestGetSnapshotsError(t *testing.T) {
+	mockImpl := clientmock.SnapshotInventoryDatabaseImpl{}
+
+	handler := MetadataConnection{Snapshots: &mockImpl}
+
+	query := &resources.SnapshotQuery{}
+	mockImpl.On("FindSnapshots", query).Return([]*metrics.Snapshot{}, fmt.Errorf("Some error"))
+
+	_, err := handler.GetSnapshots(query)
+	assert.Error(t, err)
+}
+
+func TestGetSNMPDestinations(t *testing.T) {
+	mockImpl := clientmock.SNMPDestinationDatabaseImpl{}
+	credentialStorage := clientmock.APITokenStorageImpl{}
+
+	handler := MetadataConnection{SNMPDestinations: &mockImpl, SNMPCredentials: &credentialStorage}
+
+	destinations := []*resources.SNMPDestination{
+		&resources.SNMPDestination{InternalID: "aaaa", Name: "NOC", Enabled: true, Address: "10.0.0.1:162", Version: "v2c"},
+		&resources.SNMPDestination{InternalID: "aaab", Name: "Lab", Enabled: false, Address: "10.0.0.2:162", Version: "v2c"},
+	}
+	mockImpl.On("FindSNMPDestinations", []string{}).Return(destinations, nil)
+	credentialStorage.On("HasToken", "aaaa").Return(true, nil)
+	credentialStorage.On("GetToken", "aaaa").Return(`{"community":"public"}`, nil)
+	credentialStorage.On("HasToken", "aaab").Return(false, nil)
+
+	res, err := handler.GetSNMPDestinations([]string{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 2)
+	assert.Equal(t, "public", res.Response[0]["community"])
+	assert.Equal(t, "", res.Response[1]["community"])
+	credentialStorage.AssertNotCalled(t, "GetToken", "aaab")
+}
+
+func TestGetSNMPDestinationsBadCredentials(t *testing.T) {
+	mockImpl := clientmock.SNMPDestinationDatabaseImpl{}
+	credentialStorage := clientmock.APITokenStorageImpl{}
+
+	handler := MetadataConnection{SNMPDestinations: &mockImpl, SNMPCredentials: &credentialStorage}
+
+	destinations := []*resources.SNMPDestination{
+		&resources.SNMPDestination{InternalID: "aaaa", Name: "NOC", Enabled: true, Address: "10.0.0.1:162", Version: "v2c"},
+	}
+	mockImpl.On("FindSNMPDestinations", []string{}).Return(destinations, nil)
+	credentialStorage.On("HasToken", "aaaa").Return(true, nil)
+	credentialStorage.On("GetToken", "aaaa").Return("not json", nil)
+
+	_, err := handler.GetSNMPDestinations([]string{})
+	assert.Error(t, err)
+}
+
+func TestPostSNMPDestination(t *testing.T) {
+	mockImpl := clientmock.SNMPDestinationDatabaseImpl{}
+	credentialStorage := clientmock.APITokenStorageImpl{}
+
+	handler := MetadataConnection{SNMPDestinations: &mockImpl, SNMPCredentials: &credentialStorage}
+
+	mockImpl.On("InsertSNMPDestination", mock.AnythingOfType("*resources.SNMPDestination")).Return(nil)
+	credentialStorage.On("SaveToken", mock.AnythingOfType("string"), `{"auth_passphrase":"authpass123","priv_passphrase":"privpass123"}`).Return(nil)
+
+	res, err := handler.PostSNMPDestination(map[string]interface{}{
+		"name":            "NOC",
+		"address":         "10.0.0.1",
+		"version":         "v3",
+		"username":        "pure1",
+		"auth_passphrase": "authpass123",
+		"priv_passphrase": "privpass123",
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res["id"])
+	assert.Equal(t, "10.0.0.1:162", res["address"])
+	assert.Equal(t, "sha", res["auth_protocol"])
+	assert.Equal(t, "aes", res["priv_protocol"])
+	assert.Len(t, res["engine_id"], 26)
+	credentialStorage.AssertCalled(t, "SaveToken", res["id"], `{"auth_passphrase":"authpass123","priv_passphrase":"privpass123"}`)
+}
+
+func TestPostSNMPDestinationInvalid(t *testing.T) {
+	handler := MetadataConnection{}
+
+	_, err := handler.PostSNMPDestination(map[string]interface{}{
+		"name":     "NOC",
+		"address":  "10.0.0.1",
+		"version":  "v3",
+		"username": "pure1",
+		// Privacy without authentication isn't allowed
+		"auth_protocol":   "none",
+		"priv_passphrase": "privpass123",
+	})
+	assert.Error(t, err)
+}
+
+func TestPatchSNMPDestinations(t *testing.T) {
+	mockImpl := clientmock.SNMPDestinationDatabaseImpl{}
+	credentialStorage := clientmock.APITokenStorageImpl{}
+
+	handler := MetadataConnection{SNMPDestinations: &mockImpl, SNMPCredentials: &credentialStorage}
+
+	destinations := []*resources.SNMPDestination{
+		&resources.SNMPDestination{InternalID: "aaaa", Name: "NOC", Enabled: true, Address: "10.0.0.1:162", Version: "v2c"},
+	}
+	mockImpl.On("FindSNMPDestinations", []string{"aaaa"}).Return(destinations, nil)
+	mockImpl.On("UpdateSNMPDestination", destinations[0]).Return(nil)
+	credentialStorage.On("HasToken", "aaaa").Return(true, nil)
+	credentialStorage.On("GetToken", "aaaa").Return(`{"community":"public"}`, nil)
+	credentialStorage.On("SaveToken", "aaaa", `{"community":"public"}`).Return(nil)
+
+	res, err := handler.PatchSNMPDestinations([]string{"aaaa"}, map[string]interface{}{
+		"address": "10.0.0.3:1162",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 1)
+	assert.Equal(t, "10.0.0.3:1162", res.Response[0]["address"])
+	assert.Equal(t, "public", res.Response[0]["community"])
+	mockImpl.AssertCalled(t, "UpdateSNMPDestination", destinations[0])
+	credentialStorage.AssertCalled(t, "SaveToken", "aaaa", `{"community":"public"}`)
+}
+
+func TestPatchSNMPDestinationsInvalid(t *testing.T) {
+	mockImpl := clientmock.SNMPDestinationDatabaseImpl{}
+	credentialStorage := clientmock.APITokenStorageImpl{}
+
+	handler := MetadataConnection{SNMPDestinations: &mockImpl, SNMPCredentials: &credentialStorage}
+
+	destinations := []*resources.SNMPDestination{
+		&resources.SNMPDestination{InternalID: "aaaa", Name: "NOC", Enabled: true, Address: "10.0.0.1:162", Version: "v2c"},
+	}
+	mockImpl.On("FindSNMPDestinations", []string{"aaaa"}).Return(destinations, nil)
+	credentialStorage.On("HasToken", "aaaa").Return(true, nil)
+	credentialStorage.On("GetToken", "aaaa").Return(`{"community":"public"}`, nil)
+
+	_, err := handler.PatchSNMPDestinations([]string{"aaaa"}, map[string]interface{}{
+		"community": "",
+	})
+	assert.Error(t, err)
+	mockImpl.AssertNotCalled(t, "UpdateSNMPDestination", mock.Anything)
+
+	_, err = handler.PatchSNMPDestinations([]string{"aaaa"}, map[string]interface{}{
+		"id": "000000000000000000000000",
+	})
+	assert.Error(t, err)
+}
+
+func TestDeleteSNMPDestinations(t *testing.T) {
+	mockImpl := clientmock.SNMPDestinationDatabaseImpl{}
+	credentialStorage := clientmock.APITokenStorageImpl{}
+
+	handler := MetadataConnection{SNMPDestinations: &mockImpl, SNMPCredentials: &credentialStorage}
+
+	mockImpl.On("DeleteSNMPDestinations", []string{"aaaa", "aaab"}).Return([]string{"aaaa"}, nil)
+	credentialStorage.On("DeleteToken", "aaaa").Return(nil)
+
+	count, err := handler.DeleteSNMPDestinations([]string{"aaaa", "aaab"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	credentialStorage.AssertCalled(t, "DeleteToken", "aaaa")
+	credentialStorage.AssertNotCalled(t, "DeleteToken", "aaab")
+}
+
+func TestTestSNMPDestinations(t *testing.T) {
+	mockImpl := clientmock.SNMPDestinationDatabaseImpl{}
+	credentialStorage := clientmock.APITokenStorageImpl{}
+	trapSender := clientmock.SNMPTestTrapSenderImpl{}
+
+	handler := MetadataConnection{SNMPDestinations: &mockImpl, SNMPCredentials: &credentialStorage, SNMPTestTraps: &trapSender}
+
+	destinations := []*resources.SNMPDestination{
+		&resources.SNMPDestination{InternalID: "aaaa", Name: "NOC", Enabled: true, Address: "10.0.0.1:162", Version: "v2c"},
+		&resources.SNMPDestination{InternalID: "aaab", Name: "Lab", Enabled: false, Address: "10.0.0.2:162", Version: "v2c"},
+	}
+	mockImpl.On("FindSNMPDestinations", []string{"aaaa", "aaab"}).Return(destinations, nil)
+	credentialStorage.On("HasToken", mock.AnythingOfType("string")).Return(true, nil)
+	credentialStorage.On("GetToken", mock.AnythingOfType("string")).Return(`{"community":"public"}`, nil)
+	trapSender.On("SendTestTrap", destinations[0]).Return(nil)
+	trapSender.On("SendTestTrap", destinations[1]).Return(fmt.Errorf("Some error"))
+
+	res, err := handler.TestSNMPDestinations([]string{"aaaa", "aaab"})
+	assert.NoError(t, err)
+	assert.Len(t, res.Response, 2)
+	assert.Equal(t, true, res.Response[0]["sent"])
+	assert.Equal(t, "", res.Response[0]["error"])
+	assert.Equal(t, false, res.Response[1]["sent"])
+	assert.Equal(t, "Some error", res.Response[1]["error"])
+	assert.Equal(t, "public", destinations[0].Community)
+}
@@ -21,8 +21,20 @@ import (
 // MetadataConnection provides a unified class to access metadata information through
 // any source
 type MetadataConnection struct {
-	Tokens resources.APITokenStorage
-	DAO    resources.ArrayDatabase
+	Tokens             resources.APITokenStorage
+	APIClients         resources.APITokenStorage // JSON encoded resources.APIClientCredentials, keyed by array ID
+	DAO                resources.ArrayDatabase
+	Forecasts          resources.CapacityForecastDatabase
+	Pods               resources.PodStatusDatabase
+	Hardware           resources.HardwareStatusDatabase
+	AlertRules         resources.AlertRuleDatabase
+	Alerts             resources.AlertDatabase
+	Audit              resources.AuditEventDatabase
+	Snapshots          resources.SnapshotInventoryDatabase
+	MaintenanceWindows resources.MaintenanceWindowDatabase
+	SNMPDestinations   resources.SNMPDestinationDatabase
+	SNMPCredentials    resources.APITokenStorage // JSON encoded resources.SNMPCredentials, keyed by destination ID
+	SNMPTestTraps      resources.SNMPTestTrapSender
 }
 
 // BulkResponse provides a basic template for anything that returns an array of objects, and is
@@ -30,3 +42,10 @@ type MetadataConnection struct {
 type BulkResponse struct {
 	Response []map[string]interface{} `json:"response"`
 }
+
+// ForecastResponse holds the capacity forecasts of individual arrays, along with combined forecasts for
+// the arrays sharing each tag
+type ForecastResponse struct {
+	Response []map[string]interface{} `json:"response"`
+	Tags     []map[string]interface{} `json:"tags"`
+}
@@ -20,6 +20,10 @@ import (
 	"strings"
 )
 
+// AuthenticatedUserHeader is the header the auth server identifies the user of an authorized request with.
+// The ingress passes it on to the services behind it, replacing any value sent by the client.
+const AuthenticatedUserHeader = "X-Pure1-Unplugged-User"
+
 // GetRequestTokenHeader gets a token of the specified type ("Bearer", "Basic") from
 // the headers of a request
 func GetRequestTokenHeader(header http.Header, tokenType string) (string, error) {
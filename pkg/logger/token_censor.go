@@ -22,8 +22,8 @@ import (
 
 // CensorAPITokensFromFormatter takes the given formatter and adds an extra formatter
 func CensorAPITokensFromFormatter(innerFormatter log.Formatter) log.Formatter {
-	apiServerTokenRegex, _ := regexp.Compile("\\\\\"api_token\\\\\":\\\\\"(.*?)\\\\\"")
-	apiServerTokenRegexCutOff, _ := regexp.Compile("\\\\\"api_token\\\\\":\\\\\"(.*?) \\.\\.\\.\\.\\.")
+	apiServerTokenRegex, _ := regexp.Compile("\\\\\"(api_token|api_client_private_key)\\\\\":\\\\\"(.*?)\\\\\"")
+	apiServerTokenRegexCutOff, _ := regexp.Compile("\\\\\"(api_token|api_client_private_key)\\\\\":\\\\\"(.*?) \\.\\.\\.\\.\\.")
 	return &tokenCensoringFormatter{nested: innerFormatter,
 		apiServerTokenRegex:       apiServerTokenRegex,
 		apiServerTokenRegexCutOff: apiServerTokenRegexCutOff,
@@ -37,9 +37,9 @@ func (t *tokenCensoringFormatter) Format(entry *log.Entry) ([]byte, error) {
 		return formatted, err
 	}
 	stringified := string(formatted)
-	// Replace API tokens that appear in the format of \"api_token\":\"this-is-an-api-token\"
-	stringified = t.apiServerTokenRegex.ReplaceAllString(stringified, "\\\"api_token\\\":\\\"****\\\"")
+	// Replace API tokens (and API client private keys) that appear in the format of \"api_token\":\"this-is-an-api-token\"
+	stringified = t.apiServerTokenRegex.ReplaceAllString(stringified, "\\\"$1\\\":\\\"****\\\"")
 	// Replace API tokens that got cut off by ellipses: such as \"api_token\":\"this-is-an-api-tok ....."
-	stringified = t.apiServerTokenRegexCutOff.ReplaceAllString(stringified, "\\\"api_token\\\":\\\"**** .....")
+	stringified = t.apiServerTokenRegexCutOff.ReplaceAllString(stringified, "\\\"$1\\\":\\\"**** .....")
 	return []byte(stringified), err
 }
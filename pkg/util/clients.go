@@ -15,29 +15,53 @@
 package util
 
 import (
+	"fmt"
 	"net"
 	"strings"
-
-	log "github.com/sirupsen/logrus"
 )
 
-// ParseEndpoint is a helper function that reads an endpoint and returns the looked-up IP address
-func ParseEndpoint(displayName string, endpoint string) (net.IP, error) {
+// EndpointHost is a helper function that strips the scheme and any trailing slash from an endpoint
+func EndpointHost(endpoint string) string {
 	// Remove any leading http/https if exists
 	endpoint = strings.TrimPrefix(endpoint, "http://")
 	endpoint = strings.TrimPrefix(endpoint, "https://")
 	// Remove any trailing / if exists
-	endpoint = strings.TrimSuffix(endpoint, "/")
+	return strings.TrimSuffix(endpoint, "/")
+}
 
-	ip, err := net.LookupIP(endpoint)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"display_name": displayName,
-			"endpoint":     endpoint,
-		}).Error("Could not parse endpoint into IP")
-		return nil, err
+// EndpointURLHost is a helper function that formats an endpoint (FQDN, IPv4 or IPv6 address, with or
+// without a scheme) as the host part of a URL. Host names are kept as-is so they get resolved again on
+// every connection, and IPv6 literals are wrapped in brackets (escaping any zone).
+func EndpointURLHost(endpoint string) string {
+	host := EndpointHost(endpoint)
+	if strings.HasPrefix(host, "[") {
+		return host
 	}
 
-	// Return IPv4
-	return ip[0], nil
+	address := host
+	zone := ""
+	if index := strings.Index(host, "%"); index >= 0 {
+		address, zone = host[:index], host[index+1:]
+	}
+	if !strings.Contains(address, ":") || net.ParseIP(address) == nil {
+		return host
+	}
+	if len(zone) > 0 {
+		return fmt.Sprintf("[%s%%25%s]", address, zone)
+	}
+	return fmt.Sprintf("[%s]", address)
+}
+
+// EndpointHostname is a helper function that returns the bare host name or IP address of an endpoint,
+// without scheme, port, brackets or IPv6 zone (as used to verify the array certificate)
+func EndpointHostname(endpoint string) string {
+	host := EndpointHost(endpoint)
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if index := strings.Index(host, "%"); index >= 0 {
+		host = host[:index]
+	}
+	return host
 }
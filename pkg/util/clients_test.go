@@ -22,17 +22,35 @@ import (
 )
 
 const (
-	DemoIP = "216.58.195.238" // google.com
+	DemoIP   = "216.58.195.238" // google.com
+	DemoIPv6 = "2001:db8::10"
+	DemoFQDN = "array01.example.com"
 )
 
-func TestParseEndpointHTTPSWithSlash(t *testing.T) {
-	ip, err := ParseEndpoint("test-client", fmt.Sprintf("https://%s/", DemoIP))
-	assert.NoError(t, err)
-	assert.Equal(t, DemoIP, ip.String())
+func TestEndpointURLHostHTTPSWithSlash(t *testing.T) {
+	assert.Equal(t, DemoIP, EndpointURLHost(fmt.Sprintf("https://%s/", DemoIP)))
 }
 
-func TestParseEndpointPHTTP(t *testing.T) {
-	ip, err := ParseEndpoint("test-client", fmt.Sprintf("http://%s", DemoIP))
-	assert.NoError(t, err)
-	assert.Equal(t, DemoIP, ip.String())
+func TestEndpointURLHostHTTP(t *testing.T) {
+	assert.Equal(t, DemoIP, EndpointURLHost(fmt.Sprintf("http://%s", DemoIP)))
+}
+
+func TestEndpointURLHostFQDN(t *testing.T) {
+	assert.Equal(t, DemoFQDN, EndpointURLHost(fmt.Sprintf("https://%s", DemoFQDN)))
+	assert.Equal(t, DemoFQDN+":8443", EndpointURLHost(DemoFQDN+":8443"))
+}
+
+func TestEndpointURLHostIPv6(t *testing.T) {
+	assert.Equal(t, "[2001:db8::10]", EndpointURLHost(DemoIPv6))
+	assert.Equal(t, "[2001:db8::10]", EndpointURLHost(fmt.Sprintf("https://[%s]/", DemoIPv6)))
+	assert.Equal(t, "[2001:db8::10]:8443", EndpointURLHost(fmt.Sprintf("[%s]:8443", DemoIPv6)))
+	assert.Equal(t, "[fe80::1%25eth0]", EndpointURLHost("fe80::1%eth0"))
+}
+
+func TestEndpointHostname(t *testing.T) {
+	assert.Equal(t, DemoIP, EndpointHostname(fmt.Sprintf("https://%s/", DemoIP)))
+	assert.Equal(t, DemoFQDN, EndpointHostname(DemoFQDN+":8443"))
+	assert.Equal(t, DemoIPv6, EndpointHostname(DemoIPv6))
+	assert.Equal(t, DemoIPv6, EndpointHostname(fmt.Sprintf("https://[%s]:8443", DemoIPv6)))
+	assert.Equal(t, "fe80::1", EndpointHostname("fe80::1%eth0"))
 }
@@ -0,0 +1,177 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CertificateMismatchError is returned when an array presents a management certificate that
+// doesn't match the CA bundle or fingerprint registered for it
+type CertificateMismatchError struct {
+	Reason string
+}
+
+// Error returns the reason the certificate was rejected
+func (e *CertificateMismatchError) Error() string {
+	return fmt.Sprintf("certificate mismatch: %s", e.Reason)
+}
+
+// IsCertificateMismatch checks if the given error (or any error it wraps) is a CertificateMismatchError
+func IsCertificateMismatch(err error) bool {
+	var mismatch *CertificateMismatchError
+	return errors.As(err, &mismatch)
+}
+
+// NormalizeCertificateFingerprint converts a SHA-256 fingerprint in any of the common formats
+// ("AB:CD:...", "ab cd ...", "abcd...") into lowercase hex without separators
+func NormalizeCertificateFingerprint(fingerprint string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(fingerprint))
+	normalized = strings.Replace(normalized, ":", "", -1)
+	normalized = strings.Replace(normalized, " ", "", -1)
+
+	decoded, err := hex.DecodeString(normalized)
+	if err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("Certificate fingerprint must be a hex encoded SHA-256 hash")
+	}
+	return normalized, nil
+}
+
+// ValidateCACertificate checks that the given string holds at least one PEM encoded certificate
+func ValidateCACertificate(caCertificate string) error {
+	if !x509.NewCertPool().AppendCertsFromPEM([]byte(caCertificate)) {
+		return fmt.Errorf("CA certificate must contain at least one PEM encoded certificate")
+	}
+	return nil
+}
+
+// ParseRSAPrivateKey parses a PEM encoded (PKCS #1 or PKCS #8) RSA private key, such as the key of a
+// FlashArray API client
+func ParseRSAPrivateKey(privateKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(privateKey)))
+	if block == nil {
+		return nil, fmt.Errorf("Private key must be PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Private key must be a PKCS #1 or PKCS #8 RSA private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Private key must be an RSA private key")
+	}
+	return key, nil
+}
+
+// NewArrayTLSConfig creates the TLS config used to talk to a single array. If neither a CA bundle nor a
+// fingerprint is given, certificate verification is skipped (arrays ship with self-signed certificates).
+// Otherwise the presented certificate must chain to the CA bundle (for one of the array's management host
+// names, since the same config is used for every endpoint) and/or match the pinned SHA-256 fingerprint; a
+// CertificateMismatchError is returned from the handshake if it doesn't.
+func NewArrayTLSConfig(hosts []string, caCertificate string, fingerprint string) (*tls.Config, error) {
+	caCertificate = strings.TrimSpace(caCertificate)
+	fingerprint = strings.TrimSpace(fingerprint)
+	if len(caCertificate) == 0 && len(fingerprint) == 0 {
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+
+	var roots *x509.CertPool
+	if len(caCertificate) > 0 {
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM([]byte(caCertificate)) {
+			return nil, fmt.Errorf("CA certificate must contain at least one PEM encoded certificate")
+		}
+	}
+
+	var pinned string
+	if len(fingerprint) > 0 {
+		normalized, err := NormalizeCertificateFingerprint(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		pinned = normalized
+	}
+
+	return &tls.Config{
+		// The default verification is replaced by verifyArrayCertificate, since arrays are usually
+		// addressed by IP and the standard checks would reject a pinned self-signed certificate
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyArrayCertificate(rawCerts, hosts, roots, pinned)
+		},
+	}, nil
+}
+
+// verifyArrayCertificate is a helper function that checks the raw certificates presented by an array
+// against the registered CA pool and pinned fingerprint (either of which may be unset)
+func verifyArrayCertificate(rawCerts [][]byte, hosts []string, roots *x509.CertPool, pinned string) error {
+	if len(rawCerts) == 0 {
+		return &CertificateMismatchError{Reason: "array did not present a certificate"}
+	}
+
+	if len(pinned) > 0 {
+		sum := sha256.Sum256(rawCerts[0])
+		if hex.EncodeToString(sum[:]) != pinned {
+			return &CertificateMismatchError{Reason: "certificate fingerprint does not match the pinned fingerprint"}
+		}
+	}
+
+	if roots != nil {
+		certs := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return &CertificateMismatchError{Reason: fmt.Sprintf("could not parse certificate: %v", err)}
+			}
+			certs = append(certs, cert)
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		var err error
+		for _, host := range hosts {
+			_, err = certs[0].Verify(x509.VerifyOptions{
+				DNSName:       host,
+				Intermediates: intermediates,
+				Roots:         roots,
+			})
+			if err == nil {
+				break
+			}
+		}
+		if len(hosts) == 0 {
+			err = fmt.Errorf("no management host name to verify the certificate for")
+		}
+		if err != nil {
+			return &CertificateMismatchError{Reason: err.Error()}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2019, Pure Storage Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCertificateFingerprint(t *testing.T) {
+	expected := strings.Repeat("ab", sha256.Size)
+	normalized, err := NormalizeCertificateFingerprint(strings.ToUpper(strings.Repeat("ab:", sha256.Size-1) + "ab"))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, normalized)
+}
+
+func TestNormalizeCertificateFingerprintWrongLength(t *testing.T) {
+	_, err := NormalizeCertificateFingerprint("abcd")
+	assert.Error(t, err)
+}
+
+func TestNewArrayTLSConfigNoVerification(t *testing.T) {
+	config, err := NewArrayTLSConfig([]string{"10.0.0.1"}, "", "")
+	assert.NoError(t, err)
+	assert.True(t, config.InsecureSkipVerify)
+	assert.Nil(t, config.VerifyPeerCertificate)
+}
+
+func TestNewArrayTLSConfigInvalidCA(t *testing.T) {
+	_, err := NewArrayTLSConfig([]string{"10.0.0.1"}, "not a certificate", "")
+	assert.Error(t, err)
+}
+
+func TestArrayTLSConfigPinnedFingerprint(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	sum := sha256.Sum256(server.Certificate().Raw)
+	config, err := NewArrayTLSConfig([]string{"127.0.0.1"}, "", hex.EncodeToString(sum[:]))
+	assert.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	_, err = client.Get(server.URL)
+	assert.NoError(t, err)
+}
+
+func TestArrayTLSConfigFingerprintMismatch(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	config, err := NewArrayTLSConfig([]string{"127.0.0.1"}, "", strings.Repeat("00", sha256.Size))
+	assert.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+	assert.True(t, IsCertificateMismatch(err))
+}
+
+func TestArrayTLSConfigCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caCertificate := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	config, err := NewArrayTLSConfig([]string{"127.0.0.1"}, caCertificate, "")
+	assert.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	_, err = client.Get(server.URL)
+	assert.NoError(t, err)
+}
+
+func TestArrayTLSConfigCABundleMultipleHosts(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caCertificate := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	config, err := NewArrayTLSConfig([]string{"array01.invalid", "127.0.0.1"}, caCertificate, "")
+	assert.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	_, err = client.Get(server.URL)
+	assert.NoError(t, err)
+}
+
+func TestArrayTLSConfigCABundleHostMismatch(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caCertificate := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	config, err := NewArrayTLSConfig([]string{"array01.invalid"}, caCertificate, "")
+	assert.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+	assert.True(t, IsCertificateMismatch(err))
+}
+
+func TestParseRSAPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	parsed, err := ParseRSAPrivateKey(string(pkcs1))
+	assert.NoError(t, err)
+	assert.Equal(t, key.N, parsed.N)
+
+	pkcs8Bytes, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Bytes})
+	parsed, err = ParseRSAPrivateKey("\n" + string(pkcs8) + "\n")
+	assert.NoError(t, err)
+	assert.Equal(t, key.N, parsed.N)
+}
+
+func TestParseRSAPrivateKeyInvalid(t *testing.T) {
+	_, err := ParseRSAPrivateKey("not a key")
+	assert.Error(t, err)
+
+	_, err = ParseRSAPrivateKey(string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("garbage")})))
+	assert.Error(t, err)
+}